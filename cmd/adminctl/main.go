@@ -0,0 +1,329 @@
+// Command adminctl runs admin operations against the gRPC admin API, so ops don't have to craft requests by hand.
+//
+//	adminctl [global flags] <command> [command flags]
+//
+// The caller authenticates with the access token of an admin (or a moderator for the commands they may run),
+// passed with --token or ADMINCTL_TOKEN. Creating accounts needs an admin too, the first admin of a deployment is
+// bootstrapped with admin.user_ids (ADMIN_USER_IDS) in the server config.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	adminpb "main/pkg/proto/gen/admin/v1"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// cli holds the global flags, shared by every command.
+type cli struct {
+	addr    string
+	token   string
+	useTLS  bool
+	timeout time.Duration
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "adminctl:", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	c := &cli{}
+	root := &cobra.Command{
+		Use:           "adminctl",
+		Short:         "Run admin operations against the gRPC admin API",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&c.addr, "addr", envOr("ADMINCTL_ADDR", "localhost:50052"), "address of the gRPC server")
+	flags.StringVar(&c.token, "token", os.Getenv("ADMINCTL_TOKEN"), "access token of an admin")
+	flags.BoolVar(&c.useTLS, "tls", false, "connect over TLS")
+	flags.DurationVar(&c.timeout, "timeout", 30*time.Second, "timeout of the call")
+
+	root.AddCommand(
+		c.createAdminCommand(),
+		c.lookupCommand(),
+		c.blockCommand(),
+		c.unblockCommand(),
+		c.revokeSessionsCommand(),
+		c.rotateKeyCommand(),
+		c.backfillCommand(),
+	)
+	return root
+}
+
+// call connects to the admin API and runs fn with the access token attached, within the timeout.
+func (c *cli) call(cmd *cobra.Command, fn func(ctx context.Context, client adminpb.AdminServiceClient) error) error {
+	if c.token == "" {
+		return errors.New("an access token is required, pass --token or set ADMINCTL_TOKEN")
+	}
+
+	creds := insecure.NewCredentials()
+	if c.useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(c.addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), c.timeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+	return fn(ctx, adminpb.NewAdminServiceClient(conn))
+}
+
+func (c *cli) createAdminCommand() *cobra.Command {
+	var username, email, password string
+	var moderator bool
+	cmd := &cobra.Command{
+		Use:   "create-admin",
+		Short: "Create an account with the admin role",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if password == "" {
+				fmt.Fprint(os.Stderr, "Password: ")
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("failed to read password: %w", err)
+				}
+				password = strings.TrimRight(line, "\r\n")
+			}
+			role := "admin"
+			if moderator {
+				role = "moderator"
+			}
+
+			return c.call(cmd, func(ctx context.Context, client adminpb.AdminServiceClient) error {
+				resp, err := client.CreateUser(ctx, &adminpb.CreateUserRequest{
+					Username: username,
+					Email:    email,
+					Password: password,
+					Roles:    []string{role},
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Printf("created %s %s (%s)\n", role, username, resp.GetUserId())
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&username, "username", "", "username of the account")
+	cmd.Flags().StringVar(&email, "email", "", "email address of the account")
+	cmd.Flags().StringVar(&password, "password", "", "password of the account, read from stdin if empty")
+	cmd.Flags().BoolVar(&moderator, "moderator", false, "grant the moderator role instead of admin")
+	_ = cmd.MarkFlagRequired("username")
+	_ = cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+func (c *cli) lookupCommand() *cobra.Command {
+	var user string
+	cmd := &cobra.Command{
+		Use:   "lookup",
+		Short: "Show the account of a user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.call(cmd, func(ctx context.Context, client adminpb.AdminServiceClient) error {
+				resp, err := client.LookupUser(ctx, &adminpb.LookupUserRequest{Query: user})
+				if err != nil {
+					return err
+				}
+				u := resp.GetUser()
+				fmt.Printf("id:       %s\nusername: %s\nemail:    %s\nroles:    %s\ncreated:  %s\n",
+					u.GetUserId(), u.GetUsername(), u.GetEmail(), strings.Join(u.GetRoles(), ", "), formatTime(u.GetCreatedAt()))
+				if u.GetIsBlocked() {
+					until := "indefinitely"
+					if u.GetBlockedUntil() != nil {
+						until = "until " + formatTime(u.GetBlockedUntil())
+					}
+					fmt.Printf("blocked:  %s (%s)\n", until, u.GetBlockedReason())
+				}
+				if u.GetDeletedAt() != nil {
+					fmt.Printf("deleted:  %s\n", formatTime(u.GetDeletedAt()))
+				}
+				return nil
+			})
+		},
+	}
+	userFlag(cmd, &user)
+	return cmd
+}
+
+func (c *cli) blockCommand() *cobra.Command {
+	var user, reason string
+	var duration time.Duration
+	cmd := &cobra.Command{
+		Use:   "block",
+		Short: "Block an account and end its sessions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.call(cmd, func(ctx context.Context, client adminpb.AdminServiceClient) error {
+				userID, err := resolveUser(ctx, client, user)
+				if err != nil {
+					return err
+				}
+				req := &adminpb.BlockUserRequest{UserId: userID, Reason: reason}
+				if duration > 0 {
+					req.ExpiresAt = timestamppb.New(time.Now().Add(duration))
+				}
+				if _, err := client.BlockUser(ctx, req); err != nil {
+					return err
+				}
+				fmt.Printf("blocked %s\n", userID)
+				return nil
+			})
+		},
+	}
+	userFlag(cmd, &user)
+	cmd.Flags().StringVar(&reason, "reason", "", "reason shown in the audit log")
+	cmd.Flags().DurationVar(&duration, "for", 0, "how long the block lasts, 0 blocks indefinitely")
+	return cmd
+}
+
+func (c *cli) unblockCommand() *cobra.Command {
+	var user string
+	cmd := &cobra.Command{
+		Use:   "unblock",
+		Short: "Lift the block of an account",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.call(cmd, func(ctx context.Context, client adminpb.AdminServiceClient) error {
+				userID, err := resolveUser(ctx, client, user)
+				if err != nil {
+					return err
+				}
+				if _, err := client.UnblockUser(ctx, &adminpb.UnblockUserRequest{UserId: userID}); err != nil {
+					return err
+				}
+				fmt.Printf("unblocked %s\n", userID)
+				return nil
+			})
+		},
+	}
+	userFlag(cmd, &user)
+	return cmd
+}
+
+func (c *cli) revokeSessionsCommand() *cobra.Command {
+	var user, session string
+	cmd := &cobra.Command{
+		Use:   "revoke-sessions",
+		Short: "End one or all sessions of a user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.call(cmd, func(ctx context.Context, client adminpb.AdminServiceClient) error {
+				userID, err := resolveUser(ctx, client, user)
+				if err != nil {
+					return err
+				}
+				if session != "" {
+					if _, err := client.RevokeSession(ctx, &adminpb.RevokeSessionRequest{UserId: userID, SessionId: session}); err != nil {
+						return err
+					}
+					fmt.Printf("revoked session %s of %s\n", session, userID)
+					return nil
+				}
+				if _, err := client.RevokeAllSessions(ctx, &adminpb.RevokeAllSessionsRequest{UserId: userID}); err != nil {
+					return err
+				}
+				fmt.Printf("revoked all sessions of %s\n", userID)
+				return nil
+			})
+		},
+	}
+	userFlag(cmd, &user)
+	cmd.Flags().StringVar(&session, "session", "", "ID of the session to end, all sessions if empty")
+	return cmd
+}
+
+func (c *cli) rotateKeyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Switch every instance to a new JWT signing key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.call(cmd, func(ctx context.Context, client adminpb.AdminServiceClient) error {
+				resp, err := client.RotateSigningKey(ctx, &adminpb.RotateSigningKeyRequest{})
+				if err != nil {
+					return err
+				}
+				fmt.Printf("rotating to key %s\n", resp.GetKeyId())
+				return nil
+			})
+		},
+	}
+}
+
+func (c *cli) backfillCommand() *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Run a data backfill (post_counters, follow_counters, explore_ranking)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.call(cmd, func(ctx context.Context, client adminpb.AdminServiceClient) error {
+				resp, err := client.RunBackfill(ctx, &adminpb.RunBackfillRequest{Name: name})
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s: %d rows updated\n", name, resp.GetCount())
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "backfill to run: post_counters, follow_counters or explore_ranking")
+	_ = cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+// userFlag adds the required --user flag of the commands acting on an account.
+func userFlag(cmd *cobra.Command, user *string) {
+	cmd.Flags().StringVar(user, "user", "", "user ID, username or email address")
+	_ = cmd.MarkFlagRequired("user")
+}
+
+// resolveUser returns the ID of the user given by ID, username or email address.
+func resolveUser(ctx context.Context, client adminpb.AdminServiceClient, user string) (string, error) {
+	if _, err := uuid.Parse(user); err == nil {
+		return user, nil
+	}
+	resp, err := client.LookupUser(ctx, &adminpb.LookupUserRequest{Query: user})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", user, err)
+	}
+	return resp.GetUser().GetUserId(), nil
+}
+
+func formatTime(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return "-"
+	}
+	return ts.AsTime().Local().Format(time.RFC3339)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,97 @@
+// Command migrate manages the database schema with the migrations embedded into the binary.
+//
+//	migrate -config configs/config.yaml <up|down|status|version>
+//
+// up applies every pending migration, down rolls back the newest applied one.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"main/internal/config"
+	psql "main/internal/storage/postgres"
+	"main/internal/storage/postgres/migrate"
+	"main/migrations"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: migrate [-config path] <up|down|status|version>")
+		flag.PrintDefaults()
+	}
+	cfg := config.LoadConfig()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pool, err := psql.NewPostgresConnection(cfg.PostgresConfig.DSN(), psql.Options{
+		QueryTimeout:           cfg.PostgresConfig.QueryTimeout,
+		QueryExecMode:          cfg.PostgresConfig.QueryExecMode,
+		StatementCacheCapacity: cfg.PostgresConfig.StatementCacheCapacity,
+	})
+	if err != nil {
+		fatal(fmt.Errorf("failed to connect to the database: %w", err))
+	}
+	defer pool.Close()
+
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		fatal(err)
+	}
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		m, ok, err := migrator.Down(ctx)
+		if err != nil {
+			fatal(err)
+		}
+		if !ok {
+			fmt.Println("no applied migrations")
+			return
+		}
+		fmt.Printf("rolled back %d_%s\n", m.Version, m.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fatal(err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-25s %d_%s\n", applied, s.Version, s.Name)
+		}
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("database: %d\nbinary:   %d\n", version, migrator.Latest())
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "migrate:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+var (
+	firstNames = []string{
+		"Alice", "Bob", "Carla", "Dmitri", "Elena", "Farid", "Grace", "Hiro", "Ines", "Jonas", "Kira", "Liam",
+		"Maya", "Nikolai", "Olga", "Pedro", "Quinn", "Rosa", "Sven", "Tara", "Umar", "Vera", "Wen", "Yusuf", "Zoe",
+	}
+	lastNames = []string{
+		"Anderson", "Brooks", "Castillo", "Dubois", "Eriksen", "Fischer", "Garcia", "Hughes", "Ivanova", "Jensen",
+		"Kowalski", "Lopez", "Moreau", "Nakamura", "Okafor", "Petrov", "Rossi", "Sato", "Tanaka", "Weber",
+	}
+	bios = []string{
+		"Coffee first, code second.",
+		"Photographer chasing golden hour.",
+		"Trail runner and amateur baker.",
+		"Building things on the internet.",
+		"Plants, books and too many tabs.",
+		"Learning a new language every year.",
+		"Cyclist. Cat person. Occasional poet.",
+		"",
+	}
+	hashtags = []string{
+		"travel", "food", "coding", "music", "photography", "fitness", "books", "art", "nature", "golang",
+		"coffee", "weekend", "sunset", "design", "gaming", "movies", "running", "cats", "dogs", "startup",
+	}
+	openers = []string{
+		"Just got back from", "Can't stop thinking about", "Finally tried", "Spent the whole day on",
+		"Here's my take on", "Throwback to", "Loving", "Not sure how I feel about", "Big news about", "Quick thoughts on",
+	}
+	subjects = []string{
+		"the new café downtown", "a weekend in the mountains", "my first marathon", "this album", "the sunrise today",
+		"a side project", "homemade pasta", "the book everyone is talking about", "our team offsite", "the city at night",
+		"a rainy afternoon", "the latest release", "learning to paint", "a road trip with friends", "the farmers market",
+	}
+	closers = []string{
+		"Highly recommend.", "Would do it again.", "Thoughts?", "10/10.", "More soon.", "Who's in next time?",
+		"Still processing it.", "What a day.", "", "",
+	}
+	comments = []string{
+		"Love this!", "So jealous right now.", "Where is this?", "Great shot!", "Couldn't agree more.",
+		"Adding it to my list.", "This made my day.", "Haha, classic.", "How long did it take?", "Need details!",
+		"Same here.", "Looks amazing.", "Congrats!", "Wow.", "Interesting take.",
+	}
+	messages = []string{
+		"Hey! How are you?", "Are we still on for tomorrow?", "Sounds good to me.", "Did you see the latest post?",
+		"Running a bit late, sorry!", "Haha yes", "Let me check and get back to you.", "Thanks a lot!",
+		"What time works for you?", "See you there.", "Can you send me the link?", "That's hilarious",
+		"I'll bring snacks.", "Good night!", "Sure, no problem.",
+	}
+)
+
+// faker generates realistic looking content from the seeded source, so a seed reproduces the same data.
+type faker struct {
+	rnd *rand.Rand
+}
+
+func newFaker(seed uint64) *faker {
+	return &faker{rnd: rand.New(rand.NewPCG(seed, seed))}
+}
+
+func pick[T any](f *faker, items []T) T {
+	return items[f.rnd.IntN(len(items))]
+}
+
+// person returns the display name and the username of the n-th seeded user, the number keeps usernames unique.
+func (f *faker) person(n int) (name, username string) {
+	first, last := pick(f, firstNames), pick(f, lastNames)
+	return first + " " + last, fmt.Sprintf("%s_%s%d", strings.ToLower(first), strings.ToLower(last), n)
+}
+
+func (f *faker) bio() string {
+	return pick(f, bios)
+}
+
+// post returns a post description with up to two hashtags.
+func (f *faker) post() string {
+	var b strings.Builder
+	b.WriteString(pick(f, openers))
+	b.WriteByte(' ')
+	b.WriteString(pick(f, subjects))
+	b.WriteByte('.')
+	if closer := pick(f, closers); closer != "" {
+		b.WriteByte(' ')
+		b.WriteString(closer)
+	}
+	for range f.rnd.IntN(3) {
+		b.WriteString(" #")
+		b.WriteString(pick(f, hashtags))
+	}
+	return b.String()
+}
+
+func (f *faker) comment() string {
+	return pick(f, comments)
+}
+
+func (f *faker) message() string {
+	return pick(f, messages)
+}
+
+// between returns a random time in [from, to).
+func (f *faker) between(from, to time.Time) time.Time {
+	span := to.Sub(from)
+	if span <= 0 {
+		return from
+	}
+	return from.Add(time.Duration(f.rnd.Int64N(int64(span))))
+}
+
+// percent reports true with the probability of p percent.
+func (f *faker) percent(p int) bool {
+	return f.rnd.IntN(100) < p
+}
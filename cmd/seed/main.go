@@ -0,0 +1,407 @@
+// Command seed fills the database with fake users, follows, posts, comments and chats for local development
+// and load tests.
+//
+//	seed [-config path] [-users 50] [-follows 15] [-posts 5] [-comments 3] [-chats 3] [-messages 20] [-logins file]
+//
+// The numbers other than -users are per user, per post for comments and per chat for messages. Every seeded
+// user has the password of -password. Rows are copied straight into the tables in one transaction, the
+// denormalized counters are filled in, but no events, notifications or push messages are produced. Run it
+// against a migrated database; it only adds rows, so it can be run repeatedly.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"main/domain/entity"
+	"main/internal/config"
+	psql "main/internal/storage/postgres"
+	"main/pkg/hashtag"
+	"main/pkg/password"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sizes are how many rows are seeded.
+type sizes struct {
+	users    int
+	follows  int
+	posts    int
+	comments int
+	chats    int
+	messages int
+}
+
+func main() {
+	var n sizes
+	flag.IntVar(&n.users, "users", 50, "number of users")
+	flag.IntVar(&n.follows, "follows", 15, "accounts each user follows")
+	flag.IntVar(&n.posts, "posts", 5, "posts of each user")
+	flag.IntVar(&n.comments, "comments", 3, "comments of each post, a part of them replies")
+	flag.IntVar(&n.chats, "chats", 3, "direct chats each user starts")
+	flag.IntVar(&n.messages, "messages", 20, "messages of each chat")
+	plain := flag.String("password", "Passw0rd!", "password of every seeded user")
+	logins := flag.String("logins", "", "file the usernames of the seeded users are written to, one per line, e.g. for the load tests")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "seed of the fake data, the same seed gives the same content")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: seed [-config path] [flags]")
+		flag.PrintDefaults()
+	}
+	cfg := config.LoadConfig()
+	if flag.NArg() != 0 || n.users < 0 || n.follows < 0 || n.posts < 0 || n.comments < 0 || n.chats < 0 || n.messages < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	hasher, err := password.NewHasher(cfg.PasswordConfig.Algorithm, cfg.PasswordConfig.BcryptCost, password.Argon2Params{
+		Memory:      cfg.PasswordConfig.Argon2Memory,
+		Iterations:  cfg.PasswordConfig.Argon2Iterations,
+		Parallelism: cfg.PasswordConfig.Argon2Parallelism,
+		SaltLength:  cfg.PasswordConfig.Argon2SaltLength,
+		KeyLength:   cfg.PasswordConfig.Argon2KeyLength,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	// hashing is slow on purpose, the users share one hash
+	hash, err := hasher.Hash(*plain)
+	if err != nil {
+		fatal(err)
+	}
+
+	pool, err := psql.NewPostgresConnection(cfg.PostgresConfig.DSN(), psql.Options{
+		QueryTimeout:           cfg.PostgresConfig.QueryTimeout,
+		QueryExecMode:          cfg.PostgresConfig.QueryExecMode,
+		StatementCacheCapacity: cfg.PostgresConfig.StatementCacheCapacity,
+	})
+	if err != nil {
+		fatal(fmt.Errorf("failed to connect to the database: %w", err))
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	// usernames are numbered after the existing users, so seeding again doesn't collide
+	var existing int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&existing); err != nil {
+		fatal(err)
+	}
+
+	d := generate(newFaker(*seed), n, existing, hash, time.Now())
+	if err := d.insert(ctx, pool); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("seeded %d users, %d follows, %d posts, %d comments, %d chats and %d messages (seed %d)\n",
+		len(d.users), len(d.follows), len(d.posts), len(d.comments), len(d.chats), len(d.messages), *seed)
+	if *logins != "" {
+		if err := d.writeLogins(*logins); err != nil {
+			fatal(err)
+		}
+	}
+	if len(d.users) > 0 {
+		fmt.Printf("log in as %s with the password %q\n", d.users[0].username, *plain)
+	}
+}
+
+type user struct {
+	id        uuid.UUID
+	username  string
+	name      string
+	bio       string
+	createdAt time.Time
+	followers int
+	following int
+}
+
+type follow struct {
+	followerID, followeeID uuid.UUID
+	createdAt              time.Time
+}
+
+type post struct {
+	id          uuid.UUID
+	userID      uuid.UUID
+	description string
+	visibility  entity.PostVisibility
+	comments    int
+	createdAt   time.Time
+}
+
+type comment struct {
+	id        uuid.UUID
+	postID    uuid.UUID
+	userID    uuid.UUID
+	replyTo   *uuid.UUID
+	content   string
+	replies   int
+	createdAt time.Time
+}
+
+type chat struct {
+	id         uuid.UUID
+	user1ID    uuid.UUID
+	user2ID    uuid.UUID
+	createdAt  time.Time
+	activityAt time.Time
+	// when each member last read the chat, the members are user1 and user2
+	read1At, read2At time.Time
+}
+
+type message struct {
+	id        uuid.UUID
+	chatID    uuid.UUID
+	senderID  uuid.UUID
+	content   string
+	createdAt time.Time
+}
+
+// dataset is everything seeded, generated up front so the counters are known before the rows are copied.
+type dataset struct {
+	hash     string
+	users    []*user
+	follows  []follow
+	posts    []*post
+	comments []*comment
+	chats    []*chat
+	messages []message
+}
+
+func generate(f *faker, n sizes, existing int, hash string, now time.Time) *dataset {
+	d := &dataset{hash: hash}
+
+	for i := range n.users {
+		name, username := f.person(existing + i + 1)
+		d.users = append(d.users, &user{
+			id:        uuid.New(),
+			username:  username,
+			name:      name,
+			bio:       f.bio(),
+			createdAt: f.between(now.Add(-90*24*time.Hour), now.Add(-7*24*time.Hour)),
+		})
+	}
+	for _, follower := range d.users {
+		for _, i := range f.rnd.Perm(len(d.users)) {
+			if follower.following >= n.follows {
+				break
+			}
+			followee := d.users[i]
+			if followee == follower {
+				continue
+			}
+			d.follows = append(d.follows, follow{
+				followerID: follower.id,
+				followeeID: followee.id,
+				createdAt:  f.between(later(follower.createdAt, followee.createdAt), now),
+			})
+			follower.following++
+			followee.followers++
+		}
+	}
+
+	for _, author := range d.users {
+		for range n.posts {
+			p := &post{
+				id:          uuid.New(),
+				userID:      author.id,
+				description: f.post(),
+				visibility:  entity.PostVisibilityPublic,
+				createdAt:   f.between(author.createdAt, now),
+			}
+			if f.percent(10) {
+				p.visibility = entity.PostVisibilityFollowers
+			}
+			d.posts = append(d.posts, p)
+
+			var topLevel []*comment
+			for range n.comments {
+				c := &comment{id: uuid.New(), postID: p.id, userID: pick(f, d.users).id, content: f.comment()}
+				if len(topLevel) > 0 && f.percent(30) {
+					parent := pick(f, topLevel)
+					parent.replies++
+					c.replyTo = &parent.id
+					c.createdAt = f.between(parent.createdAt, now)
+				} else {
+					c.createdAt = f.between(p.createdAt, now)
+					topLevel = append(topLevel, c)
+				}
+				p.comments++
+				d.comments = append(d.comments, c)
+			}
+		}
+	}
+
+	// a direct chat is stored once per pair, with the smaller id as user1
+	pairs := make(map[[2]uuid.UUID]struct{})
+	for _, starter := range d.users {
+		for range n.chats {
+			other := pick(f, d.users)
+			if other == starter {
+				continue
+			}
+			user1, user2 := starter, other
+			if bytes.Compare(user1.id[:], user2.id[:]) > 0 {
+				user1, user2 = user2, user1
+			}
+			if _, ok := pairs[[2]uuid.UUID{user1.id, user2.id}]; ok {
+				continue
+			}
+			pairs[[2]uuid.UUID{user1.id, user2.id}] = struct{}{}
+
+			c := &chat{id: uuid.New(), user1ID: user1.id, user2ID: user2.id, createdAt: f.between(later(user1.createdAt, user2.createdAt), now)}
+			c.activityAt = c.createdAt
+			times := make([]time.Time, n.messages)
+			for i := range times {
+				times[i] = f.between(c.createdAt, now)
+			}
+			slices.SortFunc(times, time.Time.Compare)
+			var last uuid.UUID
+			for _, at := range times {
+				sender := user1.id
+				if f.percent(50) {
+					sender = user2.id
+				}
+				d.messages = append(d.messages, message{id: uuid.New(), chatID: c.id, senderID: sender, content: f.message(), createdAt: at})
+				c.activityAt, last = at, sender
+			}
+			// the last sender has read the chat, the other member did with a probability
+			c.read1At, c.read2At = c.activityAt, c.activityAt
+			if last != uuid.Nil && f.percent(40) {
+				if last == user1.id {
+					c.read2At = c.createdAt
+				} else {
+					c.read1At = c.createdAt
+				}
+			}
+			d.chats = append(d.chats, c)
+		}
+	}
+	return d
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// insert copies the dataset into the tables in one transaction.
+func (d *dataset) insert(ctx context.Context, pool *pgxpool.Pool) (err error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	copies := []struct {
+		table   string
+		columns []string
+		rows    [][]any
+	}{
+		{"users", []string{"id", "username", "email", "password_hash", "created_at"}, rows(d.users, func(u *user) []any {
+			return []any{u.id, u.username, u.username + "@example.com", d.hash, u.createdAt}
+		})},
+		{"user_roles", []string{"user_id", "role", "granted_at"}, rows(d.users, func(u *user) []any {
+			return []any{u.id, string(entity.RoleUser), u.createdAt}
+		})},
+		{"profiles", []string{"user_id", "name", "bio", "followers_count", "following_count", "updated_at"}, rows(d.users, func(u *user) []any {
+			return []any{u.id, u.name, u.bio, u.followers, u.following, u.createdAt}
+		})},
+		{"user_settings", []string{"user_id", "updated_at"}, rows(d.users, func(u *user) []any {
+			return []any{u.id, u.createdAt}
+		})},
+		{"follows", []string{"follower_id", "followee_id", "created_at"}, rows(d.follows, func(f follow) []any {
+			return []any{f.followerID, f.followeeID, f.createdAt}
+		})},
+		{"posts", []string{"id", "user_id", "description", "visibility", "comments_count", "created_at", "updated_at"}, rows(d.posts, func(p *post) []any {
+			return []any{p.id, p.userID, p.description, string(p.visibility), p.comments, p.createdAt, p.createdAt}
+		})},
+		// replies reference their parent, the parents come first in generation order
+		{"comments", []string{"id", "post_id", "user_id", "reply_to", "content", "replies_count", "created_at", "updated_at"}, rows(d.comments, func(c *comment) []any {
+			return []any{c.id, c.postID, c.userID, c.replyTo, c.content, c.replies, c.createdAt, c.createdAt}
+		})},
+		{"chats", []string{"id", "user1_id", "user2_id", "created_at", "last_activity_at"}, rows(d.chats, func(c *chat) []any {
+			return []any{c.id, c.user1ID, c.user2ID, c.createdAt, c.activityAt}
+		})},
+		{"chat_members", []string{"chat_id", "user_id", "joined_at", "last_read_at"}, slices.Concat(
+			rows(d.chats, func(c *chat) []any { return []any{c.id, c.user1ID, c.createdAt, c.read1At} }),
+			rows(d.chats, func(c *chat) []any { return []any{c.id, c.user2ID, c.createdAt, c.read2At} }),
+		)},
+		{"messages", []string{"id", "chat_id", "sender_id", "content", "created_at"}, rows(d.messages, func(m message) []any {
+			return []any{m.id, m.chatID, m.senderID, m.content, m.createdAt}
+		})},
+	}
+	for _, c := range copies {
+		if len(c.rows) == 0 {
+			continue
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{c.table}, c.columns, pgx.CopyFromRows(c.rows)); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", c.table, err)
+		}
+	}
+	if err := d.insertHashtags(ctx, tx); err != nil {
+		return fmt.Errorf("failed to insert hashtags: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// insertHashtags links the posts to the hashtags of their description like the post repository does.
+func (d *dataset) insertHashtags(ctx context.Context, tx pgx.Tx) error {
+	var postIDs []uuid.UUID
+	var tags []string
+	var createdAt []time.Time
+	for _, p := range d.posts {
+		for _, tag := range hashtag.Extract(p.description) {
+			postIDs = append(postIDs, p.id)
+			tags = append(tags, tag)
+			createdAt = append(createdAt, p.createdAt)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO hashtags (tag) SELECT DISTINCT unnest($1::text[]) ON CONFLICT (tag) DO NOTHING", tags)
+	if err != nil {
+		return err
+	}
+	sql := `INSERT INTO post_hashtags (hashtag_id, post_id, created_at)
+			SELECT h.id, p.post_id, p.created_at
+			FROM unnest($1::uuid[], $2::text[], $3::timestamptz[]) AS p(post_id, tag, created_at)
+			JOIN hashtags h ON h.tag = p.tag`
+	_, err = tx.Exec(ctx, sql, postIDs, tags, createdAt)
+	return err
+}
+
+// writeLogins writes the usernames to the file, replacing its content.
+func (d *dataset) writeLogins(path string) error {
+	var b strings.Builder
+	for _, u := range d.users {
+		b.WriteString(u.username)
+		b.WriteByte('\n')
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+func rows[T any](items []T, row func(T) []any) [][]any {
+	out := make([][]any, len(items))
+	for i, item := range items {
+		out[i] = row(item)
+	}
+	return out
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "seed:", err)
+	os.Exit(1)
+}
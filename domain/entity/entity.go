@@ -1,7 +1,10 @@
 package entity
 
 import (
+	"encoding/json"
 	"net/netip"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,16 +18,1059 @@ type User struct {
 	PasswordHash string    `json:"password"`
 	CreatedAt    time.Time `json:"created_at"`
 	IsBlocked    bool      `json:"is_blocked"`
+	// BlockedReason and BlockedUntil describe an admin block, a nil BlockedUntil means the block never expires
+	BlockedReason string     `json:"blocked_reason,omitempty"`
+	BlockedUntil  *time.Time `json:"blocked_until,omitempty"`
+	// DeletedAt is set when the user deleted the account, PII is erased after a grace period
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// UserCard is the short public view of a user shown in search results, Following and FollowsYou
+// describe the relation to the viewer and are false for anonymous viewers.
+type UserCard struct {
+	ID         uuid.UUID `json:"id"`
+	Username   string    `json:"username"`
+	Name       string    `json:"name,omitempty"`
+	AvatarURL  string    `json:"avatar_url,omitempty"`
+	Following  bool      `json:"following"`
+	FollowsYou bool      `json:"follows_you"`
+}
+
+// Profile is the public page of a user. For a private account the viewer doesn't follow only the username,
+// the name and the avatar are filled and Restricted is set.
+type Profile struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio,omitempty"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	Gender    Gender    `json:"gender,omitempty"`
+	// Age is nil when not specified
+	Age *int `json:"age,omitempty"`
+	// the counters are denormalized from follows
+	FollowersCount int       `json:"followers_count"`
+	FollowingCount int       `json:"following_count"`
+	IsPrivate      bool      `json:"is_private"`
+	Restricted     bool      `json:"restricted"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// Version changes with every edit of the profile, updates name the version they were made on
+	Version int64 `json:"version"`
+}
+
+// ProfileUpdate lists the profile fields to change, nil fields are left unchanged.
+// An empty string clears a text field and a zero Age clears the age.
+type ProfileUpdate struct {
+	// Version is the version of the profile the update was made on, it fails if the profile changed since
+	Version   int64
+	Name      *string
+	Bio       *string
+	AvatarURL *string
+	Gender    *Gender
+	Age       *int
+}
+
+// Gender is shown on a profile, empty when not specified.
+type Gender string
+
+const (
+	GenderMale   Gender = "male"
+	GenderFemale Gender = "female"
+	GenderOther  Gender = "other"
+)
+
+// Valid reports whether the gender is one of the known values or not specified.
+func (g Gender) Valid() bool {
+	switch g {
+	case "", GenderMale, GenderFemale, GenderOther:
+		return true
+	}
+	return false
+}
+
+// FollowEntry is an entry of a follower or following list, FollowedAt is when the follow started and orders the list.
+type FollowEntry struct {
+	User       UserCard  `json:"user"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowState is where following an account left the follower.
+type FollowState string
+
+const (
+	// FollowStateFollowing means the follower follows the account
+	FollowStateFollowing FollowState = "following"
+	// FollowStateRequested means the account is private and the follow waits for the owner's approval
+	FollowStateRequested FollowState = "requested"
+)
+
+// FollowRequest is a pending request to follow a private account, RequestedAt orders the owner's list.
+type FollowRequest struct {
+	User        UserCard  `json:"user"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// UserSettings are the account preferences of a user.
+type UserSettings struct {
+	UserID       uuid.UUID    `json:"user_id"`
+	PrivacyLevel PrivacyLevel `json:"privacy_level"`
+	// PrivateAccount limits all posts and the profile details to followers
+	PrivateAccount bool                 `json:"private_account"`
+	Notifications  NotificationSettings `json:"notifications"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	// Version changes with every change of the settings, updates name the version they were made on
+	Version int64 `json:"version"`
+}
+
+// NotificationSettings tell which events the user wants to be notified about.
+type NotificationSettings struct {
+	Likes    bool `json:"likes"`
+	Comments bool `json:"comments"`
+	Follows  bool `json:"follows"`
+	Mentions bool `json:"mentions"`
+	Messages bool `json:"messages"`
+	// EmailDigest is how often the user gets a summary of the activity they missed by email
+	EmailDigest DigestFrequency `json:"email_digest"`
+}
+
+// SettingsUpdate lists the settings to change, nil fields are left unchanged.
+type SettingsUpdate struct {
+	// Version is the version of the settings the update was made on, it fails if they changed since
+	Version        int64
+	PrivacyLevel   *PrivacyLevel
+	PrivateAccount *bool
+	NotifyLikes    *bool
+	NotifyComments *bool
+	NotifyFollows  *bool
+	NotifyMentions *bool
+	NotifyMessages *bool
+	EmailDigest    *DigestFrequency
+}
+
+// DigestFrequency tells how often a user gets the activity digest email
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+// Valid reports whether the frequency is one of the known ones.
+func (f DigestFrequency) Valid() bool {
+	return f == DigestOff || f == DigestDaily || f == DigestWeekly
+}
+
+// PrivacyLevel tells who sees the profile details (bio, gender, age) of an account and finds its posts
+// in explore, hashtag listings and search. The account itself always sees everything.
+type PrivacyLevel string
+
+const (
+	PrivacyEveryone  PrivacyLevel = "everyone"
+	PrivacyFollowers PrivacyLevel = "followers"
+	PrivacyNobody    PrivacyLevel = "nobody"
+)
+
+// Valid reports whether the level is one of the known values.
+func (l PrivacyLevel) Valid() bool {
+	switch l {
+	case PrivacyEveryone, PrivacyFollowers, PrivacyNobody:
+		return true
+	}
+	return false
+}
+
+// Blacklist records that a user blocked another one. The blocked user can't see the blocker's profile
+// or posts and can't follow, comment on or message them. Blocking ends follows in both directions.
+type Blacklist struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlockedEntry is an entry of the list of users the caller blocked.
+type BlockedEntry struct {
+	User      UserCard  `json:"user"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// CloseFriendEntry is an entry of a user's close friends list, who see the user's close friends posts.
+type CloseFriendEntry struct {
+	User    UserCard  `json:"user"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Role grants access to protected endpoints, every user has at least RoleUser.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Valid reports whether the role is one of the known roles.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
 }
 
 // Session represents a user session with relevant details for authentication and tracking.
 type Session struct {
-	ID           uuid.UUID  `json:"id"`
-	UserID       uuid.UUID  `json:"user_id"`
-	RefreshToken uuid.UUID  `json:"refresh_token"`
-	IsBlocked    bool       `json:"is_blocked"`
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"user_id"`
+	RefreshToken uuid.UUID `json:"refresh_token"`
+	IsBlocked    bool      `json:"is_blocked"`
+	// IsSuspicious marks sessions started from a device the user never signed in from before
+	IsSuspicious bool       `json:"is_suspicious"`
 	ClientIP     netip.Addr `json:"client_ip"`
 	CreatedAt    time.Time  `json:"created_at"`
 	ExpiresAt    time.Time  `json:"expires_at"`
 	UserAgent    string     `json:"user_agent"`
+	// DeviceName is set by the user, DeviceLabel is derived from the User-Agent
+	DeviceName  string `json:"device_name,omitempty"`
+	DeviceLabel string `json:"device_label"`
+	// CountryCode (ISO 3166-1 alpha-2) and City are resolved from ClientIP, empty when unknown
+	CountryCode string `json:"country_code,omitempty"`
+	City        string `json:"city,omitempty"`
+}
+
+// UserAccount is a user's account as admins see it.
+type UserAccount struct {
+	ID            uuid.UUID  `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	Roles         []Role     `json:"roles"`
+	IsBlocked     bool       `json:"is_blocked"`
+	BlockedReason string     `json:"blocked_reason,omitempty"`
+	BlockedUntil  *time.Time `json:"blocked_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Post is a piece of content published by a user.
+type Post struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Description string    `json:"description"`
+	// MediaURL points to the attached image or video, IsVideo and Duration (in seconds) describe a video
+	MediaURL string `json:"media_url,omitempty"`
+	IsVideo  bool   `json:"is_video"`
+	Duration int    `json:"duration,omitempty"`
+	// QuoteOfID is the post this one quotes, nil for regular posts
+	QuoteOfID  *uuid.UUID     `json:"quote_of_id,omitempty"`
+	Visibility PostVisibility `json:"visibility"`
+	// the counters are denormalized from likes, reposts, quoting posts and comments, a background job repairs drift
+	LikesCount    int       `json:"likes_count"`
+	RepostsCount  int       `json:"reposts_count"`
+	QuotesCount   int       `json:"quotes_count"`
+	CommentsCount int       `json:"comments_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// Version changes with every edit of the post, updates name the version they were made on
+	Version int64 `json:"version"`
+	// Hashtags are extracted from the description, lowercased and without the leading '#'
+	Hashtags []string `json:"hashtags,omitempty"`
+	// Limited posts were shadow-limited by the content policy, only their author sees them
+	Limited bool `json:"-"`
+	// Links are the URLs of the description, in the order they appear
+	Links []PostLink `json:"links,omitempty"`
+}
+
+// PostLink is a URL of a post's description, shortened to /l/<Code>, which redirects to URL and counts the click.
+type PostLink struct {
+	Code string `json:"code"`
+	// Text is the URL as written in the description, URL the canonical target
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// LinkClicks is a link of a post with how often it was followed, shown to the author.
+type LinkClicks struct {
+	PostLink
+	Clicks int64 `json:"clicks"`
+}
+
+// RankedPost is a post of a ranked listing: Score is the time decayed engagement in the explore ranking
+// and the relevance in search results.
+type RankedPost struct {
+	Post  Post    `json:"post"`
+	Score float64 `json:"score"`
+}
+
+// PostVisibility restricts who can see a post. Posts of private accounts are never shown beyond followers.
+type PostVisibility string
+
+const (
+	PostVisibilityPublic    PostVisibility = "public"
+	PostVisibilityFollowers PostVisibility = "followers"
+	// PostVisibilityCloseFriends posts are only visible to the author's close friends list
+	PostVisibilityCloseFriends PostVisibility = "close_friends"
+	// PostVisibilityPrivate posts are only visible to the author
+	PostVisibilityPrivate PostVisibility = "private"
+)
+
+// Valid reports whether the visibility is one of the known values.
+func (v PostVisibility) Valid() bool {
+	switch v {
+	case PostVisibilityPublic, PostVisibilityFollowers, PostVisibilityCloseFriends, PostVisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// Counter names a denormalized counter, such as the likes of a post or the followers of a user.
+type Counter string
+
+const (
+	CounterPostLikes     Counter = "post_likes"
+	CounterPostReposts   Counter = "post_reposts"
+	CounterPostComments  Counter = "post_comments"
+	CounterUserFollowers Counter = "user_followers"
+	CounterUserFollowing Counter = "user_following"
+	// CounterLinkClicks counts the clicks of a shortened link, nothing to recount it from, so it isn't reconciled
+	CounterLinkClicks Counter = "link_clicks"
+)
+
+// Counters are all the known counters.
+var Counters = []Counter{CounterPostLikes, CounterPostReposts, CounterPostComments, CounterUserFollowers, CounterUserFollowing, CounterLinkClicks}
+
+// TrendingHashtag is a hashtag with the number of recent posts using it.
+type TrendingHashtag struct {
+	Tag        string `json:"tag"`
+	PostsCount int    `json:"posts_count"`
+}
+
+// Reposted records that a user shared a post with their followers.
+type Reposted struct {
+	UserID    uuid.UUID `json:"user_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FeedItem is a timeline entry: a post published or reposted by a followed account.
+type FeedItem struct {
+	Post Post `json:"post"`
+	// RepostedBy is the account that reposted the post, nil if the entry is the post itself
+	RepostedBy *uuid.UUID `json:"reposted_by,omitempty"`
+	// At is when the post was published or reposted, the timeline is ordered by it
+	At time.Time `json:"at"`
+}
+
+// TimelineEntry is a post or a repost on a cached home timeline, a FeedItem without the post.
+type TimelineEntry struct {
+	PostID uuid.UUID
+	// RepostedBy is the account that reposted the post, nil if the entry is the post itself
+	RepostedBy *uuid.UUID
+	At         time.Time
+}
+
+// Comment is a reply to a post or, when ReplyTo is set, to another comment of the same post.
+type Comment struct {
+	ID      uuid.UUID  `json:"id"`
+	PostID  uuid.UUID  `json:"post_id"`
+	UserID  uuid.UUID  `json:"user_id"`
+	ReplyTo *uuid.UUID `json:"reply_to,omitempty"`
+	Content string     `json:"content"`
+	// RepliesCount counts direct replies, it ranks comments in the top order
+	RepliesCount int       `json:"replies_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// Limited comments were shadow-limited by the content policy, only their author sees them
+	Limited bool `json:"-"`
+}
+
+// CommentSort is the order comments of a post are listed in.
+type CommentSort string
+
+const (
+	CommentSortNewest CommentSort = "newest"
+	CommentSortOldest CommentSort = "oldest"
+	// CommentSortTop puts the most replied comments first, newest first among equals
+	CommentSortTop CommentSort = "top"
+)
+
+// Valid reports whether the order is one of the known orders.
+func (s CommentSort) Valid() bool {
+	switch s {
+	case CommentSortNewest, CommentSortOldest, CommentSortTop:
+		return true
+	}
+	return false
+}
+
+// CommentFilter selects a page of comments of a post, a zero ReplyTo lists top level comments.
+// Results continue after the (AfterReplies, AfterTime, AfterID) position, AfterReplies is only used by CommentSortTop.
+type CommentFilter struct {
+	// ViewerID sees their own limited comments, uuid.Nil for anonymous viewers
+	ViewerID     uuid.UUID
+	PostID       uuid.UUID
+	ReplyTo      uuid.UUID
+	Sort         CommentSort
+	AfterReplies int
+	AfterTime    time.Time
+	AfterID      uuid.UUID
+	Limit        int
+}
+
+// Chat is a direct conversation between two users or a group chat. For direct chats Peer is the other participant
+// from the viewer's side, Title, AvatarURL and Public only apply to groups.
+type Chat struct {
+	ID        uuid.UUID `json:"id"`
+	IsGroup   bool      `json:"is_group"`
+	Peer      *UserCard `json:"peer,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	Public    bool      `json:"public"`
+	// MembersCount is 2 for direct chats
+	MembersCount int `json:"members_count"`
+	// UnreadCount counts the messages of others the viewer hasn't read
+	UnreadCount int `json:"unread_count"`
+	// LastMessage is nil for chats without messages
+	LastMessage    *Message  `json:"last_message,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// GroupUpdate lists the group chat fields to change, nil fields are left unchanged.
+// An empty AvatarURL clears the avatar.
+type GroupUpdate struct {
+	Title     *string
+	AvatarURL *string
+	Public    *bool
+}
+
+// ChatRole is the role of a member within a chat, admins manage the group and its members.
+type ChatRole string
+
+const (
+	ChatRoleMember ChatRole = "member"
+	ChatRoleAdmin  ChatRole = "admin"
+)
+
+// Valid reports whether the role is one of the known roles.
+func (r ChatRole) Valid() bool {
+	switch r {
+	case ChatRoleMember, ChatRoleAdmin:
+		return true
+	}
+	return false
+}
+
+// ChatMember is a participant of a chat.
+type ChatMember struct {
+	ChatID   uuid.UUID `json:"chat_id"`
+	User     UserCard  `json:"user"`
+	Role     ChatRole  `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+	// LastReadMessageID is the last message the member read, nil if they haven't read any
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id,omitempty"`
+}
+
+// Message is a message sent to a chat.
+type Message struct {
+	ID        uuid.UUID `json:"id"`
+	ChatID    uuid.UUID `json:"chat_id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	// EditedAt is nil for messages that were never edited
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// Deleted messages were deleted for everyone by the sender and have an empty Content
+	Deleted bool `json:"deleted"`
+	// Attachment is nil for text messages, Content is the caption of media messages and may be empty
+	Attachment *MessageAttachment `json:"attachment,omitempty"`
+}
+
+// MessageAttachment is a media file sent in a message. Width and Height are set for images,
+// Duration (in seconds) for videos. ThumbnailURL points to a small JPEG preview of images.
+type MessageAttachment struct {
+	URL          string `json:"url"`
+	Type         string `json:"type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	Duration     int    `json:"duration,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// MessageMatch is a message found by a search. Highlight is the content with the matched terms wrapped in
+// <mark></mark>, the content itself isn't escaped.
+type MessageMatch struct {
+	Message   Message `json:"message"`
+	Highlight string  `json:"highlight"`
+}
+
+// ChatEventType tells what happened in a chat.
+type ChatEventType string
+
+const (
+	// ChatEventMessage is a new message, Message is set
+	ChatEventMessage ChatEventType = "message"
+	// ChatEventRead tells that UserID read the chat up to MessageID
+	ChatEventRead ChatEventType = "read"
+	// ChatEventTyping tells that UserID is typing in the chat
+	ChatEventTyping ChatEventType = "typing"
+	// ChatEventMessageEdited is an edited message, Message is set
+	ChatEventMessageEdited ChatEventType = "message_edited"
+	// ChatEventMessageDeleted tells that MessageID was deleted, for everyone or, when only UserID gets it, for them
+	ChatEventMessageDeleted ChatEventType = "message_deleted"
+	// ChatEventMessageRestored is a message its sender undeleted, Message is set
+	ChatEventMessageRestored ChatEventType = "message_restored"
+)
+
+// ChatEvent is pushed in real time to the members of a chat, UserID is the member who caused it.
+type ChatEvent struct {
+	Type       ChatEventType `json:"type"`
+	ChatID     uuid.UUID     `json:"chat_id"`
+	UserID     uuid.UUID     `json:"user_id"`
+	Message    *Message      `json:"message,omitempty"`
+	MessageID  *uuid.UUID    `json:"message_id,omitempty"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
+
+// DomainEventType tells what a domain event published to other services is about.
+type DomainEventType string
+
+const (
+	// DomainEventUserRegistered is a new account, keyed by the user
+	DomainEventUserRegistered DomainEventType = "user.registered"
+	// DomainEventPostCreated is a new post, keyed by the post
+	DomainEventPostCreated DomainEventType = "post.created"
+	// DomainEventUserFollowed is a new follow, keyed by the follower
+	DomainEventUserFollowed DomainEventType = "user.followed"
+	// DomainEventMessageSent is a new chat message, keyed by the chat
+	DomainEventMessageSent DomainEventType = "message.sent"
+)
+
+// DomainEvent is a state change published to Kafka. It is stored in the outbox in the transaction of the change,
+// so an event is published if and only if the change was committed. Events with the same Key are published in
+// the order they happened; Data is the JSON payload of the event type.
+type DomainEvent struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       DomainEventType `json:"type"`
+	Key        uuid.UUID       `json:"key"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// NotificationType tells what a notification is about.
+type NotificationType string
+
+const (
+	// NotificationFollow tells that the actor followed the user
+	NotificationFollow NotificationType = "follow"
+	// NotificationFollowRequest tells that the actor asked to follow the user
+	NotificationFollowRequest NotificationType = "follow_request"
+	// NotificationLike tells that the actor liked a post of the user
+	NotificationLike NotificationType = "like"
+	// NotificationComment tells that the actor commented on a post of the user or replied to a comment of theirs
+	NotificationComment NotificationType = "comment"
+	// NotificationMention tells that the actor mentioned the user in a post or a comment
+	NotificationMention NotificationType = "mention"
+	// NotificationMessage tells that the actor sent a message to a chat of the user
+	NotificationMessage NotificationType = "message"
+)
+
+// NotificationEvent is something a user did that other users may be notified about. The subject fields
+// set depend on the type, the workers work out who is notified from them.
+type NotificationEvent struct {
+	// ID is set for events read back from the queue
+	ID        int64            `json:"-"`
+	Type      NotificationType `json:"type"`
+	ActorID   uuid.UUID        `json:"actor_id"`
+	UserID    *uuid.UUID       `json:"user_id,omitempty"`
+	PostID    *uuid.UUID       `json:"post_id,omitempty"`
+	CommentID *uuid.UUID       `json:"comment_id,omitempty"`
+	ChatID    *uuid.UUID       `json:"chat_id,omitempty"`
+	// Mentions are the lowercased usernames mentioned by a mention event
+	Mentions []string `json:"mentions,omitempty"`
+	// Attempts counts how many times the workers took the event
+	Attempts int `json:"-"`
+}
+
+// Notification tells a user what another user, the actor, did. The subject fields set depend on the type.
+type Notification struct {
+	ID        uuid.UUID        `json:"id"`
+	Type      NotificationType `json:"type"`
+	Actor     UserCard         `json:"actor"`
+	PostID    *uuid.UUID       `json:"post_id,omitempty"`
+	CommentID *uuid.UUID       `json:"comment_id,omitempty"`
+	ChatID    *uuid.UUID       `json:"chat_id,omitempty"`
+	Read      bool             `json:"read"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// PushPlatform is the push service a device is reached through.
+type PushPlatform string
+
+const (
+	// PushFCM reaches Android (and iOS) apps through Firebase Cloud Messaging
+	PushFCM PushPlatform = "fcm"
+	// PushAPNs reaches iOS apps through the Apple Push Notification service
+	PushAPNs PushPlatform = "apns"
+	// PushWebPush reaches browsers through the push service of their subscription
+	PushWebPush PushPlatform = "webpush"
+)
+
+// Valid reports whether the platform is one of the supported push services.
+func (p PushPlatform) Valid() bool {
+	return p == PushFCM || p == PushAPNs || p == PushWebPush
+}
+
+// PushDevice is a device of a user notifications are pushed to. Token is the registration token, or the
+// push service endpoint for WebPush, whose subscriptions also carry the P256dh and Auth keys.
+type PushDevice struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"-"`
+	Platform  PushPlatform `json:"platform"`
+	Token     string       `json:"token"`
+	P256dh    string       `json:"p256dh,omitempty"`
+	Auth      string       `json:"-"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Digest is the activity a user missed since Since, sent by email at the frequency the user chose.
+type Digest struct {
+	UserID    uuid.UUID
+	Email     string
+	Username  string
+	Frequency DigestFrequency
+	Since     time.Time
+	// NewFollowers lists the latest followers, NewFollowersCount counts all of them
+	NewFollowers      []UserCard
+	NewFollowersCount int
+	// TopPosts are the most liked posts of the accounts the user follows
+	TopPosts []DigestPost
+	// UnreadNotifications counts all unread notifications, not only those since Since
+	UnreadNotifications int
+}
+
+// Empty reports whether there is nothing worth sending.
+func (d Digest) Empty() bool {
+	return d.NewFollowersCount == 0 && len(d.TopPosts) == 0 && d.UnreadNotifications == 0
+}
+
+// DigestPost is a post as shown in a digest email.
+type DigestPost struct {
+	ID             uuid.UUID
+	AuthorUsername string
+	Description    string
+	LikesCount     int
+	CommentsCount  int
+}
+
+// Features rolled out gradually with feature flags, the flags are named after them.
+const (
+	// FeatureLoginConfirmation requires confirming logins from new devices by email, as
+	// login_security.require_confirmation does for everyone
+	FeatureLoginConfirmation = "login_confirmation"
+	// FeatureDataExport lets users request an export of their data
+	FeatureDataExport = "data_export"
+	// FeatureFeedRanking ranks home feeds instead of listing them newest first, for comparing the ranking with
+	// the chronological feed
+	FeatureFeedRanking = "feed_ranking"
+	// FeatureDataImport lets users import their follows and posts from an export of another platform
+	FeatureDataImport = "data_import"
+)
+
+// DataExportStatus is the state of a data export: pending until a job prepares it, then ready or failed.
+type DataExportStatus string
+
+const (
+	DataExportPending    DataExportStatus = "pending"
+	DataExportProcessing DataExportStatus = "processing"
+	DataExportReady      DataExportStatus = "ready"
+	DataExportFailed     DataExportStatus = "failed"
+)
+
+// DataExport is an archive of a user's data prepared for download on their request.
+type DataExport struct {
+	ID     uuid.UUID        `json:"id"`
+	UserID uuid.UUID        `json:"-"`
+	Status DataExportStatus `json:"status"`
+	// ObjectKey is where the archive is stored, set for ready exports
+	ObjectKey string `json:"-"`
+	Size      int64  `json:"size,omitempty"`
+	// DownloadURL is a signed link to the archive of a ready export, it expires before ExpiresAt
+	DownloadURL string     `json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt is when the archive of a ready export is deleted
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// UserData is everything about a user that goes into their data export.
+type UserData struct {
+	Account  UserAccount
+	Profile  Profile
+	Posts    []Post
+	Comments []Comment
+	// Messages are the messages the user sent
+	Messages []Message
+	Sessions []Session
+}
+
+// DataImportSource is the platform an imported archive was exported from.
+type DataImportSource string
+
+const (
+	// DataImportTwitter is a Twitter/X archive, a ZIP with data/tweets.js and data/following.js
+	DataImportTwitter DataImportSource = "twitter"
+	// DataImportThreads is a Threads export from Meta's Download Your Information, a ZIP of JSON files
+	DataImportThreads DataImportSource = "threads"
+	// DataImportJSON is the platform neutral format, a JSON document with following and posts arrays
+	DataImportJSON DataImportSource = "json"
+)
+
+// DataImportStatus is the state of a data import: pending until a job picks it up, then processing until
+// all of its entries are applied.
+type DataImportStatus string
+
+const (
+	DataImportPending    DataImportStatus = "pending"
+	DataImportProcessing DataImportStatus = "processing"
+	DataImportCompleted  DataImportStatus = "completed"
+	DataImportFailed     DataImportStatus = "failed"
+)
+
+// DataImport is an archive from another platform whose follows and posts are being applied to a user's account.
+type DataImport struct {
+	ID      uuid.UUID        `json:"id"`
+	UserID  uuid.UUID        `json:"-"`
+	Source  DataImportSource `json:"source"`
+	Status  DataImportStatus `json:"status"`
+	Follows ImportProgress   `json:"follows"`
+	Posts   ImportProgress   `json:"posts"`
+	// Error is why a failed import stopped, the entries processed before stay imported
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ImportProgress counts the entries of one kind in an import: Processed of Total were looked at so far,
+// Imported of them were applied and the others skipped, like follows of accounts that aren't on Threads.
+type ImportProgress struct {
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Imported  int `json:"imported"`
+}
+
+// ImportFollow is an account followed on the other platform, matched to a user by username or email.
+type ImportFollow struct {
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// ImportPost is a post published on the other platform, imported with its original time.
+type ImportPost struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImportArchive is the content of an uploaded archive that is imported.
+type ImportArchive struct {
+	Source  DataImportSource `json:"source"`
+	Follows []ImportFollow   `json:"follows"`
+	Posts   []ImportPost     `json:"posts"`
+}
+
+// RemoteActor is an account on another server of the fediverse, as its actor document described it when it was
+// last fetched.
+type RemoteActor struct {
+	// ID is the URL of the actor document
+	ID       string
+	Username string
+	Inbox    string
+	// SharedInbox receives the activities for all the actors of the server, empty if it has none
+	SharedInbox  string
+	KeyID        string
+	PublicKeyPEM string
+	FetchedAt    time.Time
+}
+
+// FederationDelivery is an activity of a user waiting to be delivered to the inbox of a remote server.
+type FederationDelivery struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Inbox    string
+	Activity []byte
+	// Attempts counts the failed deliveries so far
+	Attempts int
+}
+
+// PostPreview is what a shared link of a public post unfurls to, the Open Graph and Twitter Card metadata.
+type PostPreview struct {
+	// URL is the canonical URL of the post in the web app
+	URL         string
+	SiteName    string
+	Title       string
+	Description string
+	// ImageURL and VideoURL are the attached media, AvatarURL the avatar of the author
+	ImageURL    string
+	VideoURL    string
+	AvatarURL   string
+	Author      string
+	PublishedAt time.Time
+}
+
+// Sitemap is a generated sitemap file, the sitemap index lists them by Index.
+type Sitemap struct {
+	Index       int
+	Content     []byte
+	URLCount    int
+	GeneratedAt time.Time
+}
+
+// ContentKind is the type of user content the content policy checks.
+type ContentKind string
+
+const (
+	ContentPost    ContentKind = "post"
+	ContentComment ContentKind = "comment"
+	ContentMessage ContentKind = "message"
+)
+
+// Valid reports whether the kind is one of the known ones.
+func (k ContentKind) Valid() bool {
+	return k == ContentPost || k == ContentComment || k == ContentMessage
+}
+
+// ModerationVerdict is what the content policy decided about published content, empty if it had no objection.
+type ModerationVerdict string
+
+const (
+	// ModerationFlag content is published and waits for review
+	ModerationFlag ModerationVerdict = "flag"
+	// ModerationLimit content is shown to its author only and waits for review
+	ModerationLimit ModerationVerdict = "limit"
+)
+
+// ContentFlag queues content the content policy objected to for review.
+type ContentFlag struct {
+	ID         uuid.UUID         `json:"id"`
+	Kind       ContentKind       `json:"content_type"`
+	ContentID  uuid.UUID         `json:"content_id"`
+	AuthorID   uuid.UUID         `json:"author_id"`
+	Verdict    ModerationVerdict `json:"verdict"`
+	Reason     string            `json:"reason"`
+	CreatedAt  time.Time         `json:"created_at"`
+	ResolvedAt *time.Time        `json:"resolved_at,omitempty"`
+	ResolvedBy *uuid.UUID        `json:"resolved_by,omitempty"`
+	Resolution ReportResolution  `json:"resolution,omitempty"`
+}
+
+// ReportResolution is how a reviewer resolved a content flag.
+type ReportResolution string
+
+const (
+	// ReportDismissed content stays published, a shadow limit is lifted
+	ReportDismissed ReportResolution = "dismissed"
+	// ReportRemoved content was taken down
+	ReportRemoved ReportResolution = "removed"
+)
+
+// Valid reports whether the resolution is one of the known ones.
+func (r ReportResolution) Valid() bool {
+	return r == ReportDismissed || r == ReportRemoved
+}
+
+// Limited reports whether the content must only be shown to its author.
+func (f ContentFlag) Limited() bool {
+	return f.Verdict == ModerationLimit
+}
+
+// Liked records that a user likes a post.
+type Liked struct {
+	UserID    uuid.UUID `json:"user_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SessionEventType tells whether a session was started or ended.
+type SessionEventType string
+
+const (
+	SessionEventCreated SessionEventType = "created"
+	SessionEventRevoked SessionEventType = "revoked"
+)
+
+// SessionEvent notifies the clients of a user that one of their sessions was started or ended.
+type SessionEvent struct {
+	Type   SessionEventType `json:"type"`
+	UserID uuid.UUID        `json:"user_id"`
+	// SessionID is uuid.Nil when all sessions of the user were revoked at once
+	SessionID uuid.UUID `json:"session_id"`
+	// DeviceLabel, ClientIP and CountryCode describe where a created session signed in from
+	DeviceLabel string    `json:"device_label,omitempty"`
+	ClientIP    string    `json:"client_ip,omitempty"`
+	CountryCode string    `json:"country_code,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// AuditEventType names a security relevant action recorded in the audit log.
+type AuditEventType string
+
+const (
+	AuditLogin          AuditEventType = "login"
+	AuditLoginFailed    AuditEventType = "login_failed"
+	AuditLoginNewDevice AuditEventType = "login_new_device"
+	AuditLogout         AuditEventType = "logout"
+	AuditLogoutAll      AuditEventType = "logout_all"
+	AuditRefresh        AuditEventType = "refresh"
+	AuditPasswordChange AuditEventType = "password_change"
+	AuditEmailChange    AuditEventType = "email_change"
+	AuditAccountDelete  AuditEventType = "account_delete"
+	AuditAccountRestore AuditEventType = "account_restore"
+	AuditUserBlock      AuditEventType = "user_block"
+	AuditUserUnblock    AuditEventType = "user_unblock"
+	AuditRoleGrant      AuditEventType = "role_grant"
+	AuditRoleRevoke     AuditEventType = "role_revoke"
+	AuditAPIKeyIssue    AuditEventType = "api_key_issue"
+	AuditAPIKeyRevoke   AuditEventType = "api_key_revoke"
+	// AuditAppCreate, AuditAppDelete and AuditAppSecretRotate are developer apps managed by their owner
+	AuditAppCreate       AuditEventType = "app_create"
+	AuditAppDelete       AuditEventType = "app_delete"
+	AuditAppSecretRotate AuditEventType = "app_secret_rotate"
+	// AuditSessionRevoke and AuditSessionRevokeAll are sessions of a user ended by an admin
+	AuditSessionRevoke    AuditEventType = "session_revoke"
+	AuditSessionRevokeAll AuditEventType = "session_revoke_all"
+	AuditContentTakedown  AuditEventType = "content_takedown"
+	AuditReportDismiss    AuditEventType = "report_dismiss"
+	AuditUserCreate       AuditEventType = "user_create"
+	AuditKeyRotate        AuditEventType = "signing_key_rotate"
+	AuditBackfill         AuditEventType = "backfill"
+)
+
+// AuditEvent is an append-only record of a security event.
+// ActorID is who performed the action, SubjectID whose account it affected.
+type AuditEvent struct {
+	ID        int64          `json:"id"`
+	Type      AuditEventType `json:"type"`
+	ActorID   uuid.UUID      `json:"actor_id"`
+	SubjectID uuid.UUID      `json:"subject_id"`
+	ClientIP  string         `json:"client_ip"`
+	UserAgent string         `json:"user_agent"`
+	Metadata  map[string]any `json:"metadata"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// AuditFilter narrows down audit log queries, zero values are ignored.
+// Results are ordered from newest to oldest and paginated with BeforeID.
+type AuditFilter struct {
+	Type      AuditEventType
+	ActorID   uuid.UUID
+	SubjectID uuid.UUID
+	From      time.Time
+	To        time.Time
+	BeforeID  int64
+	Limit     int
+}
+
+// Token types reported by introspection, named after the RFC 7662 token_type_hint values.
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// TokenIntrospection describes a token for resource servers (RFC 7662).
+// Only Active is meaningful for inactive tokens, the other fields are left empty.
+type TokenIntrospection struct {
+	Active    bool
+	TokenType string
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// APIKey authenticates another internal service instead of a user JWT.
+// Only a hash of the key is stored, the plain key is shown once when issued.
+type APIKey struct {
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+	Prefix string    `json:"prefix"`
+	// Scopes list the gRPC methods the key may call, as "package.Service/Method" or "package.Service/*"
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  uuid.UUID  `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Active reports whether the key is neither revoked nor expired.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// SigningKey is an access token signing key shared by every instance. Tokens are signed with the newest key that
+// is active, a key is stored ahead of ActiveAt so every instance accepts its tokens before any issues them.
+type SigningKey struct {
+	ID string
+	// EncryptedKey is the PEM encoded PKCS#8 private key sealed with the key encryption key, the nonce first
+	EncryptedKey []byte
+	CreatedAt    time.Time
+	ActiveAt     time.Time
+}
+
+// Allows reports whether the key's scopes cover the gRPC method, given as "/package.Service/Method".
+func (k APIKey) Allows(fullMethod string) bool {
+	method := strings.TrimPrefix(fullMethod, "/")
+	service, _, _ := strings.Cut(method, "/")
+	for _, scope := range k.Scopes {
+		if scope == method || scope == service+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes of developer apps, each lets an app read a kind of public data over the public API.
+const (
+	AppScopePostsRead = "posts:read"
+	AppScopeUsersRead = "users:read"
+)
+
+// AppScopes are the scopes developer apps may be granted.
+var AppScopes = []string{AppScopePostsRead, AppScopeUsersRead}
+
+// DeveloperApp is a third-party integration registered by a user. It exchanges its client ID and secret for
+// access tokens (the OAuth2 client credentials grant) and acts for no user, so it only sees public data.
+// Only a hash of the secret is stored, the plain secret is shown once when it is issued.
+type DeveloperApp struct {
+	ID          uuid.UUID `json:"id"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	ClientID    string    `json:"client_id"`
+	Scopes      []string  `json:"scopes"`
+	// RateLimit is how many requests per minute the app may make, 0 for the default of the service
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// AppToken is the verified content of an access token issued to a developer app.
+type AppToken struct {
+	AppID     uuid.UUID `json:"app_id"`
+	Scopes    []string  `json:"scopes"`
+	RateLimit int       `json:"rate_limit"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Allows reports whether the token was granted the scope.
+func (t AppToken) Allows(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// AppUsage counts the requests of a developer app on a day (UTC): Requests were served, Throttled were rejected by
+// its rate limit.
+type AppUsage struct {
+	Day       string `json:"day"`
+	Requests  int64  `json:"requests"`
+	Throttled int64  `json:"throttled"`
 }
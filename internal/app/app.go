@@ -0,0 +1,685 @@
+// Package app wires the whole service together, so it can run as its own binary, be embedded
+// into another one or be started by tests.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/internal/delivery/debug"
+	"main/internal/delivery/gateway"
+	"main/internal/delivery/grpc/interceptor"
+	routes "main/internal/delivery/http"
+	httpHealthHandler "main/internal/delivery/http/health_handler"
+	httpJWKSHandler "main/internal/delivery/http/jwks_handler"
+	httpSwaggerHandler "main/internal/delivery/http/swagger_handler"
+	"main/internal/delivery/openapi"
+	"main/internal/health"
+	"main/internal/jobs"
+	"main/internal/metrics"
+	psql "main/internal/storage/postgres"
+	"main/internal/storage/postgres/migrate"
+	scheduleRepo "main/internal/storage/postgres/schedule"
+	"main/internal/storage/redis/revocation"
+	"main/migrations"
+	errHandler "main/pkg/error_handler"
+	"main/pkg/errreport"
+	"main/pkg/i18n"
+	"main/pkg/password"
+	adminpb "main/pkg/proto/gen/admin/v1"
+	"main/pkg/ratelimit"
+	"main/pkg/tlsreload"
+	"main/pkg/txmanager"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	// registers the gzip compressor, requests may be sent gzipped and are answered the same way
+	_ "google.golang.org/grpc/encoding/gzip"
+	grpcHealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// App is the whole service: its servers, background jobs and the connections they share.
+type App struct {
+	cfg      config.Config
+	logger   *slog.Logger
+	reporter errreport.Reporter
+
+	echo             *echo.Echo
+	httpServer       *http.Server
+	httpTLS          *tlsreload.Reloader
+	debugServer      *http.Server
+	grpcServer       *grpc.Server
+	grpcAddr         string
+	grpcTLS          *tlsreload.Reloader
+	grpcHealthServer *grpcHealth.Server
+	loopback         *gateway.Loopback
+
+	// jobs run next to the servers until Run returns
+	jobs  []func(ctx context.Context) error
+	hooks []Hook
+	// closers release what New opened, in reverse order
+	closers   []func()
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// New connects to the databases and wires every component, nothing is served until Run.
+// The returned App must be stopped with Shutdown, or by cancelling the context of Run.
+func New(cfg config.Config, opts ...Option) (_ *App, err error) {
+	o := options{
+		logger:     slog.New(slog.DiscardHandler),
+		registerer: prometheus.DefaultRegisterer,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	logger := o.logger
+
+	a := &App{cfg: cfg, logger: logger, reporter: errreport.Nop{}}
+	defer func() {
+		if err != nil {
+			a.close()
+		}
+	}()
+
+	// internal errors and panics go to the error aggregator, when one is configured
+	if cfg.ErrorReportingConfig.DSN != "" {
+		sentry, err := errreport.NewSentry(cfg.ErrorReportingConfig.DSN, cfg.Env, cfg.ErrorReportingConfig.Release, cfg.ErrorReportingConfig.Timeout, cfg.ErrorReportingConfig.QueueSize, logger)
+		if err != nil {
+			return nil, fmt.Errorf("set up error reporting: %w", err)
+		}
+		a.reporter = sentry
+		a.jobs = append(a.jobs, sentry.Run)
+	}
+	reporter := a.reporter
+
+	//database connection setup
+	dsn := cfg.PostgresConfig.DSN()
+	poolOptions := psql.Options{
+		QueryTimeout:           cfg.PostgresConfig.QueryTimeout,
+		QueryExecMode:          cfg.PostgresConfig.QueryExecMode,
+		StatementCacheCapacity: cfg.PostgresConfig.StatementCacheCapacity,
+		MaxConns:               cfg.PostgresConfig.MaxConns,
+		MinConns:               cfg.PostgresConfig.MinConns,
+		MaxConnLifetime:        cfg.PostgresConfig.MaxConnLifetime,
+		MaxConnIdleTime:        cfg.PostgresConfig.MaxConnIdleTime,
+		HealthCheckPeriod:      cfg.PostgresConfig.HealthCheckPeriod,
+	}
+	pool, err := psql.NewPostgresConnection(dsn, poolOptions)
+	if err != nil {
+		return nil, fmt.Errorf("connect to the database: %w", err)
+	}
+	a.closers = append(a.closers, pool.Close)
+	logger.Info("Connected to the database successfully")
+
+	replicaPools := make([]*pgxpool.Pool, 0, len(cfg.PostgresConfig.ReplicaURLs))
+	for i, replicaURL := range cfg.PostgresConfig.ReplicaURLs {
+		replicaPool, err := psql.NewPostgresConnection(replicaURL, poolOptions)
+		if err != nil {
+			return nil, fmt.Errorf("connect to read replica %d: %w", i, err)
+		}
+		a.closers = append(a.closers, replicaPool.Close)
+		replicaPools = append(replicaPools, replicaPool)
+	}
+	if len(replicaPools) > 0 {
+		logger.Info("Connected to the read replicas successfully", "count", len(replicaPools))
+	}
+	pools := map[string]*pgxpool.Pool{"primary": pool}
+	for i, replicaPool := range replicaPools {
+		pools["replica_"+strconv.Itoa(i)] = replicaPool
+	}
+
+	//prometheus metrics setup, the default registry is the one served at /metrics
+	if err := o.registerer.Register(metrics.NewPoolCollector(pools)); err != nil {
+		return nil, fmt.Errorf("register pool metrics: %w", err)
+	}
+	metrics := metrics.NewMetrics(o.registerer)
+
+	// repositories retry transient errors and fail fast while a database is unhealthy
+	retryPolicy := psql.RetryPolicy{
+		Attempts:   cfg.PostgresConfig.RetryAttempts,
+		Backoff:    cfg.PostgresConfig.RetryBackoff,
+		MaxBackoff: cfg.PostgresConfig.RetryMaxBackoff,
+	}
+	breakerOptions := psql.BreakerOptions{
+		Threshold:   cfg.PostgresConfig.BreakerThreshold,
+		OpenTimeout: cfg.PostgresConfig.BreakerOpenTimeout,
+	}
+	db := psql.NewDB("primary", pool, retryPolicy, breakerOptions, metrics)
+	replicaDBs := make([]*psql.DB, len(replicaPools))
+	for i, replicaPool := range replicaPools {
+		replicaDBs[i] = psql.NewDB("replica_"+strconv.Itoa(i), replicaPool, retryPolicy, breakerOptions, metrics)
+	}
+	replicas := psql.NewReplicas(db, replicaDBs)
+
+	//Redis client setup
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisConfig.Addr,
+		Password: cfg.RedisConfig.Password,
+		DB:       cfg.RedisConfig.DB,
+	})
+	a.closers = append(a.closers, func() { redisClient.Close() })
+
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("connect to Redis: %w", err)
+	}
+	logger.Info("Connected to Redis successfully")
+	// per route and per method limits, shared by all instances
+	rateLimiter := ratelimit.NewLimiter(redisClient)
+
+	//  Init Core Logic
+	jwtManager, err := setupJWTManager(cfg.JWTConfig)
+	if err != nil {
+		return nil, fmt.Errorf("set up JWT manager: %w", err)
+	}
+	keyCipher, err := setupKeyCipher(cfg.JWTConfig)
+	if err != nil {
+		return nil, fmt.Errorf("set up JWT key encryption: %w", err)
+	}
+	hasher, err := password.NewHasher(cfg.PasswordConfig.Algorithm, cfg.PasswordConfig.BcryptCost, password.Argon2Params{
+		Memory:      cfg.PasswordConfig.Argon2Memory,
+		Iterations:  cfg.PasswordConfig.Argon2Iterations,
+		Parallelism: cfg.PasswordConfig.Argon2Parallelism,
+		SaltLength:  cfg.PasswordConfig.Argon2SaltLength,
+		KeyLength:   cfg.PasswordConfig.Argon2KeyLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set up password hasher: %w", err)
+	}
+	emailValidator, err := setupEmailValidator(cfg.RegistrationConfig)
+	if err != nil {
+		return nil, fmt.Errorf("load disposable email domains: %w", err)
+	}
+	geoResolver, err := setupGeoResolver(cfg.GeoIPConfig)
+	if err != nil {
+		return nil, fmt.Errorf("open GeoIP database: %w", err)
+	}
+	captchaVerifier, err := setupCaptchaVerifier(cfg.CaptchaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("set up CAPTCHA verifier: %w", err)
+	}
+	mediaStore := o.mediaStore
+	if mediaStore == nil {
+		mediaStore, err = setupMediaStore(cfg.MediaConfig)
+		if err != nil {
+			return nil, fmt.Errorf("set up media storage: %w", err)
+		}
+	}
+	exportStore, err := setupExportStore(cfg.ExportConfig, cfg.MediaConfig, logger)
+	if err != nil {
+		return nil, fmt.Errorf("set up export storage: %w", err)
+	}
+	pushProviders, err := setupPushProviders(cfg.PushConfig)
+	if err != nil {
+		return nil, fmt.Errorf("set up push notifications: %w", err)
+	}
+	contentPolicy, err := setupContentPolicy(cfg.ModerationConfig)
+	if err != nil {
+		return nil, fmt.Errorf("set up content policy: %w", err)
+	}
+	flags, err := setupFeatureFlags(cfg.FeatureFlagsConfig, redisClient)
+	if err != nil {
+		return nil, fmt.Errorf("set up feature flags: %w", err)
+	}
+	mailer := o.mailer
+	if mailer == nil {
+		mailer = setupMailer(cfg.EmailConfig, logger)
+	}
+	c := &Container{
+		Config:          cfg,
+		Logger:          logger,
+		Reporter:        reporter,
+		Metrics:         metrics,
+		Pool:            pool,
+		DB:              db,
+		Replicas:        replicas,
+		Redis:           redisClient,
+		TxManager:       txmanager.New(db),
+		JWT:             jwtManager,
+		KeyCipher:       keyCipher,
+		Denylist:        revocation.NewDenylist(redisClient, cfg.JWTConfig.AccessTokenTTL),
+		RateLimiter:     rateLimiter,
+		Flags:           flags,
+		Mailer:          mailer,
+		MediaStore:      mediaStore,
+		ExportStore:     exportStore,
+		opts:            o,
+		hasher:          hasher,
+		emailValidator:  emailValidator,
+		geoResolver:     geoResolver,
+		captchaVerifier: captchaVerifier,
+		pushProviders:   pushProviders,
+		contentPolicy:   contentPolicy,
+	}
+
+	// every feature registers what it serves and runs, the core schema is migrated before the tables of the modules
+	reg := &Registry{}
+	reg.Migrations(migrations.FS)
+	if err := reg.register(c, append(defaultModules(), o.modules...)); err != nil {
+		return nil, err
+	}
+	a.hooks = reg.hooks
+
+	//schema version check, the migrations are embedded into the binary
+	migrator, err := migrate.New(pool, reg.migrations...)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	if o.migrate {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("migrate the database: %w", err)
+		}
+		logger.Info("Database migrated", "applied", len(applied), "version", migrator.Latest())
+	} else if err := migrator.Verify(context.Background()); err != nil {
+		return nil, fmt.Errorf("database schema doesn't match the binary, run with --migrate or use cmd/migrate: %w", err)
+	}
+
+	// readiness: the instance can serve requests once its dependencies are reachable and the schema is migrated
+	healthChecker := health.NewChecker(cfg.HealthConfig.CheckTimeout)
+	healthChecker.Add("postgres", pool.Ping)
+	healthChecker.Add("redis", func(ctx context.Context) error { return redisClient.Ping(ctx).Err() })
+	healthChecker.Add("migrations", migrator.Verify)
+
+	translator, err := i18n.New()
+	if err != nil {
+		return nil, fmt.Errorf("load message catalogs: %w", err)
+	}
+
+	//  HTTP Server Setup (Echo)
+	e := echo.New()
+	e.HTTPErrorHandler = errHandler.New(translator, reporter).HandleError
+	e.Validator = routes.Validator{}
+	e.Binder = &routes.ValidatingBinder{}
+	routes.MapRoutes(e, httpJWKSHandler.NewJWKSHandler(jwtManager), httpHealthHandler.NewHealthHandler(healthChecker), c.Auth(), logger, reporter, rateLimiter, rateLimitRules(cfg.RateLimiterConfig.Routes),
+		cfg.Server.MaxBodySize, bodyLimits(cfg), cfg.Server.Compression, metrics)
+	api := routes.NewAPI(e, apiOptions(cfg.APIConfig))
+	for _, mapRoutes := range reg.routes {
+		mapRoutes(e, api)
+	}
+	a.echo = e
+
+	// http.Server configuration with timeouts for better resource management and security
+	a.httpServer = &http.Server{
+		Addr:         net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)),
+		Handler:      e,
+		ReadTimeout:  cfg.Server.Timeout,
+		WriteTimeout: cfg.Server.Timeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	if cfg.Server.TLS.Enabled() {
+		a.httpTLS, err = tlsreload.New(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, cfg.Server.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("load the HTTP TLS certificate: %w", err)
+		}
+		a.httpServer.TLSConfig = a.httpTLS.TLSConfig()
+	}
+
+	// runtime diagnostics on a separate listener, never on the public one
+	if cfg.DebugServer.Enabled {
+		if cfg.DebugServer.Token == "" {
+			logger.Warn("Debug endpoints are served without authentication, keep debug.host private")
+		}
+		a.debugServer = &http.Server{
+			Addr:              net.JoinHostPort(cfg.DebugServer.Host, strconv.Itoa(cfg.DebugServer.Port)),
+			Handler:           debug.NewHandler(cfg.DebugServer.Token),
+			ReadHeaderTimeout: cfg.Server.Timeout,
+			// no write timeout, CPU profiles and traces take as long as the client asks for
+		}
+	}
+
+	// roles required per gRPC method, methods not listed are available to every authenticated user
+	methodRoles := map[string][]string{
+		adminpb.AdminService_BlockUser_FullMethodName:         {string(entity.RoleModerator), string(entity.RoleAdmin)},
+		adminpb.AdminService_UnblockUser_FullMethodName:       {string(entity.RoleModerator), string(entity.RoleAdmin)},
+		adminpb.AdminService_GrantRole_FullMethodName:         {string(entity.RoleAdmin)},
+		adminpb.AdminService_RevokeRole_FullMethodName:        {string(entity.RoleAdmin)},
+		adminpb.AdminService_ListAuditEvents_FullMethodName:   {string(entity.RoleAdmin)},
+		adminpb.AdminService_IssueAPIKey_FullMethodName:       {string(entity.RoleAdmin)},
+		adminpb.AdminService_ListAPIKeys_FullMethodName:       {string(entity.RoleAdmin)},
+		adminpb.AdminService_RevokeAPIKey_FullMethodName:      {string(entity.RoleAdmin)},
+		adminpb.AdminService_LookupUser_FullMethodName:        {string(entity.RoleModerator), string(entity.RoleAdmin)},
+		adminpb.AdminService_ListUserSessions_FullMethodName:  {string(entity.RoleAdmin)},
+		adminpb.AdminService_RevokeSession_FullMethodName:     {string(entity.RoleAdmin)},
+		adminpb.AdminService_RevokeAllSessions_FullMethodName: {string(entity.RoleAdmin)},
+		adminpb.AdminService_TakeDownContent_FullMethodName:   {string(entity.RoleModerator), string(entity.RoleAdmin)},
+		adminpb.AdminService_ListReports_FullMethodName:       {string(entity.RoleModerator), string(entity.RoleAdmin)},
+		adminpb.AdminService_ResolveReport_FullMethodName:     {string(entity.RoleModerator), string(entity.RoleAdmin)},
+		adminpb.AdminService_CreateUser_FullMethodName:        {string(entity.RoleAdmin)},
+		adminpb.AdminService_RotateSigningKey_FullMethodName:  {string(entity.RoleAdmin)},
+		adminpb.AdminService_RunBackfill_FullMethodName:       {string(entity.RoleAdmin)},
+	}
+
+	// gRPC Server Setup
+	methodLimits := rateLimitRules(cfg.RateLimiterConfig.Methods)
+	a.grpcAddr = net.JoinHostPort(cfg.GrpcServer.Host, strconv.Itoa(cfg.GrpcServer.Port))
+	// the gateway reaches the gRPC server through an in-memory listener, which needs no client certificate
+	a.loopback = gateway.NewLoopback()
+	grpcCreds := insecure.NewCredentials()
+	if cfg.GrpcServer.TLS.Enabled() {
+		a.grpcTLS, err = tlsreload.New(cfg.GrpcServer.TLS.CertFile, cfg.GrpcServer.TLS.KeyFile, cfg.GrpcServer.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("load the gRPC TLS certificate: %w", err)
+		}
+		grpcCreds = credentials.NewTLS(a.grpcTLS.TLSConfig())
+	}
+	//
+	//
+	//setup gRPC server with interceptors
+	grpcServer := grpc.NewServer(
+		grpc.Creds(a.loopback.Credentials(grpcCreds)),
+		grpc.MaxRecvMsgSize(cfg.GrpcServer.MaxRecvMsgSize),
+		grpc.ChainUnaryInterceptor(
+			interceptor.RequestIDInterceptor(),
+			interceptor.RecoveryInterceptor(logger, reporter),
+			interceptor.LoggingInterceptor(logger),
+			interceptor.ErrorInterceptor(logger, translator, reporter),
+			interceptor.ClientInfoInterceptor(),
+			interceptor.AuthInterceptor(jwtManager, c.Denylist, c.APIKeys()),
+			interceptor.RateLimitInterceptor(rateLimiter, methodLimits, logger),
+			interceptor.RoleInterceptor(methodRoles),
+			interceptor.ValidationInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.RequestIDStreamInterceptor(),
+			interceptor.RecoveryStreamInterceptor(logger, reporter),
+			interceptor.LoggingStreamInterceptor(logger),
+			interceptor.ErrorStreamInterceptor(logger, translator, reporter),
+			interceptor.ClientInfoStreamInterceptor(),
+			interceptor.AuthStreamInterceptor(jwtManager, c.Denylist, c.APIKeys()),
+			interceptor.RateLimitStreamInterceptor(rateLimiter, methodLimits, logger),
+			interceptor.RoleStreamInterceptor(methodRoles),
+			interceptor.ValidationStreamInterceptor(),
+		))
+	a.grpcServer = grpcServer
+
+	for _, service := range reg.services {
+		grpcServer.RegisterService(service.desc, service.impl)
+	}
+	// the standard health checking protocol, "" is the status of the whole instance
+	a.grpcHealthServer = grpcHealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, a.grpcHealthServer)
+	// the gRPC methods are also served as JSON over HTTP, through a connection to the gRPC server
+	gatewayConn, err := grpc.NewClient("passthrough:///loopback", grpc.WithContextDialer(a.loopback.Dial), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("set up the gRPC gateway: %w", err)
+	}
+	a.closers = append(a.closers, func() { gatewayConn.Close() })
+	rpcGateway, err := gateway.New(gatewayConn, "/rpc", slices.Collect(maps.Keys(grpcServer.GetServiceInfo())))
+	if err != nil {
+		return nil, fmt.Errorf("set up the gRPC gateway: %w", err)
+	}
+	e.Any("/rpc/*", echo.WrapHandler(rpcGateway))
+
+	// reflection for gRPC debugging tools (Postman/BloomRPC) and the API docs - only in non-production environments
+	if cfg.Env != "production" {
+		reflection.Register(grpcServer)
+
+		swaggerHandler := httpSwaggerHandler.NewSwaggerHandler(func() openapi.Document {
+			return openapi.Build(openapi.Info{Title: "Threads API", Version: "1.0"}, rpcGateway.Methods(), slices.DeleteFunc(e.Routes(), api.IsLegacy), "/rpc", "/swagger")
+		})
+		e.GET("/swagger", swaggerHandler.UI)
+		e.GET("/swagger/openapi.json", swaggerHandler.Spec)
+	}
+
+	for _, worker := range reg.workers {
+		a.jobs = append(a.jobs, func(ctx context.Context) error {
+			if err := worker.fn(ctx); err != nil {
+				return fmt.Errorf("%s: %w", worker.name, err)
+			}
+			return nil
+		})
+	}
+	for _, job := range reg.periodic {
+		a.every(job.name, job.interval, job.fn)
+	}
+
+	// pools that make requests wait for a connection are too small for the load
+	poolWatcher := psql.NewPoolWatcher(logger, pools)
+	a.every("db_pool_stats", cfg.PostgresConfig.PoolStatsInterval, poolWatcher.Check)
+
+	// rotated certificates are picked up without a restart
+	if a.httpTLS != nil {
+		a.every("http_tls_reload", cfg.Server.TLS.ReloadInterval, a.httpTLS.Reload)
+	}
+	if a.grpcTLS != nil {
+		a.every("grpc_tls_reload", cfg.GrpcServer.TLS.ReloadInterval, a.grpcTLS.Reload)
+	}
+
+	// cleanup jobs, run by one instance per interval
+	scheduler := jobs.NewScheduler(logger, reporter, scheduleRepo.NewScheduleRepo(pool), cfg.MaintenanceConfig.PollInterval)
+	for _, job := range reg.scheduled {
+		scheduler.Every(job.name, job.interval, job.fn)
+	}
+	a.jobs = append(a.jobs, scheduler.Run)
+
+	// keeps the gRPC health status in line with the readiness checks
+	a.every("health_check", cfg.HealthConfig.CheckInterval, func(ctx context.Context) error {
+		status := healthpb.HealthCheckResponse_SERVING
+		report := healthChecker.Run(ctx)
+		if !report.Ready {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		a.grpcHealthServer.SetServingStatus("", status)
+		if !report.Ready {
+			return fmt.Errorf("instance is not ready: %v", report.Checks)
+		}
+		return nil
+	})
+
+	return a, nil
+}
+
+// every adds a job running fn every interval while the app runs.
+func (a *App) every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	a.jobs = append(a.jobs, func(ctx context.Context) error {
+		return jobs.RunPeriodically(ctx, a.logger, a.reporter, name, interval, fn)
+	})
+}
+
+// HTTPHandler returns the handler of the HTTP API, e.g. for httptest.NewServer, without the HTTP server of Run.
+func (a *App) HTTPHandler() http.Handler {
+	return a.echo
+}
+
+// GRPCServer returns the gRPC server with every service registered, e.g. to serve it on a bufconn listener.
+func (a *App) GRPCServer() *grpc.Server {
+	return a.grpcServer
+}
+
+// Run serves HTTP and gRPC and runs the background jobs until ctx is done, Shutdown is called
+// or one of them fails. The servers are stopped gracefully and the connections closed before it returns.
+func (a *App) Run(ctx context.Context) error {
+	a.mu.Lock()
+	if a.done != nil {
+		a.mu.Unlock()
+		return errors.New("app is already running")
+	}
+	ctx, a.stop = context.WithCancel(ctx)
+	a.done = make(chan struct{})
+	a.mu.Unlock()
+	defer close(a.done)
+	defer a.close()
+	defer a.stop()
+
+	logger := a.logger
+	for i, hook := range a.hooks {
+		if hook.OnStart == nil {
+			continue
+		}
+		if err := hook.OnStart(ctx); err != nil {
+			return errors.Join(err, a.stopHooks(a.hooks[:i]))
+		}
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+
+	//setup gRPC server in separate goroutine
+	g.Go(func() error {
+		lis, err := net.Listen("tcp", a.grpcAddr)
+		if err != nil {
+			return errors.New("failed to listen gRPC: " + err.Error())
+		}
+		logger.Info("gRPC server started", slog.String("addr", a.grpcAddr), slog.Bool("tls", a.grpcTLS != nil), slog.Bool("mtls", a.cfg.GrpcServer.TLS.ClientCAFile != ""))
+
+		go func() {
+			// stopped together with the server, which closes the listener
+			if err := a.grpcServer.Serve(a.loopback); err != nil {
+				logger.Error("gRPC gateway listener failed", "error", err)
+			}
+		}()
+
+		if err := a.grpcServer.Serve(lis); err != nil {
+			return errors.New("gRPC server failed: " + err.Error())
+		}
+		return nil
+	})
+
+	//setup HTTP server in separate goroutine
+	g.Go(func() error {
+		logger.Info("HTTP server started", slog.String("addr", a.httpServer.Addr), slog.Bool("tls", a.httpTLS != nil))
+		if err := a.echo.StartServer(a.httpServer); err != nil {
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return errors.New("HTTP server failed: " + err.Error())
+		}
+		return nil
+	})
+
+	if a.debugServer != nil {
+		g.Go(func() error {
+			logger.Info("Debug server started", slog.String("addr", a.debugServer.Addr), slog.Bool("auth", a.cfg.DebugServer.Token != ""))
+			if err := a.debugServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return errors.New("debug server failed: " + err.Error())
+			}
+			return nil
+		})
+	}
+
+	for _, job := range a.jobs {
+		g.Go(func() error {
+			return job(gCtx)
+		})
+	}
+
+	// --- Graceful Shutdown ---
+	g.Go(func() error {
+		<-gCtx.Done()
+		logger.Info("Shutting down servers...")
+
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+
+		if a.debugServer != nil {
+			// a running profile would hold up the shutdown
+			if err := a.debugServer.Close(); err != nil {
+				logger.Error("Debug server shutdown error", slog.String("err", err.Error()))
+			}
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			if err := a.httpServer.Shutdown(shutdownCtx); err != nil {
+				logger.Error("HTTP shutdown error", slog.String("err", err.Error()))
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			// health watchers see NOT_SERVING before the connections are drained
+			a.grpcHealthServer.Shutdown()
+			a.grpcServer.GracefulStop()
+		}()
+
+		doneCh := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(doneCh)
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Info("Servers stopped gracefully")
+		case <-shutdownCtx.Done():
+			logger.Warn("Shutdown timeout exceeded, forcing gRPC stop")
+			a.grpcServer.Stop()
+		}
+
+		return nil
+	})
+
+	//wait for all goroutines to finish
+	err := g.Wait()
+	return errors.Join(err, a.stopHooks(a.hooks))
+}
+
+// stopHooks runs the stop hooks in reverse, every one of them even if some fail.
+func (a *App) stopHooks(hooks []Hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if hooks[i].OnStop == nil {
+			continue
+		}
+		if err := hooks[i].OnStop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown stops a running app and waits until Run returns, or until ctx is done.
+// An app that was never run only has its connections closed.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	stop, done := a.stop, a.done
+	a.mu.Unlock()
+	if done == nil {
+		a.close()
+		return nil
+	}
+
+	stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close releases the connections opened by New, newest first.
+func (a *App) close() {
+	a.closeOnce.Do(func() {
+		for i := len(a.closers) - 1; i >= 0; i-- {
+			a.closers[i]()
+		}
+	})
+}
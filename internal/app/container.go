@@ -0,0 +1,467 @@
+package app
+
+import (
+	"crypto/cipher"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/internal/metrics"
+	psql "main/internal/storage/postgres"
+	apiKeyRepo "main/internal/storage/postgres/apikey"
+	auditRepo "main/internal/storage/postgres/audit"
+	authRepo "main/internal/storage/postgres/auth"
+	blacklistRepo "main/internal/storage/postgres/blacklist"
+	chatRepo "main/internal/storage/postgres/chat"
+	closeFriendsRepo "main/internal/storage/postgres/closefriends"
+	commentRepo "main/internal/storage/postgres/comment"
+	counterRepo "main/internal/storage/postgres/counter"
+	importRepo "main/internal/storage/postgres/dataimport"
+	developerRepo "main/internal/storage/postgres/developer"
+	digestRepo "main/internal/storage/postgres/digest"
+	exportRepo "main/internal/storage/postgres/export"
+	federationRepo "main/internal/storage/postgres/federation"
+	followRepo "main/internal/storage/postgres/follow"
+	linkRepo "main/internal/storage/postgres/link"
+	moderationRepo "main/internal/storage/postgres/moderation"
+	notificationRepo "main/internal/storage/postgres/notification"
+	postRepo "main/internal/storage/postgres/post"
+	profileRepo "main/internal/storage/postgres/profile"
+	pushRepo "main/internal/storage/postgres/push"
+	seoRepo "main/internal/storage/postgres/seo"
+	sessionEventsBroker "main/internal/storage/postgres/sessionevents"
+	settingsRepo "main/internal/storage/postgres/settings"
+	signingKeyRepo "main/internal/storage/postgres/signingkey"
+	userRepo "main/internal/storage/postgres/user"
+	"main/internal/storage/redis/apps"
+	chatEventsBroker "main/internal/storage/redis/chatevents"
+	"main/internal/storage/redis/counters"
+	"main/internal/storage/redis/loginfailures"
+	notificationEventsBroker "main/internal/storage/redis/notificationevents"
+	"main/internal/storage/redis/presence"
+	"main/internal/storage/redis/revocation"
+	timelineCache "main/internal/storage/redis/timeline"
+	"main/internal/storage/redis/typing"
+	apiKeyUs "main/internal/usecase/apikey"
+	auditUs "main/internal/usecase/audit"
+	authUs "main/internal/usecase/auth"
+	blacklistUs "main/internal/usecase/blacklist"
+	chatUs "main/internal/usecase/chat"
+	closeFriendsUs "main/internal/usecase/closefriends"
+	commentUs "main/internal/usecase/comment"
+	counterUs "main/internal/usecase/counter"
+	importUs "main/internal/usecase/dataimport"
+	developerUs "main/internal/usecase/developer"
+	digestUs "main/internal/usecase/digest"
+	exportUs "main/internal/usecase/export"
+	federationUs "main/internal/usecase/federation"
+	feedUs "main/internal/usecase/feed"
+	followUs "main/internal/usecase/follow"
+	linkUs "main/internal/usecase/link"
+	maintenanceUs "main/internal/usecase/maintenance"
+	moderationUs "main/internal/usecase/moderation"
+	notificationUs "main/internal/usecase/notification"
+	postUs "main/internal/usecase/post"
+	profileUs "main/internal/usecase/profile"
+	pushUs "main/internal/usecase/push"
+	reviewUs "main/internal/usecase/review"
+	searchUs "main/internal/usecase/search"
+	seoUs "main/internal/usecase/seo"
+	settingsUs "main/internal/usecase/settings"
+	signingKeyUs "main/internal/usecase/signingkey"
+	timelineUs "main/internal/usecase/timeline"
+	"main/pkg/activitypub"
+	"main/pkg/email"
+	"main/pkg/errreport"
+	"main/pkg/featureflags"
+	"main/pkg/geoip"
+	"main/pkg/jwt"
+	"main/pkg/moderation"
+	"main/pkg/password"
+	"main/pkg/push"
+	"main/pkg/ratelimit"
+	"main/pkg/txmanager"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Container holds the components shared by the modules. The connections and the components whose setup
+// can fail are created by New, the usecases on first use, so every module gets the same instance whatever
+// order the modules are registered in.
+type Container struct {
+	Config   config.Config
+	Logger   *slog.Logger
+	Reporter errreport.Reporter
+	Metrics  *metrics.Metrics
+
+	Pool     *pgxpool.Pool
+	DB       *psql.DB
+	Replicas *psql.Replicas
+	Redis    *redis.Client
+
+	TxManager *txmanager.Manager
+	JWT       *jwt.JWTManager
+	// KeyCipher encrypts the stored JWT signing keys, nil for HS256
+	KeyCipher   cipher.AEAD
+	Denylist    *revocation.Denylist
+	RateLimiter *ratelimit.Limiter
+	Flags       *featureflags.Flags
+	Mailer      authUs.Mailer
+	MediaStore  postUs.MediaStore
+	ExportStore exportUs.Store
+
+	opts            options
+	hasher          *password.Hasher
+	emailValidator  *email.Validator
+	geoResolver     geoip.Resolver
+	captchaVerifier authUs.CaptchaVerifier
+	pushProviders   map[entity.PushPlatform]push.Provider
+	contentPolicy   moderation.Policy
+
+	presence           *presence.Tracker
+	sessionEvents      *sessionEventsBroker.Broker
+	chatEvents         *chatEventsBroker.Broker
+	notificationEvents *notificationEventsBroker.Broker
+	blacklistRepo      *blacklistRepo.BlacklistRepo
+	followRepo         *followRepo.FollowRepo
+	moderationRepo     *moderationRepo.ModerationRepo
+	postRepo           PostRepository
+
+	audit         *auditUs.AuditUsecase
+	apiKeys       *apiKeyUs.APIKeyUsecase
+	developer     *developerUs.DeveloperUsecase
+	auth          *authUs.AuthUsecase
+	push          *pushUs.PushUsecase
+	notifications *notificationUs.NotificationUsecase
+	digests       *digestUs.DigestUsecase
+	exports       *exportUs.ExportUsecase
+	imports       *importUs.ImportUsecase
+	federation    *federationUs.FederationUsecase
+	seo           *seoUs.SEOUsecase
+	links         *linkUs.LinkUsecase
+	moderation    *moderationUs.ModerationUsecase
+	counters      *counterUs.CounterUsecase
+	timelines     *timelineUs.TimelineUsecase
+	posts         *postUs.PostUsecase
+	feed          *feedUs.FeedUsecase
+	search        *searchUs.SearchUsecase
+	comments      *commentUs.CommentUsecase
+	blacklist     *blacklistUs.BlacklistUsecase
+	profiles      *profileUs.ProfileUsecase
+	follows       *followUs.FollowUsecase
+	settings      *settingsUs.SettingsUsecase
+	closeFriends  *closeFriendsUs.CloseFriendsUsecase
+	chat          *chatUs.ChatUsecase
+	review        *reviewUs.ReviewUsecase
+	maintenance   *maintenanceUs.MaintenanceUsecase
+	signingKeys   *signingKeyUs.SigningKeyUsecase
+}
+
+func (c *Container) Audit() *auditUs.AuditUsecase {
+	if c.audit == nil {
+		c.audit = auditUs.NewAuditUsecase(auditRepo.NewAuditRepo(c.DB, c.Metrics), c.Logger)
+	}
+	return c.audit
+}
+
+func (c *Container) APIKeys() *apiKeyUs.APIKeyUsecase {
+	if c.apiKeys == nil {
+		c.apiKeys = apiKeyUs.NewAPIKeyUsecase(apiKeyRepo.NewAPIKeyRepo(c.DB, c.Metrics), c.Audit())
+	}
+	return c.apiKeys
+}
+
+func (c *Container) Developer() *developerUs.DeveloperUsecase {
+	if c.developer == nil {
+		cfg := c.Config.DeveloperConfig
+		c.developer = developerUs.NewDeveloperUsecase(developerRepo.NewDeveloperRepo(c.DB, c.Metrics), apps.NewTokens(c.Redis), apps.NewUsage(c.Redis, cfg.UsageRetention), c.Audit(), cfg, c.Logger, c.Metrics)
+	}
+	return c.developer
+}
+
+// SessionEvents returns the broker of session changes, shared by all instances through Postgres.
+func (c *Container) SessionEvents() *sessionEventsBroker.Broker {
+	if c.sessionEvents == nil {
+		c.sessionEvents = sessionEventsBroker.NewBroker(c.Pool, c.Metrics, c.Logger)
+	}
+	return c.sessionEvents
+}
+
+func (c *Container) Auth() *authUs.AuthUsecase {
+	if c.auth == nil {
+		repo := c.opts.authRepo
+		if repo == nil {
+			repo = authRepo.NewAuthRepo(c.DB, c.Metrics)
+		}
+		loginFailures := loginfailures.NewCounter(c.Redis, c.Config.CaptchaConfig.FailureWindow)
+		c.auth = authUs.NewAuthUsecase(repo, c.TxManager, c.JWT, c.Denylist, c.hasher, c.emailValidator, c.geoResolver, c.captchaVerifier, loginFailures, c.Mailer, c.Audit(), c.SessionEvents(), c.Flags, c.Config.EmailConfig, c.Config.LoginSecurityConfig, c.Config.RegistrationConfig, c.Config.SessionConfig, c.Config.CaptchaConfig, c.Metrics)
+	}
+	return c.auth
+}
+
+// Presence returns the tracker of the users connected to any instance.
+func (c *Container) Presence() *presence.Tracker {
+	if c.presence == nil {
+		c.presence = presence.NewTracker(c.Redis)
+	}
+	return c.presence
+}
+
+// ChatEvents returns the broker of chat events, shared through Redis if enabled.
+func (c *Container) ChatEvents() *chatEventsBroker.Broker {
+	if c.chatEvents == nil {
+		var client *redis.Client
+		if c.Config.ChatConfig.RedisPubSub {
+			client = c.Redis
+		}
+		c.chatEvents = chatEventsBroker.NewBroker(client, c.Logger)
+	}
+	return c.chatEvents
+}
+
+// NotificationEvents returns the broker waking up notification streams, shared through Redis if enabled.
+func (c *Container) NotificationEvents() *notificationEventsBroker.Broker {
+	if c.notificationEvents == nil {
+		var client *redis.Client
+		if c.Config.NotificationsConfig.RedisPubSub {
+			client = c.Redis
+		}
+		c.notificationEvents = notificationEventsBroker.NewBroker(client, c.Logger)
+	}
+	return c.notificationEvents
+}
+
+func (c *Container) Push() *pushUs.PushUsecase {
+	if c.push == nil {
+		c.push = pushUs.NewPushUsecase(pushRepo.NewPushRepo(c.DB, c.Metrics), c.Presence(), c.pushProviders)
+	}
+	return c.push
+}
+
+func (c *Container) Notifications() *notificationUs.NotificationUsecase {
+	if c.notifications == nil {
+		c.notifications = notificationUs.NewNotificationUsecase(notificationRepo.NewNotificationRepo(c.DB, c.Metrics), c.NotificationEvents(), c.Push())
+	}
+	return c.notifications
+}
+
+func (c *Container) Digests() *digestUs.DigestUsecase {
+	if c.digests == nil {
+		c.digests = digestUs.NewDigestUsecase(digestRepo.NewDigestRepo(c.DB, c.Replicas, c.Metrics), c.Mailer, c.Config.DigestConfig.AppURL)
+	}
+	return c.digests
+}
+
+func (c *Container) Exports() *exportUs.ExportUsecase {
+	if c.exports == nil {
+		c.exports = exportUs.NewExportUsecase(exportRepo.NewExportRepo(c.DB, c.Metrics), c.ExportStore, c.Mailer, c.Flags, c.Config.ExportConfig)
+	}
+	return c.exports
+}
+
+func (c *Container) Imports() *importUs.ImportUsecase {
+	if c.imports == nil {
+		c.imports = importUs.NewImportUsecase(importRepo.NewImportRepo(c.DB, c.Metrics), c.Follows(), c.Posts(), c.Flags, c.Config.ImportConfig, c.Logger)
+	}
+	return c.imports
+}
+
+func (c *Container) Federation() *federationUs.FederationUsecase {
+	if c.federation == nil {
+		cfg := c.Config.FederationConfig
+		client := activitypub.NewClient("threads (+"+cfg.BaseURL+")", cfg.RequestTimeout, cfg.AllowInsecure)
+		c.federation = federationUs.NewFederationUsecase(federationRepo.NewFederationRepo(c.DB, c.Metrics), client, cfg, c.Logger)
+	}
+	return c.federation
+}
+
+func (c *Container) SEO() *seoUs.SEOUsecase {
+	if c.seo == nil {
+		c.seo = seoUs.NewSEOUsecase(seoRepo.NewSEORepo(c.DB, c.Replicas, c.Metrics), c.Config.SEOConfig)
+	}
+	return c.seo
+}
+
+func (c *Container) Links() *linkUs.LinkUsecase {
+	if c.links == nil {
+		c.links = linkUs.NewLinkUsecase(linkRepo.NewLinkRepo(c.DB, c.Replicas, c.Metrics), c.Counters())
+	}
+	return c.links
+}
+
+func (c *Container) moderationRepository() *moderationRepo.ModerationRepo {
+	if c.moderationRepo == nil {
+		c.moderationRepo = moderationRepo.NewModerationRepo(c.DB, c.Metrics)
+	}
+	return c.moderationRepo
+}
+
+func (c *Container) Moderation() *moderationUs.ModerationUsecase {
+	if c.moderation == nil {
+		c.moderation = moderationUs.NewModerationUsecase(c.moderationRepository(), c.contentPolicy)
+	}
+	return c.moderation
+}
+
+func (c *Container) postRepository() PostRepository {
+	if c.postRepo == nil {
+		c.postRepo = c.opts.postRepo
+		if c.postRepo == nil {
+			c.postRepo = postRepo.NewPostRepo(c.DB, c.Replicas, c.Metrics)
+		}
+	}
+	return c.postRepo
+}
+
+func (c *Container) Counters() *counterUs.CounterUsecase {
+	if c.counters == nil {
+		// without write-behind every increment is written at once
+		var pending counterUs.PendingStore
+		if c.Config.CountersConfig.WriteBehind {
+			pending = counters.NewPending(c.Redis)
+		}
+		c.counters = counterUs.NewCounterUsecase(counterRepo.NewCounterRepo(c.DB, c.Metrics), pending, c.Config.CountersConfig.BatchSize, c.Logger)
+	}
+	return c.counters
+}
+
+func (c *Container) Timelines() *timelineUs.TimelineUsecase {
+	if c.timelines == nil {
+		// without the cache every timeline is read from Postgres
+		var cache timelineUs.Cache
+		cfg := c.Config.TimelineConfig
+		if cfg.Enabled {
+			cache = timelineCache.NewCache(c.Redis, cfg.MaxEntries, cfg.TTL)
+		}
+		c.timelines = timelineUs.NewTimelineUsecase(c.postRepository(), c.followRepository(), cache, cfg, c.Logger)
+	}
+	return c.timelines
+}
+
+func (c *Container) Posts() *postUs.PostUsecase {
+	if c.posts == nil {
+		var federation postUs.Federation = federationUs.Nop{}
+		if c.Config.FederationConfig.Enabled {
+			federation = c.Federation()
+		}
+		c.posts = postUs.NewPostUsecase(c.postRepository(), c.MediaStore, c.Config.MediaConfig, c.Notifications(), c.Moderation(), c.Counters(), c.Timelines(), federation, c.Config.DeletionConfig.UndeleteWindow)
+	}
+	return c.posts
+}
+
+func (c *Container) Feed() *feedUs.FeedUsecase {
+	if c.feed == nil {
+		var ranker feedUs.Ranker = feedUs.Chronological{}
+		if c.Config.FeedRankingConfig.Algorithm == feedUs.AlgorithmWeighted {
+			ranker = feedUs.NewWeightedRanker(c.postRepository(), c.Config.FeedRankingConfig)
+		}
+		c.feed = feedUs.NewFeedUsecase(c.postRepository(), c.Timelines(), ranker, c.Flags, c.Config.PostsConfig, c.Config.FeedRankingConfig)
+	}
+	return c.feed
+}
+
+func (c *Container) Search() *searchUs.SearchUsecase {
+	if c.search == nil {
+		c.search = searchUs.NewSearchUsecase(c.postRepository(), userRepo.NewUserRepo(c.DB, c.Replicas, c.Metrics))
+	}
+	return c.search
+}
+
+func (c *Container) Comments() *commentUs.CommentUsecase {
+	if c.comments == nil {
+		repo := c.opts.commentRepo
+		if repo == nil {
+			repo = commentRepo.NewCommentRepo(c.DB, c.Replicas, c.Metrics)
+		}
+		c.comments = commentUs.NewCommentUsecase(repo, c.Notifications(), c.Moderation(), c.Counters(), c.Config.DeletionConfig.UndeleteWindow)
+	}
+	return c.comments
+}
+
+func (c *Container) blacklistRepository() *blacklistRepo.BlacklistRepo {
+	if c.blacklistRepo == nil {
+		c.blacklistRepo = blacklistRepo.NewBlacklistRepo(c.DB, c.Metrics)
+	}
+	return c.blacklistRepo
+}
+
+func (c *Container) Blacklist() *blacklistUs.BlacklistUsecase {
+	if c.blacklist == nil {
+		c.blacklist = blacklistUs.NewBlacklistUsecase(c.blacklistRepository(), c.Timelines())
+	}
+	return c.blacklist
+}
+
+func (c *Container) Profiles() *profileUs.ProfileUsecase {
+	if c.profiles == nil {
+		repo := c.opts.profileRepo
+		if repo == nil {
+			repo = profileRepo.NewProfileRepo(c.DB, c.Replicas, c.Metrics)
+		}
+		c.profiles = profileUs.NewProfileUsecase(repo, c.blacklistRepository())
+	}
+	return c.profiles
+}
+
+func (c *Container) followRepository() *followRepo.FollowRepo {
+	if c.followRepo == nil {
+		c.followRepo = followRepo.NewFollowRepo(c.DB, c.Replicas, c.Metrics)
+	}
+	return c.followRepo
+}
+
+func (c *Container) Follows() *followUs.FollowUsecase {
+	if c.follows == nil {
+		c.follows = followUs.NewFollowUsecase(c.followRepository(), c.blacklistRepository(), c.Notifications(), c.Counters(), c.Timelines())
+	}
+	return c.follows
+}
+
+func (c *Container) Settings() *settingsUs.SettingsUsecase {
+	if c.settings == nil {
+		c.settings = settingsUs.NewSettingsUsecase(settingsRepo.NewSettingsRepo(c.DB, c.Metrics))
+	}
+	return c.settings
+}
+
+func (c *Container) CloseFriends() *closeFriendsUs.CloseFriendsUsecase {
+	if c.closeFriends == nil {
+		c.closeFriends = closeFriendsUs.NewCloseFriendsUsecase(closeFriendsRepo.NewCloseFriendsRepo(c.DB, c.Metrics), c.blacklistRepository())
+	}
+	return c.closeFriends
+}
+
+func (c *Container) Chat() *chatUs.ChatUsecase {
+	if c.chat == nil {
+		repo := c.opts.chatRepo
+		if repo == nil {
+			repo = chatRepo.NewChatRepo(c.DB, c.Metrics)
+		}
+		c.chat = chatUs.NewChatUsecase(repo, c.blacklistRepository(), c.ChatEvents(),
+			typing.NewDebouncer(c.Redis, c.Config.ChatConfig.TypingDebounce), c.Presence(), c.MediaStore, c.Config.MediaConfig, c.Notifications(), c.Moderation(), c.Config.ChatConfig.EditWindow, c.Config.DeletionConfig.UndeleteWindow)
+	}
+	return c.chat
+}
+
+// Review returns the usecase of moderators, which takes down posts, comments and messages.
+func (c *Container) Review() *reviewUs.ReviewUsecase {
+	if c.review == nil {
+		c.review = reviewUs.NewReviewUsecase(c.moderationRepository(), c.TxManager, c.Posts(), c.Comments(), c.Chat(), c.Audit())
+	}
+	return c.review
+}
+
+func (c *Container) SigningKeys() *signingKeyUs.SigningKeyUsecase {
+	if c.signingKeys == nil {
+		c.signingKeys = signingKeyUs.NewSigningKeyUsecase(signingKeyRepo.NewSigningKeyRepo(c.DB, c.Metrics), c.JWT, c.KeyCipher,
+			c.Config.JWTConfig.AccessTokenTTL, c.Config.JWTConfig.KeySyncInterval)
+	}
+	return c.signingKeys
+}
+
+func (c *Container) Maintenance() *maintenanceUs.MaintenanceUsecase {
+	if c.maintenance == nil {
+		c.maintenance = maintenanceUs.NewMaintenanceUsecase(c.SigningKeys(), c.Counters(), c.Feed(), c.Audit())
+	}
+	return c.maintenance
+}
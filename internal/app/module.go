@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	routes "main/internal/delivery/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+)
+
+// Module is one feature of the service, e.g. posts or chat. It takes the components it needs from the
+// container and registers what it serves and runs, so adding a feature doesn't touch the rest of the wiring.
+type Module interface {
+	Name() string
+	Register(c *Container, r *Registry) error
+}
+
+// NewModule returns a module registering its components with the register function.
+func NewModule(name string, register func(c *Container, r *Registry) error) Module {
+	return funcModule{name: name, register: register}
+}
+
+type funcModule struct {
+	name     string
+	register func(c *Container, r *Registry) error
+}
+
+func (m funcModule) Name() string {
+	return m.name
+}
+
+func (m funcModule) Register(c *Container, r *Registry) error {
+	return m.register(c, r)
+}
+
+// Hook is run by the lifecycle of the app: OnStart before the servers start, OnStop after they stopped.
+// Either may be nil.
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// Registry collects what the modules contribute. It is a grpc.ServiceRegistrar, so the generated
+// Register*Server functions can be called with it directly.
+type Registry struct {
+	routes     []func(e *echo.Echo, api *routes.API)
+	services   []serviceRegistration
+	workers    []job
+	periodic   []job
+	scheduled  []job
+	migrations []fs.FS
+	hooks      []Hook
+}
+
+type serviceRegistration struct {
+	desc *grpc.ServiceDesc
+	impl any
+}
+
+// Routes adds a function mapping HTTP routes, called once the shared middlewares are set up. The API routes are
+// mapped into api, which serves them in every API version; e is for the unversioned ones, e.g. file downloads.
+func (r *Registry) Routes(fn func(e *echo.Echo, api *routes.API)) {
+	r.routes = append(r.routes, fn)
+}
+
+// RegisterService adds a gRPC service, implementing grpc.ServiceRegistrar.
+func (r *Registry) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	r.services = append(r.services, serviceRegistration{desc: desc, impl: impl})
+}
+
+// Worker adds a function running until its context is done, e.g. a subscription to a broker.
+// An error stops the whole app.
+func (r *Registry) Worker(name string, fn func(ctx context.Context) error) {
+	r.workers = append(r.workers, job{name: name, fn: fn})
+}
+
+// Every adds a job run by every instance each interval.
+func (r *Registry) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	r.periodic = append(r.periodic, job{name: name, interval: interval, fn: fn})
+}
+
+// Scheduled adds a job run by one instance per interval, e.g. a cleanup.
+func (r *Registry) Scheduled(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	r.scheduled = append(r.scheduled, job{name: name, interval: interval, fn: fn})
+}
+
+// Migrations adds the *.sql migrations at the root of fsys to the ones of the core schema.
+func (r *Registry) Migrations(fsys fs.FS) {
+	r.migrations = append(r.migrations, fsys)
+}
+
+// Append adds a lifecycle hook. Start hooks run in the order they were added, stop hooks in reverse.
+func (r *Registry) Append(hook Hook) {
+	r.hooks = append(r.hooks, hook)
+}
+
+// register registers the modules in order, the first error stops the registration.
+func (r *Registry) register(c *Container, modules []Module) error {
+	for _, m := range modules {
+		if err := m.Register(c, r); err != nil {
+			return fmt.Errorf("module %s: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package app
+
+import (
+	"context"
+	grpcAdminHandler "main/internal/delivery/grpc/admin"
+	grpcAuthHandler "main/internal/delivery/grpc/auth"
+	grpcChatHandler "main/internal/delivery/grpc/chat"
+	grpcCommentHandler "main/internal/delivery/grpc/comment"
+	grpcNotificationHandler "main/internal/delivery/grpc/notification"
+	grpcPostHandler "main/internal/delivery/grpc/post"
+	grpcProfileHandler "main/internal/delivery/grpc/profile"
+	routes "main/internal/delivery/http"
+	httpAdminHandler "main/internal/delivery/http/admin_handler"
+	httpAuthHandler "main/internal/delivery/http/auth_handler"
+	httpBlacklistHandler "main/internal/delivery/http/blacklist_handler"
+	httpChatHandler "main/internal/delivery/http/chat_handler"
+	httpCloseFriendsHandler "main/internal/delivery/http/close_friends_handler"
+	httpCommentHandler "main/internal/delivery/http/comment_handler"
+	httpDeveloperHandler "main/internal/delivery/http/developer_handler"
+	httpExportHandler "main/internal/delivery/http/export_handler"
+	httpFederationHandler "main/internal/delivery/http/federation_handler"
+	httpFollowHandler "main/internal/delivery/http/follow_handler"
+	httpGraphQLHandler "main/internal/delivery/http/graphql_handler"
+	httpImportHandler "main/internal/delivery/http/import_handler"
+	httpLinkHandler "main/internal/delivery/http/link_handler"
+	httpNotificationHandler "main/internal/delivery/http/notification_handler"
+	httpPostHandler "main/internal/delivery/http/post_handler"
+	httpProfileHandler "main/internal/delivery/http/profile_handler"
+	httpSearchHandler "main/internal/delivery/http/search_handler"
+	httpSEOHandler "main/internal/delivery/http/seo_handler"
+	httpSettingsHandler "main/internal/delivery/http/settings_handler"
+	httpWSHandler "main/internal/delivery/http/ws_handler"
+	eventsRepo "main/internal/storage/postgres/events"
+	eventsUs "main/internal/usecase/events"
+	adminpb "main/pkg/proto/gen/admin/v1"
+	pb "main/pkg/proto/gen/auth/v1"
+	chatpb "main/pkg/proto/gen/chat/v1"
+	commentspb "main/pkg/proto/gen/comments/v1"
+	notificationspb "main/pkg/proto/gen/notifications/v1"
+	postspb "main/pkg/proto/gen/posts/v1"
+	profilepb "main/pkg/proto/gen/profile/v1"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/segmentio/kafka-go"
+)
+
+// defaultModules are the features of the service, registered before the modules of WithModules.
+func defaultModules() []Module {
+	return []Module{
+		NewModule("auth", registerAuth),
+		NewModule("profiles", registerProfiles),
+		NewModule("posts", registerPosts),
+		NewModule("counters", registerCounters),
+		NewModule("developer", registerDeveloper),
+		NewModule("chat", registerChat),
+		NewModule("notifications", registerNotifications),
+		NewModule("events", registerEvents),
+		NewModule("media", registerMedia),
+		NewModule("federation", registerFederation),
+		NewModule("seo", registerSEO),
+	}
+}
+
+// registerAuth registers accounts, sessions, data exports and imports and the admin API.
+func registerAuth(c *Container, r *Registry) error {
+	cfg, logger, authUsecase := c.Config, c.Logger, c.Auth()
+
+	authHandler := httpAuthHandler.NewAuthHandler(authUsecase, cfg.SessionConfig.RefreshTokenTTL, c.Metrics)
+	adminHandler := httpAdminHandler.NewAdminHandler(authUsecase, c.Audit(), c.APIKeys(), c.Review(), c.Metrics)
+	exportHandler := httpExportHandler.NewExportHandler(c.Exports(), c.Metrics)
+	importHandler := httpImportHandler.NewImportHandler(c.Imports(), c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapAuthRoutes(api, authHandler, adminHandler, exportHandler, importHandler, authUsecase, c.APIKeys(), cfg.RateLimiterConfig, c.Metrics, c.Redis)
+	})
+	pb.RegisterAuthServiceServer(r, grpcAuthHandler.NewAuthHandler(logger, authUsecase))
+	adminpb.RegisterAdminServiceServer(r, grpcAdminHandler.NewAdminHandler(logger, authUsecase, c.Audit(), c.APIKeys(), c.Review(), c.Maintenance()))
+
+	// the first admin doesn't need manual SQL
+	r.Append(Hook{OnStart: func(ctx context.Context) error {
+		return bootstrapAdmins(ctx, authUsecase, cfg.AdminConfig.UserIDs)
+	}})
+
+	// session events published by any instance are pushed to the WatchSessions streams opened on this one
+	r.Worker("session_events", c.SessionEvents().Run)
+
+	// asymmetric signing keys are shared through the database: every instance signs with the same key and
+	// accepts the tokens of the others, rotation runs once per cluster and the previous key keeps being served
+	// in the JWKS until its tokens expire
+	if cfg.JWTConfig.Algorithm != "HS256" {
+		r.Append(Hook{OnStart: c.SigningKeys().Load})
+		r.Every("jwt_key_sync", cfg.JWTConfig.KeySyncInterval, c.SigningKeys().Load)
+		if cfg.JWTConfig.RotationInterval > 0 {
+			r.Scheduled("jwt_key_rotation", cfg.JWTConfig.RotationInterval, func(ctx context.Context) error {
+				_, err := c.SigningKeys().Rotate(ctx)
+				return err
+			})
+		}
+	}
+
+	// GDPR erasure of accounts deleted longer than the grace period ago
+	r.Every("account_erasure", cfg.AccountConfig.ErasureInterval, func(ctx context.Context) error {
+		count, err := authUsecase.AnonymizeDeletedAccounts(ctx, cfg.AccountConfig.DeletionGracePeriod)
+		if count > 0 {
+			logger.Info("Deleted accounts anonymized", "count", count)
+		}
+		return err
+	})
+
+	// archives of requested data exports
+	r.Every("data_export", cfg.ExportConfig.Interval, func(ctx context.Context) error {
+		ready, err := c.Exports().ProcessExports(ctx)
+		if ready > 0 {
+			logger.Info("Data exports prepared", "count", ready)
+		}
+		return err
+	})
+
+	// follows and posts of archives imported from other platforms
+	r.Every("data_import", cfg.ImportConfig.Interval, func(ctx context.Context) error {
+		completed, err := c.Imports().ProcessImports(ctx)
+		if completed > 0 {
+			logger.Info("Data imports completed", "count", completed)
+		}
+		return err
+	})
+
+	r.Scheduled("expired_session_cleanup", cfg.MaintenanceConfig.SessionCleanupInterval, func(ctx context.Context) error {
+		count, err := authUsecase.CleanupExpiredSessions(ctx, cfg.MaintenanceConfig.SessionRetention)
+		if count > 0 {
+			logger.Info("Expired sessions deleted", "count", count)
+		}
+		return err
+	})
+	r.Scheduled("expired_token_pruning", cfg.MaintenanceConfig.TokenPruneInterval, func(ctx context.Context) error {
+		count, err := authUsecase.PruneExpiredTokens(ctx)
+		if count > 0 {
+			logger.Info("Expired tokens pruned", "count", count)
+		}
+		return err
+	})
+	r.Scheduled("expired_export_purge", cfg.ExportConfig.PurgeInterval, func(ctx context.Context) error {
+		count, err := c.Exports().PurgeExpiredExports(ctx)
+		if count > 0 {
+			logger.Info("Expired data exports deleted", "count", count)
+		}
+		return err
+	})
+	return nil
+}
+
+// registerProfiles registers profiles, follows, blocks, close friends and settings.
+func registerProfiles(c *Container, r *Registry) error {
+	profileHandler := httpProfileHandler.NewProfileHandler(c.Profiles(), c.Metrics)
+	followHandler := httpFollowHandler.NewFollowHandler(c.Follows(), c.Metrics)
+	blacklistHandler := httpBlacklistHandler.NewBlacklistHandler(c.Blacklist(), c.Metrics)
+	closeFriendsHandler := httpCloseFriendsHandler.NewCloseFriendsHandler(c.CloseFriends(), c.Metrics)
+	settingsHandler := httpSettingsHandler.NewSettingsHandler(c.Settings(), c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapProfileRoutes(api, profileHandler, followHandler, blacklistHandler, closeFriendsHandler, settingsHandler, c.Auth(), c.Metrics)
+	})
+	profilepb.RegisterProfileServiceServer(r, grpcProfileHandler.NewProfileHandler(c.Logger, c.Profiles(), c.Follows(), c.Blacklist(), c.Settings(), c.CloseFriends()))
+	return nil
+}
+
+// registerPosts registers posts, comments, feeds, search, the shortened links of posts and the GraphQL API.
+func registerPosts(c *Container, r *Registry) error {
+	cfg, logger := c.Config, c.Logger
+
+	postHandler := httpPostHandler.NewPostHandler(c.Posts(), c.Feed(), c.Metrics)
+	commentHandler := httpCommentHandler.NewCommentHandler(c.Comments(), c.Metrics)
+	searchHandler := httpSearchHandler.NewSearchHandler(c.Search(), c.Metrics)
+	graphqlHandler := httpGraphQLHandler.NewGraphQLHandler(c.Profiles(), c.Posts(), c.Feed(), c.Comments(), c.Metrics)
+	linkHandler := httpLinkHandler.NewLinkHandler(c.Links(), c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapPostRoutes(api, postHandler, commentHandler, searchHandler, graphqlHandler, c.Auth(), c.Metrics)
+		routes.MapLinkRoutes(e, api, linkHandler, c.Auth(), c.Metrics)
+	})
+	postspb.RegisterPostServiceServer(r, grpcPostHandler.NewPostHandler(logger, c.Posts(), c.Feed(), c.Search()))
+	commentspb.RegisterCommentServiceServer(r, grpcCommentHandler.NewCommentHandler(logger, c.Comments()))
+
+	// pushes new posts and reposts to the cached timelines of the followers of their authors
+	if cfg.TimelineConfig.Enabled {
+		r.Worker("timeline_fanout", c.Timelines().Run)
+	}
+
+	// rebuilds the explore ranking, so scores follow new engagement and decay with age
+	r.Every("explore_ranking", cfg.PostsConfig.ExploreRefreshInterval, func(ctx context.Context) error {
+		_, err := c.Feed().RefreshExplore(ctx)
+		return err
+	})
+
+	r.Scheduled("deleted_post_purge", cfg.DeletionConfig.PurgeInterval, func(ctx context.Context) error {
+		count, err := c.Posts().PurgeDeletedPosts(ctx)
+		if count > 0 {
+			logger.Info("Deleted posts purged", "count", count)
+		}
+		return err
+	})
+	r.Scheduled("deleted_comment_purge", cfg.DeletionConfig.PurgeInterval, func(ctx context.Context) error {
+		count, err := c.Comments().PurgeDeletedComments(ctx)
+		if count > 0 {
+			logger.Info("Deleted comments purged", "count", count)
+		}
+		return err
+	})
+	return nil
+}
+
+// registerCounters registers the writing and the reconciliation of the like, repost, comment and follow counters.
+func registerCounters(c *Container, r *Registry) error {
+	cfg, logger, counters := c.Config, c.Logger, c.Counters()
+
+	if cfg.CountersConfig.WriteBehind {
+		// every instance flushes, each flush takes different increments
+		r.Every("counter_flush", cfg.CountersConfig.FlushInterval, func(ctx context.Context) error {
+			_, err := counters.Flush(ctx)
+			return err
+		})
+		// increments collected since the last flush are written before the instance stops
+		r.Append(Hook{OnStop: func(ctx context.Context) error {
+			_, err := counters.Flush(ctx)
+			return err
+		}})
+	}
+
+	// repairs counters that drifted, e.g. after an instance died before writing an increment
+	r.Scheduled("post_counter_reconciliation", cfg.CountersConfig.ReconcileInterval, func(ctx context.Context) error {
+		fixed, err := counters.ReconcilePosts(ctx)
+		if fixed > 0 {
+			logger.Info("Post counters reconciled", "count", fixed)
+		}
+		return err
+	})
+	r.Scheduled("follow_counter_reconciliation", cfg.CountersConfig.ReconcileInterval, func(ctx context.Context) error {
+		fixed, err := counters.ReconcileFollows(ctx)
+		if fixed > 0 {
+			logger.Info("Follow counters reconciled", "count", fixed)
+		}
+		return err
+	})
+	return nil
+}
+
+// registerDeveloper registers the apps of third-party developers and the public API they call.
+func registerDeveloper(c *Container, r *Registry) error {
+	developerHandler := httpDeveloperHandler.NewDeveloperHandler(c.Developer(), c.Metrics)
+	postHandler := httpPostHandler.NewPostHandler(c.Posts(), c.Feed(), c.Metrics)
+	commentHandler := httpCommentHandler.NewCommentHandler(c.Comments(), c.Metrics)
+	searchHandler := httpSearchHandler.NewSearchHandler(c.Search(), c.Metrics)
+	profileHandler := httpProfileHandler.NewProfileHandler(c.Profiles(), c.Metrics)
+	followHandler := httpFollowHandler.NewFollowHandler(c.Follows(), c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapDeveloperRoutes(api, developerHandler, postHandler, commentHandler, searchHandler, profileHandler, followHandler,
+			c.Auth(), c.Developer(), c.RateLimiter, c.Logger, c.Metrics)
+	})
+	return nil
+}
+
+// registerChat registers chats, messages and the WebSocket endpoint.
+func registerChat(c *Container, r *Registry) error {
+	cfg, logger, chatUsecase := c.Config, c.Logger, c.Chat()
+
+	chatHandler := httpChatHandler.NewChatHandler(chatUsecase, c.Metrics)
+	wsHandler := httpWSHandler.NewWSHandler(chatUsecase, logger, c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapChatRoutes(api, chatHandler, wsHandler, c.Auth(), c.Metrics)
+	})
+	chatpb.RegisterChatServiceServer(r, grpcChatHandler.NewChatHandler(logger, chatUsecase))
+
+	// chat events published by other instances are pushed to the WebSocket connections of this one
+	r.Worker("chat_events", c.ChatEvents().Run)
+
+	// message events that couldn't be published when the message was sent, e.g. while Redis was unavailable
+	r.Every("chat_outbox_relay", cfg.ChatConfig.OutboxInterval, func(ctx context.Context) error {
+		_, err := chatUsecase.RelayOutbox(ctx)
+		return err
+	})
+
+	r.Scheduled("deleted_message_purge", cfg.DeletionConfig.PurgeInterval, func(ctx context.Context) error {
+		count, err := chatUsecase.PurgeDeletedMessages(ctx)
+		if count > 0 {
+			logger.Info("Deleted messages purged", "count", count)
+		}
+		return err
+	})
+	return nil
+}
+
+// registerNotifications registers notifications, push devices and the activity digests.
+func registerNotifications(c *Container, r *Registry) error {
+	cfg, notificationUsecase := c.Config, c.Notifications()
+
+	notificationHandler := httpNotificationHandler.NewNotificationHandler(notificationUsecase, c.Push(), c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapNotificationRoutes(api, notificationHandler, c.Auth(), c.Metrics)
+	})
+	notificationspb.RegisterNotificationServiceServer(r, grpcNotificationHandler.NewNotificationHandler(c.Logger, notificationUsecase, c.Push()))
+
+	// notifications created by the workers of other instances wake up the notification streams of this one
+	r.Worker("notification_events", c.NotificationEvents().Run)
+
+	// notification events are fanned out by a pool of workers, each taking its own events from the queue
+	for i := 0; i < cfg.NotificationsConfig.Workers; i++ {
+		r.Every("notification_fanout", cfg.NotificationsConfig.PollInterval, func(ctx context.Context) error {
+			_, err := notificationUsecase.ProcessEvents(ctx)
+			return err
+		})
+	}
+
+	// daily and weekly activity digest emails
+	r.Every("email_digest", cfg.DigestConfig.Interval, func(ctx context.Context) error {
+		_, err := c.Digests().SendDigests(ctx)
+		return err
+	})
+	return nil
+}
+
+// registerEvents publishes the domain events of the outbox to Kafka.
+func registerEvents(c *Container, r *Registry) error {
+	cfg := c.Config.EventsConfig
+
+	// domain events are dropped unless Kafka is configured
+	var eventsProducer eventsUs.Producer
+	if len(cfg.KafkaBrokers) > 0 {
+		writer := &kafka.Writer{
+			Addr: kafka.TCP(cfg.KafkaBrokers...),
+			// hashes keys like the Java client, the events about the same entity stay in order in one partition
+			Balancer:     &kafka.Murmur2Balancer{},
+			RequiredAcks: kafka.RequireAll,
+			// a relay run writes all its events at once, waiting for more only delays them
+			BatchTimeout: 10 * time.Millisecond,
+			WriteTimeout: cfg.WriteTimeout,
+			Transport:    &kafka.Transport{ClientID: cfg.ClientID},
+		}
+		r.Append(Hook{OnStop: func(ctx context.Context) error {
+			return writer.Close()
+		}})
+		eventsProducer = writer
+	}
+	eventsUsecase := eventsUs.NewEventsUsecase(eventsRepo.NewEventsRepo(c.DB, c.Metrics), eventsProducer, cfg.Topic)
+
+	// domain events stored by the changes they're about are published to Kafka
+	r.Every("event_outbox_relay", cfg.RelayInterval, func(ctx context.Context) error {
+		_, err := eventsUsecase.RelayOutbox(ctx)
+		return err
+	})
+	return nil
+}
+
+// registerFederation serves profiles and public posts to ActivityPub servers and delivers the activities of
+// federated users to their remote followers, if federation is enabled.
+func registerFederation(c *Container, r *Registry) error {
+	cfg := c.Config.FederationConfig
+	if !cfg.Enabled {
+		return nil
+	}
+
+	federationHandler := httpFederationHandler.NewFederationHandler(c.Federation(), c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapFederationRoutes(e, federationHandler, c.Metrics)
+	})
+
+	// activities queued by posts, reposts, deletions and accepted follows
+	r.Every("federation_delivery", cfg.DeliveryInterval, func(ctx context.Context) error {
+		delivered, err := c.Federation().DeliverActivities(ctx)
+		if delivered > 0 {
+			c.Logger.Info("Federation activities delivered", "count", delivered)
+		}
+		return err
+	})
+	return nil
+}
+
+// registerSEO serves the link previews of public posts and the sitemaps of public profiles and posts.
+func registerSEO(c *Container, r *Registry) error {
+	seoHandler := httpSEOHandler.NewSEOHandler(c.SEO(), c.Metrics)
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		routes.MapSEORoutes(e, seoHandler, c.Metrics)
+	})
+
+	r.Scheduled("sitemap_generation", c.Config.SEOConfig.SitemapInterval, func(ctx context.Context) error {
+		count, err := c.SEO().GenerateSitemaps(ctx)
+		if err == nil {
+			c.Logger.Info("Sitemaps generated", "urls", count)
+		}
+		return err
+	})
+	return nil
+}
+
+// registerMedia serves the uploaded media and the data export archives stored on the local disk.
+func registerMedia(c *Container, r *Registry) error {
+	r.Routes(func(e *echo.Echo, api *routes.API) {
+		if c.Config.MediaConfig.S3Bucket == "" && c.opts.mediaStore == nil {
+			// served with Range support, so players can stream and seek in uploaded videos
+			e.Static("/media", c.Config.MediaConfig.LocalDir)
+		}
+		if handler, ok := c.ExportStore.(http.Handler); ok {
+			// local archives are only served to signed download links
+			e.GET("/exports/*", echo.WrapHandler(http.StripPrefix("/exports", handler)))
+		}
+	})
+	return nil
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"log/slog"
+	authUs "main/internal/usecase/auth"
+	chatUs "main/internal/usecase/chat"
+	commentUs "main/internal/usecase/comment"
+	feedUs "main/internal/usecase/feed"
+	postUs "main/internal/usecase/post"
+	profileUs "main/internal/usecase/profile"
+	searchUs "main/internal/usecase/search"
+	timelineUs "main/internal/usecase/timeline"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PostRepository is the storage of posts, shared by the post, feed, timeline and search usecases.
+type PostRepository interface {
+	postUs.PostRepo
+	feedUs.FeedRepo
+	feedUs.AffinityRepo
+	timelineUs.TimelineRepo
+	searchUs.SearchRepo
+}
+
+// Option changes how New wires the application.
+type Option func(*options)
+
+type options struct {
+	logger      *slog.Logger
+	registerer  prometheus.Registerer
+	migrate     bool
+	mailer      authUs.Mailer
+	mediaStore  postUs.MediaStore
+	authRepo    authUs.AuthRepo
+	postRepo    PostRepository
+	commentRepo commentUs.CommentRepo
+	chatRepo    chatUs.ChatRepo
+	profileRepo profileUs.ProfileRepo
+	modules     []Module
+}
+
+// WithLogger sets the logger of every component, by default logs are discarded.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRegisterer sets where the metrics are registered, prometheus.DefaultRegisterer by default.
+// Applications embedded into the same process need a registerer each.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = registerer
+	}
+}
+
+// WithMigrations applies the pending migrations instead of refusing to start on an outdated schema.
+func WithMigrations(migrate bool) Option {
+	return func(o *options) {
+		o.migrate = migrate
+	}
+}
+
+// WithModules adds features to the service, registered after the built-in ones.
+func WithModules(modules ...Module) Option {
+	return func(o *options) {
+		o.modules = append(o.modules, modules...)
+	}
+}
+
+// WithMailer replaces the mailer of the email config, e.g. by one recording the sent emails.
+func WithMailer(mailer authUs.Mailer) Option {
+	return func(o *options) {
+		o.mailer = mailer
+	}
+}
+
+// WithMediaStore replaces the media storage of the media config.
+func WithMediaStore(store postUs.MediaStore) Option {
+	return func(o *options) {
+		o.mediaStore = store
+	}
+}
+
+// WithAuthRepository replaces the Postgres storage of users, sessions and tokens.
+func WithAuthRepository(repo authUs.AuthRepo) Option {
+	return func(o *options) {
+		o.authRepo = repo
+	}
+}
+
+// WithPostRepository replaces the Postgres storage of posts.
+func WithPostRepository(repo PostRepository) Option {
+	return func(o *options) {
+		o.postRepo = repo
+	}
+}
+
+// WithCommentRepository replaces the Postgres storage of comments.
+func WithCommentRepository(repo commentUs.CommentRepo) Option {
+	return func(o *options) {
+		o.commentRepo = repo
+	}
+}
+
+// WithChatRepository replaces the Postgres storage of chats and messages.
+func WithChatRepository(repo chatUs.ChatRepo) Option {
+	return func(o *options) {
+		o.chatRepo = repo
+	}
+}
+
+// WithProfileRepository replaces the Postgres storage of profiles.
+func WithProfileRepository(repo profileUs.ProfileRepo) Option {
+	return func(o *options) {
+		o.profileRepo = repo
+	}
+}
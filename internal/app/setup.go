@@ -0,0 +1,262 @@
+package app
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/config"
+	routes "main/internal/delivery/http"
+	"main/internal/mailer"
+	authUs "main/internal/usecase/auth"
+	exportUs "main/internal/usecase/export"
+	postUs "main/internal/usecase/post"
+	"main/pkg/captcha"
+	"main/pkg/email"
+	"main/pkg/featureflags"
+	"main/pkg/geoip"
+	"main/pkg/jwt"
+	"main/pkg/media"
+	"main/pkg/moderation"
+	"main/pkg/push"
+	"main/pkg/ratelimit"
+	"maps"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// bootstrapAdmins grants the admin role to the users listed in the config, so the first admin doesn't need manual SQL.
+func bootstrapAdmins(ctx context.Context, authUsecase *authUs.AuthUsecase, ids []string) error {
+	for _, id := range ids {
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("parse %q: %w", id, err)
+		}
+		if err := authUsecase.GrantRole(ctx, userID, entity.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupMailer returns an SMTP mailer, or a mailer that only logs when no SMTP host is configured.
+func setupMailer(cfg config.EmailConfig, logger *slog.Logger) authUs.Mailer {
+	if cfg.SMTPHost == "" {
+		return mailer.NewLogMailer(logger)
+	}
+	return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+}
+
+// setupEmailValidator builds the email validator blocking the configured disposable domains.
+func setupEmailValidator(cfg config.RegistrationConfig) (*email.Validator, error) {
+	domains := cfg.DisposableDomains
+	if cfg.DisposableDomainsPath != "" {
+		fromFile, err := email.LoadDomainList(cfg.DisposableDomainsPath)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, fromFile...)
+	}
+	return email.NewValidator(domains), nil
+}
+
+// setupGeoResolver opens the configured GeoIP database, without one locations are left empty.
+func setupGeoResolver(cfg config.GeoIPConfig) (geoip.Resolver, error) {
+	if cfg.DatabasePath == "" {
+		return geoip.NopResolver{}, nil
+	}
+	return geoip.OpenMMDB(cfg.DatabasePath)
+}
+
+// setupCaptchaVerifier returns the verifier of the configured provider, or one accepting every token when CAPTCHA is disabled.
+func setupCaptchaVerifier(cfg config.CaptchaConfig) (authUs.CaptchaVerifier, error) {
+	if cfg.Provider == "" {
+		return captcha.NopVerifier{}, nil
+	}
+	return captcha.NewVerifier(cfg.Provider, cfg.Secret, cfg.Timeout)
+}
+
+// setupContentPolicy returns the banned words policy, followed by the classifier if one is configured.
+func setupContentPolicy(cfg config.ModerationConfig) (moderation.Policy, error) {
+	verdict, err := moderation.ParseVerdict(cfg.BannedWordsVerdict)
+	if err != nil {
+		return nil, err
+	}
+	bannedWords, err := moderation.NewBannedWords(cfg.BannedWords, verdict)
+	if err != nil {
+		return nil, err
+	}
+	policy := moderation.Chain{bannedWords}
+	if cfg.ClassifierURL != "" {
+		thresholds := moderation.Thresholds{Flag: cfg.FlagScore, Limit: cfg.LimitScore, Reject: cfg.RejectScore}
+		policy = append(policy, moderation.NewClassifier(cfg.ClassifierURL, thresholds, cfg.ClassifierTimeout))
+	}
+	return policy, nil
+}
+
+// setupMediaStore returns the S3 store if a bucket is configured, otherwise a store in the local media directory.
+func setupMediaStore(cfg config.MediaConfig) (postUs.MediaStore, error) {
+	if cfg.S3Bucket == "" {
+		return media.NewLocalStore(cfg.LocalDir, cfg.BaseURL)
+	}
+	return media.NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PublicURL, cfg.UploadTimeout), nil
+}
+
+// setupExportStore returns the S3 store if an export bucket is configured, otherwise a store in the local export
+// directory whose files are only served to signed links. Without a signing key a random one is used.
+func setupExportStore(cfg config.ExportConfig, mediaCfg config.MediaConfig, logger *slog.Logger) (exportUs.Store, error) {
+	if cfg.S3Bucket != "" {
+		return media.NewS3Store(mediaCfg.S3Endpoint, mediaCfg.S3Region, cfg.S3Bucket, mediaCfg.S3AccessKey, mediaCfg.S3SecretKey, "", mediaCfg.UploadTimeout), nil
+	}
+	secret := []byte(cfg.SigningKey)
+	if len(secret) == 0 {
+		logger.Warn("No export signing key configured, download links are only valid on this instance until it restarts")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return media.NewSignedLocalStore(cfg.LocalDir, cfg.BaseURL, secret)
+}
+
+// setupPushProviders returns the providers of the push services whose credentials are configured.
+func setupPushProviders(cfg config.PushConfig) (map[entity.PushPlatform]push.Provider, error) {
+	providers := make(map[entity.PushPlatform]push.Provider)
+	if cfg.FCMCredentialsFile != "" {
+		fcm, err := push.NewFCM(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		providers[entity.PushFCM] = fcm
+	}
+	if cfg.APNsKeyFile != "" {
+		apns, err := push.NewAPNs(cfg.APNsKeyFile, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsSandbox)
+		if err != nil {
+			return nil, err
+		}
+		providers[entity.PushAPNs] = apns
+	}
+	if cfg.VAPIDPrivateKey != "" {
+		webPush, err := push.NewWebPush(cfg.VAPIDPrivateKey, cfg.VAPIDSubject)
+		if err != nil {
+			return nil, err
+		}
+		providers[entity.PushWebPush] = webPush
+	}
+	return providers, nil
+}
+
+// setupJWTManager builds the JWT manager for the configured algorithm.
+// For RS256/EdDSA the private key signs new tokens and every configured public key is accepted on verification.
+func setupJWTManager(cfg config.JWTConfig) (*jwt.JWTManager, error) {
+	switch cfg.Algorithm {
+	case "", "HS256":
+		return jwt.NewJWTManager(cfg.Secret, cfg.AccessTokenTTL, cfg.Issuer, cfg.Audience), nil
+	case "RS256", "EdDSA":
+		privateKey, err := jwt.LoadPrivateKey(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load private key: %w", err)
+		}
+		manager, err := jwt.NewAsymmetricJWTManager(cfg.KeyID, privateKey, cfg.AccessTokenTTL, cfg.Issuer, cfg.Audience)
+		if err != nil {
+			return nil, err
+		}
+		for _, pk := range cfg.PublicKeys {
+			publicKey, err := jwt.LoadPublicKey(pk.Path)
+			if err != nil {
+				return nil, fmt.Errorf("load public key %q: %w", pk.KeyID, err)
+			}
+			if err := manager.AddPublicKey(pk.KeyID, publicKey); err != nil {
+				return nil, err
+			}
+		}
+		return manager, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+}
+
+// setupKeyCipher returns the AES-GCM cipher the signing keys shared through the database are encrypted with,
+// nil for HS256, which stores no keys.
+func setupKeyCipher(cfg config.JWTConfig) (cipher.AEAD, error) {
+	if cfg.Algorithm == "" || cfg.Algorithm == "HS256" {
+		return nil, nil
+	}
+	kek, err := base64.StdEncoding.DecodeString(cfg.KeyEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode key encryption key: %w", err)
+	}
+	if len(kek) != 32 {
+		return nil, fmt.Errorf("key encryption key must be 32 bytes, got %d", len(kek))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// setupFeatureFlags returns the flags of the config file, overridden by the flags in Redis if enabled.
+func setupFeatureFlags(cfg config.FeatureFlagsConfig, client *redis.Client) (*featureflags.Flags, error) {
+	static := make(featureflags.Static, len(cfg.Flags))
+	for name, flag := range cfg.Flags {
+		users := make([]uuid.UUID, 0, len(flag.Users))
+		for _, id := range flag.Users {
+			userID, err := uuid.Parse(id)
+			if err != nil {
+				return nil, fmt.Errorf("flag %s: parse user %q: %w", name, id, err)
+			}
+			users = append(users, userID)
+		}
+		static[name] = featureflags.Flag{Enabled: flag.Enabled, Users: users, Percentage: flag.Percentage}
+	}
+	if !cfg.Redis {
+		return featureflags.New(static), nil
+	}
+	return featureflags.New(featureflags.NewRedisProvider(client, static, cfg.CacheTTL)), nil
+}
+
+// rateLimitRules converts the configured rate limits into the rules of the limiter.
+func rateLimitRules(cfg map[string]config.RateLimitRule) map[string]ratelimit.Rule {
+	rules := make(map[string]ratelimit.Rule, len(cfg))
+	for name, rule := range cfg {
+		rules[name] = ratelimit.Rule{Requests: rule.Requests, Window: rule.Window, Burst: rule.Burst}
+	}
+	return rules
+}
+
+// uploadOverhead is room for the multipart framing and the other form fields of an upload.
+const uploadOverhead = 1 << 20
+
+// bodyLimits returns the body limits of the routes, the upload routes fitting the largest file media accepts
+// unless they are configured.
+func bodyLimits(cfg config.Config) map[string]int64 {
+	limits := map[string]int64{
+		"POST /posts/video":                   cfg.MediaConfig.MaxVideoSize + uploadOverhead,
+		"POST /chats/:id/messages/attachment": cfg.MediaConfig.MaxAttachmentSize + uploadOverhead,
+		"POST /account/import":                cfg.ImportConfig.MaxArchiveSize + uploadOverhead,
+	}
+	maps.Copy(limits, cfg.Server.BodyLimits)
+	return limits
+}
+
+// apiOptions converts the configured versioning into the options of the API, v1 being the only version yet.
+func apiOptions(cfg config.APIConfig) routes.APIOptions {
+	opts := routes.APIOptions{
+		Versions:   []routes.APIVersion{routes.V1},
+		Deprecated: make(map[routes.APIVersion]routes.Deprecation, len(cfg.Deprecated)),
+	}
+	for version, d := range cfg.Deprecated {
+		opts.Deprecated[routes.APIVersion(version)] = routes.Deprecation(d)
+	}
+	if cfg.LegacyRoutes {
+		legacy := routes.Deprecation(cfg.Legacy)
+		opts.Legacy = &legacy
+	}
+	return opts
+}
@@ -1,22 +1,402 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env               string `yaml:"env" default:"development"`
-	PostgresConfig    `yaml:"database"`
-	JWTConfig         `yaml:"jwt"`
-	Server            `yaml:"server"`
-	GrpcServer        `yaml:"grpc"`
-	RateLimiterConfig `yaml:"rate_limiter"`
-	RedisConfig       `yaml:"redis"`
+	Env                  string `yaml:"env" env:"ENV" env-default:"development"`
+	PostgresConfig       `yaml:"database"`
+	JWTConfig            `yaml:"jwt"`
+	Server               `yaml:"server"`
+	APIConfig            `yaml:"api"`
+	GrpcServer           `yaml:"grpc"`
+	DebugServer          `yaml:"debug"`
+	RateLimiterConfig    `yaml:"rate_limiter"`
+	RedisConfig          `yaml:"redis"`
+	PasswordConfig       `yaml:"password"`
+	EmailConfig          `yaml:"email"`
+	AccountConfig        `yaml:"account"`
+	DeletionConfig       `yaml:"deletion"`
+	ExportConfig         `yaml:"export"`
+	ImportConfig         `yaml:"import"`
+	FeatureFlagsConfig   `yaml:"feature_flags"`
+	MaintenanceConfig    `yaml:"maintenance"`
+	AdminConfig          `yaml:"admin"`
+	LoginSecurityConfig  `yaml:"login_security"`
+	RegistrationConfig   `yaml:"registration"`
+	SessionConfig        `yaml:"session"`
+	GeoIPConfig          `yaml:"geoip"`
+	CaptchaConfig        `yaml:"captcha"`
+	PostsConfig          `yaml:"posts"`
+	CountersConfig       `yaml:"counters"`
+	TimelineConfig       `yaml:"timeline"`
+	FeedRankingConfig    `yaml:"feed_ranking"`
+	DeveloperConfig      `yaml:"developer"`
+	FederationConfig     `yaml:"federation"`
+	SEOConfig            `yaml:"seo"`
+	MediaConfig          `yaml:"media"`
+	ChatConfig           `yaml:"chat"`
+	NotificationsConfig  `yaml:"notifications"`
+	PushConfig           `yaml:"push"`
+	DigestConfig         `yaml:"digest"`
+	ModerationConfig     `yaml:"moderation"`
+	HealthConfig         `yaml:"health"`
+	ErrorReportingConfig `yaml:"error_reporting"`
+	EventsConfig         `yaml:"events"`
+}
+
+// EventsConfig controls the domain events (user.registered, post.created, user.followed, message.sent) published
+// to Topic on the Kafka cluster reached through KafkaBrokers. Events are stored in an outbox with the change they're
+// about and relayed every RelayInterval, each produce call may take up to WriteTimeout. Without brokers the events
+// are dropped.
+type EventsConfig struct {
+	KafkaBrokers  []string      `yaml:"kafka_brokers" env:"EVENTS_KAFKA_BROKERS" env-separator:","`
+	Topic         string        `yaml:"topic" env:"EVENTS_TOPIC" env-default:"threads.events"`
+	ClientID      string        `yaml:"client_id" env:"EVENTS_CLIENT_ID" env-default:"threads"`
+	RelayInterval time.Duration `yaml:"relay_interval" env:"EVENTS_RELAY_INTERVAL" env-default:"1s"`
+	WriteTimeout  time.Duration `yaml:"write_timeout" env:"EVENTS_WRITE_TIMEOUT" env-default:"10s"`
+}
+
+// HealthConfig controls the readiness checks of Postgres, Redis and the schema version. Each check may take
+// up to CheckTimeout; the gRPC health service is updated every CheckInterval, /readyz checks on every request.
+type HealthConfig struct {
+	CheckTimeout  time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" env-default:"2s"`
+	CheckInterval time.Duration `yaml:"check_interval" env:"HEALTH_CHECK_INTERVAL" env-default:"5s"`
+}
+
+// ErrorReportingConfig sends internal errors, panics and failed background jobs to Sentry, or an aggregator
+// speaking its protocol, at DSN, tagged with the env and Release. Reports wait in a queue of QueueSize and are
+// sent in the background, each may take up to Timeout. Without a DSN errors are only logged.
+type ErrorReportingConfig struct {
+	DSN       string        `yaml:"dsn" env:"ERROR_REPORTING_DSN"`
+	Release   string        `yaml:"release" env:"ERROR_REPORTING_RELEASE"`
+	Timeout   time.Duration `yaml:"timeout" env:"ERROR_REPORTING_TIMEOUT" env-default:"5s"`
+	QueueSize int           `yaml:"queue_size" env:"ERROR_REPORTING_QUEUE_SIZE" env-default:"100"`
+}
+
+// ModerationConfig sets up the content policy new posts, comments and messages are checked against. Text matching
+// any of the BannedWords regular expressions gets the BannedWordsVerdict. With a ClassifierURL the text is also scored
+// by an external classifier, scores reaching a threshold get its verdict, a zero threshold is never reached.
+// Verdicts are "allow", "flag" (published and queued for review), "limit" (shown to the author only and queued
+// for review) or "reject"; the strictest one wins.
+type ModerationConfig struct {
+	BannedWords        []string      `yaml:"banned_words" env:"MODERATION_BANNED_WORDS" env-separator:","`
+	BannedWordsVerdict string        `yaml:"banned_words_verdict" env:"MODERATION_BANNED_WORDS_VERDICT" env-default:"reject"`
+	ClassifierURL      string        `yaml:"classifier_url" env:"MODERATION_CLASSIFIER_URL"`
+	ClassifierTimeout  time.Duration `yaml:"classifier_timeout" env:"MODERATION_CLASSIFIER_TIMEOUT" env-default:"2s"`
+	FlagScore          float64       `yaml:"flag_score" env:"MODERATION_FLAG_SCORE" env-default:"0.5"`
+	LimitScore         float64       `yaml:"limit_score" env:"MODERATION_LIMIT_SCORE" env-default:"0.8"`
+	RejectScore        float64       `yaml:"reject_score" env:"MODERATION_REJECT_SCORE" env-default:"0.95"`
+}
+
+// DigestConfig controls the activity digest emails. Every Interval the users whose daily or weekly digest is due
+// are emailed their new followers, the top posts of the accounts they follow and their unread notifications,
+// with links to the web app at AppURL.
+type DigestConfig struct {
+	Interval time.Duration `yaml:"interval" env:"DIGEST_INTERVAL" env-default:"15m"`
+	AppURL   string        `yaml:"app_url" env:"DIGEST_APP_URL" env-default:"http://localhost:3000"`
+}
+
+// PushConfig controls push notifications to users who have no WebSocket connection open. Each push service
+// is enabled by its credentials: FCMCredentialsFile for FCM, APNsKeyFile for APNs and VAPIDPrivateKey for WebPush.
+type PushConfig struct {
+	// FCMCredentialsFile is the service account key file of the Firebase project
+	FCMCredentialsFile string `yaml:"fcm_credentials_file" env:"PUSH_FCM_CREDENTIALS_FILE"`
+	// APNsKeyFile is the .p8 token signing key, APNsTopic the bundle ID of the app
+	APNsKeyFile string `yaml:"apns_key_file" env:"PUSH_APNS_KEY_FILE"`
+	APNsKeyID   string `yaml:"apns_key_id" env:"PUSH_APNS_KEY_ID"`
+	APNsTeamID  string `yaml:"apns_team_id" env:"PUSH_APNS_TEAM_ID"`
+	APNsTopic   string `yaml:"apns_topic" env:"PUSH_APNS_TOPIC"`
+	APNsSandbox bool   `yaml:"apns_sandbox" env:"PUSH_APNS_SANDBOX" env-default:"false"`
+	// VAPIDPrivateKey is an unpadded base64url P-256 private key, VAPIDSubject a contact for the push services
+	VAPIDPrivateKey string `yaml:"vapid_private_key" env:"PUSH_VAPID_PRIVATE_KEY"`
+	VAPIDSubject    string `yaml:"vapid_subject" env:"PUSH_VAPID_SUBJECT" env-default:"mailto:admin@localhost"`
+}
+
+// NotificationsConfig controls the fan-out of notification events. Workers workers turn queued events into
+// notifications of the users they concern, each checking the queue every PollInterval. Notification streams
+// are woken up on the instance that created the notifications; with RedisPubSub on every instance.
+type NotificationsConfig struct {
+	Workers      int           `yaml:"workers" env:"NOTIFICATIONS_WORKERS" env-default:"4"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"NOTIFICATIONS_POLL_INTERVAL" env-default:"1s"`
+	RedisPubSub  bool          `yaml:"redis_pubsub" env:"NOTIFICATIONS_REDIS_PUBSUB" env-default:"false"`
+}
+
+// ChatConfig controls real-time chat delivery. Events reach the WebSocket connections of the instance that
+// produced them; with RedisPubSub they are relayed through Redis to the connections of every instance.
+// A user's typing indicators in a chat are sent at most once per TypingDebounce, and messages can be edited
+// within EditWindow of sending. Message events are stored in an outbox and published right away, those that
+// couldn't be published are retried every OutboxInterval.
+type ChatConfig struct {
+	RedisPubSub    bool          `yaml:"redis_pubsub" env:"CHAT_REDIS_PUBSUB" env-default:"false"`
+	TypingDebounce time.Duration `yaml:"typing_debounce" env:"CHAT_TYPING_DEBOUNCE" env-default:"3s"`
+	EditWindow     time.Duration `yaml:"edit_window" env:"CHAT_EDIT_WINDOW" env-default:"15m"`
+	OutboxInterval time.Duration `yaml:"outbox_interval" env:"CHAT_OUTBOX_INTERVAL" env-default:"1s"`
+}
+
+// MediaConfig controls uploaded media. Files are kept in LocalDir and served by the HTTP server under /media,
+// or in an S3 compatible bucket when S3Bucket is set.
+type MediaConfig struct {
+	LocalDir string `yaml:"local_dir" env:"MEDIA_LOCAL_DIR" env-default:"./data/media"`
+	// BaseURL is the public URL LocalDir is served at
+	BaseURL     string `yaml:"base_url" env:"MEDIA_BASE_URL" env-default:"http://localhost:8082/media"`
+	S3Endpoint  string `yaml:"s3_endpoint" env:"MEDIA_S3_ENDPOINT"`
+	S3Region    string `yaml:"s3_region" env:"MEDIA_S3_REGION" env-default:"us-east-1"`
+	S3Bucket    string `yaml:"s3_bucket" env:"MEDIA_S3_BUCKET"`
+	S3AccessKey string `yaml:"s3_access_key" env:"MEDIA_S3_ACCESS_KEY"`
+	S3SecretKey string `yaml:"s3_secret_key" env:"MEDIA_S3_SECRET_KEY"`
+	// S3PublicURL is where the bucket is readable from, e.g. a CDN, defaults to the bucket URL on S3Endpoint
+	S3PublicURL   string        `yaml:"s3_public_url" env:"MEDIA_S3_PUBLIC_URL"`
+	UploadTimeout time.Duration `yaml:"upload_timeout" env:"MEDIA_UPLOAD_TIMEOUT" env-default:"5m"`
+	// MaxVideoDuration and MaxVideoSize (in bytes) limit uploaded videos
+	MaxVideoDuration time.Duration `yaml:"max_video_duration" env:"MEDIA_MAX_VIDEO_DURATION" env-default:"5m"`
+	MaxVideoSize     int64         `yaml:"max_video_size" env:"MEDIA_MAX_VIDEO_SIZE" env-default:"104857600"`
+	// AttachmentTypes are the MIME types of files that can be sent in chat messages, MaxAttachmentSize (in bytes) limits them
+	AttachmentTypes   []string `yaml:"attachment_types" env:"MEDIA_ATTACHMENT_TYPES" env-separator:"," env-default:"image/jpeg,image/png,image/gif,video/mp4"`
+	MaxAttachmentSize int64    `yaml:"max_attachment_size" env:"MEDIA_MAX_ATTACHMENT_SIZE" env-default:"26214400"`
+}
+
+// PostsConfig controls background maintenance of posts.
+type PostsConfig struct {
+	// ExploreRefreshInterval is how often the explore ranking is rebuilt from posts published within ExploreWindow
+	ExploreRefreshInterval time.Duration `yaml:"explore_refresh_interval" env:"POSTS_EXPLORE_REFRESH_INTERVAL" env-default:"10m"`
+	ExploreWindow          time.Duration `yaml:"explore_window" env:"POSTS_EXPLORE_WINDOW" env-default:"72h"`
+}
+
+// CountersConfig controls the like, repost, comment and follow counters. With WriteBehind, increments are collected
+// in Redis and written to Postgres every FlushInterval, at most BatchSize rows per statement, otherwise each one is
+// written at once. Every ReconcileInterval the counters are recounted from the source tables to repair drift.
+type CountersConfig struct {
+	WriteBehind       bool          `yaml:"write_behind" env:"COUNTERS_WRITE_BEHIND" env-default:"true"`
+	FlushInterval     time.Duration `yaml:"flush_interval" env:"COUNTERS_FLUSH_INTERVAL" env-default:"2s"`
+	BatchSize         int           `yaml:"batch_size" env:"COUNTERS_BATCH_SIZE" env-default:"1000"`
+	ReconcileInterval time.Duration `yaml:"reconcile_interval" env:"COUNTERS_RECONCILE_INTERVAL" env-default:"1h"`
+}
+
+// TimelineConfig controls the cache of home timelines. New posts and reposts are pushed to the cached timelines
+// of the author's followers by Workers workers, QueueSize fan-outs may wait for them and each reads FanoutBatchSize
+// followers at a time. Accounts with CelebrityThreshold followers or more aren't pushed, their posts are merged in
+// when a timeline is read. A timeline keeps its MaxEntries newest entries and expires TTL after it was last read.
+// Disabled, timelines are read from Postgres.
+type TimelineConfig struct {
+	Enabled            bool          `yaml:"enabled" env:"TIMELINE_ENABLED" env-default:"true"`
+	MaxEntries         int           `yaml:"max_entries" env:"TIMELINE_MAX_ENTRIES" env-default:"800"`
+	TTL                time.Duration `yaml:"ttl" env:"TIMELINE_TTL" env-default:"72h"`
+	CelebrityThreshold int64         `yaml:"celebrity_threshold" env:"TIMELINE_CELEBRITY_THRESHOLD" env-default:"10000"`
+	Workers            int           `yaml:"workers" env:"TIMELINE_WORKERS" env-default:"8"`
+	QueueSize          int           `yaml:"queue_size" env:"TIMELINE_QUEUE_SIZE" env-default:"1024"`
+	FanoutBatchSize    int           `yaml:"fanout_batch_size" env:"TIMELINE_FANOUT_BATCH_SIZE" env-default:"1000"`
+}
+
+// FeedRankingConfig controls the ranking of home feeds for users with the feed_ranking feature flag, the others
+// read them newest first. Algorithm is "weighted" or "chronological" and ranks the CandidateSize newest entries,
+// older pages are read newest first. The weighted algorithm multiplies the recency of an entry, halved every
+// HalfLife, by its affinity (the user's likes and comments on the author within AffinityWindow) and its
+// engagement, each weighted.
+type FeedRankingConfig struct {
+	Algorithm        string        `yaml:"algorithm" env:"FEED_RANKING_ALGORITHM" env-default:"weighted"`
+	CandidateSize    int           `yaml:"candidate_size" env:"FEED_RANKING_CANDIDATE_SIZE" env-default:"200"`
+	HalfLife         time.Duration `yaml:"half_life" env:"FEED_RANKING_HALF_LIFE" env-default:"6h"`
+	AffinityWindow   time.Duration `yaml:"affinity_window" env:"FEED_RANKING_AFFINITY_WINDOW" env-default:"720h"`
+	AffinityWeight   float64       `yaml:"affinity_weight" env:"FEED_RANKING_AFFINITY_WEIGHT" env-default:"1"`
+	EngagementWeight float64       `yaml:"engagement_weight" env:"FEED_RANKING_ENGAGEMENT_WEIGHT" env-default:"0.5"`
+}
+
+// DeveloperConfig controls the apps third-party developers register for the public API. Each user may own MaxApps
+// apps, an app's access tokens are valid for TokenTTL and it may make RateLimit requests per minute unless its own
+// limit is set. The daily usage of an app is kept for UsageRetention.
+type DeveloperConfig struct {
+	MaxApps        int           `yaml:"max_apps" env:"DEVELOPER_MAX_APPS" env-default:"10"`
+	TokenTTL       time.Duration `yaml:"token_ttl" env:"DEVELOPER_TOKEN_TTL" env-default:"1h"`
+	RateLimit      int           `yaml:"rate_limit" env:"DEVELOPER_RATE_LIMIT" env-default:"300"`
+	UsageRetention time.Duration `yaml:"usage_retention" env:"DEVELOPER_USAGE_RETENTION" env-default:"720h"`
+}
+
+// FederationConfig enables ActivityPub federation with Mastodon-compatible servers. Public accounts are served as
+// actors under BaseURL and found with WebFinger as username@Domain; their public posts are delivered to remote
+// followers every DeliveryInterval, a failed delivery is retried up to MaxAttempts times with growing delays.
+// Remote actors are fetched again after ActorTTL, and inbound activities must be signed within SignatureSkew of
+// their Date. Servers of BlockedDomains are ignored. AllowInsecure allows plain HTTP and private addresses,
+// only for federating between local test servers.
+type FederationConfig struct {
+	Enabled           bool          `yaml:"enabled" env:"FEDERATION_ENABLED" env-default:"false"`
+	Domain            string        `yaml:"domain" env:"FEDERATION_DOMAIN" env-default:"localhost:8082"`
+	BaseURL           string        `yaml:"base_url" env:"FEDERATION_BASE_URL" env-default:"http://localhost:8082"`
+	BlockedDomains    []string      `yaml:"blocked_domains" env:"FEDERATION_BLOCKED_DOMAINS" env-separator:","`
+	PageSize          int           `yaml:"page_size" env:"FEDERATION_PAGE_SIZE" env-default:"20"`
+	ActorTTL          time.Duration `yaml:"actor_ttl" env:"FEDERATION_ACTOR_TTL" env-default:"24h"`
+	SignatureSkew     time.Duration `yaml:"signature_skew" env:"FEDERATION_SIGNATURE_SKEW" env-default:"1h"`
+	RequestTimeout    time.Duration `yaml:"request_timeout" env:"FEDERATION_REQUEST_TIMEOUT" env-default:"10s"`
+	DeliveryInterval  time.Duration `yaml:"delivery_interval" env:"FEDERATION_DELIVERY_INTERVAL" env-default:"10s"`
+	DeliveryBatchSize int           `yaml:"delivery_batch_size" env:"FEDERATION_DELIVERY_BATCH_SIZE" env-default:"100"`
+	MaxAttempts       int           `yaml:"max_attempts" env:"FEDERATION_MAX_ATTEMPTS" env-default:"8"`
+	AllowInsecure     bool          `yaml:"allow_insecure" env:"FEDERATION_ALLOW_INSECURE" env-default:"false"`
+}
+
+// SEOConfig controls what crawlers see of public content. Shared links of public posts point at /p/<post ID>,
+// a page with the Open Graph and Twitter Card metadata of the post that sends visitors on to the post in the web
+// app at AppURL. The sitemaps of public profiles and posts, at most SitemapMaxURLs of them, are generated every
+// SitemapInterval and served at BaseURL, the public URL of this server.
+type SEOConfig struct {
+	SiteName        string        `yaml:"site_name" env:"SEO_SITE_NAME" env-default:"Threads"`
+	AppURL          string        `yaml:"app_url" env:"SEO_APP_URL" env-default:"http://localhost:3000"`
+	BaseURL         string        `yaml:"base_url" env:"SEO_BASE_URL" env-default:"http://localhost:8082"`
+	SitemapInterval time.Duration `yaml:"sitemap_interval" env:"SEO_SITEMAP_INTERVAL" env-default:"6h"`
+	SitemapMaxURLs  int           `yaml:"sitemap_max_urls" env:"SEO_SITEMAP_MAX_URLS" env-default:"500000"`
+}
+
+// CaptchaConfig enables CAPTCHA verification on registration and on logins after repeated failures.
+// An empty provider disables it, e.g. for local development.
+type CaptchaConfig struct {
+	// Provider is one of "hcaptcha", "recaptcha" or "turnstile"
+	Provider string        `yaml:"provider" env:"CAPTCHA_PROVIDER"`
+	Secret   string        `yaml:"secret" env:"CAPTCHA_SECRET"`
+	Timeout  time.Duration `yaml:"timeout" env:"CAPTCHA_TIMEOUT" env-default:"5s"`
+	// LoginFailures is how many failed logins within FailureWindow make a CAPTCHA required for the login name
+	LoginFailures int           `yaml:"login_failures" env:"CAPTCHA_LOGIN_FAILURES" env-default:"3"`
+	FailureWindow time.Duration `yaml:"failure_window" env:"CAPTCHA_FAILURE_WINDOW" env-default:"15m"`
+}
+
+// GeoIPConfig points to a MaxMind GeoIP2/GeoLite2 City database used to locate sessions.
+// Without a database session locations stay empty and country changes are not detected.
+type GeoIPConfig struct {
+	DatabasePath string `yaml:"database_path" env:"GEOIP_DATABASE_PATH"`
+}
+
+// SessionConfig controls refresh token lifetimes. With sliding expiration every refresh extends the session
+// by RefreshTokenTTL, but never past AbsoluteLifetime after login (0 means no absolute limit).
+type SessionConfig struct {
+	RefreshTokenTTL   time.Duration `yaml:"refresh_token_ttl" env:"SESSION_REFRESH_TOKEN_TTL" env-default:"360h"`
+	SlidingExpiration bool          `yaml:"sliding_expiration" env:"SESSION_SLIDING_EXPIRATION" env-default:"true"`
+	AbsoluteLifetime  time.Duration `yaml:"absolute_lifetime" env:"SESSION_ABSOLUTE_LIFETIME" env-default:"720h"`
+}
+
+// RegistrationConfig holds the rules new accounts are checked against.
+type RegistrationConfig struct {
+	// ReservedUsernames can't be registered by anyone, compared case-insensitively
+	ReservedUsernames []string `yaml:"reserved_usernames" env:"REGISTRATION_RESERVED_USERNAMES" env-separator:"," env-default:"admin,administrator,root,support,system,moderator,threads,api,help,security"`
+	// DisposableDomainsPath is a file with one blocked email domain per line, loaded at startup
+	DisposableDomainsPath string `yaml:"disposable_domains_path" env:"REGISTRATION_DISPOSABLE_DOMAINS_PATH"`
+	// DisposableDomains are blocked in addition to the ones from the file
+	DisposableDomains []string `yaml:"disposable_domains" env:"REGISTRATION_DISPOSABLE_DOMAINS" env-separator:","`
+}
+
+// LoginSecurityConfig controls new device detection: logins from an IP and User-Agent the user never
+// signed in from are flagged and reported by email, and optionally have to be confirmed by email first.
+type LoginSecurityConfig struct {
+	NewDeviceAlerts     bool `yaml:"new_device_alerts" env:"LOGIN_NEW_DEVICE_ALERTS" env-default:"true"`
+	RequireConfirmation bool `yaml:"require_confirmation" env:"LOGIN_REQUIRE_CONFIRMATION" env-default:"false"`
+	// ConfirmationURL is the frontend page the login confirmation token is appended to as ?token=
+	ConfirmationURL string        `yaml:"confirmation_url" env:"LOGIN_CONFIRMATION_URL" env-default:"http://localhost:3000/login/confirm"`
+	ConfirmationTTL time.Duration `yaml:"confirmation_ttl" env:"LOGIN_CONFIRMATION_TTL" env-default:"15m"`
+}
+
+// AdminConfig lists users that are granted the admin role on startup, to bootstrap the first admins.
+type AdminConfig struct {
+	UserIDs []string `yaml:"user_ids" env:"ADMIN_USER_IDS" env-separator:","`
+}
+
+// AccountConfig controls account deletion: deleted accounts are anonymized after the grace period.
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" env:"ACCOUNT_DELETION_GRACE_PERIOD" env-default:"720h"`
+	ErasureInterval     time.Duration `yaml:"erasure_interval" env:"ACCOUNT_ERASURE_INTERVAL" env-default:"1h"`
+}
+
+// DeletionConfig controls deleted posts, comments and messages: their authors can restore them within
+// UndeleteWindow, afterwards they are removed for good by a job running every PurgeInterval.
+type DeletionConfig struct {
+	UndeleteWindow time.Duration `yaml:"undelete_window" env:"DELETION_UNDELETE_WINDOW" env-default:"168h"`
+	PurgeInterval  time.Duration `yaml:"purge_interval" env:"DELETION_PURGE_INTERVAL" env-default:"1h"`
+}
+
+// ExportConfig controls data exports. Archives are kept in an S3 bucket if S3Bucket is set, on the endpoint and
+// with the credentials of the media storage; otherwise in LocalDir, served at BaseURL to links signed with
+// SigningKey (a random key if empty, so links only work on the instance that signed them until it restarts).
+// Download links are valid for LinkTTL, archives are deleted after Retention.
+type ExportConfig struct {
+	LocalDir   string        `yaml:"local_dir" env:"EXPORT_LOCAL_DIR" env-default:"./data/exports"`
+	BaseURL    string        `yaml:"base_url" env:"EXPORT_BASE_URL" env-default:"http://localhost:8082/exports"`
+	SigningKey string        `yaml:"signing_key" env:"EXPORT_SIGNING_KEY"`
+	S3Bucket   string        `yaml:"s3_bucket" env:"EXPORT_S3_BUCKET"`
+	LinkTTL    time.Duration `yaml:"link_ttl" env:"EXPORT_LINK_TTL" env-default:"24h"`
+	Retention  time.Duration `yaml:"retention" env:"EXPORT_RETENTION" env-default:"168h"`
+	// exports are prepared every Interval, one that is still processing after ProcessTimeout is started again
+	Interval       time.Duration `yaml:"interval" env:"EXPORT_INTERVAL" env-default:"1m"`
+	ProcessTimeout time.Duration `yaml:"process_timeout" env:"EXPORT_PROCESS_TIMEOUT" env-default:"30m"`
+	PurgeInterval  time.Duration `yaml:"purge_interval" env:"EXPORT_PURGE_INTERVAL" env-default:"1h"`
+}
+
+// ImportConfig controls imports of archives exported from other platforms. Archives up to MaxArchiveSize bytes are
+// accepted, with up to MaxFollows follows and MaxPosts posts, the rest is left out. Imports are applied every
+// Interval in batches of BatchSize entries; one whose progress wasn't saved for ProcessTimeout is resumed.
+type ImportConfig struct {
+	MaxArchiveSize int64         `yaml:"max_archive_size" env:"IMPORT_MAX_ARCHIVE_SIZE" env-default:"268435456"`
+	MaxFollows     int           `yaml:"max_follows" env:"IMPORT_MAX_FOLLOWS" env-default:"5000"`
+	MaxPosts       int           `yaml:"max_posts" env:"IMPORT_MAX_POSTS" env-default:"10000"`
+	BatchSize      int           `yaml:"batch_size" env:"IMPORT_BATCH_SIZE" env-default:"100"`
+	Interval       time.Duration `yaml:"interval" env:"IMPORT_INTERVAL" env-default:"1m"`
+	ProcessTimeout time.Duration `yaml:"process_timeout" env:"IMPORT_PROCESS_TIMEOUT" env-default:"10m"`
+}
+
+// FeatureFlagsConfig controls gradually rolled out features. Flags are defined in the config file; with Redis
+// enabled, flags stored in Redis override them and are cached for CacheTTL. Undefined flags are off.
+type FeatureFlagsConfig struct {
+	Flags    map[string]FeatureFlag `yaml:"flags"`
+	Redis    bool                   `yaml:"redis" env:"FEATURE_FLAGS_REDIS" env-default:"false"`
+	CacheTTL time.Duration          `yaml:"cache_ttl" env:"FEATURE_FLAGS_CACHE_TTL" env-default:"30s"`
+}
+
+// FeatureFlag turns a feature on for everyone if Enabled, otherwise for the Users (IDs) and Percentage percent of
+// the other users.
+type FeatureFlag struct {
+	Enabled    bool     `yaml:"enabled"`
+	Users      []string `yaml:"users"`
+	Percentage int      `yaml:"percentage"`
+}
+
+// MaintenanceConfig controls the cleanup jobs. They run on one instance at a time, once per interval across all
+// instances; every instance checks whether a job is due every PollInterval.
+type MaintenanceConfig struct {
+	PollInterval           time.Duration `yaml:"poll_interval" env:"MAINTENANCE_POLL_INTERVAL" env-default:"1m"`
+	SessionCleanupInterval time.Duration `yaml:"session_cleanup_interval" env:"MAINTENANCE_SESSION_CLEANUP_INTERVAL" env-default:"1h"`
+	// SessionRetention keeps expired sessions this long, a refresh with such a session is still told it expired
+	SessionRetention   time.Duration `yaml:"session_retention" env:"MAINTENANCE_SESSION_RETENTION" env-default:"24h"`
+	TokenPruneInterval time.Duration `yaml:"token_prune_interval" env:"MAINTENANCE_TOKEN_PRUNE_INTERVAL" env-default:"1h"`
+}
+
+// EmailConfig configures outgoing mail. With an empty SMTPHost emails are only logged.
+type EmailConfig struct {
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT" env-default:"587"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	From         string `yaml:"from" env:"EMAIL_FROM" env-default:"no-reply@threads.local"`
+	// ConfirmationURL is the frontend page the confirmation token is appended to as ?token=
+	ConfirmationURL string        `yaml:"confirmation_url" env:"EMAIL_CONFIRMATION_URL" env-default:"http://localhost:3000/email/confirm"`
+	ConfirmationTTL time.Duration `yaml:"confirmation_ttl" env:"EMAIL_CONFIRMATION_TTL" env-default:"24h"`
+}
+
+// PasswordConfig selects the password hashing algorithm. Hashes made with the other algorithm
+// are still accepted and transparently re-hashed on the next successful login.
+type PasswordConfig struct {
+	Algorithm         string `yaml:"algorithm" env:"PASSWORD_ALGORITHM" env-default:"bcrypt"`
+	BcryptCost        int    `yaml:"bcrypt_cost" env:"PASSWORD_BCRYPT_COST" env-default:"10"`
+	Argon2Memory      uint32 `yaml:"argon2_memory" env:"PASSWORD_ARGON2_MEMORY" env-default:"65536"`
+	Argon2Iterations  uint32 `yaml:"argon2_iterations" env:"PASSWORD_ARGON2_ITERATIONS" env-default:"3"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env:"PASSWORD_ARGON2_PARALLELISM" env-default:"2"`
+	Argon2SaltLength  uint32 `yaml:"argon2_salt_length" env:"PASSWORD_ARGON2_SALT_LENGTH" env-default:"16"`
+	Argon2KeyLength   uint32 `yaml:"argon2_key_length" env:"PASSWORD_ARGON2_KEY_LENGTH" env-default:"32"`
 }
 
 type RedisConfig struct {
@@ -29,42 +409,354 @@ type RedisConfig struct {
 type RateLimiterConfig struct {
 	Limit  int           `yaml:"limit" env:"RATE_LIMITER_LIMIT" env-default:"100"`
 	Window time.Duration `yaml:"window" env:"RATE_LIMITER_WINDOW" env-default:"1m"`
+	// Routes are limited per HTTP route pattern, e.g. "POST /posts", and Methods per full gRPC method name
+	Routes  map[string]RateLimitRule `yaml:"routes"`
+	Methods map[string]RateLimitRule `yaml:"methods"`
+}
+
+// RateLimitRule allows Requests requests per Window to each caller, up to Burst of them at once (all of them if 0).
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+	Burst    int           `yaml:"burst"`
 }
 
 type Server struct {
 	Port        int           `yaml:"port" env:"SERVER_PORT" env-default:"8082"`
 	Mode        string        `yaml:"mode" env:"SERVER_MODE" env-default:"debug"`
 	Host        string        `yaml:"host" env:"SERVER_HOST" env-default:"localhost"`
-	Timeout     time.Duration `yaml:"timeout" env:"SERVER_TIMEOUT" env-default:"15"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" env-default:"60"`
+	Timeout     time.Duration `yaml:"timeout" env:"SERVER_TIMEOUT" env-default:"15s"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" env-default:"60s"`
+	TLS         TLSConfig     `yaml:"tls" env-prefix:"SERVER_TLS_"`
+	// MaxBodySize limits request bodies (in bytes), BodyLimits overrides it per route, "<method> <route path>"
+	// like the rate limiter routes; the media upload routes default to the media size limits
+	MaxBodySize int64             `yaml:"max_body_size" env:"SERVER_MAX_BODY_SIZE" env-default:"1048576"`
+	BodyLimits  map[string]int64  `yaml:"body_limits"`
+	Compression CompressionConfig `yaml:"compression" env-prefix:"SERVER_COMPRESSION_"`
+}
+
+// CompressionConfig gzips the responses of clients accepting it when Enabled, at Level (1 to 9, -1 for the
+// default) and only once they are MinLength bytes long. Streams, media files and metrics are never compressed.
+type CompressionConfig struct {
+	Enabled   bool `yaml:"enabled" env:"ENABLED" env-default:"true"`
+	Level     int  `yaml:"level" env:"LEVEL" env-default:"-1"`
+	MinLength int  `yaml:"min_length" env:"MIN_LENGTH" env-default:"1024"`
+}
+
+// APIConfig is the versioning of the HTTP API served under /api/v<N>. The unversioned routes of before /api/v1
+// and deprecated versions keep being served, announcing their removal with the Deprecation and Sunset headers.
+type APIConfig struct {
+	// LegacyRoutes serves the routes at their unversioned paths too, as v1 or the version of the API-Version header
+	LegacyRoutes bool        `yaml:"legacy_routes" env:"API_LEGACY_ROUTES" env-default:"true"`
+	Legacy       Deprecation `yaml:"legacy" env-prefix:"API_LEGACY_"`
+	// Deprecated announces the removal of whole versions, keyed by the major version
+	Deprecated map[int]Deprecation `yaml:"deprecated"`
+}
+
+// Deprecation announces the removal of routes. Dates are written like 2027-04-18, unquoted in YAML.
+type Deprecation struct {
+	Since time.Time `yaml:"since" env:"SINCE" env-layout:"2006-01-02" env-default:"2026-10-18"`
+	// Sunset is when the routes stop being served, none is announced when empty
+	Sunset time.Time `yaml:"sunset" env:"SUNSET" env-layout:"2006-01-02"`
+	// Link is the migration guide
+	Link string `yaml:"link" env:"LINK"`
 }
 
 type GrpcServer struct {
-	Host string `yaml:"host" env:"GRPC_HOST" env-default:"0.0.0.0"`
-	Port int    `yaml:"port" env:"GRPC_PORT" env-default:"50052"`
+	Host string    `yaml:"host" env:"GRPC_HOST" env-default:"0.0.0.0"`
+	Port int       `yaml:"port" env:"GRPC_PORT" env-default:"50052"`
+	TLS  TLSConfig `yaml:"tls" env-prefix:"GRPC_TLS_"`
+	// MaxRecvMsgSize limits received messages (in bytes), bigger ones fail with ResourceExhausted
+	MaxRecvMsgSize int `yaml:"max_recv_msg_size" env:"GRPC_MAX_RECV_MSG_SIZE" env-default:"4194304"`
+}
+
+// DebugServer serves pprof profiles, expvar variables and goroutine dumps on a separate listener when Enabled.
+// With a Token requests must send it as a bearer token; either way Host shouldn't be reachable from the internet.
+type DebugServer struct {
+	Enabled bool   `yaml:"enabled" env:"DEBUG_ENABLED"`
+	Host    string `yaml:"host" env:"DEBUG_HOST" env-default:"localhost"`
+	Port    int    `yaml:"port" env:"DEBUG_PORT" env-default:"6060"`
+	Token   string `yaml:"token" env:"DEBUG_TOKEN"`
+}
+
+// TLSConfig turns on TLS for a server when CertFile and KeyFile are set, plain connections are served otherwise.
+// The files are checked every ReloadInterval and re-read when they change, so rotated certificates are used
+// without a restart.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" env:"CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"KEY_FILE"`
+	// ClientCAFile requires clients to present a certificate signed by one of its CAs (mutual TLS)
+	ClientCAFile   string        `yaml:"client_ca_file" env:"CLIENT_CA_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"RELOAD_INTERVAL" env-default:"1m"`
+}
+
+// Enabled reports whether TLS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
 }
 
 type JWTConfig struct {
-	Secret            string `yaml:"secret"`
-	ExpirationMinutes int    `yaml:"expiration_minutes" default:"15"`
+	Secret         string        `yaml:"secret" env:"JWT_SECRET"`
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl" env:"JWT_ACCESS_TOKEN_TTL" env-default:"15m"`
+	// Issuer and Audience are put into "iss"/"aud" and required to match on verification
+	Issuer   string `yaml:"issuer" env:"JWT_ISSUER" env-default:"threads-auth"`
+	Audience string `yaml:"audience" env:"JWT_AUDIENCE" env-default:"threads"`
+	// Algorithm is HS256 (shared secret) or RS256/EdDSA (key pair loaded from PrivateKeyPath)
+	Algorithm      string         `yaml:"algorithm" env:"JWT_ALGORITHM" env-default:"HS256"`
+	KeyID          string         `yaml:"key_id" env:"JWT_KEY_ID"`
+	PrivateKeyPath string         `yaml:"private_key_path" env:"JWT_PRIVATE_KEY_PATH"`
+	PublicKeys     []JWTPublicKey `yaml:"public_keys"`
+	// KeyEncryptionKey is the base64 encoded 32 byte AES-256 key the signing keys are encrypted with (AES-GCM)
+	// before they are stored in the database, required for RS256/EdDSA
+	KeyEncryptionKey string `yaml:"key_encryption_key" env:"JWT_KEY_ENCRYPTION_KEY"`
+	// RotationInterval generates a new signing key periodically (asymmetric algorithms only), 0 disables rotation.
+	// The keys are stored in the database and shared by every instance, one instance rotates per interval
+	RotationInterval time.Duration `yaml:"rotation_interval" env:"JWT_ROTATION_INTERVAL" env-default:"0"`
+	// KeySyncInterval is how often instances load the stored signing keys, a rotated key signs after two intervals
+	KeySyncInterval time.Duration `yaml:"key_sync_interval" env:"JWT_KEY_SYNC_INTERVAL" env-default:"1m"`
+}
+
+// JWTPublicKey is an extra public key accepted for verification, e.g. the previous key during rotation.
+type JWTPublicKey struct {
+	KeyID string `yaml:"key_id"`
+	Path  string `yaml:"path"`
 }
 
-// postgres config
+// PostgresConfig is the database connection. A URL (DATABASE_URL) takes precedence over the separate fields.
 type PostgresConfig struct {
-	Host     string `yaml:"host" default:"localhost"`
-	Port     int    `yaml:"port" default:"5432"`
-	Username string `yaml:"username" default:"postgres"`
-	Password string `yaml:"password" default:"postgres"`
-	Name     string `yaml:"name" default:"myappdb"`
+	URL      string `yaml:"url" env:"DATABASE_URL"`
+	Host     string `yaml:"host" env:"DB_HOST" env-default:"localhost"`
+	Port     int    `yaml:"port" env:"DB_PORT" env-default:"5432"`
+	Username string `yaml:"username" env:"DB_USER" env-default:"postgres"`
+	Password string `yaml:"password" env:"DB_PASSWORD" env-default:"postgres"`
+	Name     string `yaml:"name" env:"DB_NAME" env-default:"myappdb"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" env-default:"disable"`
+	// QueryTimeout cancels statements running longer, 0 disables it; migrations aren't limited
+	QueryTimeout time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT" env-default:"5s"`
+	// QueryExecMode is cache_statement (statements are prepared once per connection), or one of cache_describe,
+	// describe_exec, exec and simple_protocol behind PgBouncer in transaction mode
+	QueryExecMode          string `yaml:"query_exec_mode" env:"DB_QUERY_EXEC_MODE" env-default:"cache_statement"`
+	StatementCacheCapacity int    `yaml:"statement_cache_capacity" env:"DB_STATEMENT_CACHE_CAPACITY" env-default:"512"`
+	// ReplicaURLs are the connection URLs of read replicas, reads that tolerate replication lag (feeds, lists,
+	// search) are spread over them; without replicas everything goes to the primary
+	ReplicaURLs []string `yaml:"replica_urls" env:"DB_REPLICA_URLS" env-separator:","`
+	// the pool of the primary and of each replica, 0 keeps the pgx default
+	MaxConns          int32         `yaml:"max_conns" env:"DB_MAX_CONNS" env-default:"0"`
+	MinConns          int32         `yaml:"min_conns" env:"DB_MIN_CONNS" env-default:"0"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" env-default:"1h"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" env-default:"30m"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD" env-default:"1m"`
+	// PoolStatsInterval is how often pools whose acquisitions waited for a connection are logged
+	PoolStatsInterval time.Duration `yaml:"pool_stats_interval" env:"DB_POOL_STATS_INTERVAL" env-default:"1m"`
+	// RetryAttempts is how many times a statement failing with a transient error (serialization failure, deadlock,
+	// connection lost before it was sent) is run, 1 disables retries; the backoff doubles up to RetryMaxBackoff
+	RetryAttempts   int           `yaml:"retry_attempts" env:"DB_RETRY_ATTEMPTS" env-default:"3"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff" env:"DB_RETRY_BACKOFF" env-default:"50ms"`
+	RetryMaxBackoff time.Duration `yaml:"retry_max_backoff" env:"DB_RETRY_MAX_BACKOFF" env-default:"1s"`
+	// BreakerThreshold consecutive connection failures open the circuit breaker of a pool, its calls then fail
+	// fast until a probe after BreakerOpenTimeout succeeds
+	BreakerThreshold   int           `yaml:"breaker_threshold" env:"DB_BREAKER_THRESHOLD" env-default:"5"`
+	BreakerOpenTimeout time.Duration `yaml:"breaker_open_timeout" env:"DB_BREAKER_OPEN_TIMEOUT" env-default:"10s"`
 }
 
+// DSN returns the connection URL, the credentials are escaped so they may contain any character.
 func (cfg *PostgresConfig) DSN() string {
-	return "postgres://" +
-		cfg.Username + ":" +
-		cfg.Password + "@" +
-		cfg.Host + ":" +
-		strconv.Itoa(cfg.Port) + "/" +
-		cfg.Name + "?sslmode=disable"
+	if cfg.URL != "" {
+		return cfg.URL
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Name,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// Validate reports every setting the application can't start with.
+func (cfg *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if cfg.PostgresConfig.URL != "" {
+		u, err := url.Parse(cfg.PostgresConfig.URL)
+		check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql"),
+			"database.url must be a postgres:// URL")
+	} else {
+		check(cfg.PostgresConfig.Host != "", "database.host is required")
+		check(validPort(cfg.PostgresConfig.Port), "database.port %d is not a valid port", cfg.PostgresConfig.Port)
+		check(cfg.PostgresConfig.Name != "", "database.name is required")
+	}
+	check(validPort(cfg.Server.Port), "server.port %d is not a valid port", cfg.Server.Port)
+	check(validPort(cfg.GrpcServer.Port), "grpc.port %d is not a valid port", cfg.GrpcServer.Port)
+	check(cfg.Server.Timeout > 0, "server.timeout must be positive")
+	check(cfg.Server.MaxBodySize > 0, "server.max_body_size must be positive")
+	for route, limit := range cfg.Server.BodyLimits {
+		check(limit > 0, "server.body_limits[%q] must be positive", route)
+	}
+	check(cfg.Server.Compression.Level == -1 || cfg.Server.Compression.Level >= 1 && cfg.Server.Compression.Level <= 9,
+		"server.compression.level must be between 1 and 9, or -1, got %d", cfg.Server.Compression.Level)
+	check(cfg.GrpcServer.MaxRecvMsgSize > 0, "grpc.max_recv_msg_size must be positive")
+	if cfg.DebugServer.Enabled {
+		check(validPort(cfg.DebugServer.Port), "debug.port %d is not a valid port", cfg.DebugServer.Port)
+		check(cfg.DebugServer.Port != cfg.Server.Port && cfg.DebugServer.Port != cfg.GrpcServer.Port,
+			"debug.port %d must differ from server.port and grpc.port", cfg.DebugServer.Port)
+	}
+	check(cfg.RedisConfig.Addr != "", "redis.addr is required")
+
+	switch cfg.JWTConfig.Algorithm {
+	case "HS256":
+		check(cfg.JWTConfig.Secret != "", "jwt.secret is required for HS256")
+	case "RS256", "EdDSA":
+		check(cfg.JWTConfig.PrivateKeyPath != "", "jwt.private_key_path is required for %s", cfg.JWTConfig.Algorithm)
+		check(cfg.JWTConfig.KeyEncryptionKey != "", "jwt.key_encryption_key is required for %s", cfg.JWTConfig.Algorithm)
+	default:
+		check(false, "jwt.algorithm must be HS256, RS256 or EdDSA, got %q", cfg.JWTConfig.Algorithm)
+	}
+	check(cfg.JWTConfig.AccessTokenTTL > 0, "jwt.access_token_ttl must be positive")
+	check(cfg.JWTConfig.RotationInterval >= 0, "jwt.rotation_interval must not be negative")
+	check(cfg.JWTConfig.KeySyncInterval > 0, "jwt.key_sync_interval must be positive")
+	check(cfg.PostgresConfig.QueryTimeout >= 0, "database.query_timeout must not be negative")
+	switch cfg.PostgresConfig.QueryExecMode {
+	case "cache_statement", "cache_describe", "describe_exec", "exec", "simple_protocol":
+	default:
+		check(false, "database.query_exec_mode must be cache_statement, cache_describe, describe_exec, exec or simple_protocol")
+	}
+	check(cfg.PostgresConfig.StatementCacheCapacity > 0, "database.statement_cache_capacity must be positive")
+	check(cfg.PostgresConfig.MaxConns >= 0 && cfg.PostgresConfig.MinConns >= 0, "database.max_conns and database.min_conns must not be negative")
+	check(cfg.PostgresConfig.MaxConns == 0 || cfg.PostgresConfig.MinConns <= cfg.PostgresConfig.MaxConns,
+		"database.min_conns must not exceed database.max_conns")
+	check(cfg.PostgresConfig.MaxConnLifetime >= 0 && cfg.PostgresConfig.MaxConnIdleTime >= 0,
+		"database.max_conn_lifetime and database.max_conn_idle_time must not be negative")
+	check(cfg.PostgresConfig.RetryAttempts >= 1, "database.retry_attempts must be at least 1")
+	check(cfg.PostgresConfig.RetryBackoff <= cfg.PostgresConfig.RetryMaxBackoff, "database.retry_backoff must not exceed database.retry_max_backoff")
+	check(cfg.PostgresConfig.BreakerThreshold >= 1, "database.breaker_threshold must be at least 1")
+	for i, replicaURL := range cfg.PostgresConfig.ReplicaURLs {
+		u, err := url.Parse(replicaURL)
+		check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql"),
+			"database.replica_urls[%d] must be a postgres:// URL", i)
+	}
+	check(cfg.CountersConfig.BatchSize > 0, "counters.batch_size must be positive")
+	if cfg.TimelineConfig.Enabled {
+		check(cfg.TimelineConfig.MaxEntries > 0, "timeline.max_entries must be positive")
+		check(cfg.TimelineConfig.TTL > 0, "timeline.ttl must be positive")
+		check(cfg.TimelineConfig.CelebrityThreshold > 0, "timeline.celebrity_threshold must be positive")
+		check(cfg.TimelineConfig.Workers > 0, "timeline.workers must be positive")
+		check(cfg.TimelineConfig.QueueSize >= 0, "timeline.queue_size must not be negative")
+		check(cfg.TimelineConfig.FanoutBatchSize > 0, "timeline.fanout_batch_size must be positive")
+	}
+	check(cfg.FeedRankingConfig.Algorithm == "weighted" || cfg.FeedRankingConfig.Algorithm == "chronological",
+		"feed_ranking.algorithm must be weighted or chronological")
+	check(cfg.FeedRankingConfig.CandidateSize > 0, "feed_ranking.candidate_size must be positive")
+	check(cfg.FeedRankingConfig.HalfLife > 0, "feed_ranking.half_life must be positive")
+	check(cfg.FeedRankingConfig.AffinityWindow > 0, "feed_ranking.affinity_window must be positive")
+	check(cfg.FeedRankingConfig.AffinityWeight >= 0 && cfg.FeedRankingConfig.EngagementWeight >= 0,
+		"feed_ranking weights must not be negative")
+	check(cfg.DeveloperConfig.MaxApps >= 0, "developer.max_apps must not be negative")
+	check(cfg.DeveloperConfig.TokenTTL > 0, "developer.token_ttl must be positive")
+	check(cfg.DeveloperConfig.RateLimit > 0, "developer.rate_limit must be positive")
+	check(cfg.DeveloperConfig.UsageRetention >= 24*time.Hour, "developer.usage_retention must be at least a day")
+	if fed := cfg.FederationConfig; fed.Enabled {
+		check(fed.Domain != "", "federation.domain is required")
+		base, err := url.Parse(fed.BaseURL)
+		check(err == nil && (base.Scheme == "https" || base.Scheme == "http" && fed.AllowInsecure) && base.Host != "",
+			"federation.base_url must be an https URL")
+		check(fed.PageSize > 0 && fed.DeliveryBatchSize > 0 && fed.MaxAttempts > 0,
+			"federation.page_size, federation.delivery_batch_size and federation.max_attempts must be positive")
+		check(fed.ActorTTL > 0 && fed.SignatureSkew > 0 && fed.RequestTimeout > 0 && fed.DeliveryInterval > 0,
+			"federation durations must be positive")
+	}
+	for name, rawURL := range map[string]string{"seo.app_url": cfg.SEOConfig.AppURL, "seo.base_url": cfg.SEOConfig.BaseURL} {
+		u, err := url.Parse(rawURL)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "", "%s must be an http(s) URL", name)
+	}
+	check(cfg.SEOConfig.SitemapMaxURLs > 0, "seo.sitemap_max_urls must be positive")
+	check(cfg.MaintenanceConfig.SessionRetention >= 0, "maintenance.session_retention must not be negative")
+	check(cfg.ExportConfig.S3Bucket == "" || cfg.MediaConfig.S3Endpoint != "", "export.s3_bucket requires media.s3_endpoint")
+	check(cfg.ImportConfig.MaxArchiveSize > 0, "import.max_archive_size must be positive")
+	check(cfg.ImportConfig.MaxFollows >= 0 && cfg.ImportConfig.MaxPosts >= 0, "import.max_follows and import.max_posts must not be negative")
+	check(cfg.ImportConfig.BatchSize > 0, "import.batch_size must be positive")
+
+	// background jobs tick at these intervals
+	for name, interval := range map[string]time.Duration{
+		"chat.outbox_interval":                 cfg.ChatConfig.OutboxInterval,
+		"notifications.poll_interval":          cfg.NotificationsConfig.PollInterval,
+		"digest.interval":                      cfg.DigestConfig.Interval,
+		"account.erasure_interval":             cfg.AccountConfig.ErasureInterval,
+		"counters.flush_interval":              cfg.CountersConfig.FlushInterval,
+		"counters.reconcile_interval":          cfg.CountersConfig.ReconcileInterval,
+		"posts.explore_refresh_interval":       cfg.PostsConfig.ExploreRefreshInterval,
+		"health.check_interval":                cfg.HealthConfig.CheckInterval,
+		"events.relay_interval":                cfg.EventsConfig.RelayInterval,
+		"server.tls.reload_interval":           cfg.Server.TLS.ReloadInterval,
+		"grpc.tls.reload_interval":             cfg.GrpcServer.TLS.ReloadInterval,
+		"deletion.undelete_window":             cfg.DeletionConfig.UndeleteWindow,
+		"deletion.purge_interval":              cfg.DeletionConfig.PurgeInterval,
+		"export.link_ttl":                      cfg.ExportConfig.LinkTTL,
+		"export.retention":                     cfg.ExportConfig.Retention,
+		"export.interval":                      cfg.ExportConfig.Interval,
+		"export.process_timeout":               cfg.ExportConfig.ProcessTimeout,
+		"export.purge_interval":                cfg.ExportConfig.PurgeInterval,
+		"import.interval":                      cfg.ImportConfig.Interval,
+		"import.process_timeout":               cfg.ImportConfig.ProcessTimeout,
+		"seo.sitemap_interval":                 cfg.SEOConfig.SitemapInterval,
+		"maintenance.poll_interval":            cfg.MaintenanceConfig.PollInterval,
+		"database.health_check_period":         cfg.PostgresConfig.HealthCheckPeriod,
+		"database.pool_stats_interval":         cfg.PostgresConfig.PoolStatsInterval,
+		"database.retry_backoff":               cfg.PostgresConfig.RetryBackoff,
+		"database.breaker_open_timeout":        cfg.PostgresConfig.BreakerOpenTimeout,
+		"maintenance.session_cleanup_interval": cfg.MaintenanceConfig.SessionCleanupInterval,
+		"maintenance.token_prune_interval":     cfg.MaintenanceConfig.TokenPruneInterval,
+	} {
+		check(interval > 0, "%s must be positive", name)
+	}
+
+	switch strings.ToLower(cfg.ModerationConfig.BannedWordsVerdict) {
+	case "allow", "flag", "limit", "reject":
+	default:
+		check(false, "moderation.banned_words_verdict must be allow, flag, limit or reject")
+	}
+	for name, tlsCfg := range map[string]TLSConfig{"server.tls": cfg.Server.TLS, "grpc.tls": cfg.GrpcServer.TLS} {
+		check((tlsCfg.CertFile == "") == (tlsCfg.KeyFile == ""), "%s.cert_file and %s.key_file must be set together", name, name)
+		check(tlsCfg.ClientCAFile == "" || tlsCfg.Enabled(), "%s.client_ca_file requires %s.cert_file and %s.key_file", name, name, name)
+	}
+	for scope, rules := range map[string]map[string]RateLimitRule{"rate_limiter.routes": cfg.RateLimiterConfig.Routes, "rate_limiter.methods": cfg.RateLimiterConfig.Methods} {
+		for name, rule := range rules {
+			check(rule.Requests > 0 && rule.Window > 0, "%s[%q] needs positive requests and window", scope, name)
+			check(rule.Burst >= 0, "%s[%q].burst must not be negative", scope, name)
+		}
+	}
+	check(cfg.APIConfig.Legacy.Sunset.IsZero() || cfg.APIConfig.Legacy.Sunset.After(cfg.APIConfig.Legacy.Since),
+		"api.legacy.sunset must be after api.legacy.since")
+	for version, d := range cfg.APIConfig.Deprecated {
+		check(!d.Since.IsZero(), "api.deprecated[%d].since is required", version)
+		check(d.Sunset.IsZero() || d.Sunset.After(d.Since), "api.deprecated[%d].sunset must be after its since", version)
+	}
+	for name, flag := range cfg.FeatureFlagsConfig.Flags {
+		check(flag.Percentage >= 0 && flag.Percentage <= 100, "feature_flags.flags[%q].percentage must be between 0 and 100", name)
+	}
+	if cfg.ErrorReportingConfig.DSN != "" {
+		u, err := url.Parse(cfg.ErrorReportingConfig.DSN)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.User != nil,
+			"error_reporting.dsn must be an http(s) URL with a key, like https://<key>@<host>/<project ID>")
+		check(cfg.ErrorReportingConfig.Timeout > 0, "error_reporting.timeout must be positive")
+		check(cfg.ErrorReportingConfig.QueueSize > 0, "error_reporting.queue_size must be positive")
+	}
+	check(!cfg.FeatureFlagsConfig.Redis || cfg.FeatureFlagsConfig.CacheTTL > 0, "feature_flags.cache_ttl must be positive")
+	if len(cfg.EventsConfig.KafkaBrokers) > 0 {
+		check(cfg.EventsConfig.Topic != "", "events.topic is required with events.kafka_brokers")
+		check(cfg.EventsConfig.WriteTimeout > 0, "events.write_timeout must be positive")
+	}
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
 }
 
 // -------------Get Config Path from Flag or Env --------------
@@ -87,16 +779,20 @@ func fetchConfigPath() string {
 		res = os.Getenv("CONFIG_PATH")
 	}
 
-	if res == "" {
-		panic("config path is not provided")
-	}
-
 	return res
 }
+
+// LoadConfig reads the config file given with -config or CONFIG_PATH, environment variables override it.
+// Without a config file the config is read from the environment only. It panics if the config is invalid.
 func LoadConfig() Config {
 	path := fetchConfigPath()
 	if path == "" {
-		panic("config path is empty")
+		var cfg Config
+		if err := cleanenv.ReadEnv(&cfg); err != nil {
+			panic(err)
+		}
+		mustValidate(&cfg)
+		return cfg
 	}
 	return LoadConfigFromPath(path)
 }
@@ -106,5 +802,12 @@ func LoadConfigFromPath(path string) Config {
 	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
 		panic(err)
 	}
+	mustValidate(&cfg)
 	return cfg
 }
+
+func mustValidate(cfg *Config) {
+	if err := cfg.Validate(); err != nil {
+		panic(fmt.Errorf("invalid config: %w", err))
+	}
+}
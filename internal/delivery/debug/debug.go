@@ -0,0 +1,47 @@
+// Package debug serves the runtime diagnostics of the instance: the profiles of net/http/pprof under /debug/pprof/,
+// the expvar variables under /debug/vars and a dump of the stacks of all goroutines under /debug/goroutines.
+// It is meant for a separate listener that isn't reachable from the internet, never for the public API.
+package debug
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	rpprof "runtime/pprof"
+	"strings"
+)
+
+// NewHandler returns the diagnostics endpoints. With a token every request must send it as a bearer token.
+func NewHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/goroutines", goroutines)
+	if token == "" {
+		return mux
+	}
+	return authenticate(token, mux)
+}
+
+// goroutines writes the stacks of all goroutines in the format of an unrecovered panic.
+func goroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func authenticate(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="debug"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
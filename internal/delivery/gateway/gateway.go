@@ -0,0 +1,142 @@
+// Package gateway serves the gRPC services as JSON over HTTP with the reverse proxy grpc-gateway generates from the
+// protos, so HTTP clients get the API defined by the protos without a hand-written handler per method:
+// POST <prefix>/<package.Service>/<Method> with the request message as protojson answers with the response message.
+// Calls go through a connection to the gRPC server, so its interceptors authenticate, authorize and log them like
+// native gRPC calls.
+package gateway
+
+import (
+	"context"
+	adminpb "main/pkg/proto/gen/admin/v1"
+	authpb "main/pkg/proto/gen/auth/v1"
+	chatpb "main/pkg/proto/gen/chat/v1"
+	commentspb "main/pkg/proto/gen/comments/v1"
+	notificationspb "main/pkg/proto/gen/notifications/v1"
+	postspb "main/pkg/proto/gen/posts/v1"
+	profilepb "main/pkg/proto/gen/profile/v1"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// maxBodySize limits request bodies, like the default message size limit of the gRPC server.
+const maxBodySize = 4 << 20
+
+type registerFunc func(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error
+
+// handlers registers the generated handlers of each service, by the full name of the service.
+var handlers = map[string]registerFunc{
+	adminpb.AdminService_ServiceDesc.ServiceName:                adminpb.RegisterAdminServiceHandler,
+	authpb.AuthService_ServiceDesc.ServiceName:                  authpb.RegisterAuthServiceHandler,
+	chatpb.ChatService_ServiceDesc.ServiceName:                  chatpb.RegisterChatServiceHandler,
+	commentspb.CommentService_ServiceDesc.ServiceName:           commentspb.RegisterCommentServiceHandler,
+	notificationspb.NotificationService_ServiceDesc.ServiceName: notificationspb.RegisterNotificationServiceHandler,
+	postspb.PostService_ServiceDesc.ServiceName:                 postspb.RegisterPostServiceHandler,
+	profilepb.ProfileService_ServiceDesc.ServiceName:            profilepb.RegisterProfileServiceHandler,
+}
+
+// forwardedHeaders are the headers of the HTTP request passed to the gRPC server, by their metadata key.
+// grpc-gateway always passes Authorization and puts the address of the HTTP client into x-forwarded-for.
+var forwardedHeaders = map[string]string{
+	"X-Api-Key":       "x-api-key",
+	"User-Agent":      "grpc-gateway-user-agent",
+	"X-Captcha-Token": "x-captcha-token",
+	"Accept-Language": "accept-language",
+}
+
+type Gateway struct {
+	handler http.Handler
+	methods []Method
+}
+
+// New serves the given services, e.g. the keys of grpc.Server.GetServiceInfo, under the prefix. Services without
+// generated handlers are skipped.
+func New(conn *grpc.ClientConn, prefix string, services []string) (*Gateway, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithMetadata(requestMetadata),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+	)
+	g := &Gateway{handler: http.StripPrefix(prefix, mux)}
+	for _, name := range services {
+		register, ok := handlers[name]
+		if !ok {
+			continue
+		}
+		if err := register(context.Background(), mux, conn); err != nil {
+			return nil, err
+		}
+		g.methods = append(g.methods, unaryMethods(prefix, name)...)
+	}
+	slices.SortFunc(g.methods, func(a, b Method) int { return strings.Compare(a.Path, b.Path) })
+	return g, nil
+}
+
+// Method is a gRPC method served by the gateway.
+type Method struct {
+	// Path is the URL path of the method, including the prefix
+	Path   string
+	Input  protoreflect.MessageDescriptor
+	Output protoreflect.MessageDescriptor
+}
+
+// Methods returns the served unary methods sorted by path.
+func (g *Gateway) Methods() []Method {
+	return g.methods
+}
+
+// unaryMethods returns the unary methods of the service, whose descriptor is linked in with its handlers.
+func unaryMethods(prefix, name string) []Method {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	var methods []Method
+	for i := 0; i < service.Methods().Len(); i++ {
+		m := service.Methods().Get(i)
+		if m.IsStreamingClient() || m.IsStreamingServer() {
+			continue
+		}
+		path := prefix + "/" + string(service.FullName()) + "/" + string(m.Name())
+		methods = append(methods, Method{Path: path, Input: m.Input(), Output: m.Output()})
+	}
+	return methods
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	// grpc-gateway appends the address of the HTTP client to the header, the addresses in front of it are whatever
+	// the client claims, so only the address of the client is sent
+	r.Header.Del("X-Forwarded-For")
+	g.handler.ServeHTTP(w, r)
+}
+
+func incomingHeader(key string) (string, bool) {
+	name, ok := forwardedHeaders[textproto.CanonicalMIMEHeaderKey(key)]
+	return name, ok
+}
+
+// requestMetadata passes the ID the HTTP request got, so the call is logged under the same ID.
+func requestMetadata(_ context.Context, r *http.Request) metadata.MD {
+	if id, ok := ctxUtil.RequestIDFromContext(r.Context()); ok {
+		return metadata.Pairs("x-request-id", id)
+	}
+	return nil
+}
@@ -0,0 +1,455 @@
+package grp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	"main/pkg/jwt"
+	adminv1 "main/pkg/proto/gen/admin/v1"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type RPCAdminHandler struct {
+	adminv1.UnimplementedAdminServiceServer
+	logger             *slog.Logger
+	AdminUsecase       AdminUsecase
+	AuditUsecase       AuditUsecase
+	APIKeyUsecase      APIKeyUsecase
+	ReviewUsecase      ReviewUsecase
+	MaintenanceUsecase MaintenanceUsecase
+}
+
+type AdminUsecase interface {
+
+	//BlockUser blocks the user until the given time (nil blocks indefinitely) and revokes all of their sessions.
+	BlockUser(ctx context.Context, userID uuid.UUID, reason string, until *time.Time) error
+
+	//UnblockUser lifts the block of the user.
+	UnblockUser(ctx context.Context, userID uuid.UUID) error
+
+	//GrantRole grants the role to the user.
+	GrantRole(ctx context.Context, userID uuid.UUID, role entity.Role) error
+
+	//RevokeRole revokes the role from the user.
+	RevokeRole(ctx context.Context, userID uuid.UUID, role entity.Role) error
+
+	//LookupUser returns the account of a user, query is the user ID, the username or the email address.
+	LookupUser(ctx context.Context, query string) (entity.UserAccount, error)
+
+	//ListSessions returns a page of the active sessions of the user and the cursor of the next page.
+	ListSessions(ctx context.Context, userID uuid.UUID, cursor string, limit int) (sessions []entity.Session, nextCursor string, err error)
+
+	//RevokeUserSession ends one of the sessions of the user.
+	RevokeUserSession(ctx context.Context, userID, sessionID uuid.UUID) error
+
+	//RevokeUserSessions ends all sessions of the user.
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+
+	//CreateUser creates an account with the given roles in addition to the user role.
+	CreateUser(ctx context.Context, username, email, password string, roles []entity.Role) (uuid.UUID, error)
+}
+
+type MaintenanceUsecase interface {
+
+	//RotateSigningKey switches to a new access token signing key and returns its ID.
+	RotateSigningKey(ctx context.Context) (string, error)
+
+	//RunBackfill runs the named backfill and returns how many rows it changed.
+	RunBackfill(ctx context.Context, name string) (int64, error)
+}
+
+type ReviewUsecase interface {
+
+	//ListReports returns a page of the content waiting for review, oldest first, and the cursor of the next page.
+	ListReports(ctx context.Context, cursor string, limit int) (reports []entity.ContentFlag, nextCursor string, err error)
+
+	//ResolveReport dismisses a report or takes the reported content down.
+	ResolveReport(ctx context.Context, reportID uuid.UUID, resolution entity.ReportResolution) error
+
+	//TakeDown removes a post, a comment or a message.
+	TakeDown(ctx context.Context, kind entity.ContentKind, contentID uuid.UUID, reason string) error
+}
+
+type AuditUsecase interface {
+
+	//ListEvents returns audit events matching the filter, newest first.
+	ListEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error)
+}
+
+type APIKeyUsecase interface {
+
+	//IssueKey creates a key allowed to call the methods in scopes and returns the plain key, which is shown only once.
+	IssueKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (string, entity.APIKey, error)
+
+	//ListKeys returns all keys, newest first.
+	ListKeys(ctx context.Context) ([]entity.APIKey, error)
+
+	//RevokeKey revokes the key.
+	RevokeKey(ctx context.Context, id uuid.UUID) error
+}
+
+func NewAdminHandler(logger *slog.Logger, adminUsecase AdminUsecase, auditUsecase AuditUsecase, apiKeyUsecase APIKeyUsecase, reviewUsecase ReviewUsecase, maintenanceUsecase MaintenanceUsecase) *RPCAdminHandler {
+	return &RPCAdminHandler{
+		logger:             logger,
+		AdminUsecase:       adminUsecase,
+		AuditUsecase:       auditUsecase,
+		APIKeyUsecase:      apiKeyUsecase,
+		ReviewUsecase:      reviewUsecase,
+		MaintenanceUsecase: maintenanceUsecase,
+	}
+}
+
+// BlockUser blocks the user with a reason and optional expiry.
+func (h *RPCAdminHandler) BlockUser(ctx context.Context, req *adminv1.BlockUserRequest) (*adminv1.BlockUserResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	var until *time.Time
+	if req.GetExpiresAt() != nil {
+		t := req.GetExpiresAt().AsTime()
+		until = &t
+	}
+
+	if err := h.AdminUsecase.BlockUser(ctx, userID, req.GetReason(), until); err != nil {
+		return nil, fmt.Errorf("failed to block user: %w", err)
+	}
+	return &adminv1.BlockUserResponse{
+		Success: true,
+	}, nil
+}
+
+// UnblockUser lifts the block of the user.
+func (h *RPCAdminHandler) UnblockUser(ctx context.Context, req *adminv1.UnblockUserRequest) (*adminv1.UnblockUserResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	if err := h.AdminUsecase.UnblockUser(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return &adminv1.UnblockUserResponse{
+		Success: true,
+	}, nil
+}
+
+// GrantRole grants a role to the user.
+func (h *RPCAdminHandler) GrantRole(ctx context.Context, req *adminv1.GrantRoleRequest) (*adminv1.GrantRoleResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	if err := h.AdminUsecase.GrantRole(ctx, userID, entity.Role(req.GetRole())); err != nil {
+		return nil, fmt.Errorf("failed to grant role: %w", err)
+	}
+	return &adminv1.GrantRoleResponse{
+		Success: true,
+	}, nil
+}
+
+// RevokeRole revokes a role from the user.
+func (h *RPCAdminHandler) RevokeRole(ctx context.Context, req *adminv1.RevokeRoleRequest) (*adminv1.RevokeRoleResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	if err := h.AdminUsecase.RevokeRole(ctx, userID, entity.Role(req.GetRole())); err != nil {
+		return nil, fmt.Errorf("failed to revoke role: %w", err)
+	}
+	return &adminv1.RevokeRoleResponse{
+		Success: true,
+	}, nil
+}
+
+// ListAuditEvents returns audit events matching the filters, newest first.
+func (h *RPCAdminHandler) ListAuditEvents(ctx context.Context, req *adminv1.ListAuditEventsRequest) (*adminv1.ListAuditEventsResponse, error) {
+	filter := entity.AuditFilter{
+		Type:     entity.AuditEventType(req.GetType()),
+		BeforeID: req.GetBeforeId(),
+		Limit:    int(req.GetLimit()),
+	}
+	if req.GetActorId() != "" {
+		filter.ActorID = uuid.MustParse(req.GetActorId())
+	}
+	if req.GetSubjectId() != "" {
+		filter.SubjectID = uuid.MustParse(req.GetSubjectId())
+	}
+	if req.GetFrom() != nil {
+		filter.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		filter.To = req.GetTo().AsTime()
+	}
+
+	events, err := h.AuditUsecase.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	resp := &adminv1.ListAuditEventsResponse{}
+	for _, e := range events {
+		metadata, err := structpb.NewStruct(e.Metadata)
+		if err != nil {
+			h.logger.Warn("Audit event metadata is not convertible", "error", err, "event_id", e.ID)
+		}
+		resp.Events = append(resp.Events, &adminv1.AuditEvent{
+			Id:        e.ID,
+			Type:      string(e.Type),
+			ActorId:   e.ActorID.String(),
+			SubjectId: e.SubjectID.String(),
+			ClientIp:  e.ClientIP,
+			UserAgent: e.UserAgent,
+			Metadata:  metadata,
+			CreatedAt: timestamppb.New(e.CreatedAt),
+		})
+	}
+	if len(events) > 0 {
+		resp.NextBeforeId = events[len(events)-1].ID
+	}
+	return resp, nil
+}
+
+// IssueAPIKey creates an API key for an internal service.
+func (h *RPCAdminHandler) IssueAPIKey(ctx context.Context, req *adminv1.IssueAPIKeyRequest) (*adminv1.IssueAPIKeyResponse, error) {
+	var expiresAt *time.Time
+	if req.GetExpiresAt() != nil {
+		t := req.GetExpiresAt().AsTime()
+		expiresAt = &t
+	}
+
+	plain, key, err := h.APIKeyUsecase.IssueKey(ctx, req.GetName(), req.GetScopes(), expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue API key: %w", err)
+	}
+	return &adminv1.IssueAPIKeyResponse{
+		Key:    plain,
+		ApiKey: toProtoAPIKey(key),
+	}, nil
+}
+
+// ListAPIKeys returns all API keys without their secrets.
+func (h *RPCAdminHandler) ListAPIKeys(ctx context.Context, req *adminv1.ListAPIKeysRequest) (*adminv1.ListAPIKeysResponse, error) {
+	keys, err := h.APIKeyUsecase.ListKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	resp := &adminv1.ListAPIKeysResponse{}
+	for _, key := range keys {
+		resp.ApiKeys = append(resp.ApiKeys, toProtoAPIKey(key))
+	}
+	return resp, nil
+}
+
+// RevokeAPIKey revokes an API key.
+func (h *RPCAdminHandler) RevokeAPIKey(ctx context.Context, req *adminv1.RevokeAPIKeyRequest) (*adminv1.RevokeAPIKeyResponse, error) {
+	keyID := uuid.MustParse(req.GetId())
+
+	err := h.APIKeyUsecase.RevokeKey(ctx, keyID)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return nil, status.Error(codes.NotFound, "API key not found or already revoked")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return &adminv1.RevokeAPIKeyResponse{
+		Success: true,
+	}, nil
+}
+
+// LookupUser finds a user by ID, username or email address.
+func (h *RPCAdminHandler) LookupUser(ctx context.Context, req *adminv1.LookupUserRequest) (*adminv1.LookupUserResponse, error) {
+	account, err := h.AdminUsecase.LookupUser(ctx, req.GetQuery())
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	user := &adminv1.UserAccount{
+		UserId:        account.ID.String(),
+		Username:      account.Username,
+		Email:         account.Email,
+		IsBlocked:     account.IsBlocked,
+		BlockedReason: account.BlockedReason,
+		BlockedUntil:  optionalTimestamp(account.BlockedUntil),
+		CreatedAt:     timestamppb.New(account.CreatedAt),
+		DeletedAt:     optionalTimestamp(account.DeletedAt),
+	}
+	for _, role := range account.Roles {
+		user.Roles = append(user.Roles, string(role))
+	}
+	return &adminv1.LookupUserResponse{
+		User: user,
+	}, nil
+}
+
+// ListUserSessions returns a page of the active sessions of a user.
+func (h *RPCAdminHandler) ListUserSessions(ctx context.Context, req *adminv1.ListUserSessionsRequest) (*adminv1.ListUserSessionsResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	sessions, nextCursor, err := h.AdminUsecase.ListSessions(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	resp := &adminv1.ListUserSessionsResponse{NextCursor: nextCursor}
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, &adminv1.Session{
+			Id:           s.ID.String(),
+			ClientIp:     s.ClientIP.String(),
+			UserAgent:    s.UserAgent,
+			DeviceLabel:  s.DeviceLabel,
+			IsSuspicious: s.IsSuspicious,
+			CountryCode:  s.CountryCode,
+			City:         s.City,
+			CreatedAt:    timestamppb.New(s.CreatedAt),
+			ExpiresAt:    timestamppb.New(s.ExpiresAt),
+		})
+	}
+	return resp, nil
+}
+
+// RevokeSession ends one session of a user.
+func (h *RPCAdminHandler) RevokeSession(ctx context.Context, req *adminv1.RevokeSessionRequest) (*adminv1.RevokeSessionResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+	sessionID := uuid.MustParse(req.GetSessionId())
+
+	if err := h.AdminUsecase.RevokeUserSession(ctx, userID, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return &adminv1.RevokeSessionResponse{
+		Success: true,
+	}, nil
+}
+
+// RevokeAllSessions ends every session of a user.
+func (h *RPCAdminHandler) RevokeAllSessions(ctx context.Context, req *adminv1.RevokeAllSessionsRequest) (*adminv1.RevokeAllSessionsResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	if err := h.AdminUsecase.RevokeUserSessions(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return &adminv1.RevokeAllSessionsResponse{
+		Success: true,
+	}, nil
+}
+
+// TakeDownContent removes a post, a comment or a message.
+func (h *RPCAdminHandler) TakeDownContent(ctx context.Context, req *adminv1.TakeDownContentRequest) (*adminv1.TakeDownContentResponse, error) {
+	contentID := uuid.MustParse(req.GetContentId())
+
+	err := h.ReviewUsecase.TakeDown(ctx, entity.ContentKind(req.GetContentType()), contentID, req.GetReason())
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "content not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take down content: %w", err)
+	}
+	return &adminv1.TakeDownContentResponse{
+		Success: true,
+	}, nil
+}
+
+// ListReports returns a page of the open reports, oldest first.
+func (h *RPCAdminHandler) ListReports(ctx context.Context, req *adminv1.ListReportsRequest) (*adminv1.ListReportsResponse, error) {
+	reports, nextCursor, err := h.ReviewUsecase.ListReports(ctx, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	resp := &adminv1.ListReportsResponse{NextCursor: nextCursor}
+	for _, r := range reports {
+		resp.Reports = append(resp.Reports, &adminv1.Report{
+			Id:          r.ID.String(),
+			ContentType: string(r.Kind),
+			ContentId:   r.ContentID.String(),
+			AuthorId:    r.AuthorID.String(),
+			Verdict:     string(r.Verdict),
+			Reason:      r.Reason,
+			CreatedAt:   timestamppb.New(r.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+// ResolveReport dismisses a report or takes the reported content down.
+func (h *RPCAdminHandler) ResolveReport(ctx context.Context, req *adminv1.ResolveReportRequest) (*adminv1.ResolveReportResponse, error) {
+	reportID := uuid.MustParse(req.GetReportId())
+
+	err := h.ReviewUsecase.ResolveReport(ctx, reportID, entity.ReportResolution(req.GetResolution()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "report or reported content not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve report: %w", err)
+	}
+	return &adminv1.ResolveReportResponse{
+		Success: true,
+	}, nil
+}
+
+func toProtoAPIKey(key entity.APIKey) *adminv1.APIKey {
+	return &adminv1.APIKey{
+		Id:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedBy:  key.CreatedBy.String(),
+		CreatedAt:  timestamppb.New(key.CreatedAt),
+		ExpiresAt:  optionalTimestamp(key.ExpiresAt),
+		RevokedAt:  optionalTimestamp(key.RevokedAt),
+		LastUsedAt: optionalTimestamp(key.LastUsedAt),
+	}
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// CreateUser creates an account, e.g. the first admin of a new deployment.
+func (h *RPCAdminHandler) CreateUser(ctx context.Context, req *adminv1.CreateUserRequest) (*adminv1.CreateUserResponse, error) {
+	roles := make([]entity.Role, len(req.GetRoles()))
+	for i, role := range req.GetRoles() {
+		roles[i] = entity.Role(role)
+	}
+
+	userID, err := h.AdminUsecase.CreateUser(ctx, req.GetUsername(), req.GetEmail(), req.GetPassword(), roles)
+	if err != nil {
+		// the caller is an admin, so the validation error is safe to show
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &adminv1.CreateUserResponse{
+		UserId: userID.String(),
+	}, nil
+}
+
+// RotateSigningKey switches every instance to a new access token signing key.
+func (h *RPCAdminHandler) RotateSigningKey(ctx context.Context, req *adminv1.RotateSigningKeyRequest) (*adminv1.RotateSigningKeyResponse, error) {
+	keyID, err := h.MaintenanceUsecase.RotateSigningKey(ctx)
+	if errors.Is(err, jwt.ErrUnsupportedKey) {
+		return nil, status.Error(codes.FailedPrecondition, "only asymmetric signing keys can be rotated")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate signing key: %w", err)
+	}
+	return &adminv1.RotateSigningKeyResponse{
+		KeyId: keyID,
+	}, nil
+}
+
+// RunBackfill runs the named backfill.
+func (h *RPCAdminHandler) RunBackfill(ctx context.Context, req *adminv1.RunBackfillRequest) (*adminv1.RunBackfillResponse, error) {
+	count, err := h.MaintenanceUsecase.RunBackfill(ctx, req.GetName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to run backfill: %w", err)
+	}
+	return &adminv1.RunBackfillResponse{
+		Count: count,
+	}, nil
+}
@@ -2,16 +2,20 @@ package grp
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"main/domain/entity"
+	"main/pkg/customerrors"
 	authv1 "main/pkg/proto/gen/auth/v1"
-	"net"
-	"strings"
+	ctxUtil "main/pkg/utils/context"
 
 	"github.com/google/uuid"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type RPCAuthHandler struct {
@@ -23,19 +27,49 @@ type RPCAuthHandler struct {
 type AuthUsecase interface {
 
 	//RegisterUser registers a new user and returns the user ID as a string.
-	RegisterUser(ctx context.Context, username, email, password string) (userID uuid.UUID, err error)
+	RegisterUser(ctx context.Context, username, email, password, captchaToken string) (userID uuid.UUID, err error)
+
+	//CheckUsername reports whether the username can be registered, and if not, why.
+	CheckUsername(ctx context.Context, username string) (available bool, reason string, err error)
 
 	//LoginUser authenticates a user and returns an access token.
-	LoginUser(ctx context.Context, login, password, userAgent string, ip string) (userID uuid.UUID, accessToken string, refreshToken string, err error)
+	LoginUser(ctx context.Context, login, password, userAgent, ip, captchaToken string) (userID uuid.UUID, accessToken string, refreshToken string, err error)
+
+	//ConfirmLogin completes a login from a new device with the token emailed to the user.
+	ConfirmLogin(ctx context.Context, token string) (userID uuid.UUID, accessToken string, refreshToken string, err error)
 
-	//LogoutSession logs out a user from a specific session.
-	LogoutSession(ctx context.Context, userID string, sessionID string) error
+	//LogoutSession logs out a user from one of their own sessions.
+	LogoutSession(ctx context.Context, userID, sessionID uuid.UUID) error
 
 	//LogoutAllSessions logs out a user from all sessions.
-	LogoutAllSessions(ctx context.Context, userID string) error
+	LogoutAllSessions(ctx context.Context, userID uuid.UUID) error
 
 	//RefreshSessionToken refreshes the session token for a user and returns the new access token and refresh token.
 	RefreshSessionToken(ctx context.Context, refreshToken string) (string, string, error)
+
+	//ChangePassword verifies the current password and replaces it with the new one.
+	ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string, revokeSessions bool) error
+
+	//RequestEmailChange mails a confirmation token to the new email address.
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail string) error
+
+	//ConfirmEmailChange swaps the email of the user the token was issued for.
+	ConfirmEmailChange(ctx context.Context, token string) error
+
+	//DeleteAccount soft-deletes the account after re-confirming the password.
+	DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error
+
+	//ListSessions returns a page of the active sessions of the user with a device label for each, newest first.
+	ListSessions(ctx context.Context, userID uuid.UUID, cursor string, limit int) (sessions []entity.Session, nextCursor string, err error)
+
+	//RenameSession names the device of one of the user's sessions.
+	RenameSession(ctx context.Context, userID, sessionID uuid.UUID, name string) error
+
+	//WatchSessions returns the session events of the user until cancel is called.
+	WatchSessions(userID uuid.UUID) (events <-chan entity.SessionEvent, cancel func())
+
+	//Introspect reports whether an access or refresh token is currently valid and what it grants.
+	Introspect(ctx context.Context, token, tokenTypeHint string) (entity.TokenIntrospection, error)
 }
 
 func NewAuthHandler(logger *slog.Logger, authUsecase AuthUsecase) *RPCAuthHandler {
@@ -48,10 +82,9 @@ func NewAuthHandler(logger *slog.Logger, authUsecase AuthUsecase) *RPCAuthHandle
 
 // RegisterUser registers a new user and returns the user ID.
 func (h *RPCAuthHandler) Register(ctx context.Context, req *authv1.RegisterRequest) (*authv1.RegisterResponse, error) {
-	userID, err := h.AuthUsecase.RegisterUser(ctx, req.Username, req.Email, req.Password)
+	userID, err := h.AuthUsecase.RegisterUser(ctx, req.Username, req.Email, req.Password, captchaToken(ctx, req.GetCaptchaToken()))
 	if err != nil {
-		h.logger.Error("Failed to register user", "error", err)
-		return nil, status.Error(codes.Internal, "failed to register user")
+		return nil, fmt.Errorf("failed to register user: %w", err)
 	}
 	return &authv1.RegisterResponse{
 		UserId: userID.String()}, nil
@@ -59,14 +92,30 @@ func (h *RPCAuthHandler) Register(ctx context.Context, req *authv1.RegisterReque
 }
 
 // LoginUser authenticates the user and returns an access token if successful.
+// CheckUsername reports whether the username can be registered.
+func (h *RPCAuthHandler) CheckUsername(ctx context.Context, req *authv1.CheckUsernameRequest) (*authv1.CheckUsernameResponse, error) {
+	available, reason, err := h.AuthUsecase.CheckUsername(ctx, req.GetUsername())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	}
+	return &authv1.CheckUsernameResponse{
+		Available: available,
+		Reason:    reason,
+	}, nil
+}
+
 func (h *RPCAuthHandler) Login(ctx context.Context, req *authv1.LoginRequest) (*authv1.LoginResponse, error) {
-	if req.GetLogin() == "" || req.GetPassword() == "" {
-		h.logger.Error("Login or password is empty")
-		return nil, status.Error(codes.InvalidArgument, "login or password is empty")
+	clientIP, userAgent := ctxUtil.ClientInfoFromContext(ctx)
+	userID, accessToken, refreshToken, err := h.AuthUsecase.LoginUser(ctx, req.GetLogin(), req.GetPassword(), userAgent, clientIP,
+		captchaToken(ctx, req.GetCaptchaToken()))
+	if errors.Is(err, customerrors.ErrLoginConfirmationRequired) {
+		return &authv1.LoginResponse{
+			ConfirmationRequired: true,
+		}, nil
+	}
+	if errors.Is(err, customerrors.ErrCaptchaRequired) {
+		return nil, err
 	}
-	userAgent := getUserAgent(ctx)
-	clientIP := getClientIP(ctx)
-	userID, accessToken, refreshToken, err := h.AuthUsecase.LoginUser(ctx, req.GetLogin(), req.GetPassword(), userAgent, clientIP)
 	if err != nil {
 		h.logger.Error("Failed to login user", "error", err)
 		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
@@ -80,12 +129,41 @@ func (h *RPCAuthHandler) Login(ctx context.Context, req *authv1.LoginRequest) (*
 
 }
 
-// LogoutSession logs out the user from a specific session by deleting that session from the database.
+// ConfirmLogin completes a login from a new device with the token from the confirmation email.
+func (h *RPCAuthHandler) ConfirmLogin(ctx context.Context, req *authv1.ConfirmLoginRequest) (*authv1.ConfirmLoginResponse, error) {
+	_, accessToken, refreshToken, err := h.AuthUsecase.ConfirmLogin(ctx, req.GetToken())
+	if errors.Is(err, customerrors.ErrInvalidToken) {
+		return nil, err
+	}
+	if err != nil {
+		h.logger.Error("Failed to confirm login", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to confirm login")
+	}
+	return &authv1.ConfirmLoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Logout logs out the authenticated user from the session of the access token, or from another of their sessions if session_id is set.
 func (h *RPCAuthHandler) Logout(ctx context.Context, req *authv1.LogoutRequest) (*authv1.LogoutResponse, error) {
-	err := h.AuthUsecase.LogoutSession(ctx, req.GetUserId(), req.GetSessionId())
+	userID, err := userIDFromContext(ctx)
 	if err != nil {
-		h.logger.Error("Failed to logout session", "error", err)
-		return nil, status.Error(codes.Internal, "failed to logout session")
+		return nil, err
+	}
+
+	principal, _ := ctxUtil.PrincipalFromContext(ctx)
+	sessionID := principal.SessionID
+	if req.GetSessionId() != "" {
+		sessionID, err = uuid.Parse(req.GetSessionId())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid session ID")
+		}
+	}
+
+	err = h.AuthUsecase.LogoutSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to logout session: %w", err)
 
 	}
 	return &authv1.LogoutResponse{
@@ -93,12 +171,15 @@ func (h *RPCAuthHandler) Logout(ctx context.Context, req *authv1.LogoutRequest)
 	}, nil
 }
 
-// LogoutAllSessions logs out the user from all sessions by deleting all sessions associated with the user from the database.
+// LogoutAll logs out the authenticated user from all sessions by deleting all sessions associated with the user from the database.
 func (h *RPCAuthHandler) LogoutAll(ctx context.Context, req *authv1.LogoutAllRequest) (*authv1.LogoutAllResponse, error) {
-	err := h.AuthUsecase.LogoutAllSessions(ctx, req.GetUserId())
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = h.AuthUsecase.LogoutAllSessions(ctx, userID)
 	if err != nil {
-		h.logger.Error("Failed to logout all sessions", "error", err)
-		return nil, status.Error(codes.Internal, "failed to logout all sessions")
+		return nil, fmt.Errorf("failed to logout all sessions: %w", err)
 	}
 	return &authv1.LogoutAllResponse{
 		Success: true,
@@ -109,8 +190,7 @@ func (h *RPCAuthHandler) LogoutAll(ctx context.Context, req *authv1.LogoutAllReq
 func (h *RPCAuthHandler) RefreshSession(ctx context.Context, req *authv1.RefreshTokenRequest) (*authv1.RefreshTokenResponse, error) {
 	newAccessToken, newRefreshToken, err := h.AuthUsecase.RefreshSessionToken(ctx, req.GetRefreshToken())
 	if err != nil {
-		h.logger.Error("Failed to refresh session token", "error", err)
-		return nil, status.Error(codes.Internal, "failed to refresh session token")
+		return nil, fmt.Errorf("failed to refresh session token: %w", err)
 	}
 	return &authv1.RefreshTokenResponse{
 		AccessToken:  newAccessToken,
@@ -118,55 +198,185 @@ func (h *RPCAuthHandler) RefreshSession(ctx context.Context, req *authv1.Refresh
 	}, nil
 }
 
-// getClientIP extracts the client IP address from gRPC metadata or peer info.
-func getClientIP(ctx context.Context) string {
-	// 1. First, try to get the IP from gRPC metadata headers
-	md, ok := metadata.FromIncomingContext(ctx)
-	if ok {
-		// Standard header for client IP
-		if xff := md.Get("x-forwarded-for"); len(xff) > 0 {
-			// The X-Forwarded-For header can contain multiple IPs, the first one is the client's IP
-			ips := strings.Split(xff[0], ",")
-			return strings.TrimSpace(ips[0])
-		}
+// ChangePassword changes the password of the authenticated user after checking the current one.
+func (h *RPCAuthHandler) ChangePassword(ctx context.Context, req *authv1.ChangePasswordRequest) (*authv1.ChangePasswordResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		// Common alternative header
-		if xrip := md.Get("x-real-ip"); len(xrip) > 0 {
-			return xrip[0]
-		}
+	err = h.AuthUsecase.ChangePassword(ctx, userID, req.GetCurrentPassword(), req.GetNewPassword(), req.GetRevokeSessions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+	return &authv1.ChangePasswordResponse{
+		Success: true,
+	}, nil
+}
+
+// RequestEmailChange sends a confirmation token to the new email address of the authenticated user.
+func (h *RPCAuthHandler) RequestEmailChange(ctx context.Context, req *authv1.RequestEmailChangeRequest) (*authv1.RequestEmailChangeResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.AuthUsecase.RequestEmailChange(ctx, userID, req.GetNewEmail()); err != nil {
+		return nil, fmt.Errorf("failed to request email change: %w", err)
+	}
+	return &authv1.RequestEmailChangeResponse{
+		Success: true,
+	}, nil
+}
+
+// ConfirmEmailChange applies the email change identified by the confirmation token.
+func (h *RPCAuthHandler) ConfirmEmailChange(ctx context.Context, req *authv1.ConfirmEmailChangeRequest) (*authv1.ConfirmEmailChangeResponse, error) {
+	err := h.AuthUsecase.ConfirmEmailChange(ctx, req.GetToken())
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm email change: %w", err)
 	}
+	return &authv1.ConfirmEmailChangeResponse{
+		Success: true,
+	}, nil
+}
 
-	// 2. Fallback to peer info from context
-	if p, ok := peer.FromContext(ctx); ok {
-		addr := p.Addr.String()
+// DeleteAccount deletes the account of the authenticated user, the password has to be re-confirmed.
+func (h *RPCAuthHandler) DeleteAccount(ctx context.Context, req *authv1.DeleteAccountRequest) (*authv1.DeleteAccountResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		host, _, err := net.SplitHostPort(addr)
-		if err != nil {
+	err = h.AuthUsecase.DeleteAccount(ctx, userID, req.GetPassword())
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete account: %w", err)
+	}
+	return &authv1.DeleteAccountResponse{
+		Success: true,
+	}, nil
+}
 
-			return addr
-		}
-		return host
+// userIDFromContext returns the user ID put into the context by the auth interceptor.
+// ListSessions returns the active sessions of the authenticated user.
+func (h *RPCAuthHandler) ListSessions(ctx context.Context, req *authv1.ListSessionsRequest) (*authv1.ListSessionsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	principal, _ := ctxUtil.PrincipalFromContext(ctx)
+	currentID := principal.SessionID
+
+	sessions, nextCursor, err := h.AuthUsecase.ListSessions(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
 	}
 
-	return "unknown"
+	resp := &authv1.ListSessionsResponse{NextCursor: nextCursor}
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, &authv1.Session{
+			Id:          s.ID.String(),
+			DeviceName:  s.DeviceName,
+			DeviceLabel: s.DeviceLabel,
+			UserAgent:   s.UserAgent,
+			ClientIp:    s.ClientIP.String(),
+			CountryCode: s.CountryCode,
+			City:        s.City,
+			CreatedAt:   timestamppb.New(s.CreatedAt),
+			ExpiresAt:   timestamppb.New(s.ExpiresAt),
+			Current:     s.ID == currentID,
+		})
+	}
+	return resp, nil
 }
 
-// getUserAgent extracts the User-Agent from gRPC metadata.
-func getUserAgent(ctx context.Context) string {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return "unknown"
+// RenameSession names the device of one of the authenticated user's sessions.
+func (h *RPCAuthHandler) RenameSession(ctx context.Context, req *authv1.RenameSessionRequest) (*authv1.RenameSessionResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
+	sessionID := uuid.MustParse(req.GetSessionId())
 
-	//gRPC initially sets user-agent in "user-agent" metadata field
-	if ua := md.Get("user-agent"); len(ua) > 0 {
-		return ua[0]
+	err = h.AuthUsecase.RenameSession(ctx, userID, sessionID, req.GetName())
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return nil, status.Error(codes.NotFound, "session not found")
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename session: %w", err)
+	}
+	return &authv1.RenameSessionResponse{
+		Success: true,
+	}, nil
+}
+
+// WatchSessions streams the session events of the authenticated user until the client disconnects.
+func (h *RPCAuthHandler) WatchSessions(req *authv1.WatchSessionsRequest, stream grpc.ServerStreamingServer[authv1.SessionEvent]) error {
+	userID, err := userIDFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	events, cancel := h.AuthUsecase.WatchSessions(userID)
+	defer cancel()
 
-	// Some proxies forward the original UA in x-user-agent
-	if ua := md.Get("grpc-gateway-user-agent"); len(ua) > 0 {
-		return ua[0]
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			msg := &authv1.SessionEvent{
+				Type:        string(event.Type),
+				DeviceLabel: event.DeviceLabel,
+				ClientIp:    event.ClientIP,
+				CountryCode: event.CountryCode,
+				OccurredAt:  timestamppb.New(event.OccurredAt),
+			}
+			if event.SessionID != uuid.Nil {
+				msg.SessionId = event.SessionID.String()
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
 	}
+}
 
-	return "unknown"
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := ctxUtil.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "missing user in context")
+	}
+	return userID, nil
+}
+
+// Introspect tells a resource server whether the token is active and what it grants.
+func (h *RPCAuthHandler) Introspect(ctx context.Context, req *authv1.IntrospectRequest) (*authv1.IntrospectResponse, error) {
+	info, err := h.AuthUsecase.Introspect(ctx, req.GetToken(), req.GetTokenTypeHint())
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect token: %w", err)
+	}
+	if !info.Active {
+		return &authv1.IntrospectResponse{Active: false}, nil
+	}
+	return &authv1.IntrospectResponse{
+		Active:    true,
+		TokenType: info.TokenType,
+		UserId:    info.UserID.String(),
+		SessionId: info.SessionID.String(),
+		Scopes:    info.Scopes,
+		IssuedAt:  timestamppb.New(info.IssuedAt),
+		ExpiresAt: timestamppb.New(info.ExpiresAt),
+	}, nil
+}
+
+// captchaToken returns the CAPTCHA token from the request field, falling back to the "x-captcha-token" metadata.
+func captchaToken(ctx context.Context, field string) string {
+	if field != "" {
+		return field
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-captcha-token"); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
 }
@@ -0,0 +1,528 @@
+package grp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	chatv1 "main/pkg/proto/gen/chat/v1"
+	ctxUtil "main/pkg/utils/context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type RPCChatHandler struct {
+	chatv1.UnimplementedChatServiceServer
+	logger      *slog.Logger
+	ChatUsecase ChatUsecase
+}
+
+type ChatUsecase interface {
+
+	//CreateChat returns the direct chat between the user and the peer, creating it on the first call.
+	CreateChat(ctx context.Context, userID, peerID uuid.UUID) (entity.Chat, error)
+
+	//CreateGroup creates a group chat with the user as its admin and memberIDs as regular members.
+	CreateGroup(ctx context.Context, userID uuid.UUID, title, avatarURL string, public bool, memberIDs []uuid.UUID) (entity.Chat, error)
+
+	//GetChat returns one of the user's chats.
+	GetChat(ctx context.Context, userID, chatID uuid.UUID) (entity.Chat, error)
+
+	//UpdateGroup changes the non-nil fields of a group chat the user is an admin of.
+	UpdateGroup(ctx context.Context, userID, chatID uuid.UUID, update entity.GroupUpdate) (entity.Chat, error)
+
+	//ListMembers returns a page of the members of one of the user's chats and the cursor of the next page.
+	ListMembers(ctx context.Context, userID, chatID uuid.UUID, cursor string, limit int) (members []entity.ChatMember, nextCursor string, err error)
+
+	//AddMembers adds users to a group chat the user is an admin of.
+	AddMembers(ctx context.Context, userID, chatID uuid.UUID, memberIDs []uuid.UUID) error
+
+	//RemoveMember removes a member from a group chat the user is an admin of, or the user themselves.
+	RemoveMember(ctx context.Context, userID, chatID, memberID uuid.UUID) error
+
+	//SetMemberRole changes the role of a member of a group chat the user is an admin of.
+	SetMemberRole(ctx context.Context, userID, chatID, memberID uuid.UUID, role entity.ChatRole) error
+
+	//JoinChat makes the user a member of a public group chat.
+	JoinChat(ctx context.Context, userID, chatID uuid.UUID) error
+
+	//LeaveChat removes the user from a group chat.
+	LeaveChat(ctx context.Context, userID, chatID uuid.UUID) error
+
+	//MarkRead marks one of the user's chats read up to the message.
+	MarkRead(ctx context.Context, userID, chatID, messageID uuid.UUID) error
+
+	//ListChats returns a page of the user's chats, most recently active first, and the cursor of the next page.
+	ListChats(ctx context.Context, userID uuid.UUID, cursor string, limit int) (chats []entity.Chat, nextCursor string, err error)
+
+	//SendMessage sends a message to one of the user's chats.
+	SendMessage(ctx context.Context, userID, chatID uuid.UUID, content string) (entity.Message, error)
+
+	//ListMessages returns a page of the history of one of the user's chats, newest first, and the cursor of the next page.
+	ListMessages(ctx context.Context, userID, chatID uuid.UUID, cursor string, limit int) (messages []entity.Message, nextCursor string, err error)
+
+	//SearchMessages returns a page of the user's messages matching the query, in all chats or one of them, and the cursor of the next page.
+	SearchMessages(ctx context.Context, userID, chatID uuid.UUID, query, cursor string, limit int) (matches []entity.MessageMatch, nextCursor string, err error)
+
+	//EditMessage replaces the content of one of the user's messages.
+	EditMessage(ctx context.Context, userID, chatID, messageID uuid.UUID, content string) (entity.Message, error)
+
+	//DeleteMessage deletes a message of one of the user's chats for everyone or for the user only.
+	DeleteMessage(ctx context.Context, userID, chatID, messageID uuid.UUID, forEveryone bool) error
+}
+
+func NewChatHandler(logger *slog.Logger, chatUsecase ChatUsecase) *RPCChatHandler {
+	return &RPCChatHandler{
+		logger:      logger,
+		ChatUsecase: chatUsecase,
+	}
+}
+
+// CreateChat returns the caller's chat with a user, creating it on the first call.
+func (h *RPCChatHandler) CreateChat(ctx context.Context, req *chatv1.CreateChatRequest) (*chatv1.CreateChatResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	peerID := uuid.MustParse(req.GetUserId())
+
+	chat, err := h.ChatUsecase.CreateChat(ctx, userID, peerID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat: %w", err)
+	}
+	return &chatv1.CreateChatResponse{
+		Chat: chatToProto(chat),
+	}, nil
+}
+
+// ListChats returns a page of the caller's chats.
+func (h *RPCChatHandler) ListChats(ctx context.Context, req *chatv1.ListChatsRequest) (*chatv1.ListChatsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chats, nextCursor, err := h.ChatUsecase.ListChats(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	resp := &chatv1.ListChatsResponse{NextCursor: nextCursor}
+	for _, c := range chats {
+		resp.Chats = append(resp.Chats, chatToProto(c))
+	}
+	return resp, nil
+}
+
+// SendMessage sends a message to one of the caller's chats.
+func (h *RPCChatHandler) SendMessage(ctx context.Context, req *chatv1.SendMessageRequest) (*chatv1.SendMessageResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+
+	message, err := h.ChatUsecase.SendMessage(ctx, userID, chatID, req.GetContent())
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	return &chatv1.SendMessageResponse{
+		Message: messageToProto(message),
+	}, nil
+}
+
+// SearchMessages returns a page of the caller's messages matching the query.
+func (h *RPCChatHandler) SearchMessages(ctx context.Context, req *chatv1.SearchMessagesRequest) (*chatv1.SearchMessagesResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var chatID uuid.UUID
+	if req.GetChatId() != "" {
+		chatID = uuid.MustParse(req.GetChatId())
+	}
+
+	matches, nextCursor, err := h.ChatUsecase.SearchMessages(ctx, userID, chatID, req.GetQuery(), req.GetCursor(), int(req.GetLimit()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	resp := &chatv1.SearchMessagesResponse{NextCursor: nextCursor}
+	for _, m := range matches {
+		resp.Matches = append(resp.Matches, &chatv1.MessageMatch{
+			Message:   messageToProto(m.Message),
+			Highlight: m.Highlight,
+		})
+	}
+	return resp, nil
+}
+
+// EditMessage replaces the content of one of the caller's messages.
+func (h *RPCChatHandler) EditMessage(ctx context.Context, req *chatv1.EditMessageRequest) (*chatv1.EditMessageResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+	messageID := uuid.MustParse(req.GetMessageId())
+
+	message, err := h.ChatUsecase.EditMessage(ctx, userID, chatID, messageID, req.GetContent())
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "message not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit message: %w", err)
+	}
+	return &chatv1.EditMessageResponse{
+		Message: messageToProto(message),
+	}, nil
+}
+
+// DeleteMessage deletes a message for the caller or, if they sent it, for everyone.
+func (h *RPCChatHandler) DeleteMessage(ctx context.Context, req *chatv1.DeleteMessageRequest) (*chatv1.DeleteMessageResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+	messageID := uuid.MustParse(req.GetMessageId())
+
+	err = h.ChatUsecase.DeleteMessage(ctx, userID, chatID, messageID, req.GetForEveryone())
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "message not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete message: %w", err)
+	}
+	return &chatv1.DeleteMessageResponse{
+		Success: true,
+	}, nil
+}
+
+// ListMessages returns a page of the history of one of the caller's chats.
+func (h *RPCChatHandler) ListMessages(ctx context.Context, req *chatv1.ListMessagesRequest) (*chatv1.ListMessagesResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+
+	messages, nextCursor, err := h.ChatUsecase.ListMessages(ctx, userID, chatID, req.GetCursor(), int(req.GetLimit()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	resp := &chatv1.ListMessagesResponse{NextCursor: nextCursor}
+	for _, m := range messages {
+		resp.Messages = append(resp.Messages, messageToProto(m))
+	}
+	return resp, nil
+}
+
+// CreateGroup creates a group chat with the caller as its admin.
+func (h *RPCChatHandler) CreateGroup(ctx context.Context, req *chatv1.CreateGroupRequest) (*chatv1.CreateGroupResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chat, err := h.ChatUsecase.CreateGroup(ctx, userID, req.GetTitle(), req.GetAvatarUrl(), req.GetPublic(), parseUserIDs(req.GetMemberIds()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+	return &chatv1.CreateGroupResponse{
+		Chat: chatToProto(chat),
+	}, nil
+}
+
+// GetChat returns one of the caller's chats.
+func (h *RPCChatHandler) GetChat(ctx context.Context, req *chatv1.GetChatRequest) (*chatv1.GetChatResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+
+	chat, err := h.ChatUsecase.GetChat(ctx, userID, chatID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+	return &chatv1.GetChatResponse{
+		Chat: chatToProto(chat),
+	}, nil
+}
+
+// UpdateGroup changes a group chat the caller is an admin of.
+func (h *RPCChatHandler) UpdateGroup(ctx context.Context, req *chatv1.UpdateGroupRequest) (*chatv1.UpdateGroupResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+
+	update := entity.GroupUpdate{
+		Title:     req.Title,
+		AvatarURL: req.AvatarUrl,
+		Public:    req.Public,
+	}
+	chat, err := h.ChatUsecase.UpdateGroup(ctx, userID, chatID, update)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "group not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+	return &chatv1.UpdateGroupResponse{
+		Chat: chatToProto(chat),
+	}, nil
+}
+
+// ListMembers returns a page of the members of one of the caller's chats.
+func (h *RPCChatHandler) ListMembers(ctx context.Context, req *chatv1.ListMembersRequest) (*chatv1.ListMembersResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+
+	members, nextCursor, err := h.ChatUsecase.ListMembers(ctx, userID, chatID, req.GetCursor(), int(req.GetLimit()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	resp := &chatv1.ListMembersResponse{NextCursor: nextCursor}
+	for _, m := range members {
+		member := &chatv1.ChatMember{
+			User:     peerToProto(m.User),
+			Role:     string(m.Role),
+			JoinedAt: timestamppb.New(m.JoinedAt),
+		}
+		if m.LastReadMessageID != nil {
+			member.LastReadMessageId = m.LastReadMessageID.String()
+		}
+		resp.Members = append(resp.Members, member)
+	}
+	return resp, nil
+}
+
+// AddMembers adds users to a group chat the caller is an admin of.
+func (h *RPCChatHandler) AddMembers(ctx context.Context, req *chatv1.AddMembersRequest) (*chatv1.AddMembersResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+	err = h.ChatUsecase.AddMembers(ctx, userID, chatID, parseUserIDs(req.GetUserIds()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "group or user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add members: %w", err)
+	}
+	return &chatv1.AddMembersResponse{
+		Success: true,
+	}, nil
+}
+
+// RemoveMember removes a member from a group chat the caller is an admin of.
+func (h *RPCChatHandler) RemoveMember(ctx context.Context, req *chatv1.RemoveMemberRequest) (*chatv1.RemoveMemberResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+	memberID := uuid.MustParse(req.GetUserId())
+
+	err = h.ChatUsecase.RemoveMember(ctx, userID, chatID, memberID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "group or member not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove member: %w", err)
+	}
+	return &chatv1.RemoveMemberResponse{
+		Success: true,
+	}, nil
+}
+
+// SetMemberRole changes the role of a member of a group chat the caller is an admin of.
+func (h *RPCChatHandler) SetMemberRole(ctx context.Context, req *chatv1.SetMemberRoleRequest) (*chatv1.SetMemberRoleResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+	memberID := uuid.MustParse(req.GetUserId())
+
+	err = h.ChatUsecase.SetMemberRole(ctx, userID, chatID, memberID, entity.ChatRole(req.GetRole()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "group or member not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set member role: %w", err)
+	}
+	return &chatv1.SetMemberRoleResponse{
+		Success: true,
+	}, nil
+}
+
+// JoinChat makes the caller a member of a public group chat.
+func (h *RPCChatHandler) JoinChat(ctx context.Context, req *chatv1.JoinChatRequest) (*chatv1.JoinChatResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+
+	err = h.ChatUsecase.JoinChat(ctx, userID, chatID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "group not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to join group: %w", err)
+	}
+	return &chatv1.JoinChatResponse{
+		Success: true,
+	}, nil
+}
+
+// LeaveChat removes the caller from a group chat.
+func (h *RPCChatHandler) LeaveChat(ctx context.Context, req *chatv1.LeaveChatRequest) (*chatv1.LeaveChatResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+
+	err = h.ChatUsecase.LeaveChat(ctx, userID, chatID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "group not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to leave group: %w", err)
+	}
+	return &chatv1.LeaveChatResponse{
+		Success: true,
+	}, nil
+}
+
+// MarkRead marks one of the caller's chats read up to a message.
+func (h *RPCChatHandler) MarkRead(ctx context.Context, req *chatv1.MarkReadRequest) (*chatv1.MarkReadResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatID := uuid.MustParse(req.GetChatId())
+	messageID := uuid.MustParse(req.GetMessageId())
+
+	err = h.ChatUsecase.MarkRead(ctx, userID, chatID, messageID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat or message not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark chat read: %w", err)
+	}
+	return &chatv1.MarkReadResponse{
+		Success: true,
+	}, nil
+}
+
+func chatToProto(c entity.Chat) *chatv1.Chat {
+	pb := &chatv1.Chat{
+		Id:             c.ID.String(),
+		IsGroup:        c.IsGroup,
+		Title:          c.Title,
+		AvatarUrl:      c.AvatarURL,
+		Public:         c.Public,
+		MembersCount:   int32(c.MembersCount),
+		UnreadCount:    int32(c.UnreadCount),
+		CreatedAt:      timestamppb.New(c.CreatedAt),
+		LastActivityAt: timestamppb.New(c.LastActivityAt),
+	}
+	if c.Peer != nil {
+		pb.Peer = peerToProto(*c.Peer)
+	}
+	if c.LastMessage != nil {
+		pb.LastMessage = messageToProto(*c.LastMessage)
+	}
+	return pb
+}
+
+func peerToProto(u entity.UserCard) *chatv1.Peer {
+	return &chatv1.Peer{
+		UserId:     u.ID.String(),
+		Username:   u.Username,
+		Name:       u.Name,
+		AvatarUrl:  u.AvatarURL,
+		Following:  u.Following,
+		FollowsYou: u.FollowsYou,
+	}
+}
+
+// parseUserIDs parses IDs the validation interceptor already checked are UUIDs.
+func parseUserIDs(ids []string) []uuid.UUID {
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		parsed = append(parsed, uuid.MustParse(id))
+	}
+	return parsed
+}
+
+func messageToProto(m entity.Message) *chatv1.Message {
+	message := &chatv1.Message{
+		Id:        m.ID.String(),
+		ChatId:    m.ChatID.String(),
+		SenderId:  m.SenderID.String(),
+		Content:   m.Content,
+		CreatedAt: timestamppb.New(m.CreatedAt),
+		Deleted:   m.Deleted,
+	}
+	if m.EditedAt != nil {
+		message.EditedAt = timestamppb.New(*m.EditedAt)
+	}
+	if a := m.Attachment; a != nil {
+		message.Attachment = &chatv1.Attachment{
+			Url:          a.URL,
+			Type:         a.Type,
+			Size:         a.Size,
+			Width:        int32(a.Width),
+			Height:       int32(a.Height),
+			Duration:     int32(a.Duration),
+			ThumbnailUrl: a.ThumbnailURL,
+		}
+	}
+	return message
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := ctxUtil.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "missing user in context")
+	}
+	return userID, nil
+}
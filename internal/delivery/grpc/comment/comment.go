@@ -0,0 +1,154 @@
+package grp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	commentsv1 "main/pkg/proto/gen/comments/v1"
+	ctxUtil "main/pkg/utils/context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type RPCCommentHandler struct {
+	commentsv1.UnimplementedCommentServiceServer
+	logger         *slog.Logger
+	CommentUsecase CommentUsecase
+}
+
+type CommentUsecase interface {
+
+	//CreateComment comments the post on behalf of the user, replyTo is uuid.Nil for a top level comment.
+	CreateComment(ctx context.Context, userID, postID, replyTo uuid.UUID, content string) (entity.Comment, error)
+
+	//UpdateComment edits the content of one of the user's comments.
+	UpdateComment(ctx context.Context, userID, commentID uuid.UUID, content string) (entity.Comment, error)
+
+	//DeleteComment deletes one of the user's comments together with the replies to it.
+	DeleteComment(ctx context.Context, userID, commentID uuid.UUID) error
+
+	//ListComments returns a page of comments of the post or replies to replyTo, and the cursor of the next page.
+	ListComments(ctx context.Context, viewerID, postID, replyTo uuid.UUID, sort entity.CommentSort, cursor string, limit int) (comments []entity.Comment, nextCursor string, err error)
+}
+
+func NewCommentHandler(logger *slog.Logger, commentUsecase CommentUsecase) *RPCCommentHandler {
+	return &RPCCommentHandler{
+		logger:         logger,
+		CommentUsecase: commentUsecase,
+	}
+}
+
+// CreateComment comments a post on behalf of the caller.
+func (h *RPCCommentHandler) CreateComment(ctx context.Context, req *commentsv1.CreateCommentRequest) (*commentsv1.CreateCommentResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID := uuid.MustParse(req.GetPostId())
+	var replyTo uuid.UUID
+	if req.GetReplyTo() != "" {
+		replyTo = uuid.MustParse(req.GetReplyTo())
+	}
+
+	comment, err := h.CommentUsecase.CreateComment(ctx, userID, postID, replyTo, req.GetContent())
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "post or parent comment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	return &commentsv1.CreateCommentResponse{
+		Comment: commentToProto(comment),
+	}, nil
+}
+
+// UpdateComment edits the content of one of the caller's comments.
+func (h *RPCCommentHandler) UpdateComment(ctx context.Context, req *commentsv1.UpdateCommentRequest) (*commentsv1.UpdateCommentResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	commentID := uuid.MustParse(req.GetCommentId())
+
+	comment, err := h.CommentUsecase.UpdateComment(ctx, userID, commentID, req.GetContent())
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return nil, status.Error(codes.NotFound, "comment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	return &commentsv1.UpdateCommentResponse{
+		Comment: commentToProto(comment),
+	}, nil
+}
+
+// DeleteComment deletes one of the caller's comments with its replies.
+func (h *RPCCommentHandler) DeleteComment(ctx context.Context, req *commentsv1.DeleteCommentRequest) (*commentsv1.DeleteCommentResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	commentID := uuid.MustParse(req.GetCommentId())
+
+	err = h.CommentUsecase.DeleteComment(ctx, userID, commentID)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return nil, status.Error(codes.NotFound, "comment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return &commentsv1.DeleteCommentResponse{
+		Success: true,
+	}, nil
+}
+
+// ListComments returns a page of comments of a post or of replies to a comment.
+func (h *RPCCommentHandler) ListComments(ctx context.Context, req *commentsv1.ListCommentsRequest) (*commentsv1.ListCommentsResponse, error) {
+	postID := uuid.MustParse(req.GetPostId())
+	var replyTo uuid.UUID
+	if req.GetReplyTo() != "" {
+		replyTo = uuid.MustParse(req.GetReplyTo())
+	}
+	// public method, the caller is only known if they sent a valid access token
+	viewerID, _ := userIDFromContext(ctx)
+	comments, nextCursor, err := h.CommentUsecase.ListComments(ctx, viewerID, postID, replyTo, entity.CommentSort(req.GetSort()), req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	resp := &commentsv1.ListCommentsResponse{NextCursor: nextCursor}
+	for _, c := range comments {
+		resp.Comments = append(resp.Comments, commentToProto(c))
+	}
+	return resp, nil
+}
+
+func commentToProto(c entity.Comment) *commentsv1.Comment {
+	pb := &commentsv1.Comment{
+		Id:           c.ID.String(),
+		PostId:       c.PostID.String(),
+		UserId:       c.UserID.String(),
+		Content:      c.Content,
+		RepliesCount: int32(c.RepliesCount),
+		CreatedAt:    timestamppb.New(c.CreatedAt),
+		UpdatedAt:    timestamppb.New(c.UpdatedAt),
+	}
+	if c.ReplyTo != nil {
+		pb.ReplyTo = c.ReplyTo.String()
+	}
+	return pb
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := ctxUtil.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "missing user in context")
+	}
+	return userID, nil
+}
@@ -0,0 +1,121 @@
+package interceptor
+
+import (
+	"context"
+	"main/internal/delivery/gateway"
+	ctxUtil "main/pkg/utils/context"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RequestIDInterceptor puts the ID of the call into the context and sends it back in the x-request-id header.
+// The ID is taken from the x-request-id metadata, e.g. set by the gateway, or generated. It must come first in
+// the chain, so every other interceptor sees the ID.
+func RequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		id := getRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", id))
+		return handler(ctxUtil.WithRequestID(ctx, id), req)
+	}
+}
+
+// RequestIDStreamInterceptor puts the ID of the stream into its context like RequestIDInterceptor does for calls.
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		id := getRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs("x-request-id", id))
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctxUtil.WithRequestID(ss.Context(), id)})
+	}
+}
+
+func getRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get("x-request-id"); len(ids) > 0 && ids[0] != "" && len(ids[0]) <= 128 {
+		return ids[0]
+	}
+	return uuid.NewString()
+}
+
+// ClientInfoInterceptor puts the client IP and User-Agent into the context for sessions and audit events.
+func ClientInfoInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		return handler(ctxUtil.WithClientInfo(ctx, getClientIP(ctx), getUserAgent(ctx)), req)
+	}
+}
+
+// ClientInfoStreamInterceptor puts the client IP and User-Agent into the context of streams.
+func ClientInfoStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := ss.Context()
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctxUtil.WithClientInfo(ctx, getClientIP(ctx), getUserAgent(ctx))})
+	}
+}
+
+// getClientIP returns the address of the peer. Calls from the gateway carry the address of its HTTP client as the
+// last hop of x-forwarded-for, which the gateway adds itself; other callers could claim any address in the header,
+// so it is ignored for them.
+func getClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+	if p.AuthInfo != nil && p.AuthInfo.AuthType() == gateway.LoopbackAuthType {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if xff := md.Get("x-forwarded-for"); len(xff) > 0 {
+			hops := strings.Split(xff[len(xff)-1], ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+
+	addr := p.Addr.String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// getUserAgent extracts the User-Agent from gRPC metadata.
+func getUserAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+
+	// The HTTP gateway forwards the UA of its client, "user-agent" is then the one of the gateway
+	if ua := md.Get("grpc-gateway-user-agent"); len(ua) > 0 {
+		return ua[0]
+	}
+
+	//gRPC initially sets user-agent in "user-agent" metadata field
+	if ua := md.Get("user-agent"); len(ua) > 0 {
+		return ua[0]
+	}
+
+	return "unknown"
+}
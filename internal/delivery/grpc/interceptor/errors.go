@@ -0,0 +1,92 @@
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"main/pkg/apperror"
+	"main/pkg/errreport"
+	"main/pkg/i18n"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain is the domain of the ErrorInfo details, it scopes the error codes to this API.
+const errorDomain = "threads"
+
+// ErrorInterceptor turns the errors handlers return into statuses: an apperror.Error gets the code of its kind,
+// its machine-readable code as the reason of an ErrorInfo detail and its field violations as a BadRequest detail,
+// a status is returned as is and any other error is logged, sent to the reporter and returned as internal without
+// details. Messages are translated into the language of the "locale" or "accept-language" metadata.
+func ErrorInterceptor(logger *slog.Logger, translator *i18n.Translator, reporter errreport.Reporter) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, toStatus(ctx, logger, translator, reporter, info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// ErrorStreamInterceptor converts the errors of streaming handlers like ErrorInterceptor does for unary ones.
+func ErrorStreamInterceptor(logger *slog.Logger, translator *i18n.Translator, reporter errreport.Reporter) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatus(ss.Context(), logger, translator, reporter, info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+// locale returns the language the client asked for in the metadata of the call.
+func locale(ctx context.Context, translator *i18n.Translator) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return translator.Locale(append(md.Get("locale"), md.Get("accept-language")...)...)
+}
+
+func toStatus(ctx context.Context, logger *slog.Logger, translator *i18n.Translator, reporter errreport.Reporter, method string, err error) error {
+	if appErr := apperror.From(err); appErr != nil {
+		appErr = translator.Error(locale(ctx, translator), appErr)
+		details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: appErr.Code, Domain: errorDomain}}
+		if len(appErr.Fields) > 0 {
+			badRequest := &errdetails.BadRequest{}
+			for _, f := range appErr.Fields {
+				badRequest.FieldViolations = append(badRequest.FieldViolations,
+					&errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Description})
+			}
+			details = append(details, badRequest)
+		}
+		st := status.New(appErr.Kind.GRPCCode(), appErr.Message)
+		if withDetails, detailsErr := st.WithDetails(details...); detailsErr == nil {
+			st = withDetails
+		}
+		return st.Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	// the context of the call ended, e.g. the client went away or the deadline passed
+	if st := status.FromContextError(err); st.Code() != codes.Unknown {
+		return st.Err()
+	}
+	logger.Error("gRPC handler failed",
+		"method", method,
+		"err", err,
+	)
+	reporter.Report(ctx, err, map[string]string{"method": method})
+	return status.Error(codes.Internal, translator.Message(locale(ctx, translator), "internal server error"))
+}
@@ -2,10 +2,15 @@ package interceptor
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"main/domain/entity"
+	"main/pkg/errreport"
+	"main/pkg/jwt"
 	ctxUtil "main/pkg/utils/context"
 	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
@@ -15,46 +20,182 @@ import (
 )
 
 var publicMethods = map[string]struct{}{
-	"/auth.v1.AuthService/Register": {},
-	"/auth.v1.AuthService/Login":    {},
+	"/auth.v1.AuthService/Register":      {},
+	"/auth.v1.AuthService/Login":         {},
+	"/auth.v1.AuthService/CheckUsername": {},
+	// the confirmation token itself authenticates the request
+	"/auth.v1.AuthService/ConfirmEmailChange": {},
+	"/auth.v1.AuthService/ConfirmLogin":       {},
+	// posts and comments are readable without an account, like over HTTP
+	"/posts.v1.PostService/GetPost":             {},
+	"/posts.v1.PostService/GetHashtagPosts":     {},
+	"/posts.v1.PostService/GetTrendingHashtags": {},
+	"/posts.v1.PostService/SearchPosts":         {},
+	"/comments.v1.CommentService/ListComments":  {},
+	"/profile.v1.ProfileService/GetProfile":     {},
+	"/profile.v1.ProfileService/ListFollowers":  {},
+	"/profile.v1.ProfileService/ListFollowing":  {},
+	// probes of load balancers and orchestrators
+	"/grpc.health.v1.Health/Check": {},
+	"/grpc.health.v1.Health/List":  {},
+	"/grpc.health.v1.Health/Watch": {},
 }
 
 type JWTManager interface {
-	VerifyAccessToken(tokenString string) (userID uuid.UUID, err error)
+	ParseAccessToken(tokenString string) (jwt.AccessToken, error)
+}
+
+type TokenDenylist interface {
+	IsRevoked(ctx context.Context, userID, sessionID uuid.UUID, issuedAt time.Time) (bool, error)
+}
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plain string) (entity.APIKey, error)
 }
 
 // AuthInterceptor is a gRPC middleware that intercepts incoming requests to perform authentication.
-func AuthInterceptor(jwtManager JWTManager) grpc.UnaryServerInterceptor {
+// Tokens revoked through the denylist (logout-all, password change, block) are rejected.
+// Internal services may authenticate with an API key in the "x-api-key" metadata instead of a user token,
+// the call is then limited to the key's scopes.
+func AuthInterceptor(jwtManager JWTManager, denylist TokenDenylist, apiKeys APIKeyAuthenticator) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req any,
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (any, error) {
-		if _, ok := publicMethods[info.FullMethod]; ok {
-			// Public method, proceed without authentication
-			return handler(ctx, req)
-		}
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.Unauthenticated, "missing metadata")
+		newCtx, err := authenticate(ctx, info.FullMethod, jwtManager, denylist, apiKeys)
+		if err != nil {
+			return nil, err
 		}
+		return handler(newCtx, req)
+	}
+}
 
-		values := md["authorization"]
-		if len(values) == 0 {
-			return nil, status.Errorf(codes.Unauthenticated, "missing authorization token")
+// AuthStreamInterceptor authenticates streaming calls the same way AuthInterceptor does for unary ones.
+// The token is only checked when the stream is opened.
+func AuthStreamInterceptor(jwtManager JWTManager, denylist TokenDenylist, apiKeys APIKeyAuthenticator) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		newCtx, err := authenticate(ss.Context(), info.FullMethod, jwtManager, denylist, apiKeys)
+		if err != nil {
+			return err
 		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: newCtx})
+	}
+}
+
+// wrappedStream replaces the context of a server stream, so values set by interceptors reach the handler.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
 
-		accessToken := strings.TrimPrefix(values[0], "Bearer ")
+// authenticate verifies the API key or access token of the call and returns the context carrying the caller.
+func authenticate(ctx context.Context, fullMethod string, jwtManager JWTManager, denylist TokenDenylist, apiKeys APIKeyAuthenticator) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if _, public := publicMethods[fullMethod]; public {
+		// Public method, proceed without authentication. A valid access token still identifies the caller,
+		// e.g. to show them posts only their followers may see.
+		if ok && len(md["authorization"]) > 0 {
+			if newCtx, err := verifyAccessToken(ctx, md["authorization"][0], jwtManager, denylist); err == nil {
+				return newCtx, nil
+			}
+		}
+		return ctx, nil
+	}
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "missing metadata")
+	}
 
-		userID, err := jwtManager.VerifyAccessToken(accessToken)
+	if keys := md.Get("x-api-key"); len(keys) > 0 {
+		key, err := apiKeys.Authenticate(ctx, keys[0])
 		if err != nil {
-			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		if !key.Allows(fullMethod) {
+			return nil, status.Error(codes.PermissionDenied, "API key is not allowed to call this method")
 		}
+		return ctxUtil.WithPrincipal(ctx, ctxUtil.Principal{APIKeyID: key.ID, Method: ctxUtil.AuthAPIKey}), nil
+	}
+
+	values := md["authorization"]
+	if len(values) == 0 {
+		return nil, status.Errorf(codes.Unauthenticated, "missing authorization token")
+	}
+	return verifyAccessToken(ctx, values[0], jwtManager, denylist)
+}
 
-		newCtx := ctxUtil.NewContext(ctx, userID.String())
+// verifyAccessToken checks the bearer token from the authorization metadata and returns the context carrying the caller.
+func verifyAccessToken(ctx context.Context, authorization string, jwtManager JWTManager, denylist TokenDenylist) (context.Context, error) {
+	accessToken := strings.TrimPrefix(authorization, "Bearer ")
 
-		return handler(newCtx, req)
+	token, err := jwtManager.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+
+	revoked, err := denylist.IsRevoked(ctx, token.UserID, token.SessionID, token.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+	}
+
+	return ctxUtil.WithPrincipal(ctx, ctxUtil.Principal{
+		UserID:    token.UserID,
+		SessionID: token.SessionID,
+		Roles:     token.Roles,
+		Method:    ctxUtil.AuthAccessToken,
+	}), nil
+}
+
+// RoleInterceptor enforces role requirements declared per method: a call to a method listed in methodRoles
+// is allowed if the caller has any of the listed roles. Unlisted methods are not restricted.
+// It must be chained after AuthInterceptor.
+func RoleInterceptor(methodRoles map[string][]string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		required, ok := methodRoles[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		if principal, _ := ctxUtil.PrincipalFromContext(ctx); !principal.HasAnyRole(required...) {
+			return nil, status.Error(codes.PermissionDenied, "insufficient role")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RoleStreamInterceptor checks the roles of the caller opening a stream the same way RoleInterceptor does.
+func RoleStreamInterceptor(methodRoles map[string][]string) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		required, ok := methodRoles[info.FullMethod]
+		if !ok {
+			return handler(srv, ss)
+		}
+		if principal, _ := ctxUtil.PrincipalFromContext(ss.Context()); !principal.HasAnyRole(required...) {
+			return status.Error(codes.PermissionDenied, "insufficient role")
+		}
+		return handler(srv, ss)
 	}
 }
 
@@ -98,8 +239,50 @@ func LoggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
 	}
 }
 
-// RecoveryInterceptor is a gRPC middleware that recovers from panics in handlers and logs the panic details.
-func RecoveryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+// LoggingStreamInterceptor logs streams once they end, with how long they were open. Messages aren't logged,
+// a stream may carry any number of them.
+func LoggingStreamInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+		err := handler(srv, ss)
+
+		if err == nil {
+			logger.Info("gRPC Stream",
+				"method", info.FullMethod,
+				"duration", time.Since(start),
+			)
+			return nil
+		}
+
+		st, ok := status.FromError(err)
+
+		if ok {
+			logger.Warn("gRPC Client Error",
+				"method", info.FullMethod,
+				"code", st.Code(),
+				"msg", st.Message(),
+				"duration", time.Since(start),
+			)
+			return err
+		}
+
+		logger.Error("gRPC SYSTEM ERROR",
+			"method", info.FullMethod,
+			"err", err,
+		)
+
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// RecoveryInterceptor is a gRPC middleware that recovers from panics in handlers, logs the panic details and
+// reports the panic.
+func RecoveryInterceptor(logger *slog.Logger, reporter errreport.Reporter) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
@@ -116,6 +299,7 @@ func RecoveryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
 					"panic", r,
 					"stack", stackTrace,
 				)
+				reporter.Report(ctx, fmt.Errorf("panic: %v", r), map[string]string{"method": info.FullMethod})
 
 				err = status.Errorf(codes.Internal, "internal server error")
 			}
@@ -124,3 +308,27 @@ func RecoveryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
 		return handler(ctx, req)
 	}
 }
+
+// RecoveryStreamInterceptor recovers from panics in streaming handlers like RecoveryInterceptor does for unary ones.
+func RecoveryStreamInterceptor(logger *slog.Logger, reporter errreport.Reporter) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("PANIC RECOVERED",
+					"method", info.FullMethod,
+					"panic", r,
+					"stack", string(debug.Stack()),
+				)
+				reporter.Report(ss.Context(), fmt.Errorf("panic: %v", r), map[string]string{"method": info.FullMethod})
+				err = status.Errorf(codes.Internal, "internal server error")
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
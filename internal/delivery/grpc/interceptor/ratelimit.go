@@ -0,0 +1,86 @@
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"main/pkg/apperror"
+	"main/pkg/ratelimit"
+	ctxUtil "main/pkg/utils/context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, rule ratelimit.Rule) (ratelimit.Result, error)
+}
+
+// RateLimitInterceptor limits the methods having a rule per caller: the user, or the API key of an internal
+// service, when the call is authenticated and the client IP otherwise. It must run after AuthInterceptor.
+// Calls are let through while Redis is unavailable.
+func RateLimitInterceptor(limiter RateLimiter, rules map[string]ratelimit.Rule, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		header, err := checkRateLimit(ctx, info.FullMethod, limiter, rules, logger)
+		if header != nil {
+			_ = grpc.SetHeader(ctx, header)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor limits opening streams the same way RateLimitInterceptor limits unary calls.
+func RateLimitStreamInterceptor(limiter RateLimiter, rules map[string]ratelimit.Rule, logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		header, err := checkRateLimit(ss.Context(), info.FullMethod, limiter, rules, logger)
+		if header != nil {
+			_ = ss.SetHeader(header)
+		}
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// checkRateLimit counts the call against the rule of the method and returns the rate limit headers of the response.
+func checkRateLimit(ctx context.Context, fullMethod string, limiter RateLimiter, rules map[string]ratelimit.Rule, logger *slog.Logger) (metadata.MD, error) {
+	rule, ok := rules[fullMethod]
+	if !ok {
+		return nil, nil
+	}
+
+	caller := "ip:" + getClientIP(ctx)
+	if principal, ok := ctxUtil.PrincipalFromContext(ctx); ok && principal.IsUser() {
+		caller = "user:" + principal.UserID.String()
+	} else if ok {
+		caller = "api_key:" + principal.APIKeyID.String()
+	}
+	result, err := limiter.Allow(ctx, fullMethod+"|"+caller, rule)
+	if err != nil {
+		logger.Warn("Rate limiter unavailable", "method", fullMethod, "error", err)
+		return nil, nil
+	}
+
+	header := metadata.MD{}
+	for name, value := range result.Headers() {
+		header.Set(strings.ToLower(name), value)
+	}
+	if !result.Allowed {
+		return header, apperror.ResourceExhausted("rate_limited", "too many requests, try again later")
+	}
+	return header, nil
+}
@@ -0,0 +1,56 @@
+package interceptor
+
+import (
+	"context"
+	"main/pkg/validate"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// ValidationInterceptor checks requests against the validate.v1.field rules of their messages before the handler
+// runs, so handlers get well-formed requests. Invalid requests fail with every violation at once.
+// It must be chained after ErrorInterceptor, which turns the violations into a status.
+func ValidationInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if m, ok := req.(proto.Message); ok {
+			if err := validate.Message(m); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ValidationStreamInterceptor checks every message a streaming handler receives like ValidationInterceptor does
+// for unary requests.
+func ValidationStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &validatingStream{ServerStream: ss})
+	}
+}
+
+// validatingStream validates the messages received from the client.
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if msg, ok := m.(proto.Message); ok {
+		return validate.Message(msg)
+	}
+	return nil
+}
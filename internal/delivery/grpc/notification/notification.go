@@ -0,0 +1,232 @@
+package grp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	notificationsv1 "main/pkg/proto/gen/notifications/v1"
+	ctxUtil "main/pkg/utils/context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type RPCNotificationHandler struct {
+	notificationsv1.UnimplementedNotificationServiceServer
+	logger              *slog.Logger
+	NotificationUsecase NotificationUsecase
+	PushUsecase         PushUsecase
+}
+
+type NotificationUsecase interface {
+
+	//ListNotifications returns a page of the user's notifications, newest first, and the cursor of the next page.
+	ListNotifications(ctx context.Context, userID uuid.UUID, cursor string, limit int) (notifications []entity.Notification, nextCursor string, err error)
+
+	//CountUnread returns the number of the user's unread notifications.
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+
+	//MarkRead marks one of the user's notifications read.
+	MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error
+
+	//MarkAllRead marks all of the user's notifications read.
+	MarkAllRead(ctx context.Context, userID uuid.UUID) error
+}
+
+type PushUsecase interface {
+
+	//RegisterDevice registers a device of the user to push notifications to.
+	RegisterDevice(ctx context.Context, userID uuid.UUID, device entity.PushDevice) (entity.PushDevice, error)
+
+	//UnregisterDevice stops pushing notifications to one of the user's devices.
+	UnregisterDevice(ctx context.Context, userID, deviceID uuid.UUID) error
+
+	//ListDevices returns the user's devices.
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]entity.PushDevice, error)
+}
+
+func NewNotificationHandler(logger *slog.Logger, notificationUsecase NotificationUsecase, pushUsecase PushUsecase) *RPCNotificationHandler {
+	return &RPCNotificationHandler{
+		logger:              logger,
+		NotificationUsecase: notificationUsecase,
+		PushUsecase:         pushUsecase,
+	}
+}
+
+// ListNotifications returns a page of the caller's notifications, newest first.
+func (h *RPCNotificationHandler) ListNotifications(ctx context.Context, req *notificationsv1.ListNotificationsRequest) (*notificationsv1.ListNotificationsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, nextCursor, err := h.NotificationUsecase.ListNotifications(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	resp := &notificationsv1.ListNotificationsResponse{NextCursor: nextCursor}
+	for _, n := range notifications {
+		resp.Notifications = append(resp.Notifications, notificationToProto(n))
+	}
+	return resp, nil
+}
+
+// GetUnreadCount returns the number of the caller's unread notifications.
+func (h *RPCNotificationHandler) GetUnreadCount(ctx context.Context, req *notificationsv1.GetUnreadCountRequest) (*notificationsv1.GetUnreadCountResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := h.NotificationUsecase.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return &notificationsv1.GetUnreadCountResponse{
+		UnreadCount: int32(count),
+	}, nil
+}
+
+// MarkRead marks one of the caller's notifications read.
+func (h *RPCNotificationHandler) MarkRead(ctx context.Context, req *notificationsv1.MarkReadRequest) (*notificationsv1.MarkReadResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	notificationID := uuid.MustParse(req.GetNotificationId())
+
+	err = h.NotificationUsecase.MarkRead(ctx, userID, notificationID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "notification not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return &notificationsv1.MarkReadResponse{
+		Success: true,
+	}, nil
+}
+
+// MarkAllRead marks all of the caller's notifications read.
+func (h *RPCNotificationHandler) MarkAllRead(ctx context.Context, req *notificationsv1.MarkAllReadRequest) (*notificationsv1.MarkAllReadResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.NotificationUsecase.MarkAllRead(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return &notificationsv1.MarkAllReadResponse{
+		Success: true,
+	}, nil
+}
+
+// RegisterDevice registers a device of the caller to push notifications to.
+func (h *RPCNotificationHandler) RegisterDevice(ctx context.Context, req *notificationsv1.RegisterDeviceRequest) (*notificationsv1.RegisterDeviceResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := h.PushUsecase.RegisterDevice(ctx, userID, entity.PushDevice{
+		Platform: entity.PushPlatform(req.GetPlatform()),
+		Token:    req.GetToken(),
+		P256dh:   req.GetP256Dh(),
+		Auth:     req.GetAuth(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+	return &notificationsv1.RegisterDeviceResponse{
+		Device: deviceToProto(device),
+	}, nil
+}
+
+// UnregisterDevice stops pushing notifications to one of the caller's devices.
+func (h *RPCNotificationHandler) UnregisterDevice(ctx context.Context, req *notificationsv1.UnregisterDeviceRequest) (*notificationsv1.UnregisterDeviceResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deviceID := uuid.MustParse(req.GetDeviceId())
+
+	err = h.PushUsecase.UnregisterDevice(ctx, userID, deviceID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "device not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unregister device: %w", err)
+	}
+	return &notificationsv1.UnregisterDeviceResponse{
+		Success: true,
+	}, nil
+}
+
+// ListDevices returns the devices the caller registered.
+func (h *RPCNotificationHandler) ListDevices(ctx context.Context, req *notificationsv1.ListDevicesRequest) (*notificationsv1.ListDevicesResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := h.PushUsecase.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	resp := &notificationsv1.ListDevicesResponse{}
+	for _, d := range devices {
+		resp.Devices = append(resp.Devices, deviceToProto(d))
+	}
+	return resp, nil
+}
+
+func deviceToProto(d entity.PushDevice) *notificationsv1.Device {
+	return &notificationsv1.Device{
+		Id:        d.ID.String(),
+		Platform:  string(d.Platform),
+		Token:     d.Token,
+		CreatedAt: timestamppb.New(d.CreatedAt),
+	}
+}
+
+func notificationToProto(n entity.Notification) *notificationsv1.Notification {
+	pb := &notificationsv1.Notification{
+		Id:   n.ID.String(),
+		Type: string(n.Type),
+		Actor: &notificationsv1.Actor{
+			UserId:     n.Actor.ID.String(),
+			Username:   n.Actor.Username,
+			Name:       n.Actor.Name,
+			AvatarUrl:  n.Actor.AvatarURL,
+			Following:  n.Actor.Following,
+			FollowsYou: n.Actor.FollowsYou,
+		},
+		Read:      n.Read,
+		CreatedAt: timestamppb.New(n.CreatedAt),
+	}
+	if n.PostID != nil {
+		pb.PostId = n.PostID.String()
+	}
+	if n.CommentID != nil {
+		pb.CommentId = n.CommentID.String()
+	}
+	if n.ChatID != nil {
+		pb.ChatId = n.ChatID.String()
+	}
+	return pb
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := ctxUtil.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "missing user in context")
+	}
+	return userID, nil
+}
@@ -0,0 +1,338 @@
+package grp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	postsv1 "main/pkg/proto/gen/posts/v1"
+	ctxUtil "main/pkg/utils/context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type RPCPostHandler struct {
+	postsv1.UnimplementedPostServiceServer
+	logger        *slog.Logger
+	PostUsecase   PostUsecase
+	FeedUsecase   FeedUsecase
+	SearchUsecase SearchUsecase
+}
+
+type PostUsecase interface {
+
+	//CreatePost publishes a post of the user, quoteOfID is uuid.Nil for a regular post.
+	CreatePost(ctx context.Context, userID uuid.UUID, description, mediaURL string, quoteOfID uuid.UUID, visibility entity.PostVisibility) (entity.Post, error)
+
+	//GetPost returns the post if the viewer may see it.
+	GetPost(ctx context.Context, viewerID, postID uuid.UUID) (entity.Post, error)
+
+	//UpdatePost edits the description and, unless empty, the visibility of one of the user's posts at the version.
+	UpdatePost(ctx context.Context, userID, postID uuid.UUID, version int64, description string, visibility entity.PostVisibility) (entity.Post, error)
+
+	//DeletePost deletes one of the user's posts.
+	DeletePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//LikePost likes the post on behalf of the user, liking an already liked post succeeds.
+	LikePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//UnlikePost removes the user's like from the post.
+	UnlikePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//Repost shares the post with the user's followers, reposting an already reposted post succeeds.
+	Repost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//Unrepost removes the user's repost of the post.
+	Unrepost(ctx context.Context, userID, postID uuid.UUID) error
+}
+
+type FeedUsecase interface {
+
+	//GetFeed returns a page of the user's home timeline, newest first, and the cursor of the next page.
+	GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) (items []entity.FeedItem, nextCursor string, err error)
+
+	//GetHashtagPosts returns a page of posts tagged with the hashtag, newest first, and the cursor of the next page.
+	GetHashtagPosts(ctx context.Context, viewerID uuid.UUID, tag, cursor string, limit int) (posts []entity.Post, nextCursor string, err error)
+
+	//TrendingHashtags returns the most used hashtags of the last day.
+	TrendingHashtags(ctx context.Context, limit int) ([]entity.TrendingHashtag, error)
+}
+
+type SearchUsecase interface {
+
+	//SearchPosts returns a page of posts matching the query, most relevant first, and the cursor of the next page.
+	SearchPosts(ctx context.Context, viewerID uuid.UUID, query, cursor string, limit int) (posts []entity.RankedPost, nextCursor string, err error)
+}
+
+func NewPostHandler(logger *slog.Logger, postUsecase PostUsecase, feedUsecase FeedUsecase, searchUsecase SearchUsecase) *RPCPostHandler {
+	return &RPCPostHandler{
+		logger:        logger,
+		PostUsecase:   postUsecase,
+		FeedUsecase:   feedUsecase,
+		SearchUsecase: searchUsecase,
+	}
+}
+
+// CreatePost publishes a post of the caller.
+func (h *RPCPostHandler) CreatePost(ctx context.Context, req *postsv1.CreatePostRequest) (*postsv1.CreatePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var quoteOfID uuid.UUID
+	if req.GetQuoteOfId() != "" {
+		quoteOfID = uuid.MustParse(req.GetQuoteOfId())
+	}
+
+	post, err := h.PostUsecase.CreatePost(ctx, userID, req.GetDescription(), req.GetMediaUrl(), quoteOfID, entity.PostVisibility(req.GetVisibility()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "quoted post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
+	}
+	return &postsv1.CreatePostResponse{
+		Post: postToProto(post),
+	}, nil
+}
+
+// GetPost returns the post.
+func (h *RPCPostHandler) GetPost(ctx context.Context, req *postsv1.GetPostRequest) (*postsv1.GetPostResponse, error) {
+	postID := uuid.MustParse(req.GetPostId())
+
+	// public method, the caller is only known if they sent a valid access token
+	viewerID, _ := userIDFromContext(ctx)
+	post, err := h.PostUsecase.GetPost(ctx, viewerID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return &postsv1.GetPostResponse{
+		Post: postToProto(post),
+	}, nil
+}
+
+// UpdatePost edits the description of one of the caller's posts.
+func (h *RPCPostHandler) UpdatePost(ctx context.Context, req *postsv1.UpdatePostRequest) (*postsv1.UpdatePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID := uuid.MustParse(req.GetPostId())
+
+	post, err := h.PostUsecase.UpdatePost(ctx, userID, postID, req.GetVersion(), req.GetDescription(), entity.PostVisibility(req.GetVisibility()))
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	return &postsv1.UpdatePostResponse{
+		Post: postToProto(post),
+	}, nil
+}
+
+// DeletePost deletes one of the caller's posts.
+func (h *RPCPostHandler) DeletePost(ctx context.Context, req *postsv1.DeletePostRequest) (*postsv1.DeletePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID := uuid.MustParse(req.GetPostId())
+
+	err = h.PostUsecase.DeletePost(ctx, userID, postID)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete post: %w", err)
+	}
+	return &postsv1.DeletePostResponse{
+		Success: true,
+	}, nil
+}
+
+// LikePost likes the post on behalf of the caller.
+func (h *RPCPostHandler) LikePost(ctx context.Context, req *postsv1.LikePostRequest) (*postsv1.LikePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID := uuid.MustParse(req.GetPostId())
+
+	err = h.PostUsecase.LikePost(ctx, userID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to like post: %w", err)
+	}
+	return &postsv1.LikePostResponse{
+		Success: true,
+	}, nil
+}
+
+// UnlikePost removes the caller's like from the post.
+func (h *RPCPostHandler) UnlikePost(ctx context.Context, req *postsv1.UnlikePostRequest) (*postsv1.UnlikePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID := uuid.MustParse(req.GetPostId())
+
+	if err := h.PostUsecase.UnlikePost(ctx, userID, postID); err != nil {
+		return nil, fmt.Errorf("failed to unlike post: %w", err)
+	}
+	return &postsv1.UnlikePostResponse{
+		Success: true,
+	}, nil
+}
+
+// Repost shares the post with the caller's followers.
+func (h *RPCPostHandler) Repost(ctx context.Context, req *postsv1.RepostRequest) (*postsv1.RepostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID := uuid.MustParse(req.GetPostId())
+
+	err = h.PostUsecase.Repost(ctx, userID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to repost post: %w", err)
+	}
+	return &postsv1.RepostResponse{
+		Success: true,
+	}, nil
+}
+
+// Unrepost removes the caller's repost of the post.
+func (h *RPCPostHandler) Unrepost(ctx context.Context, req *postsv1.UnrepostRequest) (*postsv1.UnrepostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID := uuid.MustParse(req.GetPostId())
+
+	if err := h.PostUsecase.Unrepost(ctx, userID, postID); err != nil {
+		return nil, fmt.Errorf("failed to undo repost: %w", err)
+	}
+	return &postsv1.UnrepostResponse{
+		Success: true,
+	}, nil
+}
+
+// GetFeed returns a page of the caller's home timeline.
+func (h *RPCPostHandler) GetFeed(ctx context.Context, req *postsv1.GetFeedRequest) (*postsv1.GetFeedResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items, nextCursor, err := h.FeedUsecase.GetFeed(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+
+	resp := &postsv1.GetFeedResponse{NextCursor: nextCursor}
+	for _, item := range items {
+		pbItem := &postsv1.FeedItem{
+			Post: postToProto(item.Post),
+			At:   timestamppb.New(item.At),
+		}
+		if item.RepostedBy != nil {
+			pbItem.RepostedBy = item.RepostedBy.String()
+		}
+		resp.Items = append(resp.Items, pbItem)
+	}
+	return resp, nil
+}
+
+// GetHashtagPosts returns a page of posts tagged with the hashtag.
+func (h *RPCPostHandler) GetHashtagPosts(ctx context.Context, req *postsv1.GetHashtagPostsRequest) (*postsv1.GetHashtagPostsResponse, error) {
+	viewerID, _ := userIDFromContext(ctx)
+	posts, nextCursor, err := h.FeedUsecase.GetHashtagPosts(ctx, viewerID, req.GetTag(), req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hashtag posts: %w", err)
+	}
+
+	resp := &postsv1.GetHashtagPostsResponse{NextCursor: nextCursor}
+	for _, p := range posts {
+		resp.Posts = append(resp.Posts, postToProto(p))
+	}
+	return resp, nil
+}
+
+// GetTrendingHashtags returns the most used hashtags of the last day.
+func (h *RPCPostHandler) GetTrendingHashtags(ctx context.Context, req *postsv1.GetTrendingHashtagsRequest) (*postsv1.GetTrendingHashtagsResponse, error) {
+	hashtags, err := h.FeedUsecase.TrendingHashtags(ctx, int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trending hashtags: %w", err)
+	}
+
+	resp := &postsv1.GetTrendingHashtagsResponse{}
+	for _, t := range hashtags {
+		resp.Hashtags = append(resp.Hashtags, &postsv1.TrendingHashtag{
+			Tag:        t.Tag,
+			PostsCount: int32(t.PostsCount),
+		})
+	}
+	return resp, nil
+}
+
+// SearchPosts returns a page of posts matching the query.
+func (h *RPCPostHandler) SearchPosts(ctx context.Context, req *postsv1.SearchPostsRequest) (*postsv1.SearchPostsResponse, error) {
+	viewerID, _ := userIDFromContext(ctx)
+	posts, nextCursor, err := h.SearchUsecase.SearchPosts(ctx, viewerID, req.GetQuery(), req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+
+	resp := &postsv1.SearchPostsResponse{NextCursor: nextCursor}
+	for _, p := range posts {
+		resp.Posts = append(resp.Posts, postToProto(p.Post))
+	}
+	return resp, nil
+}
+
+func postToProto(p entity.Post) *postsv1.Post {
+	pb := &postsv1.Post{
+		Id:            p.ID.String(),
+		UserId:        p.UserID.String(),
+		Description:   p.Description,
+		MediaUrl:      p.MediaURL,
+		IsVideo:       p.IsVideo,
+		Duration:      int32(p.Duration),
+		CreatedAt:     timestamppb.New(p.CreatedAt),
+		UpdatedAt:     timestamppb.New(p.UpdatedAt),
+		LikesCount:    int32(p.LikesCount),
+		RepostsCount:  int32(p.RepostsCount),
+		QuotesCount:   int32(p.QuotesCount),
+		CommentsCount: int32(p.CommentsCount),
+		Hashtags:      p.Hashtags,
+		Visibility:    string(p.Visibility),
+		Version:       p.Version,
+	}
+	if p.QuoteOfID != nil {
+		pb.QuoteOfId = p.QuoteOfID.String()
+	}
+	return pb
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := ctxUtil.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "missing user in context")
+	}
+	return userID, nil
+}
@@ -0,0 +1,517 @@
+package grp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	profilev1 "main/pkg/proto/gen/profile/v1"
+	ctxUtil "main/pkg/utils/context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type RPCProfileHandler struct {
+	profilev1.UnimplementedProfileServiceServer
+	logger              *slog.Logger
+	ProfileUsecase      ProfileUsecase
+	FollowUsecase       FollowUsecase
+	BlacklistUsecase    BlacklistUsecase
+	SettingsUsecase     SettingsUsecase
+	CloseFriendsUsecase CloseFriendsUsecase
+}
+
+type ProfileUsecase interface {
+
+	//GetProfile returns the user's profile as the viewer may see it.
+	GetProfile(ctx context.Context, viewerID, userID uuid.UUID) (entity.Profile, error)
+
+	//UpdateProfile changes the non-nil fields of the user's profile and returns the updated profile.
+	UpdateProfile(ctx context.Context, userID uuid.UUID, update entity.ProfileUpdate) (entity.Profile, error)
+}
+
+type FollowUsecase interface {
+
+	//Follow makes the user follow another account, or asks to if it is private, following an already followed account succeeds.
+	Follow(ctx context.Context, userID, followeeID uuid.UUID) (entity.FollowState, error)
+
+	//Unfollow stops the user following another account or withdraws the request, unfollowing an account that isn't followed succeeds.
+	Unfollow(ctx context.Context, userID, followeeID uuid.UUID) error
+
+	//ListFollowers returns a page of the accounts following the user and the cursor of the next page.
+	ListFollowers(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) (entries []entity.FollowEntry, nextCursor string, err error)
+
+	//ListFollowing returns a page of the accounts the user follows and the cursor of the next page.
+	ListFollowing(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) (entries []entity.FollowEntry, nextCursor string, err error)
+
+	//ListFollowRequests returns a page of the pending requests to follow the user and the cursor of the next page.
+	ListFollowRequests(ctx context.Context, userID uuid.UUID, cursor string, limit int) (requests []entity.FollowRequest, nextCursor string, err error)
+
+	//ApproveFollowRequest lets the follower follow the user's private account.
+	ApproveFollowRequest(ctx context.Context, userID, followerID uuid.UUID) error
+
+	//RejectFollowRequest turns down the follower's request to follow the user.
+	RejectFollowRequest(ctx context.Context, userID, followerID uuid.UUID) error
+}
+
+type BlacklistUsecase interface {
+
+	//Block blocks another user on behalf of the user and ends the follows between them.
+	Block(ctx context.Context, userID, blockedID uuid.UUID) error
+
+	//Unblock removes the user's block of another user.
+	Unblock(ctx context.Context, userID, blockedID uuid.UUID) error
+
+	//ListBlocked returns a page of the users the user blocked and the cursor of the next page.
+	ListBlocked(ctx context.Context, userID uuid.UUID, cursor string, limit int) (entries []entity.BlockedEntry, nextCursor string, err error)
+}
+
+type SettingsUsecase interface {
+
+	//GetSettings returns the settings of the user.
+	GetSettings(ctx context.Context, userID uuid.UUID) (entity.UserSettings, error)
+
+	//UpdateSettings changes the non-nil settings of the user and returns the updated settings.
+	UpdateSettings(ctx context.Context, userID uuid.UUID, update entity.SettingsUpdate) (entity.UserSettings, error)
+}
+
+type CloseFriendsUsecase interface {
+
+	//AddCloseFriend puts another user on the user's close friends list.
+	AddCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error
+
+	//RemoveCloseFriend takes another user off the user's close friends list.
+	RemoveCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error
+
+	//ListCloseFriends returns a page of the user's close friends list and the cursor of the next page.
+	ListCloseFriends(ctx context.Context, userID uuid.UUID, cursor string, limit int) (entries []entity.CloseFriendEntry, nextCursor string, err error)
+}
+
+func NewProfileHandler(logger *slog.Logger, profileUsecase ProfileUsecase, followUsecase FollowUsecase, blacklistUsecase BlacklistUsecase, settingsUsecase SettingsUsecase, closeFriendsUsecase CloseFriendsUsecase) *RPCProfileHandler {
+	return &RPCProfileHandler{
+		logger:              logger,
+		ProfileUsecase:      profileUsecase,
+		FollowUsecase:       followUsecase,
+		BlacklistUsecase:    blacklistUsecase,
+		SettingsUsecase:     settingsUsecase,
+		CloseFriendsUsecase: closeFriendsUsecase,
+	}
+}
+
+// GetProfile returns a user's profile, the caller is optional.
+func (h *RPCProfileHandler) GetProfile(ctx context.Context, req *profilev1.GetProfileRequest) (*profilev1.GetProfileResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	viewerID, _ := userIDFromContext(ctx)
+	profile, err := h.ProfileUsecase.GetProfile(ctx, viewerID, userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	return &profilev1.GetProfileResponse{
+		Profile: profileToProto(profile),
+	}, nil
+}
+
+// UpdateProfile edits the caller's profile.
+func (h *RPCProfileHandler) UpdateProfile(ctx context.Context, req *profilev1.UpdateProfileRequest) (*profilev1.UpdateProfileResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update := entity.ProfileUpdate{
+		Version:   req.GetVersion(),
+		Name:      req.Name,
+		Bio:       req.Bio,
+		AvatarURL: req.AvatarUrl,
+	}
+	if req.Gender != nil {
+		gender := entity.Gender(req.GetGender())
+		update.Gender = &gender
+	}
+	if req.Age != nil {
+		age := int(req.GetAge())
+		update.Age = &age
+	}
+
+	profile, err := h.ProfileUsecase.UpdateProfile(ctx, userID, update)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "profile not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	return &profilev1.UpdateProfileResponse{
+		Profile: profileToProto(profile),
+	}, nil
+}
+
+// Follow makes the caller follow a user.
+func (h *RPCProfileHandler) Follow(ctx context.Context, req *profilev1.FollowRequest) (*profilev1.FollowResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	followeeID := uuid.MustParse(req.GetUserId())
+
+	state, err := h.FollowUsecase.Follow(ctx, userID, followeeID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow user: %w", err)
+	}
+	return &profilev1.FollowResponse{
+		Success:   true,
+		Requested: state == entity.FollowStateRequested,
+	}, nil
+}
+
+// Unfollow makes the caller stop following a user.
+func (h *RPCProfileHandler) Unfollow(ctx context.Context, req *profilev1.UnfollowRequest) (*profilev1.UnfollowResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	followeeID := uuid.MustParse(req.GetUserId())
+
+	if err := h.FollowUsecase.Unfollow(ctx, userID, followeeID); err != nil {
+		return nil, fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	return &profilev1.UnfollowResponse{
+		Success: true,
+	}, nil
+}
+
+// ListFollowRequests returns a page of the pending requests to follow the caller.
+func (h *RPCProfileHandler) ListFollowRequests(ctx context.Context, req *profilev1.ListFollowRequestsRequest) (*profilev1.ListFollowRequestsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, nextCursor, err := h.FollowUsecase.ListFollowRequests(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list follow requests: %w", err)
+	}
+
+	resp := &profilev1.ListFollowRequestsResponse{NextCursor: nextCursor}
+	for _, fr := range requests {
+		resp.Entries = append(resp.Entries, &profilev1.FollowRequestEntry{
+			User:        userCardToProto(fr.User),
+			RequestedAt: timestamppb.New(fr.RequestedAt),
+		})
+	}
+	return resp, nil
+}
+
+// ApproveFollowRequest lets a user follow the caller.
+func (h *RPCProfileHandler) ApproveFollowRequest(ctx context.Context, req *profilev1.ApproveFollowRequestRequest) (*profilev1.ApproveFollowRequestResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	followerID := uuid.MustParse(req.GetUserId())
+
+	err = h.FollowUsecase.ApproveFollowRequest(ctx, userID, followerID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "follow request not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve follow request: %w", err)
+	}
+	return &profilev1.ApproveFollowRequestResponse{
+		Success: true,
+	}, nil
+}
+
+// RejectFollowRequest turns down a user's request to follow the caller.
+func (h *RPCProfileHandler) RejectFollowRequest(ctx context.Context, req *profilev1.RejectFollowRequestRequest) (*profilev1.RejectFollowRequestResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	followerID := uuid.MustParse(req.GetUserId())
+
+	err = h.FollowUsecase.RejectFollowRequest(ctx, userID, followerID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "follow request not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject follow request: %w", err)
+	}
+	return &profilev1.RejectFollowRequestResponse{
+		Success: true,
+	}, nil
+}
+
+// ListFollowers returns a page of the followers of a user, the caller is optional.
+func (h *RPCProfileHandler) ListFollowers(ctx context.Context, req *profilev1.ListFollowsRequest) (*profilev1.ListFollowsResponse, error) {
+	return h.listFollows(ctx, req, h.FollowUsecase.ListFollowers)
+}
+
+// ListFollowing returns a page of the accounts a user follows, the caller is optional.
+func (h *RPCProfileHandler) ListFollowing(ctx context.Context, req *profilev1.ListFollowsRequest) (*profilev1.ListFollowsResponse, error) {
+	return h.listFollows(ctx, req, h.FollowUsecase.ListFollowing)
+}
+
+type listFunc func(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) ([]entity.FollowEntry, string, error)
+
+func (h *RPCProfileHandler) listFollows(ctx context.Context, req *profilev1.ListFollowsRequest, list listFunc) (*profilev1.ListFollowsResponse, error) {
+	userID := uuid.MustParse(req.GetUserId())
+
+	viewerID, _ := userIDFromContext(ctx)
+	entries, nextCursor, err := list(ctx, viewerID, userID, req.GetCursor(), int(req.GetLimit()))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list follows: %w", err)
+	}
+
+	resp := &profilev1.ListFollowsResponse{NextCursor: nextCursor}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &profilev1.FollowEntry{
+			User:       userCardToProto(e.User),
+			FollowedAt: timestamppb.New(e.FollowedAt),
+		})
+	}
+	return resp, nil
+}
+
+// BlockUser blocks a user on behalf of the caller.
+func (h *RPCProfileHandler) BlockUser(ctx context.Context, req *profilev1.BlockUserRequest) (*profilev1.BlockUserResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blockedID := uuid.MustParse(req.GetUserId())
+
+	err = h.BlacklistUsecase.Block(ctx, userID, blockedID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to block user: %w", err)
+	}
+	return &profilev1.BlockUserResponse{
+		Success: true,
+	}, nil
+}
+
+// UnblockUser removes the caller's block of a user.
+func (h *RPCProfileHandler) UnblockUser(ctx context.Context, req *profilev1.UnblockUserRequest) (*profilev1.UnblockUserResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blockedID := uuid.MustParse(req.GetUserId())
+
+	if err := h.BlacklistUsecase.Unblock(ctx, userID, blockedID); err != nil {
+		return nil, fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return &profilev1.UnblockUserResponse{
+		Success: true,
+	}, nil
+}
+
+// ListBlockedUsers returns a page of the users the caller blocked.
+func (h *RPCProfileHandler) ListBlockedUsers(ctx context.Context, req *profilev1.ListBlockedUsersRequest) (*profilev1.ListBlockedUsersResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, nextCursor, err := h.BlacklistUsecase.ListBlocked(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocked users: %w", err)
+	}
+
+	resp := &profilev1.ListBlockedUsersResponse{NextCursor: nextCursor}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &profilev1.BlockedEntry{
+			User:      userCardToProto(e.User),
+			BlockedAt: timestamppb.New(e.BlockedAt),
+		})
+	}
+	return resp, nil
+}
+
+// AddCloseFriend puts a user on the caller's close friends list.
+func (h *RPCProfileHandler) AddCloseFriend(ctx context.Context, req *profilev1.AddCloseFriendRequest) (*profilev1.AddCloseFriendResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	friendID := uuid.MustParse(req.GetUserId())
+
+	err = h.CloseFriendsUsecase.AddCloseFriend(ctx, userID, friendID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add close friend: %w", err)
+	}
+	return &profilev1.AddCloseFriendResponse{
+		Success: true,
+	}, nil
+}
+
+// RemoveCloseFriend takes a user off the caller's close friends list.
+func (h *RPCProfileHandler) RemoveCloseFriend(ctx context.Context, req *profilev1.RemoveCloseFriendRequest) (*profilev1.RemoveCloseFriendResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	friendID := uuid.MustParse(req.GetUserId())
+
+	if err := h.CloseFriendsUsecase.RemoveCloseFriend(ctx, userID, friendID); err != nil {
+		return nil, fmt.Errorf("failed to remove close friend: %w", err)
+	}
+	return &profilev1.RemoveCloseFriendResponse{
+		Success: true,
+	}, nil
+}
+
+// ListCloseFriends returns a page of the caller's close friends list.
+func (h *RPCProfileHandler) ListCloseFriends(ctx context.Context, req *profilev1.ListCloseFriendsRequest) (*profilev1.ListCloseFriendsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, nextCursor, err := h.CloseFriendsUsecase.ListCloseFriends(ctx, userID, req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list close friends: %w", err)
+	}
+
+	resp := &profilev1.ListCloseFriendsResponse{NextCursor: nextCursor}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &profilev1.CloseFriendEntry{
+			User:    userCardToProto(e.User),
+			AddedAt: timestamppb.New(e.AddedAt),
+		})
+	}
+	return resp, nil
+}
+
+// GetSettings returns the caller's settings.
+func (h *RPCProfileHandler) GetSettings(ctx context.Context, req *profilev1.GetSettingsRequest) (*profilev1.GetSettingsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := h.SettingsUsecase.GetSettings(ctx, userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "settings not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	return &profilev1.GetSettingsResponse{
+		Settings: settingsToProto(settings),
+	}, nil
+}
+
+// UpdateSettings changes the caller's settings.
+func (h *RPCProfileHandler) UpdateSettings(ctx context.Context, req *profilev1.UpdateSettingsRequest) (*profilev1.UpdateSettingsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update := entity.SettingsUpdate{
+		Version:        req.GetVersion(),
+		PrivateAccount: req.PrivateAccount,
+		NotifyLikes:    req.NotifyLikes,
+		NotifyComments: req.NotifyComments,
+		NotifyFollows:  req.NotifyFollows,
+		NotifyMentions: req.NotifyMentions,
+		NotifyMessages: req.NotifyMessages,
+	}
+	if req.PrivacyLevel != nil {
+		level := entity.PrivacyLevel(req.GetPrivacyLevel())
+		update.PrivacyLevel = &level
+	}
+	if req.EmailDigest != nil {
+		digest := entity.DigestFrequency(req.GetEmailDigest())
+		update.EmailDigest = &digest
+	}
+
+	settings, err := h.SettingsUsecase.UpdateSettings(ctx, userID, update)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "settings not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+	return &profilev1.UpdateSettingsResponse{
+		Settings: settingsToProto(settings),
+	}, nil
+}
+
+func settingsToProto(s entity.UserSettings) *profilev1.Settings {
+	return &profilev1.Settings{
+		PrivacyLevel:   string(s.PrivacyLevel),
+		PrivateAccount: s.PrivateAccount,
+		Notifications: &profilev1.NotificationSettings{
+			Likes:       s.Notifications.Likes,
+			Comments:    s.Notifications.Comments,
+			Follows:     s.Notifications.Follows,
+			Mentions:    s.Notifications.Mentions,
+			Messages:    s.Notifications.Messages,
+			EmailDigest: string(s.Notifications.EmailDigest),
+		},
+		UpdatedAt: timestamppb.New(s.UpdatedAt),
+		Version:   s.Version,
+	}
+}
+
+func userCardToProto(c entity.UserCard) *profilev1.UserCard {
+	return &profilev1.UserCard{
+		UserId:     c.ID.String(),
+		Username:   c.Username,
+		Name:       c.Name,
+		AvatarUrl:  c.AvatarURL,
+		Following:  c.Following,
+		FollowsYou: c.FollowsYou,
+	}
+}
+
+func profileToProto(p entity.Profile) *profilev1.Profile {
+	pb := &profilev1.Profile{
+		UserId:         p.UserID.String(),
+		Username:       p.Username,
+		Name:           p.Name,
+		Bio:            p.Bio,
+		AvatarUrl:      p.AvatarURL,
+		Gender:         string(p.Gender),
+		FollowersCount: int32(p.FollowersCount),
+		FollowingCount: int32(p.FollowingCount),
+		IsPrivate:      p.IsPrivate,
+		Restricted:     p.Restricted,
+		UpdatedAt:      timestamppb.New(p.UpdatedAt),
+		Version:        p.Version,
+	}
+	if p.Age != nil {
+		pb.Age = int32(*p.Age)
+	}
+	return pb
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := ctxUtil.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "missing user in context")
+	}
+	return userID, nil
+}
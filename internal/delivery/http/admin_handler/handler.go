@@ -0,0 +1,477 @@
+package adminHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type AdminHandler struct {
+	AdminUsecase  AdminUsecase
+	AuditUsecase  AuditUsecase
+	APIKeyUsecase APIKeyUsecase
+	ReviewUsecase ReviewUsecase
+	Metrics       *metrics.Metrics
+}
+
+type AdminUsecase interface {
+
+	//BlockUser blocks the user until the given time (nil blocks indefinitely) and revokes all of their sessions.
+	BlockUser(ctx context.Context, userID uuid.UUID, reason string, until *time.Time) error
+
+	//UnblockUser lifts the block of the user.
+	UnblockUser(ctx context.Context, userID uuid.UUID) error
+
+	//RestoreAccount undeletes the account of the user unless its personal data was already erased.
+	RestoreAccount(ctx context.Context, userID uuid.UUID) error
+
+	//GrantRole grants the role to the user.
+	GrantRole(ctx context.Context, userID uuid.UUID, role entity.Role) error
+
+	//RevokeRole revokes the role from the user.
+	RevokeRole(ctx context.Context, userID uuid.UUID, role entity.Role) error
+
+	//LookupUser returns the account of a user, query is the user ID, the username or the email address.
+	LookupUser(ctx context.Context, query string) (entity.UserAccount, error)
+
+	//ListSessions returns a page of the active sessions of the user and the cursor of the next page.
+	ListSessions(ctx context.Context, userID uuid.UUID, cursor string, limit int) (sessions []entity.Session, nextCursor string, err error)
+
+	//RevokeUserSession ends one of the sessions of the user.
+	RevokeUserSession(ctx context.Context, userID, sessionID uuid.UUID) error
+
+	//RevokeUserSessions ends all sessions of the user.
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+}
+
+type ReviewUsecase interface {
+
+	//ListReports returns a page of the content waiting for review, oldest first, and the cursor of the next page.
+	ListReports(ctx context.Context, cursor string, limit int) (reports []entity.ContentFlag, nextCursor string, err error)
+
+	//ResolveReport dismisses a report or takes the reported content down.
+	ResolveReport(ctx context.Context, reportID uuid.UUID, resolution entity.ReportResolution) error
+
+	//TakeDown removes a post, a comment or a message.
+	TakeDown(ctx context.Context, kind entity.ContentKind, contentID uuid.UUID, reason string) error
+}
+
+type AuditUsecase interface {
+
+	//ListEvents returns audit events matching the filter, newest first.
+	ListEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error)
+}
+
+type APIKeyUsecase interface {
+
+	//IssueKey creates a key allowed to call the methods in scopes and returns the plain key, which is shown only once.
+	IssueKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (string, entity.APIKey, error)
+
+	//ListKeys returns all keys, newest first.
+	ListKeys(ctx context.Context) ([]entity.APIKey, error)
+
+	//RevokeKey revokes the key.
+	RevokeKey(ctx context.Context, id uuid.UUID) error
+}
+
+func NewAdminHandler(adminUsecase AdminUsecase, auditUsecase AuditUsecase, apiKeyUsecase APIKeyUsecase, reviewUsecase ReviewUsecase, metrics *metrics.Metrics) *AdminHandler {
+	return &AdminHandler{
+		AdminUsecase:  adminUsecase,
+		AuditUsecase:  auditUsecase,
+		APIKeyUsecase: apiKeyUsecase,
+		ReviewUsecase: reviewUsecase,
+		Metrics:       metrics,
+	}
+}
+
+// DTOs
+type BlockUserRequest struct {
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type GrantRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+type IssueAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=255"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type IssueAPIKeyResponse struct {
+	// Key is the plain API key, it is not stored and can't be retrieved again
+	Key    string        `json:"key"`
+	APIKey entity.APIKey `json:"api_key"`
+}
+
+type ListAuditEventsRequest struct {
+	Type      string    `query:"type"`
+	ActorID   string    `query:"actor_id" validate:"omitempty,uuid"`
+	SubjectID string    `query:"subject_id" validate:"omitempty,uuid"`
+	From      time.Time `query:"from"`
+	To        time.Time `query:"to"`
+	BeforeID  int64     `query:"before_id"`
+	Limit     int       `query:"limit"`
+}
+
+type LookupUserRequest struct {
+	// Query is the user ID, the username or the email address
+	Query string `query:"q" validate:"required"`
+}
+
+type SessionResponse struct {
+	ID           string    `json:"id"`
+	DeviceName   string    `json:"device_name,omitempty"`
+	DeviceLabel  string    `json:"device_label"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIP     string    `json:"client_ip"`
+	IsSuspicious bool      `json:"is_suspicious"`
+	CountryCode  string    `json:"country_code,omitempty"`
+	City         string    `json:"city,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type ListSessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+	pagination.Response
+}
+
+type TakeDownRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ListReportsResponse struct {
+	Reports []entity.ContentFlag `json:"reports"`
+	pagination.Response
+}
+
+type ResolveReportRequest struct {
+	// Resolution is "dismissed" to keep the content and lift a shadow limit or "removed" to take it down
+	Resolution string `json:"resolution" validate:"required,oneof=dismissed removed"`
+}
+
+type ListAuditEventsResponse struct {
+	Events []entity.AuditEvent `json:"events"`
+	// NextBeforeID is passed as before_id to fetch the next page, 0 when the page is empty
+	NextBeforeID int64 `json:"next_before_id"`
+}
+
+// BlockUser blocks the user from the path with a reason and optional expiry.
+func (h *AdminHandler) BlockUser(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	var req BlockUserRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	if err := h.AdminUsecase.BlockUser(c.Request().Context(), userID, req.Reason, req.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// UnblockUser lifts the block of the user from the path.
+func (h *AdminHandler) UnblockUser(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.AdminUsecase.UnblockUser(c.Request().Context(), userID); err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// RestoreUser undeletes the account of the user from the path.
+func (h *AdminHandler) RestoreUser(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	err = h.AdminUsecase.RestoreAccount(c.Request().Context(), userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "deleted user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// GrantRole grants the role from the request body to the user from the path.
+func (h *AdminHandler) GrantRole(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	var req GrantRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	if err := h.AdminUsecase.GrantRole(c.Request().Context(), userID, entity.Role(req.Role)); err != nil {
+		return fmt.Errorf("failed to grant role: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// RevokeRole revokes the role from the path from the user.
+func (h *AdminHandler) RevokeRole(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.AdminUsecase.RevokeRole(c.Request().Context(), userID, entity.Role(c.Param("role"))); err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// ListAuditEvents returns audit events matching the query filters, newest first.
+func (h *AdminHandler) ListAuditEvents(c echo.Context) error {
+	var req ListAuditEventsRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	filter := entity.AuditFilter{
+		Type:     entity.AuditEventType(req.Type),
+		From:     req.From,
+		To:       req.To,
+		BeforeID: req.BeforeID,
+		Limit:    req.Limit,
+	}
+	if req.ActorID != "" {
+		filter.ActorID = uuid.MustParse(req.ActorID)
+	}
+	if req.SubjectID != "" {
+		filter.SubjectID = uuid.MustParse(req.SubjectID)
+	}
+
+	events, err := h.AuditUsecase.ListEvents(c.Request().Context(), filter)
+	if err != nil {
+		return fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	resp := ListAuditEventsResponse{Events: events}
+	if len(events) > 0 {
+		resp.NextBeforeID = events[len(events)-1].ID
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// IssueAPIKey creates an API key for an internal service.
+func (h *AdminHandler) IssueAPIKey(c echo.Context) error {
+	var req IssueAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	plain, key, err := h.APIKeyUsecase.IssueKey(c.Request().Context(), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to issue API key: %w", err)
+	}
+	return c.JSON(http.StatusCreated, IssueAPIKeyResponse{
+		Key:    plain,
+		APIKey: key,
+	})
+}
+
+// ListAPIKeys returns all API keys without their secrets.
+func (h *AdminHandler) ListAPIKeys(c echo.Context) error {
+	keys, err := h.APIKeyUsecase.ListKeys(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return c.JSON(http.StatusOK, map[string][]entity.APIKey{"api_keys": keys})
+}
+
+// RevokeAPIKey revokes the API key from the path.
+func (h *AdminHandler) RevokeAPIKey(c echo.Context) error {
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid API key ID")
+	}
+
+	err = h.APIKeyUsecase.RevokeKey(c.Request().Context(), keyID)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return echo.NewHTTPError(http.StatusNotFound, "API key not found or already revoked")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// LookupUser finds a user by the ID, username or email address in the q query parameter.
+func (h *AdminHandler) LookupUser(c echo.Context) error {
+	var req LookupUserRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	account, err := h.AdminUsecase.LookupUser(c.Request().Context(), req.Query)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+	if account.Roles == nil {
+		account.Roles = []entity.Role{}
+	}
+	return c.JSON(http.StatusOK, account)
+}
+
+// ListUserSessions returns a page of the active sessions of the user from the path.
+func (h *AdminHandler) ListUserSessions(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	sessions, nextCursor, err := h.AdminUsecase.ListSessions(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	resp := make([]SessionResponse, len(sessions))
+	for i, s := range sessions {
+		resp[i] = SessionResponse{
+			ID:           s.ID.String(),
+			DeviceName:   s.DeviceName,
+			DeviceLabel:  s.DeviceLabel,
+			UserAgent:    s.UserAgent,
+			ClientIP:     s.ClientIP.String(),
+			IsSuspicious: s.IsSuspicious,
+			CountryCode:  s.CountryCode,
+			City:         s.City,
+			CreatedAt:    s.CreatedAt,
+			ExpiresAt:    s.ExpiresAt,
+		}
+	}
+	return c.JSON(http.StatusOK, ListSessionsResponse{
+		Sessions: resp,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// RevokeSession ends the session from the path of the user from the path.
+func (h *AdminHandler) RevokeSession(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid session ID")
+	}
+
+	if err := h.AdminUsecase.RevokeUserSession(c.Request().Context(), userID, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// RevokeAllSessions ends every session of the user from the path.
+func (h *AdminHandler) RevokeAllSessions(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.AdminUsecase.RevokeUserSessions(c.Request().Context(), userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// TakeDownContent removes the post, comment or message from the path.
+func (h *AdminHandler) TakeDownContent(c echo.Context) error {
+	kind := entity.ContentKind(c.Param("type"))
+	if !kind.Valid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "content type must be post, comment or message")
+	}
+	contentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid content ID")
+	}
+	var req TakeDownRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	err = h.ReviewUsecase.TakeDown(c.Request().Context(), kind, contentID, req.Reason)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "content not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to take down content: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// ListReports returns a page of the open reports, oldest first.
+func (h *AdminHandler) ListReports(c echo.Context) error {
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	reports, nextCursor, err := h.ReviewUsecase.ListReports(c.Request().Context(), req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list reports: %w", err)
+	}
+	if reports == nil {
+		reports = []entity.ContentFlag{}
+	}
+	return c.JSON(http.StatusOK, ListReportsResponse{
+		Reports:  reports,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// ResolveReport dismisses the report from the path or takes the reported content down.
+func (h *AdminHandler) ResolveReport(c echo.Context) error {
+	reportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid report ID")
+	}
+	var req ResolveReportRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	err = h.ReviewUsecase.ResolveReport(c.Request().Context(), reportID, entity.ReportResolution(req.Resolution))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "report or reported content not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve report: %w", err)
+	}
+	return c.NoContent(204)
+}
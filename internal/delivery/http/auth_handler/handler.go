@@ -2,9 +2,15 @@ package authHandler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"main/domain/entity"
 	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,134 +18,330 @@ import (
 )
 
 type AuthHandler struct {
-	AuthUsecase AuthUsecase
-	Metrics     *metrics.Metrics
+	AuthUsecase     AuthUsecase
+	RefreshTokenTTL time.Duration
+	Metrics         *metrics.Metrics
 }
 
 type AuthUsecase interface {
 
 	//RegisterUser registers a new user and returns the user ID as a string.
-	RegisterUser(ctx context.Context, username, email, password string) (userID uuid.UUID, err error)
+	RegisterUser(ctx context.Context, username, email, password, captchaToken string) (userID uuid.UUID, err error)
+
+	//CheckUsername reports whether the username can be registered, and if not, why.
+	CheckUsername(ctx context.Context, username string) (available bool, reason string, err error)
 
 	//LoginUser authenticates a user and returns the user ID, access token, and refresh token.
-	LoginUser(ctx context.Context, login, password, userAgent string, ip string) (userID uuid.UUID, accessToken string, refreshToken string, err error)
+	LoginUser(ctx context.Context, login, password, userAgent, ip, captchaToken string) (userID uuid.UUID, accessToken string, refreshToken string, err error)
+
+	//ConfirmLogin completes a login from a new device with the token emailed to the user.
+	ConfirmLogin(ctx context.Context, token string) (userID uuid.UUID, accessToken string, refreshToken string, err error)
 
-	//LogoutSession logs out a user from a specific session.
-	LogoutSession(ctx context.Context, userID string, sessionID string) error
+	//LogoutSession logs out a user from one of their own sessions.
+	LogoutSession(ctx context.Context, userID, sessionID uuid.UUID) error
 
 	//LogoutAllSessions logs out a user from all sessions.
-	LogoutAllSessions(ctx context.Context, userID string) error
+	LogoutAllSessions(ctx context.Context, userID uuid.UUID) error
 
 	//RefreshSessionToken refreshes the access token using a valid refresh token and returns the new access token and refresh token.
 	RefreshSessionToken(ctx context.Context, refreshToken string) (newAccessToken string, newRefreshToken string, err error)
+
+	//ChangePassword verifies the current password and replaces it with the new one.
+	ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string, revokeSessions bool) error
+
+	//RequestEmailChange mails a confirmation token to the new email address.
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail string) error
+
+	//ConfirmEmailChange swaps the email of the user the token was issued for.
+	ConfirmEmailChange(ctx context.Context, token string) error
+
+	//DeleteAccount soft-deletes the account after re-confirming the password.
+	DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error
+
+	//ListSessions returns a page of the active sessions of the user with a device label for each, newest first.
+	ListSessions(ctx context.Context, userID uuid.UUID, cursor string, limit int) (sessions []entity.Session, nextCursor string, err error)
+
+	//RenameSession names the device of one of the user's sessions.
+	RenameSession(ctx context.Context, userID, sessionID uuid.UUID, name string) error
+
+	//Introspect reports whether an access or refresh token is currently valid and what it grants.
+	Introspect(ctx context.Context, token, tokenTypeHint string) (entity.TokenIntrospection, error)
 }
 
-func NewAuthHandler(authUsecase AuthUsecase, metrics *metrics.Metrics) *AuthHandler {
+func NewAuthHandler(authUsecase AuthUsecase, refreshTokenTTL time.Duration, metrics *metrics.Metrics) *AuthHandler {
 	return &AuthHandler{
-		AuthUsecase: authUsecase,
-		Metrics:     metrics,
+		AuthUsecase:     authUsecase,
+		RefreshTokenTTL: refreshTokenTTL,
+		Metrics:         metrics,
 	}
 }
 
 // DTOs
 type RegisterRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	// CaptchaToken may be sent in the X-Captcha-Token header instead
+	CaptchaToken string `json:"captcha_token"`
+}
+
+type UsernameAvailabilityResponse struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	// Reason is invalid, reserved or taken when the username is not available
+	Reason string `json:"reason,omitempty"`
 }
 
 type LoginRequest struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login    string `json:"login" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	// CaptchaToken is required after repeated failed logins, it may be sent in the X-Captcha-Token header instead
+	CaptchaToken string `json:"captcha_token"`
+}
+
+type ConfirmLoginRequest struct {
+	Token string `json:"token" validate:"required"`
 }
 
 type LogoutRequest struct {
-	UserID    string `json:"user_id"`
-	SessionID string `json:"session_id"`
+	// SessionID is optional, by default the session of the access token is logged out
+	SessionID string `json:"session_id" validate:"omitempty,uuid"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+	RevokeSessions  bool   `json:"revoke_sessions"`
+}
+
+type EmailChangeRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type SessionResponse struct {
+	ID          string    `json:"id"`
+	DeviceName  string    `json:"device_name,omitempty"`
+	DeviceLabel string    `json:"device_label"`
+	UserAgent   string    `json:"user_agent"`
+	ClientIP    string    `json:"client_ip"`
+	CountryCode string    `json:"country_code,omitempty"`
+	City        string    `json:"city,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// Current marks the session of the access token the list was requested with
+	Current bool `json:"current"`
+}
+
+type ListSessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+	pagination.Response
+}
+
+type RenameSessionRequest struct {
+	// Name is the new device name, empty restores the label derived from the User-Agent
+	Name string `json:"name" validate:"max=64"`
+}
+
+// IntrospectRequest accepts the form encoding of RFC 7662 as well as JSON.
+type IntrospectRequest struct {
+	Token         string `json:"token" form:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+}
+
+// IntrospectResponse follows RFC 7662, inactive tokens only carry "active": false.
+type IntrospectResponse struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	// Scope is the space separated list of the user's roles
+	Scope     string `json:"scope,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
 }
 
 func (h *AuthHandler) Register(c echo.Context) error {
 	var req RegisterRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return fmt.Errorf("invalid request: %w", err)
 	}
-	userID, err := h.AuthUsecase.RegisterUser(c.Request().Context(), req.Username, req.Email, req.Password)
+	userID, err := h.AuthUsecase.RegisterUser(c.Request().Context(), req.Username, req.Email, req.Password, captchaToken(c, req.CaptchaToken))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to register user: %v", err))
+		return fmt.Errorf("failed to register user: %w", err)
 	}
 	return c.JSON(201, map[string]string{"user_id": userID.String()})
 }
 
+// CheckUsername reports whether the username from the "u" query parameter can be registered.
+func (h *AuthHandler) CheckUsername(c echo.Context) error {
+	username := c.QueryParam("u")
+	if username == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "username is required")
+	}
+	available, reason, err := h.AuthUsecase.CheckUsername(c.Request().Context(), username)
+	if err != nil {
+		return fmt.Errorf("failed to check username: %w", err)
+	}
+	return c.JSON(200, UsernameAvailabilityResponse{
+		Username:  username,
+		Available: available,
+		Reason:    reason,
+	})
+}
+
 func (h *AuthHandler) Login(c echo.Context) error {
 	var req LoginRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return fmt.Errorf("invalid request: %w", err)
 	}
 	userID, accessToken, refreshToken, err := h.AuthUsecase.LoginUser(
 		c.Request().Context(),
 		req.Login,
 		req.Password,
 		c.Request().UserAgent(),
-		c.RealIP())
+		c.RealIP(),
+		captchaToken(c, req.CaptchaToken))
+	if errors.Is(err, customerrors.ErrLoginConfirmationRequired) {
+		return c.JSON(http.StatusAccepted, map[string]string{"status": "confirmation_required"})
+	}
+	if errors.Is(err, customerrors.ErrCaptchaRequired) {
+		return err
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("invalid credentials: %v", err))
 	}
 
+	h.setRefreshTokenCookie(c, refreshToken)
+	c.Set("user_id", userID) // Store user ID in context for later use (e.g., in refresh handler)
+
+	return c.JSON(200, map[string]string{"access_token": accessToken})
+
+}
+
+// ConfirmLogin completes a login from a new device with the token from the confirmation email.
+func (h *AuthHandler) ConfirmLogin(c echo.Context) error {
+	var req ConfirmLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	_, accessToken, refreshToken, err := h.AuthUsecase.ConfirmLogin(c.Request().Context(), req.Token)
+	if errors.Is(err, customerrors.ErrInvalidToken) {
+		return err
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("failed to confirm login: %v", err))
+	}
+
+	h.setRefreshTokenCookie(c, refreshToken)
+	return c.JSON(200, map[string]string{"access_token": accessToken})
+}
+
+// captchaToken returns the CAPTCHA token from the request body, falling back to the X-Captcha-Token header.
+func captchaToken(c echo.Context, field string) string {
+	if field != "" {
+		return field
+	}
+	return c.Request().Header.Get("X-Captcha-Token")
+}
+
+// setRefreshTokenCookie hands the refresh token to the client as an HttpOnly cookie.
+func (h *AuthHandler) setRefreshTokenCookie(c echo.Context, refreshToken string) {
 	cookie := &http.Cookie{
 		Name:     "refresh_token",
 		Value:    refreshToken,
 		HttpOnly: true,
 		Secure:   true,
-		Expires:  time.Now().Add(15 * 24 * time.Hour),
+		Expires:  time.Now().Add(h.RefreshTokenTTL),
 		Path:     "/",
 		// could add SameSite attribute if needed
 		// could add another sites for different environments (e.g., development vs production)
 	}
 
 	c.SetCookie(cookie)
-	c.Set("user_id", userID) // Store user ID in context for later use (e.g., in refresh handler)
-
-	return c.JSON(200, map[string]string{"access_token": accessToken})
+}
 
+// clearRefreshTokenCookie removes the refresh token of a session that was logged out from the client.
+func (h *AuthHandler) clearRefreshTokenCookie(c echo.Context) {
+	c.SetCookie(&http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		HttpOnly: true,
+		Secure:   true,
+		Expires:  time.Unix(0, 0), // Expire the cookie immediately
+		Path:     "/",
+	})
 }
 
-// Logout handles the logout request by invalidating the specified session for the user.
-// It expects a JSON payload containing the user ID and session ID. If the request is valid and the session is successfully invalidated,
-// it returns a 204 No Content response. If there are any errors during the process, it returns an appropriate HTTP error response.
+// Logout handles the logout request by invalidating a session of the authenticated user.
+// The session of the access token is used unless another session of the same user is given in the JSON payload.
+// If the session is successfully invalidated, it returns a 204 No Content response.
 func (h *AuthHandler) Logout(c echo.Context) error {
-	var req LogoutRequest
+	principal, ok := ctxUtil.PrincipalFromContext(c.Request().Context())
+	if !ok || !principal.IsUser() {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	userID, sessionID := principal.UserID, principal.SessionID
 
+	var req LogoutRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	if req.SessionID != "" {
+		sessionID = uuid.MustParse(req.SessionID)
 	}
-	err := h.AuthUsecase.LogoutSession(c.Request().Context(), req.UserID, req.SessionID)
+
+	err := h.AuthUsecase.LogoutSession(c.Request().Context(), userID, sessionID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to logout session: %v", err))
+		return fmt.Errorf("failed to logout session: %w", err)
+	}
+	if sessionID == principal.SessionID {
+		h.clearRefreshTokenCookie(c)
 	}
 
 	return c.NoContent(204)
 }
 
-// LogoutAll handles the logout request by invalidating all sessions for the user.
-func (h *AuthHandler) LogoutAll(c echo.Context) error {
-	var req LogoutRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+// LogoutSession logs out one of the authenticated user's sessions, e.g. a lost device picked from ListSessions.
+// Sessions of other users, or already logged out ones, are left alone.
+func (h *AuthHandler) LogoutSession(c echo.Context) error {
+	principal, ok := ctxUtil.PrincipalFromContext(c.Request().Context())
+	if !ok || !principal.IsUser() {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
 	}
-	err := h.AuthUsecase.LogoutAllSessions(c.Request().Context(), req.UserID)
+	sessionID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to logout all sessions: %v", err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid session ID")
 	}
 
-	c.SetCookie(
-		&http.Cookie{
-			Name:     "refresh_token",
-			Value:    "",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  time.Unix(0, 0), // Expire the cookie immediately
-		},
-	)
+	err = h.AuthUsecase.LogoutSession(c.Request().Context(), principal.UserID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to logout session: %w", err)
+	}
+	if sessionID == principal.SessionID {
+		h.clearRefreshTokenCookie(c)
+	}
+	return c.NoContent(204)
+}
+
+// LogoutAll handles the logout request by invalidating all sessions for the authenticated user.
+func (h *AuthHandler) LogoutAll(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	err := h.AuthUsecase.LogoutAllSessions(c.Request().Context(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to logout all sessions: %w", err)
+	}
+	h.clearRefreshTokenCookie(c)
 
 	return c.NoContent(204)
 }
@@ -154,22 +356,94 @@ func (h *AuthHandler) RefreshSession(c echo.Context) error {
 
 	newAccessToken, newRefreshToken, err := h.AuthUsecase.RefreshSessionToken(c.Request().Context(), refreshToken)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to refresh session: %v", err))
+		return fmt.Errorf("failed to refresh session: %w", err)
 	}
+	// the same path as at login, otherwise the client would keep sending the rotated token too
+	h.setRefreshTokenCookie(c, newRefreshToken)
 
-	newCookie := &http.Cookie{
-		Name:     "refresh_token",
-		Value:    newRefreshToken,
-		HttpOnly: true,
-		Secure:   true,
-		Expires:  time.Now().Add(15 * 24 * time.Hour),
-		Path:     "/refresh",
-		// could add SameSite attribute if needed
-		// could add another sites for different environments (e.g., development vs production)
+	return c.JSON(200, map[string]string{"access_token": newAccessToken})
+}
+
+// ChangePassword changes the password of the authenticated user after checking the current one.
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
 	}
-	c.SetCookie(newCookie)
 
-	return c.JSON(200, map[string]string{"access_token": newAccessToken})
+	var req ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	err := h.AuthUsecase.ChangePassword(c.Request().Context(), userID, req.CurrentPassword, req.NewPassword, req.RevokeSessions)
+	if err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	return c.NoContent(204)
+}
+
+// RequestEmailChange sends a confirmation token to the new email address of the authenticated user.
+func (h *AuthHandler) RequestEmailChange(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req EmailChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	if err := h.AuthUsecase.RequestEmailChange(c.Request().Context(), userID, req.NewEmail); err != nil {
+		return fmt.Errorf("failed to request email change: %w", err)
+	}
+
+	return c.NoContent(202)
+}
+
+// ConfirmEmailChange applies the email change identified by the confirmation token.
+func (h *AuthHandler) ConfirmEmailChange(c echo.Context) error {
+	var req ConfirmEmailChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	err := h.AuthUsecase.ConfirmEmailChange(c.Request().Context(), req.Token)
+	if err != nil {
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+
+	return c.NoContent(204)
+}
+
+// DeleteAccount deletes the account of the authenticated user after re-confirming the password.
+func (h *AuthHandler) DeleteAccount(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req DeleteAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	err := h.AuthUsecase.DeleteAccount(c.Request().Context(), userID, req.Password)
+	if err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	c.SetCookie(
+		&http.Cookie{
+			Name:     "refresh_token",
+			Value:    "",
+			HttpOnly: true,
+			Secure:   true,
+			Expires:  time.Unix(0, 0), // Expire the cookie immediately
+		},
+	)
+
+	return c.NoContent(204)
 }
 
 // Silly example of how to use the metrics in handler
@@ -187,3 +461,93 @@ func (h *AuthHandler) GetTokenForAdmin(c echo.Context) error {
 	return c.JSON(200, nil)
 
 }
+
+// ListSessions returns the active sessions of the authenticated user, so a client can offer "log out this device".
+func (h *AuthHandler) ListSessions(c echo.Context) error {
+	principal, ok := ctxUtil.PrincipalFromContext(c.Request().Context())
+	if !ok || !principal.IsUser() {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	userID, currentID := principal.UserID, principal.SessionID
+
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	sessions, nextCursor, err := h.AuthUsecase.ListSessions(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	resp := make([]SessionResponse, len(sessions))
+	for i, s := range sessions {
+		resp[i] = SessionResponse{
+			ID:          s.ID.String(),
+			DeviceName:  s.DeviceName,
+			DeviceLabel: s.DeviceLabel,
+			UserAgent:   s.UserAgent,
+			ClientIP:    s.ClientIP.String(),
+			CountryCode: s.CountryCode,
+			City:        s.City,
+			CreatedAt:   s.CreatedAt,
+			ExpiresAt:   s.ExpiresAt,
+			Current:     s.ID == currentID,
+		}
+	}
+	return c.JSON(200, ListSessionsResponse{
+		Sessions: resp,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// RenameSession names the device of one of the authenticated user's sessions.
+func (h *AuthHandler) RenameSession(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid session ID")
+	}
+
+	var req RenameSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	err = h.AuthUsecase.RenameSession(c.Request().Context(), userID, sessionID, req.Name)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return echo.NewHTTPError(http.StatusNotFound, "session not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rename session: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// Introspect tells a resource server whether the token is active and what it grants (RFC 7662).
+func (h *AuthHandler) Introspect(c echo.Context) error {
+	var req IntrospectRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	info, err := h.AuthUsecase.Introspect(c.Request().Context(), req.Token, req.TokenTypeHint)
+	if err != nil {
+		return fmt.Errorf("failed to introspect token: %w", err)
+	}
+	if !info.Active {
+		return c.JSON(200, IntrospectResponse{Active: false})
+	}
+	return c.JSON(200, IntrospectResponse{
+		Active:    true,
+		TokenType: info.TokenType,
+		Subject:   info.UserID.String(),
+		SessionID: info.SessionID.String(),
+		Scope:     strings.Join(info.Scopes, " "),
+		IssuedAt:  info.IssuedAt.Unix(),
+		ExpiresAt: info.ExpiresAt.Unix(),
+	})
+}
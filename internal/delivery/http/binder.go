@@ -0,0 +1,27 @@
+package http
+
+import (
+	"main/pkg/validate"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Validator checks bound DTOs against their `validate` tags, it is the echo.Validator of the server.
+type Validator struct{}
+
+func (Validator) Validate(i any) error {
+	return validate.Struct(i)
+}
+
+// ValidatingBinder binds requests like echo.DefaultBinder and then validates the bound DTO with the server's
+// Validator, so handlers get requests that are already well-formed and clients get every invalid field at once.
+type ValidatingBinder struct {
+	echo.DefaultBinder
+}
+
+func (b *ValidatingBinder) Bind(i any, c echo.Context) error {
+	if err := b.DefaultBinder.Bind(i, c); err != nil {
+		return err
+	}
+	return c.Validate(i)
+}
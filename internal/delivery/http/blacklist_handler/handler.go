@@ -0,0 +1,108 @@
+package blacklistHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type BlacklistHandler struct {
+	BlacklistUsecase BlacklistUsecase
+	Metrics          *metrics.Metrics
+}
+
+type BlacklistUsecase interface {
+
+	//Block blocks another user on behalf of the user and ends the follows between them.
+	Block(ctx context.Context, userID, blockedID uuid.UUID) error
+
+	//Unblock removes the user's block of another user.
+	Unblock(ctx context.Context, userID, blockedID uuid.UUID) error
+
+	//ListBlocked returns a page of the users the user blocked and the cursor of the next page.
+	ListBlocked(ctx context.Context, userID uuid.UUID, cursor string, limit int) (entries []entity.BlockedEntry, nextCursor string, err error)
+}
+
+func NewBlacklistHandler(blacklistUsecase BlacklistUsecase, metrics *metrics.Metrics) *BlacklistHandler {
+	return &BlacklistHandler{
+		BlacklistUsecase: blacklistUsecase,
+		Metrics:          metrics,
+	}
+}
+
+// DTOs
+type BlockedListResponse struct {
+	Users []entity.BlockedEntry `json:"users"`
+	pagination.Response
+}
+
+// Block blocks the user from the path on behalf of the authenticated user.
+func (h *BlacklistHandler) Block(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	blockedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	err = h.BlacklistUsecase.Block(c.Request().Context(), userID, blockedID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Unblock removes the authenticated user's block of the user from the path.
+func (h *BlacklistHandler) Unblock(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	blockedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.BlacklistUsecase.Unblock(c.Request().Context(), userID, blockedID); err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListBlocked returns a page of the users the authenticated user blocked.
+func (h *BlacklistHandler) ListBlocked(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	entries, nextCursor, err := h.BlacklistUsecase.ListBlocked(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list blocked users: %w", err)
+	}
+	if entries == nil {
+		entries = []entity.BlockedEntry{}
+	}
+	return c.JSON(http.StatusOK, BlockedListResponse{
+		Users:    entries,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
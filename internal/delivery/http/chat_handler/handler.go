@@ -0,0 +1,666 @@
+package chatHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ChatHandler struct {
+	ChatUsecase ChatUsecase
+	Metrics     *metrics.Metrics
+}
+
+type ChatUsecase interface {
+
+	//CreateChat returns the direct chat between the user and the peer, creating it on the first call.
+	CreateChat(ctx context.Context, userID, peerID uuid.UUID) (entity.Chat, error)
+
+	//CreateGroup creates a group chat with the user as its admin and memberIDs as regular members.
+	CreateGroup(ctx context.Context, userID uuid.UUID, title, avatarURL string, public bool, memberIDs []uuid.UUID) (entity.Chat, error)
+
+	//GetChat returns one of the user's chats.
+	GetChat(ctx context.Context, userID, chatID uuid.UUID) (entity.Chat, error)
+
+	//UpdateGroup changes the non-nil fields of a group chat the user is an admin of.
+	UpdateGroup(ctx context.Context, userID, chatID uuid.UUID, update entity.GroupUpdate) (entity.Chat, error)
+
+	//ListMembers returns a page of the members of one of the user's chats and the cursor of the next page.
+	ListMembers(ctx context.Context, userID, chatID uuid.UUID, cursor string, limit int) (members []entity.ChatMember, nextCursor string, err error)
+
+	//AddMembers adds users to a group chat the user is an admin of.
+	AddMembers(ctx context.Context, userID, chatID uuid.UUID, memberIDs []uuid.UUID) error
+
+	//RemoveMember removes a member from a group chat the user is an admin of, or the user themselves.
+	RemoveMember(ctx context.Context, userID, chatID, memberID uuid.UUID) error
+
+	//SetMemberRole changes the role of a member of a group chat the user is an admin of.
+	SetMemberRole(ctx context.Context, userID, chatID, memberID uuid.UUID, role entity.ChatRole) error
+
+	//JoinChat makes the user a member of a public group chat.
+	JoinChat(ctx context.Context, userID, chatID uuid.UUID) error
+
+	//LeaveChat removes the user from a group chat.
+	LeaveChat(ctx context.Context, userID, chatID uuid.UUID) error
+
+	//MarkRead marks one of the user's chats read up to the message.
+	MarkRead(ctx context.Context, userID, chatID, messageID uuid.UUID) error
+
+	//ListChats returns a page of the user's chats, most recently active first, and the cursor of the next page.
+	ListChats(ctx context.Context, userID uuid.UUID, cursor string, limit int) (chats []entity.Chat, nextCursor string, err error)
+
+	//SendMessage sends a message to one of the user's chats.
+	SendMessage(ctx context.Context, userID, chatID uuid.UUID, content string) (entity.Message, error)
+
+	//ListMessages returns a page of the history of one of the user's chats, newest first, and the cursor of the next page.
+	ListMessages(ctx context.Context, userID, chatID uuid.UUID, cursor string, limit int) (messages []entity.Message, nextCursor string, err error)
+
+	//SendAttachment sends a message with an uploaded media file and an optional caption to one of the user's chats.
+	SendAttachment(ctx context.Context, userID, chatID uuid.UUID, caption string, file io.ReadSeeker, size int64) (entity.Message, error)
+
+	//SearchMessages returns a page of the user's messages matching the query, in all chats or one of them, and the cursor of the next page.
+	SearchMessages(ctx context.Context, userID, chatID uuid.UUID, query, cursor string, limit int) (matches []entity.MessageMatch, nextCursor string, err error)
+
+	//EditMessage replaces the content of one of the user's messages.
+	EditMessage(ctx context.Context, userID, chatID, messageID uuid.UUID, content string) (entity.Message, error)
+
+	//DeleteMessage deletes a message of one of the user's chats for everyone or for the user only.
+	DeleteMessage(ctx context.Context, userID, chatID, messageID uuid.UUID, forEveryone bool) error
+
+	//RestoreMessage undeletes a message the user deleted for everyone within the undelete window.
+	RestoreMessage(ctx context.Context, userID, chatID, messageID uuid.UUID) error
+}
+
+func NewChatHandler(chatUsecase ChatUsecase, metrics *metrics.Metrics) *ChatHandler {
+	return &ChatHandler{
+		ChatUsecase: chatUsecase,
+		Metrics:     metrics,
+	}
+}
+
+// DTOs
+type CreateChatRequest struct {
+	UserID string `json:"user_id" validate:"required,uuid"`
+}
+
+type CreateGroupRequest struct {
+	Title     string `json:"title" validate:"max=100"`
+	AvatarURL string `json:"avatar_url"`
+	// Public lets anyone join the group without being added
+	Public    bool     `json:"public"`
+	MemberIDs []string `json:"member_ids" validate:"dive,uuid"`
+}
+
+// UpdateGroupRequest changes only the fields present in the body.
+type UpdateGroupRequest struct {
+	Title     *string `json:"title" validate:"omitempty,max=100"`
+	AvatarURL *string `json:"avatar_url"`
+	Public    *bool   `json:"public"`
+}
+
+type AddMembersRequest struct {
+	UserIDs []string `json:"user_ids" validate:"required,dive,uuid"`
+}
+
+type SetMemberRoleRequest struct {
+	// Role is member or admin
+	Role string `json:"role" validate:"required,oneof=member admin"`
+}
+
+type MarkReadRequest struct {
+	// MessageID is the last message the user has seen
+	MessageID string `json:"message_id" validate:"required,uuid"`
+}
+
+type ListMembersResponse struct {
+	Members []entity.ChatMember `json:"members"`
+	pagination.Response
+}
+
+type SendMessageRequest struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
+
+type EditMessageRequest struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
+
+type DeleteMessageRequest struct {
+	// ForEveryone deletes the message for all members instead of only the user, only the sender may do that
+	ForEveryone bool `query:"for_everyone"`
+}
+
+type SearchMessagesRequest struct {
+	Query string `query:"q"`
+	// ChatID searches one chat instead of all chats of the user
+	ChatID string `query:"chat_id" validate:"omitempty,uuid"`
+	pagination.Request
+}
+
+type SearchMessagesResponse struct {
+	Matches []entity.MessageMatch `json:"matches"`
+	pagination.Response
+}
+
+type ListChatsResponse struct {
+	Chats []entity.Chat `json:"chats"`
+	pagination.Response
+}
+
+type ListMessagesResponse struct {
+	Messages []entity.Message `json:"messages"`
+	pagination.Response
+}
+
+// CreateChat returns the chat of the authenticated user with the user from the body, creating it on the first call.
+func (h *ChatHandler) CreateChat(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req CreateChatRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	chat, err := h.ChatUsecase.CreateChat(c.Request().Context(), userID, uuid.MustParse(req.UserID))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create chat: %w", err)
+	}
+	return c.JSON(http.StatusOK, chat)
+}
+
+// ListChats returns a page of the authenticated user's chats.
+func (h *ChatHandler) ListChats(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	chats, nextCursor, err := h.ChatUsecase.ListChats(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list chats: %w", err)
+	}
+	if chats == nil {
+		chats = []entity.Chat{}
+	}
+	return c.JSON(http.StatusOK, ListChatsResponse{
+		Chats:    chats,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// SendMessage sends a message to the chat from the path on behalf of the authenticated user.
+func (h *ChatHandler) SendMessage(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	var req SendMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	message, err := h.ChatUsecase.SendMessage(c.Request().Context(), userID, chatID, req.Content)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "chat not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.JSON(http.StatusCreated, message)
+}
+
+// SearchMessages returns a page of the authenticated user's messages matching the q parameter, in all of their
+// chats or in the one given by chat_id.
+func (h *ChatHandler) SearchMessages(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req SearchMessagesRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	var chatID uuid.UUID
+	if req.ChatID != "" {
+		chatID = uuid.MustParse(req.ChatID)
+	}
+
+	matches, nextCursor, err := h.ChatUsecase.SearchMessages(c.Request().Context(), userID, chatID, req.Query, req.Cursor, req.Limit)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "chat not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to search messages: %w", err)
+	}
+	if matches == nil {
+		matches = []entity.MessageMatch{}
+	}
+	return c.JSON(http.StatusOK, SearchMessagesResponse{
+		Matches:  matches,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// EditMessage replaces the content of the authenticated user's message from the path.
+func (h *ChatHandler) EditMessage(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	messageID, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid message ID")
+	}
+	var req EditMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	message, err := h.ChatUsecase.EditMessage(c.Request().Context(), userID, chatID, messageID, req.Content)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "message not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	return c.JSON(http.StatusOK, message)
+}
+
+// DeleteMessage deletes the message from the path for the authenticated user, or for everyone with for_everyone=true.
+func (h *ChatHandler) DeleteMessage(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	messageID, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid message ID")
+	}
+	var req DeleteMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	err = h.ChatUsecase.DeleteMessage(c.Request().Context(), userID, chatID, messageID, req.ForEveryone)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "message not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RestoreMessage undeletes the message from the path the authenticated user deleted for everyone, if it was deleted
+// within the undelete window.
+func (h *ChatHandler) RestoreMessage(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	messageID, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid message ID")
+	}
+
+	err = h.ChatUsecase.RestoreMessage(c.Request().Context(), userID, chatID, messageID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "deleted message not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore message: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SendAttachment sends a message with the file uploaded as the multipart "file" field and the "content" field as its
+// caption to the chat from the path on behalf of the authenticated user.
+func (h *ChatHandler) SendAttachment(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	defer file.Close()
+
+	message, err := h.ChatUsecase.SendAttachment(c.Request().Context(), userID, chatID, c.FormValue("content"), file, fileHeader.Size)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "chat not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.JSON(http.StatusCreated, message)
+}
+
+// ListMessages returns a page of the history of the chat from the path.
+func (h *ChatHandler) ListMessages(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	messages, nextCursor, err := h.ChatUsecase.ListMessages(c.Request().Context(), userID, chatID, req.Cursor, req.Limit)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "chat not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+	if messages == nil {
+		messages = []entity.Message{}
+	}
+	return c.JSON(http.StatusOK, ListMessagesResponse{
+		Messages: messages,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// CreateGroup creates a group chat with the authenticated user as its admin.
+func (h *ChatHandler) CreateGroup(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req CreateGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	chat, err := h.ChatUsecase.CreateGroup(c.Request().Context(), userID, req.Title, req.AvatarURL, req.Public, parseUserIDs(req.MemberIDs))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+	return c.JSON(http.StatusCreated, chat)
+}
+
+// GetChat returns the chat from the path if the authenticated user is a member of it.
+func (h *ChatHandler) GetChat(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+
+	chat, err := h.ChatUsecase.GetChat(c.Request().Context(), userID, chatID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "chat not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+	return c.JSON(http.StatusOK, chat)
+}
+
+// UpdateGroup changes the group chat from the path, the authenticated user must be its admin.
+func (h *ChatHandler) UpdateGroup(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	var req UpdateGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	update := entity.GroupUpdate{
+		Title:     req.Title,
+		AvatarURL: req.AvatarURL,
+		Public:    req.Public,
+	}
+	chat, err := h.ChatUsecase.UpdateGroup(c.Request().Context(), userID, chatID, update)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "group not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update group: %w", err)
+	}
+	return c.JSON(http.StatusOK, chat)
+}
+
+// ListMembers returns a page of the members of the chat from the path.
+func (h *ChatHandler) ListMembers(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	members, nextCursor, err := h.ChatUsecase.ListMembers(c.Request().Context(), userID, chatID, req.Cursor, req.Limit)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "chat not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list members: %w", err)
+	}
+	if members == nil {
+		members = []entity.ChatMember{}
+	}
+	return c.JSON(http.StatusOK, ListMembersResponse{
+		Members:  members,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// AddMembers adds the users from the body to the group chat from the path.
+func (h *ChatHandler) AddMembers(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	var req AddMembersRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	err = h.ChatUsecase.AddMembers(c.Request().Context(), userID, chatID, parseUserIDs(req.UserIDs))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "group or user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add members: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveMember removes the user from the path from the group chat from the path.
+func (h *ChatHandler) RemoveMember(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	err = h.ChatUsecase.RemoveMember(c.Request().Context(), userID, chatID, memberID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "group or member not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SetMemberRole changes the role of the user from the path in the group chat from the path.
+func (h *ChatHandler) SetMemberRole(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+	var req SetMemberRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	err = h.ChatUsecase.SetMemberRole(c.Request().Context(), userID, chatID, memberID, entity.ChatRole(req.Role))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "group or member not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set member role: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// JoinChat makes the authenticated user a member of the public group chat from the path.
+func (h *ChatHandler) JoinChat(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+
+	err = h.ChatUsecase.JoinChat(c.Request().Context(), userID, chatID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "group not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to join group: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LeaveChat removes the authenticated user from the group chat from the path.
+func (h *ChatHandler) LeaveChat(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+
+	err = h.ChatUsecase.LeaveChat(c.Request().Context(), userID, chatID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "group not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to leave group: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// MarkRead marks the chat from the path read by the authenticated user up to the message from the body.
+func (h *ChatHandler) MarkRead(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid chat ID")
+	}
+	var req MarkReadRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	err = h.ChatUsecase.MarkRead(c.Request().Context(), userID, chatID, uuid.MustParse(req.MessageID))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "chat or message not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark chat read: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// parseUserIDs parses IDs the binder already validated as UUIDs.
+func parseUserIDs(ids []string) []uuid.UUID {
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		parsed = append(parsed, uuid.MustParse(id))
+	}
+	return parsed
+}
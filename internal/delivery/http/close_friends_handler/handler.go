@@ -0,0 +1,108 @@
+package closeFriendsHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type CloseFriendsHandler struct {
+	CloseFriendsUsecase CloseFriendsUsecase
+	Metrics             *metrics.Metrics
+}
+
+type CloseFriendsUsecase interface {
+
+	//AddCloseFriend puts another user on the user's close friends list.
+	AddCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error
+
+	//RemoveCloseFriend takes another user off the user's close friends list.
+	RemoveCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error
+
+	//ListCloseFriends returns a page of the user's close friends list and the cursor of the next page.
+	ListCloseFriends(ctx context.Context, userID uuid.UUID, cursor string, limit int) (entries []entity.CloseFriendEntry, nextCursor string, err error)
+}
+
+func NewCloseFriendsHandler(closeFriendsUsecase CloseFriendsUsecase, metrics *metrics.Metrics) *CloseFriendsHandler {
+	return &CloseFriendsHandler{
+		CloseFriendsUsecase: closeFriendsUsecase,
+		Metrics:             metrics,
+	}
+}
+
+// DTOs
+type CloseFriendsListResponse struct {
+	Users []entity.CloseFriendEntry `json:"users"`
+	pagination.Response
+}
+
+// AddCloseFriend puts the user from the path on the authenticated user's close friends list.
+func (h *CloseFriendsHandler) AddCloseFriend(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	friendID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	err = h.CloseFriendsUsecase.AddCloseFriend(c.Request().Context(), userID, friendID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add close friend: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveCloseFriend takes the user from the path off the authenticated user's close friends list.
+func (h *CloseFriendsHandler) RemoveCloseFriend(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	friendID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.CloseFriendsUsecase.RemoveCloseFriend(c.Request().Context(), userID, friendID); err != nil {
+		return fmt.Errorf("failed to remove close friend: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListCloseFriends returns a page of the authenticated user's close friends list.
+func (h *CloseFriendsHandler) ListCloseFriends(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	entries, nextCursor, err := h.CloseFriendsUsecase.ListCloseFriends(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list close friends: %w", err)
+	}
+	if entries == nil {
+		entries = []entity.CloseFriendEntry{}
+	}
+	return c.JSON(http.StatusOK, CloseFriendsListResponse{
+		Users:    entries,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
@@ -0,0 +1,198 @@
+package commentHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type CommentHandler struct {
+	CommentUsecase CommentUsecase
+	Metrics        *metrics.Metrics
+}
+
+type CommentUsecase interface {
+
+	//CreateComment comments the post on behalf of the user, replyTo is uuid.Nil for a top level comment.
+	CreateComment(ctx context.Context, userID, postID, replyTo uuid.UUID, content string) (entity.Comment, error)
+
+	//UpdateComment edits the content of one of the user's comments.
+	UpdateComment(ctx context.Context, userID, commentID uuid.UUID, content string) (entity.Comment, error)
+
+	//DeleteComment deletes one of the user's comments together with the replies to it.
+	DeleteComment(ctx context.Context, userID, commentID uuid.UUID) error
+
+	//RestoreComment undeletes one of the user's comments deleted within the undelete window with its replies.
+	RestoreComment(ctx context.Context, userID, commentID uuid.UUID) error
+
+	//ListComments returns a page of comments of the post or replies to replyTo, and the cursor of the next page.
+	ListComments(ctx context.Context, viewerID, postID, replyTo uuid.UUID, sort entity.CommentSort, cursor string, limit int) (comments []entity.Comment, nextCursor string, err error)
+}
+
+func NewCommentHandler(commentUsecase CommentUsecase, metrics *metrics.Metrics) *CommentHandler {
+	return &CommentHandler{
+		CommentUsecase: commentUsecase,
+		Metrics:        metrics,
+	}
+}
+
+// DTOs
+type CreateCommentRequest struct {
+	Content string `json:"content" validate:"required,max=500"`
+	// ReplyTo makes the comment a reply to another comment of the post
+	ReplyTo string `json:"reply_to" validate:"omitempty,uuid"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content" validate:"required,max=500"`
+}
+
+type ListCommentsRequest struct {
+	// ReplyTo lists replies to the comment instead of top level comments
+	ReplyTo string `query:"reply_to" validate:"omitempty,uuid"`
+	// Sort is newest (default), oldest or top
+	Sort string `query:"sort" validate:"omitempty,oneof=newest oldest top"`
+	pagination.Request
+}
+
+type ListCommentsResponse struct {
+	Comments []entity.Comment `json:"comments"`
+	pagination.Response
+}
+
+// CreateComment comments the post from the path on behalf of the authenticated user.
+func (h *CommentHandler) CreateComment(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	var req CreateCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	var replyTo uuid.UUID
+	if req.ReplyTo != "" {
+		replyTo = uuid.MustParse(req.ReplyTo)
+	}
+
+	comment, err := h.CommentUsecase.CreateComment(c.Request().Context(), userID, postID, replyTo, req.Content)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "post or parent comment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	return c.JSON(http.StatusCreated, comment)
+}
+
+// ListComments returns a page of comments of the post from the path.
+func (h *CommentHandler) ListComments(c echo.Context) error {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	var req ListCommentsRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	var replyTo uuid.UUID
+	if req.ReplyTo != "" {
+		replyTo = uuid.MustParse(req.ReplyTo)
+	}
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	comments, nextCursor, err := h.CommentUsecase.ListComments(c.Request().Context(), viewerID, postID, replyTo, entity.CommentSort(req.Sort), req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list comments: %w", err)
+	}
+	if comments == nil {
+		comments = []entity.Comment{}
+	}
+	return c.JSON(http.StatusOK, ListCommentsResponse{
+		Comments: comments,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// UpdateComment edits the content of the authenticated user's comment from the path.
+func (h *CommentHandler) UpdateComment(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment ID")
+	}
+
+	var req UpdateCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	comment, err := h.CommentUsecase.UpdateComment(c.Request().Context(), userID, commentID, req.Content)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return echo.NewHTTPError(http.StatusNotFound, "comment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	return c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment deletes the authenticated user's comment from the path with its replies.
+func (h *CommentHandler) DeleteComment(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment ID")
+	}
+
+	err = h.CommentUsecase.DeleteComment(c.Request().Context(), userID, commentID)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return echo.NewHTTPError(http.StatusNotFound, "comment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// RestoreComment undeletes the authenticated user's comment from the path with the replies deleted with it, if it was
+// deleted within the undelete window.
+func (h *CommentHandler) RestoreComment(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment ID")
+	}
+
+	err = h.CommentUsecase.RestoreComment(c.Request().Context(), userID, commentID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "deleted comment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore comment: %w", err)
+	}
+	return c.NoContent(204)
+}
@@ -0,0 +1,231 @@
+package developerHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type DeveloperHandler struct {
+	DeveloperUsecase DeveloperUsecase
+	Metrics          *metrics.Metrics
+}
+
+type DeveloperUsecase interface {
+
+	//CreateApp registers an app of the user and returns it with its plain client secret.
+	CreateApp(ctx context.Context, ownerID uuid.UUID, name, description string, scopes []string) (string, entity.DeveloperApp, error)
+
+	//ListApps returns the apps of the user, newest first.
+	ListApps(ctx context.Context, ownerID uuid.UUID) ([]entity.DeveloperApp, error)
+
+	//RotateSecret replaces the client secret of the user's app and returns the new plain secret.
+	RotateSecret(ctx context.Context, ownerID, appID uuid.UUID) (string, error)
+
+	//DeleteApp deletes the user's app and revokes its tokens.
+	DeleteApp(ctx context.Context, ownerID, appID uuid.UUID) error
+
+	//Usage returns the daily requests of the user's app over the last days, today first.
+	Usage(ctx context.Context, ownerID, appID uuid.UUID, days int) ([]entity.AppUsage, error)
+
+	//IssueToken authenticates an app by its client ID and secret and issues an access token with the scopes.
+	IssueToken(ctx context.Context, clientID, secret string, scopes []string) (string, entity.AppToken, error)
+}
+
+func NewDeveloperHandler(developerUsecase DeveloperUsecase, metrics *metrics.Metrics) *DeveloperHandler {
+	return &DeveloperHandler{
+		DeveloperUsecase: developerUsecase,
+		Metrics:          metrics,
+	}
+}
+
+type CreateAppRequest struct {
+	Name        string   `json:"name" validate:"required,max=100"`
+	Description string   `json:"description" validate:"max=500"`
+	Scopes      []string `json:"scopes" validate:"required,dive,oneof=posts:read users:read"`
+}
+
+// CreateAppResponse is the created app with its client secret, which is only shown once.
+type CreateAppResponse struct {
+	App          entity.DeveloperApp `json:"app"`
+	ClientSecret string              `json:"client_secret"`
+}
+
+type AppsResponse struct {
+	Apps []entity.DeveloperApp `json:"apps"`
+}
+
+type SecretResponse struct {
+	ClientSecret string `json:"client_secret"`
+}
+
+type UsageResponse struct {
+	Usage []entity.AppUsage `json:"usage"`
+}
+
+// TokenResponse is the successful response of the token endpoint (RFC 6749 section 5.1).
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// TokenError is the error response of the token endpoint (RFC 6749 section 5.2).
+type TokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// CreateApp registers an app of the authenticated user, the client secret is only returned here.
+func (h *DeveloperHandler) CreateApp(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req CreateAppRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	secret, app, err := h.DeveloperUsecase.CreateApp(c.Request().Context(), userID, req.Name, req.Description, req.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to create app: %w", err)
+	}
+	return c.JSON(http.StatusCreated, CreateAppResponse{App: app, ClientSecret: secret})
+}
+
+// ListApps returns the apps of the authenticated user without their secrets.
+func (h *DeveloperHandler) ListApps(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	apps, err := h.DeveloperUsecase.ListApps(c.Request().Context(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to list apps: %w", err)
+	}
+	if apps == nil {
+		apps = []entity.DeveloperApp{}
+	}
+	return c.JSON(http.StatusOK, AppsResponse{Apps: apps})
+}
+
+// RotateSecret replaces the client secret of the authenticated user's app from the path, tokens issued with the
+// old secret stop working.
+func (h *DeveloperHandler) RotateSecret(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	appID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid app ID")
+	}
+
+	secret, err := h.DeveloperUsecase.RotateSecret(c.Request().Context(), userID, appID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "app not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rotate app secret: %w", err)
+	}
+	return c.JSON(http.StatusOK, SecretResponse{ClientSecret: secret})
+}
+
+// DeleteApp deletes the authenticated user's app from the path.
+func (h *DeveloperHandler) DeleteApp(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	appID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid app ID")
+	}
+
+	err = h.DeveloperUsecase.DeleteApp(c.Request().Context(), userID, appID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "app not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete app: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetUsage returns the daily requests of the authenticated user's app from the path over the last days
+// (query parameter days, a week by default).
+func (h *DeveloperHandler) GetUsage(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	appID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid app ID")
+	}
+	var days int
+	if raw := c.QueryParam("days"); raw != "" {
+		if days, err = strconv.Atoi(raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid days")
+		}
+	}
+
+	usage, err := h.DeveloperUsecase.Usage(c.Request().Context(), userID, appID, days)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "app not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get app usage: %w", err)
+	}
+	return c.JSON(http.StatusOK, UsageResponse{Usage: usage})
+}
+
+// Token is the OAuth2 token endpoint of developer apps. It only supports the client credentials grant: the app
+// authenticates with HTTP Basic auth or the client_id and client_secret form fields and may narrow its scopes with
+// the space separated scope field. Errors are answered in the format of RFC 6749 rather than the one of the API,
+// so OAuth2 client libraries understand them.
+func (h *DeveloperHandler) Token(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	if c.FormValue("grant_type") != "client_credentials" {
+		return c.JSON(http.StatusBadRequest, TokenError{Error: "unsupported_grant_type", Description: "only the client_credentials grant is supported"})
+	}
+	clientID, secret, ok := c.Request().BasicAuth()
+	if !ok {
+		clientID, secret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+	if clientID == "" || secret == "" {
+		return c.JSON(http.StatusBadRequest, TokenError{Error: "invalid_request", Description: "client credentials are required"})
+	}
+
+	plain, token, err := h.DeveloperUsecase.IssueToken(c.Request().Context(), clientID, secret, strings.Fields(c.FormValue("scope")))
+	if errors.Is(err, customerrors.ErrInvalidClient) {
+		c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		return c.JSON(http.StatusUnauthorized, TokenError{Error: "invalid_client", Description: customerrors.ErrInvalidClient.Message})
+	}
+	if errors.Is(err, customerrors.ErrInvalidScope) {
+		return c.JSON(http.StatusBadRequest, TokenError{Error: "invalid_scope", Description: customerrors.ErrInvalidScope.Message})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to issue app token: %w", err)
+	}
+	return c.JSON(http.StatusOK, TokenResponse{
+		AccessToken: plain,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(token.ExpiresAt).Seconds()),
+		Scope:       strings.Join(token.Scopes, " "),
+	})
+}
@@ -0,0 +1,75 @@
+package exportHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ExportHandler struct {
+	ExportUsecase ExportUsecase
+	Metrics       *metrics.Metrics
+}
+
+type ExportUsecase interface {
+
+	//RequestExport queues an export of the user's data, the user gets an email when the archive is ready.
+	RequestExport(ctx context.Context, userID uuid.UUID) (entity.DataExport, error)
+
+	//GetExport returns a data export of the user, with a download link if the archive is ready.
+	GetExport(ctx context.Context, userID, exportID uuid.UUID) (entity.DataExport, error)
+}
+
+func NewExportHandler(exportUsecase ExportUsecase, metrics *metrics.Metrics) *ExportHandler {
+	return &ExportHandler{
+		ExportUsecase: exportUsecase,
+		Metrics:       metrics,
+	}
+}
+
+// RequestExport queues an export of the authenticated user's data. The export is returned as pending,
+// its status can be polled with GetExport.
+func (h *ExportHandler) RequestExport(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	export, err := h.ExportUsecase.RequestExport(c.Request().Context(), userID)
+	if errors.Is(err, customerrors.ErrExportInProgress) {
+		return echo.NewHTTPError(http.StatusConflict, "a data export is already being prepared")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to request data export: %w", err)
+	}
+	return c.JSON(http.StatusAccepted, export)
+}
+
+// GetExport returns the authenticated user's data export from the path, with a signed download link once it's ready.
+func (h *ExportHandler) GetExport(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid export ID")
+	}
+
+	export, err := h.ExportUsecase.GetExport(c.Request().Context(), userID, exportID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "export not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get data export: %w", err)
+	}
+	return c.JSON(http.StatusOK, export)
+}
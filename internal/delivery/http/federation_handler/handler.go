@@ -0,0 +1,188 @@
+package federationHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"main/internal/metrics"
+	"main/pkg/activitypub"
+	"main/pkg/customerrors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type FederationHandler struct {
+	FederationUsecase FederationUsecase
+	Metrics           *metrics.Metrics
+}
+
+type FederationUsecase interface {
+
+	//WebFinger resolves acct:username@domain, or the URL of an actor, to the actor of the user.
+	WebFinger(ctx context.Context, resource string) (activitypub.JRD, error)
+
+	//Actor returns the Person of the user with its public key.
+	Actor(ctx context.Context, userID uuid.UUID) (activitypub.Actor, error)
+
+	//Outbox returns the collection of the user's public posts, or one of its pages if page is set.
+	Outbox(ctx context.Context, userID uuid.UUID, page bool, cursor string) (any, error)
+
+	//Followers returns the collection of the user's followers.
+	Followers(ctx context.Context, userID uuid.UUID) (activitypub.OrderedCollection, error)
+
+	//Following returns the collection of the accounts the user follows.
+	Following(ctx context.Context, userID uuid.UUID) (activitypub.OrderedCollection, error)
+
+	//Note returns the Note of a public post.
+	Note(ctx context.Context, postID uuid.UUID) (activitypub.Note, error)
+
+	//HandleInbox handles a signed activity delivered to an inbox.
+	HandleInbox(ctx context.Context, r *http.Request, body []byte) error
+}
+
+func NewFederationHandler(federationUsecase FederationUsecase, metrics *metrics.Metrics) *FederationHandler {
+	return &FederationHandler{
+		FederationUsecase: federationUsecase,
+		Metrics:           metrics,
+	}
+}
+
+// activityJSON writes an ActivityPub document, served with the media type remote servers expect.
+func activityJSON(c echo.Context, v any) error {
+	c.Response().Header().Set(echo.HeaderContentType, activitypub.ContentType)
+	return c.JSON(http.StatusOK, v)
+}
+
+// userID parses the user ID from the path, a malformed ID is a user that doesn't exist.
+func userID(c echo.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	return id, nil
+}
+
+// WebFinger resolves the resource query parameter, acct:username@domain, to the ActivityPub actor of the user.
+func (h *FederationHandler) WebFinger(c echo.Context) error {
+	resource := c.QueryParam("resource")
+	if resource == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "resource is required")
+	}
+
+	jrd, err := h.FederationUsecase.WebFinger(c.Request().Context(), resource)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve webfinger resource: %w", err)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, activitypub.JRDContentType)
+	return c.JSON(http.StatusOK, jrd)
+}
+
+// Actor returns the ActivityPub actor of the user from the path.
+func (h *FederationHandler) Actor(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return err
+	}
+
+	actor, err := h.FederationUsecase.Actor(c.Request().Context(), id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get actor: %w", err)
+	}
+	return activityJSON(c, actor)
+}
+
+// Outbox returns the outbox collection of the user from the path, or with ?page=true one of its pages of
+// Create activities; the following pages are linked with a cursor.
+func (h *FederationHandler) Outbox(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return err
+	}
+
+	outbox, err := h.FederationUsecase.Outbox(c.Request().Context(), id, c.QueryParam("page") == "true", c.QueryParam("cursor"))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get outbox: %w", err)
+	}
+	return activityJSON(c, outbox)
+}
+
+// Followers returns the followers collection of the user from the path.
+func (h *FederationHandler) Followers(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return err
+	}
+
+	followers, err := h.FederationUsecase.Followers(c.Request().Context(), id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get followers collection: %w", err)
+	}
+	return activityJSON(c, followers)
+}
+
+// Following returns the following collection of the user from the path.
+func (h *FederationHandler) Following(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return err
+	}
+
+	following, err := h.FederationUsecase.Following(c.Request().Context(), id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get following collection: %w", err)
+	}
+	return activityJSON(c, following)
+}
+
+// Note returns the public post from the path as an ActivityPub Note.
+func (h *FederationHandler) Note(c echo.Context) error {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+
+	note, err := h.FederationUsecase.Note(c.Request().Context(), postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get note: %w", err)
+	}
+	return activityJSON(c, note)
+}
+
+// Inbox accepts an activity delivered by a remote server to the inbox of a user or the shared inbox. The request
+// must carry an HTTP signature of the activity's actor.
+func (h *FederationHandler) Inbox(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+
+	err = h.FederationUsecase.HandleInbox(c.Request().Context(), c.Request(), body)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to handle activity: %w", err)
+	}
+	return c.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,198 @@
+package followHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type FollowHandler struct {
+	FollowUsecase FollowUsecase
+	Metrics       *metrics.Metrics
+}
+
+type FollowUsecase interface {
+
+	//Follow makes the user follow another account, or asks to if it is private, following an already followed account succeeds.
+	Follow(ctx context.Context, userID, followeeID uuid.UUID) (entity.FollowState, error)
+
+	//Unfollow stops the user following another account or withdraws the request, unfollowing an account that isn't followed succeeds.
+	Unfollow(ctx context.Context, userID, followeeID uuid.UUID) error
+
+	//ListFollowers returns a page of the accounts following the user and the cursor of the next page.
+	ListFollowers(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) (entries []entity.FollowEntry, nextCursor string, err error)
+
+	//ListFollowing returns a page of the accounts the user follows and the cursor of the next page.
+	ListFollowing(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) (entries []entity.FollowEntry, nextCursor string, err error)
+
+	//ListFollowRequests returns a page of the pending requests to follow the user and the cursor of the next page.
+	ListFollowRequests(ctx context.Context, userID uuid.UUID, cursor string, limit int) (requests []entity.FollowRequest, nextCursor string, err error)
+
+	//ApproveFollowRequest lets the follower follow the user's private account.
+	ApproveFollowRequest(ctx context.Context, userID, followerID uuid.UUID) error
+
+	//RejectFollowRequest turns down the follower's request to follow the user.
+	RejectFollowRequest(ctx context.Context, userID, followerID uuid.UUID) error
+}
+
+func NewFollowHandler(followUsecase FollowUsecase, metrics *metrics.Metrics) *FollowHandler {
+	return &FollowHandler{
+		FollowUsecase: followUsecase,
+		Metrics:       metrics,
+	}
+}
+
+// DTOs
+type FollowListResponse struct {
+	Users []entity.FollowEntry `json:"users"`
+	pagination.Response
+}
+
+type FollowRequestListResponse struct {
+	Requests []entity.FollowRequest `json:"requests"`
+	pagination.Response
+}
+
+// Follow makes the authenticated user follow the user from the path. A private account answers with 202 Accepted,
+// the follow then waits for its owner's approval.
+func (h *FollowHandler) Follow(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	followeeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	state, err := h.FollowUsecase.Follow(c.Request().Context(), userID, followeeID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to follow user: %w", err)
+	}
+	if state == entity.FollowStateRequested {
+		return c.NoContent(http.StatusAccepted)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Unfollow makes the authenticated user stop following the user from the path.
+func (h *FollowHandler) Unfollow(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	followeeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	if err := h.FollowUsecase.Unfollow(c.Request().Context(), userID, followeeID); err != nil {
+		return fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListFollowRequests returns a page of the pending requests to follow the authenticated user.
+func (h *FollowHandler) ListFollowRequests(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	requests, nextCursor, err := h.FollowUsecase.ListFollowRequests(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list follow requests: %w", err)
+	}
+	if requests == nil {
+		requests = []entity.FollowRequest{}
+	}
+	return c.JSON(http.StatusOK, FollowRequestListResponse{
+		Requests: requests,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// ApproveFollowRequest lets the user from the path follow the authenticated user.
+func (h *FollowHandler) ApproveFollowRequest(c echo.Context) error {
+	return h.answerRequest(c, h.FollowUsecase.ApproveFollowRequest)
+}
+
+// RejectFollowRequest turns down the request of the user from the path to follow the authenticated user.
+func (h *FollowHandler) RejectFollowRequest(c echo.Context) error {
+	return h.answerRequest(c, h.FollowUsecase.RejectFollowRequest)
+}
+
+func (h *FollowHandler) answerRequest(c echo.Context, answer func(ctx context.Context, userID, followerID uuid.UUID) error) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	followerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	err = answer(c.Request().Context(), userID, followerID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "follow request not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to answer follow request: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListFollowers returns a page of the followers of the user from the path, the caller is optional.
+func (h *FollowHandler) ListFollowers(c echo.Context) error {
+	return h.list(c, h.FollowUsecase.ListFollowers)
+}
+
+// ListFollowing returns a page of the accounts the user from the path follows, the caller is optional.
+func (h *FollowHandler) ListFollowing(c echo.Context) error {
+	return h.list(c, h.FollowUsecase.ListFollowing)
+}
+
+type listFunc func(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) ([]entity.FollowEntry, string, error)
+
+func (h *FollowHandler) list(c echo.Context, list listFunc) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	entries, nextCursor, err := list(c.Request().Context(), viewerID, userID, req.Cursor, req.Limit)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list follows: %w", err)
+	}
+	if entries == nil {
+		entries = []entity.FollowEntry{}
+	}
+	return c.JSON(http.StatusOK, FollowListResponse{
+		Users:    entries,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
@@ -0,0 +1,229 @@
+package graphqlHandler
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/labstack/echo/v4"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	// loaderWait is how long a loader collects keys before it fetches them, the resolvers of a list's items
+	// run concurrently and load well within it
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+	// maxQueryDepth keeps clients from nesting posts and comments arbitrarily deep
+	maxQueryDepth = 10
+	// queryCacheSize is how many parsed and validated queries are kept, clients send the same few queries
+	queryCacheSize = 1000
+)
+
+//go:generate go tool gqlgen generate
+
+//go:embed schema.graphqls
+var schemaSDL string
+
+type GraphQLHandler struct {
+	ProfileUsecase ProfileUsecase
+	PostUsecase    PostUsecase
+	FeedUsecase    FeedUsecase
+	CommentUsecase CommentUsecase
+	Metrics        *metrics.Metrics
+
+	server *handler.Server
+}
+
+type ProfileUsecase interface {
+
+	//GetProfile returns the user's profile as the viewer may see it.
+	GetProfile(ctx context.Context, viewerID, userID uuid.UUID) (entity.Profile, error)
+
+	//GetProfiles returns the profiles of the users as the viewer may see them, users who blocked the viewer are left out.
+	GetProfiles(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]entity.Profile, error)
+}
+
+type PostUsecase interface {
+
+	//GetPost returns the post if the viewer may see it.
+	GetPost(ctx context.Context, viewerID, postID uuid.UUID) (entity.Post, error)
+
+	//GetPosts returns the posts of the IDs the viewer may see.
+	GetPosts(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) ([]entity.Post, error)
+
+	//LikedPosts returns which of the posts the user liked.
+	LikedPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) ([]uuid.UUID, error)
+}
+
+type FeedUsecase interface {
+
+	//GetFeed returns a page of the user's home timeline, newest first, and the cursor of the next page.
+	GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) (items []entity.FeedItem, nextCursor string, err error)
+
+	//GetHashtagPosts returns a page of posts tagged with the hashtag, newest first, and the cursor of the next page.
+	GetHashtagPosts(ctx context.Context, viewerID uuid.UUID, tag, cursor string, limit int) (posts []entity.Post, nextCursor string, err error)
+
+	//GetExplore returns a page of trending posts, highest score first, and the cursor of the next page.
+	GetExplore(ctx context.Context, viewerID uuid.UUID, cursor string, limit int) (posts []entity.RankedPost, nextCursor string, err error)
+}
+
+type CommentUsecase interface {
+
+	//ListComments returns a page of comments of the post or replies to replyTo, and the cursor of the next page.
+	ListComments(ctx context.Context, viewerID, postID, replyTo uuid.UUID, sort entity.CommentSort, cursor string, limit int) (comments []entity.Comment, nextCursor string, err error)
+}
+
+func NewGraphQLHandler(profileUsecase ProfileUsecase, postUsecase PostUsecase, feedUsecase FeedUsecase, commentUsecase CommentUsecase, metrics *metrics.Metrics) *GraphQLHandler {
+	h := &GraphQLHandler{
+		ProfileUsecase: profileUsecase,
+		PostUsecase:    postUsecase,
+		FeedUsecase:    feedUsecase,
+		CommentUsecase: commentUsecase,
+		Metrics:        metrics,
+	}
+	// introspection isn't enabled, the schema is served by Schema
+	h.server = handler.New(NewExecutableSchema(Config{Resolvers: &Resolver{h: h}}))
+	h.server.AddTransport(transport.GET{})
+	h.server.AddTransport(transport.POST{})
+	h.server.SetQueryCache(lru.New[*ast.QueryDocument](queryCacheSize))
+	h.server.Use(depthLimit{max: maxQueryDepth})
+	return h
+}
+
+// Query runs a GraphQL query, posted as JSON or passed in the query, variables JSON-encoded.
+// Field errors are reported in the errors of the 200 response next to the data that could be resolved.
+func (h *GraphQLHandler) Query(c echo.Context) error {
+	// the route is public, userID is only set for callers with a valid access token
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	ctx := h.withRequest(c.Request().Context(), viewerID)
+	h.server.ServeHTTP(c.Response(), c.Request().WithContext(ctx))
+	return nil
+}
+
+// Schema returns the schema in the schema definition language, the endpoint doesn't answer introspection queries.
+func (h *GraphQLHandler) Schema(c echo.Context) error {
+	return c.String(http.StatusOK, schemaSDL)
+}
+
+type requestKey struct{}
+
+// request holds the viewer and the loaders of one query, loaded values are cached for the query only.
+type request struct {
+	viewerID uuid.UUID
+	profiles *dataloader.Loader[uuid.UUID, *entity.Profile]
+	posts    *dataloader.Loader[uuid.UUID, *entity.Post]
+	liked    *dataloader.Loader[uuid.UUID, bool]
+}
+
+func (h *GraphQLHandler) withRequest(ctx context.Context, viewerID uuid.UUID) context.Context {
+	r := &request{viewerID: viewerID}
+	r.profiles = newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*entity.Profile, error) {
+		profiles, err := h.ProfileUsecase.GetProfiles(ctx, viewerID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get profiles: %v", err)
+		}
+		byID := make(map[uuid.UUID]*entity.Profile, len(profiles))
+		for i := range profiles {
+			byID[profiles[i].UserID] = &profiles[i]
+		}
+		return byID, nil
+	})
+	r.posts = newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*entity.Post, error) {
+		posts, err := h.PostUsecase.GetPosts(ctx, viewerID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get posts: %v", err)
+		}
+		byID := make(map[uuid.UUID]*entity.Post, len(posts))
+		for i := range posts {
+			byID[posts[i].ID] = &posts[i]
+		}
+		return byID, nil
+	})
+	r.liked = newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+		liked, err := h.PostUsecase.LikedPosts(ctx, viewerID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get likes: %v", err)
+		}
+		byID := make(map[uuid.UUID]bool, len(liked))
+		for _, id := range liked {
+			byID[id] = true
+		}
+		return byID, nil
+	})
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+// newLoader returns a loader batching the keys loaded within loaderWait into one fetch. Keys missing from the map
+// fetch returns load as the zero value.
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *dataloader.Loader[K, V] {
+	return dataloader.NewBatchedLoader(func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		byKey, err := fetch(ctx, keys)
+		results := make([]*dataloader.Result[V], len(keys))
+		for i, key := range keys {
+			results[i] = &dataloader.Result[V]{Data: byKey[key], Error: err}
+		}
+		return results
+	}, dataloader.WithWait[K, V](loaderWait), dataloader.WithBatchCapacity[K, V](loaderMaxBatch))
+}
+
+// depthLimit rejects operations whose fields nest deeper than max levels, counting the fields of fragments.
+type depthLimit struct {
+	max int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = depthLimit{}
+
+func (depthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (depthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (l depthLimit) MutateOperationContext(_ context.Context, oc *graphql.OperationContext) *gqlerror.Error {
+	if depth(oc.Operation.SelectionSet) > l.max {
+		return gqlerror.Errorf("the query is nested deeper than %d levels", l.max)
+	}
+	return nil
+}
+
+// depth returns how many levels of fields the selections nest, validation already rejected fragment cycles.
+func depth(selections ast.SelectionSet) int {
+	deepest := 0
+	for _, sel := range selections {
+		d := 0
+		switch sel := sel.(type) {
+		case *ast.Field:
+			d = 1 + depth(sel.SelectionSet)
+		case *ast.InlineFragment:
+			d = depth(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				d = depth(sel.Definition.SelectionSet)
+			}
+		}
+		deepest = max(deepest, d)
+	}
+	return deepest
+}
@@ -0,0 +1,34 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package graphqlHandler
+
+import (
+	"main/domain/entity"
+)
+
+type CommentPage struct {
+	Items []entity.Comment `json:"items"`
+	// Cursor of the next page, null on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+type ExplorePage struct {
+	Items []entity.RankedPost `json:"items"`
+	// Cursor of the next page, null on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+type FeedPage struct {
+	Items []entity.FeedItem `json:"items"`
+	// Cursor of the next page, null on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+type PostPage struct {
+	Items []entity.Post `json:"items"`
+	// Cursor of the next page, null on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+type Query struct {
+}
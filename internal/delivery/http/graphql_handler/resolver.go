@@ -0,0 +1,67 @@
+package graphqlHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+
+	"github.com/google/uuid"
+)
+
+// Resolver is the root of the resolvers gqlgen generated from schema.graphqls, they are implemented in
+// schema.resolvers.go with the usecases of the handler.
+type Resolver struct {
+	h *GraphQLHandler
+}
+
+// getProfile returns the profile, nil if it doesn't exist or is hidden from the viewer.
+func (r *Resolver) getProfile(ctx context.Context, userID uuid.UUID) (*entity.Profile, error) {
+	p, err := r.h.ProfileUsecase.GetProfile(ctx, requestFrom(ctx).viewerID, userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %v", err)
+	}
+	return &p, nil
+}
+
+// loadProfile is getProfile through the loader, for the authors of lists of posts and comments.
+func (r *Resolver) loadProfile(ctx context.Context, userID uuid.UUID) (*entity.Profile, error) {
+	return requestFrom(ctx).profiles.Load(ctx, userID)()
+}
+
+func (r *Resolver) listComments(ctx context.Context, postID, replyTo uuid.UUID, sort *entity.CommentSort, first *int, after *string) (*CommentPage, error) {
+	comments, next, err := r.h.CommentUsecase.ListComments(ctx, requestFrom(ctx).viewerID, postID, replyTo,
+		deref(sort), deref(after), deref(first))
+	if err != nil {
+		return nil, listError("comments", err)
+	}
+	return &CommentPage{Items: comments, NextCursor: optional(next)}, nil
+}
+
+func listError(what string, err error) error {
+	if errors.Is(err, customerrors.ErrInvalidCursor) {
+		return err
+	}
+	return fmt.Errorf("failed to list %s: %v", what, err)
+}
+
+// optional turns an empty string into null.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// deref returns the value of an optional argument, the zero value if it is null.
+func deref[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
@@ -0,0 +1,171 @@
+package graphqlHandler
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+
+	"github.com/google/uuid"
+)
+
+// Author is the resolver for the author field.
+func (r *commentResolver) Author(ctx context.Context, obj *entity.Comment) (*entity.Profile, error) {
+	return r.loadProfile(ctx, obj.UserID)
+}
+
+// Replies is the resolver for the replies field.
+func (r *commentResolver) Replies(ctx context.Context, obj *entity.Comment, sort *entity.CommentSort, first *int, after *string) (*CommentPage, error) {
+	return r.listComments(ctx, obj.PostID, obj.ID, sort, first, after)
+}
+
+// RepostedBy is the resolver for the repostedBy field.
+func (r *feedItemResolver) RepostedBy(ctx context.Context, obj *entity.FeedItem) (*entity.Profile, error) {
+	if obj.RepostedBy == nil {
+		return nil, nil
+	}
+	return r.loadProfile(ctx, *obj.RepostedBy)
+}
+
+// Author is the resolver for the author field.
+func (r *postResolver) Author(ctx context.Context, obj *entity.Post) (*entity.Profile, error) {
+	return r.loadProfile(ctx, obj.UserID)
+}
+
+// MediaURL is the resolver for the mediaUrl field.
+func (r *postResolver) MediaURL(ctx context.Context, obj *entity.Post) (*string, error) {
+	return optional(obj.MediaURL), nil
+}
+
+// Duration is the resolver for the duration field.
+func (r *postResolver) Duration(ctx context.Context, obj *entity.Post) (*int, error) {
+	if !obj.IsVideo {
+		return nil, nil
+	}
+	return &obj.Duration, nil
+}
+
+// LikedByViewer is the resolver for the likedByViewer field.
+func (r *postResolver) LikedByViewer(ctx context.Context, obj *entity.Post) (bool, error) {
+	req := requestFrom(ctx)
+	if req.viewerID == uuid.Nil {
+		return false, nil
+	}
+	return req.liked.Load(ctx, obj.ID)()
+}
+
+// QuoteOf is the resolver for the quoteOf field.
+func (r *postResolver) QuoteOf(ctx context.Context, obj *entity.Post) (*entity.Post, error) {
+	if obj.QuoteOfID == nil {
+		return nil, nil
+	}
+	return requestFrom(ctx).posts.Load(ctx, *obj.QuoteOfID)()
+}
+
+// Comments is the resolver for the comments field.
+func (r *postResolver) Comments(ctx context.Context, obj *entity.Post, sort *entity.CommentSort, first *int, after *string) (*CommentPage, error) {
+	return r.listComments(ctx, obj.ID, uuid.Nil, sort, first, after)
+}
+
+// Bio is the resolver for the bio field.
+func (r *profileResolver) Bio(ctx context.Context, obj *entity.Profile) (*string, error) {
+	return optional(obj.Bio), nil
+}
+
+// AvatarURL is the resolver for the avatarUrl field.
+func (r *profileResolver) AvatarURL(ctx context.Context, obj *entity.Profile) (*string, error) {
+	return optional(obj.AvatarURL), nil
+}
+
+// Gender is the resolver for the gender field.
+func (r *profileResolver) Gender(ctx context.Context, obj *entity.Profile) (*string, error) {
+	return optional(string(obj.Gender)), nil
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*entity.Profile, error) {
+	viewerID := requestFrom(ctx).viewerID
+	if viewerID == uuid.Nil {
+		return nil, nil
+	}
+	return r.getProfile(ctx, viewerID)
+}
+
+// Profile is the resolver for the profile field.
+func (r *queryResolver) Profile(ctx context.Context, id uuid.UUID) (*entity.Profile, error) {
+	return r.getProfile(ctx, id)
+}
+
+// Post is the resolver for the post field.
+func (r *queryResolver) Post(ctx context.Context, id uuid.UUID) (*entity.Post, error) {
+	p, err := r.h.PostUsecase.GetPost(ctx, requestFrom(ctx).viewerID, id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %v", err)
+	}
+	return &p, nil
+}
+
+// Feed is the resolver for the feed field.
+func (r *queryResolver) Feed(ctx context.Context, first *int, after *string) (*FeedPage, error) {
+	viewerID := requestFrom(ctx).viewerID
+	if viewerID == uuid.Nil {
+		return nil, errors.New("authentication required")
+	}
+	items, next, err := r.h.FeedUsecase.GetFeed(ctx, viewerID, deref(after), deref(first))
+	if err != nil {
+		return nil, listError("feed", err)
+	}
+	return &FeedPage{Items: items, NextCursor: optional(next)}, nil
+}
+
+// Explore is the resolver for the explore field.
+func (r *queryResolver) Explore(ctx context.Context, first *int, after *string) (*ExplorePage, error) {
+	posts, next, err := r.h.FeedUsecase.GetExplore(ctx, requestFrom(ctx).viewerID, deref(after), deref(first))
+	if err != nil {
+		return nil, listError("explore", err)
+	}
+	return &ExplorePage{Items: posts, NextCursor: optional(next)}, nil
+}
+
+// HashtagPosts is the resolver for the hashtagPosts field.
+func (r *queryResolver) HashtagPosts(ctx context.Context, tag string, first *int, after *string) (*PostPage, error) {
+	posts, next, err := r.h.FeedUsecase.GetHashtagPosts(ctx, requestFrom(ctx).viewerID, tag, deref(after), deref(first))
+	if err != nil {
+		return nil, listError("hashtag posts", err)
+	}
+	return &PostPage{Items: posts, NextCursor: optional(next)}, nil
+}
+
+// Comments is the resolver for the comments field.
+func (r *queryResolver) Comments(ctx context.Context, postID uuid.UUID, sort *entity.CommentSort, first *int, after *string) (*CommentPage, error) {
+	return r.listComments(ctx, postID, uuid.Nil, sort, first, after)
+}
+
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
+// FeedItem returns FeedItemResolver implementation.
+func (r *Resolver) FeedItem() FeedItemResolver { return &feedItemResolver{r} }
+
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
+// Profile returns ProfileResolver implementation.
+func (r *Resolver) Profile() ProfileResolver { return &profileResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type commentResolver struct{ *Resolver }
+type feedItemResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
+type profileResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
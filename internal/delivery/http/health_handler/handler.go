@@ -0,0 +1,39 @@
+package healthHandler
+
+import (
+	"context"
+	"main/internal/health"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthHandler struct {
+	Checker Checker
+}
+
+type Checker interface {
+	// Run runs the readiness checks of the instance.
+	Run(ctx context.Context) health.Report
+}
+
+func NewHealthHandler(checker Checker) *HealthHandler {
+	return &HealthHandler{
+		Checker: checker,
+	}
+}
+
+// Liveness answers as long as the process serves HTTP, a failing liveness probe restarts the instance.
+func (h *HealthHandler) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness reports whether Postgres and Redis are reachable and the schema is migrated. The instance gets no
+// traffic while it answers 503.
+func (h *HealthHandler) Readiness(c echo.Context) error {
+	report := h.Checker.Run(c.Request().Context())
+	if !report.Ready {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
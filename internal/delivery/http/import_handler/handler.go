@@ -0,0 +1,87 @@
+package importHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ImportHandler struct {
+	ImportUsecase ImportUsecase
+	Metrics       *metrics.Metrics
+}
+
+type ImportUsecase interface {
+
+	//StartImport reads an archive exported from another platform and queues the import of its follows and posts.
+	StartImport(ctx context.Context, userID uuid.UUID, r io.ReaderAt, size int64) (entity.DataImport, error)
+
+	//GetImport returns a data import of the user with its progress.
+	GetImport(ctx context.Context, userID, importID uuid.UUID) (entity.DataImport, error)
+}
+
+func NewImportHandler(importUsecase ImportUsecase, metrics *metrics.Metrics) *ImportHandler {
+	return &ImportHandler{
+		ImportUsecase: importUsecase,
+		Metrics:       metrics,
+	}
+}
+
+// StartImport queues an import of the archive uploaded as the multipart file archive into the authenticated
+// user's account. The import is returned as pending, its progress can be polled with GetImport.
+func (h *ImportHandler) StartImport(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	fileHeader, err := c.FormFile("archive")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	archive, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	defer archive.Close()
+
+	imp, err := h.ImportUsecase.StartImport(c.Request().Context(), userID, archive, fileHeader.Size)
+	if errors.Is(err, customerrors.ErrImportInProgress) {
+		return echo.NewHTTPError(http.StatusConflict, "a data import is already in progress")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start data import: %w", err)
+	}
+	return c.JSON(http.StatusAccepted, imp)
+}
+
+// GetImport returns the authenticated user's data import from the path with how many of its follows and posts
+// were processed and imported so far.
+func (h *ImportHandler) GetImport(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	importID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid import ID")
+	}
+
+	imp, err := h.ImportUsecase.GetImport(c.Request().Context(), userID, importID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "import not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get data import: %w", err)
+	}
+	return c.JSON(http.StatusOK, imp)
+}
@@ -0,0 +1,30 @@
+package jwksHandler
+
+import (
+	"main/pkg/jwt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type JWKSHandler struct {
+	KeySet KeySet
+}
+
+type KeySet interface {
+	// JWKS returns the public keys currently accepted for access token verification.
+	JWKS() jwt.JWKSet
+}
+
+func NewJWKSHandler(keySet KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		KeySet: keySet,
+	}
+}
+
+// GetJWKS serves the active public keys so other services (posts, chat) can verify access tokens locally.
+// The response is cacheable for a short time only, because keys are rotated.
+func (h *JWKSHandler) GetJWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.KeySet.JWKS())
+}
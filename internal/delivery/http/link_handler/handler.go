@@ -0,0 +1,81 @@
+package linkHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type LinkHandler struct {
+	LinkUsecase LinkUsecase
+	Metrics     *metrics.Metrics
+}
+
+type LinkUsecase interface {
+
+	//Follow returns the target of the shortened link with the code and counts the click.
+	Follow(ctx context.Context, code string) (string, error)
+
+	//PostLinks returns the links of one of the user's posts with their clicks.
+	PostLinks(ctx context.Context, userID, postID uuid.UUID) ([]entity.LinkClicks, error)
+}
+
+func NewLinkHandler(linkUsecase LinkUsecase, metrics *metrics.Metrics) *LinkHandler {
+	return &LinkHandler{
+		LinkUsecase: linkUsecase,
+		Metrics:     metrics,
+	}
+}
+
+type PostLinksResponse struct {
+	Links       []entity.LinkClicks `json:"links"`
+	TotalClicks int64               `json:"total_clicks"`
+}
+
+// Redirect sends the client to the target of the shortened link from the path. The redirect isn't cached, so
+// every click reaches the server and is counted.
+func (h *LinkHandler) Redirect(c echo.Context) error {
+	url, err := h.LinkUsecase.Follow(c.Request().Context(), c.Param("code"))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "link not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to follow link: %w", err)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Redirect(http.StatusFound, url)
+}
+
+// PostLinks returns the links of the authenticated user's post from the path with their clicks.
+func (h *LinkHandler) PostLinks(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	links, err := h.LinkUsecase.PostLinks(c.Request().Context(), userID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list post links: %w", err)
+	}
+
+	resp := PostLinksResponse{Links: links}
+	for _, l := range links {
+		resp.TotalClicks += l.Clicks
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -1,60 +1,225 @@
 package http
 
 import (
+	"compress/gzip"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"main/domain/entity"
 	"main/internal/config"
 	metrics "main/internal/metrics"
+	"main/pkg/apperror"
+	"main/pkg/errreport"
+	"main/pkg/jwt"
+	"main/pkg/ratelimit"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/redis/go-redis/v9"
 )
 
 type AuthUsecase interface {
-	// VerifyUser verifies the access token and returns the user ID.
-	VerifyUser(token string) (userID uuid.UUID, err error)
+	// VerifyUser verifies the access token and returns its user ID, session ID and roles.
+	VerifyUser(ctx context.Context, token string) (jwt.AccessToken, error)
 }
 
-// Just a silly example
-func IsAdminMiddleware() echo.MiddlewareFunc {
+func AuthMiddleware(authUsecase AuthUsecase) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+
 			header := c.Request().Header.Get("authorization")
 			if header == "" || !strings.HasPrefix(header, "Bearer ") {
 				return echo.NewHTTPError(401, "Unauthorized")
 			}
-			if !strings.Contains(header, "admin_token") {
-				return echo.NewHTTPError(403, "Forbidden")
+
+			accessToken := strings.TrimPrefix(header, "Bearer ")
+
+			token, err := authUsecase.VerifyUser(c.Request().Context(), accessToken)
+			if err != nil {
+				return echo.NewHTTPError(401, "Unauthorized")
+			}
+			if token.UserID == uuid.Nil {
+				return echo.NewHTTPError(401, "Unauthorized")
 			}
-			return next(c)
 
+			// handlers and usecases read the caller from the request context, e.g. as the actor of audit events
+			c.SetRequest(c.Request().WithContext(ctxUtil.WithPrincipal(c.Request().Context(), principal(token))))
+			return next(c)
 		}
 	}
 }
 
-func AuthMiddleware(authUsecase AuthUsecase) echo.MiddlewareFunc {
+// QueryTokenMiddleware accepts the access token in the access_token query parameter for clients that can't set
+// headers, like browsers opening a WebSocket. It must run before AuthMiddleware and only be used on such routes.
+func QueryTokenMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if token := c.QueryParam("access_token"); token != "" && c.Request().Header.Get("authorization") == "" {
+				c.Request().Header.Set("authorization", "Bearer "+token)
+			}
+			return next(c)
+		}
+	}
+}
 
+// OptionalAuthMiddleware identifies the caller like AuthMiddleware on public routes, e.g. to show them posts
+// only their followers may see. Requests without a valid access token continue anonymously.
+func OptionalAuthMiddleware(authUsecase AuthUsecase) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
 			header := c.Request().Header.Get("authorization")
-			if header == "" || !strings.HasPrefix(header, "Bearer ") {
-				return echo.NewHTTPError(401, "Unauthorized")
+			if !strings.HasPrefix(header, "Bearer ") {
+				return next(c)
+			}
+			token, err := authUsecase.VerifyUser(c.Request().Context(), strings.TrimPrefix(header, "Bearer "))
+			if err != nil || token.UserID == uuid.Nil {
+				return next(c)
 			}
 
-			accessToken := strings.TrimPrefix(header, "Bearer ")
+			c.SetRequest(c.Request().WithContext(ctxUtil.WithPrincipal(c.Request().Context(), principal(token))))
+			return next(c)
+		}
+	}
+}
+
+// principal is the user authenticated by the access token.
+func principal(token jwt.AccessToken) ctxUtil.Principal {
+	return ctxUtil.Principal{UserID: token.UserID, SessionID: token.SessionID, Roles: token.Roles, Method: ctxUtil.AuthAccessToken}
+}
+
+type APIKeyAuthenticator interface {
+	// Authenticate returns the active API key matching the plain key.
+	Authenticate(ctx context.Context, plain string) (entity.APIKey, error)
+}
 
-			userID, err := authUsecase.VerifyUser(accessToken)
+// APIKeyMiddleware only lets through internal services presenting an API key in the X-API-Key header
+// whose scopes cover the given gRPC method, so a key grants the same access over HTTP and gRPC.
+func APIKeyMiddleware(apiKeys APIKeyAuthenticator, fullMethod string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			plain := c.Request().Header.Get("X-API-Key")
+			if plain == "" {
+				return echo.NewHTTPError(401, "Unauthorized")
+			}
+			key, err := apiKeys.Authenticate(c.Request().Context(), plain)
 			if err != nil {
 				return echo.NewHTTPError(401, "Unauthorized")
 			}
-			if userID == uuid.Nil {
+			if !key.Allows(fullMethod) {
+				return echo.NewHTTPError(403, "Forbidden")
+			}
+			c.SetRequest(c.Request().WithContext(ctxUtil.WithPrincipal(c.Request().Context(), ctxUtil.Principal{APIKeyID: key.ID, Method: ctxUtil.AuthAPIKey})))
+			return next(c)
+		}
+	}
+}
+
+type AppAuthenticator interface {
+	// Authenticate returns the valid access token of a developer app matching the plain token.
+	Authenticate(ctx context.Context, plain string) (entity.AppToken, error)
+
+	// RecordRequest counts a request of the app towards its usage, throttled if its rate limit rejected it.
+	RecordRequest(ctx context.Context, appID uuid.UUID, throttled bool)
+}
+
+// AppTokenMiddleware only lets through developer apps presenting an access token granted the scope, at most as
+// many requests per minute as the token allows. The app acts for no user, so the handlers behind it only serve
+// what anonymous callers see. Requests are let through while Redis can't count them.
+func AppTokenMiddleware(apps AppAuthenticator, limiter RateLimiter, scope string, logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get("authorization")
+			if !strings.HasPrefix(header, "Bearer ") {
+				c.Response().Header().Set("WWW-Authenticate", `Bearer realm="public"`)
+				return echo.NewHTTPError(401, "Unauthorized")
+			}
+			token, err := apps.Authenticate(c.Request().Context(), strings.TrimPrefix(header, "Bearer "))
+			if err != nil {
+				c.Response().Header().Set("WWW-Authenticate", `Bearer realm="public", error="invalid_token"`)
 				return echo.NewHTTPError(401, "Unauthorized")
 			}
+			if !token.Allows(scope) {
+				c.Response().Header().Set("WWW-Authenticate", `Bearer realm="public", error="insufficient_scope", scope="`+scope+`"`)
+				return echo.NewHTTPError(403, "Forbidden")
+			}
+
+			result, err := limiter.Allow(c.Request().Context(), "app:"+token.AppID.String(), ratelimit.Rule{Requests: token.RateLimit, Window: time.Minute})
+			if err != nil {
+				logger.Warn("Rate limiter unavailable", "app_id", token.AppID, "error", err)
+			} else {
+				for name, value := range result.Headers() {
+					c.Response().Header().Set(name, value)
+				}
+			}
+			throttled := err == nil && !result.Allowed
+			apps.RecordRequest(c.Request().Context(), token.AppID, throttled)
+			if throttled {
+				return apperror.ResourceExhausted("rate_limited", "too many requests, try again later")
+			}
+
+			c.SetRequest(c.Request().WithContext(ctxUtil.WithPrincipal(c.Request().Context(), ctxUtil.Principal{AppID: token.AppID, Method: ctxUtil.AuthAppToken})))
+			return next(c)
+		}
+	}
+}
+
+// RequestIDMiddleware puts the ID of the request into the context and sends it back in the X-Request-Id header.
+// The ID is taken from the X-Request-Id header of the request or generated.
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.SetRequest(c.Request().WithContext(ctxUtil.WithRequestID(c.Request().Context(), id)))
+		},
+	})
+}
+
+// RecoverMiddleware recovers from panics in handlers, logs and reports them and answers with a 500.
+func RecoverMiddleware(logger *slog.Logger, reporter errreport.Reporter) echo.MiddlewareFunc {
+	return middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			logger.Error("PANIC RECOVERED",
+				"path", c.Path(),
+				"method", c.Request().Method,
+				"panic", err,
+				"stack", string(stack),
+			)
+			// still recovering, so the reported stack includes the handler that panicked
+			reporter.Report(c.Request().Context(), fmt.Errorf("panic: %w", err), map[string]string{"method": c.Request().Method, "path": c.Path()})
+			return echo.NewHTTPError(500, "Internal Server Error").SetInternal(err)
+		},
+	})
+}
 
-			c.Set("userID", userID)
+// ClientInfoMiddleware puts the client IP and User-Agent into the request context for sessions and audit events.
+func ClientInfoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := ctxUtil.WithClientInfo(c.Request().Context(), c.RealIP(), c.Request().UserAgent())
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// RequireRoles only lets through users having any of the given roles, it must run after AuthMiddleware.
+func RequireRoles(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := ctxUtil.PrincipalFromContext(c.Request().Context())
+			if !ok || !principal.IsUser() {
+				return echo.NewHTTPError(401, "Unauthorized")
+			}
+			if !principal.HasAnyRole(roles...) {
+				return echo.NewHTTPError(403, "Forbidden")
+			}
 			return next(c)
 		}
 	}
@@ -97,6 +262,46 @@ func RateLimitMiddleware(client *redis.Client, cfg *config.RateLimiterConfig) ec
 	}
 }
 
+type RateLimiter interface {
+	// Allow counts a request of the caller identified by key against the rule.
+	Allow(ctx context.Context, key string, rule ratelimit.Rule) (ratelimit.Result, error)
+}
+
+// RouteRateLimitMiddleware limits the routes having a rule, keyed by "<method> <route path>" without the version
+// prefix so every version of a route shares one limit, per caller: the user
+// when the request carries a valid access token, the IP otherwise. It runs before the routes' own middlewares, so
+// it identifies the user itself. Requests are let through while Redis is unavailable.
+func RouteRateLimitMiddleware(limiter RateLimiter, authUsecase AuthUsecase, rules map[string]ratelimit.Rule, logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Request().Method + " " + routePath(c.Path())
+			rule, ok := rules[route]
+			if !ok {
+				return next(c)
+			}
+
+			caller := "ip:" + c.RealIP()
+			if header := c.Request().Header.Get("authorization"); strings.HasPrefix(header, "Bearer ") {
+				if token, err := authUsecase.VerifyUser(c.Request().Context(), strings.TrimPrefix(header, "Bearer ")); err == nil && token.UserID != uuid.Nil {
+					caller = "user:" + token.UserID.String()
+				}
+			}
+			result, err := limiter.Allow(c.Request().Context(), route+"|"+caller, rule)
+			if err != nil {
+				logger.Warn("Rate limiter unavailable", "route", route, "error", err)
+				return next(c)
+			}
+			for name, value := range result.Headers() {
+				c.Response().Header().Set(name, value)
+			}
+			if !result.Allowed {
+				return apperror.ResourceExhausted("rate_limited", "too many requests, try again later")
+			}
+			return next(c)
+		}
+	}
+}
+
 func MetricsMiddleware(m *metrics.Metrics) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -115,3 +320,92 @@ func MetricsMiddleware(m *metrics.Metrics) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// DecompressMiddleware inflates request bodies sent with Content-Encoding: gzip, so the body limits apply to the
+// inflated body rather than to what was sent.
+func DecompressMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Header.Get(echo.HeaderContentEncoding) != "gzip" {
+				return next(c)
+			}
+			body, err := gzip.NewReader(req.Body)
+			if errors.Is(err, io.EOF) {
+				return next(c)
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid gzip request body")
+			}
+			defer body.Close()
+
+			req.Body = body
+			req.ContentLength = -1
+			req.Header.Del(echo.HeaderContentEncoding)
+			return next(c)
+		}
+	}
+}
+
+// BodyLimitMiddleware answers requests whose body is over the limit of their route with 413. Limits are keyed by
+// "<method> <route path>" without the version prefix, other routes get defaultLimit. A body of unknown length is
+// cut at the limit, failing the handler reading past it.
+func BodyLimitMiddleware(defaultLimit int64, limits map[string]int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			limit, ok := limits[req.Method+" "+routePath(c.Path())]
+			if !ok {
+				limit = defaultLimit
+			}
+			if req.ContentLength > limit {
+				return errRequestTooLarge
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Response().Writer, req.Body, limit)}
+			req.Body = body
+			if err := next(c); err != nil {
+				// handlers report the failed read as a bad request, or wrap it, the limit is what failed
+				if body.exceeded {
+					return errRequestTooLarge
+				}
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+var errRequestTooLarge = apperror.RequestTooLarge("request_too_large", "request body is too large")
+
+// limitedBody records whether reading the body failed for going past its limit.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// GzipMiddleware compresses the responses of clients accepting gzip. Streams, which must reach the client as they
+// are written, media files, which are compressed already, and /metrics, which compresses itself, are skipped.
+func GzipMiddleware(cfg config.CompressionConfig) echo.MiddlewareFunc {
+	return middleware.GzipWithConfig(middleware.GzipConfig{
+		Level:     cfg.Level,
+		MinLength: cfg.MinLength,
+		Skipper: func(c echo.Context) bool {
+			switch path := routePath(c.Path()); path {
+			case "/metrics", "/ws", "/notifications/stream":
+				return true
+			default:
+				return strings.HasPrefix(path, "/media")
+			}
+		},
+	})
+}
@@ -0,0 +1,312 @@
+package notificationHandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// streamPingInterval is how often an idle stream gets a comment, so proxies keep it open and missed signals are caught up.
+const streamPingInterval = 30 * time.Second
+
+type NotificationHandler struct {
+	NotificationUsecase NotificationUsecase
+	PushUsecase         PushUsecase
+	Metrics             *metrics.Metrics
+}
+
+type NotificationUsecase interface {
+
+	//ListNotifications returns a page of the user's notifications, newest first, and the cursor of the next page.
+	ListNotifications(ctx context.Context, userID uuid.UUID, cursor string, limit int) (notifications []entity.Notification, nextCursor string, err error)
+
+	//WatchNotifications returns a signal whenever the user gets new notifications until cancel is called.
+	WatchNotifications(userID uuid.UUID) (signals <-chan struct{}, cancel func())
+
+	//NotificationsAfter returns up to limit of the user's notifications created after the cursor position, oldest first.
+	NotificationsAfter(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]entity.Notification, error)
+
+	//CountUnread returns the number of the user's unread notifications.
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+
+	//MarkRead marks one of the user's notifications read.
+	MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error
+
+	//MarkAllRead marks all of the user's notifications read.
+	MarkAllRead(ctx context.Context, userID uuid.UUID) error
+}
+
+type PushUsecase interface {
+
+	//RegisterDevice registers a device of the user to push notifications to.
+	RegisterDevice(ctx context.Context, userID uuid.UUID, device entity.PushDevice) (entity.PushDevice, error)
+
+	//UnregisterDevice stops pushing notifications to one of the user's devices.
+	UnregisterDevice(ctx context.Context, userID, deviceID uuid.UUID) error
+
+	//ListDevices returns the user's devices.
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]entity.PushDevice, error)
+
+	//WebPushKey returns the VAPID public key browsers subscribe with, empty if WebPush isn't enabled.
+	WebPushKey() string
+}
+
+func NewNotificationHandler(notificationUsecase NotificationUsecase, pushUsecase PushUsecase, metrics *metrics.Metrics) *NotificationHandler {
+	return &NotificationHandler{
+		NotificationUsecase: notificationUsecase,
+		PushUsecase:         pushUsecase,
+		Metrics:             metrics,
+	}
+}
+
+// DTOs
+type ListNotificationsResponse struct {
+	Notifications []entity.Notification `json:"notifications"`
+	pagination.Response
+}
+
+type UnreadCountResponse struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+// RegisterDeviceRequest registers an FCM or APNs token, or a WebPush subscription with its endpoint as the token.
+type RegisterDeviceRequest struct {
+	Platform entity.PushPlatform `json:"platform" validate:"required,oneof=fcm apns webpush"`
+	Token    string              `json:"token" validate:"required"`
+	P256dh   string              `json:"p256dh"`
+	Auth     string              `json:"auth"`
+}
+
+type DevicesResponse struct {
+	Devices []entity.PushDevice `json:"devices"`
+}
+
+type WebPushKeyResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+// ListNotifications returns a page of the authenticated user's notifications.
+func (h *NotificationHandler) ListNotifications(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req pagination.Request
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	notifications, nextCursor, err := h.NotificationUsecase.ListNotifications(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list notifications: %w", err)
+	}
+	if notifications == nil {
+		notifications = []entity.Notification{}
+	}
+	return c.JSON(http.StatusOK, ListNotificationsResponse{
+		Notifications: notifications,
+		Response:      pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// Stream pushes the authenticated user's new notifications as Server-Sent Events until the client goes away.
+// Each event's id is the position of the notification: a client reconnecting with Last-Event-ID first gets
+// the notifications it missed, a new stream starts after the newest notification.
+func (h *NotificationHandler) Stream(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	ctx := c.Request().Context()
+
+	// subscribed before the first read, so notifications created in between aren't missed
+	signals, cancel := h.NotificationUsecase.WatchNotifications(userID)
+	defer cancel()
+
+	cursor := c.Request().Header.Get("Last-Event-ID")
+	if cursor == "" {
+		latest, _, err := h.NotificationUsecase.ListNotifications(ctx, userID, "", 1)
+		if err != nil {
+			return fmt.Errorf("failed to list notifications: %w", err)
+		}
+		if len(latest) > 0 {
+			cursor = pagination.Cursor{CreatedAt: latest[0].CreatedAt, ID: latest[0].ID}.Encode()
+		}
+	} else if _, err := pagination.Decode(cursor); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Last-Event-ID")
+	}
+
+	res := c.Response()
+	// the server timeouts are meant for plain requests
+	_ = http.NewResponseController(res).SetWriteDeadline(time.Time{})
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	for {
+		for {
+			notifications, err := h.NotificationUsecase.NotificationsAfter(ctx, userID, cursor, pagination.MaxLimit)
+			if err != nil {
+				// the response has started, the client reconnects with the last id it got
+				return nil
+			}
+			for _, n := range notifications {
+				data, err := json.Marshal(n)
+				if err != nil {
+					return nil
+				}
+				cursor = pagination.Cursor{CreatedAt: n.CreatedAt, ID: n.ID}.Encode()
+				if _, err := fmt.Fprintf(res, "id: %s\nevent: notification\ndata: %s\n\n", cursor, data); err != nil {
+					return nil
+				}
+			}
+			res.Flush()
+			if len(notifications) < pagination.MaxLimit {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signals:
+		case <-ping.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// UnreadCount returns the number of the authenticated user's unread notifications for the badge.
+func (h *NotificationHandler) UnreadCount(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	count, err := h.NotificationUsecase.CountUnread(c.Request().Context(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return c.JSON(http.StatusOK, UnreadCountResponse{UnreadCount: count})
+}
+
+// MarkRead marks the authenticated user's notification from the path read.
+func (h *NotificationHandler) MarkRead(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid notification ID")
+	}
+
+	err = h.NotificationUsecase.MarkRead(c.Request().Context(), userID, notificationID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "notification not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// MarkAllRead marks all of the authenticated user's notifications read.
+func (h *NotificationHandler) MarkAllRead(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	if err := h.NotificationUsecase.MarkAllRead(c.Request().Context(), userID); err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegisterDevice registers a device of the authenticated user to push notifications to while they are offline.
+func (h *NotificationHandler) RegisterDevice(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req RegisterDeviceRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	device, err := h.PushUsecase.RegisterDevice(c.Request().Context(), userID, entity.PushDevice{
+		Platform: req.Platform,
+		Token:    req.Token,
+		P256dh:   req.P256dh,
+		Auth:     req.Auth,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register device: %w", err)
+	}
+	return c.JSON(http.StatusCreated, device)
+}
+
+// ListDevices returns the devices the authenticated user registered.
+func (h *NotificationHandler) ListDevices(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	devices, err := h.PushUsecase.ListDevices(c.Request().Context(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	if devices == nil {
+		devices = []entity.PushDevice{}
+	}
+	return c.JSON(http.StatusOK, DevicesResponse{Devices: devices})
+}
+
+// UnregisterDevice stops pushing notifications to the authenticated user's device from the path.
+func (h *NotificationHandler) UnregisterDevice(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid device ID")
+	}
+
+	err = h.PushUsecase.UnregisterDevice(c.Request().Context(), userID, deviceID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "device not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unregister device: %w", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// WebPushKey returns the VAPID public key browsers pass as applicationServerKey when subscribing.
+func (h *NotificationHandler) WebPushKey(c echo.Context) error {
+	key := h.PushUsecase.WebPushKey()
+	if key == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "web push is not enabled")
+	}
+	return c.JSON(http.StatusOK, WebPushKeyResponse{PublicKey: key})
+}
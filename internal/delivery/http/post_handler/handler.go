@@ -0,0 +1,428 @@
+package postHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/etag"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type PostHandler struct {
+	PostUsecase PostUsecase
+	FeedUsecase FeedUsecase
+	Metrics     *metrics.Metrics
+}
+
+type PostUsecase interface {
+
+	//CreatePost publishes a post of the user, quoteOfID is uuid.Nil for a regular post.
+	CreatePost(ctx context.Context, userID uuid.UUID, description, mediaURL string, quoteOfID uuid.UUID, visibility entity.PostVisibility) (entity.Post, error)
+
+	//CreateVideoPost publishes a post of the user with an uploaded MP4/QuickTime video.
+	CreateVideoPost(ctx context.Context, userID uuid.UUID, description string, visibility entity.PostVisibility, video io.ReadSeeker, size int64) (entity.Post, error)
+
+	//GetPost returns the post if the viewer may see it.
+	GetPost(ctx context.Context, viewerID, postID uuid.UUID) (entity.Post, error)
+
+	//UpdatePost edits the description and, unless empty, the visibility of one of the user's posts at the version.
+	UpdatePost(ctx context.Context, userID, postID uuid.UUID, version int64, description string, visibility entity.PostVisibility) (entity.Post, error)
+
+	//DeletePost deletes one of the user's posts.
+	DeletePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//RestorePost undeletes one of the user's posts deleted within the undelete window.
+	RestorePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//LikePost likes the post on behalf of the user, liking an already liked post succeeds.
+	LikePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//UnlikePost removes the user's like from the post.
+	UnlikePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//Repost shares the post with the user's followers, reposting an already reposted post succeeds.
+	Repost(ctx context.Context, userID, postID uuid.UUID) error
+
+	//Unrepost removes the user's repost of the post.
+	Unrepost(ctx context.Context, userID, postID uuid.UUID) error
+}
+
+type FeedUsecase interface {
+
+	//GetFeed returns a page of the user's home timeline, newest first, and the cursor of the next page.
+	GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) (items []entity.FeedItem, nextCursor string, err error)
+
+	//GetHashtagPosts returns a page of posts tagged with the hashtag, newest first, and the cursor of the next page.
+	GetHashtagPosts(ctx context.Context, viewerID uuid.UUID, tag, cursor string, limit int) (posts []entity.Post, nextCursor string, err error)
+
+	//TrendingHashtags returns the most used hashtags of the last day.
+	TrendingHashtags(ctx context.Context, limit int) ([]entity.TrendingHashtag, error)
+
+	//GetExplore returns a page of trending posts, highest score first, and the cursor of the next page.
+	GetExplore(ctx context.Context, viewerID uuid.UUID, cursor string, limit int) (posts []entity.RankedPost, nextCursor string, err error)
+}
+
+func NewPostHandler(postUsecase PostUsecase, feedUsecase FeedUsecase, metrics *metrics.Metrics) *PostHandler {
+	return &PostHandler{
+		PostUsecase: postUsecase,
+		FeedUsecase: feedUsecase,
+		Metrics:     metrics,
+	}
+}
+
+// DTOs
+type CreatePostRequest struct {
+	Description string `json:"description" validate:"max=500"`
+	MediaURL    string `json:"media_url"`
+	// QuoteOfID makes the post a quote of another post
+	QuoteOfID string `json:"quote_of_id" validate:"omitempty,uuid"`
+	// Visibility is public (default), followers, close_friends or private
+	Visibility entity.PostVisibility `json:"visibility" validate:"omitempty,oneof=public followers close_friends private"`
+}
+
+type UpdatePostRequest struct {
+	// Version is the version of the post the edit was made on, the update fails with 409 if it was edited since
+	Version     int64  `json:"version" validate:"required"`
+	Description string `json:"description" validate:"max=500"`
+	// Visibility is left unchanged if empty
+	Visibility entity.PostVisibility `json:"visibility" validate:"omitempty,oneof=public followers close_friends private"`
+}
+
+type FeedRequest struct {
+	pagination.Request
+}
+
+type FeedResponse struct {
+	Items []entity.FeedItem `json:"items"`
+	pagination.Response
+}
+
+type HashtagPostsResponse struct {
+	Posts []entity.Post `json:"posts"`
+	pagination.Response
+}
+
+type ExploreResponse struct {
+	Posts []entity.RankedPost `json:"posts"`
+	pagination.Response
+}
+
+type TrendingHashtagsRequest struct {
+	Limit int `query:"limit"`
+}
+
+type TrendingHashtagsResponse struct {
+	Hashtags []entity.TrendingHashtag `json:"hashtags"`
+}
+
+// CreatePost publishes a post of the authenticated user.
+func (h *PostHandler) CreatePost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req CreatePostRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	var quoteOfID uuid.UUID
+	if req.QuoteOfID != "" {
+		quoteOfID = uuid.MustParse(req.QuoteOfID)
+	}
+
+	post, err := h.PostUsecase.CreatePost(c.Request().Context(), userID, req.Description, req.MediaURL, quoteOfID, req.Visibility)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "quoted post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create post: %w", err)
+	}
+	return c.JSON(http.StatusCreated, post)
+}
+
+// CreateVideoPost publishes a post of the authenticated user with the video uploaded as the multipart "video" field.
+func (h *PostHandler) CreateVideoPost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	fileHeader, err := c.FormFile("video")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	video, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	defer video.Close()
+
+	post, err := h.PostUsecase.CreateVideoPost(c.Request().Context(), userID, c.FormValue("description"),
+		entity.PostVisibility(c.FormValue("visibility")), video, fileHeader.Size)
+	if err != nil {
+		return fmt.Errorf("failed to create post: %w", err)
+	}
+	return c.JSON(http.StatusCreated, post)
+}
+
+// GetPost returns the post from the path.
+func (h *PostHandler) GetPost(c echo.Context) error {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	// the route is public, userID is only set for callers with a valid access token
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	post, err := h.PostUsecase.GetPost(c.Request().Context(), viewerID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	// every viewer allowed to see the post sees the same representation; the version only changes with edits
+	return etag.Respond(c, etag.Weak(post.Version, post.LikesCount, post.RepostsCount, post.QuotesCount, post.CommentsCount), post)
+}
+
+// UpdatePost edits the description of the authenticated user's post from the path.
+func (h *PostHandler) UpdatePost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	var req UpdatePostRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	post, err := h.PostUsecase.UpdatePost(c.Request().Context(), userID, postID, req.Version, req.Description, req.Visibility)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update post: %w", err)
+	}
+	return c.JSON(http.StatusOK, post)
+}
+
+// DeletePost deletes the authenticated user's post from the path.
+func (h *PostHandler) DeletePost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	err = h.PostUsecase.DeletePost(c.Request().Context(), userID, postID)
+	if errors.Is(err, customerrors.ErrNoTagsAffected) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// RestorePost undeletes the authenticated user's post from the path, if it was deleted within the undelete window.
+func (h *PostHandler) RestorePost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	err = h.PostUsecase.RestorePost(c.Request().Context(), userID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "deleted post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore post: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// GetFeed returns a page of the authenticated user's home timeline.
+func (h *PostHandler) GetFeed(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req FeedRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	items, nextCursor, err := h.FeedUsecase.GetFeed(c.Request().Context(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to get feed: %w", err)
+	}
+	if items == nil {
+		items = []entity.FeedItem{}
+	}
+	return c.JSON(http.StatusOK, FeedResponse{
+		Items:    items,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// LikePost likes the post from the path on behalf of the authenticated user.
+func (h *PostHandler) LikePost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	err = h.PostUsecase.LikePost(c.Request().Context(), userID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to like post: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// UnlikePost removes the authenticated user's like from the post from the path.
+func (h *PostHandler) UnlikePost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	if err := h.PostUsecase.UnlikePost(c.Request().Context(), userID, postID); err != nil {
+		return fmt.Errorf("failed to unlike post: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// Repost shares the post from the path with the authenticated user's followers.
+func (h *PostHandler) Repost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	err = h.PostUsecase.Repost(c.Request().Context(), userID, postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to repost post: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// Unrepost removes the authenticated user's repost of the post from the path.
+func (h *PostHandler) Unrepost(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid post ID")
+	}
+
+	if err := h.PostUsecase.Unrepost(c.Request().Context(), userID, postID); err != nil {
+		return fmt.Errorf("failed to undo repost: %w", err)
+	}
+	return c.NoContent(204)
+}
+
+// GetHashtagPosts returns a page of posts tagged with the hashtag from the path.
+func (h *PostHandler) GetHashtagPosts(c echo.Context) error {
+	var req FeedRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	posts, nextCursor, err := h.FeedUsecase.GetHashtagPosts(c.Request().Context(), viewerID, c.Param("tag"), req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to get hashtag posts: %w", err)
+	}
+	if posts == nil {
+		posts = []entity.Post{}
+	}
+	return c.JSON(http.StatusOK, HashtagPostsResponse{
+		Posts:    posts,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// TrendingHashtags returns the most used hashtags of the last day.
+func (h *PostHandler) TrendingHashtags(c echo.Context) error {
+	var req TrendingHashtagsRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	hashtags, err := h.FeedUsecase.TrendingHashtags(c.Request().Context(), req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to get trending hashtags: %w", err)
+	}
+	if hashtags == nil {
+		hashtags = []entity.TrendingHashtag{}
+	}
+	return c.JSON(http.StatusOK, TrendingHashtagsResponse{
+		Hashtags: hashtags,
+	})
+}
+
+// GetExplore returns a page of trending posts, the caller is optional.
+func (h *PostHandler) GetExplore(c echo.Context) error {
+	var req FeedRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	posts, nextCursor, err := h.FeedUsecase.GetExplore(c.Request().Context(), viewerID, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to get explore: %w", err)
+	}
+	if posts == nil {
+		posts = []entity.RankedPost{}
+	}
+	return c.JSON(http.StatusOK, ExploreResponse{
+		Posts:    posts,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
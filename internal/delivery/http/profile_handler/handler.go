@@ -0,0 +1,107 @@
+package profileHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"main/pkg/etag"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ProfileHandler struct {
+	ProfileUsecase ProfileUsecase
+	Metrics        *metrics.Metrics
+}
+
+type ProfileUsecase interface {
+
+	//GetProfile returns the user's profile as the viewer may see it.
+	GetProfile(ctx context.Context, viewerID, userID uuid.UUID) (entity.Profile, error)
+
+	//UpdateProfile changes the non-nil fields of the user's profile and returns the updated profile.
+	UpdateProfile(ctx context.Context, userID uuid.UUID, update entity.ProfileUpdate) (entity.Profile, error)
+}
+
+func NewProfileHandler(profileUsecase ProfileUsecase, metrics *metrics.Metrics) *ProfileHandler {
+	return &ProfileHandler{
+		ProfileUsecase: profileUsecase,
+		Metrics:        metrics,
+	}
+}
+
+// DTOs
+// UpdateProfileRequest changes only the fields present in the body, an empty string clears a field and age 0 clears the age.
+type UpdateProfileRequest struct {
+	// Version is the version of the profile the edit was made on, the update fails with 409 if it was edited since
+	Version   int64          `json:"version" validate:"required"`
+	Name      *string        `json:"name"`
+	Bio       *string        `json:"bio"`
+	AvatarURL *string        `json:"avatar_url" validate:"omitempty,http_url,max=2048"`
+	Gender    *entity.Gender `json:"gender" validate:"omitempty,oneof=male female other"`
+	Age       *int           `json:"age" validate:"omitempty,min=13,max=120"`
+}
+
+// GetProfile returns the profile of the user from the path, the caller is optional.
+func (h *ProfileHandler) GetProfile(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+
+	// the route is public, viewerID is only set for callers with a valid access token
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	profile, err := h.ProfileUsecase.GetProfile(c.Request().Context(), viewerID, userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get profile: %w", err)
+	}
+	// the version only changes with edits, the privacy settings aren't part of the profile and restricted viewers
+	// see less of it
+	return etag.Respond(c, etag.Weak(profile.Version, profile.FollowersCount, profile.FollowingCount,
+		profile.IsPrivate, profile.Restricted), profile)
+}
+
+// UpdateProfile edits the profile of the authenticated user, the user in the path must be the caller.
+func (h *ProfileHandler) UpdateProfile(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+	if profileID != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "only your own profile can be edited")
+	}
+
+	var req UpdateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	profile, err := h.ProfileUsecase.UpdateProfile(c.Request().Context(), userID, entity.ProfileUpdate{
+		Version:   req.Version,
+		Name:      req.Name,
+		Bio:       req.Bio,
+		AvatarURL: req.AvatarURL,
+		Gender:    req.Gender,
+		Age:       req.Age,
+	})
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "profile not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	return c.JSON(http.StatusOK, profile)
+}
@@ -2,9 +2,35 @@ package http
 
 import (
 	"log/slog"
+	"main/domain/entity"
 	"main/internal/config"
+	adminHandler "main/internal/delivery/http/admin_handler"
 	handler "main/internal/delivery/http/auth_handler"
+	blacklistHandler "main/internal/delivery/http/blacklist_handler"
+	chatHandler "main/internal/delivery/http/chat_handler"
+	closeFriendsHandler "main/internal/delivery/http/close_friends_handler"
+	commentHandler "main/internal/delivery/http/comment_handler"
+	developerHandler "main/internal/delivery/http/developer_handler"
+	exportHandler "main/internal/delivery/http/export_handler"
+	federationHandler "main/internal/delivery/http/federation_handler"
+	followHandler "main/internal/delivery/http/follow_handler"
+	graphqlHandler "main/internal/delivery/http/graphql_handler"
+	healthHandler "main/internal/delivery/http/health_handler"
+	importHandler "main/internal/delivery/http/import_handler"
+	jwksHandler "main/internal/delivery/http/jwks_handler"
+	linkHandler "main/internal/delivery/http/link_handler"
+	notificationHandler "main/internal/delivery/http/notification_handler"
+	postHandler "main/internal/delivery/http/post_handler"
+	profileHandler "main/internal/delivery/http/profile_handler"
+	searchHandler "main/internal/delivery/http/search_handler"
+	seoHandler "main/internal/delivery/http/seo_handler"
+	settingsHandler "main/internal/delivery/http/settings_handler"
+	wsHandler "main/internal/delivery/http/ws_handler"
 	metrics "main/internal/metrics"
+	"main/pkg/errreport"
+	authv1 "main/pkg/proto/gen/auth/v1"
+	"main/pkg/ratelimit"
+	"net/http"
 
 	"github.com/labstack/echo/v4"
 	middleware "github.com/labstack/echo/v4/middleware"
@@ -12,20 +38,38 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// MapRoutes sets up the middlewares shared by every route and the unversioned routes of the instance itself,
+// the feature routes are mapped into the versioned API by the Map*Routes functions of their modules.
 func MapRoutes(
 	e *echo.Echo,
-	authHandler *handler.AuthHandler,
+	jwksHandler *jwksHandler.JWKSHandler,
+	healthHandler *healthHandler.HealthHandler,
 	authUsecase AuthUsecase,
 	logger *slog.Logger,
-	rateLimiterConfig config.RateLimiterConfig,
+	reporter errreport.Reporter,
+	limiter RateLimiter,
+	routeLimits map[string]ratelimit.Rule,
+	maxBodySize int64,
+	bodyLimits map[string]int64,
+	compression config.CompressionConfig,
 	m *metrics.Metrics,
-	client *redis.Client,
 ) {
 	// Middlewares
-	e.Use(middleware.Recover())
+	e.Use(RequestIDMiddleware())
+	e.Use(RecoverMiddleware(logger, reporter))
+	if compression.Enabled {
+		e.Use(GzipMiddleware(compression))
+		e.Use(DecompressMiddleware())
+	}
+	e.Use(BodyLimitMiddleware(maxBodySize, bodyLimits))
 	e.Use(middleware.CORS())
+	e.Use(ClientInfoMiddleware())
+	e.Use(RouteRateLimitMiddleware(limiter, authUsecase, routeLimits, logger))
 	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		Skipper:   func(c echo.Context) bool { return c.Path() == "/metrics" }, // Skip logging for /metrics endpoint
+		Skipper: func(c echo.Context) bool {
+			// Skip logging for /metrics and the probes, they are polled every few seconds
+			return c.Path() == "/metrics" || c.Path() == "/healthz" || c.Path() == "/readyz"
+		},
 		LogURI:    true,
 		LogMethod: true,
 		LogStatus: true,
@@ -59,12 +103,246 @@ func MapRoutes(
 	))
 
 	//routes
-	e.POST("/logout", authHandler.Logout, MetricsMiddleware(m))
-	e.POST("/logout_all", authHandler.LogoutAll, AuthMiddleware(authUsecase), MetricsMiddleware(m))
-	e.POST("/register", authHandler.Register, MetricsMiddleware(m))
-	e.POST("/login", authHandler.Login, RateLimitMiddleware(client, &rateLimiterConfig), MetricsMiddleware(m))
-	e.POST("/refresh", authHandler.RefreshSession, MetricsMiddleware(m))
+	e.GET("/.well-known/jwks.json", jwksHandler.GetJWKS, MetricsMiddleware(m))
 	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+	e.GET("/healthz", healthHandler.Liveness)
+	e.GET("/readyz", healthHandler.Readiness)
 
 	logger.Info("HTTP routes mapped successfully")
 }
+
+// MapAuthRoutes maps the account, session and admin routes.
+func MapAuthRoutes(
+	api *API,
+	authHandler *handler.AuthHandler,
+	adminHandler *adminHandler.AdminHandler,
+	exportHandler *exportHandler.ExportHandler,
+	importHandler *importHandler.ImportHandler,
+	authUsecase AuthUsecase,
+	apiKeys APIKeyAuthenticator,
+	rateLimiterConfig config.RateLimiterConfig,
+	m *metrics.Metrics,
+	client *redis.Client,
+) {
+	// sessions: the user comes from the access token, except for refresh, which the refresh token cookie authenticates
+	sessions := api.Group("/auth", MetricsMiddleware(m))
+	sessions.POST("/refresh", authHandler.RefreshSession)
+	sessions.POST("/logout", authHandler.Logout, AuthMiddleware(authUsecase))
+	sessions.POST("/logout_all", authHandler.LogoutAll, AuthMiddleware(authUsecase))
+	sessions.GET("/sessions", authHandler.ListSessions, AuthMiddleware(authUsecase))
+	sessions.PATCH("/sessions/:id", authHandler.RenameSession, AuthMiddleware(authUsecase))
+	sessions.DELETE("/sessions/:id", authHandler.LogoutSession, AuthMiddleware(authUsecase))
+	// the session routes before the /auth group, only served unversioned for existing clients
+	api.Legacy(http.MethodPost, "/logout", "/auth/logout", authHandler.Logout, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.Legacy(http.MethodGet, "/sessions", "/auth/sessions", authHandler.ListSessions, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.Legacy(http.MethodPatch, "/sessions/:id", "/auth/sessions/:id", authHandler.RenameSession, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.Legacy(http.MethodPost, "/logout_all", "/auth/logout_all", authHandler.LogoutAll, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.Legacy(http.MethodPost, "/refresh", "/auth/refresh", authHandler.RefreshSession, MetricsMiddleware(m))
+	api.POST("/register", authHandler.Register, MetricsMiddleware(m))
+	api.GET("/username/available", authHandler.CheckUsername, RateLimitMiddleware(client, &rateLimiterConfig), MetricsMiddleware(m))
+	api.POST("/login", authHandler.Login, RateLimitMiddleware(client, &rateLimiterConfig), MetricsMiddleware(m))
+	api.POST("/login/confirm", authHandler.ConfirmLogin, RateLimitMiddleware(client, &rateLimiterConfig), MetricsMiddleware(m))
+	api.POST("/password", authHandler.ChangePassword, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/email", authHandler.RequestEmailChange, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/introspect", authHandler.Introspect, APIKeyMiddleware(apiKeys, authv1.AuthService_Introspect_FullMethodName), MetricsMiddleware(m))
+	api.POST("/email/confirm", authHandler.ConfirmEmailChange, MetricsMiddleware(m))
+	api.DELETE("/account", authHandler.DeleteAccount, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/account/export", exportHandler.RequestExport, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/account/export/:id", exportHandler.GetExport, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/account/import", importHandler.StartImport, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/account/import/:id", importHandler.GetImport, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+
+	admin := api.Group("/admin", AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	admin.POST("/users/:id/block", adminHandler.BlockUser, RequireRoles("moderator", "admin"))
+	admin.DELETE("/users/:id/block", adminHandler.UnblockUser, RequireRoles("moderator", "admin"))
+	admin.POST("/users/:id/restore", adminHandler.RestoreUser, RequireRoles("admin"))
+	admin.POST("/users/:id/roles", adminHandler.GrantRole, RequireRoles("admin"))
+	admin.DELETE("/users/:id/roles/:role", adminHandler.RevokeRole, RequireRoles("admin"))
+	admin.GET("/audit", adminHandler.ListAuditEvents, RequireRoles("admin"))
+	admin.POST("/api-keys", adminHandler.IssueAPIKey, RequireRoles("admin"))
+	admin.GET("/api-keys", adminHandler.ListAPIKeys, RequireRoles("admin"))
+	admin.DELETE("/api-keys/:id", adminHandler.RevokeAPIKey, RequireRoles("admin"))
+	admin.GET("/users/lookup", adminHandler.LookupUser, RequireRoles("moderator", "admin"))
+	admin.GET("/users/:id/sessions", adminHandler.ListUserSessions, RequireRoles("admin"))
+	admin.DELETE("/users/:id/sessions", adminHandler.RevokeAllSessions, RequireRoles("admin"))
+	admin.DELETE("/users/:id/sessions/:session_id", adminHandler.RevokeSession, RequireRoles("admin"))
+	admin.DELETE("/content/:type/:id", adminHandler.TakeDownContent, RequireRoles("moderator", "admin"))
+	admin.GET("/reports", adminHandler.ListReports, RequireRoles("moderator", "admin"))
+	admin.POST("/reports/:id/resolve", adminHandler.ResolveReport, RequireRoles("moderator", "admin"))
+}
+
+// MapProfileRoutes maps the routes of profiles and the relations between users.
+func MapProfileRoutes(
+	api *API,
+	profileHandler *profileHandler.ProfileHandler,
+	followHandler *followHandler.FollowHandler,
+	blacklistHandler *blacklistHandler.BlacklistHandler,
+	closeFriendsHandler *closeFriendsHandler.CloseFriendsHandler,
+	settingsHandler *settingsHandler.SettingsHandler,
+	authUsecase AuthUsecase,
+	m *metrics.Metrics,
+) {
+	api.GET("/users/:id/profile", profileHandler.GetProfile, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.PATCH("/users/:id/profile", profileHandler.UpdateProfile, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/users/:id/follow", followHandler.Follow, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/users/:id/follow", followHandler.Unfollow, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/users/:id/followers", followHandler.ListFollowers, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/users/:id/following", followHandler.ListFollowing, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/follow-requests", followHandler.ListFollowRequests, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/follow-requests/:id/approve", followHandler.ApproveFollowRequest, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/follow-requests/:id/reject", followHandler.RejectFollowRequest, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/users/:id/block", blacklistHandler.Block, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/users/:id/block", blacklistHandler.Unblock, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/blocks", blacklistHandler.ListBlocked, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/close-friends", closeFriendsHandler.ListCloseFriends, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/close-friends/:id", closeFriendsHandler.AddCloseFriend, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/close-friends/:id", closeFriendsHandler.RemoveCloseFriend, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/settings", settingsHandler.GetSettings, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.PATCH("/settings", settingsHandler.UpdateSettings, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+}
+
+// MapPostRoutes maps the routes of posts, comments, feeds and search.
+func MapPostRoutes(
+	api *API,
+	postHandler *postHandler.PostHandler,
+	commentHandler *commentHandler.CommentHandler,
+	searchHandler *searchHandler.SearchHandler,
+	graphqlHandler *graphqlHandler.GraphQLHandler,
+	authUsecase AuthUsecase,
+	m *metrics.Metrics,
+) {
+	api.GET("/feed", postHandler.GetFeed, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/explore", postHandler.GetExplore, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/posts", postHandler.CreatePost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/posts/video", postHandler.CreateVideoPost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/posts/:id", postHandler.GetPost, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.PATCH("/posts/:id", postHandler.UpdatePost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/posts/:id", postHandler.DeletePost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/posts/:id/restore", postHandler.RestorePost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/posts/:id/like", postHandler.LikePost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/posts/:id/like", postHandler.UnlikePost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/posts/:id/repost", postHandler.Repost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/posts/:id/repost", postHandler.Unrepost, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/search/posts", searchHandler.SearchPosts, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/search/users", searchHandler.SearchUsers, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/hashtags/trending", postHandler.TrendingHashtags, MetricsMiddleware(m))
+	api.GET("/hashtags/:tag/posts", postHandler.GetHashtagPosts, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/posts/:id/comments", commentHandler.ListComments, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/posts/:id/comments", commentHandler.CreateComment, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.PATCH("/comments/:id", commentHandler.UpdateComment, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/comments/:id", commentHandler.DeleteComment, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/comments/:id/restore", commentHandler.RestoreComment, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	// GraphQL for clients fetching nested data in one round trip, e.g. posts with their authors and like state
+	api.POST("/graphql", graphqlHandler.Query, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/graphql", graphqlHandler.Query, OptionalAuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/graphql/schema", graphqlHandler.Schema, MetricsMiddleware(m))
+}
+
+// MapChatRoutes maps the chat routes and the WebSocket endpoint.
+func MapChatRoutes(api *API, chatHandler *chatHandler.ChatHandler, wsHandler *wsHandler.WSHandler, authUsecase AuthUsecase, m *metrics.Metrics) {
+	api.GET("/chats", chatHandler.ListChats, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats", chatHandler.CreateChat, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/chats/search", chatHandler.SearchMessages, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/groups", chatHandler.CreateGroup, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/chats/:id", chatHandler.GetChat, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.PATCH("/chats/:id", chatHandler.UpdateGroup, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/:id/join", chatHandler.JoinChat, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/:id/leave", chatHandler.LeaveChat, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/chats/:id/members", chatHandler.ListMembers, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/:id/members", chatHandler.AddMembers, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/chats/:id/members/:userId", chatHandler.RemoveMember, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.PATCH("/chats/:id/members/:userId", chatHandler.SetMemberRole, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/chats/:id/messages", chatHandler.ListMessages, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/:id/messages", chatHandler.SendMessage, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/:id/messages/attachment", chatHandler.SendAttachment, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.PATCH("/chats/:id/messages/:messageId", chatHandler.EditMessage, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/chats/:id/messages/:messageId", chatHandler.DeleteMessage, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/:id/messages/:messageId/restore", chatHandler.RestoreMessage, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/chats/:id/read", chatHandler.MarkRead, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	// long-lived, so it is left out of the request duration metrics
+	api.GET("/ws", wsHandler.Serve, QueryTokenMiddleware(), AuthMiddleware(authUsecase))
+}
+
+// MapNotificationRoutes maps the routes of notifications and push devices.
+func MapNotificationRoutes(api *API, notificationHandler *notificationHandler.NotificationHandler, authUsecase AuthUsecase, m *metrics.Metrics) {
+	api.GET("/notifications", notificationHandler.ListNotifications, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	// long-lived like /ws; EventSource can't set headers, so the access token may also come in the query
+	api.GET("/notifications/stream", notificationHandler.Stream, QueryTokenMiddleware(), AuthMiddleware(authUsecase))
+	api.GET("/notifications/unread-count", notificationHandler.UnreadCount, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/notifications/read", notificationHandler.MarkAllRead, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/notifications/:id/read", notificationHandler.MarkRead, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/notifications/devices", notificationHandler.ListDevices, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.POST("/notifications/devices", notificationHandler.RegisterDevice, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.DELETE("/notifications/devices/:id", notificationHandler.UnregisterDevice, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	api.GET("/notifications/webpush-key", notificationHandler.WebPushKey, MetricsMiddleware(m))
+}
+
+// MapDeveloperRoutes maps the management of developer apps, their token endpoint and the public API the apps call.
+// The public API serves the read routes of posts, profiles and search to apps, each behind the scope it needs
+// and the rate limit of the app; pages use the cursors of the routes they share the handlers with.
+func MapDeveloperRoutes(
+	api *API,
+	developerHandler *developerHandler.DeveloperHandler,
+	postHandler *postHandler.PostHandler,
+	commentHandler *commentHandler.CommentHandler,
+	searchHandler *searchHandler.SearchHandler,
+	profileHandler *profileHandler.ProfileHandler,
+	followHandler *followHandler.FollowHandler,
+	authUsecase AuthUsecase,
+	apps AppAuthenticator,
+	limiter RateLimiter,
+	logger *slog.Logger,
+	m *metrics.Metrics,
+) {
+	developer := api.Group("/developer", AuthMiddleware(authUsecase), MetricsMiddleware(m))
+	developer.POST("/apps", developerHandler.CreateApp)
+	developer.GET("/apps", developerHandler.ListApps)
+	developer.POST("/apps/:id/secret", developerHandler.RotateSecret)
+	developer.DELETE("/apps/:id", developerHandler.DeleteApp)
+	developer.GET("/apps/:id/usage", developerHandler.GetUsage)
+	api.POST("/oauth/token", developerHandler.Token, MetricsMiddleware(m))
+
+	posts := AppTokenMiddleware(apps, limiter, entity.AppScopePostsRead, logger)
+	users := AppTokenMiddleware(apps, limiter, entity.AppScopeUsersRead, logger)
+	public := api.Group("/public", MetricsMiddleware(m))
+	public.GET("/posts/:id", postHandler.GetPost, posts)
+	public.GET("/posts/:id/comments", commentHandler.ListComments, posts)
+	public.GET("/explore", postHandler.GetExplore, posts)
+	public.GET("/hashtags/trending", postHandler.TrendingHashtags, posts)
+	public.GET("/hashtags/:tag/posts", postHandler.GetHashtagPosts, posts)
+	public.GET("/search/posts", searchHandler.SearchPosts, posts)
+	public.GET("/users/:id/profile", profileHandler.GetProfile, users)
+	public.GET("/users/:id/followers", followHandler.ListFollowers, users)
+	public.GET("/users/:id/following", followHandler.ListFollowing, users)
+	public.GET("/search/users", searchHandler.SearchUsers, users)
+}
+
+// MapFederationRoutes maps WebFinger and the ActivityPub documents and inboxes. They are unversioned, their URLs
+// are the IDs of the documents on the servers that federate with this one.
+func MapFederationRoutes(e *echo.Echo, federationHandler *federationHandler.FederationHandler, m *metrics.Metrics) {
+	e.GET("/.well-known/webfinger", federationHandler.WebFinger, MetricsMiddleware(m))
+
+	ap := e.Group("/ap", MetricsMiddleware(m))
+	ap.POST("/inbox", federationHandler.Inbox)
+	ap.GET("/users/:id", federationHandler.Actor)
+	ap.POST("/users/:id/inbox", federationHandler.Inbox)
+	ap.GET("/users/:id/outbox", federationHandler.Outbox)
+	ap.GET("/users/:id/followers", federationHandler.Followers)
+	ap.GET("/users/:id/following", federationHandler.Following)
+	ap.GET("/posts/:id", federationHandler.Note)
+}
+
+// MapSEORoutes maps the link previews of public posts and the sitemaps. They are unversioned, shared links and
+// search engines keep them.
+func MapSEORoutes(e *echo.Echo, seoHandler *seoHandler.SEOHandler, m *metrics.Metrics) {
+	e.GET("/p/:id", seoHandler.PostPreview, MetricsMiddleware(m))
+	e.GET("/sitemap.xml", seoHandler.SitemapIndex, MetricsMiddleware(m))
+	e.GET("/sitemaps/:name", seoHandler.Sitemap, MetricsMiddleware(m))
+}
+
+// MapLinkRoutes maps the redirects of the shortened links of posts, unversioned as they are part of post texts,
+// and the click counts of the links of a post for its author.
+func MapLinkRoutes(e *echo.Echo, api *API, linkHandler *linkHandler.LinkHandler, authUsecase AuthUsecase, m *metrics.Metrics) {
+	e.GET("/l/:code", linkHandler.Redirect, MetricsMiddleware(m))
+	api.GET("/posts/:id/links", linkHandler.PostLinks, AuthMiddleware(authUsecase), MetricsMiddleware(m))
+}
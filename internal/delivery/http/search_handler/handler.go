@@ -0,0 +1,91 @@
+package searchHandler
+
+import (
+	"context"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type SearchHandler struct {
+	SearchUsecase SearchUsecase
+	Metrics       *metrics.Metrics
+}
+
+type SearchUsecase interface {
+
+	//SearchPosts returns a page of posts matching the query, most relevant first, and the cursor of the next page.
+	SearchPosts(ctx context.Context, viewerID uuid.UUID, query, cursor string, limit int) (posts []entity.RankedPost, nextCursor string, err error)
+
+	//SearchUsers returns users matching the query by username prefix or display name, best matches first.
+	SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit int) ([]entity.UserCard, error)
+}
+
+func NewSearchHandler(searchUsecase SearchUsecase, metrics *metrics.Metrics) *SearchHandler {
+	return &SearchHandler{
+		SearchUsecase: searchUsecase,
+		Metrics:       metrics,
+	}
+}
+
+// DTOs
+type SearchRequest struct {
+	Query string `query:"q"`
+	pagination.Request
+}
+
+type SearchPostsResponse struct {
+	Posts []entity.RankedPost `json:"posts"`
+	pagination.Response
+}
+
+type SearchUsersResponse struct {
+	Users []entity.UserCard `json:"users"`
+}
+
+// SearchPosts returns a page of posts matching the q parameter, the caller is optional.
+func (h *SearchHandler) SearchPosts(c echo.Context) error {
+	var req SearchRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	posts, nextCursor, err := h.SearchUsecase.SearchPosts(c.Request().Context(), viewerID, req.Query, req.Cursor, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to search posts: %w", err)
+	}
+	if posts == nil {
+		posts = []entity.RankedPost{}
+	}
+	return c.JSON(http.StatusOK, SearchPostsResponse{
+		Posts:    posts,
+		Response: pagination.Response{NextCursor: nextCursor},
+	})
+}
+
+// SearchUsers returns users matching the q parameter, the follow state is relative to the caller if there is one.
+func (h *SearchHandler) SearchUsers(c echo.Context) error {
+	var req SearchRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	viewerID, _ := ctxUtil.UserIDFromContext(c.Request().Context())
+	users, err := h.SearchUsecase.SearchUsers(c.Request().Context(), viewerID, req.Query, req.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to search users: %w", err)
+	}
+	if users == nil {
+		users = []entity.UserCard{}
+	}
+	return c.JSON(http.StatusOK, SearchUsersResponse{
+		Users: users,
+	})
+}
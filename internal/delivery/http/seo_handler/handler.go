@@ -0,0 +1,100 @@
+package seoHandler
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed preview.html
+var previewHTML string
+
+var previewTemplate = template.Must(template.New("preview").Parse(previewHTML))
+
+type SEOHandler struct {
+	SEOUsecase SEOUsecase
+	Metrics    *metrics.Metrics
+}
+
+type SEOUsecase interface {
+
+	//PostPreview returns the link preview of a public post.
+	PostPreview(ctx context.Context, postID uuid.UUID) (entity.PostPreview, error)
+
+	//SitemapIndex returns the sitemap index listing the generated sitemaps.
+	SitemapIndex(ctx context.Context) ([]byte, error)
+
+	//Sitemap returns a generated sitemap.
+	Sitemap(ctx context.Context, index int) ([]byte, error)
+}
+
+func NewSEOHandler(seoUsecase SEOUsecase, metrics *metrics.Metrics) *SEOHandler {
+	return &SEOHandler{
+		SEOUsecase: seoUsecase,
+		Metrics:    metrics,
+	}
+}
+
+// PostPreview renders the page shared links of a public post point at: the Open Graph and Twitter Card metadata
+// link previews are made of, for crawlers, and a redirect to the post in the web app, for people.
+func (h *SEOHandler) PostPreview(c echo.Context) error {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+
+	preview, err := h.SEOUsecase.PostPreview(c.Request().Context(), postID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get post preview: %w", err)
+	}
+
+	var page bytes.Buffer
+	if err := previewTemplate.Execute(&page, preview); err != nil {
+		return fmt.Errorf("failed to render post preview: %w", err)
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.HTMLBlob(http.StatusOK, page.Bytes())
+}
+
+// SitemapIndex serves the sitemap index, the entry point search engines are pointed at.
+func (h *SEOHandler) SitemapIndex(c echo.Context) error {
+	index, err := h.SEOUsecase.SitemapIndex(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("failed to get sitemap index: %w", err)
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+	return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, index)
+}
+
+// Sitemap serves a sitemap listed by the index, the path is its number with the .xml extension.
+func (h *SEOHandler) Sitemap(c echo.Context) error {
+	index, err := strconv.Atoi(strings.TrimSuffix(c.Param("name"), ".xml"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "sitemap not found")
+	}
+
+	sitemap, err := h.SEOUsecase.Sitemap(c.Request().Context(), index)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "sitemap not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get sitemap: %w", err)
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+	return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, sitemap)
+}
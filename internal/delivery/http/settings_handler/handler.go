@@ -0,0 +1,107 @@
+package settingsHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type SettingsHandler struct {
+	SettingsUsecase SettingsUsecase
+	Metrics         *metrics.Metrics
+}
+
+type SettingsUsecase interface {
+
+	//GetSettings returns the settings of the user.
+	GetSettings(ctx context.Context, userID uuid.UUID) (entity.UserSettings, error)
+
+	//UpdateSettings changes the non-nil settings of the user and returns the updated settings.
+	UpdateSettings(ctx context.Context, userID uuid.UUID, update entity.SettingsUpdate) (entity.UserSettings, error)
+}
+
+func NewSettingsHandler(settingsUsecase SettingsUsecase, metrics *metrics.Metrics) *SettingsHandler {
+	return &SettingsHandler{
+		SettingsUsecase: settingsUsecase,
+		Metrics:         metrics,
+	}
+}
+
+// DTOs
+// UpdateSettingsRequest changes only the settings present in the body.
+type UpdateSettingsRequest struct {
+	// Version is the version of the settings the change was made on, the update fails with 409 if they changed since
+	Version        int64                       `json:"version" validate:"required"`
+	PrivacyLevel   *entity.PrivacyLevel        `json:"privacy_level" validate:"omitempty,oneof=everyone followers nobody"`
+	PrivateAccount *bool                       `json:"private_account"`
+	Notifications  *NotificationSettingsUpdate `json:"notifications"`
+}
+
+type NotificationSettingsUpdate struct {
+	Likes    *bool `json:"likes"`
+	Comments *bool `json:"comments"`
+	Follows  *bool `json:"follows"`
+	Mentions *bool `json:"mentions"`
+	Messages *bool `json:"messages"`
+	// EmailDigest is off, daily or weekly
+	EmailDigest *entity.DigestFrequency `json:"email_digest" validate:"omitempty,oneof=off daily weekly"`
+}
+
+// GetSettings returns the settings of the authenticated user.
+func (h *SettingsHandler) GetSettings(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	settings, err := h.SettingsUsecase.GetSettings(c.Request().Context(), userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "settings not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings changes the settings of the authenticated user.
+func (h *SettingsHandler) UpdateSettings(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+	var req UpdateSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	update := entity.SettingsUpdate{
+		Version:        req.Version,
+		PrivacyLevel:   req.PrivacyLevel,
+		PrivateAccount: req.PrivateAccount,
+	}
+	if n := req.Notifications; n != nil {
+		update.NotifyLikes = n.Likes
+		update.NotifyComments = n.Comments
+		update.NotifyFollows = n.Follows
+		update.NotifyMentions = n.Mentions
+		update.NotifyMessages = n.Messages
+		update.EmailDigest = n.EmailDigest
+	}
+	settings, err := h.SettingsUsecase.UpdateSettings(c.Request().Context(), userID, update)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "settings not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+	return c.JSON(http.StatusOK, settings)
+}
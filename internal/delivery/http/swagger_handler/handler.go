@@ -0,0 +1,52 @@
+package swaggerHandler
+
+import (
+	"main/internal/delivery/openapi"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SwaggerHandler struct {
+	build func() openapi.Document
+
+	once sync.Once
+	doc  openapi.Document
+}
+
+// NewSwaggerHandler serves the document returned by build. It's built on the first request, when all routes
+// are registered.
+func NewSwaggerHandler(build func() openapi.Document) *SwaggerHandler {
+	return &SwaggerHandler{
+		build: build,
+	}
+}
+
+// Spec returns the OpenAPI document.
+func (h *SwaggerHandler) Spec(c echo.Context) error {
+	h.once.Do(func() { h.doc = h.build() })
+	return c.JSON(http.StatusOK, h.doc)
+}
+
+// UI serves Swagger UI for the document, the UI itself is loaded from a CDN.
+func (h *SwaggerHandler) UI(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUI)
+}
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Threads API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/swagger/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,255 @@
+package http
+
+import (
+	"fmt"
+	"main/pkg/apperror"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIVersion is a major version of the HTTP API, served under /api/v<N>.
+type APIVersion int
+
+// V1 is the first version, the unversioned routes are served as it by default.
+const V1 APIVersion = 1
+
+// APIVersionHeader selects the version an unversioned route is served in, and tells the version of every response.
+const APIVersionHeader = "API-Version"
+
+func (v APIVersion) prefix() string {
+	return "/api/v" + strconv.Itoa(int(v))
+}
+
+// Deprecation announces the removal of routes with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers.
+type Deprecation struct {
+	// Since is when the routes were deprecated, it may be in the future; the Deprecation header is only sent with it
+	Since time.Time
+	// Sunset is when the routes stop being served, none is announced when zero
+	Sunset time.Time
+	// Link documents the migration, sent as a deprecation link when not empty
+	Link string
+}
+
+// APIOptions are the versions an API serves.
+type APIOptions struct {
+	// Versions are the served versions
+	Versions []APIVersion
+	// Deprecated versions announce their removal on every response
+	Deprecated map[APIVersion]Deprecation
+	// Legacy serves every route at its unversioned path too, in the version of the API-Version header or the
+	// oldest one, with the deprecation when it is not nil
+	Legacy *Deprecation
+}
+
+// Handlers implement a route per version: each one serves its version and the newer ones up to the next
+// implementation, a nil handler removes the route from its version on.
+type Handlers map[APIVersion]echo.HandlerFunc
+
+// API maps routes into every served version of the API. Versions share the handlers that didn't change, so a new
+// version only lists the routes it changes or removes.
+type API struct {
+	e          *echo.Echo
+	opts       APIOptions
+	prefix     string
+	middleware []echo.MiddlewareFunc
+	// legacy holds the "<method> <path>" of the unversioned routes
+	legacy map[string]struct{}
+}
+
+// NewAPI returns the API of the versions of opts mapped on e.
+func NewAPI(e *echo.Echo, opts APIOptions) *API {
+	opts.Versions = slices.Clone(opts.Versions)
+	slices.Sort(opts.Versions)
+	return &API{e: e, opts: opts, legacy: make(map[string]struct{})}
+}
+
+// Group returns the API under the prefix, running the middlewares before the ones of its routes.
+func (a *API) Group(prefix string, m ...echo.MiddlewareFunc) *API {
+	group := *a
+	group.prefix = a.prefix + prefix
+	group.middleware = append(slices.Clip(a.middleware), m...)
+	return &group
+}
+
+func (a *API) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	a.Add(http.MethodGet, path, a.all(h), m...)
+}
+
+func (a *API) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	a.Add(http.MethodPost, path, a.all(h), m...)
+}
+
+func (a *API) PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	a.Add(http.MethodPatch, path, a.all(h), m...)
+}
+
+func (a *API) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	a.Add(http.MethodDelete, path, a.all(h), m...)
+}
+
+// all implements a route with the same handler in every version.
+func (a *API) all(h echo.HandlerFunc) Handlers {
+	if len(a.opts.Versions) == 0 {
+		return nil
+	}
+	return Handlers{a.opts.Versions[0]: h}
+}
+
+// Add maps a route into every version one of the handlers serves.
+func (a *API) Add(method, path string, handlers Handlers, m ...echo.MiddlewareFunc) {
+	path = a.prefix + path
+	m = append(slices.Clip(a.middleware), m...)
+	served := make(map[APIVersion]echo.HandlerFunc)
+	for _, v := range a.opts.Versions {
+		h := handlerFor(handlers, v)
+		if h == nil {
+			continue
+		}
+		served[v] = h
+		a.e.Add(method, v.prefix()+path, h, append([]echo.MiddlewareFunc{a.versionMiddleware(v)}, m...)...)
+	}
+	if a.opts.Legacy == nil || len(served) == 0 {
+		return
+	}
+	a.legacy[method+" "+path] = struct{}{}
+	a.e.Add(method, path, func(c echo.Context) error {
+		return served[c.Get(apiVersionKey).(APIVersion)](c)
+	}, append([]echo.MiddlewareFunc{a.negotiate(served)}, m...)...)
+}
+
+// Legacy maps a route served at its unversioned path only, deprecated in favour of the successor route of the
+// newest version, whose path parameters are the ones of the request. It isn't mapped when the unversioned routes
+// aren't served.
+func (a *API) Legacy(method, path, successor string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	if a.opts.Legacy == nil || len(a.opts.Versions) == 0 {
+		return
+	}
+	path = a.prefix + path
+	a.legacy[method+" "+path] = struct{}{}
+	successor = a.opts.Versions[len(a.opts.Versions)-1].prefix() + a.prefix + successor
+	a.e.Add(method, path, h, append([]echo.MiddlewareFunc{
+		a.versionMiddleware(a.opts.Versions[0]),
+		DeprecatedMiddleware(*a.opts.Legacy, func(c echo.Context) string { return withParams(successor, c) }),
+	}, append(slices.Clip(a.middleware), m...)...)...)
+}
+
+// IsLegacy reports whether the route is one of the unversioned routes.
+func (a *API) IsLegacy(r *echo.Route) bool {
+	_, ok := a.legacy[r.Method+" "+r.Path]
+	return ok
+}
+
+// withParams replaces the :name parameters of the route path with the ones of the request.
+func withParams(path string, c echo.Context) string {
+	for _, name := range c.ParamNames() {
+		path = strings.Replace(path, ":"+name, c.Param(name), 1)
+	}
+	return path
+}
+
+// handlerFor returns the implementation of the newest version not newer than v.
+func handlerFor(handlers Handlers, v APIVersion) echo.HandlerFunc {
+	var h echo.HandlerFunc
+	newest := APIVersion(0)
+	for version, handler := range handlers {
+		if version <= v && version > newest {
+			h, newest = handler, version
+		}
+	}
+	return h
+}
+
+const apiVersionKey = "api_version"
+
+// versionMiddleware tells the version of the response and announces the removal of a deprecated version.
+func (a *API) versionMiddleware(v APIVersion) echo.MiddlewareFunc {
+	deprecation, deprecated := a.opts.Deprecated[v]
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(apiVersionKey, v)
+			c.Response().Header().Set(APIVersionHeader, strconv.Itoa(int(v)))
+			if deprecated {
+				setDeprecationHeaders(c, deprecation, "")
+			}
+			return next(c)
+		}
+	}
+}
+
+// negotiate picks the version of an unversioned route from the API-Version header, the oldest version serving
+// the route by default, and marks the response deprecated in favour of the versioned path.
+func (a *API) negotiate(served map[APIVersion]echo.HandlerFunc) echo.MiddlewareFunc {
+	oldest := APIVersion(0)
+	middlewares := make(map[APIVersion]echo.MiddlewareFunc, len(served))
+	for v := range served {
+		if oldest == 0 || v < oldest {
+			oldest = v
+		}
+		middlewares[v] = a.versionMiddleware(v)
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			v := oldest
+			if header := c.Request().Header.Get(APIVersionHeader); header != "" {
+				n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(header), "v"))
+				if err != nil || served[APIVersion(n)] == nil {
+					return apperror.InvalidArgument("unsupported_api_version",
+						fmt.Sprintf("API version %q is not served by this route", header))
+				}
+				v = APIVersion(n)
+			}
+			setDeprecationHeaders(c, *a.opts.Legacy, v.prefix()+c.Request().URL.Path)
+			return middlewares[v](next)(c)
+		}
+	}
+}
+
+// DeprecatedMiddleware announces the removal of a route, e.g. one retired in favour of another. successor returns
+// the path of the route replacing it, none is linked when it is nil or returns an empty path.
+func DeprecatedMiddleware(d Deprecation, successor func(c echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := ""
+			if successor != nil {
+				path = successor(c)
+			}
+			setDeprecationHeaders(c, d, path)
+			return next(c)
+		}
+	}
+}
+
+func setDeprecationHeaders(c echo.Context, d Deprecation, successor string) {
+	header := c.Response().Header()
+	if !d.Since.IsZero() {
+		header.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	}
+	if !d.Sunset.IsZero() {
+		header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		header.Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", d.Link))
+	}
+	if successor != "" {
+		header.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+	}
+}
+
+// routePath returns the path of the route without its version prefix, so rules keyed by route apply to every
+// version of it.
+func routePath(path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/v")
+	if !ok {
+		return path
+	}
+	i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+	if i <= 0 || rest[i] != '/' {
+		return path
+	}
+	return rest[i:]
+}
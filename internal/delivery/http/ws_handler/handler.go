@@ -0,0 +1,147 @@
+package wsHandler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/metrics"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// readTimeout closes connections the client sent nothing on for this long, idle clients send pings to stay connected.
+	readTimeout = 90 * time.Second
+	// writeTimeout closes connections an event couldn't be written to for this long.
+	writeTimeout = 10 * time.Second
+)
+
+type WSHandler struct {
+	ChatUsecase ChatUsecase
+	logger      *slog.Logger
+	Metrics     *metrics.Metrics
+}
+
+type ChatUsecase interface {
+
+	//WatchChats returns the events of all chats of the user until cancel is called.
+	WatchChats(ctx context.Context, userID uuid.UUID) (events <-chan entity.ChatEvent, cancel func())
+
+	//SendTyping tells the other members of one of the user's chats that the user is typing.
+	SendTyping(ctx context.Context, userID, chatID uuid.UUID) error
+
+	//MarkRead marks one of the user's chats read up to the message and tells the members about it.
+	MarkRead(ctx context.Context, userID, chatID, messageID uuid.UUID) error
+}
+
+func NewWSHandler(chatUsecase ChatUsecase, logger *slog.Logger, metrics *metrics.Metrics) *WSHandler {
+	return &WSHandler{
+		ChatUsecase: chatUsecase,
+		logger:      logger,
+		Metrics:     metrics,
+	}
+}
+
+// DTOs
+
+// ClientEvent is a frame sent by the client: "typing" and "read" are forwarded to the members of the chat,
+// "ping" only keeps an idle connection open.
+type ClientEvent struct {
+	Type      string `json:"type"`
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+}
+
+// Serve upgrades the request of the authenticated user to a WebSocket and pushes the events of all their chats
+// as JSON frames until either side closes the connection.
+func (h *WSHandler) Serve(c echo.Context) error {
+	userID, ok := ctxUtil.UserIDFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	// the origin isn't checked: the connection is authenticated by the access token, which other sites can't read
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			h.serveConn(c.Request().Context(), ws, userID)
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+func (h *WSHandler) serveConn(ctx context.Context, ws *websocket.Conn, userID uuid.UUID) {
+	// the server timeouts are meant for plain requests, the connection sets its own deadlines
+	_ = ws.SetDeadline(time.Time{})
+
+	events, cancel := h.ChatUsecase.WatchChats(ctx, userID)
+	defer cancel()
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	// the reader stops the connection once the client goes away or stays silent for too long
+	go func() {
+		defer stop()
+		for {
+			_ = ws.SetReadDeadline(time.Now().Add(readTimeout))
+			var frame []byte
+			if err := websocket.Message.Receive(ws, &frame); err != nil {
+				return
+			}
+			var event ClientEvent
+			if err := json.Unmarshal(frame, &event); err != nil {
+				h.logger.Debug("Malformed WebSocket frame", "user_id", userID, "error", err)
+				continue
+			}
+			h.handleClientEvent(ctx, userID, event)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			_ = ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleClientEvent forwards a typing indicator or a read receipt, invalid events are dropped.
+func (h *WSHandler) handleClientEvent(ctx context.Context, userID uuid.UUID, event ClientEvent) {
+	if event.Type == "ping" {
+		return
+	}
+	chatID, err := uuid.Parse(event.ChatID)
+	if err != nil {
+		h.logger.Debug("WebSocket event with invalid chat ID", "user_id", userID, "type", event.Type)
+		return
+	}
+
+	switch entity.ChatEventType(event.Type) {
+	case entity.ChatEventTyping:
+		err = h.ChatUsecase.SendTyping(ctx, userID, chatID)
+	case entity.ChatEventRead:
+		messageID, parseErr := uuid.Parse(event.MessageID)
+		if parseErr != nil {
+			h.logger.Debug("WebSocket read receipt with invalid message ID", "user_id", userID)
+			return
+		}
+		err = h.ChatUsecase.MarkRead(ctx, userID, chatID, messageID)
+	default:
+		h.logger.Debug("Unknown WebSocket event", "user_id", userID, "type", event.Type)
+		return
+	}
+	if err != nil {
+		h.logger.Debug("Failed to forward WebSocket event", "user_id", userID, "type", event.Type, "error", err)
+	}
+}
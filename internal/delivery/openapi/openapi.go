@@ -0,0 +1,278 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document. Methods of the gRPC gateway get their request
+// and response schemas from the proto descriptors; the Echo routes are listed with their path parameters.
+package openapi
+
+import (
+	"regexp"
+	"strings"
+
+	"main/internal/delivery/gateway"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   map[string]any `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema map[string]any `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]map[string]any `json:"schemas"`
+	SecuritySchemes map[string]map[string]any `json:"securitySchemes"`
+}
+
+// Build describes the gateway methods and the Echo routes. Routes under skipPrefixes, e.g. the gateway itself
+// and the documentation, are left out.
+func Build(info Info, methods []gateway.Method, routes []*echo.Route, skipPrefixes ...string) Document {
+	doc := Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]map[string]Operation),
+		Components: Components{
+			Schemas: make(map[string]map[string]any),
+			SecuritySchemes: map[string]map[string]any{
+				"bearerAuth": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     {"type": "apiKey", "in": "header", "name": "X-Api-Key"},
+			},
+		},
+		// authentication is optional on the document level, public endpoints don't need it
+		Security: []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}, {}},
+	}
+	schemas := &schemaSet{schemas: doc.Components.Schemas}
+
+	for _, m := range methods {
+		name := strings.TrimPrefix(m.Path, "/")
+		service := name[strings.Index(name, "/")+1 : strings.LastIndex(name, "/")]
+		doc.Paths[m.Path] = map[string]Operation{"post": {
+			OperationID: strings.NewReplacer("/", "_", ".", "_").Replace(name),
+			Tags:        []string{service},
+			RequestBody: &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: schemas.ref(m.Input)}},
+			},
+			Responses: map[string]Response{
+				"200": {Description: "OK", Content: map[string]MediaType{"application/json": {Schema: schemas.ref(m.Output)}}},
+				"default": {Description: "gRPC status of the failed call", Content: map[string]MediaType{
+					"application/json": {Schema: map[string]any{"$ref": "#/components/schemas/google.rpc.Status"}},
+				}},
+			},
+		}}
+	}
+	if len(methods) > 0 {
+		doc.Components.Schemas["google.rpc.Status"] = map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code":    map[string]any{"type": "integer", "format": "int32"},
+				"message": map[string]any{"type": "string"},
+				"details": map[string]any{"type": "array", "items": map[string]any{"type": "object"}},
+			},
+		}
+	}
+
+route:
+	for _, r := range routes {
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(r.Path, prefix) {
+				continue route
+			}
+		}
+		method := strings.ToLower(r.Method)
+		switch method {
+		case "get", "post", "put", "patch", "delete", "head", "options":
+		default:
+			continue
+		}
+		path, params := convertPath(r.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]Operation)
+		}
+		op := Operation{
+			OperationID: operationID(r.Name, method, path),
+			Tags:        []string{tag(r.Path)},
+			Parameters:  params,
+			Responses:   map[string]Response{"default": {Description: "JSON response of the handler"}},
+		}
+		if method == "post" || method == "put" || method == "patch" {
+			op.RequestBody = &RequestBody{Content: map[string]MediaType{"application/json": {Schema: map[string]any{"type": "object"}}}}
+		}
+		doc.Paths[path][method] = op
+	}
+	return doc
+}
+
+var pathParam = regexp.MustCompile(`:([A-Za-z0-9_]+)|\*`)
+
+// convertPath turns an Echo path like /posts/:id into the OpenAPI form /posts/{id} and lists its parameters.
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	converted := pathParam.ReplaceAllStringFunc(path, func(s string) string {
+		name := strings.TrimPrefix(s, ":")
+		if s == "*" {
+			name = "path"
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: map[string]any{"type": "string"}})
+		return "{" + name + "}"
+	})
+	return converted, params
+}
+
+// operationID derives an operation ID from the handler name Echo recorded, e.g.
+// "main/internal/delivery/http/auth_handler.(*AuthHandler).Login-fm" becomes "AuthHandler_Login".
+func operationID(handler, method, path string) string {
+	name := handler[strings.LastIndex(handler, "/")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.Index(name, ".("); i >= 0 {
+		name = name[i+2:]
+		name = strings.NewReplacer("*", "", ")", "", ".", "_").Replace(name)
+		return name
+	}
+	return method + strings.NewReplacer("/", "_", "{", "", "}", "", ".", "_", "-", "_").Replace(path)
+}
+
+// tag groups the routes by their first path segment.
+func tag(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment == "" {
+		return "root"
+	}
+	return segment
+}
+
+// schemaSet collects the schemas of the messages reachable from the gateway methods.
+type schemaSet struct {
+	schemas map[string]map[string]any
+}
+
+// ref returns a reference to the schema of the message and adds it, and the messages it contains, to the set.
+func (s *schemaSet) ref(md protoreflect.MessageDescriptor) map[string]any {
+	if schema, ok := wellKnown(md.FullName()); ok {
+		return schema
+	}
+	name := string(md.FullName())
+	if _, ok := s.schemas[name]; !ok {
+		// placeholder first, messages may reference themselves
+		s.schemas[name] = map[string]any{}
+		s.schemas[name] = s.message(md)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (s *schemaSet) message(md protoreflect.MessageDescriptor) map[string]any {
+	properties := make(map[string]any, md.Fields().Len())
+	for i := 0; i < md.Fields().Len(); i++ {
+		f := md.Fields().Get(i)
+		properties[string(f.Name())] = s.field(f)
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func (s *schemaSet) field(f protoreflect.FieldDescriptor) map[string]any {
+	if f.IsMap() {
+		return map[string]any{"type": "object", "additionalProperties": s.value(f.MapValue())}
+	}
+	if f.IsList() {
+		return map[string]any{"type": "array", "items": s.value(f)}
+	}
+	return s.value(f)
+}
+
+// value is the schema of a single value of the field, following the protojson mapping.
+func (s *schemaSet) value(f protoreflect.FieldDescriptor) map[string]any {
+	switch f.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64-bit integers are strings in JSON, JavaScript numbers can't hold them
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := f.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return s.ref(f.Message())
+	}
+	return map[string]any{}
+}
+
+// wellKnown returns the schemas of the well-known types, which have their own JSON form.
+func wellKnown(name protoreflect.FullName) (map[string]any, bool) {
+	switch name {
+	case "google.protobuf.Timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}, true
+	case "google.protobuf.Duration":
+		return map[string]any{"type": "string", "example": "1.5s"}, true
+	case "google.protobuf.Struct":
+		return map[string]any{"type": "object", "additionalProperties": true}, true
+	case "google.protobuf.Value":
+		return map[string]any{}, true
+	case "google.protobuf.ListValue":
+		return map[string]any{"type": "array", "items": map[string]any{}}, true
+	case "google.protobuf.Empty":
+		return map[string]any{"type": "object"}, true
+	case "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return map[string]any{"type": "string"}, true
+	case "google.protobuf.BoolValue":
+		return map[string]any{"type": "boolean"}, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return map[string]any{"type": "integer"}, true
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return map[string]any{"type": "string", "format": "int64"}, true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return map[string]any{"type": "number"}, true
+	}
+	return nil, false
+}
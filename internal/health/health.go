@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check reports whether a dependency the instance needs to serve requests is usable.
+type Check func(ctx context.Context) error
+
+// Report is the result of the readiness checks, Checks holds "ok" or the error of every check.
+type Report struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Checker runs the readiness checks of the instance concurrently, each limited to the timeout.
+type Checker struct {
+	timeout time.Duration
+	names   []string
+	checks  []Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check under a name. Checks must be added before the checker is used.
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks = append(c.checks, check)
+}
+
+// Run runs every check, the instance is ready if all of them pass.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]error, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Checks: make(map[string]string, len(c.checks))}
+	for i, err := range results {
+		if err != nil {
+			report.Ready = false
+			report.Checks[c.names[i]] = err.Error()
+			continue
+		}
+		report.Checks[c.names[i]] = "ok"
+	}
+	return report
+}
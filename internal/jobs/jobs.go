@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"main/pkg/errreport"
+	"runtime/debug"
+	"time"
+)
+
+// RunPeriodically calls fn every interval until ctx is cancelled.
+// Failed runs are logged, reported and retried on the next tick, they never stop the loop, not even by panicking.
+func RunPeriodically(ctx context.Context, logger *slog.Logger, reporter errreport.Reporter, name string, interval time.Duration, fn func(ctx context.Context) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			runOnce(ctx, logger, reporter, name, fn)
+		}
+	}
+}
+
+func runOnce(ctx context.Context, logger *slog.Logger, reporter errreport.Reporter, name string, fn func(ctx context.Context) error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("PANIC RECOVERED",
+				"job", name,
+				"panic", r,
+				"stack", string(debug.Stack()),
+			)
+			reporter.Report(ctx, fmt.Errorf("panic: %v", r), map[string]string{"job": name})
+		}
+	}()
+
+	start := time.Now()
+	if err := fn(ctx); err != nil {
+		logger.Error("Background job failed", "job", name, "error", err)
+		// a run cancelled by the shutdown didn't fail
+		if ctx.Err() == nil {
+			reporter.Report(ctx, err, map[string]string{"job": name})
+		}
+		return
+	}
+	logger.Debug("Background job finished", "job", name, "duration", time.Since(start))
+}
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"main/pkg/errreport"
+	"sync"
+	"time"
+)
+
+// Runner runs a job if it is due, across all instances.
+type Runner interface {
+	// RunIfDue runs fn if the job didn't start within interval on any instance and reports whether it ran.
+	RunIfDue(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) (bool, error)
+}
+
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// Scheduler runs jobs that must run on one instance at a time, once per interval however many instances there
+// are. Every instance checks each job every poll interval, the first one finding it due runs it.
+type Scheduler struct {
+	logger   *slog.Logger
+	reporter errreport.Reporter
+	runner   Runner
+	poll     time.Duration
+	jobs     []scheduledJob
+}
+
+func NewScheduler(logger *slog.Logger, reporter errreport.Reporter, runner Runner, poll time.Duration) *Scheduler {
+	return &Scheduler{
+		logger:   logger,
+		reporter: reporter,
+		runner:   runner,
+		poll:     poll,
+	}
+}
+
+// Every registers a job running every interval, it must be called before Run.
+func (s *Scheduler) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, fn: fn})
+}
+
+// Run checks the jobs until ctx is cancelled, failed runs are logged and retried on the next check.
+func (s *Scheduler) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = RunPeriodically(ctx, s.logger, s.reporter, job.name, min(s.poll, job.interval), func(ctx context.Context) error {
+				_, err := s.runner.RunIfDue(ctx, job.name, job.interval, job.fn)
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// SMTPMailer sends plain text emails through an SMTP server.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send delivers a plain text email to a single recipient.
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	var msg strings.Builder
+	msg.WriteString("From: " + m.from + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + subject + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	msg.WriteString(body)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg.String()))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// LogMailer only logs emails, used in local development when no SMTP server is configured.
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+func NewLogMailer(logger *slog.Logger) *LogMailer {
+	return &LogMailer{
+		logger: logger,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.logger.Info("Email sent", "to", to, "subject", subject, "body", body)
+	return nil
+}
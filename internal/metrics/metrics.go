@@ -18,6 +18,14 @@ type Metrics struct {
 	DbQueryDuration *prometheus.HistogramVec
 	//CPU temperature gauge with core label
 	CpuTemp *prometheus.GaugeVec
+	//Database statement retries counter with pool label
+	DbRetries *prometheus.CounterVec
+	//Database circuit breaker state gauge with pool label: 0 closed, 1 half-open, 2 open
+	DbBreakerState *prometheus.GaugeVec
+	//Database calls rejected by an open circuit breaker counter with pool label
+	DbBreakerRejections *prometheus.CounterVec
+	//Public API requests of developer apps counter with status label: ok or throttled
+	AppRequests *prometheus.CounterVec
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -58,6 +66,34 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		},
 			[]string{"core"},
 		),
+		//Database statement retries counter with pool label
+		DbRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_retries_total",
+			Help: "Number of database statements retried after a transient error.",
+		},
+			[]string{"pool"},
+		),
+		//Database circuit breaker state gauge with pool label
+		DbBreakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "db_circuit_breaker_state",
+			Help: "State of the database circuit breaker: 0 closed, 1 half-open, 2 open.",
+		},
+			[]string{"pool"},
+		),
+		//Database calls rejected by an open circuit breaker counter with pool label
+		DbBreakerRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_circuit_breaker_rejections_total",
+			Help: "Number of database calls failed fast by an open circuit breaker.",
+		},
+			[]string{"pool"},
+		),
+		//Public API requests of developer apps counter with status label
+		AppRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "app_requests_total",
+			Help: "Number of public API requests of developer apps, by whether their rate limit let them through.",
+		},
+			[]string{"status"},
+		),
 	}
 	// Register metrics with the provided registry
 	reg.MustRegister(m.RequestDuration)
@@ -65,6 +101,10 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 	reg.MustRegister(m.TotalErrors)
 	reg.MustRegister(m.DbQueryDuration)
 	reg.MustRegister(m.CpuTemp)
+	reg.MustRegister(m.DbRetries)
+	reg.MustRegister(m.DbBreakerState)
+	reg.MustRegister(m.DbBreakerRejections)
+	reg.MustRegister(m.AppRequests)
 	return m
 }
 
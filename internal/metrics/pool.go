@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolConnsDesc = prometheus.NewDesc("db_pool_conns", "Connections of the database pool by state.",
+		[]string{"pool", "state"}, nil)
+	poolMaxConnsDesc = prometheus.NewDesc("db_pool_max_conns", "Maximum size of the database pool.",
+		[]string{"pool"}, nil)
+	poolAcquiresDesc = prometheus.NewDesc("db_pool_acquires_total", "Connections acquired from the database pool.",
+		[]string{"pool"}, nil)
+	poolEmptyAcquiresDesc = prometheus.NewDesc("db_pool_empty_acquires_total",
+		"Acquisitions that waited for a connection because none was idle.", []string{"pool"}, nil)
+	poolEmptyAcquireWaitDesc = prometheus.NewDesc("db_pool_empty_acquire_wait_seconds_total",
+		"Time spent waiting for a connection by acquisitions that found none idle.", []string{"pool"}, nil)
+	poolCanceledAcquiresDesc = prometheus.NewDesc("db_pool_canceled_acquires_total",
+		"Acquisitions cancelled by their context while waiting for a connection.", []string{"pool"}, nil)
+)
+
+// PoolCollector exports the statistics of database pools, read when the metrics are scraped.
+type PoolCollector struct {
+	pools map[string]*pgxpool.Pool
+}
+
+// NewPoolCollector returns a collector of the pools, keyed by the value of their "pool" label.
+func NewPoolCollector(pools map[string]*pgxpool.Pool) *PoolCollector {
+	return &PoolCollector{pools: pools}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnsDesc
+	ch <- poolMaxConnsDesc
+	ch <- poolAcquiresDesc
+	ch <- poolEmptyAcquiresDesc
+	ch <- poolEmptyAcquireWaitDesc
+	ch <- poolCanceledAcquiresDesc
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, pool := range c.pools {
+		stat := pool.Stat()
+		ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()), name, "acquired")
+		ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stat.IdleConns()), name, "idle")
+		ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stat.ConstructingConns()), name, "constructing")
+		ch <- prometheus.MustNewConstMetric(poolMaxConnsDesc, prometheus.GaugeValue, float64(stat.MaxConns()), name)
+		ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(stat.AcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(poolEmptyAcquiresDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(poolEmptyAcquireWaitDesc, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(poolCanceledAcquiresDesc, prometheus.CounterValue, float64(stat.CanceledAcquireCount()), name)
+	}
+}
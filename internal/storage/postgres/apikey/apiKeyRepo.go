@@ -0,0 +1,107 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type APIKeyRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewAPIKeyRepo(pool *postgres.DB, metrics *metrics.Metrics) *APIKeyRepo {
+	return &APIKeyRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+const selectKey = `SELECT id, name, prefix, scopes, COALESCE(created_by, '00000000-0000-0000-0000-000000000000'),
+			created_at, expires_at, revoked_at, last_used_at
+		FROM api_keys`
+
+// CreateKey stores a new API key by its hash.
+func (r *APIKeyRepo) CreateKey(ctx context.Context, key entity.APIKey, keyHash []byte) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_api_key", start, err)
+	}(time.Now())
+
+	var createdBy any
+	if key.CreatedBy != uuid.Nil {
+		createdBy = key.CreatedBy
+	}
+	sql := `INSERT INTO api_keys (id, name, key_hash, prefix, scopes, created_by, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = r.pool.Exec(ctx, sql, key.ID, key.Name, keyHash, key.Prefix, key.Scopes, createdBy, key.ExpiresAt)
+	return err
+}
+
+// GetKeyByHash returns the key with the given hash, customerrors.ErrInvalidToken if there is none.
+func (r *APIKeyRepo) GetKeyByHash(ctx context.Context, keyHash []byte) (key entity.APIKey, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_api_key_by_hash", start, err)
+	}(time.Now())
+
+	key, err = scanKey(r.pool.QueryRow(ctx, selectKey+" WHERE key_hash = $1", keyHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrInvalidToken
+	}
+	return key, err
+}
+
+// ListKeys returns all keys, newest first.
+func (r *APIKeyRepo) ListKeys(ctx context.Context) (keys []entity.APIKey, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_api_keys", start, err)
+	}(time.Now())
+
+	rows, err := r.pool.Query(ctx, selectKey+" ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	keys, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.APIKey, error) {
+		return scanKey(row)
+	})
+	return keys, err
+}
+
+// RevokeKey marks the key as revoked, returns customerrors.ErrNoTagsAffected if it doesn't exist or is already revoked.
+func (r *APIKeyRepo) RevokeKey(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("revoke_api_key", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrNoTagsAffected
+	}
+	return nil
+}
+
+// TouchKey records that the key was just used.
+func (r *APIKeyRepo) TouchKey(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("touch_api_key", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "UPDATE api_keys SET last_used_at = NOW() WHERE id = $1", id)
+	return err
+}
+
+func scanKey(row pgx.Row) (entity.APIKey, error) {
+	var k entity.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedBy, &k.CreatedAt, &k.ExpiresAt, &k.RevokedAt, &k.LastUsedAt)
+	return k, err
+}
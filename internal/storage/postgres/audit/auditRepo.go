@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type AuditRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewAuditRepo(pool *postgres.DB, metrics *metrics.Metrics) *AuditRepo {
+	return &AuditRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// InsertEvent appends an event to the audit log.
+func (r *AuditRepo) InsertEvent(ctx context.Context, event entity.AuditEvent) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_audit_event", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO audit_events (event_type, actor_id, subject_id, ip_address, user_agent, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = r.pool.Exec(ctx, sql,
+		event.Type, nullUUID(event.ActorID), nullUUID(event.SubjectID), nullIP(event.ClientIP), event.UserAgent, event.Metadata)
+	return err
+}
+
+// ListEvents returns audit events matching the filter, newest first.
+func (r *AuditRepo) ListEvents(ctx context.Context, filter entity.AuditFilter) (events []entity.AuditEvent, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_audit_events", start, err)
+	}(time.Now())
+
+	var (
+		conds []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if filter.Type != "" {
+		add("event_type = ?", filter.Type)
+	}
+	if filter.ActorID != uuid.Nil {
+		add("actor_id = ?", filter.ActorID)
+	}
+	if filter.SubjectID != uuid.Nil {
+		add("subject_id = ?", filter.SubjectID)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < ?", filter.To)
+	}
+	if filter.BeforeID > 0 {
+		add("id < ?", filter.BeforeID)
+	}
+
+	sql := `SELECT id, event_type, COALESCE(actor_id, '00000000-0000-0000-0000-000000000000'),
+				COALESCE(subject_id, '00000000-0000-0000-0000-000000000000'),
+				COALESCE(host(ip_address), ''), COALESCE(user_agent, ''), metadata, created_at
+			FROM audit_events`
+	if len(conds) > 0 {
+		sql += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, filter.Limit)
+	sql += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	events, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.AuditEvent, error) {
+		var e entity.AuditEvent
+		err := row.Scan(&e.ID, &e.Type, &e.ActorID, &e.SubjectID, &e.ClientIP, &e.UserAgent, &e.Metadata, &e.CreatedAt)
+		return e, err
+	})
+	return events, err
+}
+
+func nullUUID(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id
+}
+
+func nullIP(ip string) any {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	return addr
+}
@@ -2,43 +2,100 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"main/domain/entity"
 	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/internal/storage/postgres/events"
 	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"net/netip"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type AuthRepo struct {
-	pool    *pgxpool.Pool
+	pool    *postgres.DB
 	Metrics *metrics.Metrics
 }
 
-func NewAuthRepo(pool *pgxpool.Pool, metrics *metrics.Metrics) *AuthRepo {
+func NewAuthRepo(pool *postgres.DB, metrics *metrics.Metrics) *AuthRepo {
 	return &AuthRepo{
 		pool:    pool,
 		Metrics: metrics,
 	}
 }
 
-// CreateUser creates a new user in the database with the provided details and returns the user ID.
+// takenError reports a unique violation on the email or username of users as customerrors.ErrEmailTaken or
+// customerrors.ErrUsernameTaken, other errors are returned as is.
+func takenError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "users_email_key":
+		return customerrors.ErrEmailTaken
+	case "users_username_key":
+		return customerrors.ErrUsernameTaken
+	}
+	return err
+}
+
+// db returns the transaction of the context, so the repository takes part in txmanager transactions, or the pool.
+func (r *AuthRepo) db(ctx context.Context) txmanager.Querier {
+	return txmanager.From(ctx, r.pool)
+}
+
+// CreateUser creates a new user in the database with the provided details and the default "user" role, and returns the user ID.
 func (r *AuthRepo) CreateUser(ctx context.Context, userID uuid.UUID, email, username, passwordHash string) (uuid.UUID, error) {
 	var err error
 	defer func(start time.Time) {
 		r.Metrics.ObserveDB("insert_user", start, err)
 	}(time.Now())
-	tag, err := r.pool.Exec(ctx, "INSERT INTO users (id, email, username, password_hash) VALUES ($1, $2, $3, $4)",
+
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "INSERT INTO users (id, email, username, password_hash) VALUES ($1, $2, $3, $4)",
 		userID, email, username, passwordHash)
 
 	if err != nil {
+		err = takenError(err)
 		return uuid.Nil, err
 	}
 	if tag.RowsAffected() != 1 {
 		err = customerrors.ErrNoTagsAffected
 		return uuid.Nil, err
 	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO user_roles (user_id, role) VALUES ($1, $2)", userID, entity.RoleUser)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	// every user has a profile, it starts empty, and settings, they start with the defaults
+	_, err = tx.Exec(ctx, "INSERT INTO profiles (user_id) VALUES ($1)", userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO user_settings (user_id) VALUES ($1)", userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = events.Enqueue(ctx, tx, entity.DomainEventUserRegistered, userID, map[string]any{"user_id": userID, "username": username})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return uuid.Nil, err
+	}
 	return userID, nil
 }
 
@@ -49,7 +106,7 @@ func (r *AuthRepo) GetUserByLogin(ctx context.Context, login string) (userID uui
 		r.Metrics.ObserveDB("select_user_by_login", start, err)
 	}(time.Now())
 
-	err = r.pool.QueryRow(ctx, "select id, password_hash from users where username = $1 OR email = $1", login).Scan(
+	err = r.db(ctx).QueryRow(ctx, "select id, password_hash from users where (username = $1 OR email = LOWER($1)) AND deleted_at IS NULL", login).Scan(
 		&userID,
 		&passwordHash,
 	)
@@ -66,11 +123,12 @@ func (r *AuthRepo) StoreSession(ctx context.Context, userID uuid.UUID, session e
 		r.Metrics.ObserveDB("insert_session", start, err)
 	}(time.Now())
 	sql := `INSERT INTO sessions 
-			(id, user_id, refresh_token, created_at, expires_at, user_agent, ip_address) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+			(id, user_id, refresh_token, created_at, expires_at, user_agent, ip_address, is_suspicious, country_code, city) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''))`
 
-	_, err = r.pool.Exec(ctx,
-		sql, session.ID, userID, session.RefreshToken, session.CreatedAt, session.ExpiresAt, session.UserAgent, session.ClientIP)
+	_, err = r.db(ctx).Exec(ctx,
+		sql, session.ID, userID, session.RefreshToken, session.CreatedAt, session.ExpiresAt, session.UserAgent, session.ClientIP, session.IsSuspicious,
+		session.CountryCode, session.City)
 
 	return err
 
@@ -79,25 +137,37 @@ func (r *AuthRepo) StoreSession(ctx context.Context, userID uuid.UUID, session e
 // DeleteSession removes a specific session for a user, effectively logging them out from that ONE SPECIFIC SESSION.
 func (r *AuthRepo) DeleteSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
 	sql := `DELETE FROM sessions WHERE id = $1 AND user_id = $2`
-	_, err := r.pool.Exec(ctx, sql, sessionID, userID)
+	_, err := r.db(ctx).Exec(ctx, sql, sessionID, userID)
 	return err
 }
 
 // DeleteAllSessions removes all sessions for a user, effectively logging them out from !ALL! sessions.
 func (r *AuthRepo) DeleteAllSessions(ctx context.Context, userID uuid.UUID) error {
 	sql := `DELETE FROM sessions WHERE user_id = $1`
-	_, err := r.pool.Exec(ctx, sql, userID)
+	_, err := r.db(ctx).Exec(ctx, sql, userID)
 	return err
 }
 
+// DeleteOtherSessions removes every session of the user except the one with keepID and returns the IDs of the
+// removed sessions, so the access tokens bound to them can be revoked.
+func (r *AuthRepo) DeleteOtherSessions(ctx context.Context, userID, keepID uuid.UUID) ([]uuid.UUID, error) {
+	sql := `DELETE FROM sessions WHERE user_id = $1 AND id <> $2 RETURNING id`
+	rows, err := r.db(ctx).Query(ctx, sql, userID, keepID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
 func (r *AuthRepo) RefreshSession(ctx context.Context, session entity.Session) (err error) {
 
 	defer func(start time.Time) {
 		r.Metrics.ObserveDB("update_session", start, err)
 	}(time.Now())
 
-	sql := `UPDATE sessions SET created_at = $1, expires_at = $2, refresh_token = $3 WHERE id = $4 AND user_id = $5`
-	_, err = r.pool.Exec(ctx, sql, session.CreatedAt, session.ExpiresAt, session.RefreshToken, session.ID, session.UserID)
+	// created_at stays the login time, the absolute session lifetime is counted from it
+	sql := `UPDATE sessions SET refreshed_at = NOW(), expires_at = $1, refresh_token = $2 WHERE id = $3 AND user_id = $4`
+	_, err = r.db(ctx).Exec(ctx, sql, session.ExpiresAt, session.RefreshToken, session.ID, session.UserID)
 	return err
 }
 
@@ -109,7 +179,7 @@ func (r *AuthRepo) GetSessionByRefreshToken(ctx context.Context, refreshToken uu
 
 	sql := `SELECT id, user_id, created_at, expires_at, user_agent, ip_address
 			FROM sessions WHERE refresh_token = $1`
-	err = r.pool.QueryRow(ctx, sql, refreshToken).Scan(
+	err = r.db(ctx).QueryRow(ctx, sql, refreshToken).Scan(
 		&session.ID,
 		&session.UserID,
 		&session.CreatedAt,
@@ -117,17 +187,450 @@ func (r *AuthRepo) GetSessionByRefreshToken(ctx context.Context, refreshToken uu
 		&session.UserAgent,
 		&session.ClientIP,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrInvalidToken
+	}
 	return session, err
 
 }
 
-func (r *AuthRepo) UserIsBlocked(userID uuid.UUID) (bool, error) {
-	var isBlocked bool
-	err := r.pool.QueryRow(context.Background(),
-		"SELECT is_blocked FROM users WHERE id = $1", userID).
+// GetPasswordHash retrieves the stored password hash of the user.
+func (r *AuthRepo) GetPasswordHash(ctx context.Context, userID uuid.UUID) (passwordHash string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_password_hash", start, err)
+	}(time.Now())
+
+	err = r.db(ctx).QueryRow(ctx, "SELECT password_hash FROM users WHERE id = $1", userID).Scan(&passwordHash)
+	return passwordHash, err
+}
+
+// UpdatePasswordHash replaces the stored password hash of the user, used for re-hashing and password changes.
+func (r *AuthRepo) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_password_hash", start, err)
+	}(time.Now())
+
+	tag, err := r.db(ctx).Exec(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		err = customerrors.ErrNoTagsAffected
+		return err
+	}
+	return nil
+}
+
+// CreateEmailChangeRequest stores a pending email change, replacing any previous pending request of the user.
+func (r *AuthRepo) CreateEmailChangeRequest(ctx context.Context, userID uuid.UUID, newEmail string, tokenHash []byte, expiresAt time.Time) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_email_change_request", start, err)
+	}(time.Now())
+
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, "DELETE FROM email_change_requests WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	sql := `INSERT INTO email_change_requests (token_hash, user_id, new_email, expires_at) VALUES ($1, $2, $3, $4)`
+	if _, err = tx.Exec(ctx, sql, tokenHash, userID, newEmail, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ConfirmEmailChange consumes the pending request with the given token hash and swaps the email in one transaction,
+// keeping the previous address in email_history for audit.
+func (r *AuthRepo) ConfirmEmailChange(ctx context.Context, tokenHash []byte) (userID uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("confirm_email_change", start, err)
+	}(time.Now())
+
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var newEmail string
+	err = tx.QueryRow(ctx,
+		`DELETE FROM email_change_requests WHERE token_hash = $1 AND expires_at > NOW() RETURNING user_id, new_email`,
+		tokenHash).Scan(&userID, &newEmail)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrInvalidToken
+		return uuid.Nil, err
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var oldEmail string
+	err = tx.QueryRow(ctx,
+		`UPDATE users u SET email = $1 FROM (SELECT id, email FROM users WHERE id = $2 FOR UPDATE) old
+		 WHERE u.id = old.id RETURNING old.email`,
+		newEmail, userID).Scan(&oldEmail)
+	if err != nil {
+		// another account registered the address since the change was requested
+		err = takenError(err)
+		return uuid.Nil, err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO email_history (user_id, old_email, new_email) VALUES ($1, $2, $3)",
+		userID, oldEmail, newEmail)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = tx.Commit(ctx)
+	return userID, err
+}
+
+// SoftDeleteUser marks the user as deleted and removes all of their sessions in one transaction.
+func (r *AuthRepo) SoftDeleteUser(ctx context.Context, userID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("soft_delete_user", start, err)
+	}(time.Now())
+
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		err = customerrors.ErrNoTagsAffected
+		return err
+	}
+	if _, err = tx.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RestoreUser undeletes the user, as long as their personal data hasn't been erased yet.
+// Returns customerrors.ErrNotFound if there is no such deleted user.
+func (r *AuthRepo) RestoreUser(ctx context.Context, userID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("restore_user", start, err)
+	}(time.Now())
+
+	sql := `UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL`
+	tag, err := r.db(ctx).Exec(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	return nil
+}
+
+// AnonymizeDeletedUsers irreversibly replaces the PII of users deleted before the given time
+// and drops related personal data. It returns the number of anonymized users.
+func (r *AuthRepo) AnonymizeDeletedUsers(ctx context.Context, deletedBefore time.Time) (count int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("anonymize_deleted_users", start, err)
+	}(time.Now())
+
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE users SET
+				email = 'deleted-' || id::text || '@deleted.invalid',
+				username = 'deleted_' || replace(id::text, '-', ''),
+				password_hash = '',
+				anonymized_at = NOW()
+			WHERE deleted_at < $1 AND anonymized_at IS NULL
+			RETURNING id`
+	rows, err := tx.Query(ctx, sql, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, sql := range []string{
+		"DELETE FROM email_history WHERE user_id = ANY($1)",
+		"DELETE FROM email_change_requests WHERE user_id = ANY($1)",
+		"DELETE FROM sessions WHERE user_id = ANY($1)",
+		"UPDATE profiles SET name = '', bio = '', avatar_url = '', gender = '', age = NULL, updated_at = NOW() WHERE user_id = ANY($1)",
+	} {
+		batch.Queue(sql, ids)
+	}
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(ctx)
+	return int64(len(ids)), err
+}
+
+// DeleteExpiredSessions deletes the sessions that expired before the given time and returns how many were deleted.
+func (r *AuthRepo) DeleteExpiredSessions(ctx context.Context, expiredBefore time.Time) (count int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_expired_sessions", start, err)
+	}(time.Now())
+
+	tag, err := r.db(ctx).Exec(ctx, "DELETE FROM sessions WHERE expires_at < $1", expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteExpiredTokens deletes the email change requests and login confirmations that expired before the given
+// time and returns how many were deleted.
+func (r *AuthRepo) DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time) (count int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_expired_tokens", start, err)
+	}(time.Now())
+
+	var tag pgconn.CommandTag
+	for _, sql := range []string{
+		"DELETE FROM email_change_requests WHERE expires_at < $1",
+		"DELETE FROM login_confirmations WHERE expires_at < $1",
+	} {
+		if tag, err = r.db(ctx).Exec(ctx, sql, expiredBefore); err != nil {
+			return count, err
+		}
+		count += tag.RowsAffected()
+	}
+	return count, nil
+}
+
+// UserIsBlocked returns true if the user is blocked and the block has not expired yet.
+func (r *AuthRepo) UserIsBlocked(ctx context.Context, userID uuid.UUID) (isBlocked bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_user_blocked", start, err)
+	}(time.Now())
+
+	err = r.db(ctx).QueryRow(ctx,
+		"SELECT is_blocked AND (blocked_until IS NULL OR blocked_until > NOW()) FROM users WHERE id = $1", userID).
 		Scan(&isBlocked)
 	if err != nil {
 		return false, err
 	}
-	return !isBlocked, nil
+	return isBlocked, nil
+}
+
+// SetUserBlocked blocks the user with a reason and optional expiry, or lifts the block.
+func (r *AuthRepo) SetUserBlocked(ctx context.Context, userID uuid.UUID, blocked bool, reason string, until *time.Time) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_user_blocked", start, err)
+	}(time.Now())
+
+	sql := `UPDATE users SET is_blocked = $1, blocked_reason = NULLIF($2, ''), blocked_until = $3 WHERE id = $4 AND deleted_at IS NULL`
+	tag, err := r.db(ctx).Exec(ctx, sql, blocked, reason, until, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		err = customerrors.ErrNoTagsAffected
+		return err
+	}
+	return nil
+}
+
+// LookupUser returns the account with the ID, or with the username or email address matching login, deleted
+// accounts included. Returns customerrors.ErrNotFound if there is no such account.
+func (r *AuthRepo) LookupUser(ctx context.Context, userID uuid.UUID, login string) (account entity.UserAccount, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_user_account", start, err)
+	}(time.Now())
+
+	sql := `SELECT id, username, email, ARRAY(SELECT role FROM user_roles WHERE user_id = users.id ORDER BY role),
+				COALESCE(is_blocked, FALSE) AND (blocked_until IS NULL OR blocked_until > NOW()), COALESCE(blocked_reason, ''),
+				blocked_until, created_at, deleted_at
+			FROM users
+			WHERE id = $1 OR lower(username) = lower($2) OR email = lower($2)
+			LIMIT 1`
+	err = r.db(ctx).QueryRow(ctx, sql, userID, login).Scan(&account.ID, &account.Username, &account.Email, &account.Roles,
+		&account.IsBlocked, &account.BlockedReason, &account.BlockedUntil, &account.CreatedAt, &account.DeletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return account, err
+}
+
+// GetUserRoles returns the roles granted to the user.
+func (r *AuthRepo) GetUserRoles(ctx context.Context, userID uuid.UUID) (roles []entity.Role, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_user_roles", start, err)
+	}(time.Now())
+
+	rows, err := r.db(ctx).Query(ctx, "SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role", userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err = pgx.CollectRows(rows, pgx.RowTo[entity.Role])
+	return roles, err
+}
+
+// AddUserRole grants the role to the user, granting an already granted role is a no-op.
+func (r *AuthRepo) AddUserRole(ctx context.Context, userID uuid.UUID, role entity.Role) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_user_role", start, err)
+	}(time.Now())
+
+	_, err = r.db(ctx).Exec(ctx, "INSERT INTO user_roles (user_id, role) VALUES ($1, $2) ON CONFLICT DO NOTHING", userID, role)
+	return err
+}
+
+// RemoveUserRole revokes the role from the user.
+func (r *AuthRepo) RemoveUserRole(ctx context.Context, userID uuid.UUID, role entity.Role) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_user_role", start, err)
+	}(time.Now())
+
+	_, err = r.db(ctx).Exec(ctx, "DELETE FROM user_roles WHERE user_id = $1 AND role = $2", userID, role)
+	return err
+}
+
+// ListSessions returns the unexpired sessions of the user older than the (beforeTime, beforeID) position, newest first.
+// A zero beforeTime starts from the newest session.
+func (r *AuthRepo) ListSessions(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (sessions []entity.Session, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_sessions", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := `SELECT id, user_id, created_at, expires_at, COALESCE(user_agent, ''), ip_address,
+				COALESCE(is_suspicious, FALSE), COALESCE(device_name, ''), COALESCE(country_code, ''), COALESCE(city, '')
+			FROM sessions WHERE user_id = $1 AND expires_at > NOW()
+				AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+			ORDER BY created_at DESC, id DESC
+			LIMIT $4`
+	rows, err := r.db(ctx).Query(ctx, sql, userID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Session, error) {
+		var s entity.Session
+		err := row.Scan(&s.ID, &s.UserID, &s.CreatedAt, &s.ExpiresAt, &s.UserAgent, &s.ClientIP, &s.IsSuspicious, &s.DeviceName,
+			&s.CountryCode, &s.City)
+		return s, err
+	})
+	return sessions, err
+}
+
+// RenameSession sets the device name of a session of the user, returns customerrors.ErrNoTagsAffected if there is no such session.
+func (r *AuthRepo) RenameSession(ctx context.Context, userID, sessionID uuid.UUID, name string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_session_device_name", start, err)
+	}(time.Now())
+
+	tag, err := r.db(ctx).Exec(ctx, "UPDATE sessions SET device_name = NULLIF($1, '') WHERE id = $2 AND user_id = $3", name, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrNoTagsAffected
+	}
+	return nil
+}
+
+// UsernameExists reports whether the username is taken, ignoring case.
+// Deleted accounts keep their username reserved until they are anonymized.
+func (r *AuthRepo) UsernameExists(ctx context.Context, username string) (exists bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_username_exists", start, err)
+	}(time.Now())
+
+	err = r.db(ctx).QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))", username).Scan(&exists)
+	return exists, err
+}
+
+// GetUserEmail returns the email address of the user.
+func (r *AuthRepo) GetUserEmail(ctx context.Context, userID uuid.UUID) (email string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_user_email", start, err)
+	}(time.Now())
+
+	err = r.db(ctx).QueryRow(ctx, "SELECT email FROM users WHERE id = $1", userID).Scan(&email)
+	return email, err
+}
+
+// IsKnownDevice reports whether the user signed in from the IP and User-Agent before.
+// hasHistory is false for users that never signed in, their first device is not considered new.
+func (r *AuthRepo) IsKnownDevice(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string) (known, hasHistory bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_known_device", start, err)
+	}(time.Now())
+
+	sql := `SELECT COUNT(*) > 0, COALESCE(bool_or(ip_address = $2 AND user_agent = $3), FALSE)
+			FROM user_devices WHERE user_id = $1`
+	err = r.db(ctx).QueryRow(ctx, sql, userID, ip, userAgent).Scan(&hasHistory, &known)
+	return known, hasHistory, err
+}
+
+// RememberDevice records a successful sign in from the IP and User-Agent in the given country.
+func (r *AuthRepo) RememberDevice(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent, countryCode string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("upsert_user_device", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO user_devices (user_id, ip_address, user_agent, country_code) VALUES ($1, $2, $3, NULLIF($4, ''))
+			ON CONFLICT (user_id, ip_address, user_agent)
+			DO UPDATE SET last_seen_at = NOW(), country_code = COALESCE(EXCLUDED.country_code, user_devices.country_code)`
+	_, err = r.db(ctx).Exec(ctx, sql, userID, ip, userAgent, countryCode)
+	return err
+}
+
+// IsKnownCountry reports whether the user signed in from the country before.
+func (r *AuthRepo) IsKnownCountry(ctx context.Context, userID uuid.UUID, countryCode string) (known bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_known_country", start, err)
+	}(time.Now())
+
+	err = r.db(ctx).QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM user_devices WHERE user_id = $1 AND country_code = $2)", userID, countryCode).Scan(&known)
+	return known, err
+}
+
+// CreateLoginConfirmation stores a login from a new device that is completed once the user confirms it by email.
+func (r *AuthRepo) CreateLoginConfirmation(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string, tokenHash []byte, expiresAt time.Time) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_login_confirmation", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO login_confirmations (token_hash, user_id, ip_address, user_agent, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = r.db(ctx).Exec(ctx, sql, tokenHash, userID, ip, userAgent, expiresAt)
+	return err
+}
+
+// ConsumeLoginConfirmation deletes the pending login and returns the user and device it was made from.
+// Returns customerrors.ErrInvalidToken if the token is unknown or expired.
+func (r *AuthRepo) ConsumeLoginConfirmation(ctx context.Context, tokenHash []byte) (userID uuid.UUID, ip netip.Addr, userAgent string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("consume_login_confirmation", start, err)
+	}(time.Now())
+
+	err = r.db(ctx).QueryRow(ctx,
+		`DELETE FROM login_confirmations WHERE token_hash = $1 AND expires_at > NOW() RETURNING user_id, ip_address, user_agent`,
+		tokenHash).Scan(&userID, &ip, &userAgent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrInvalidToken
+	}
+	return userID, ip, userAgent, err
 }
@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/internal/storage/postgres/auth"
+	"main/internal/storage/postgres/postgrestest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sessions stores n sessions of the user, deleted when the benchmark ends.
+func sessions(b *testing.B, repo *auth.AuthRepo, userID uuid.UUID, n int) []entity.Session {
+	b.Helper()
+	ctx := context.Background()
+	stored := make([]entity.Session, n)
+	for i := range stored {
+		now := time.Now()
+		stored[i] = entity.Session{
+			ID:           uuid.New(),
+			UserID:       userID,
+			RefreshToken: uuid.New(),
+			ClientIP:     netip.MustParseAddr("203.0.113.7"),
+			UserAgent:    "benchmark",
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(time.Hour),
+		}
+		if err := repo.StoreSession(ctx, userID, stored[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Cleanup(func() {
+		for _, s := range stored {
+			_ = repo.DeleteSession(ctx, userID, s.ID)
+		}
+	})
+	return stored
+}
+
+func BenchmarkSessionLookups(b *testing.B) {
+	db, m := postgrestest.Open(b)
+	repo := auth.NewAuthRepo(db, m)
+	userID := postgrestest.ActiveUser(b, db)
+	stored := sessions(b, repo, userID, 20)
+	ctx := context.Background()
+
+	// every refresh looks the session up by its refresh token
+	b.Run("by_refresh_token", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			if _, err := repo.GetSessionByRefreshToken(ctx, stored[i%len(stored)].RefreshToken); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+	// every authenticated request checks the block of the caller
+	b.Run("user_is_blocked", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := repo.UserIsBlocked(ctx, userID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("list_sessions", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := repo.ListSessions(ctx, userID, time.Time{}, uuid.Nil, 20); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
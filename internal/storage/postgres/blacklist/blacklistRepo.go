@@ -0,0 +1,135 @@
+package blacklist
+
+import (
+	"context"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type BlacklistRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewBlacklistRepo(pool *postgres.DB, metrics *metrics.Metrics) *BlacklistRepo {
+	return &BlacklistRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// Block records that the blocker blocked the user and removes the follows between them in both directions,
+// adjusting the follow counters, their pending follow requests and their close friends entries.
+// Blocking twice changes nothing. Returns customerrors.ErrNotFound if the user doesn't exist.
+func (r *BlacklistRepo) Block(ctx context.Context, blockerID, blockedID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_blacklist", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO blacklist (blocker_id, blocked_id)
+			SELECT $1, id FROM users WHERE id = $2 AND deleted_at IS NULL
+			ON CONFLICT (blocker_id, blocked_id) DO NOTHING`
+	if _, err = tx.Exec(ctx, sql, blockerID, blockedID); err != nil {
+		return err
+	}
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM blacklist WHERE blocker_id = $1 AND blocked_id = $2)", blockerID, blockedID).
+		Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err = customerrors.ErrNotFound
+		return err
+	}
+
+	sql = `WITH removed AS (
+				DELETE FROM follows
+				WHERE follower_id = $1 AND followee_id = $2 OR follower_id = $2 AND followee_id = $1
+				RETURNING follower_id, followee_id
+			),
+			following AS (
+				UPDATE profiles SET following_count = GREATEST(following_count - 1, 0)
+				WHERE user_id IN (SELECT follower_id FROM removed)
+			)
+			UPDATE profiles SET followers_count = GREATEST(followers_count - 1, 0)
+			WHERE user_id IN (SELECT followee_id FROM removed)`
+	if _, err = tx.Exec(ctx, sql, blockerID, blockedID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM close_friends WHERE user_id = $1 AND friend_id = $2 OR user_id = $2 AND friend_id = $1",
+		blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM follow_requests WHERE follower_id = $1 AND followee_id = $2 OR follower_id = $2 AND followee_id = $1",
+		blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Unblock removes the block, it is a no-op if there is no block. Follows ended by the block are not restored.
+func (r *BlacklistRepo) Unblock(ctx context.Context, blockerID, blockedID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_blacklist", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM blacklist WHERE blocker_id = $1 AND blocked_id = $2", blockerID, blockedID)
+	return err
+}
+
+// IsBlocked reports whether the blocker blocked the user. Either ID may be uuid.Nil, which is never blocked.
+func (r *BlacklistRepo) IsBlocked(ctx context.Context, blockerID, blockedID uuid.UUID) (blocked bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_blacklist", start, err)
+	}(time.Now())
+
+	err = r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM blacklist WHERE blocker_id = $1 AND blocked_id = $2)", blockerID, blockedID).
+		Scan(&blocked)
+	return blocked, err
+}
+
+// ListBlocked returns the users the blocker blocked before the (beforeTime, beforeID) position, newest block first.
+// A zero beforeTime starts from the newest block.
+func (r *BlacklistRepo) ListBlocked(ctx context.Context, blockerID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (entries []entity.BlockedEntry, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_blacklist_entries", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''), b.created_at
+			FROM blacklist b
+				JOIN users u ON u.id = b.blocked_id
+				LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE b.blocker_id = $1
+				AND ($2::timestamptz IS NULL OR (b.created_at, b.blocked_id) < ($2, $3))
+			ORDER BY b.created_at DESC, b.blocked_id DESC
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, blockerID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.BlockedEntry, error) {
+		var e entity.BlockedEntry
+		err := row.Scan(&e.User.ID, &e.User.Username, &e.User.Name, &e.User.AvatarURL, &e.BlockedAt)
+		return e, err
+	})
+	return entries, err
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a Breaker, its value is what the state gauge reports.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single probe through to find out whether the database is back
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+// Breaker is a circuit breaker: after threshold consecutive failures it opens and calls fail fast without
+// reaching the database. Once openFor passed a single probe is let through, its success closes the breaker
+// and its failure opens it again.
+type Breaker struct {
+	threshold int
+	openFor   time.Duration
+	onChange  func(BreakerState)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker returns a closed breaker, onChange is called with every new state.
+func NewBreaker(threshold int, openFor time.Duration, onChange func(BreakerState)) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		openFor:   openFor,
+		onChange:  onChange,
+	}
+}
+
+// Allow reports whether a call may go to the database, every allowed call must be followed by Record.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.openFor {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Open reports whether calls fail fast right now, without letting a probe through.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == BreakerOpen && time.Since(b.openedAt) < b.openFor
+}
+
+// Record reports the outcome of an allowed call, failed is true for failures that tell the database is unhealthy.
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.open()
+			return
+		}
+		b.failures = 0
+		b.setState(BreakerClosed)
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = time.Now()
+	b.setState(BreakerOpen)
+}
+
+func (b *Breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
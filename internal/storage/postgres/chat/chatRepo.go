@@ -0,0 +1,869 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/internal/storage/postgres/events"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ChatRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewChatRepo(pool *postgres.DB, metrics *metrics.Metrics) *ChatRepo {
+	return &ChatRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// selectChat selects the chats the user given as $1 is a member of with the other participant of direct chats
+// and the last message, the rows are read by scanChat.
+const selectChat = `SELECT c.id, c.is_group, COALESCE(c.title, ''), COALESCE(c.avatar_url, ''), c.public,
+				(SELECT COUNT(*) FROM chat_members cc WHERE cc.chat_id = c.id),
+				(SELECT COUNT(*) FROM messages mu WHERE mu.chat_id = c.id AND mu.sender_id <> $1 AND mu.deleted_at IS NULL
+					AND (cm.last_read_at IS NULL OR mu.created_at > cm.last_read_at)),
+				u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = u.id AND f.followee_id = $1),
+				m.id, m.sender_id, ` + messageContent + `, m.created_at, m.edited_at, m.deleted_at IS NOT NULL, ` + messageAttachment + `,
+				c.created_at, c.last_activity_at
+			FROM chat_members cm
+				JOIN chats c ON c.id = cm.chat_id
+				LEFT JOIN users u ON NOT c.is_group AND u.id = CASE WHEN c.user1_id = $1 THEN c.user2_id ELSE c.user1_id END
+				LEFT JOIN profiles p ON p.user_id = u.id
+				LEFT JOIN LATERAL (
+					SELECT id, sender_id, content, created_at, edited_at, deleted_at, attachment FROM messages mh
+					WHERE chat_id = c.id
+						AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $1 AND h.message_id = mh.id)
+					ORDER BY created_at DESC, id DESC LIMIT 1
+				) m ON TRUE
+			WHERE cm.user_id = $1`
+
+// outboxLockID is the advisory lock held while relaying the outbox, so one relay at a time publishes the events
+// in the order they happened.
+const outboxLockID = 7501
+
+// messageContent and messageAttachment select the content and the attachment of the message m. A message deleted
+// for everyone keeps them until it is purged, so that its sender can restore it, but shows neither.
+const (
+	messageContent    = `CASE WHEN m.deleted_at IS NULL THEN m.content ELSE '' END`
+	messageAttachment = `CASE WHEN m.deleted_at IS NULL THEN m.attachment END`
+)
+
+// selectMessage selects messages as m, the rows are read by scanMessage.
+const selectMessage = `SELECT m.id, m.chat_id, m.sender_id, ` + messageContent + `, m.created_at, m.edited_at, m.deleted_at IS NOT NULL,
+				` + messageAttachment + `
+			FROM messages m`
+
+// CreateChat returns the id of the direct chat between the user and the peer, creating the chat if there is none.
+// Returns customerrors.ErrNotFound if the peer doesn't exist or deleted the account.
+func (r *ChatRepo) CreateChat(ctx context.Context, userID, peerID uuid.UUID) (chatID uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_chat", start, err)
+	}(time.Now())
+
+	// the no-op update makes RETURNING give the existing chat, also when a concurrent call created it
+	sql := `WITH chat AS (
+				INSERT INTO chats (id, user1_id, user2_id)
+				SELECT $3, LEAST($1::uuid, id), GREATEST($1::uuid, id) FROM users WHERE id = $2 AND deleted_at IS NULL
+				ON CONFLICT (user1_id, user2_id) DO UPDATE SET user1_id = EXCLUDED.user1_id
+				RETURNING id
+			),
+			members AS (
+				INSERT INTO chat_members (chat_id, user_id)
+				SELECT chat.id, member FROM chat, unnest(ARRAY[$1::uuid, $2::uuid]) member
+				ON CONFLICT (chat_id, user_id) DO NOTHING
+			)
+			SELECT id FROM chat`
+	err = r.pool.QueryRow(ctx, sql, userID, peerID, uuid.New()).Scan(&chatID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return chatID, err
+}
+
+// CreateGroup stores a new group chat with the creator as its admin and the members as regular members.
+// Returns customerrors.ErrNotFound if any of the members doesn't exist or deleted the account.
+func (r *ChatRepo) CreateGroup(ctx context.Context, chat entity.Chat, creatorID uuid.UUID, memberIDs []uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_group_chat", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO chats (id, is_group, title, avatar_url, public, created_at, last_activity_at)
+			VALUES ($1, TRUE, $2, NULLIF($3, ''), $4, $5, $5)`,
+		chat.ID, chat.Title, chat.AvatarURL, chat.Public, chat.CreatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO chat_members (chat_id, user_id, role, joined_at) VALUES ($1, $2, 'admin', $3)",
+		chat.ID, creatorID, chat.CreatedAt)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `INSERT INTO chat_members (chat_id, user_id, joined_at)
+			SELECT $1, id, $3 FROM users WHERE id = ANY($2) AND deleted_at IS NULL
+			ON CONFLICT (chat_id, user_id) DO NOTHING`, chat.ID, memberIDs, chat.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != int64(len(memberIDs)) {
+		return customerrors.ErrNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+// GetChat returns a chat of the user, customerrors.ErrNotFound if there is none or the user isn't a member of it.
+func (r *ChatRepo) GetChat(ctx context.Context, userID, chatID uuid.UUID) (chat entity.Chat, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_chat", start, err)
+	}(time.Now())
+
+	chat, err = scanChat(r.pool.QueryRow(ctx, selectChat+" AND c.id = $2", userID, chatID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return chat, err
+}
+
+// ListChats returns the chats of the user active before the (beforeTime, beforeID) position, most recently
+// active first. A zero beforeTime starts from the most recent chat.
+func (r *ChatRepo) ListChats(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (chats []entity.Chat, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_chats", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := selectChat + ` AND ($2::timestamptz IS NULL OR (c.last_activity_at, c.id) < ($2, $3))
+			ORDER BY c.last_activity_at DESC, c.id DESC
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, userID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	chats, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Chat, error) {
+		return scanChat(row)
+	})
+	return chats, err
+}
+
+// UpdateGroup changes the non-nil fields of a group chat, customerrors.ErrNotFound if there is no such group.
+func (r *ChatRepo) UpdateGroup(ctx context.Context, chatID uuid.UUID, update entity.GroupUpdate) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_group_chat", start, err)
+	}(time.Now())
+
+	sql := `UPDATE chats SET
+				title = COALESCE($2, title),
+				avatar_url = CASE WHEN $3::text IS NULL THEN avatar_url ELSE NULLIF($3, '') END,
+				public = COALESCE($4, public)
+			WHERE id = $1 AND is_group`
+	tag, err := r.pool.Exec(ctx, sql, chatID, update.Title, update.AvatarURL, update.Public)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	return nil
+}
+
+// GetMember returns the membership of the user in a chat, customerrors.ErrNotFound if the user isn't a member.
+func (r *ChatRepo) GetMember(ctx context.Context, chatID, userID uuid.UUID) (member entity.ChatMember, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_chat_member", start, err)
+	}(time.Now())
+
+	member = entity.ChatMember{ChatID: chatID}
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''), cm.role, cm.joined_at, cm.last_read_message_id
+			FROM chat_members cm
+				JOIN users u ON u.id = cm.user_id
+				LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE cm.chat_id = $1 AND cm.user_id = $2`
+	err = r.pool.QueryRow(ctx, sql, chatID, userID).
+		Scan(&member.User.ID, &member.User.Username, &member.User.Name, &member.User.AvatarURL, &member.Role, &member.JoinedAt,
+			&member.LastReadMessageID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return member, err
+}
+
+// ListMemberIDs returns the ids of all members of a chat.
+func (r *ChatRepo) ListMemberIDs(ctx context.Context, chatID uuid.UUID) (ids []uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_chat_member_ids", start, err)
+	}(time.Now())
+
+	rows, err := r.pool.Query(ctx, "SELECT user_id FROM chat_members WHERE chat_id = $1", chatID)
+	if err != nil {
+		return nil, err
+	}
+	ids, err = pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	return ids, err
+}
+
+// ListMembers returns the members of a chat who joined after the (afterTime, afterID) position with the follow state
+// relative to the viewer, in the order they joined. A zero afterTime starts from the first member.
+func (r *ChatRepo) ListMembers(ctx context.Context, viewerID, chatID uuid.UUID, afterTime time.Time, afterID uuid.UUID, limit int) (members []entity.ChatMember, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_chat_members", start, err)
+	}(time.Now())
+
+	var after any
+	if !afterTime.IsZero() {
+		after = afterTime
+	}
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = u.id AND f.followee_id = $1),
+				cm.role, cm.joined_at, cm.last_read_message_id
+			FROM chat_members cm
+				JOIN users u ON u.id = cm.user_id
+				LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE cm.chat_id = $2 AND ($3::timestamptz IS NULL OR (cm.joined_at, cm.user_id) > ($3, $4))
+			ORDER BY cm.joined_at, cm.user_id
+			LIMIT $5`
+	rows, err := r.pool.Query(ctx, sql, viewerID, chatID, after, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	members, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.ChatMember, error) {
+		m := entity.ChatMember{ChatID: chatID}
+		err := row.Scan(&m.User.ID, &m.User.Username, &m.User.Name, &m.User.AvatarURL, &m.User.Following, &m.User.FollowsYou,
+			&m.Role, &m.JoinedAt, &m.LastReadMessageID)
+		return m, err
+	})
+	return members, err
+}
+
+// AddMembers adds users to a group chat as regular members, users who are already members keep their role.
+// Returns customerrors.ErrNotFound if the chat isn't a group or any of the users doesn't exist or deleted the account.
+func (r *ChatRepo) AddMembers(ctx context.Context, chatID uuid.UUID, userIDs []uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_chat_members", start, err)
+	}(time.Now())
+
+	sql := `WITH found AS (
+				SELECT u.id FROM users u, chats c
+				WHERE u.id = ANY($2) AND u.deleted_at IS NULL AND c.id = $1 AND c.is_group
+			),
+			inserted AS (
+				INSERT INTO chat_members (chat_id, user_id) SELECT $1, id FROM found
+				ON CONFLICT (chat_id, user_id) DO NOTHING
+			)
+			SELECT COUNT(*) FROM found`
+	var found int
+	if err = r.pool.QueryRow(ctx, sql, chatID, userIDs).Scan(&found); err != nil {
+		return err
+	}
+	if found != len(userIDs) {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	return nil
+}
+
+// RemoveMember removes a user from a group chat. If the last admin leaves, the longest standing member becomes
+// an admin, and a group left without members is deleted. Returns customerrors.ErrNotFound if the user isn't
+// a member of such a group.
+func (r *ChatRepo) RemoveMember(ctx context.Context, chatID, userID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_chat_member", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM chat_members cm USING chats c
+			WHERE cm.chat_id = $1 AND cm.user_id = $2 AND c.id = cm.chat_id AND c.is_group`, chatID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrNotFound
+	}
+
+	sql := `UPDATE chat_members SET role = 'admin'
+			WHERE chat_id = $1 AND user_id = (
+				SELECT user_id FROM chat_members WHERE chat_id = $1 ORDER BY joined_at, user_id LIMIT 1
+			) AND NOT EXISTS (SELECT 1 FROM chat_members WHERE chat_id = $1 AND role = 'admin')`
+	if _, err = tx.Exec(ctx, sql, chatID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM chats WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM chat_members WHERE chat_id = $1)", chatID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetMemberRole changes the role of a member of a group chat, customerrors.ErrNotFound if the user isn't a member
+// of such a group.
+func (r *ChatRepo) SetMemberRole(ctx context.Context, chatID, userID uuid.UUID, role entity.ChatRole) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_chat_member", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, `UPDATE chat_members cm SET role = $3 FROM chats c
+			WHERE cm.chat_id = $1 AND cm.user_id = $2 AND c.id = cm.chat_id AND c.is_group`, chatID, userID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	return nil
+}
+
+// JoinChat adds the user to a public group chat, joining a group the user is a member of changes nothing.
+// Returns customerrors.ErrNotFound if there is no such public group.
+func (r *ChatRepo) JoinChat(ctx context.Context, chatID, userID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_chat_member", start, err)
+	}(time.Now())
+
+	sql := `WITH chat AS (SELECT id FROM chats WHERE id = $1 AND is_group AND public),
+			inserted AS (
+				INSERT INTO chat_members (chat_id, user_id) SELECT id, $2 FROM chat
+				ON CONFLICT (chat_id, user_id) DO NOTHING
+			)
+			SELECT EXISTS (SELECT 1 FROM chat)`
+	var exists bool
+	if err = r.pool.QueryRow(ctx, sql, chatID, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	return nil
+}
+
+// SendMessage stores a message and moves the chat to the top of the members' chat lists in the same transaction.
+// Returns customerrors.ErrNotFound if the chat doesn't exist or the sender isn't a member of it
+// and customerrors.ErrBlockedByUser if the other participant of a direct chat blocked the sender.
+func (r *ChatRepo) SendMessage(ctx context.Context, message entity.Message) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_message", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the peer is NULL for group chats
+	var peerID *uuid.UUID
+	sql := `UPDATE chats SET last_activity_at = GREATEST(last_activity_at, $3)
+			WHERE id = $1 AND EXISTS (SELECT 1 FROM chat_members WHERE chat_id = $1 AND user_id = $2)
+			RETURNING CASE WHEN user1_id = $2 THEN user2_id ELSE user1_id END`
+	err = tx.QueryRow(ctx, sql, message.ChatID, message.SenderID, message.CreatedAt).Scan(&peerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customerrors.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if peerID != nil {
+		var blocked bool
+		err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM blacklist WHERE blocker_id = $1 AND blocked_id = $2)", *peerID, message.SenderID).
+			Scan(&blocked)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return customerrors.ErrBlockedByUser
+		}
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO messages (id, chat_id, sender_id, content, created_at, attachment) VALUES ($1, $2, $3, $4, $5, $6)",
+		message.ID, message.ChatID, message.SenderID, message.Content, message.CreatedAt, message.Attachment)
+	if err != nil {
+		return err
+	}
+	// replying reads the chat up to the reply
+	_, err = tx.Exec(ctx, "UPDATE chat_members SET last_read_message_id = $3, last_read_at = $4 WHERE chat_id = $1 AND user_id = $2",
+		message.ChatID, message.SenderID, message.ID, message.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if err = enqueueEvent(ctx, tx, entity.ChatEventMessage, message.ChatID, message.SenderID, message.ID); err != nil {
+		return err
+	}
+	// the content stays private to the chat
+	err = events.Enqueue(ctx, tx, entity.DomainEventMessageSent, message.ChatID, map[string]any{
+		"message_id": message.ID,
+		"chat_id":    message.ChatID,
+		"sender_id":  message.SenderID,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkRead moves the user's read position in a chat forward to the message and reports whether it moved,
+// marking an older message than the last read one changes nothing. Returns customerrors.ErrNotFound if the user
+// isn't a member of the chat or the message isn't in it.
+func (r *ChatRepo) MarkRead(ctx context.Context, userID, chatID, messageID uuid.UUID) (advanced bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_chat_read", start, err)
+	}(time.Now())
+
+	sql := `WITH msg AS (
+				SELECT m.id, m.created_at FROM messages m
+					JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $2
+				WHERE m.id = $3 AND m.chat_id = $1
+			),
+			updated AS (
+				UPDATE chat_members cm SET last_read_message_id = msg.id, last_read_at = msg.created_at
+				FROM msg
+				WHERE cm.chat_id = $1 AND cm.user_id = $2 AND (cm.last_read_at IS NULL OR msg.created_at > cm.last_read_at)
+				RETURNING 1
+			)
+			SELECT EXISTS (SELECT 1 FROM msg), EXISTS (SELECT 1 FROM updated)`
+	var found bool
+	if err = r.pool.QueryRow(ctx, sql, chatID, userID, messageID).Scan(&found, &advanced); err != nil {
+		return false, err
+	}
+	if !found {
+		err = customerrors.ErrNotFound
+		return false, err
+	}
+	return advanced, nil
+}
+
+// ListMessages returns the messages of a chat of the user sent before the (beforeTime, beforeID) position, newest first.
+// A zero beforeTime starts from the newest message. Returns customerrors.ErrNotFound if the user isn't a member of the chat.
+func (r *ChatRepo) ListMessages(ctx context.Context, userID, chatID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (messages []entity.Message, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_messages", start, err)
+	}(time.Now())
+
+	var member bool
+	err = r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM chat_members WHERE chat_id = $1 AND user_id = $2)", chatID, userID).
+		Scan(&member)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		err = customerrors.ErrNotFound
+		return nil, err
+	}
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := selectMessage + `
+			WHERE m.chat_id = $1 AND ($2::timestamptz IS NULL OR (m.created_at, m.id) < ($2, $3))
+				AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $5 AND h.message_id = m.id)
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, chatID, before, beforeID, limit, userID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Message, error) {
+		return scanMessage(row)
+	})
+	return messages, err
+}
+
+// SearchMessages returns the messages of the chats of the user matching the full-text query, newest first,
+// only those of chatID unless it's uuid.Nil. The query uses web search syntax ("quoted phrases", -excluded, or).
+// Deleted messages and those the user deleted for themselves aren't found. A zero beforeTime starts from
+// the newest match.
+func (r *ChatRepo) SearchMessages(ctx context.Context, userID, chatID uuid.UUID, query string, beforeTime time.Time, beforeID uuid.UUID, limit int) (matches []entity.MessageMatch, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("search_messages", start, err)
+	}(time.Now())
+
+	var inChat, before any
+	if chatID != uuid.Nil {
+		inChat = chatID
+	}
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	// messages are short enough to be highlighted whole
+	sql := `SELECT m.id, m.chat_id, m.sender_id, m.content, m.created_at, m.edited_at, m.deleted_at IS NOT NULL, m.attachment,
+				ts_headline('simple', m.content, websearch_to_tsquery('simple', $2), 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>')
+			FROM messages m
+				JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $1
+			WHERE m.search_vector @@ websearch_to_tsquery('simple', $2) AND m.deleted_at IS NULL
+				AND ($3::uuid IS NULL OR m.chat_id = $3)
+				AND ($4::timestamptz IS NULL OR (m.created_at, m.id) < ($4, $5))
+				AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $1 AND h.message_id = m.id)
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT $6`
+	rows, err := r.pool.Query(ctx, sql, userID, query, inChat, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	matches, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.MessageMatch, error) {
+		var mm entity.MessageMatch
+		m := &mm.Message
+		err := row.Scan(&m.ID, &m.ChatID, &m.SenderID, &m.Content, &m.CreatedAt, &m.EditedAt, &m.Deleted, &m.Attachment, &mm.Highlight)
+		return mm, err
+	})
+	return matches, err
+}
+
+// GetMessage returns a message of a chat of the user. Returns customerrors.ErrNotFound if the user isn't a member
+// of the chat, the message isn't in it or the user deleted it for themselves.
+func (r *ChatRepo) GetMessage(ctx context.Context, userID, chatID, messageID uuid.UUID) (message entity.Message, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_message", start, err)
+	}(time.Now())
+
+	sql := selectMessage + `
+				JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $1
+			WHERE m.chat_id = $2 AND m.id = $3
+				AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.user_id = $1 AND h.message_id = m.id)`
+	message, err = scanMessage(r.pool.QueryRow(ctx, sql, userID, chatID, messageID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return message, err
+}
+
+// EditMessage replaces the content of a message of the sender and marks it edited.
+// Returns customerrors.ErrNotFound if the message isn't theirs or was deleted.
+func (r *ChatRepo) EditMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID, content string) (message entity.Message, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_message", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return entity.Message{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE messages SET content = $4, edited_at = NOW()
+			WHERE id = $3 AND chat_id = $2 AND sender_id = $1 AND deleted_at IS NULL
+			RETURNING id, chat_id, sender_id, content, created_at, edited_at, false, attachment`
+	message, err = scanMessage(tx.QueryRow(ctx, sql, senderID, chatID, messageID, content))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	if err != nil {
+		return entity.Message{}, err
+	}
+	if err = enqueueEvent(ctx, tx, entity.ChatEventMessageEdited, chatID, senderID, messageID); err != nil {
+		return entity.Message{}, err
+	}
+	return message, tx.Commit(ctx)
+}
+
+// DeleteMessage deletes a message of the sender for everyone. The members see a tombstone, the content and
+// the attachment are kept until the message is purged so that the sender can restore it.
+// Returns customerrors.ErrNotFound if the message isn't theirs or was already deleted.
+func (r *ChatRepo) DeleteMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_message", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE messages SET deleted_at = NOW()
+			WHERE id = $3 AND chat_id = $2 AND sender_id = $1 AND deleted_at IS NULL`, senderID, chatID, messageID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	if err = enqueueEvent(ctx, tx, entity.ChatEventMessageDeleted, chatID, senderID, messageID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RestoreMessage undeletes a message of the sender deleted for everyone since the given time and stores its event.
+// Returns customerrors.ErrNotFound if the message isn't theirs, isn't deleted or was deleted earlier.
+func (r *ChatRepo) RestoreMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID, deletedSince time.Time) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("restore_message", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE messages SET deleted_at = NULL
+			WHERE id = $3 AND chat_id = $2 AND sender_id = $1 AND deleted_at >= $4 AND purged_at IS NULL`,
+		senderID, chatID, messageID, deletedSince)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	if err = enqueueEvent(ctx, tx, entity.ChatEventMessageRestored, chatID, senderID, messageID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RemoveMessage deletes a message of the sender for everyone and erases its content and attachment at once,
+// whether or not it was deleted before. Returns the attachment the message had, nil if none, and
+// customerrors.ErrNotFound if the message isn't theirs or was already purged.
+func (r *ChatRepo) RemoveMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID) (attachment *entity.MessageAttachment, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("remove_message", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE messages m SET content = '', attachment = NULL, deleted_at = COALESCE(m.deleted_at, NOW()), purged_at = NOW()
+			FROM (SELECT id, attachment FROM messages WHERE id = $3 FOR UPDATE) old
+			WHERE m.id = old.id AND m.chat_id = $2 AND m.sender_id = $1 AND m.purged_at IS NULL
+			RETURNING old.attachment`
+	err = tx.QueryRow(ctx, sql, senderID, chatID, messageID).Scan(&attachment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = enqueueEvent(ctx, tx, entity.ChatEventMessageDeleted, chatID, senderID, messageID); err != nil {
+		return nil, err
+	}
+	return attachment, tx.Commit(ctx)
+}
+
+// PurgeDeletedMessages erases the content and the attachments of the messages deleted before the given time,
+// leaving their tombstones, and returns the IDs of the purged messages with the attachments they had.
+func (r *ChatRepo) PurgeDeletedMessages(ctx context.Context, deletedBefore time.Time) (purged []entity.Message, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("purge_deleted_messages", start, err)
+	}(time.Now())
+
+	sql := `UPDATE messages m SET content = '', attachment = NULL, purged_at = NOW()
+			FROM (SELECT id, attachment FROM messages WHERE deleted_at < $1 AND purged_at IS NULL FOR UPDATE) old
+			WHERE m.id = old.id
+			RETURNING m.id, old.attachment`
+	rows, err := r.pool.Query(ctx, sql, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	purged, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Message, error) {
+		var m entity.Message
+		err := row.Scan(&m.ID, &m.Attachment)
+		return m, err
+	})
+	return purged, err
+}
+
+// HideMessage deletes a message of a chat of the user for the user only, hiding it again is a no-op.
+// Returns customerrors.ErrNotFound if the user isn't a member of the chat or the message isn't in it.
+func (r *ChatRepo) HideMessage(ctx context.Context, userID, chatID, messageID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_hidden_message", start, err)
+	}(time.Now())
+
+	sql := `WITH msg AS (
+				SELECT m.id FROM messages m
+					JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = $1
+				WHERE m.chat_id = $2 AND m.id = $3
+			),
+			hidden AS (
+				INSERT INTO hidden_messages (user_id, message_id)
+				SELECT $1, id FROM msg
+				ON CONFLICT (user_id, message_id) DO NOTHING
+			)
+			SELECT EXISTS (SELECT 1 FROM msg)`
+	var found bool
+	if err = r.pool.QueryRow(ctx, sql, userID, chatID, messageID).Scan(&found); err != nil {
+		return err
+	}
+	if !found {
+		err = customerrors.ErrNotFound
+	}
+	return err
+}
+
+// RelayOutbox publishes up to limit events of the outbox in the order they happened and deletes the published ones.
+// It stops at the first event publish fails on, which is retried by the next call. Only one relay runs at a time,
+// a call made while another one runs returns right away.
+func (r *ChatRepo) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, event entity.ChatEvent, recipients []uuid.UUID) error) (relayed int, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("relay_chat_outbox", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err = tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxLockID).Scan(&locked); err != nil || !locked {
+		return 0, err
+	}
+
+	type outboxEvent struct {
+		id         int64
+		event      entity.ChatEvent
+		recipients []uuid.UUID
+	}
+	sql := `SELECT o.id, o.event_type, o.chat_id, o.user_id, o.message_id, o.recipients, o.created_at,
+				m.id, m.chat_id, m.sender_id, ` + messageContent + `, m.created_at, m.edited_at, m.deleted_at IS NOT NULL, ` + messageAttachment + `
+			FROM chat_outbox o
+				LEFT JOIN messages m ON m.id = o.message_id
+			ORDER BY o.id
+			LIMIT $1`
+	rows, err := tx.Query(ctx, sql, limit)
+	if err != nil {
+		return 0, err
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (outboxEvent, error) {
+		var e outboxEvent
+		var messageID, msgID, msgChatID, senderID *uuid.UUID
+		var content *string
+		var sentAt, editedAt *time.Time
+		var deleted *bool
+		var attachment *entity.MessageAttachment
+		err := row.Scan(&e.id, &e.event.Type, &e.event.ChatID, &e.event.UserID, &messageID, &e.recipients, &e.event.OccurredAt,
+			&msgID, &msgChatID, &senderID, &content, &sentAt, &editedAt, &deleted, &attachment)
+		if err != nil {
+			return e, err
+		}
+		// new and edited messages carry the message as it is now, deletions only its id
+		if msgID != nil && e.event.Type != entity.ChatEventMessageDeleted {
+			e.event.Message = &entity.Message{
+				ID:         *msgID,
+				ChatID:     *msgChatID,
+				SenderID:   *senderID,
+				Content:    *content,
+				CreatedAt:  *sentAt,
+				EditedAt:   editedAt,
+				Deleted:    *deleted,
+				Attachment: attachment,
+			}
+		} else {
+			e.event.MessageID = messageID
+		}
+		return e, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	published := make([]int64, 0, len(events))
+	var publishErr error
+	for _, e := range events {
+		if publishErr = publish(ctx, e.event, e.recipients); publishErr != nil {
+			break
+		}
+		published = append(published, e.id)
+	}
+	if len(published) > 0 {
+		if _, err = tx.Exec(ctx, "DELETE FROM chat_outbox WHERE id = ANY($1)", published); err != nil {
+			return 0, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return 0, err
+		}
+	}
+	return len(published), publishErr
+}
+
+// enqueueEvent stores an event about a message in the outbox for the current members of its chat, in the
+// transaction of the change the event is about.
+func enqueueEvent(ctx context.Context, tx pgx.Tx, eventType entity.ChatEventType, chatID, userID, messageID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `INSERT INTO chat_outbox (event_type, chat_id, user_id, message_id, recipients)
+			SELECT $1, $2, $3, $4, COALESCE(array_agg(user_id), '{}') FROM chat_members WHERE chat_id = $2`,
+		string(eventType), chatID, userID, messageID)
+	return err
+}
+
+func scanMessage(row pgx.Row) (entity.Message, error) {
+	var m entity.Message
+	err := row.Scan(&m.ID, &m.ChatID, &m.SenderID, &m.Content, &m.CreatedAt, &m.EditedAt, &m.Deleted, &m.Attachment)
+	return m, err
+}
+
+func scanChat(row pgx.Row) (entity.Chat, error) {
+	var c entity.Chat
+	var peerID *uuid.UUID
+	var peer entity.UserCard
+	var peerUsername, peerName, peerAvatar *string
+	var following, followsYou bool
+	var messageID, senderID *uuid.UUID
+	var content *string
+	var sentAt, editedAt *time.Time
+	var deleted *bool
+	var attachment *entity.MessageAttachment
+	err := row.Scan(&c.ID, &c.IsGroup, &c.Title, &c.AvatarURL, &c.Public, &c.MembersCount, &c.UnreadCount,
+		&peerID, &peerUsername, &peerName, &peerAvatar, &following, &followsYou,
+		&messageID, &senderID, &content, &sentAt, &editedAt, &deleted, &attachment, &c.CreatedAt, &c.LastActivityAt)
+	if err != nil {
+		return entity.Chat{}, err
+	}
+	if peerID != nil {
+		peer = entity.UserCard{
+			ID:         *peerID,
+			Username:   *peerUsername,
+			Name:       *peerName,
+			AvatarURL:  *peerAvatar,
+			Following:  following,
+			FollowsYou: followsYou,
+		}
+		c.Peer = &peer
+	}
+	if messageID != nil {
+		c.LastMessage = &entity.Message{
+			ID:         *messageID,
+			ChatID:     c.ID,
+			SenderID:   *senderID,
+			Content:    *content,
+			CreatedAt:  *sentAt,
+			EditedAt:   editedAt,
+			Deleted:    *deleted,
+			Attachment: attachment,
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,93 @@
+package closefriends
+
+import (
+	"context"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type CloseFriendsRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewCloseFriendsRepo(pool *postgres.DB, metrics *metrics.Metrics) *CloseFriendsRepo {
+	return &CloseFriendsRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// AddCloseFriend puts the friend on the user's close friends list, adding twice changes nothing.
+// Returns customerrors.ErrNotFound if the friend doesn't exist or deleted the account.
+func (r *CloseFriendsRepo) AddCloseFriend(ctx context.Context, userID, friendID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_close_friend", start, err)
+	}(time.Now())
+
+	sql := `WITH friend AS (SELECT id FROM users WHERE id = $2 AND deleted_at IS NULL),
+			inserted AS (
+				INSERT INTO close_friends (user_id, friend_id) SELECT $1, id FROM friend
+				ON CONFLICT (user_id, friend_id) DO NOTHING
+			)
+			SELECT EXISTS (SELECT 1 FROM friend)`
+	var exists bool
+	if err = r.pool.QueryRow(ctx, sql, userID, friendID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	return nil
+}
+
+// RemoveCloseFriend takes the friend off the user's close friends list, it is a no-op if they aren't on it.
+func (r *CloseFriendsRepo) RemoveCloseFriend(ctx context.Context, userID, friendID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_close_friend", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM close_friends WHERE user_id = $1 AND friend_id = $2", userID, friendID)
+	return err
+}
+
+// ListCloseFriends returns the active users on the user's close friends list added before the (beforeTime, beforeID)
+// position, with the follow state relative to the user, most recently added first. A zero beforeTime starts from the newest.
+func (r *CloseFriendsRepo) ListCloseFriends(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (entries []entity.CloseFriendEntry, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_close_friends", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = u.id AND f.followee_id = $1),
+				cf.created_at
+			FROM close_friends cf
+				JOIN users u ON u.id = cf.friend_id
+				LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE cf.user_id = $1 AND u.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR (cf.created_at, cf.friend_id) < ($2, $3))
+			ORDER BY cf.created_at DESC, cf.friend_id DESC
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, userID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.CloseFriendEntry, error) {
+		var e entity.CloseFriendEntry
+		err := row.Scan(&e.User.ID, &e.User.Username, &e.User.Name, &e.User.AvatarURL, &e.User.Following, &e.User.FollowsYou, &e.AddedAt)
+		return e, err
+	})
+	return entries, err
+}
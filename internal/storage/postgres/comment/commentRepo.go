@@ -0,0 +1,293 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type CommentRepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewCommentRepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *CommentRepo {
+	return &CommentRepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *CommentRepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+const commentColumns = `id, post_id, user_id, reply_to, content, replies_count, created_at, updated_at`
+
+// CreateComment stores a new comment and bumps the reply counter of the parent in the same transaction, the comment
+// counter of the post is left to the caller. Returns customerrors.ErrNotFound if the post doesn't exist or the parent isn't a comment of it
+// and customerrors.ErrBlockedByUser if the author of either blocked the commenter.
+func (r *CommentRepo) CreateComment(ctx context.Context, comment entity.Comment) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_comment", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the post and the parent are locked, so they can't be deleted before the comment references them
+	var authors []uuid.UUID
+	var authorID uuid.UUID
+	err = tx.QueryRow(ctx, "SELECT user_id FROM posts WHERE id = $1 AND deleted_at IS NULL FOR SHARE", comment.PostID).
+		Scan(&authorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customerrors.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	authors = append(authors, authorID)
+	if comment.ReplyTo != nil {
+		err = tx.QueryRow(ctx, "UPDATE comments SET replies_count = replies_count + 1 WHERE id = $1 AND post_id = $2 AND deleted_at IS NULL RETURNING user_id",
+			*comment.ReplyTo, comment.PostID).Scan(&authorID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customerrors.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		authors = append(authors, authorID)
+	}
+
+	// neither the author of the post nor of the parent comment may have blocked the commenter
+	var blocked bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM blacklist WHERE blocker_id = ANY($1) AND blocked_id = $2)", authors, comment.UserID).
+		Scan(&blocked)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return customerrors.ErrBlockedByUser
+	}
+
+	sql := `INSERT INTO comments (id, post_id, user_id, reply_to, content, limited, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.Exec(ctx, sql,
+		comment.ID, comment.PostID, comment.UserID, comment.ReplyTo, comment.Content, comment.Limited, comment.CreatedAt, comment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UpdateComment replaces the content of a comment of the user and returns the updated comment,
+// customerrors.ErrNoTagsAffected if the user has no such comment.
+func (r *CommentRepo) UpdateComment(ctx context.Context, userID, commentID uuid.UUID, content string) (comment entity.Comment, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_comment", start, err)
+	}(time.Now())
+
+	sql := `UPDATE comments SET content = $3, updated_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+			RETURNING ` + commentColumns
+	comment, err = scanComment(r.pool.QueryRow(ctx, sql, commentID, userID, content))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNoTagsAffected
+	}
+	return comment, err
+}
+
+// DeleteComment marks a comment of the user deleted together with its replies, so they can be restored until they
+// are purged, and decrements the counters of the post and the parent comment. Returns customerrors.ErrNoTagsAffected
+// if the user has no such comment.
+func (r *CommentRepo) DeleteComment(ctx context.Context, userID, commentID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_comment", start, err)
+	}(time.Now())
+
+	// the whole thread is marked with one timestamp, so that restoring the comment brings back the replies
+	// deleted with it but not the ones their authors deleted before
+	sql := `WITH RECURSIVE root AS (SELECT id, post_id, reply_to FROM comments WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL),
+			thread AS (
+				SELECT id FROM root
+				UNION ALL
+				SELECT c.id FROM comments c JOIN thread t ON c.reply_to = t.id WHERE c.deleted_at IS NULL
+			),
+			deleted AS (UPDATE comments SET deleted_at = NOW() WHERE id IN (SELECT id FROM thread) RETURNING id),
+			parent AS (UPDATE comments SET replies_count = replies_count - 1 WHERE id = (SELECT reply_to FROM root)),
+			counted AS (
+				UPDATE posts SET comments_count = comments_count - (SELECT COUNT(*) FROM deleted)
+				WHERE id = (SELECT post_id FROM root)
+			)
+			SELECT COUNT(*) FROM deleted`
+	var deleted int
+	if err = r.pool.QueryRow(ctx, sql, commentID, userID).Scan(&deleted); err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return customerrors.ErrNoTagsAffected
+	}
+	return nil
+}
+
+// RestoreComment undeletes a comment of the user deleted since the given time together with the replies deleted
+// with it and bumps the counters of the post and the parent comment. Returns customerrors.ErrNotFound if the user
+// has no such comment, it was deleted earlier, or its post or parent comment is deleted.
+func (r *CommentRepo) RestoreComment(ctx context.Context, userID, commentID uuid.UUID, deletedSince time.Time) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("restore_comment", start, err)
+	}(time.Now())
+
+	sql := `WITH RECURSIVE root AS (
+				SELECT c.id, c.post_id, c.reply_to, c.deleted_at FROM comments c JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL
+				WHERE c.id = $1 AND c.user_id = $2 AND c.deleted_at >= $3
+					AND NOT EXISTS (SELECT 1 FROM comments parent WHERE parent.id = c.reply_to AND parent.deleted_at IS NOT NULL)
+			),
+			thread AS (
+				SELECT id FROM root
+				UNION ALL
+				SELECT c.id FROM comments c JOIN thread t ON c.reply_to = t.id WHERE c.deleted_at = (SELECT deleted_at FROM root)
+			),
+			restored AS (UPDATE comments SET deleted_at = NULL WHERE id IN (SELECT id FROM thread) RETURNING id),
+			parent AS (UPDATE comments SET replies_count = replies_count + 1 WHERE id = (SELECT reply_to FROM root)),
+			counted AS (
+				UPDATE posts SET comments_count = comments_count + (SELECT COUNT(*) FROM restored)
+				WHERE id = (SELECT post_id FROM root)
+			)
+			SELECT COUNT(*) FROM restored`
+	var restored int
+	if err = r.pool.QueryRow(ctx, sql, commentID, userID, deletedSince).Scan(&restored); err != nil {
+		return err
+	}
+	if restored == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// RemoveComment deletes a comment for good together with its replies, whether or not they were deleted before,
+// and decrements the counters of the post and the parent comment by what was still published.
+// Returns customerrors.ErrNotFound if there is no such comment.
+func (r *CommentRepo) RemoveComment(ctx context.Context, commentID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("remove_comment", start, err)
+	}(time.Now())
+
+	sql := `WITH RECURSIVE root AS (SELECT id, post_id, reply_to, deleted_at FROM comments WHERE id = $1),
+			thread AS (
+				SELECT id, deleted_at FROM root
+				UNION ALL
+				SELECT c.id, c.deleted_at FROM comments c JOIN thread t ON c.reply_to = t.id
+			),
+			removed AS (DELETE FROM comments WHERE id IN (SELECT id FROM thread) RETURNING id),
+			parent AS (UPDATE comments SET replies_count = replies_count - 1 WHERE id = (SELECT reply_to FROM root WHERE deleted_at IS NULL)),
+			counted AS (
+				UPDATE posts SET comments_count = comments_count - (SELECT COUNT(*) FROM thread WHERE deleted_at IS NULL)
+				WHERE id = (SELECT post_id FROM root)
+			)
+			SELECT COUNT(*) FROM removed`
+	var removed int
+	if err = r.pool.QueryRow(ctx, sql, commentID).Scan(&removed); err != nil {
+		return err
+	}
+	if removed == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedComments deletes for good the comments deleted before the given time and returns how many were purged.
+func (r *CommentRepo) PurgeDeletedComments(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("purge_deleted_comments", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM comments WHERE deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetComment returns the comment, customerrors.ErrNotFound if there is none or it is deleted.
+func (r *CommentRepo) GetComment(ctx context.Context, id uuid.UUID) (comment entity.Comment, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_comment", start, err)
+	}(time.Now())
+
+	comment, err = scanComment(r.pool.QueryRow(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = $1 AND deleted_at IS NULL", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return comment, err
+}
+
+// ListComments returns a page of comments of a post, or of replies to a comment, in the order of the filter.
+func (r *CommentRepo) ListComments(ctx context.Context, filter entity.CommentFilter) (comments []entity.Comment, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_comments", start, err)
+	}(time.Now())
+
+	// shadow-limited comments are only listed for their author
+	sql := "SELECT " + commentColumns + " FROM comments WHERE post_id = $1 AND deleted_at IS NULL AND (NOT limited OR user_id = $2)"
+	args := []any{filter.PostID, filter.ViewerID}
+	if filter.ReplyTo != uuid.Nil {
+		args = append(args, filter.ReplyTo)
+		sql += fmt.Sprintf(" AND reply_to = $%d", len(args))
+	} else {
+		sql += " AND reply_to IS NULL"
+	}
+
+	after := !filter.AfterTime.IsZero()
+	switch filter.Sort {
+	case entity.CommentSortOldest:
+		if after {
+			args = append(args, filter.AfterTime, filter.AfterID)
+			sql += fmt.Sprintf(" AND (created_at, id) > ($%d, $%d)", len(args)-1, len(args))
+		}
+		sql += " ORDER BY created_at, id"
+	case entity.CommentSortTop:
+		if after {
+			args = append(args, filter.AfterReplies, filter.AfterTime, filter.AfterID)
+			sql += fmt.Sprintf(" AND (replies_count, created_at, id) < ($%d, $%d, $%d)", len(args)-2, len(args)-1, len(args))
+		}
+		sql += " ORDER BY replies_count DESC, created_at DESC, id DESC"
+	default:
+		if after {
+			args = append(args, filter.AfterTime, filter.AfterID)
+			sql += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+		}
+		sql += " ORDER BY created_at DESC, id DESC"
+	}
+	args = append(args, filter.Limit)
+	sql += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := r.read(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	comments, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Comment, error) {
+		return scanComment(row)
+	})
+	return comments, err
+}
+
+func scanComment(row pgx.Row) (entity.Comment, error) {
+	var c entity.Comment
+	err := row.Scan(&c.ID, &c.PostID, &c.UserID, &c.ReplyTo, &c.Content, &c.RepliesCount, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
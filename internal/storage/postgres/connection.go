@@ -2,18 +2,74 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewPostgresConnection(dbURL string) (*pgxpool.Pool, error) {
+// Options tune the connections of the pool.
+type Options struct {
+	// QueryTimeout makes the server cancel statements running longer, 0 lets them run as long as they take.
+	QueryTimeout time.Duration
+	// QueryExecMode is how statements are run: cache_statement prepares each statement once per connection,
+	// cache_describe, describe_exec, exec and simple_protocol work behind PgBouncer in transaction mode.
+	QueryExecMode string
+	// StatementCacheCapacity is how many prepared statements, or descriptions with cache_describe, each
+	// connection keeps.
+	StatementCacheCapacity int
+	// MaxConns and MinConns bound the size of the pool, 0 keeps the pgx defaults (max(4, CPUs) and 0).
+	MaxConns int32
+	MinConns int32
+	// MaxConnLifetime and MaxConnIdleTime close connections that are older or idle longer, HealthCheckPeriod is
+	// how often that and the minimum size are checked; 0 keeps the pgx defaults.
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+func NewPostgresConnection(dbURL string, opts Options) (*pgxpool.Pool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	config, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
 		return nil, err
 	}
+	config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.QueryTimeout.Milliseconds(), 10)
+	if opts.QueryExecMode != "" {
+		mode, ok := queryExecModes[opts.QueryExecMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown query exec mode %q", opts.QueryExecMode)
+		}
+		config.ConnConfig.DefaultQueryExecMode = mode
+	}
+	if opts.MaxConns > 0 {
+		config.MaxConns = opts.MaxConns
+	}
+	config.MinConns = opts.MinConns
+	if opts.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = opts.HealthCheckPeriod
+	}
+	if opts.StatementCacheCapacity > 0 {
+		config.ConnConfig.StatementCacheCapacity = opts.StatementCacheCapacity
+		config.ConnConfig.DescriptionCacheCapacity = opts.StatementCacheCapacity
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -0,0 +1,119 @@
+package counter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// column is where a counter is stored, the table is keyed by the ID the counter belongs to.
+type column struct {
+	table, key, name string
+}
+
+var columns = map[entity.Counter]column{
+	entity.CounterPostLikes:     {"posts", "id", "likes_count"},
+	entity.CounterPostReposts:   {"posts", "id", "reposts_count"},
+	entity.CounterPostComments:  {"posts", "id", "comments_count"},
+	entity.CounterUserFollowers: {"profiles", "user_id", "followers_count"},
+	entity.CounterUserFollowing: {"profiles", "user_id", "following_count"},
+	entity.CounterLinkClicks:    {"post_links", "id", "clicks"},
+}
+
+type CounterRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewCounterRepo(pool *postgres.DB, metrics *metrics.Metrics) *CounterRepo {
+	return &CounterRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// AddDeltas adds the deltas to the counter of the rows they are keyed by in one statement, counters don't go
+// below zero. Deltas of rows that don't exist anymore are dropped.
+func (r *CounterRepo) AddDeltas(ctx context.Context, counter entity.Counter, deltas map[uuid.UUID]int64) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("add_counter_deltas", start, err)
+	}(time.Now())
+
+	col, ok := columns[counter]
+	if !ok {
+		return fmt.Errorf("unknown counter %q", counter)
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+	// rows are updated in ID order, so concurrent batches lock them in the same order and rarely deadlock
+	ids := make([]uuid.UUID, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	values := make([]int64, len(ids))
+	for i, id := range ids {
+		values[i] = deltas[id]
+	}
+
+	sql := fmt.Sprintf(`UPDATE %[1]s t SET %[3]s = GREATEST(t.%[3]s + d.delta, 0)
+			FROM (SELECT * FROM unnest($1::uuid[], $2::bigint[]) AS d(id, delta) ORDER BY id) d
+			WHERE t.%[2]s = d.id`, col.table, col.key, col.name)
+	_, err = r.pool.Exec(ctx, sql, ids, values)
+	return err
+}
+
+// ReconcilePostCounters recounts likes, reposts, quotes and comments of every post whose counters drifted and returns
+// how many were fixed. Deleted quotes and comments aren't counted.
+func (r *CounterRepo) ReconcilePostCounters(ctx context.Context) (fixed int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("reconcile_post_counters", start, err)
+	}(time.Now())
+
+	sql := `UPDATE posts p SET likes_count = c.likes, reposts_count = c.reposts, quotes_count = c.quotes, comments_count = c.comments
+			FROM (
+				SELECT posts.id,
+					(SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.id) AS likes,
+					(SELECT COUNT(*) FROM reposts WHERE reposts.post_id = posts.id) AS reposts,
+					(SELECT COUNT(*) FROM posts q WHERE q.quote_of_id = posts.id AND q.deleted_at IS NULL) AS quotes,
+					(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.deleted_at IS NULL) AS comments
+				FROM posts
+			) c
+			WHERE p.id = c.id AND (p.likes_count, p.reposts_count, p.quotes_count, p.comments_count)
+				IS DISTINCT FROM (c.likes, c.reposts, c.quotes, c.comments)`
+	tag, err := r.pool.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ReconcileFollowCounters recounts followers and followings of every profile whose counters drifted and returns
+// how many were fixed.
+func (r *CounterRepo) ReconcileFollowCounters(ctx context.Context) (fixed int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("reconcile_follow_counters", start, err)
+	}(time.Now())
+
+	sql := `UPDATE profiles p SET followers_count = c.followers, following_count = c.following
+			FROM (
+				SELECT profiles.user_id,
+					(SELECT COUNT(*) FROM follows WHERE follows.followee_id = profiles.user_id) AS followers,
+					(SELECT COUNT(*) FROM follows WHERE follows.follower_id = profiles.user_id) AS following
+				FROM profiles
+			) c
+			WHERE p.user_id = c.user_id AND (p.followers_count, p.following_count) IS DISTINCT FROM (c.followers, c.following)`
+	tag, err := r.pool.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,183 @@
+package dataimport
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ImportRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewImportRepo(pool *postgres.DB, metrics *metrics.Metrics) *ImportRepo {
+	return &ImportRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+const importColumns = `id, user_id, source, status, follows_total, follows_processed, follows_imported,
+	posts_total, posts_processed, posts_imported, COALESCE(error, ''), created_at, completed_at`
+
+func scanImport(row pgx.Row) (entity.DataImport, error) {
+	var i entity.DataImport
+	err := row.Scan(&i.ID, &i.UserID, &i.Source, &i.Status, &i.Follows.Total, &i.Follows.Processed, &i.Follows.Imported,
+		&i.Posts.Total, &i.Posts.Processed, &i.Posts.Imported, &i.Error, &i.CreatedAt, &i.CompletedAt)
+	return i, err
+}
+
+// CreateImport queues an import of the archive into the user's account. Returns customerrors.ErrImportInProgress
+// if the previous import of the user is still pending or processing.
+func (r *ImportRepo) CreateImport(ctx context.Context, importID, userID uuid.UUID, archive entity.ImportArchive) (imp entity.DataImport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_data_import", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO data_imports (id, user_id, source, archive, follows_total, posts_total)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING ` + importColumns
+	imp, err = scanImport(r.pool.QueryRow(ctx, sql, importID, userID, archive.Source, archive, len(archive.Follows), len(archive.Posts)))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_data_imports_user_active" {
+		err = customerrors.ErrImportInProgress
+	}
+	return imp, err
+}
+
+// GetImport returns a data import of the user, customerrors.ErrNotFound if the user has no such import.
+func (r *ImportRepo) GetImport(ctx context.Context, userID, importID uuid.UUID) (imp entity.DataImport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_data_import", start, err)
+	}(time.Now())
+
+	sql := `SELECT ` + importColumns + ` FROM data_imports WHERE id = $1 AND user_id = $2`
+	imp, err = scanImport(r.pool.QueryRow(ctx, sql, importID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return imp, err
+}
+
+// ClaimImports marks up to limit pending imports as processing, oldest first, and returns them. Imports whose
+// progress wasn't saved since staleBefore are claimed again, the job processing them is assumed to have died.
+// Concurrent jobs claim different imports.
+func (r *ImportRepo) ClaimImports(ctx context.Context, staleBefore time.Time, limit int) (imports []entity.DataImport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("claim_data_imports", start, err)
+	}(time.Now())
+
+	sql := `WITH claimed AS (
+				SELECT id FROM data_imports
+				WHERE status = 'pending' OR (status = 'processing' AND updated_at < $1)
+				ORDER BY created_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			UPDATE data_imports i SET status = 'processing', updated_at = NOW()
+			FROM claimed
+			WHERE i.id = claimed.id
+			RETURNING i.id, i.user_id, i.source, i.status, i.follows_total, i.follows_processed, i.follows_imported,
+				i.posts_total, i.posts_processed, i.posts_imported, COALESCE(i.error, ''), i.created_at, i.completed_at`
+	rows, err := r.pool.Query(ctx, sql, staleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.DataImport, error) {
+		return scanImport(row)
+	})
+}
+
+// GetArchive returns the follows and posts of an import that is still being applied,
+// customerrors.ErrNotFound once it ended.
+func (r *ImportRepo) GetArchive(ctx context.Context, importID uuid.UUID) (archive entity.ImportArchive, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_data_import_archive", start, err)
+	}(time.Now())
+
+	err = r.pool.QueryRow(ctx, "SELECT archive FROM data_imports WHERE id = $1 AND archive IS NOT NULL", importID).Scan(&archive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return archive, err
+}
+
+// SaveProgress records how far the import got, which also keeps it from being claimed again as abandoned.
+func (r *ImportRepo) SaveProgress(ctx context.Context, importID uuid.UUID, follows, posts entity.ImportProgress) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_data_import_progress", start, err)
+	}(time.Now())
+
+	sql := `UPDATE data_imports SET follows_processed = $2, follows_imported = $3, posts_processed = $4, posts_imported = $5,
+				updated_at = NOW()
+			WHERE id = $1`
+	_, err = r.pool.Exec(ctx, sql, importID, follows.Processed, follows.Imported, posts.Processed, posts.Imported)
+	return err
+}
+
+// CompleteImport marks the import completed and drops its archive.
+func (r *ImportRepo) CompleteImport(ctx context.Context, importID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("complete_data_import", start, err)
+	}(time.Now())
+
+	sql := `UPDATE data_imports SET status = 'completed', archive = NULL, updated_at = NOW(), completed_at = NOW() WHERE id = $1`
+	_, err = r.pool.Exec(ctx, sql, importID)
+	return err
+}
+
+// FailImport marks the import failed with the reason and drops its archive.
+func (r *ImportRepo) FailImport(ctx context.Context, importID uuid.UUID, reason string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("fail_data_import", start, err)
+	}(time.Now())
+
+	sql := `UPDATE data_imports SET status = 'failed', error = $2, archive = NULL, updated_at = NOW(), completed_at = NOW() WHERE id = $1`
+	_, err = r.pool.Exec(ctx, sql, importID, reason)
+	return err
+}
+
+// FindUsers returns the IDs of the active users with any of the usernames or emails, keyed by the lowercase
+// username and the lowercase email of each user found. Both are matched case-insensitively.
+func (r *ImportRepo) FindUsers(ctx context.Context, usernames, emails []string) (users map[string]uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_import_users", start, err)
+	}(time.Now())
+
+	sql := `SELECT id, LOWER(username), LOWER(email) FROM users
+			WHERE deleted_at IS NULL AND (LOWER(username) = ANY($1) OR LOWER(email) = ANY($2))`
+	rows, err := r.pool.Query(ctx, sql, lower(usernames), lower(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users = make(map[string]uuid.UUID)
+	for rows.Next() {
+		var id uuid.UUID
+		var username, email string
+		if err = rows.Scan(&id, &username, &email); err != nil {
+			return nil, err
+		}
+		users[username], users[email] = id, id
+	}
+	return users, rows.Err()
+}
+
+func lower(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(v)
+	}
+	return lowered
+}
@@ -0,0 +1,148 @@
+package developer
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type DeveloperRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewDeveloperRepo(pool *postgres.DB, metrics *metrics.Metrics) *DeveloperRepo {
+	return &DeveloperRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+const selectApp = `SELECT id, owner_id, name, description, client_id, scopes, COALESCE(rate_limit, 0), created_at, last_used_at
+		FROM developer_apps`
+
+// CreateApp stores a new app with the hash of its client secret.
+func (r *DeveloperRepo) CreateApp(ctx context.Context, app entity.DeveloperApp, secretHash []byte) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_developer_app", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO developer_apps (id, owner_id, name, description, client_id, secret_hash, scopes, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = r.pool.Exec(ctx, sql, app.ID, app.OwnerID, app.Name, app.Description, app.ClientID, secretHash, app.Scopes, app.CreatedAt)
+	return err
+}
+
+// CountApps returns how many apps the user owns.
+func (r *DeveloperRepo) CountApps(ctx context.Context, ownerID uuid.UUID) (count int, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("count_developer_apps", start, err)
+	}(time.Now())
+
+	err = r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM developer_apps WHERE owner_id = $1", ownerID).Scan(&count)
+	return count, err
+}
+
+// ListApps returns the apps of the user, newest first.
+func (r *DeveloperRepo) ListApps(ctx context.Context, ownerID uuid.UUID) (apps []entity.DeveloperApp, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_developer_apps", start, err)
+	}(time.Now())
+
+	rows, err := r.pool.Query(ctx, selectApp+" WHERE owner_id = $1 ORDER BY created_at DESC", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	apps, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.DeveloperApp, error) {
+		return scanApp(row)
+	})
+	return apps, err
+}
+
+// GetApp returns the app of the owner, customerrors.ErrNotFound if the owner has no such app.
+func (r *DeveloperRepo) GetApp(ctx context.Context, ownerID, appID uuid.UUID) (app entity.DeveloperApp, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_developer_app", start, err)
+	}(time.Now())
+
+	app, err = scanApp(r.pool.QueryRow(ctx, selectApp+" WHERE id = $1 AND owner_id = $2", appID, ownerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return app, err
+}
+
+// GetAppByClientID returns the app with the client ID and the hash of its secret, customerrors.ErrInvalidToken if
+// there is none.
+func (r *DeveloperRepo) GetAppByClientID(ctx context.Context, clientID string) (app entity.DeveloperApp, secretHash []byte, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_developer_app_by_client_id", start, err)
+	}(time.Now())
+
+	sql := `SELECT id, owner_id, name, description, client_id, scopes, COALESCE(rate_limit, 0), created_at, last_used_at, secret_hash
+			FROM developer_apps WHERE client_id = $1`
+	err = r.pool.QueryRow(ctx, sql, clientID).Scan(append(appFields(&app), &secretHash)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrInvalidToken
+	}
+	return app, secretHash, err
+}
+
+// UpdateSecret replaces the secret hash of the owner's app, customerrors.ErrNotFound if the owner has no such app.
+func (r *DeveloperRepo) UpdateSecret(ctx context.Context, ownerID, appID uuid.UUID, secretHash []byte) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_developer_app_secret", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "UPDATE developer_apps SET secret_hash = $3 WHERE id = $1 AND owner_id = $2", appID, ownerID, secretHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteApp deletes the owner's app, customerrors.ErrNotFound if the owner has no such app.
+func (r *DeveloperRepo) DeleteApp(ctx context.Context, ownerID, appID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_developer_app", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM developer_apps WHERE id = $1 AND owner_id = $2", appID, ownerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// TouchApp records that the app was just issued a token.
+func (r *DeveloperRepo) TouchApp(ctx context.Context, appID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("touch_developer_app", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "UPDATE developer_apps SET last_used_at = NOW() WHERE id = $1", appID)
+	return err
+}
+
+func appFields(a *entity.DeveloperApp) []any {
+	return []any{&a.ID, &a.OwnerID, &a.Name, &a.Description, &a.ClientID, &a.Scopes, &a.RateLimit, &a.CreatedAt, &a.LastUsedAt}
+}
+
+func scanApp(row pgx.Row) (entity.DeveloperApp, error) {
+	var a entity.DeveloperApp
+	err := row.Scan(appFields(&a)...)
+	return a, err
+}
@@ -0,0 +1,137 @@
+package digest
+
+import (
+	"context"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type DigestRepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewDigestRepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *DigestRepo {
+	return &DigestRepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *DigestRepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+// digestPeriod is the time between two digests of the frequency in settings row s.
+const digestPeriod = `CASE s.email_digest WHEN 'daily' THEN INTERVAL '1 day' ELSE INTERVAL '7 days' END`
+
+// ClaimDue returns up to limit active users whose digest is due and marks it sent, so concurrent jobs take different
+// users. Only the recipient fields of the digests are filled, Since is the previous digest or one period ago
+// for the first one.
+func (r *DigestRepo) ClaimDue(ctx context.Context, limit int) (digests []entity.Digest, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("claim_due_digests", start, err)
+	}(time.Now())
+
+	sql := `WITH due AS (
+				SELECT s.user_id, s.digest_sent_at
+				FROM user_settings s
+					JOIN users u ON u.id = s.user_id
+				WHERE s.email_digest <> 'off'
+					AND (s.digest_sent_at IS NULL OR s.digest_sent_at <= NOW() - ` + digestPeriod + `)
+					AND u.deleted_at IS NULL AND NOT COALESCE(u.is_blocked, FALSE)
+				ORDER BY s.digest_sent_at NULLS FIRST
+				LIMIT $1
+				FOR UPDATE OF s SKIP LOCKED
+			)
+			UPDATE user_settings s SET digest_sent_at = NOW()
+			FROM due, users u
+			WHERE s.user_id = due.user_id AND u.id = due.user_id
+			RETURNING s.user_id, u.email, u.username, s.email_digest,
+				COALESCE(due.digest_sent_at, NOW() - ` + digestPeriod + `)`
+	rows, err := r.pool.Query(ctx, sql, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Digest, error) {
+		var d entity.Digest
+		err := row.Scan(&d.UserID, &d.Email, &d.Username, &d.Frequency, &d.Since)
+		return d, err
+	})
+}
+
+// NewFollowers returns up to limit of the latest accounts that followed the user after since and how many there are.
+func (r *DigestRepo) NewFollowers(ctx context.Context, userID uuid.UUID, since time.Time, limit int) (followers []entity.UserCard, total int, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_digest_followers", start, err)
+	}(time.Now())
+
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f2 WHERE f2.follower_id = $1 AND f2.followee_id = u.id),
+				COUNT(*) OVER ()
+			FROM follows f
+				JOIN users u ON u.id = f.follower_id AND u.deleted_at IS NULL
+				LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE f.followee_id = $1 AND f.created_at > $2
+			ORDER BY f.created_at DESC
+			LIMIT $3`
+	rows, err := r.read(ctx).Query(ctx, sql, userID, since, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	followers, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.UserCard, error) {
+		c := entity.UserCard{FollowsYou: true}
+		err := row.Scan(&c.ID, &c.Username, &c.Name, &c.AvatarURL, &c.Following, &total)
+		return c, err
+	})
+	return followers, total, err
+}
+
+// TopPosts returns up to limit of the most liked posts the accounts the user follows published after since
+// and the user may see.
+func (r *DigestRepo) TopPosts(ctx context.Context, userID uuid.UUID, since time.Time, limit int) (posts []entity.DigestPost, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_digest_posts", start, err)
+	}(time.Now())
+
+	// following the author makes public and followers posts visible
+	sql := `SELECT posts.id, u.username, posts.description, posts.likes_count, posts.comments_count
+			FROM follows f
+				JOIN posts ON posts.user_id = f.followee_id AND posts.deleted_at IS NULL
+				JOIN users u ON u.id = posts.user_id AND u.deleted_at IS NULL
+			WHERE f.follower_id = $1 AND posts.created_at > $2
+				AND (posts.visibility IN ('public', 'followers')
+					OR posts.visibility = 'close_friends'
+						AND EXISTS (SELECT 1 FROM close_friends cf WHERE cf.user_id = posts.user_id AND cf.friend_id = $1))
+				AND NOT EXISTS (SELECT 1 FROM blacklist b WHERE b.blocker_id = posts.user_id AND b.blocked_id = $1)
+			ORDER BY posts.likes_count DESC, posts.created_at DESC
+			LIMIT $3`
+	rows, err := r.read(ctx).Query(ctx, sql, userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.DigestPost, error) {
+		var p entity.DigestPost
+		err := row.Scan(&p.ID, &p.AuthorUsername, &p.Description, &p.LikesCount, &p.CommentsCount)
+		return p, err
+	})
+}
+
+// CountUnread returns the number of unread notifications of the user.
+func (r *DigestRepo) CountUnread(ctx context.Context, userID uuid.UUID) (count int, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("count_digest_unread", start, err)
+	}(time.Now())
+
+	err = r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// relayLockID is the advisory lock held while relaying the outbox, so one relay at a time publishes the events
+// in the order they happened.
+const relayLockID = 7502
+
+type EventsRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewEventsRepo(pool *postgres.DB, metrics *metrics.Metrics) *EventsRepo {
+	return &EventsRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// Enqueue stores a domain event in the outbox in the transaction of the change the event is about, data is
+// encoded as the JSON payload of the event.
+func Enqueue(ctx context.Context, tx pgx.Tx, eventType entity.DomainEventType, key uuid.UUID, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO event_outbox (event_id, event_type, event_key, payload) VALUES ($1, $2, $3, $4)",
+		uuid.New(), string(eventType), key, payload)
+	return err
+}
+
+// RelayOutbox hands up to limit events of the outbox to publish in the order they happened and deletes them once
+// publish succeeds; if it fails, the events stay for the next call. Only one relay runs at a time, a call made
+// while another one runs returns right away.
+func (r *EventsRepo) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []entity.DomainEvent) error) (relayed int, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("relay_event_outbox", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err = tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", relayLockID).Scan(&locked); err != nil || !locked {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT id, event_id, event_type, event_key, payload, created_at FROM event_outbox ORDER BY id LIMIT $1", limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.DomainEvent, error) {
+		var id int64
+		var e entity.DomainEvent
+		err := row.Scan(&id, &e.ID, &e.Type, &e.Key, &e.Data, &e.OccurredAt)
+		ids = append(ids, id)
+		return e, err
+	})
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	if err = publish(ctx, events); err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(ctx, "DELETE FROM event_outbox WHERE id = ANY($1)", ids); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
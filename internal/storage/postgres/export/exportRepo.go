@@ -0,0 +1,255 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ExportRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewExportRepo(pool *postgres.DB, metrics *metrics.Metrics) *ExportRepo {
+	return &ExportRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+const exportColumns = `id, user_id, status, COALESCE(object_key, ''), size, created_at, completed_at, expires_at`
+
+func scanExport(row pgx.Row) (entity.DataExport, error) {
+	var e entity.DataExport
+	err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.ObjectKey, &e.Size, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt)
+	return e, err
+}
+
+// CreateExport queues a data export of the user. Returns customerrors.ErrExportInProgress if the previous export
+// of the user is still pending or processing.
+func (r *ExportRepo) CreateExport(ctx context.Context, exportID, userID uuid.UUID) (export entity.DataExport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_data_export", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO data_exports (id, user_id) VALUES ($1, $2) RETURNING ` + exportColumns
+	export, err = scanExport(r.pool.QueryRow(ctx, sql, exportID, userID))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_data_exports_user_active" {
+		err = customerrors.ErrExportInProgress
+	}
+	return export, err
+}
+
+// GetExport returns a data export of the user, customerrors.ErrNotFound if the user has no such export.
+func (r *ExportRepo) GetExport(ctx context.Context, userID, exportID uuid.UUID) (export entity.DataExport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_data_export", start, err)
+	}(time.Now())
+
+	sql := `SELECT ` + exportColumns + ` FROM data_exports WHERE id = $1 AND user_id = $2`
+	export, err = scanExport(r.pool.QueryRow(ctx, sql, exportID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return export, err
+}
+
+// ClaimExports marks up to limit pending exports as processing, oldest first, and returns them. Exports that started
+// processing before staleBefore are claimed again, the job processing them is assumed to have died.
+// Concurrent jobs claim different exports.
+func (r *ExportRepo) ClaimExports(ctx context.Context, staleBefore time.Time, limit int) (exports []entity.DataExport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("claim_data_exports", start, err)
+	}(time.Now())
+
+	sql := `WITH claimed AS (
+				SELECT id FROM data_exports
+				WHERE status = 'pending' OR (status = 'processing' AND started_at < $1)
+				ORDER BY created_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			UPDATE data_exports e SET status = 'processing', started_at = NOW()
+			FROM claimed
+			WHERE e.id = claimed.id
+			RETURNING e.id, e.user_id, e.status, COALESCE(e.object_key, ''), e.size, e.created_at, e.completed_at, e.expires_at`
+	rows, err := r.pool.Query(ctx, sql, staleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.DataExport, error) {
+		return scanExport(row)
+	})
+}
+
+// CompleteExport marks the export ready with its archive, which is kept until expiresAt.
+func (r *ExportRepo) CompleteExport(ctx context.Context, exportID uuid.UUID, objectKey string, size int64, expiresAt time.Time) (export entity.DataExport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("complete_data_export", start, err)
+	}(time.Now())
+
+	sql := `UPDATE data_exports SET status = 'ready', object_key = $2, size = $3, completed_at = NOW(), expires_at = $4
+			WHERE id = $1
+			RETURNING ` + exportColumns
+	export, err = scanExport(r.pool.QueryRow(ctx, sql, exportID, objectKey, size, expiresAt))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return export, err
+}
+
+// FailExport marks the export failed with the reason.
+func (r *ExportRepo) FailExport(ctx context.Context, exportID uuid.UUID, reason string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("fail_data_export", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW() WHERE id = $1", exportID, reason)
+	return err
+}
+
+// ExpiredExports returns up to limit ready exports whose archive expired before the given time.
+func (r *ExportRepo) ExpiredExports(ctx context.Context, before time.Time, limit int) (exports []entity.DataExport, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_expired_data_exports", start, err)
+	}(time.Now())
+
+	sql := `SELECT ` + exportColumns + ` FROM data_exports WHERE expires_at < $1 ORDER BY expires_at LIMIT $2`
+	rows, err := r.pool.Query(ctx, sql, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.DataExport, error) {
+		return scanExport(row)
+	})
+}
+
+// DeleteExport deletes the record of an export, its archive has to be deleted before.
+func (r *ExportRepo) DeleteExport(ctx context.Context, exportID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_data_export", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM data_exports WHERE id = $1", exportID)
+	return err
+}
+
+// GetUserData collects the data of the user that goes into an export: the account with its profile, the posts,
+// comments and messages the user published and not deleted, and the active sessions. The queries run in one
+// repeatable read transaction, so the parts are consistent with each other.
+// Returns customerrors.ErrNotFound if there is no such user.
+func (r *ExportRepo) GetUserData(ctx context.Context, userID uuid.UUID) (data entity.UserData, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_user_data", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return data, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err = tx.Exec(ctx, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+		return data, err
+	}
+
+	account, profile := &data.Account, &data.Profile
+	var age *int16
+	err = tx.QueryRow(ctx, `SELECT u.id, u.username, u.email, ARRAY(SELECT role FROM user_roles WHERE user_id = u.id ORDER BY role),
+				u.created_at, COALESCE(p.name, ''), COALESCE(p.bio, ''), COALESCE(p.avatar_url, ''), COALESCE(p.gender, ''), p.age,
+				COALESCE(p.followers_count, 0), COALESCE(p.following_count, 0), COALESCE(s.private_account, FALSE),
+				COALESCE(p.updated_at, u.created_at)
+			FROM users u
+				LEFT JOIN profiles p ON p.user_id = u.id
+				LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE u.id = $1`, userID).Scan(&account.ID, &account.Username, &account.Email, &account.Roles, &account.CreatedAt,
+		&profile.Name, &profile.Bio, &profile.AvatarURL, &profile.Gender, &age,
+		&profile.FollowersCount, &profile.FollowingCount, &profile.IsPrivate, &profile.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+		return data, err
+	}
+	if err != nil {
+		return data, err
+	}
+	profile.UserID, profile.Username = account.ID, account.Username
+	if age != nil {
+		a := int(*age)
+		profile.Age = &a
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id, user_id, description, COALESCE(media_url, ''), is_video, duration, quote_of_id, visibility,
+				likes_count, reposts_count, quotes_count, comments_count, created_at, updated_at,
+				ARRAY(SELECT h.tag FROM post_hashtags ph JOIN hashtags h ON h.id = ph.hashtag_id WHERE ph.post_id = posts.id ORDER BY h.tag)
+			FROM posts WHERE user_id = $1 AND deleted_at IS NULL
+			ORDER BY created_at, id`, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Posts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Post, error) {
+		var p entity.Post
+		err := row.Scan(&p.ID, &p.UserID, &p.Description, &p.MediaURL, &p.IsVideo, &p.Duration, &p.QuoteOfID, &p.Visibility,
+			&p.LikesCount, &p.RepostsCount, &p.QuotesCount, &p.CommentsCount, &p.CreatedAt, &p.UpdatedAt, &p.Hashtags)
+		return p, err
+	})
+	if err != nil {
+		return data, err
+	}
+
+	rows, err = tx.Query(ctx, `SELECT id, post_id, user_id, reply_to, content, replies_count, created_at, updated_at
+			FROM comments WHERE user_id = $1 AND deleted_at IS NULL
+			ORDER BY created_at, id`, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Comments, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Comment, error) {
+		var c entity.Comment
+		err := row.Scan(&c.ID, &c.PostID, &c.UserID, &c.ReplyTo, &c.Content, &c.RepliesCount, &c.CreatedAt, &c.UpdatedAt)
+		return c, err
+	})
+	if err != nil {
+		return data, err
+	}
+
+	rows, err = tx.Query(ctx, `SELECT id, chat_id, sender_id, content, created_at, edited_at, attachment
+			FROM messages WHERE sender_id = $1 AND deleted_at IS NULL
+			ORDER BY created_at, id`, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Messages, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Message, error) {
+		var m entity.Message
+		err := row.Scan(&m.ID, &m.ChatID, &m.SenderID, &m.Content, &m.CreatedAt, &m.EditedAt, &m.Attachment)
+		return m, err
+	})
+	if err != nil {
+		return data, err
+	}
+
+	rows, err = tx.Query(ctx, `SELECT id, user_id, created_at, expires_at, COALESCE(user_agent, ''), ip_address,
+				COALESCE(is_suspicious, FALSE), COALESCE(device_name, ''), COALESCE(country_code, ''), COALESCE(city, '')
+			FROM sessions WHERE user_id = $1 AND expires_at > NOW()
+			ORDER BY created_at, id`, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Sessions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Session, error) {
+		var s entity.Session
+		err := row.Scan(&s.ID, &s.UserID, &s.CreatedAt, &s.ExpiresAt, &s.UserAgent, &s.ClientIP, &s.IsSuspicious, &s.DeviceName,
+			&s.CountryCode, &s.City)
+		return s, err
+	})
+	if err != nil {
+		return data, err
+	}
+	return data, tx.Commit(ctx)
+}
@@ -0,0 +1,308 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type FederationRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewFederationRepo(pool *postgres.DB, metrics *metrics.Metrics) *FederationRepo {
+	return &FederationRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// federated is the condition for the users that federate: accounts that aren't deleted, private or hidden from
+// anyone. The users table is u, their settings s.
+const federated = `u.deleted_at IS NULL AND NOT COALESCE(s.private_account, FALSE) AND COALESCE(s.privacy_level, 'everyone') = 'everyone'`
+
+const selectProfile = `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.bio, ''), COALESCE(p.avatar_url, ''),
+			COALESCE(p.followers_count, 0) + (SELECT COUNT(*) FROM remote_followers rf WHERE rf.user_id = u.id),
+			COALESCE(p.following_count, 0), COALESCE(p.updated_at, u.created_at)
+		FROM users u
+			LEFT JOIN profiles p ON p.user_id = u.id
+			LEFT JOIN user_settings s ON s.user_id = u.id
+		WHERE ` + federated
+
+func scanProfile(row pgx.Row) (entity.Profile, error) {
+	var p entity.Profile
+	err := row.Scan(&p.UserID, &p.Username, &p.Name, &p.Bio, &p.AvatarURL, &p.FollowersCount, &p.FollowingCount, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return p, err
+}
+
+// GetProfile returns the profile of the federated user, with the remote followers counted among the followers.
+// Returns customerrors.ErrNotFound if there is no such user or they don't federate.
+func (r *FederationRepo) GetProfile(ctx context.Context, userID uuid.UUID) (profile entity.Profile, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_federated_profile", start, err)
+	}(time.Now())
+
+	profile, err = scanProfile(r.pool.QueryRow(ctx, selectProfile+" AND u.id = $1", userID))
+	return profile, err
+}
+
+// GetProfileByUsername returns the profile of the federated user with the username, matched case-insensitively.
+func (r *FederationRepo) GetProfileByUsername(ctx context.Context, username string) (profile entity.Profile, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_federated_profile_by_username", start, err)
+	}(time.Now())
+
+	profile, err = scanProfile(r.pool.QueryRow(ctx, selectProfile+" AND LOWER(u.username) = LOWER($1)", username))
+	return profile, err
+}
+
+// selectPublicPost selects the posts that federate: public posts of federated users that aren't deleted or limited.
+const selectPublicPost = `SELECT posts.id, posts.user_id, posts.description, COALESCE(posts.media_url, ''), posts.is_video,
+			posts.created_at, posts.updated_at
+		FROM posts
+			JOIN users u ON u.id = posts.user_id
+			LEFT JOIN user_settings s ON s.user_id = u.id
+		WHERE posts.deleted_at IS NULL AND posts.visibility = 'public' AND NOT posts.limited AND ` + federated
+
+func scanPublicPost(row pgx.Row) (entity.Post, error) {
+	var p entity.Post
+	err := row.Scan(&p.ID, &p.UserID, &p.Description, &p.MediaURL, &p.IsVideo, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// GetPublicPost returns a post that federates, customerrors.ErrNotFound if there is none.
+func (r *FederationRepo) GetPublicPost(ctx context.Context, postID uuid.UUID) (post entity.Post, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_federated_post", start, err)
+	}(time.Now())
+
+	post, err = scanPublicPost(r.pool.QueryRow(ctx, selectPublicPost+" AND posts.id = $1", postID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return post, err
+}
+
+// ListPublicPosts returns the posts of the user that federate, newest first. Only posts older than the
+// (beforeTime, beforeID) position are returned unless beforeTime is zero.
+func (r *FederationRepo) ListPublicPosts(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (posts []entity.Post, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_federated_posts", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := selectPublicPost + ` AND posts.user_id = $1 AND ($2::timestamptz IS NULL OR (posts.created_at, posts.id) < ($2, $3))
+			ORDER BY posts.created_at DESC, posts.id DESC
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, userID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Post, error) {
+		return scanPublicPost(row)
+	})
+}
+
+// CountPublicPosts returns how many posts of the user federate.
+func (r *FederationRepo) CountPublicPosts(ctx context.Context, userID uuid.UUID) (count int, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("count_federated_posts", start, err)
+	}(time.Now())
+
+	sql := `SELECT COUNT(*) FROM posts WHERE user_id = $1 AND deleted_at IS NULL AND visibility = 'public' AND NOT limited`
+	err = r.pool.QueryRow(ctx, sql, userID).Scan(&count)
+	return count, err
+}
+
+// GetKey returns the PEM encoded key pair of the user, customerrors.ErrNotFound if none was made yet.
+func (r *FederationRepo) GetKey(ctx context.Context, userID uuid.UUID) (privateKey, publicKey string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_federation_key", start, err)
+	}(time.Now())
+
+	err = r.pool.QueryRow(ctx, "SELECT private_key, public_key FROM federation_keys WHERE user_id = $1", userID).Scan(&privateKey, &publicKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return privateKey, publicKey, err
+}
+
+// CreateKey stores the key pair of the user unless one was stored meanwhile, and returns the stored one.
+func (r *FederationRepo) CreateKey(ctx context.Context, userID uuid.UUID, privateKey, publicKey string) (storedPrivate, storedPublic string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_federation_key", start, err)
+	}(time.Now())
+
+	sql := `WITH inserted AS (
+				INSERT INTO federation_keys (user_id, private_key, public_key) VALUES ($1, $2, $3)
+				ON CONFLICT (user_id) DO NOTHING
+				RETURNING private_key, public_key
+			)
+			SELECT private_key, public_key FROM inserted
+			UNION ALL
+			SELECT private_key, public_key FROM federation_keys WHERE user_id = $1
+			LIMIT 1`
+	err = r.pool.QueryRow(ctx, sql, userID, privateKey, publicKey).Scan(&storedPrivate, &storedPublic)
+	return storedPrivate, storedPublic, err
+}
+
+// GetRemoteActor returns the cached remote actor, customerrors.ErrNotFound if it isn't cached.
+func (r *FederationRepo) GetRemoteActor(ctx context.Context, actorID string) (actor entity.RemoteActor, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_remote_actor", start, err)
+	}(time.Now())
+
+	sql := `SELECT id, username, inbox, COALESCE(shared_inbox, ''), key_id, public_key, fetched_at FROM remote_actors WHERE id = $1`
+	err = r.pool.QueryRow(ctx, sql, actorID).Scan(&actor.ID, &actor.Username, &actor.Inbox, &actor.SharedInbox, &actor.KeyID,
+		&actor.PublicKeyPEM, &actor.FetchedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return actor, err
+}
+
+// SaveRemoteActor caches the remote actor, replacing what was cached before.
+func (r *FederationRepo) SaveRemoteActor(ctx context.Context, actor entity.RemoteActor) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("upsert_remote_actor", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO remote_actors (id, username, inbox, shared_inbox, key_id, public_key, fetched_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+			ON CONFLICT (id) DO UPDATE SET username = EXCLUDED.username, inbox = EXCLUDED.inbox, shared_inbox = EXCLUDED.shared_inbox,
+				key_id = EXCLUDED.key_id, public_key = EXCLUDED.public_key, fetched_at = EXCLUDED.fetched_at`
+	_, err = r.pool.Exec(ctx, sql, actor.ID, actor.Username, actor.Inbox, actor.SharedInbox, actor.KeyID, actor.PublicKeyPEM, actor.FetchedAt)
+	return err
+}
+
+// DeleteRemoteActor forgets a remote actor and its follows.
+func (r *FederationRepo) DeleteRemoteActor(ctx context.Context, actorID string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_remote_actor", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM remote_actors WHERE id = $1", actorID)
+	return err
+}
+
+// AddRemoteFollower records that the remote actor follows the user with the Follow activity, following again
+// replaces the activity.
+func (r *FederationRepo) AddRemoteFollower(ctx context.Context, userID uuid.UUID, actorID, followID string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_remote_follower", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO remote_followers (user_id, actor_id, follow_id) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, actor_id) DO UPDATE SET follow_id = EXCLUDED.follow_id`
+	_, err = r.pool.Exec(ctx, sql, userID, actorID, followID)
+	return err
+}
+
+// RemoveRemoteFollower removes the remote actor from the followers of the user.
+func (r *FederationRepo) RemoveRemoteFollower(ctx context.Context, userID uuid.UUID, actorID string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_remote_follower", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM remote_followers WHERE user_id = $1 AND actor_id = $2", userID, actorID)
+	return err
+}
+
+// FollowerInboxes returns the inboxes the activities of the user are delivered to, the shared inbox of a server
+// once instead of the inboxes of each of its actors.
+func (r *FederationRepo) FollowerInboxes(ctx context.Context, userID uuid.UUID) (inboxes []string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_follower_inboxes", start, err)
+	}(time.Now())
+
+	sql := `SELECT DISTINCT COALESCE(a.shared_inbox, a.inbox)
+			FROM remote_followers f JOIN remote_actors a ON a.id = f.actor_id
+			WHERE f.user_id = $1`
+	rows, err := r.pool.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// EnqueueDeliveries queues the activity of the user for delivery to each of the inboxes.
+func (r *FederationRepo) EnqueueDeliveries(ctx context.Context, userID uuid.UUID, inboxes []string, activity []byte) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_federation_deliveries", start, err)
+	}(time.Now())
+
+	ids := make([]uuid.UUID, len(inboxes))
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	sql := `INSERT INTO federation_deliveries (id, user_id, inbox, activity)
+			SELECT id, $2, inbox, $4 FROM unnest($1::uuid[], $3::text[]) AS d(id, inbox)`
+	_, err = r.pool.Exec(ctx, sql, ids, userID, inboxes, string(activity))
+	return err
+}
+
+// ClaimDeliveries returns up to limit deliveries that are due, oldest first, and postpones them by lease so
+// concurrent jobs claim different ones and a delivery whose job died is tried again after the lease.
+func (r *FederationRepo) ClaimDeliveries(ctx context.Context, lease time.Duration, limit int) (deliveries []entity.FederationDelivery, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("claim_federation_deliveries", start, err)
+	}(time.Now())
+
+	sql := `WITH claimed AS (
+				SELECT id FROM federation_deliveries
+				WHERE next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			UPDATE federation_deliveries d SET next_attempt_at = NOW() + $1::interval
+			FROM claimed
+			WHERE d.id = claimed.id
+			RETURNING d.id, d.user_id, d.inbox, d.activity::text, d.attempts`
+	rows, err := r.pool.Query(ctx, sql, lease, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.FederationDelivery, error) {
+		var d entity.FederationDelivery
+		var activity string
+		err := row.Scan(&d.ID, &d.UserID, &d.Inbox, &activity, &d.Attempts)
+		d.Activity = []byte(activity)
+		return d, err
+	})
+}
+
+// DeleteDelivery removes a delivery that succeeded or is given up.
+func (r *FederationRepo) DeleteDelivery(ctx context.Context, deliveryID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_federation_delivery", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM federation_deliveries WHERE id = $1", deliveryID)
+	return err
+}
+
+// RetryDelivery records a failed attempt of the delivery with the reason and schedules the next one.
+func (r *FederationRepo) RetryDelivery(ctx context.Context, deliveryID uuid.UUID, nextAttemptAt time.Time, reason string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("retry_federation_delivery", start, err)
+	}(time.Now())
+
+	sql := `UPDATE federation_deliveries SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3 WHERE id = $1`
+	_, err = r.pool.Exec(ctx, sql, deliveryID, nextAttemptAt, reason)
+	return err
+}
@@ -0,0 +1,308 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/internal/storage/postgres/events"
+	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type FollowRepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewFollowRepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *FollowRepo {
+	return &FollowRepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *FollowRepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+// Follow records that the follower follows the followee and reports whether they didn't follow before, following
+// twice changes nothing. The follow of a private account is stored as a request that waits for the owner's approval
+// instead, FollowStateRequested is returned and asking twice changes nothing either.
+// The counters of both users are left to the caller.
+// Returns customerrors.ErrNotFound if the followee doesn't exist or deleted the account.
+func (r *FollowRepo) Follow(ctx context.Context, followerID, followeeID uuid.UUID) (state entity.FollowState, changed bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_follow", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var private, following bool
+	sql := `SELECT COALESCE(s.private_account, FALSE),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id)
+			FROM users u LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE u.id = $2 AND u.deleted_at IS NULL`
+	err = tx.QueryRow(ctx, sql, followerID, followeeID).Scan(&private, &following)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+		return "", false, err
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if following {
+		return entity.FollowStateFollowing, false, nil
+	}
+
+	if private {
+		var requested bool
+		sql = `WITH inserted AS (
+					INSERT INTO follow_requests (follower_id, followee_id) VALUES ($1, $2)
+					ON CONFLICT (follower_id, followee_id) DO NOTHING
+					RETURNING 1
+				)
+				SELECT EXISTS (SELECT 1 FROM inserted)`
+		if err = tx.QueryRow(ctx, sql, followerID, followeeID).Scan(&requested); err != nil {
+			return "", false, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return "", false, err
+		}
+		return entity.FollowStateRequested, requested, nil
+	}
+
+	followed, err := insertFollow(ctx, tx, followerID, followeeID)
+	if err != nil {
+		return "", false, err
+	}
+	// a request sent while the account was private is settled by the follow
+	_, err = tx.Exec(ctx, "DELETE FROM follow_requests WHERE follower_id = $1 AND followee_id = $2", followerID, followeeID)
+	if err != nil {
+		return "", false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return "", false, err
+	}
+	return entity.FollowStateFollowing, followed, nil
+}
+
+// insertFollow stores the follow in the transaction with its domain event and reports whether it is new.
+func insertFollow(ctx context.Context, tx pgx.Tx, followerID, followeeID uuid.UUID) (bool, error) {
+	sql := `INSERT INTO follows (follower_id, followee_id) VALUES ($1, $2)
+			ON CONFLICT (follower_id, followee_id) DO NOTHING`
+	tag, err := tx.Exec(ctx, sql, followerID, followeeID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	err = events.Enqueue(ctx, tx, entity.DomainEventUserFollowed, followerID, map[string]any{"follower_id": followerID, "followee_id": followeeID})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Unfollow removes the follow, or withdraws the pending follow request, and reports whether there was a follow.
+// The counters of both users are left to the caller.
+func (r *FollowRepo) Unfollow(ctx context.Context, followerID, followeeID uuid.UUID) (removed bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_follow", start, err)
+	}(time.Now())
+
+	sql := `WITH request AS (
+				DELETE FROM follow_requests WHERE follower_id = $1 AND followee_id = $2
+			)
+			DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2`
+	tag, err := r.pool.Exec(ctx, sql, followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListFollowRequests returns the active accounts asking to follow the user, with the follow state relative to
+// the user, that asked before the (beforeTime, beforeID) position, newest request first.
+// A zero beforeTime starts from the newest request.
+func (r *FollowRepo) ListFollowRequests(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (requests []entity.FollowRequest, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_follow_requests", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = u.id AND f.followee_id = $1),
+				fr.created_at
+			FROM follow_requests fr
+				JOIN users u ON u.id = fr.follower_id
+				LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE fr.followee_id = $1 AND u.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR (fr.created_at, fr.follower_id) < ($2, $3))
+			ORDER BY fr.created_at DESC, fr.follower_id DESC
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, userID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.FollowRequest, error) {
+		var fr entity.FollowRequest
+		err := row.Scan(&fr.User.ID, &fr.User.Username, &fr.User.Name, &fr.User.AvatarURL, &fr.User.Following, &fr.User.FollowsYou, &fr.RequestedAt)
+		return fr, err
+	})
+}
+
+// ApproveFollowRequest turns the follower's pending request into a follow of the user and reports whether
+// the follow is new. The counters of both users are left to the caller.
+// Returns customerrors.ErrNotFound if the follower didn't ask to follow the user.
+func (r *FollowRepo) ApproveFollowRequest(ctx context.Context, userID, followerID uuid.UUID) (followed bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("approve_follow_request", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM follow_requests WHERE follower_id = $1 AND followee_id = $2", followerID, userID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+		return false, err
+	}
+	if followed, err = insertFollow(ctx, tx, followerID, userID); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return followed, nil
+}
+
+// RejectFollowRequest removes the follower's pending request to follow the user.
+// Returns customerrors.ErrNotFound if the follower didn't ask to follow the user.
+func (r *FollowRepo) RejectFollowRequest(ctx context.Context, userID, followerID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("reject_follow_request", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM follow_requests WHERE follower_id = $1 AND followee_id = $2", followerID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+	}
+	return err
+}
+
+// FollowerIDs returns up to limit IDs of the accounts following the user with an ID after afterID, in ID order.
+func (r *FollowRepo) FollowerIDs(ctx context.Context, userID, afterID uuid.UUID, limit int) (ids []uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_follower_ids", start, err)
+	}(time.Now())
+
+	sql := `SELECT follower_id FROM follows WHERE followee_id = $1 AND follower_id > $2 ORDER BY follower_id LIMIT $3`
+	rows, err := r.read(ctx).Query(ctx, sql, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+// FollowersCount returns the followers counter of the user, 0 if the user has no profile.
+func (r *FollowRepo) FollowersCount(ctx context.Context, userID uuid.UUID) (count int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_followers_count", start, err)
+	}(time.Now())
+
+	err = r.read(ctx).QueryRow(ctx, "SELECT followers_count FROM profiles WHERE user_id = $1", userID).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return count, err
+}
+
+// Restricted reports whether the user's account is private and the viewer is neither the user nor a follower,
+// a pending follow request doesn't count.
+// Returns customerrors.ErrNotFound if the user doesn't exist or deleted the account.
+func (r *FollowRepo) Restricted(ctx context.Context, viewerID, userID uuid.UUID) (restricted bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_follow_restricted", start, err)
+	}(time.Now())
+
+	sql := `SELECT COALESCE(s.private_account, FALSE) AND u.id <> $1
+				AND NOT EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id)
+			FROM users u LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE u.id = $2 AND u.deleted_at IS NULL`
+	err = r.pool.QueryRow(ctx, sql, viewerID, userID).Scan(&restricted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return restricted, err
+}
+
+// ListFollowers returns the active accounts following the user, with the follow state relative to the viewer,
+// that followed before the (beforeTime, beforeID) position, newest follow first. A zero beforeTime starts from the newest follow.
+func (r *FollowRepo) ListFollowers(ctx context.Context, viewerID, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (entries []entity.FollowEntry, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_followers", start, err)
+	}(time.Now())
+
+	return r.listFollows(ctx, "followee_id", "follower_id", viewerID, userID, beforeTime, beforeID, limit)
+}
+
+// ListFollowing returns the active accounts the user follows, ordered and paginated like ListFollowers.
+func (r *FollowRepo) ListFollowing(ctx context.Context, viewerID, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (entries []entity.FollowEntry, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_following", start, err)
+	}(time.Now())
+
+	return r.listFollows(ctx, "follower_id", "followee_id", viewerID, userID, beforeTime, beforeID, limit)
+}
+
+// listFollows lists the accounts in the other column of the user's follows, by is the column holding the user.
+func (r *FollowRepo) listFollows(ctx context.Context, by, other string, viewerID, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.FollowEntry, error) {
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = u.id AND f.followee_id = $1),
+				fl.created_at
+			FROM follows fl
+				JOIN users u ON u.id = fl.` + other + `
+				LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE fl.` + by + ` = $2 AND u.deleted_at IS NULL
+				AND ($3::timestamptz IS NULL OR (fl.created_at, fl.` + other + `) < ($3, $4))
+			ORDER BY fl.created_at DESC, fl.` + other + ` DESC
+			LIMIT $5`
+	rows, err := r.read(ctx).Query(ctx, sql, viewerID, userID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.FollowEntry, error) {
+		var e entity.FollowEntry
+		err := row.Scan(&e.User.ID, &e.User.Username, &e.User.Name, &e.User.AvatarURL, &e.User.Following, &e.User.FollowsYou, &e.FollowedAt)
+		return e, err
+	})
+}
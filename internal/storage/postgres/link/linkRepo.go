@@ -0,0 +1,83 @@
+package link
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type LinkRepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewLinkRepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *LinkRepo {
+	return &LinkRepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *LinkRepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+// GetLink returns the ID and the target of the shortened link with the code if its post is visible to anyone:
+// a public post of a public account that isn't shadow-limited or deleted. The redirect is followed by browsers
+// without credentials, so it has the rights of an anonymous viewer.
+// Returns customerrors.ErrNotFound if there is no such link or its post is hidden.
+func (r *LinkRepo) GetLink(ctx context.Context, code string) (id uuid.UUID, url string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_link", start, err)
+	}(time.Now())
+
+	sql := `SELECT l.id, l.url
+			FROM post_links l
+				JOIN posts ON posts.id = l.post_id
+			WHERE l.code = $1 AND posts.deleted_at IS NULL AND posts.visibility = 'public' AND NOT posts.limited
+				AND NOT EXISTS (SELECT 1 FROM user_settings s WHERE s.user_id = posts.user_id AND s.private_account)`
+	err = r.read(ctx).QueryRow(ctx, sql, code).Scan(&id, &url)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return id, url, err
+}
+
+// ListPostLinks returns the links of a post of the user with their clicks, in the order they appear.
+// Returns customerrors.ErrNotFound if the user has no such post.
+func (r *LinkRepo) ListPostLinks(ctx context.Context, userID, postID uuid.UUID) (links []entity.LinkClicks, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_post_links", start, err)
+	}(time.Now())
+
+	var exists bool
+	sql := "SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)"
+	if err = r.read(ctx).QueryRow(ctx, sql, postID, userID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		err = customerrors.ErrNotFound
+		return nil, err
+	}
+
+	rows, err := r.read(ctx).Query(ctx, "SELECT code, text, url, clicks FROM post_links WHERE post_id = $1 ORDER BY position", postID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.LinkClicks, error) {
+		var l entity.LinkClicks
+		err := row.Scan(&l.Code, &l.Text, &l.URL, &l.Clicks)
+		return l, err
+	})
+}
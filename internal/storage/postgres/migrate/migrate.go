@@ -0,0 +1,362 @@
+package migrate
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrPendingMigrations is returned by Verify when the database schema is older than the binary.
+var ErrPendingMigrations = errors.New("database schema has pending migrations")
+
+// lockID is the advisory lock held while migrating, so instances starting together don't apply a migration twice.
+const lockID = 7_319_004_211
+
+// Migration is a versioned schema change read from a goose-style SQL file.
+type Migration struct {
+	Version int64
+	Name    string
+
+	up   []string
+	down []string
+	// noTx runs the statements outside of a transaction, e.g. for CREATE INDEX CONCURRENTLY
+	noTx bool
+}
+
+// MigrationStatus is a migration and when it was applied, nil if it's pending.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies the migrations of a directory and records them in the goose_db_version table, so databases
+// migrated with the goose CLI keep working.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+// New reads the *.sql migrations from the root of every source, e.g. the core schema and the tables of a module.
+// The versions are ordered across sources, so they must be unique across them too.
+func New(pool *pgxpool.Pool, sources ...fs.FS) (*Migrator, error) {
+	m := &Migrator{pool: pool}
+	for _, fsys := range sources {
+		files, err := fs.Glob(fsys, "*.sql")
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			migration, err := parseFile(fsys, file)
+			if err != nil {
+				return nil, fmt.Errorf("migration %s: %w", file, err)
+			}
+			m.migrations = append(m.migrations, migration)
+		}
+	}
+	slices.SortFunc(m.migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i := 1; i < len(m.migrations); i++ {
+		if m.migrations[i].Version == m.migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.migrations[i].Version)
+		}
+	}
+	return m, nil
+}
+
+// Latest returns the version of the newest migration, 0 if there are none.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration in version order and returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := run(ctx, conn, migration, migration.up,
+				"INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)"); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the newest applied migration and returns it. It returns false if no migration is applied.
+func (m *Migrator) Down(ctx context.Context) (rolledBack Migration, ok bool, err error) {
+	err = m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, applied := done[migration.Version]; !applied {
+				continue
+			}
+			if err := run(ctx, conn, migration, migration.down,
+				"DELETE FROM goose_db_version WHERE version_id = $1"); err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			rolledBack, ok = migration, true
+			return nil
+		}
+		return nil
+	})
+	return rolledBack, ok, err
+}
+
+// Status returns every known migration in version order with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{Migration: migration}
+		if at, ok := done[migration.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Version returns the newest applied migration version, 0 for an empty database.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v := range done {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+// Verify returns ErrPendingMigrations if a migration of the binary isn't applied. Migrations the binary doesn't
+// know are fine, they are expected while a newer version is rolled out.
+func (m *Migrator) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, strconv.FormatInt(s.Version, 10))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrPendingMigrations, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// locked runs fn on one connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// migrations and waiting for another instance migrating may take longer than the query timeout
+	if _, err := conn.Exec(ctx, "SET statement_timeout = 0"); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "RESET statement_timeout")
+	}()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID)
+	}()
+
+	// the same table the goose CLI uses, including its version 0 row
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS goose_db_version (
+			id SERIAL PRIMARY KEY,
+			version_id BIGINT NOT NULL,
+			is_applied BOOLEAN NOT NULL,
+			tstamp TIMESTAMP DEFAULT NOW()
+		);
+		INSERT INTO goose_db_version (version_id, is_applied)
+		SELECT 0, TRUE WHERE NOT EXISTS (SELECT 1 FROM goose_db_version)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// appliedVersions returns the applied versions with the time they were applied, none before the first migration.
+// It only reads, so readiness probes can call it.
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('goose_db_version') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int64]time.Time{}, nil
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version_id, MAX(tstamp) FROM goose_db_version WHERE is_applied AND version_id > 0 GROUP BY version_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version int64
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+// run executes the statements of a migration and records it with the record statement, in a transaction unless
+// the migration opted out.
+func run(ctx context.Context, conn *pgxpool.Conn, migration Migration, statements []string, record string) error {
+	if migration.noTx {
+		for _, stmt := range statements {
+			if _, err := conn.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		_, err := conn.Exec(ctx, record, migration.Version)
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx, record, migration.Version)
+		return err
+	})
+}
+
+// parseFile reads a migration in the goose SQL format: statements after "-- +goose Up" apply it and statements
+// after "-- +goose Down" roll it back. Statements end with a semicolon at the end of a line, unless they are
+// wrapped in "-- +goose StatementBegin" and "-- +goose StatementEnd".
+func parseFile(fsys fs.FS, file string) (Migration, error) {
+	base := path.Base(file)
+	prefix, name, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), "_")
+	if !ok {
+		return Migration{}, errors.New("file name must be <version>_<name>.sql")
+	}
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return Migration{}, fmt.Errorf("invalid version %q", prefix)
+	}
+
+	f, err := fsys.Open(file)
+	if err != nil {
+		return Migration{}, err
+	}
+	defer f.Close()
+
+	migration := Migration{Version: version, Name: name}
+	var (
+		section   *[]string
+		stmt      strings.Builder
+		inBlock   bool
+		sawUp     bool
+		scanner   = bufio.NewScanner(f)
+		flushStmt = func() {
+			if s := strings.TrimSpace(stmt.String()); s != "" && section != nil {
+				*section = append(*section, s)
+			}
+			stmt.Reset()
+		}
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if directive, ok := strings.CutPrefix(trimmed, "-- +goose "); ok {
+			switch strings.TrimSpace(directive) {
+			case "Up":
+				flushStmt()
+				section, sawUp = &migration.up, true
+			case "Down":
+				flushStmt()
+				section = &migration.down
+			case "StatementBegin":
+				flushStmt()
+				inBlock = true
+			case "StatementEnd":
+				flushStmt()
+				inBlock = false
+			case "NO TRANSACTION":
+				migration.noTx = true
+			}
+			continue
+		}
+		if section == nil {
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteByte('\n')
+		if !inBlock && strings.HasSuffix(trimmed, ";") {
+			flushStmt()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
+	}
+	if inBlock {
+		return Migration{}, errors.New("StatementBegin without StatementEnd")
+	}
+	flushStmt()
+	if !sawUp {
+		return Migration{}, errors.New("missing -- +goose Up section")
+	}
+	return migration, nil
+}
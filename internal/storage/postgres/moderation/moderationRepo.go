@@ -0,0 +1,150 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ModerationRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewModerationRepo(pool *postgres.DB, metrics *metrics.Metrics) *ModerationRepo {
+	return &ModerationRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// db returns the transaction of the context, so the repository takes part in txmanager transactions, or the pool.
+func (r *ModerationRepo) db(ctx context.Context) txmanager.Querier {
+	return txmanager.From(ctx, r.pool)
+}
+
+// AddFlag queues content for review.
+func (r *ModerationRepo) AddFlag(ctx context.Context, flag entity.ContentFlag) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_content_flag", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO content_flags (id, content_type, content_id, author_id, verdict, reason, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = r.db(ctx).Exec(ctx, sql, flag.ID, flag.Kind, flag.ContentID, flag.AuthorID, flag.Verdict, flag.Reason, flag.CreatedAt)
+	return err
+}
+
+const flagColumns = `id, content_type, content_id, author_id, verdict, reason, created_at, resolved_at, resolved_by,
+		COALESCE(resolution, '')`
+
+func scanFlag(row pgx.Row) (entity.ContentFlag, error) {
+	var f entity.ContentFlag
+	err := row.Scan(&f.ID, &f.Kind, &f.ContentID, &f.AuthorID, &f.Verdict, &f.Reason, &f.CreatedAt, &f.ResolvedAt,
+		&f.ResolvedBy, &f.Resolution)
+	return f, err
+}
+
+// ListOpenFlags returns the unresolved flags created after the given position, oldest first.
+func (r *ModerationRepo) ListOpenFlags(ctx context.Context, afterTime time.Time, afterID uuid.UUID, limit int) (flags []entity.ContentFlag, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_open_content_flags", start, err)
+	}(time.Now())
+
+	var after any
+	if !afterTime.IsZero() {
+		after = afterTime
+	}
+	sql := `SELECT ` + flagColumns + ` FROM content_flags
+			WHERE resolved_at IS NULL AND ($1::timestamptz IS NULL OR (created_at, id) > ($1, $2))
+			ORDER BY created_at, id
+			LIMIT $3`
+	rows, err := r.db(ctx).Query(ctx, sql, after, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.ContentFlag, error) {
+		return scanFlag(row)
+	})
+}
+
+// GetFlag returns the flag, customerrors.ErrNotFound if it doesn't exist.
+func (r *ModerationRepo) GetFlag(ctx context.Context, id uuid.UUID) (flag entity.ContentFlag, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_content_flag", start, err)
+	}(time.Now())
+
+	flag, err = scanFlag(r.db(ctx).QueryRow(ctx, "SELECT "+flagColumns+" FROM content_flags WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return flag, err
+}
+
+// ResolveFlags resolves the open flags of the content and returns how many there were.
+func (r *ModerationRepo) ResolveFlags(ctx context.Context, kind entity.ContentKind, contentID, resolvedBy uuid.UUID, resolution entity.ReportResolution) (resolved int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_content_flags_resolved", start, err)
+	}(time.Now())
+
+	// uuid.Nil is a resolution without a user, e.g. through an API key
+	var by any
+	if resolvedBy != uuid.Nil {
+		by = resolvedBy
+	}
+	sql := `UPDATE content_flags SET resolved_at = NOW(), resolved_by = $3, resolution = $4
+			WHERE content_type = $1 AND content_id = $2 AND resolved_at IS NULL`
+	tag, err := r.db(ctx).Exec(ctx, sql, kind, contentID, by, resolution)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// LiftLimit shows shadow-limited content to everyone again, messages are never limited.
+func (r *ModerationRepo) LiftLimit(ctx context.Context, kind entity.ContentKind, contentID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_content_unlimited", start, err)
+	}(time.Now())
+
+	switch kind {
+	case entity.ContentPost:
+		_, err = r.db(ctx).Exec(ctx, "UPDATE posts SET limited = FALSE WHERE id = $1", contentID)
+	case entity.ContentComment:
+		_, err = r.db(ctx).Exec(ctx, "UPDATE comments SET limited = FALSE WHERE id = $1", contentID)
+	}
+	return err
+}
+
+// ContentAuthor returns the author of the content and, for a message, its chat. Content its author deleted is
+// found until it is purged, so that it can still be taken down. Returns customerrors.ErrNotFound if the content
+// doesn't exist.
+func (r *ModerationRepo) ContentAuthor(ctx context.Context, kind entity.ContentKind, contentID uuid.UUID) (authorID, chatID uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_content_author", start, err)
+	}(time.Now())
+
+	switch kind {
+	case entity.ContentPost:
+		err = r.db(ctx).QueryRow(ctx, "SELECT user_id FROM posts WHERE id = $1", contentID).Scan(&authorID)
+	case entity.ContentComment:
+		err = r.db(ctx).QueryRow(ctx, "SELECT user_id FROM comments WHERE id = $1", contentID).Scan(&authorID)
+	case entity.ContentMessage:
+		err = r.db(ctx).QueryRow(ctx, "SELECT sender_id, chat_id FROM messages WHERE id = $1 AND purged_at IS NULL", contentID).
+			Scan(&authorID, &chatID)
+	default:
+		err = pgx.ErrNoRows
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return authorID, chatID, err
+}
@@ -0,0 +1,299 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type NotificationRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewNotificationRepo(pool *postgres.DB, metrics *metrics.Metrics) *NotificationRepo {
+	return &NotificationRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// preferenceColumns maps notification types to the user_settings column that turns them off.
+var preferenceColumns = map[entity.NotificationType]string{
+	entity.NotificationFollow:        "notify_follows",
+	entity.NotificationFollowRequest: "notify_follows",
+	entity.NotificationLike:          "notify_likes",
+	entity.NotificationComment:       "notify_comments",
+	entity.NotificationMention:       "notify_mentions",
+	entity.NotificationMessage:       "notify_messages",
+}
+
+// AddEvent queues an event for the fan-out workers.
+func (r *NotificationRepo) AddEvent(ctx context.Context, event entity.NotificationEvent) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_notification_event", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "INSERT INTO notification_events (payload) VALUES ($1)", event)
+	return err
+}
+
+// ClaimEvents takes up to limit queued events, oldest first, for the lease duration. Events taken by another
+// worker are skipped until their lease expires.
+func (r *NotificationRepo) ClaimEvents(ctx context.Context, limit int, lease time.Duration) (events []entity.NotificationEvent, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("claim_notification_events", start, err)
+	}(time.Now())
+
+	sql := `WITH claimed AS (
+				SELECT id FROM notification_events
+				WHERE locked_until IS NULL OR locked_until < NOW()
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			UPDATE notification_events e SET locked_until = NOW() + make_interval(secs => $2), attempts = e.attempts + 1
+			FROM claimed
+			WHERE e.id = claimed.id
+			RETURNING e.id, e.payload, e.attempts`
+	rows, err := r.pool.Query(ctx, sql, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	events, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.NotificationEvent, error) {
+		var id int64
+		var payload []byte
+		var attempts int
+		if err := row.Scan(&id, &payload, &attempts); err != nil {
+			return entity.NotificationEvent{}, err
+		}
+		var e entity.NotificationEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return entity.NotificationEvent{}, err
+		}
+		e.ID, e.Attempts = id, attempts
+		return e, nil
+	})
+	return events, err
+}
+
+// DeleteEvents removes events from the queue once they're fanned out.
+func (r *NotificationRepo) DeleteEvents(ctx context.Context, ids []int64) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_notification_events", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM notification_events WHERE id = ANY($1)", ids)
+	return err
+}
+
+// Recipients returns the users each event concerns: the followed user, the author of the liked or commented post
+// and of the comment replied to, the mentioned users or the members of the chat. The actor may be among them.
+// The queries of all events are sent in one batch.
+func (r *NotificationRepo) Recipients(ctx context.Context, events []entity.NotificationEvent) (recipients [][]uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_notification_recipients", start, err)
+	}(time.Now())
+
+	recipients = make([][]uuid.UUID, len(events))
+	batch := &pgx.Batch{}
+	for i, event := range events {
+		if event.Type == entity.NotificationFollow || event.Type == entity.NotificationFollowRequest {
+			if event.UserID != nil {
+				recipients[i] = []uuid.UUID{*event.UserID}
+			}
+			continue
+		}
+		sql, args := recipientsQuery(event)
+		if sql == "" {
+			continue
+		}
+		batch.Queue(sql, args...).Query(func(rows pgx.Rows) error {
+			ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+			recipients[i] = ids
+			return err
+		})
+	}
+	if batch.Len() == 0 {
+		return recipients, nil
+	}
+	err = r.pool.SendBatch(ctx, batch).Close()
+	return recipients, err
+}
+
+func recipientsQuery(event entity.NotificationEvent) (string, []any) {
+	switch event.Type {
+	case entity.NotificationLike:
+		return "SELECT user_id FROM posts WHERE id = $1", []any{event.PostID}
+	case entity.NotificationComment:
+		return `SELECT user_id FROM posts WHERE id = $1
+				UNION
+				SELECT parent.user_id FROM comments c JOIN comments parent ON parent.id = c.reply_to WHERE c.id = $2`,
+			[]any{event.PostID, event.CommentID}
+	case entity.NotificationMention:
+		// mentioned users may not be allowed to see posts with a narrower audience, so only public ones notify them
+		return `SELECT u.id FROM users u
+				WHERE lower(u.username) = ANY($1) AND u.deleted_at IS NULL
+					AND EXISTS (
+						SELECT 1 FROM posts p LEFT JOIN user_settings s ON s.user_id = p.user_id
+						WHERE p.id = $2 AND p.visibility = 'public' AND NOT COALESCE(s.private_account, FALSE)
+					)`,
+			[]any{event.Mentions, event.PostID}
+	case entity.NotificationMessage:
+		return "SELECT user_id FROM chat_members WHERE chat_id = $1", []any{event.ChatID}
+	}
+	return "", nil
+}
+
+// AddNotifications notifies the recipients of each event and returns the users who were notified per event, the
+// inserts of all events are sent in one batch. The actor, recipients who turned the type off in their settings and
+// those blocked by or blocking the actor are skipped, as are recipients who have an unread notification about the
+// same thing.
+func (r *NotificationRepo) AddNotifications(ctx context.Context, events []entity.NotificationEvent, recipients [][]uuid.UUID) (notified [][]uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_notifications", start, err)
+	}(time.Now())
+
+	notified = make([][]uuid.UUID, len(events))
+	batch := &pgx.Batch{}
+	for i, event := range events {
+		column, ok := preferenceColumns[event.Type]
+		if !ok || len(recipients[i]) == 0 {
+			continue
+		}
+		// the subject may have been deleted since the event happened
+		sql := `INSERT INTO notifications (user_id, type, actor_id, post_id, comment_id, chat_id)
+				SELECT r.id, $2, $3, $4, $5, $6
+				FROM unnest($1::uuid[]) AS r(id)
+					JOIN users u ON u.id = r.id AND u.deleted_at IS NULL
+					LEFT JOIN user_settings s ON s.user_id = r.id
+				WHERE r.id <> $3 AND COALESCE(s.` + column + `, TRUE)
+					AND NOT EXISTS (SELECT 1 FROM blacklist b
+						WHERE (b.blocker_id = r.id AND b.blocked_id = $3) OR (b.blocker_id = $3 AND b.blocked_id = r.id))
+					AND NOT EXISTS (SELECT 1 FROM notifications n
+						WHERE n.user_id = r.id AND n.type = $2 AND n.actor_id = $3 AND n.read_at IS NULL
+							AND n.post_id IS NOT DISTINCT FROM $4 AND n.comment_id IS NOT DISTINCT FROM $5 AND n.chat_id IS NOT DISTINCT FROM $6)
+					AND ($4::uuid IS NULL OR EXISTS (SELECT 1 FROM posts WHERE id = $4 AND deleted_at IS NULL))
+					AND ($5::uuid IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = $5 AND deleted_at IS NULL))
+					AND ($6::uuid IS NULL OR EXISTS (SELECT 1 FROM chats WHERE id = $6))
+				RETURNING user_id`
+		batch.Queue(sql, recipients[i], string(event.Type), event.ActorID, event.PostID, event.CommentID, event.ChatID).
+			Query(func(rows pgx.Rows) error {
+				ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+				notified[i] = ids
+				return err
+			})
+	}
+	if batch.Len() == 0 {
+		return notified, nil
+	}
+	err = r.pool.SendBatch(ctx, batch).Close()
+	return notified, err
+}
+
+// ListNotifications returns the notifications of the user created before the (beforeTime, beforeID) position,
+// newest first. A zero beforeTime starts from the newest notification.
+func (r *NotificationRepo) ListNotifications(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) (notifications []entity.Notification, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_notifications", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := selectNotification + `
+			WHERE n.user_id = $1 AND u.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR (n.created_at, n.id) < ($2, $3))
+			ORDER BY n.created_at DESC, n.id DESC
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, userID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanNotification)
+}
+
+// ListNotificationsAfter returns the notifications of the user created after the (afterTime, afterID) position,
+// oldest first. A zero afterTime starts from the oldest notification.
+func (r *NotificationRepo) ListNotificationsAfter(ctx context.Context, userID uuid.UUID, afterTime time.Time, afterID uuid.UUID, limit int) (notifications []entity.Notification, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_notifications_after", start, err)
+	}(time.Now())
+
+	var after any
+	if !afterTime.IsZero() {
+		after = afterTime
+	}
+	sql := selectNotification + `
+			WHERE n.user_id = $1 AND u.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR (n.created_at, n.id) > ($2, $3))
+			ORDER BY n.created_at, n.id
+			LIMIT $4`
+	rows, err := r.pool.Query(ctx, sql, userID, after, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanNotification)
+}
+
+// selectNotification selects notifications with their actors, $1 is the user the follow state is relative to.
+const selectNotification = `SELECT n.id, n.type, u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = u.id AND f.followee_id = $1),
+				n.post_id, n.comment_id, n.chat_id, n.read_at IS NOT NULL, n.created_at
+			FROM notifications n
+				JOIN users u ON u.id = n.actor_id
+				LEFT JOIN profiles p ON p.user_id = u.id`
+
+func scanNotification(row pgx.CollectableRow) (entity.Notification, error) {
+	var n entity.Notification
+	err := row.Scan(&n.ID, &n.Type, &n.Actor.ID, &n.Actor.Username, &n.Actor.Name, &n.Actor.AvatarURL, &n.Actor.Following,
+		&n.Actor.FollowsYou, &n.PostID, &n.CommentID, &n.ChatID, &n.Read, &n.CreatedAt)
+	return n, err
+}
+
+// CountUnread returns the number of unread notifications of the user.
+func (r *NotificationRepo) CountUnread(ctx context.Context, userID uuid.UUID) (count int, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("count_unread_notifications", start, err)
+	}(time.Now())
+
+	err = r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks a notification of the user read, marking a read notification again succeeds.
+// Returns customerrors.ErrNotFound if the user has no such notification.
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID, notificationID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_notification_read", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2",
+		notificationID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+	}
+	return err
+}
+
+// MarkAllRead marks every notification of the user read.
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_notifications_read", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL", userID)
+	return err
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type poolWaits struct {
+	count int64
+	wait  time.Duration
+}
+
+// PoolWatcher logs the pools whose acquisitions had to wait for a connection since the previous check, a sign
+// the pool is too small for the load.
+type PoolWatcher struct {
+	logger *slog.Logger
+	pools  map[string]*pgxpool.Pool
+	last   map[string]poolWaits
+}
+
+func NewPoolWatcher(logger *slog.Logger, pools map[string]*pgxpool.Pool) *PoolWatcher {
+	return &PoolWatcher{
+		logger: logger,
+		pools:  pools,
+		last:   make(map[string]poolWaits, len(pools)),
+	}
+}
+
+// Check compares the wait statistics of the pools with the previous check, it is run periodically.
+func (w *PoolWatcher) Check(ctx context.Context) error {
+	for name, pool := range w.pools {
+		stat := pool.Stat()
+		current := poolWaits{count: stat.EmptyAcquireCount(), wait: stat.EmptyAcquireWaitTime()}
+		previous, seen := w.last[name]
+		w.last[name] = current
+		if !seen || current.count == previous.count {
+			continue
+		}
+		waited := current.count - previous.count
+		w.logger.Warn("Database pool acquisitions waited for a connection",
+			"pool", name,
+			"waited", waited,
+			"avg_wait", (current.wait-previous.wait)/time.Duration(waited),
+			"acquired", stat.AcquiredConns(),
+			"max_conns", stat.MaxConns(),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,694 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/internal/storage/postgres/events"
+	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PostRepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewPostRepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *PostRepo {
+	return &PostRepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *PostRepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+const postColumns = `id, user_id, description, COALESCE(media_url, ''), is_video, duration, quote_of_id, visibility,
+		likes_count, reposts_count, quotes_count, comments_count, created_at, updated_at, version,
+		ARRAY(SELECT h.tag FROM post_hashtags ph JOIN hashtags h ON h.id = ph.hashtag_id WHERE ph.post_id = posts.id ORDER BY h.tag),
+		COALESCE((SELECT jsonb_agg(jsonb_build_object('code', l.code, 'text', l.text, 'url', l.url) ORDER BY l.position)
+			FROM post_links l WHERE l.post_id = posts.id), '[]')`
+
+const selectPost = "SELECT " + postColumns + " FROM posts"
+
+// visibleTo is the condition for posts the viewer, given as a query parameter, may see: their own posts,
+// public posts of public accounts, public and followers posts if the viewer follows the author
+// and close friends posts if the author put the viewer on their close friends list.
+// Nothing of an author who blocked the viewer is visible, nor shadow-limited posts of others, nor deleted posts.
+// uuid.Nil is an anonymous viewer.
+func visibleTo(viewer string) string {
+	return `(posts.deleted_at IS NULL AND (posts.user_id = ` + viewer + `
+			OR NOT posts.limited AND (posts.visibility = 'public'
+					AND NOT EXISTS (SELECT 1 FROM user_settings s WHERE s.user_id = posts.user_id AND s.private_account)
+				OR posts.visibility IN ('public', 'followers')
+					AND EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = ` + viewer + ` AND f.followee_id = posts.user_id)
+				OR posts.visibility = 'close_friends'
+					AND EXISTS (SELECT 1 FROM close_friends cf WHERE cf.user_id = posts.user_id AND cf.friend_id = ` + viewer + `))
+				AND NOT EXISTS (SELECT 1 FROM blacklist b WHERE b.blocker_id = posts.user_id AND b.blocked_id = ` + viewer + `)))`
+}
+
+// discoverableTo is the condition, on top of visibleTo, for posts the viewer may find in explore, hashtag listings
+// and search: the author's privacy level must include the viewer.
+func discoverableTo(viewer string) string {
+	return `(posts.user_id = ` + viewer + `
+			OR NOT EXISTS (SELECT 1 FROM user_settings s WHERE s.user_id = posts.user_id
+				AND (s.privacy_level = 'nobody'
+					OR s.privacy_level = 'followers'
+						AND NOT EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = ` + viewer + ` AND f.followee_id = posts.user_id))))`
+}
+
+// CreatePost stores a new post with its hashtags. For a quote post the quotes counter of the original is bumped
+// in the same transaction, customerrors.ErrNotFound is returned if the original doesn't exist or its author
+// can't see it.
+func (r *PostRepo) CreatePost(ctx context.Context, post entity.Post) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_post", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if post.QuoteOfID != nil {
+		// also locks the original, so it can't be deleted before the quote references it
+		tag, err := tx.Exec(ctx, "UPDATE posts SET quotes_count = quotes_count + 1 WHERE id = $1 AND "+visibleTo("$2"),
+			*post.QuoteOfID, post.UserID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return customerrors.ErrNotFound
+		}
+	}
+
+	sql := `INSERT INTO posts (id, user_id, description, media_url, is_video, duration, quote_of_id, visibility, limited, created_at, updated_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11)`
+	_, err = tx.Exec(ctx, sql, post.ID, post.UserID, post.Description, post.MediaURL, post.IsVideo, post.Duration, post.QuoteOfID,
+		post.Visibility, post.Limited, post.CreatedAt, post.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if err = saveHashtags(ctx, tx, post.ID, post.CreatedAt, post.Hashtags); err != nil {
+		return err
+	}
+	if err = saveLinks(ctx, tx, post.ID, post.Links); err != nil {
+		return err
+	}
+	err = events.Enqueue(ctx, tx, entity.DomainEventPostCreated, post.ID, map[string]any{
+		"post_id":     post.ID,
+		"user_id":     post.UserID,
+		"quote_of_id": post.QuoteOfID,
+		"visibility":  post.Visibility,
+		"is_video":    post.IsVideo,
+		"hashtags":    post.Hashtags,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ImportPost stores a post brought over from another platform with its hashtags and reports whether it was stored.
+// A post whose ID is already taken was imported before and is left alone, so an interrupted import can be resumed.
+func (r *PostRepo) ImportPost(ctx context.Context, post entity.Post) (imported bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("import_post", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO posts (id, user_id, description, visibility, limited, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id) DO NOTHING`
+	tag, err := tx.Exec(ctx, sql, post.ID, post.UserID, post.Description, post.Visibility, post.Limited, post.CreatedAt, post.UpdatedAt)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	if err = saveHashtags(ctx, tx, post.ID, post.CreatedAt, post.Hashtags); err != nil {
+		return false, err
+	}
+	if err = saveLinks(ctx, tx, post.ID, post.Links); err != nil {
+		return false, err
+	}
+	err = events.Enqueue(ctx, tx, entity.DomainEventPostCreated, post.ID, map[string]any{
+		"post_id":    post.ID,
+		"user_id":    post.UserID,
+		"visibility": post.Visibility,
+		"hashtags":   post.Hashtags,
+		"imported":   true,
+	})
+	if err != nil {
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetPost returns the post if the viewer may see it, customerrors.ErrNotFound if there is none or it is hidden.
+func (r *PostRepo) GetPost(ctx context.Context, viewerID, id uuid.UUID) (post entity.Post, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_post", start, err)
+	}(time.Now())
+
+	post, err = scanPost(r.read(ctx).QueryRow(ctx, selectPost+" WHERE id = $1 AND "+visibleTo("$2"), id, viewerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return post, err
+}
+
+// GetPosts returns the posts of the IDs the viewer may see, in no particular order.
+func (r *PostRepo) GetPosts(ctx context.Context, viewerID uuid.UUID, ids []uuid.UUID) (posts []entity.Post, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_posts", start, err)
+	}(time.Now())
+
+	rows, err := r.read(ctx).Query(ctx, selectPost+" WHERE id = ANY($1) AND "+visibleTo("$2"), ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Post, error) {
+		return scanPost(row)
+	})
+}
+
+// LikedPosts returns which of the posts the user liked.
+func (r *PostRepo) LikedPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (liked []uuid.UUID, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_liked_posts", start, err)
+	}(time.Now())
+
+	rows, err := r.pool.Query(ctx, "SELECT post_id FROM likes WHERE user_id = $1 AND post_id = ANY($2)", userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+// UpdatePost replaces the description, the hashtags and the links of a post of the user, and the visibility unless
+// it is empty.
+// Returns the updated post, customerrors.ErrNoTagsAffected if the user has no such post and
+// customerrors.ErrVersionConflict if the post isn't at the version of the update anymore.
+func (r *PostRepo) UpdatePost(ctx context.Context, userID, postID uuid.UUID, version int64, description string, visibility entity.PostVisibility, hashtags []string, links []entity.PostLink) (post entity.Post, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_post", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return entity.Post{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var createdAt time.Time
+	sql := `UPDATE posts SET description = $3, visibility = COALESCE(NULLIF($4, ''), visibility), updated_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $5 RETURNING created_at`
+	err = tx.QueryRow(ctx, sql, postID, userID, description, visibility, version).Scan(&createdAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)", postID, userID).Scan(&exists)
+		if err != nil {
+			return entity.Post{}, err
+		}
+		if !exists {
+			return entity.Post{}, customerrors.ErrNoTagsAffected
+		}
+		return entity.Post{}, customerrors.ErrVersionConflict
+	}
+	if err != nil {
+		return entity.Post{}, err
+	}
+	if _, err = tx.Exec(ctx, "DELETE FROM post_hashtags WHERE post_id = $1", postID); err != nil {
+		return entity.Post{}, err
+	}
+	if err = saveHashtags(ctx, tx, postID, createdAt, hashtags); err != nil {
+		return entity.Post{}, err
+	}
+	urls := make([]string, len(links))
+	for i, link := range links {
+		urls[i] = link.URL
+	}
+	if _, err = tx.Exec(ctx, "DELETE FROM post_links WHERE post_id = $1 AND url <> ALL($2::text[])", postID, urls); err != nil {
+		return entity.Post{}, err
+	}
+	if err = saveLinks(ctx, tx, postID, links); err != nil {
+		return entity.Post{}, err
+	}
+	if post, err = scanPost(tx.QueryRow(ctx, selectPost+" WHERE id = $1", postID)); err != nil {
+		return entity.Post{}, err
+	}
+	return post, tx.Commit(ctx)
+}
+
+// saveHashtags links the post to its hashtags, creating the ones used for the first time.
+func saveHashtags(ctx context.Context, tx pgx.Tx, postID uuid.UUID, createdAt time.Time, hashtags []string) error {
+	if len(hashtags) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO hashtags (tag) SELECT unnest($1::text[]) ON CONFLICT (tag) DO NOTHING", hashtags)
+	if err != nil {
+		return err
+	}
+	sql := `INSERT INTO post_hashtags (hashtag_id, post_id, created_at)
+			SELECT id, $2, $3 FROM hashtags WHERE tag = ANY($1::text[])`
+	_, err = tx.Exec(ctx, sql, hashtags, postID, createdAt)
+	return err
+}
+
+// saveLinks stores the shortened links of the post in their order. A link the post already has keeps its code
+// and clicks, only its position and text are updated.
+func saveLinks(ctx context.Context, tx pgx.Tx, postID uuid.UUID, links []entity.PostLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(links))
+	codes, texts, urls := make([]string, len(links)), make([]string, len(links)), make([]string, len(links))
+	positions := make([]int16, len(links))
+	for i, link := range links {
+		ids[i], codes[i], texts[i], urls[i], positions[i] = uuid.New(), link.Code, link.Text, link.URL, int16(i)
+	}
+	sql := `INSERT INTO post_links (id, code, post_id, position, text, url)
+			SELECT l.id, l.code, $1, l.position, l.text, l.url
+			FROM unnest($2::uuid[], $3::text[], $4::smallint[], $5::text[], $6::text[]) AS l(id, code, position, text, url)
+			ON CONFLICT (post_id, url) DO UPDATE SET position = EXCLUDED.position, text = EXCLUDED.text`
+	_, err := tx.Exec(ctx, sql, postID, ids, codes, positions, texts, urls)
+	return err
+}
+
+// ListHashtagPosts returns posts tagged with the hashtag that the viewer may see, newest first.
+// Only posts older than the (beforeTime, beforeID) position are returned unless beforeTime is zero.
+func (r *PostRepo) ListHashtagPosts(ctx context.Context, viewerID uuid.UUID, tag string, beforeTime time.Time, beforeID uuid.UUID, limit int) (posts []entity.Post, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_hashtag_posts", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := selectPost + ` JOIN post_hashtags ph ON ph.post_id = posts.id JOIN hashtags h ON h.id = ph.hashtag_id
+			WHERE h.tag = $1 AND ($2::timestamptz IS NULL OR (ph.created_at, ph.post_id) < ($2, $3))
+				AND ` + visibleTo("$5") + ` AND ` + discoverableTo("$5") + `
+			ORDER BY ph.created_at DESC, ph.post_id DESC
+			LIMIT $4`
+	rows, err := r.read(ctx).Query(ctx, sql, tag, before, beforeID, limit, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Post, error) {
+		return scanPost(row)
+	})
+	return posts, err
+}
+
+// TrendingHashtags returns the hashtags used by the most posts created since the given time, most used first.
+func (r *PostRepo) TrendingHashtags(ctx context.Context, since time.Time, limit int) (hashtags []entity.TrendingHashtag, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_trending_hashtags", start, err)
+	}(time.Now())
+
+	sql := `SELECT h.tag, COUNT(*) AS posts FROM post_hashtags ph JOIN hashtags h ON h.id = ph.hashtag_id
+				JOIN posts ON posts.id = ph.post_id AND posts.deleted_at IS NULL
+			WHERE ph.created_at >= $1
+			GROUP BY h.tag
+			ORDER BY posts DESC, h.tag
+			LIMIT $2`
+	rows, err := r.read(ctx).Query(ctx, sql, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	hashtags, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.TrendingHashtag, error) {
+		var h entity.TrendingHashtag
+		err := row.Scan(&h.Tag, &h.PostsCount)
+		return h, err
+	})
+	return hashtags, err
+}
+
+// DeletePost marks a post of the user deleted, so it can be restored until it is purged, and decrements the quotes
+// counter of the post it quoted. Returns customerrors.ErrNoTagsAffected if the user has no such post.
+func (r *PostRepo) DeletePost(ctx context.Context, userID, postID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_post", start, err)
+	}(time.Now())
+
+	sql := `WITH deleted AS (
+				UPDATE posts SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL RETURNING quote_of_id
+			),
+			counted AS (UPDATE posts SET quotes_count = quotes_count - 1 WHERE id IN (SELECT quote_of_id FROM deleted))
+			SELECT COUNT(*) FROM deleted`
+	var deleted int
+	if err = r.pool.QueryRow(ctx, sql, postID, userID).Scan(&deleted); err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return customerrors.ErrNoTagsAffected
+	}
+	return nil
+}
+
+// RestorePost undeletes a post of the user deleted since the given time and bumps the quotes counter of the post
+// it quoted. Returns customerrors.ErrNotFound if the user has no such post or it was deleted earlier.
+func (r *PostRepo) RestorePost(ctx context.Context, userID, postID uuid.UUID, deletedSince time.Time) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("restore_post", start, err)
+	}(time.Now())
+
+	sql := `WITH restored AS (
+				UPDATE posts SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at >= $3 RETURNING quote_of_id
+			),
+			counted AS (UPDATE posts SET quotes_count = quotes_count + 1 WHERE id IN (SELECT quote_of_id FROM restored))
+			SELECT COUNT(*) FROM restored`
+	var restored int
+	if err = r.pool.QueryRow(ctx, sql, postID, userID, deletedSince).Scan(&restored); err != nil {
+		return err
+	}
+	if restored == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// RemovePost deletes a post for good, whether or not its author deleted it before, and decrements the quotes
+// counter of the post it quoted if it was still published. Returns customerrors.ErrNotFound if there is no such post.
+func (r *PostRepo) RemovePost(ctx context.Context, postID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("remove_post", start, err)
+	}(time.Now())
+
+	sql := `WITH removed AS (DELETE FROM posts WHERE id = $1 RETURNING quote_of_id, deleted_at),
+			counted AS (
+				UPDATE posts SET quotes_count = quotes_count - 1 WHERE id IN (SELECT quote_of_id FROM removed WHERE deleted_at IS NULL)
+			)
+			SELECT COUNT(*) FROM removed`
+	var removed int
+	if err = r.pool.QueryRow(ctx, sql, postID).Scan(&removed); err != nil {
+		return err
+	}
+	if removed == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedPosts deletes for good the posts deleted before the given time and returns how many were purged.
+// Their likes, reposts, hashtags and comments go with them, quotes of them lose the reference.
+func (r *PostRepo) PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("purge_deleted_posts", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM posts WHERE deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListFeed returns posts published or reposted by the user and by the accounts they follow, newest first.
+// Reposts of posts the user may not see are left out.
+// Only entries older than the (beforeTime, beforePostID) position are returned unless beforeTime is zero.
+func (r *PostRepo) ListFeed(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int) (items []entity.FeedItem, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_feed", start, err)
+	}(time.Now())
+
+	authors := `SELECT $1::uuid AS id UNION SELECT followee_id FROM follows WHERE follower_id = $1`
+	return r.listFeed(ctx, authors, userID, beforeTime, beforePostID, limit)
+}
+
+// ListCelebrityFeed is ListFeed limited to the accounts the user follows that have at least minFollowers followers.
+func (r *PostRepo) ListCelebrityFeed(ctx context.Context, userID uuid.UUID, minFollowers int64, beforeTime time.Time, beforePostID uuid.UUID, limit int) (items []entity.FeedItem, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_celebrity_feed", start, err)
+	}(time.Now())
+
+	authors := `SELECT f.followee_id AS id FROM follows f JOIN profiles p ON p.user_id = f.followee_id
+				WHERE f.follower_id = $1 AND p.followers_count >= $5`
+	return r.listFeed(ctx, authors, userID, beforeTime, beforePostID, limit, minFollowers)
+}
+
+// listFeed returns the posts published or reposted by the authors the query selects, $5 and on are the extra args.
+func (r *PostRepo) listFeed(ctx context.Context, authors string, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int, args ...any) ([]entity.FeedItem, error) {
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := `WITH authors AS (` + authors + `),
+			entries AS (
+				SELECT id AS post_id, NULL::uuid AS reposted_by, created_at AS at FROM posts WHERE user_id IN (SELECT id FROM authors)
+				UNION ALL
+				SELECT post_id, user_id, created_at FROM reposts WHERE user_id IN (SELECT id FROM authors)
+			)
+			SELECT ` + postColumns + `, entries.reposted_by, entries.at
+			FROM entries JOIN posts ON posts.id = entries.post_id
+			WHERE ($2::timestamptz IS NULL OR (entries.at, entries.post_id) < ($2, $3)) AND ` + visibleTo("$1") + `
+			ORDER BY entries.at DESC, entries.post_id DESC
+			LIMIT $4`
+	rows, err := r.read(ctx).Query(ctx, sql, append([]any{userID, before, beforePostID, limit}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.FeedItem, error) {
+		var item entity.FeedItem
+		err := row.Scan(append(postFields(&item.Post), &item.RepostedBy, &item.At)...)
+		return item, err
+	})
+}
+
+// Affinities returns how many of the authors' posts the user liked or commented on since the given time, keyed by
+// author. Authors the user didn't interact with are left out.
+func (r *PostRepo) Affinities(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID, since time.Time) (affinities map[uuid.UUID]int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_affinities", start, err)
+	}(time.Now())
+
+	affinities = make(map[uuid.UUID]int64, len(authorIDs))
+	if len(authorIDs) == 0 {
+		return affinities, nil
+	}
+	sql := `SELECT p.user_id, COUNT(*) FROM (
+				SELECT post_id FROM likes WHERE user_id = $1 AND created_at >= $3
+				UNION ALL
+				SELECT post_id FROM comments WHERE user_id = $1 AND created_at >= $3 AND deleted_at IS NULL
+			) i JOIN posts p ON p.id = i.post_id
+			WHERE p.user_id = ANY($2)
+			GROUP BY p.user_id`
+	rows, err := r.read(ctx).Query(ctx, sql, userID, authorIDs, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var authorID uuid.UUID
+		var count int64
+		if err = rows.Scan(&authorID, &count); err != nil {
+			return nil, err
+		}
+		affinities[authorID] = count
+	}
+	return affinities, rows.Err()
+}
+
+// RefreshExploreRanking rebuilds the explore ranking from public posts of public accounts published since the given time
+// and returns how many posts were ranked. The score is the weighted engagement decayed by the age of the post.
+func (r *PostRepo) RefreshExploreRanking(ctx context.Context, since time.Time) (ranked int64, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("refresh_explore_ranking", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, "DELETE FROM post_rankings"); err != nil {
+		return 0, err
+	}
+	// engagement over (age in hours + 2)^1.5, so a post needs ever more engagement to stay on top as it ages
+	sql := `INSERT INTO post_rankings (post_id, score, computed_at)
+			SELECT id,
+				(likes_count + 2 * reposts_count + 2 * quotes_count + 3 * comments_count)
+					/ power(EXTRACT(EPOCH FROM NOW() - created_at) / 3600 + 2, 1.5),
+				NOW()
+			FROM posts
+			WHERE created_at >= $1 AND visibility = 'public' AND NOT limited AND deleted_at IS NULL
+				AND likes_count + reposts_count + quotes_count + comments_count > 0
+				AND NOT EXISTS (SELECT 1 FROM user_settings s WHERE s.user_id = posts.user_id AND s.private_account)`
+	tag, err := tx.Exec(ctx, sql, since)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+// ListExplore returns posts of the explore ranking that the viewer may see, highest score first.
+// Only posts ranked below the (beforeScore, beforeID) position are returned unless beforeID is uuid.Nil.
+func (r *PostRepo) ListExplore(ctx context.Context, viewerID uuid.UUID, beforeScore float64, beforeID uuid.UUID, limit int) (posts []entity.RankedPost, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_explore", start, err)
+	}(time.Now())
+
+	var before any
+	if beforeID != uuid.Nil {
+		before = beforeScore
+	}
+	sql := `SELECT ` + postColumns + `, pr.score FROM post_rankings pr JOIN posts ON posts.id = pr.post_id
+			WHERE ($2::float8 IS NULL OR (pr.score, pr.post_id) < ($2, $3))
+				AND ` + visibleTo("$1") + ` AND ` + discoverableTo("$1") + `
+			ORDER BY pr.score DESC, pr.post_id DESC
+			LIMIT $4`
+	rows, err := r.read(ctx).Query(ctx, sql, viewerID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	posts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.RankedPost, error) {
+		var ranked entity.RankedPost
+		err := row.Scan(append(postFields(&ranked.Post), &ranked.Score)...)
+		return ranked, err
+	})
+	return posts, err
+}
+
+// SearchPosts returns posts matching the full-text query that the viewer may see, most relevant first.
+// The query uses web search syntax ("quoted phrases", -excluded, or). Only posts ranked below
+// the (beforeRank, beforeID) position are returned unless beforeID is uuid.Nil.
+func (r *PostRepo) SearchPosts(ctx context.Context, viewerID uuid.UUID, query string, beforeRank float64, beforeID uuid.UUID, limit int) (posts []entity.RankedPost, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("search_posts", start, err)
+	}(time.Now())
+
+	var before any
+	if beforeID != uuid.Nil {
+		before = beforeRank
+	}
+	// ts_rank returns real, the cast keeps ranks exact when they come back in a cursor
+	sql := `WITH matched AS (
+				SELECT ` + postColumns + `, ts_rank(search_vector, websearch_to_tsquery('simple', $2))::float8 AS rank
+				FROM posts
+				WHERE search_vector @@ websearch_to_tsquery('simple', $2)
+					AND ` + visibleTo("$1") + ` AND ` + discoverableTo("$1") + `
+			)
+			SELECT * FROM matched
+			WHERE ($3::float8 IS NULL OR (rank, id) < ($3, $4))
+			ORDER BY rank DESC, id DESC
+			LIMIT $5`
+	rows, err := r.read(ctx).Query(ctx, sql, viewerID, query, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	posts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.RankedPost, error) {
+		var ranked entity.RankedPost
+		err := row.Scan(append(postFields(&ranked.Post), &ranked.Score)...)
+		return ranked, err
+	})
+	return posts, err
+}
+
+// Repost shares the post with the user's followers at the given time and reports whether it wasn't reposted before,
+// reposting twice changes nothing. The counter of the post is left to the caller.
+// Returns customerrors.ErrNotFound if the post doesn't exist.
+func (r *PostRepo) Repost(ctx context.Context, userID, postID uuid.UUID, at time.Time) (reposted bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_repost", start, err)
+	}(time.Now())
+
+	sql := `WITH post AS (SELECT id FROM posts WHERE id = $2 AND deleted_at IS NULL),
+			inserted AS (
+				INSERT INTO reposts (user_id, post_id, created_at) SELECT $1, id, $3 FROM post
+				ON CONFLICT (user_id, post_id) DO NOTHING
+				RETURNING post_id
+			)
+			SELECT EXISTS(SELECT 1 FROM post), EXISTS(SELECT 1 FROM inserted)`
+	var exists bool
+	if err = r.pool.QueryRow(ctx, sql, userID, postID, at).Scan(&exists, &reposted); err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, customerrors.ErrNotFound
+	}
+	return reposted, nil
+}
+
+// Unrepost removes the repost and reports whether there was one. The counter of the post is left to the caller.
+func (r *PostRepo) Unrepost(ctx context.Context, userID, postID uuid.UUID) (removed bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_repost", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM reposts WHERE user_id = $1 AND post_id = $2", userID, postID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// LikePost records the like and reports whether the post wasn't liked before, liking twice changes nothing.
+// The counter of the post is left to the caller.
+// Returns customerrors.ErrNotFound if the post doesn't exist.
+func (r *PostRepo) LikePost(ctx context.Context, userID, postID uuid.UUID) (liked bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_like", start, err)
+	}(time.Now())
+
+	sql := `WITH post AS (SELECT id FROM posts WHERE id = $2 AND deleted_at IS NULL),
+			inserted AS (
+				INSERT INTO likes (user_id, post_id) SELECT $1, id FROM post
+				ON CONFLICT (user_id, post_id) DO NOTHING
+				RETURNING post_id
+			)
+			SELECT EXISTS(SELECT 1 FROM post), EXISTS(SELECT 1 FROM inserted)`
+	var exists bool
+	if err = r.pool.QueryRow(ctx, sql, userID, postID).Scan(&exists, &liked); err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, customerrors.ErrNotFound
+	}
+	return liked, nil
+}
+
+// UnlikePost removes the like and reports whether there was one. The counter of the post is left to the caller.
+func (r *PostRepo) UnlikePost(ctx context.Context, userID, postID uuid.UUID) (removed bool, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_like", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM likes WHERE user_id = $1 AND post_id = $2", userID, postID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanPost(row pgx.Row) (entity.Post, error) {
+	var p entity.Post
+	err := row.Scan(postFields(&p)...)
+	return p, err
+}
+
+// postFields returns the scan destinations of postColumns.
+func postFields(p *entity.Post) []any {
+	return []any{&p.ID, &p.UserID, &p.Description, &p.MediaURL, &p.IsVideo, &p.Duration, &p.QuoteOfID, &p.Visibility,
+		&p.LikesCount, &p.RepostsCount, &p.QuotesCount, &p.CommentsCount, &p.CreatedAt, &p.UpdatedAt, &p.Version, &p.Hashtags, &p.Links}
+}
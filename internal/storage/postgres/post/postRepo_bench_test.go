@@ -0,0 +1,44 @@
+package post_test
+
+import (
+	"context"
+	"main/internal/storage/postgres"
+	"main/internal/storage/postgres/post"
+	"main/internal/storage/postgres/postgrestest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func BenchmarkListFeed(b *testing.B) {
+	db, m := postgrestest.Open(b)
+	repo := post.NewPostRepo(db, postgres.NewReplicas(db, nil), m)
+	userID := postgrestest.ActiveUser(b, db)
+	ctx := context.Background()
+
+	b.Run("first_page", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := repo.ListFeed(ctx, userID, time.Time{}, uuid.Nil, 20); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// a page deep into the feed, where the keyset position does the work instead of the ORDER BY ... LIMIT
+	items, err := repo.ListFeed(ctx, userID, time.Time{}, uuid.Nil, 100)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(items) == 0 {
+		b.Skip("the feed of the user is empty")
+	}
+	last := items[len(items)-1]
+	b.Run("deep_page", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := repo.ListFeed(ctx, userID, last.At, last.Post.ID, 20); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
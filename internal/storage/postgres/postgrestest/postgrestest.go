@@ -0,0 +1,44 @@
+// Package postgrestest connects the storage benchmarks to a database. They run against the migrated database of
+// TEST_DATABASE_URL, filled with cmd/seed, and are skipped when it isn't set, so `go test ./...` needs no database.
+package postgrestest
+
+import (
+	"context"
+	"main/internal/metrics"
+	"main/internal/storage/postgres"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Open connects to TEST_DATABASE_URL without retries, the pool is closed when the test ends.
+func Open(tb testing.TB) (*postgres.DB, *metrics.Metrics) {
+	tb.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+	pool, err := postgres.NewPostgresConnection(url, postgres.Options{QueryTimeout: 5 * time.Second})
+	if err != nil {
+		tb.Fatalf("failed to connect to the database: %v", err)
+	}
+	tb.Cleanup(pool.Close)
+
+	m := metrics.NewMetrics(prometheus.NewRegistry())
+	return postgres.NewDB("test", pool, postgres.RetryPolicy{Attempts: 1}, postgres.BreakerOptions{Threshold: 1 << 30}, m), m
+}
+
+// ActiveUser returns the seeded user following the most accounts, whose feed is the largest.
+func ActiveUser(tb testing.TB, db *postgres.DB) uuid.UUID {
+	tb.Helper()
+	var userID uuid.UUID
+	err := db.QueryRow(context.Background(),
+		"SELECT follower_id FROM follows GROUP BY follower_id ORDER BY COUNT(*) DESC LIMIT 1").Scan(&userID)
+	if err != nil {
+		tb.Skipf("no follows in the database, fill it with cmd/seed: %v", err)
+	}
+	return userID
+}
@@ -0,0 +1,139 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ProfileRepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewProfileRepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *ProfileRepo {
+	return &ProfileRepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *ProfileRepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+// GetProfile returns the profile of an active user. Restricted is set if the viewer isn't the user and the account
+// is private or its privacy level doesn't include the viewer, the caller hides the private fields.
+func (r *ProfileRepo) GetProfile(ctx context.Context, viewerID, userID uuid.UUID) (p entity.Profile, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_profile", start, err)
+	}(time.Now())
+
+	sql := `SELECT u.id, u.username, p.name, p.bio, p.avatar_url, p.gender, p.age, p.followers_count, p.following_count,
+				COALESCE(s.private_account, FALSE), p.updated_at, p.version,
+				COALESCE(u.id <> $1 AND (s.privacy_level = 'nobody'
+					OR (s.private_account OR s.privacy_level = 'followers')
+						AND NOT EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id)), FALSE)
+			FROM users u
+				JOIN profiles p ON p.user_id = u.id
+				LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE u.id = $2 AND u.deleted_at IS NULL`
+	var age *int16
+	err = r.read(ctx).QueryRow(ctx, sql, viewerID, userID).Scan(&p.UserID, &p.Username, &p.Name, &p.Bio, &p.AvatarURL, &p.Gender, &age,
+		&p.FollowersCount, &p.FollowingCount, &p.IsPrivate, &p.UpdatedAt, &p.Version, &p.Restricted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+		return entity.Profile{}, err
+	}
+	if err != nil {
+		return entity.Profile{}, err
+	}
+	if age != nil {
+		a := int(*age)
+		p.Age = &a
+	}
+	return p, nil
+}
+
+// GetProfiles returns the profiles of the active users among the IDs, in no particular order. Users who blocked
+// the viewer are left out; Restricted is set as GetProfile sets it.
+func (r *ProfileRepo) GetProfiles(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) (profiles []entity.Profile, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_profiles", start, err)
+	}(time.Now())
+
+	sql := `SELECT u.id, u.username, p.name, p.bio, p.avatar_url, p.gender, p.age, p.followers_count, p.following_count,
+				COALESCE(s.private_account, FALSE), p.updated_at, p.version,
+				COALESCE(u.id <> $1 AND (s.privacy_level = 'nobody'
+					OR (s.private_account OR s.privacy_level = 'followers')
+						AND NOT EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id)), FALSE)
+			FROM users u
+				JOIN profiles p ON p.user_id = u.id
+				LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE u.id = ANY($2) AND u.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM blacklist b WHERE b.blocker_id = u.id AND b.blocked_id = $1)`
+	rows, err := r.read(ctx).Query(ctx, sql, viewerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Profile, error) {
+		var (
+			p   entity.Profile
+			age *int16
+		)
+		err := row.Scan(&p.UserID, &p.Username, &p.Name, &p.Bio, &p.AvatarURL, &p.Gender, &age,
+			&p.FollowersCount, &p.FollowingCount, &p.IsPrivate, &p.UpdatedAt, &p.Version, &p.Restricted)
+		if age != nil {
+			a := int(*age)
+			p.Age = &a
+		}
+		return p, err
+	})
+}
+
+// UpdateProfile changes the non-nil fields of the user's profile, a zero age is stored as not specified.
+// Returns customerrors.ErrNotFound if the user has no profile and customerrors.ErrVersionConflict if the
+// profile isn't at the version of the update anymore.
+func (r *ProfileRepo) UpdateProfile(ctx context.Context, userID uuid.UUID, update entity.ProfileUpdate) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_profile", start, err)
+	}(time.Now())
+
+	sql := `UPDATE profiles SET
+				name = COALESCE($2, name),
+				bio = COALESCE($3, bio),
+				avatar_url = COALESCE($4, avatar_url),
+				gender = COALESCE($5, gender),
+				age = CASE WHEN $6::smallint IS NULL THEN age ELSE NULLIF($6, 0) END,
+				updated_at = NOW()
+			WHERE user_id = $1 AND version = $7`
+	tag, err := r.pool.Exec(ctx, sql, userID, update.Name, update.Bio, update.AvatarURL, update.Gender, update.Age, update.Version)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err = r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM profiles WHERE user_id = $1)", userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	err = customerrors.ErrVersionConflict
+	return err
+}
@@ -0,0 +1,104 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PushRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewPushRepo(pool *postgres.DB, metrics *metrics.Metrics) *PushRepo {
+	return &PushRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+const selectDevice = `SELECT id, user_id, platform, token, COALESCE(p256dh, ''), COALESCE(auth, ''), created_at FROM push_devices`
+
+func scanDevice(row pgx.Row) (entity.PushDevice, error) {
+	var d entity.PushDevice
+	err := row.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.P256dh, &d.Auth, &d.CreatedAt)
+	return d, err
+}
+
+// AddDevice registers the device of the user and returns it. A token registered before, by the user or by someone
+// else on the same device, is moved to the user with the new keys.
+func (r *PushRepo) AddDevice(ctx context.Context, device entity.PushDevice) (d entity.PushDevice, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("upsert_push_device", start, err)
+	}(time.Now())
+
+	sql := `INSERT INTO push_devices (user_id, platform, token, p256dh, auth) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+			ON CONFLICT (platform, token) DO UPDATE SET user_id = EXCLUDED.user_id, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth,
+				created_at = CASE WHEN push_devices.user_id = EXCLUDED.user_id THEN push_devices.created_at ELSE NOW() END
+			RETURNING id, user_id, platform, token, COALESCE(p256dh, ''), COALESCE(auth, ''), created_at`
+	return scanDevice(r.pool.QueryRow(ctx, sql, device.UserID, device.Platform, device.Token, device.P256dh, device.Auth))
+}
+
+// DeleteDevice unregisters a device of the user, returns customerrors.ErrNotFound if the user has no such device.
+func (r *PushRepo) DeleteDevice(ctx context.Context, userID, deviceID uuid.UUID) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_push_device", start, err)
+	}(time.Now())
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM push_devices WHERE id = $1 AND user_id = $2", deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = customerrors.ErrNotFound
+		return err
+	}
+	return nil
+}
+
+// DeleteToken forgets a token the push service stopped accepting.
+func (r *PushRepo) DeleteToken(ctx context.Context, platform entity.PushPlatform, token string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_push_token", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM push_devices WHERE platform = $1 AND token = $2", platform, token)
+	return err
+}
+
+// ListDevices returns the devices of the users, most recently registered first.
+func (r *PushRepo) ListDevices(ctx context.Context, userIDs []uuid.UUID) (devices []entity.PushDevice, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_push_devices", start, err)
+	}(time.Now())
+
+	rows, err := r.pool.Query(ctx, selectDevice+" WHERE user_id = ANY($1) ORDER BY created_at DESC, id DESC", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	devices, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.PushDevice, error) {
+		return scanDevice(row)
+	})
+	return devices, err
+}
+
+// Username returns the username of an active user, returns customerrors.ErrNotFound if there is no such user.
+func (r *PushRepo) Username(ctx context.Context, userID uuid.UUID) (username string, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_push_username", start, err)
+	}(time.Now())
+
+	err = r.pool.QueryRow(ctx, "SELECT username FROM users WHERE id = $1 AND deleted_at IS NULL", userID).Scan(&username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return username, err
+}
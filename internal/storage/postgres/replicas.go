@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"sync/atomic"
+
+	"main/pkg/txmanager"
+)
+
+// Replicas spreads reads that tolerate replication lag over the read replicas, round-robin, skipping the replicas
+// whose circuit breaker is open. Without an available replica the reads go to the primary.
+type Replicas struct {
+	primary *DB
+	dbs     []*DB
+	next    atomic.Uint64
+}
+
+func NewReplicas(primary *DB, dbs []*DB) *Replicas {
+	return &Replicas{
+		primary: primary,
+		dbs:     dbs,
+	}
+}
+
+// Pool returns the database the next read goes to.
+func (r *Replicas) Pool() txmanager.Querier {
+	for range r.dbs {
+		db := r.dbs[(r.next.Add(1)-1)%uint64(len(r.dbs))]
+		if db.Available() {
+			return db
+		}
+	}
+	return r.primary
+}
@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"main/internal/metrics"
+	"main/pkg/customerrors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RetryPolicy is how statements that failed with a transient error are retried.
+type RetryPolicy struct {
+	// Attempts is how many times a statement is run at most, 1 disables retries.
+	Attempts int
+	// Backoff is the wait before the first retry, it doubles with every retry up to MaxBackoff. Each wait is
+	// jittered so that callers failing together don't retry together.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// BreakerOptions configure the circuit breaker of a pool.
+type BreakerOptions struct {
+	// Threshold is how many consecutive connection failures open the breaker.
+	Threshold int
+	// OpenTimeout is how long the breaker fails calls fast before it lets a probe through.
+	OpenTimeout time.Duration
+}
+
+// DB is the pool repositories run statements on. Statements outside of a transaction that fail with a transient
+// error, a serialization failure, a deadlock or a connection that broke before the statement was sent, are
+// retried with backoff. Statements in a transaction aren't: the transaction is rolled back and must be run again
+// as a whole. While the circuit breaker is open calls fail fast with customerrors.ErrDatabaseUnavailable.
+type DB struct {
+	name    string
+	pool    *pgxpool.Pool
+	policy  RetryPolicy
+	breaker *Breaker
+	metrics *metrics.Metrics
+}
+
+// NewDB wraps the pool, name labels its metrics, e.g. "primary".
+func NewDB(name string, pool *pgxpool.Pool, policy RetryPolicy, breaker BreakerOptions, metrics *metrics.Metrics) *DB {
+	metrics.DbBreakerState.WithLabelValues(name).Set(float64(BreakerClosed))
+	return &DB{
+		name:   name,
+		pool:   pool,
+		policy: policy,
+		breaker: NewBreaker(breaker.Threshold, breaker.OpenTimeout, func(state BreakerState) {
+			metrics.DbBreakerState.WithLabelValues(name).Set(float64(state))
+		}),
+		metrics: metrics,
+	}
+}
+
+// Available reports whether calls reach the database, false while the circuit breaker is open.
+func (db *DB) Available() bool {
+	return !db.breaker.Open()
+}
+
+func (db *DB) Exec(ctx context.Context, sql string, args ...any) (tag pgconn.CommandTag, err error) {
+	err = db.do(ctx, func() error {
+		tag, err = db.pool.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Query retries only errors returned by Query itself, errors reading the rows are returned by rows.Err.
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (rows pgx.Rows, err error) {
+	err = db.do(ctx, func() error {
+		rows, err = db.pool.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow runs the statement when the row is scanned, so that Scan can retry it.
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &row{db: db, ctx: ctx, sql: sql, args: args}
+}
+
+// Begin retries starting the transaction, the statements of the transaction run on it directly.
+func (db *DB) Begin(ctx context.Context) (tx pgx.Tx, err error) {
+	err = db.do(ctx, func() error {
+		tx, err = db.pool.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+// SendBatch isn't retried, the results are read after it returns. It fails fast while the breaker is open.
+func (db *DB) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	if db.breaker.Open() {
+		db.metrics.DbBreakerRejections.WithLabelValues(db.name).Inc()
+		return errBatchResults{err: customerrors.ErrDatabaseUnavailable}
+	}
+	return db.pool.SendBatch(ctx, batch)
+}
+
+func (db *DB) do(ctx context.Context, op func() error) error {
+	backoff := db.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		if !db.breaker.Allow() {
+			db.metrics.DbBreakerRejections.WithLabelValues(db.name).Inc()
+			return customerrors.ErrDatabaseUnavailable
+		}
+		err := op()
+		// a caller that went away says nothing about the database, a deadline that passed does
+		db.breaker.Record(unhealthy(err) && !errors.Is(ctx.Err(), context.Canceled))
+		if err == nil || ctx.Err() != nil || attempt >= db.policy.Attempts || !retryable(err) {
+			return err
+		}
+		db.metrics.DbRetries.WithLabelValues(db.name).Inc()
+		timer := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, db.policy.MaxBackoff)
+	}
+}
+
+// retryable reports whether running the statement again can't apply it twice: it was rolled back, or it never
+// reached the server.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected, cannot_connect_now and the connection exceptions
+		return pgErr.Code == "40001" || pgErr.Code == "40P01" || pgErr.Code == "57P03" || strings.HasPrefix(pgErr.Code, "08")
+	}
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.SafeToRetry(err)
+}
+
+// unhealthy reports whether err tells the database is unreachable or overloaded, rather than that the statement
+// itself failed, e.g. on a constraint.
+func unhealthy(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// connection exceptions, insufficient resources and the server shutting down or starting up
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) || pgconn.Timeout(err) || pgconn.SafeToRetry(err) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+type row struct {
+	db   *DB
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+func (r *row) Scan(dest ...any) error {
+	return r.db.do(r.ctx, func() error {
+		return r.db.pool.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+// errBatchResults is what SendBatch returns without sending the batch, every call returns the error.
+type errBatchResults struct {
+	err error
+}
+
+func (b errBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, b.err
+}
+
+func (b errBatchResults) Query() (pgx.Rows, error) {
+	return nil, b.err
+}
+
+func (b errBatchResults) QueryRow() pgx.Row {
+	return errRow{err: b.err}
+}
+
+func (b errBatchResults) Close() error {
+	return b.err
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
@@ -0,0 +1,56 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockNamespace is the first key of the advisory locks of scheduled jobs, the second one is the hash of the
+// job name, so the locks don't collide with the single key locks of the outbox relays.
+const lockNamespace = 7503
+
+type ScheduleRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewScheduleRepo(pool *pgxpool.Pool) *ScheduleRepo {
+	return &ScheduleRepo{pool: pool}
+}
+
+// RunIfDue runs fn if the job didn't start within interval on any instance, and reports whether it ran. The job
+// holds an advisory lock while it runs, a call made while it runs elsewhere returns right away. A failed run
+// isn't recorded, so the job is due again on the next call.
+func (r *ScheduleRepo) RunIfDue(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	// a session lock on a connection of its own, so fn may use the pool and run as long as it takes
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", lockNamespace, name).Scan(&locked); err != nil || !locked {
+		return false, err
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1, hashtext($2))", lockNamespace, name)
+
+	var due bool
+	var startedAt time.Time
+	err = conn.QueryRow(ctx,
+		`SELECT NOT EXISTS (SELECT 1 FROM scheduled_jobs WHERE name = $1 AND last_run_at > NOW() - make_interval(secs => $2)), NOW()`,
+		name, interval.Seconds()).Scan(&due, &startedAt)
+	if err != nil || !due {
+		return false, err
+	}
+
+	if err := fn(ctx); err != nil {
+		return true, err
+	}
+	// the start is recorded, so the job runs every interval however long it takes
+	_, err = conn.Exec(ctx,
+		"INSERT INTO scheduled_jobs (name, last_run_at) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET last_run_at = EXCLUDED.last_run_at",
+		name, startedAt)
+	return true, err
+}
@@ -0,0 +1,177 @@
+package seo
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"main/pkg/txmanager"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type SEORepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewSEORepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *SEORepo {
+	return &SEORepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *SEORepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+// indexable is the condition for the users whose profiles and posts crawlers see: accounts that aren't deleted,
+// private or hidden from anyone. The users table is u, their settings s.
+const indexable = `u.deleted_at IS NULL AND NOT COALESCE(s.private_account, FALSE) AND COALESCE(s.privacy_level, 'everyone') = 'everyone'`
+
+// GetPostPreview returns a public post of an indexable user with the profile of its author.
+// Returns customerrors.ErrNotFound if there is no such post.
+func (r *SEORepo) GetPostPreview(ctx context.Context, postID uuid.UUID) (post entity.Post, author entity.Profile, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_post_preview", start, err)
+	}(time.Now())
+
+	sql := `SELECT posts.id, posts.user_id, posts.description, COALESCE(posts.media_url, ''), posts.is_video, posts.created_at,
+				u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, '')
+			FROM posts
+				JOIN users u ON u.id = posts.user_id
+				LEFT JOIN profiles p ON p.user_id = u.id
+				LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE posts.id = $1 AND posts.deleted_at IS NULL AND posts.visibility = 'public' AND NOT posts.limited AND ` + indexable
+	err = r.pool.QueryRow(ctx, sql, postID).Scan(&post.ID, &post.UserID, &post.Description, &post.MediaURL, &post.IsVideo,
+		&post.CreatedAt, &author.Username, &author.Name, &author.AvatarURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+		return entity.Post{}, entity.Profile{}, err
+	}
+	author.UserID = post.UserID
+	return post, author, err
+}
+
+// ListIndexableProfiles returns the usernames and update times of the indexable users with IDs after afterID,
+// ordered by ID.
+func (r *SEORepo) ListIndexableProfiles(ctx context.Context, afterID uuid.UUID, limit int) (profiles []entity.Profile, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_indexable_profiles", start, err)
+	}(time.Now())
+
+	sql := `SELECT u.id, u.username, COALESCE(p.updated_at, u.created_at)
+			FROM users u
+				LEFT JOIN profiles p ON p.user_id = u.id
+				LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE u.id > $1 AND ` + indexable + `
+			ORDER BY u.id
+			LIMIT $2`
+	rows, err := r.read(ctx).Query(ctx, sql, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Profile, error) {
+		var p entity.Profile
+		err := row.Scan(&p.UserID, &p.Username, &p.UpdatedAt)
+		return p, err
+	})
+}
+
+// ListIndexablePosts returns the IDs and times of the public posts of indexable users, newest first. Only posts
+// older than the (beforeTime, beforeID) position are returned unless beforeTime is zero.
+func (r *SEORepo) ListIndexablePosts(ctx context.Context, beforeTime time.Time, beforeID uuid.UUID, limit int) (posts []entity.Post, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_indexable_posts", start, err)
+	}(time.Now())
+
+	var before any
+	if !beforeTime.IsZero() {
+		before = beforeTime
+	}
+	sql := `SELECT posts.id, posts.user_id, posts.created_at, posts.updated_at
+			FROM posts
+				JOIN users u ON u.id = posts.user_id
+				LEFT JOIN user_settings s ON s.user_id = u.id
+			WHERE posts.deleted_at IS NULL AND posts.visibility = 'public' AND NOT posts.limited AND ` + indexable + `
+				AND ($1::timestamptz IS NULL OR (posts.created_at, posts.id) < ($1, $2))
+			ORDER BY posts.created_at DESC, posts.id DESC
+			LIMIT $3`
+	rows, err := r.read(ctx).Query(ctx, sql, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Post, error) {
+		var p entity.Post
+		err := row.Scan(&p.ID, &p.UserID, &p.CreatedAt, &p.UpdatedAt)
+		return p, err
+	})
+}
+
+// ReplaceSitemaps replaces the stored sitemaps with the generated ones in one transaction, so the sitemap index
+// never lists a mix of two runs.
+func (r *SEORepo) ReplaceSitemaps(ctx context.Context, sitemaps []entity.Sitemap) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("replace_sitemaps", start, err)
+	}(time.Now())
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, "DELETE FROM sitemaps"); err != nil {
+		return err
+	}
+	for _, sitemap := range sitemaps {
+		_, err = tx.Exec(ctx, "INSERT INTO sitemaps (idx, content, url_count, generated_at) VALUES ($1, $2, $3, $4)",
+			sitemap.Index, string(sitemap.Content), sitemap.URLCount, sitemap.GeneratedAt)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit(ctx)
+	return err
+}
+
+// ListSitemaps returns the stored sitemaps without their content, ordered by index.
+func (r *SEORepo) ListSitemaps(ctx context.Context) (sitemaps []entity.Sitemap, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_sitemaps", start, err)
+	}(time.Now())
+
+	rows, err := r.read(ctx).Query(ctx, "SELECT idx, url_count, generated_at FROM sitemaps ORDER BY idx")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Sitemap, error) {
+		var s entity.Sitemap
+		err := row.Scan(&s.Index, &s.URLCount, &s.GeneratedAt)
+		return s, err
+	})
+}
+
+// GetSitemap returns a stored sitemap, customerrors.ErrNotFound if there is none with the index.
+func (r *SEORepo) GetSitemap(ctx context.Context, index int) (sitemap entity.Sitemap, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_sitemap", start, err)
+	}(time.Now())
+
+	var content string
+	err = r.read(ctx).QueryRow(ctx, "SELECT idx, content, url_count, generated_at FROM sitemaps WHERE idx = $1", index).
+		Scan(&sitemap.Index, &content, &sitemap.URLCount, &sitemap.GeneratedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	sitemap.Content = []byte(content)
+	return sitemap, err
+}
@@ -0,0 +1,137 @@
+package sessionevents
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/metrics"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// channel is the Postgres NOTIFY channel session events are sent on.
+const channel = "session_events"
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before new events are dropped for it.
+const subscriberBuffer = 16
+
+// Broker delivers session events to subscribers on every instance through Postgres LISTEN/NOTIFY:
+// Publish sends a notification, Run listens on a dedicated connection and fans the events out to local subscribers.
+type Broker struct {
+	pool    *pgxpool.Pool
+	Metrics *metrics.Metrics
+	logger  *slog.Logger
+
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan entity.SessionEvent]struct{}
+}
+
+func NewBroker(pool *pgxpool.Pool, metrics *metrics.Metrics, logger *slog.Logger) *Broker {
+	return &Broker{
+		pool:    pool,
+		Metrics: metrics,
+		logger:  logger,
+		subs:    make(map[uuid.UUID]map[chan entity.SessionEvent]struct{}),
+	}
+}
+
+// Publish notifies the subscribers of the event's user on all instances.
+func (b *Broker) Publish(ctx context.Context, event entity.SessionEvent) (err error) {
+	defer func(start time.Time) {
+		b.Metrics.ObserveDB("notify_session_event", start, err)
+	}(time.Now())
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = b.pool.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(payload))
+	return err
+}
+
+// Subscribe returns the events of the user until cancel is called, which also closes the channel.
+func (b *Broker) Subscribe(userID uuid.UUID) (<-chan entity.SessionEvent, func()) {
+	ch := make(chan entity.SessionEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[chan entity.SessionEvent]struct{})
+	}
+	b.subs[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[userID], ch)
+			if len(b.subs[userID]) == 0 {
+				delete(b.subs, userID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Run listens for session events until ctx is cancelled, reconnecting when the connection is lost.
+// Events published while the listener is reconnecting are not delivered.
+func (b *Broker) Run(ctx context.Context) error {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		b.logger.Error("Session events listener failed, reconnecting", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection stays in LISTEN mode, so it is taken out of the pool for good
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var event entity.SessionEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			b.logger.Warn("Malformed session event", "payload", notification.Payload, "error", err)
+			continue
+		}
+		b.dispatch(event)
+	}
+}
+
+// dispatch hands the event to the local subscribers of its user without blocking on slow ones.
+func (b *Broker) dispatch(event entity.SessionEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			b.logger.Warn("Session event dropped for slow subscriber", "user_id", event.UserID)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type SettingsRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewSettingsRepo(pool *postgres.DB, metrics *metrics.Metrics) *SettingsRepo {
+	return &SettingsRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+const settingsColumns = `user_id, privacy_level, private_account,
+	notify_likes, notify_comments, notify_follows, notify_mentions, notify_messages, email_digest, updated_at, version`
+
+func scanSettings(row pgx.Row) (entity.UserSettings, error) {
+	var s entity.UserSettings
+	n := &s.Notifications
+	err := row.Scan(&s.UserID, &s.PrivacyLevel, &s.PrivateAccount,
+		&n.Likes, &n.Comments, &n.Follows, &n.Mentions, &n.Messages, &n.EmailDigest, &s.UpdatedAt, &s.Version)
+	return s, err
+}
+
+// GetSettings returns the settings of the user, customerrors.ErrNotFound if the user doesn't exist.
+func (r *SettingsRepo) GetSettings(ctx context.Context, userID uuid.UUID) (settings entity.UserSettings, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_user_settings", start, err)
+	}(time.Now())
+
+	settings, err = scanSettings(r.pool.QueryRow(ctx, "SELECT "+settingsColumns+" FROM user_settings WHERE user_id = $1", userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = customerrors.ErrNotFound
+	}
+	return settings, err
+}
+
+// UpdateSettings changes the non-nil settings of the user and returns the updated settings,
+// customerrors.ErrNotFound if the user doesn't exist and customerrors.ErrVersionConflict if the settings aren't
+// at the version of the update anymore.
+func (r *SettingsRepo) UpdateSettings(ctx context.Context, userID uuid.UUID, update entity.SettingsUpdate) (settings entity.UserSettings, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("update_user_settings", start, err)
+	}(time.Now())
+
+	sql := `UPDATE user_settings SET
+				privacy_level = COALESCE($2, privacy_level),
+				private_account = COALESCE($3, private_account),
+				notify_likes = COALESCE($4, notify_likes),
+				notify_comments = COALESCE($5, notify_comments),
+				notify_follows = COALESCE($6, notify_follows),
+				notify_mentions = COALESCE($7, notify_mentions),
+				notify_messages = COALESCE($8, notify_messages),
+				email_digest = COALESCE($9, email_digest),
+				updated_at = NOW()
+			WHERE user_id = $1 AND version = $10
+			RETURNING ` + settingsColumns
+	settings, err = scanSettings(r.pool.QueryRow(ctx, sql, userID, update.PrivacyLevel, update.PrivateAccount,
+		update.NotifyLikes, update.NotifyComments, update.NotifyFollows, update.NotifyMentions, update.NotifyMessages, update.EmailDigest,
+		update.Version))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return settings, err
+	}
+
+	var exists bool
+	if err = r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM user_settings WHERE user_id = $1)", userID).Scan(&exists); err != nil {
+		return entity.UserSettings{}, err
+	}
+	if !exists {
+		err = customerrors.ErrNotFound
+		return entity.UserSettings{}, err
+	}
+	err = customerrors.ErrVersionConflict
+	return entity.UserSettings{}, err
+}
@@ -0,0 +1,63 @@
+package signingkey
+
+import (
+	"context"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SigningKeyRepo struct {
+	pool    *postgres.DB
+	Metrics *metrics.Metrics
+}
+
+func NewSigningKeyRepo(pool *postgres.DB, metrics *metrics.Metrics) *SigningKeyRepo {
+	return &SigningKeyRepo{
+		pool:    pool,
+		Metrics: metrics,
+	}
+}
+
+// ListSigningKeys returns every stored signing key, ordered by the time it becomes active. Keys are read from
+// the primary, a replica lagging behind a rotation would hide the new key.
+func (r *SigningKeyRepo) ListSigningKeys(ctx context.Context) (keys []entity.SigningKey, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("select_signing_keys", start, err)
+	}(time.Now())
+
+	rows, err := r.pool.Query(ctx, "SELECT id, encrypted_key, created_at, active_at FROM signing_keys ORDER BY active_at, created_at")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.SigningKey, error) {
+		var k entity.SigningKey
+		err := row.Scan(&k.ID, &k.EncryptedKey, &k.CreatedAt, &k.ActiveAt)
+		return k, err
+	})
+}
+
+// CreateSigningKey stores a signing key. A key with the same ID is kept, so instances starting at once with the
+// same configured key store it once.
+func (r *SigningKeyRepo) CreateSigningKey(ctx context.Context, key entity.SigningKey) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("insert_signing_key", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "INSERT INTO signing_keys (id, encrypted_key, active_at) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING",
+		key.ID, key.EncryptedKey, key.ActiveAt)
+	return err
+}
+
+// DeleteSigningKeys deletes the signing keys with the IDs.
+func (r *SigningKeyRepo) DeleteSigningKeys(ctx context.Context, ids []string) (err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("delete_signing_keys", start, err)
+	}(time.Now())
+
+	_, err = r.pool.Exec(ctx, "DELETE FROM signing_keys WHERE id = ANY($1)", ids)
+	return err
+}
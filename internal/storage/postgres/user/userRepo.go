@@ -0,0 +1,69 @@
+package user
+
+import (
+	"context"
+	"main/domain/entity"
+	metrics "main/internal/metrics"
+	"main/internal/storage/postgres"
+	"main/pkg/txmanager"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type UserRepo struct {
+	pool     *postgres.DB
+	replicas txmanager.Replicas
+	Metrics  *metrics.Metrics
+}
+
+func NewUserRepo(pool *postgres.DB, replicas txmanager.Replicas, metrics *metrics.Metrics) *UserRepo {
+	return &UserRepo{
+		pool:     pool,
+		replicas: replicas,
+		Metrics:  metrics,
+	}
+}
+
+// read returns what reads that tolerate replication lag run on.
+func (r *UserRepo) read(ctx context.Context) txmanager.Querier {
+	return txmanager.ForRead(ctx, r.replicas)
+}
+
+// likeEscaper escapes the LIKE wildcards of user input, '\' is the default escape character of LIKE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers returns active users whose username starts with the query or whose display name contains it,
+// with the follow state between them and the viewer, users who blocked the viewer are left out. Exact and prefix username matches come first,
+// then the closest trigram matches.
+func (r *UserRepo) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit int) (cards []entity.UserCard, err error) {
+	defer func(start time.Time) {
+		r.Metrics.ObserveDB("search_users", start, err)
+	}(time.Now())
+
+	pattern := likeEscaper.Replace(strings.ToLower(query))
+	sql := `SELECT u.id, u.username, COALESCE(p.name, ''), COALESCE(p.avatar_url, ''),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.followee_id = u.id),
+				EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = u.id AND f.followee_id = $1)
+			FROM users u LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE u.deleted_at IS NULL AND u.is_blocked IS NOT TRUE
+				AND NOT EXISTS (SELECT 1 FROM blacklist b WHERE b.blocker_id = u.id AND b.blocked_id = $1)
+				AND (LOWER(u.username) LIKE $2 || '%' OR LOWER(p.name) LIKE '%' || $2 || '%')
+			ORDER BY LOWER(u.username) = $3 DESC,
+				LOWER(u.username) LIKE $2 || '%' DESC,
+				GREATEST(similarity(LOWER(u.username), $3), similarity(LOWER(COALESCE(p.name, '')), $3)) DESC,
+				u.username
+			LIMIT $4`
+	rows, err := r.read(ctx).Query(ctx, sql, viewerID, pattern, strings.ToLower(query), limit)
+	if err != nil {
+		return nil, err
+	}
+	cards, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.UserCard, error) {
+		var c entity.UserCard
+		err := row.Scan(&c.ID, &c.Username, &c.Name, &c.AvatarURL, &c.Following, &c.FollowsYou)
+		return c, err
+	})
+	return cards, err
+}
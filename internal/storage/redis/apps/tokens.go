@@ -0,0 +1,84 @@
+package apps
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	tokenPrefix     = "apps:token:"
+	appTokensPrefix = "apps:tokens:"
+)
+
+// Tokens keeps the access tokens issued to developer apps until they expire, keyed by the hash of the token.
+// The hashes of the tokens of each app are kept in a set too, so the tokens of an app can be revoked at once.
+type Tokens struct {
+	client *redis.Client
+}
+
+func NewTokens(client *redis.Client) *Tokens {
+	return &Tokens{
+		client: client,
+	}
+}
+
+// Store keeps the token under its hash until it expires.
+func (t *Tokens) Store(ctx context.Context, tokenHash []byte, token entity.AppToken) error {
+	value, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(token.ExpiresAt)
+	pipe := t.client.TxPipeline()
+	pipe.Set(ctx, tokenKey(tokenHash), value, ttl)
+	pipe.SAdd(ctx, appTokensKey(token.AppID), hex.EncodeToString(tokenHash))
+	// the set lives as long as the newest token of the app, the hashes of expired tokens in it are harmless
+	pipe.Expire(ctx, appTokensKey(token.AppID), ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get returns the token with the hash, customerrors.ErrInvalidToken if it expired or was revoked.
+func (t *Tokens) Get(ctx context.Context, tokenHash []byte) (entity.AppToken, error) {
+	value, err := t.client.Get(ctx, tokenKey(tokenHash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return entity.AppToken{}, customerrors.ErrInvalidToken
+	}
+	if err != nil {
+		return entity.AppToken{}, err
+	}
+	var token entity.AppToken
+	if err := json.Unmarshal(value, &token); err != nil {
+		return entity.AppToken{}, err
+	}
+	return token, nil
+}
+
+// RevokeApp drops every token of the app.
+func (t *Tokens) RevokeApp(ctx context.Context, appID uuid.UUID) error {
+	hashes, err := t.client.SMembers(ctx, appTokensKey(appID)).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{appTokensKey(appID)}
+	for _, hash := range hashes {
+		keys = append(keys, tokenPrefix+hash)
+	}
+	return t.client.Del(ctx, keys...).Err()
+}
+
+func tokenKey(tokenHash []byte) string {
+	return tokenPrefix + hex.EncodeToString(tokenHash)
+}
+
+func appTokensKey(appID uuid.UUID) string {
+	return appTokensPrefix + appID.String()
+}
@@ -0,0 +1,73 @@
+package apps
+
+import (
+	"context"
+	"main/domain/entity"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	usagePrefix = "apps:usage:"
+	dayLayout   = "2006-01-02"
+)
+
+// Usage counts the requests of developer apps per day in a hash per app and day, which expires after the
+// retention.
+type Usage struct {
+	client    *redis.Client
+	retention time.Duration
+}
+
+func NewUsage(client *redis.Client, retention time.Duration) *Usage {
+	return &Usage{
+		client:    client,
+		retention: retention,
+	}
+}
+
+// Record counts a request of the app made at the given time, throttled if its rate limit rejected it.
+func (u *Usage) Record(ctx context.Context, appID uuid.UUID, at time.Time, throttled bool) error {
+	key := usageKey(appID, at)
+	field := "requests"
+	if throttled {
+		field = "throttled"
+	}
+	pipe := u.client.Pipeline()
+	pipe.HIncrBy(ctx, key, field, 1)
+	pipe.Expire(ctx, key, u.retention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Days returns the usage of the app on each of the days days up to the given time, newest first. Days without
+// requests are included with zero counts.
+func (u *Usage) Days(ctx context.Context, appID uuid.UUID, until time.Time, days int) ([]entity.AppUsage, error) {
+	pipe := u.client.Pipeline()
+	counts := make([]*redis.MapStringStringCmd, days)
+	for i := range days {
+		counts[i] = pipe.HGetAll(ctx, usageKey(appID, until.AddDate(0, 0, -i)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	usage := make([]entity.AppUsage, days)
+	for i, cmd := range counts {
+		values := cmd.Val()
+		requests, _ := strconv.ParseInt(values["requests"], 10, 64)
+		throttled, _ := strconv.ParseInt(values["throttled"], 10, 64)
+		usage[i] = entity.AppUsage{
+			Day:       until.AddDate(0, 0, -i).UTC().Format(dayLayout),
+			Requests:  requests,
+			Throttled: throttled,
+		}
+	}
+	return usage, nil
+}
+
+func usageKey(appID uuid.UUID, at time.Time) string {
+	return usagePrefix + appID.String() + ":" + at.UTC().Format(dayLayout)
+}
@@ -0,0 +1,146 @@
+package chatevents
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"main/domain/entity"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// channel is the Redis pub/sub channel chat events are relayed on.
+const channel = "chat_events"
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before new events are dropped for it.
+const subscriberBuffer = 64
+
+// envelope is a chat event on the wire together with the users it is delivered to.
+type envelope struct {
+	Recipients []uuid.UUID      `json:"recipients"`
+	Event      entity.ChatEvent `json:"event"`
+}
+
+// Broker delivers chat events to the connections of their recipients. Without a Redis client it is an in-process hub
+// and Publish dispatches to local subscribers directly; with one, Publish sends the event to Redis and Run fans
+// the events of all instances out to local subscribers.
+type Broker struct {
+	client *redis.Client
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan entity.ChatEvent]struct{}
+}
+
+// NewBroker returns a broker relaying events through Redis pub/sub, or an in-process one if client is nil.
+func NewBroker(client *redis.Client, logger *slog.Logger) *Broker {
+	return &Broker{
+		client: client,
+		logger: logger,
+		subs:   make(map[uuid.UUID]map[chan entity.ChatEvent]struct{}),
+	}
+}
+
+// Publish delivers the event to the subscribers of the recipients on all instances.
+func (b *Broker) Publish(ctx context.Context, event entity.ChatEvent, recipients []uuid.UUID) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+	if b.client == nil {
+		b.dispatch(envelope{Recipients: recipients, Event: event})
+		return nil
+	}
+	payload, err := json.Marshal(envelope{Recipients: recipients, Event: event})
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe returns the events of the user until cancel is called, which also closes the channel.
+func (b *Broker) Subscribe(userID uuid.UUID) (<-chan entity.ChatEvent, func()) {
+	ch := make(chan entity.ChatEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[chan entity.ChatEvent]struct{})
+	}
+	b.subs[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[userID], ch)
+			if len(b.subs[userID]) == 0 {
+				delete(b.subs, userID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Run relays the events published by all instances until ctx is cancelled, resubscribing when the connection is lost.
+// Events published while it is resubscribing are not delivered. Without a Redis client it only waits for ctx.
+func (b *Broker) Run(ctx context.Context) error {
+	if b.client == nil {
+		<-ctx.Done()
+		return nil
+	}
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		b.logger.Error("Chat events subscription failed, resubscribing", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	pubsub := b.client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	// the first receive confirms the subscription
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var env envelope
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			b.logger.Warn("Malformed chat event", "payload", msg.Payload, "error", err)
+			continue
+		}
+		b.dispatch(env)
+	}
+}
+
+// dispatch hands the event to the local subscribers of its recipients without blocking on slow ones.
+func (b *Broker) dispatch(env envelope) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, userID := range env.Recipients {
+		for ch := range b.subs[userID] {
+			select {
+			case ch <- env.Event:
+			default:
+				b.logger.Warn("Chat event dropped for slow subscriber", "user_id", userID)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package counters
+
+import (
+	"context"
+	"main/domain/entity"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "counters:pending:"
+
+// take reads and removes the pending deltas of a counter at once, so increments made meanwhile go to the next flush.
+var take = redis.NewScript(`
+local deltas = redis.call('HGETALL', KEYS[1])
+redis.call('DEL', KEYS[1])
+return deltas`)
+
+// Pending keeps counter increments that weren't written to Postgres yet. Each counter has a hash of deltas
+// keyed by the ID of the row it belongs to, shared by every instance so any of them can flush it.
+type Pending struct {
+	client *redis.Client
+}
+
+func NewPending(client *redis.Client) *Pending {
+	return &Pending{
+		client: client,
+	}
+}
+
+// Add adds delta to the pending delta of the counter of id.
+func (p *Pending) Add(ctx context.Context, counter entity.Counter, id uuid.UUID, delta int64) error {
+	return p.client.HIncrBy(ctx, key(counter), id.String(), delta).Err()
+}
+
+// AddAll adds the deltas back, e.g. when they couldn't be written.
+func (p *Pending) AddAll(ctx context.Context, counter entity.Counter, deltas map[uuid.UUID]int64) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	pipe := p.client.TxPipeline()
+	for id, delta := range deltas {
+		pipe.HIncrBy(ctx, key(counter), id.String(), delta)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Take removes and returns the pending deltas of the counter, deltas that add up to zero are left out.
+func (p *Pending) Take(ctx context.Context, counter entity.Counter) (map[uuid.UUID]int64, error) {
+	fields, err := take.Run(ctx, p.client, []string{key(counter)}).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	deltas := make(map[uuid.UUID]int64, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		id, err := uuid.Parse(fields[i])
+		if err != nil {
+			continue
+		}
+		delta, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil || delta == 0 {
+			continue
+		}
+		deltas[id] = delta
+	}
+	return deltas, nil
+}
+
+func key(counter entity.Counter) string {
+	return keyPrefix + string(counter)
+}
@@ -0,0 +1,53 @@
+package loginfailures
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "login_failures:"
+
+// Counter counts failed logins per login name within a sliding window, the window restarts with every failure.
+// Redis is used so every instance sees the same count.
+type Counter struct {
+	client *redis.Client
+	window time.Duration
+}
+
+func NewCounter(client *redis.Client, window time.Duration) *Counter {
+	return &Counter{
+		client: client,
+		window: window,
+	}
+}
+
+// Count returns the failed logins for the login name in the current window.
+func (c *Counter) Count(ctx context.Context, login string) (int64, error) {
+	count, err := c.client.Get(ctx, key(login)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+// Add records a failed login for the login name.
+func (c *Counter) Add(ctx context.Context, login string) error {
+	pipe := c.client.TxPipeline()
+	pipe.Incr(ctx, key(login))
+	pipe.Expire(ctx, key(login), c.window)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Reset forgets the failed logins for the login name after a successful login.
+func (c *Counter) Reset(ctx context.Context, login string) error {
+	return c.client.Del(ctx, key(login)).Err()
+}
+
+// key is case-insensitive since usernames and emails are matched case-insensitively on login.
+func key(login string) string {
+	return keyPrefix + strings.ToLower(strings.TrimSpace(login))
+}
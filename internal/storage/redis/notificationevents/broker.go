@@ -0,0 +1,136 @@
+package notificationevents
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// channel is the Redis pub/sub channel notification signals are relayed on.
+const channel = "notifications"
+
+// Broker wakes up the notification streams of users who got new notifications. A signal carries no data,
+// streams read the notifications themselves, so signals of a user that weren't consumed yet are merged into one.
+// Without a Redis client it is an in-process hub; with one, Publish sends the signal to Redis and Run hands
+// the signals of all instances to local subscribers.
+type Broker struct {
+	client *redis.Client
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan struct{}]struct{}
+}
+
+// NewBroker returns a broker relaying signals through Redis pub/sub, or an in-process one if client is nil.
+func NewBroker(client *redis.Client, logger *slog.Logger) *Broker {
+	return &Broker{
+		client: client,
+		logger: logger,
+		subs:   make(map[uuid.UUID]map[chan struct{}]struct{}),
+	}
+}
+
+// Publish wakes up the streams of the users on all instances.
+func (b *Broker) Publish(ctx context.Context, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	if b.client == nil {
+		b.dispatch(userIDs)
+		return nil
+	}
+	payload, err := json.Marshal(userIDs)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe returns the signals of the user until cancel is called, which also closes the channel.
+func (b *Broker) Subscribe(userID uuid.UUID) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[chan struct{}]struct{})
+	}
+	b.subs[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[userID], ch)
+			if len(b.subs[userID]) == 0 {
+				delete(b.subs, userID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Run relays the signals published by all instances until ctx is cancelled, resubscribing when the connection is lost.
+// Without a Redis client it only waits for ctx.
+func (b *Broker) Run(ctx context.Context) error {
+	if b.client == nil {
+		<-ctx.Done()
+		return nil
+	}
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		b.logger.Error("Notification signals subscription failed, resubscribing", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	pubsub := b.client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	// the first receive confirms the subscription
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var userIDs []uuid.UUID
+		if err := json.Unmarshal([]byte(msg.Payload), &userIDs); err != nil {
+			b.logger.Warn("Malformed notification signal", "payload", msg.Payload, "error", err)
+			continue
+		}
+		b.dispatch(userIDs)
+	}
+}
+
+// dispatch signals the local subscribers of the users, a subscriber with a pending signal needs no other one.
+func (b *Broker) dispatch(userIDs []uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, userID := range userIDs {
+		for ch := range b.subs[userID] {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package presence
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "presence:"
+	// ttl is how long a connection counts as open after its last heartbeat, so connections of an instance
+	// that died stop counting on their own.
+	ttl = time.Minute
+	// heartbeat is how often an open connection is refreshed.
+	heartbeat = ttl / 3
+)
+
+// Tracker keeps the open real-time connections of users in Redis, so any instance can tell whether a user is
+// online. Each user has a sorted set of connection IDs scored by the time they expire.
+type Tracker struct {
+	client *redis.Client
+}
+
+func NewTracker(client *redis.Client) *Tracker {
+	return &Tracker{
+		client: client,
+	}
+}
+
+// Track counts a connection of the user as open until stop is called or ctx is cancelled.
+func (t *Tracker) Track(ctx context.Context, userID uuid.UUID) (stop func()) {
+	key := keyPrefix + userID.String()
+	connID := uuid.NewString()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			// failures are retried by the next heartbeat, meanwhile the user only looks offline
+			t.refresh(ctx, key, connID)
+			select {
+			case <-ctx.Done():
+				// the connection may be closing because the request ended, so its context can't be used
+				_ = t.client.ZRem(context.WithoutCancel(ctx), key, connID).Err()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (t *Tracker) refresh(ctx context.Context, key, connID string) {
+	now := time.Now()
+	pipe := t.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ttl).Unix()), Member: connID})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipe.Expire(ctx, key, ttl)
+	_, _ = pipe.Exec(ctx)
+}
+
+// Online returns the users among userIDs that have an open connection.
+func (t *Tracker) Online(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	online := make(map[uuid.UUID]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return online, nil
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	pipe := t.client.Pipeline()
+	counts := make([]*redis.IntCmd, len(userIDs))
+	for i, id := range userIDs {
+		counts[i] = pipe.ZCount(ctx, keyPrefix+id.String(), "("+now, "+inf")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	for i, id := range userIDs {
+		if counts[i].Val() > 0 {
+			online[id] = true
+		}
+	}
+	return online, nil
+}
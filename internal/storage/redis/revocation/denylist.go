@@ -0,0 +1,119 @@
+package revocation
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userKeyPrefix    = "revoked_user:"
+	sessionKeyPrefix = "revoked_session:"
+)
+
+// Denylist revokes already issued access tokens. A user entry stores a cutoff time and rejects every token
+// of the user issued before it; a session entry rejects every token bound to that session.
+// Entries live for the access token TTL, after that all revoked tokens are expired anyway.
+// Redis is the primary store so all instances share the list; a local in-memory copy is used when Redis is unavailable.
+type Denylist struct {
+	client *redis.Client
+	ttl    time.Duration
+
+	mu    sync.RWMutex
+	local map[string]localEntry
+}
+
+type localEntry struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+func NewDenylist(client *redis.Client, ttl time.Duration) *Denylist {
+	return &Denylist{
+		client: client,
+		ttl:    ttl,
+		local:  make(map[string]localEntry),
+	}
+}
+
+// RevokeUser invalidates every access token of the user issued before the given time.
+func (d *Denylist) RevokeUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return d.store(ctx, userKeyPrefix+userID.String(), at)
+}
+
+// RevokeSession invalidates every access token bound to the session.
+func (d *Denylist) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	return d.store(ctx, sessionKeyPrefix+sessionID.String(), time.Now())
+}
+
+// IsRevoked reports whether a token of the user bound to the session and issued at issuedAt has been revoked.
+// Redis errors fall back to the in-memory list instead of failing the request.
+func (d *Denylist) IsRevoked(ctx context.Context, userID, sessionID uuid.UUID, issuedAt time.Time) (bool, error) {
+	if sessionID != uuid.Nil {
+		_, found, err := d.load(ctx, sessionKeyPrefix+sessionID.String())
+		if err != nil || found {
+			return found, err
+		}
+	}
+
+	revokedAt, found, err := d.load(ctx, userKeyPrefix+userID.String())
+	if err != nil || !found {
+		return false, err
+	}
+	return issuedAt.Before(revokedAt), nil
+}
+
+func (d *Denylist) store(ctx context.Context, key string, at time.Time) error {
+	d.storeLocal(key, at)
+
+	if d.client == nil {
+		return nil
+	}
+	return d.client.Set(ctx, key, at.Unix(), d.ttl).Err()
+}
+
+func (d *Denylist) load(ctx context.Context, key string) (time.Time, bool, error) {
+	if d.client != nil {
+		val, err := d.client.Get(ctx, key).Result()
+		switch {
+		case err == redis.Nil:
+			return time.Time{}, false, nil
+		case err == nil:
+			unix, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return time.Time{}, false, err
+			}
+			return time.Unix(unix, 0), true, nil
+		}
+	}
+	revokedAt, found := d.loadLocal(key)
+	return revokedAt, found, nil
+}
+
+func (d *Denylist) storeLocal(key string, at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range d.local {
+		if now.After(entry.expiresAt) {
+			delete(d.local, k)
+		}
+	}
+	d.local[key] = localEntry{revokedAt: at.Truncate(time.Second), expiresAt: now.Add(d.ttl)}
+}
+
+func (d *Denylist) loadLocal(key string) (time.Time, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.local[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return time.Time{}, false
+	}
+	return entry.revokedAt, true
+}
@@ -0,0 +1,183 @@
+package timeline
+
+import (
+	"context"
+	"main/domain/entity"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "timeline:"
+	// sentinel marks a timeline as built, so an empty timeline isn't mistaken for one that must be built.
+	// Its score of 0 keeps it below every entry.
+	sentinel = "_"
+	// tieAllowance is how many entries sharing the microsecond of a cursor a page may skip
+	tieAllowance = 8
+)
+
+// push adds an entry to a timeline that was built and trims it to ARGV[3] entries, timelines that weren't
+// built are left alone since they would only hold the entries pushed since.
+var push = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZREMRANGEBYRANK', KEYS[1], 1, -(tonumber(ARGV[3]) + 1))
+return 1`)
+
+// Cache keeps the newest entries of home timelines in Redis. Each timeline is a sorted set of
+// "<post ID>[:<reposter ID>]" scored by the microsecond of the entry, so pages are read by score like the
+// timeline is ordered in Postgres. Timelines of users who stop reading them expire.
+type Cache struct {
+	client *redis.Client
+	// maxEntries is how many entries a timeline keeps, older ones are read from Postgres
+	maxEntries int
+	ttl        time.Duration
+}
+
+func NewCache(client *redis.Client, maxEntries int, ttl time.Duration) *Cache {
+	return &Cache{
+		client:     client,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+	}
+}
+
+// Push adds the entry to the built timelines of the users.
+func (c *Cache) Push(ctx context.Context, userIDs []uuid.UUID, entry entity.TimelineEntry) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	s, m := score(entry.At), member(entry)
+	pipe := c.client.Pipeline()
+	for _, userID := range userIDs {
+		push.Eval(ctx, pipe, []string{key(userID)}, s, m, c.maxEntries)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Remove removes the entry from the timelines of the users.
+func (c *Cache) Remove(ctx context.Context, userIDs []uuid.UUID, entry entity.TimelineEntry) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	m := member(entry)
+	pipe := c.client.Pipeline()
+	for _, userID := range userIDs {
+		pipe.ZRem(ctx, key(userID), m)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Store replaces the timeline of the user with the entries, newest first, which must be all entries of the
+// timeline up to the maximum the cache keeps.
+func (c *Cache) Store(ctx context.Context, userID uuid.UUID, entries []entity.TimelineEntry) error {
+	members := make([]redis.Z, 0, len(entries)+1)
+	members = append(members, redis.Z{Score: 0, Member: sentinel})
+	for _, entry := range entries[:min(len(entries), c.maxEntries)] {
+		members = append(members, redis.Z{Score: score(entry.At), Member: member(entry)})
+	}
+	pipe := c.client.TxPipeline()
+	pipe.Del(ctx, key(userID))
+	pipe.ZAdd(ctx, key(userID), members...)
+	pipe.Expire(ctx, key(userID), c.ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Range returns up to limit entries of the user's timeline older than the (beforeTime, beforePostID) position,
+// newest first, a zero beforeTime starts from the newest entry. built is false if the timeline isn't cached,
+// full tells that the timeline reached the maximum the cache keeps, so older entries may be missing from it.
+// Reading a timeline keeps it cached.
+func (c *Cache) Range(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int) (entries []entity.TimelineEntry, built, full bool, err error) {
+	newest := "+inf"
+	if !beforeTime.IsZero() {
+		newest = strconv.FormatFloat(score(beforeTime), 'f', -1, 64)
+	}
+	pipe := c.client.Pipeline()
+	card := pipe.ZCard(ctx, key(userID))
+	page := pipe.ZRangeArgsWithScores(ctx, redis.ZRangeArgs{
+		Key:     key(userID),
+		Start:   "(0",
+		Stop:    newest,
+		ByScore: true,
+		Rev:     true,
+		Count:   int64(limit + tieAllowance),
+	})
+	pipe.Expire(ctx, key(userID), c.ttl)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, false, false, err
+	}
+	if card.Val() == 0 {
+		return nil, false, false, nil
+	}
+
+	for _, z := range page.Val() {
+		entry, ok := parse(z)
+		if !ok {
+			continue
+		}
+		// entries at the cursor's microsecond are only older if their post ID sorts before
+		if !beforeTime.IsZero() && entry.At.Equal(beforeTime.Truncate(time.Microsecond)) && entry.PostID.String() >= beforePostID.String() {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) == limit {
+			break
+		}
+	}
+	return entries, true, card.Val()-1 >= int64(c.maxEntries), nil
+}
+
+// Invalidate drops the timelines of the users, they are built again when read.
+func (c *Cache) Invalidate(ctx context.Context, userIDs ...uuid.UUID) error {
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = key(userID)
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+func key(userID uuid.UUID) string {
+	return keyPrefix + userID.String()
+}
+
+func score(at time.Time) float64 {
+	return float64(at.UnixMicro())
+}
+
+func member(entry entity.TimelineEntry) string {
+	if entry.RepostedBy != nil {
+		return entry.PostID.String() + ":" + entry.RepostedBy.String()
+	}
+	return entry.PostID.String()
+}
+
+func parse(z redis.Z) (entity.TimelineEntry, bool) {
+	m, ok := z.Member.(string)
+	if !ok {
+		return entity.TimelineEntry{}, false
+	}
+	postPart, reposterPart, reposted := strings.Cut(m, ":")
+	postID, err := uuid.Parse(postPart)
+	if err != nil {
+		return entity.TimelineEntry{}, false
+	}
+	entry := entity.TimelineEntry{PostID: postID, At: time.UnixMicro(int64(math.Round(z.Score)))}
+	if reposted {
+		reposterID, err := uuid.Parse(reposterPart)
+		if err != nil {
+			return entity.TimelineEntry{}, false
+		}
+		entry.RepostedBy = &reposterID
+	}
+	return entry, true
+}
@@ -0,0 +1,34 @@
+package typing
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "typing:"
+
+// Debouncer lets one typing indicator per user and chat through within an interval, clients send one on every
+// keystroke. Redis is used so the interval holds for the connections of the user on every instance.
+type Debouncer struct {
+	client   *redis.Client
+	interval time.Duration
+}
+
+func NewDebouncer(client *redis.Client, interval time.Duration) *Debouncer {
+	return &Debouncer{
+		client:   client,
+		interval: interval,
+	}
+}
+
+// Allow reports whether the typing indicator of the user in the chat should be sent, which it is if none was
+// sent within the interval. With a non-positive interval every indicator is sent.
+func (d *Debouncer) Allow(ctx context.Context, userID, chatID uuid.UUID) (bool, error) {
+	if d.interval <= 0 {
+		return true, nil
+	}
+	return d.client.SetNX(ctx, keyPrefix+chatID.String()+":"+userID.String(), 1, d.interval).Result()
+}
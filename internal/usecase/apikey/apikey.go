@@ -0,0 +1,141 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	ctxUtil "main/pkg/utils/context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// keyPrefix marks API keys so leaked ones are easy to recognize, e.g. by secret scanners.
+const keyPrefix = "thr_"
+
+// APIKeyRepo defines the interface for API key storage.
+type APIKeyRepo interface {
+	// CreateKey stores a new API key by its hash.
+	CreateKey(ctx context.Context, key entity.APIKey, keyHash []byte) error
+
+	// GetKeyByHash returns the key with the given hash.
+	GetKeyByHash(ctx context.Context, keyHash []byte) (entity.APIKey, error)
+
+	// ListKeys returns all keys, newest first.
+	ListKeys(ctx context.Context) ([]entity.APIKey, error)
+
+	// RevokeKey marks the key as revoked.
+	RevokeKey(ctx context.Context, id uuid.UUID) error
+
+	// TouchKey records that the key was just used.
+	TouchKey(ctx context.Context, id uuid.UUID) error
+}
+
+// AuditRecorder defines the interface for recording security events.
+type AuditRecorder interface {
+	Record(ctx context.Context, eventType entity.AuditEventType, actorID, subjectID uuid.UUID, metadata map[string]any)
+}
+
+type APIKeyUsecase struct {
+	apiKeyRepo APIKeyRepo
+	Audit      AuditRecorder
+}
+
+func NewAPIKeyUsecase(apiKeyRepo APIKeyRepo, audit AuditRecorder) *APIKeyUsecase {
+	return &APIKeyUsecase{
+		apiKeyRepo: apiKeyRepo,
+		Audit:      audit,
+	}
+}
+
+// IssueKey creates a key allowed to call the methods in scopes. The returned plain key is not stored and can't be shown again.
+func (uc *APIKeyUsecase) IssueKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (string, entity.APIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", entity.APIKey{}, apperror.InvalidArgument("name_required", "name is required")
+	}
+	if len(scopes) == 0 {
+		return "", entity.APIKey{}, apperror.InvalidArgument("scope_required", "at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return "", entity.APIKey{}, apperror.InvalidArgument("invalid_scope", "invalid scope "+scope+", expected package.Service/Method or package.Service/*")
+		}
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return "", entity.APIKey{}, apperror.InvalidArgument("invalid_expiry", "expiry is in the past")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", entity.APIKey{}, err
+	}
+	plain := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	key := entity.APIKey{
+		ID:        uuid.New(),
+		Name:      name,
+		Prefix:    plain[:len(keyPrefix)+8],
+		Scopes:    scopes,
+		CreatedBy: actorFromContext(ctx),
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := uc.apiKeyRepo.CreateKey(ctx, key, hashKey(plain)); err != nil {
+		return "", entity.APIKey{}, err
+	}
+	uc.Audit.Record(ctx, entity.AuditAPIKeyIssue, key.CreatedBy, uuid.Nil, map[string]any{"key_id": key.ID, "name": name, "scopes": scopes})
+	return plain, key, nil
+}
+
+// RevokeKey revokes the key, calls made with it are rejected from then on.
+func (uc *APIKeyUsecase) RevokeKey(ctx context.Context, id uuid.UUID) error {
+	if err := uc.apiKeyRepo.RevokeKey(ctx, id); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditAPIKeyRevoke, actorFromContext(ctx), uuid.Nil, map[string]any{"key_id": id})
+	return nil
+}
+
+// ListKeys returns all keys, newest first, without their secrets.
+func (uc *APIKeyUsecase) ListKeys(ctx context.Context) ([]entity.APIKey, error) {
+	return uc.apiKeyRepo.ListKeys(ctx)
+}
+
+// Authenticate returns the key matching the plain key if it is active.
+// Returns customerrors.ErrInvalidToken for unknown, revoked or expired keys.
+func (uc *APIKeyUsecase) Authenticate(ctx context.Context, plain string) (entity.APIKey, error) {
+	if !strings.HasPrefix(plain, keyPrefix) {
+		return entity.APIKey{}, customerrors.ErrInvalidToken
+	}
+	key, err := uc.apiKeyRepo.GetKeyByHash(ctx, hashKey(plain))
+	if err != nil {
+		return entity.APIKey{}, err
+	}
+	if !key.Active(time.Now()) {
+		return entity.APIKey{}, customerrors.ErrInvalidToken
+	}
+	// last use is informational, failing to record it must not fail the call
+	_ = uc.apiKeyRepo.TouchKey(ctx, key.ID)
+	return key, nil
+}
+
+func hashKey(plain string) []byte {
+	sum := sha256.Sum256([]byte(plain))
+	return sum[:]
+}
+
+func validScope(scope string) bool {
+	service, method, ok := strings.Cut(scope, "/")
+	return ok && strings.Contains(service, ".") && method != "" && !strings.Contains(method, "/")
+}
+
+// actorFromContext returns the authenticated user performing the request, uuid.Nil for system actions.
+func actorFromContext(ctx context.Context) uuid.UUID {
+	actorID, _ := ctxUtil.UserIDFromContext(ctx)
+	return actorID
+}
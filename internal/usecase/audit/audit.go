@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"main/domain/entity"
+	ctxUtil "main/pkg/utils/context"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// AuditRepo defines the interface for audit log storage.
+type AuditRepo interface {
+	// InsertEvent appends an event to the audit log.
+	InsertEvent(ctx context.Context, event entity.AuditEvent) error
+
+	// ListEvents returns audit events matching the filter, newest first.
+	ListEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error)
+}
+
+type AuditUsecase struct {
+	auditRepo AuditRepo
+	logger    *slog.Logger
+}
+
+func NewAuditUsecase(auditRepo AuditRepo, logger *slog.Logger) *AuditUsecase {
+	return &AuditUsecase{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// Record appends a security event to the audit log. Client IP and User-Agent are taken from the request context.
+// A failing audit write is logged but never fails the audited operation.
+func (uc *AuditUsecase) Record(ctx context.Context, eventType entity.AuditEventType, actorID, subjectID uuid.UUID, metadata map[string]any) {
+	ip, userAgent := ctxUtil.ClientInfoFromContext(ctx)
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	event := entity.AuditEvent{
+		Type:      eventType,
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		ClientIP:  ip,
+		UserAgent: userAgent,
+		Metadata:  metadata,
+	}
+	// the request may already be cancelled (e.g. client disconnected), the event should be written anyway
+	if err := uc.auditRepo.InsertEvent(context.WithoutCancel(ctx), event); err != nil {
+		uc.logger.Error("Failed to record audit event", "type", eventType, "error", err)
+	}
+}
+
+// ListEvents returns audit events matching the filter, newest first, at most maxListLimit per page.
+func (uc *AuditUsecase) ListEvents(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	return uc.auditRepo.ListEvents(ctx, filter)
+}
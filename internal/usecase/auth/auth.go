@@ -2,16 +2,30 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"main/internal/config"
 	metrics "main/internal/metrics"
+	"main/pkg/apperror"
 	"net/netip"
+	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"main/domain/entity"
+	"main/pkg/captcha"
+	"main/pkg/customerrors"
+	"main/pkg/geoip"
+	"main/pkg/jwt"
+	"main/pkg/pagination"
+	"main/pkg/useragent"
+	ctxUtil "main/pkg/utils/context"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthRepo defines the interface for authentication-related database operations.
@@ -31,33 +45,235 @@ type AuthRepo interface {
 	// DeleteAllSessions removes all sessions associated with a user, effectively logging them out from !ALL! devices.
 	DeleteAllSessions(ctx context.Context, userID uuid.UUID) error
 
-	// UserIsBlocked checks if the user is blocked and returns true if the user is not blocked, false otherwise.
-	UserIsBlocked(userID uuid.UUID) (bool, error)
+	// DeleteOtherSessions removes every session of the user except the one with keepID and returns the removed IDs.
+	DeleteOtherSessions(ctx context.Context, userID, keepID uuid.UUID) ([]uuid.UUID, error)
+
+	// UserIsBlocked returns true if the user is blocked and the block has not expired yet.
+	UserIsBlocked(ctx context.Context, userID uuid.UUID) (bool, error)
+
+	// SetUserBlocked blocks the user with a reason and optional expiry, or lifts the block.
+	SetUserBlocked(ctx context.Context, userID uuid.UUID, blocked bool, reason string, until *time.Time) error
+
+	// GetUserRoles returns the roles granted to the user.
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]entity.Role, error)
+
+	// AddUserRole grants the role to the user.
+	AddUserRole(ctx context.Context, userID uuid.UUID, role entity.Role) error
+
+	// RemoveUserRole revokes the role from the user.
+	RemoveUserRole(ctx context.Context, userID uuid.UUID, role entity.Role) error
 
 	// GetSessionByRefreshToken retrieves the session information based on the provided refresh token.
 	GetSessionByRefreshToken(ctx context.Context, refreshToken uuid.UUID) (entity.Session, error)
 
 	// RefreshSession updates the session information in the database, allowing for token renewal and session extension.
 	RefreshSession(ctx context.Context, session entity.Session) error
+
+	// GetPasswordHash retrieves the stored password hash of the user.
+	GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error)
+
+	// UpdatePasswordHash replaces the stored password hash of the user.
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
+
+	// CreateEmailChangeRequest stores a pending email change identified by the hash of the confirmation token.
+	CreateEmailChangeRequest(ctx context.Context, userID uuid.UUID, newEmail string, tokenHash []byte, expiresAt time.Time) error
+
+	// ConfirmEmailChange applies the pending email change atomically and returns the affected user ID.
+	ConfirmEmailChange(ctx context.Context, tokenHash []byte) (uuid.UUID, error)
+
+	// SoftDeleteUser marks the user as deleted and removes all of their sessions.
+	SoftDeleteUser(ctx context.Context, userID uuid.UUID) error
+
+	// RestoreUser undeletes the user unless their personal data was already erased.
+	RestoreUser(ctx context.Context, userID uuid.UUID) error
+
+	// AnonymizeDeletedUsers erases PII of users deleted before the given time and returns how many were anonymized.
+	AnonymizeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	// DeleteExpiredSessions deletes the sessions that expired before the given time and returns how many were deleted.
+	DeleteExpiredSessions(ctx context.Context, expiredBefore time.Time) (int64, error)
+
+	// DeleteExpiredTokens deletes the email change and login confirmation tokens that expired before the given time.
+	DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time) (int64, error)
+
+	//ListSessions returns the unexpired sessions of the user older than the given position, newest first.
+	ListSessions(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Session, error)
+
+	//RenameSession sets the device name of a session of the user.
+	RenameSession(ctx context.Context, userID, sessionID uuid.UUID, name string) error
+
+	//UsernameExists reports whether the username is taken, ignoring case.
+	UsernameExists(ctx context.Context, username string) (bool, error)
+
+	//LookupUser returns the account with the ID, or with the username or email address matching login.
+	LookupUser(ctx context.Context, userID uuid.UUID, login string) (entity.UserAccount, error)
+
+	//GetUserEmail returns the email address of the user.
+	GetUserEmail(ctx context.Context, userID uuid.UUID) (string, error)
+
+	//IsKnownDevice reports whether the user signed in from the IP and User-Agent before, and whether the user signed in at all.
+	IsKnownDevice(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string) (known, hasHistory bool, err error)
+
+	//RememberDevice records a successful sign in from the IP and User-Agent in the given country.
+	RememberDevice(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent, countryCode string) error
+
+	//IsKnownCountry reports whether the user signed in from the country before.
+	IsKnownCountry(ctx context.Context, userID uuid.UUID, countryCode string) (bool, error)
+
+	//CreateLoginConfirmation stores a login from a new device pending email confirmation.
+	CreateLoginConfirmation(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string, tokenHash []byte, expiresAt time.Time) error
+
+	//ConsumeLoginConfirmation deletes the pending login and returns the user and device it was made from.
+	ConsumeLoginConfirmation(ctx context.Context, tokenHash []byte) (userID uuid.UUID, ip netip.Addr, userAgent string, err error)
+}
+
+// Transactor defines the interface for running several repository calls atomically.
+type Transactor interface {
+	// WithinTx runs fn in a transaction carried by the context passed to it.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // JWTManager defines the interface for JWT token management.
 type JWTManager interface {
-	NewAccessToken(userID uuid.UUID) (string, error)
-	VerifyAccessToken(token string) (userID uuid.UUID, err error)
+	NewAccessToken(userID, sessionID uuid.UUID, roles []string) (string, error)
+	ParseAccessToken(token string) (jwt.AccessToken, error)
+}
+
+// TokenDenylist defines the interface for revoking already issued access tokens.
+type TokenDenylist interface {
+	// RevokeUser invalidates every access token of the user issued before the given time.
+	RevokeUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+
+	// RevokeSession invalidates every access token bound to the session.
+	RevokeSession(ctx context.Context, sessionID uuid.UUID) error
+
+	// IsRevoked reports whether a token of the user bound to the session and issued at issuedAt has been revoked.
+	IsRevoked(ctx context.Context, userID, sessionID uuid.UUID, issuedAt time.Time) (bool, error)
+}
+
+// PasswordHasher defines the interface for hashing and verifying passwords.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+
+	// Verify compares the password with the hash, needsRehash reports a legacy algorithm or outdated parameters.
+	Verify(password, hash string) (ok bool, needsRehash bool)
+}
+
+// AuditRecorder defines the interface for recording security events.
+type AuditRecorder interface {
+	Record(ctx context.Context, eventType entity.AuditEventType, actorID, subjectID uuid.UUID, metadata map[string]any)
+}
+
+// SessionEventBus defines the interface for notifying the clients of a user about their sessions.
+type SessionEventBus interface {
+	Publish(ctx context.Context, event entity.SessionEvent) error
+
+	// Subscribe returns the events of the user until cancel is called.
+	Subscribe(userID uuid.UUID) (events <-chan entity.SessionEvent, cancel func())
+}
+
+// EmailValidator defines the interface for validating and normalizing email addresses.
+type EmailValidator interface {
+	// Normalize validates the address and returns the form it is stored in.
+	Normalize(address string) (string, error)
+}
+
+// GeoResolver defines the interface for looking up the location of client IPs.
+type GeoResolver interface {
+	Lookup(ip netip.Addr) (geoip.Location, error)
+}
+
+// CaptchaVerifier defines the interface for checking CAPTCHA tokens solved by clients.
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// FeatureFlags tells which gradually rolled out features are on for a user.
+type FeatureFlags interface {
+	Enabled(ctx context.Context, name string, userID uuid.UUID) bool
+}
+
+// LoginFailureCounter defines the interface for counting recent failed logins per login name.
+type LoginFailureCounter interface {
+	Count(ctx context.Context, login string) (int64, error)
+	Add(ctx context.Context, login string) error
+	Reset(ctx context.Context, login string) error
+}
+
+// Mailer defines the interface for sending emails to users.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
 }
 
 type AuthUsecase struct {
 	authRepo   AuthRepo
+	Tx         Transactor
 	JWTManager JWTManager
+	Denylist   TokenDenylist
+	Hasher     PasswordHasher
+	Emails     EmailValidator
+	Geo        GeoResolver
+	Captcha    CaptchaVerifier
+	Failures   LoginFailureCounter
+	Mailer     Mailer
+	Audit      AuditRecorder
+	Events     SessionEventBus
+	Flags      FeatureFlags
+	EmailCfg   config.EmailConfig
+	LoginCfg   config.LoginSecurityConfig
+	SessionCfg config.SessionConfig
+	CaptchaCfg config.CaptchaConfig
 	Metrics    *metrics.Metrics
+
+	reservedUsernames map[string]struct{}
 }
 
-func NewAuthUsecase(authRepo AuthRepo, JWTManager JWTManager, metrics *metrics.Metrics) *AuthUsecase {
+func NewAuthUsecase(
+	authRepo AuthRepo,
+	tx Transactor,
+	JWTManager JWTManager,
+	denylist TokenDenylist,
+	hasher PasswordHasher,
+	emails EmailValidator,
+	geo GeoResolver,
+	captchaVerifier CaptchaVerifier,
+	failures LoginFailureCounter,
+	mailer Mailer,
+	audit AuditRecorder,
+	events SessionEventBus,
+	flags FeatureFlags,
+	emailCfg config.EmailConfig,
+	loginCfg config.LoginSecurityConfig,
+	registrationCfg config.RegistrationConfig,
+	sessionCfg config.SessionConfig,
+	captchaCfg config.CaptchaConfig,
+	metrics *metrics.Metrics,
+) *AuthUsecase {
+	reserved := make(map[string]struct{}, len(registrationCfg.ReservedUsernames))
+	for _, name := range registrationCfg.ReservedUsernames {
+		reserved[strings.ToLower(name)] = struct{}{}
+	}
 	return &AuthUsecase{
 		authRepo:   authRepo,
+		Tx:         tx,
 		JWTManager: JWTManager,
+		Denylist:   denylist,
+		Hasher:     hasher,
+		Emails:     emails,
+		Geo:        geo,
+		Captcha:    captchaVerifier,
+		Failures:   failures,
+		Mailer:     mailer,
+		Audit:      audit,
+		Events:     events,
+		Flags:      flags,
+		EmailCfg:   emailCfg,
+		LoginCfg:   loginCfg,
+		SessionCfg: sessionCfg,
+		CaptchaCfg: captchaCfg,
 		Metrics:    metrics,
+
+		reservedUsernames: reserved,
 	}
 }
 
@@ -65,7 +281,7 @@ func NewAuthUsecase(authRepo AuthRepo, JWTManager JWTManager, metrics *metrics.M
 func (uc *AuthUsecase) RefreshSessionToken(ctx context.Context, refreshToken string) (string, string, error) {
 	sid, err := uuid.Parse(refreshToken)
 	if err != nil {
-		return "", "", errors.New("invalid session ID")
+		return "", "", apperror.InvalidArgument("invalid_session_id", "invalid session ID")
 	}
 
 	session, err := uc.authRepo.GetSessionByRefreshToken(ctx, sid)
@@ -74,13 +290,14 @@ func (uc *AuthUsecase) RefreshSessionToken(ctx context.Context, refreshToken str
 	}
 	uid := session.UserID
 
-	if session.ExpiresAt.Before(session.CreatedAt) {
+	if !time.Now().Before(session.ExpiresAt) {
 		uc.authRepo.DeleteSession(ctx, uid, session.ID)
-		return "", "", errors.New("session has expired")
+		return "", "", apperror.Unauthenticated("session_expired", "session has expired")
 	}
 
-	session.ExpiresAt = time.Now().Add(15 * 24 * time.Hour)
-	session.CreatedAt = time.Now()
+	if uc.SessionCfg.SlidingExpiration {
+		session.ExpiresAt = uc.sessionExpiry(session.CreatedAt, time.Now())
+	}
 	session.RefreshToken, err = uuid.NewUUID()
 	if err != nil {
 		return "", "", err
@@ -91,156 +308,801 @@ func (uc *AuthUsecase) RefreshSessionToken(ctx context.Context, refreshToken str
 		return "", "", err
 	}
 
-	newAccessToken, err := uc.JWTManager.NewAccessToken(uid)
+	newAccessToken, err := uc.newAccessToken(ctx, uid, session.ID)
 	if err != nil {
 		return "", "", err
 	}
 
+	uc.Audit.Record(ctx, entity.AuditRefresh, uid, uid, map[string]any{"session_id": session.ID})
+
 	return newAccessToken, session.RefreshToken.String(), nil
 }
 
 // RegisterUser validates the input, hashes the password, and creates a new user in the database.
+// The CAPTCHA token is verified first when CAPTCHA is enabled.
 // It returns the user ID as a string or an error if the registration fails.
-func (uc *AuthUsecase) RegisterUser(ctx context.Context, username, email, password string) (userID uuid.UUID, err error) {
+func (uc *AuthUsecase) RegisterUser(ctx context.Context, username, email, password, captchaToken string) (userID uuid.UUID, err error) {
+	ip, _ := ctxUtil.ClientInfoFromContext(ctx)
+	if err := uc.verifyCaptcha(ctx, captchaToken, ip); err != nil {
+		return uuid.Nil, err
+	}
+
+	return uc.createUser(ctx, username, email, password)
+}
+
+// CreateUser creates an account on behalf of an admin, e.g. the first admin of a new deployment, and grants it
+// the given roles in addition to the user role. The CAPTCHA is skipped but the input is validated like on sign-up.
+func (uc *AuthUsecase) CreateUser(ctx context.Context, username, email, password string, roles []entity.Role) (uuid.UUID, error) {
+	for _, role := range roles {
+		if !role.Valid() {
+			return uuid.Nil, apperror.InvalidArgument("unknown_role", fmt.Sprintf("unknown role %q", role))
+		}
+	}
+	// an account without the requested roles would be useless, so the roles are granted in the same transaction
+	var userID uuid.UUID
+	err := uc.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if userID, err = uc.createUser(ctx, username, email, password); err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if role == entity.RoleUser {
+				continue
+			}
+			if err := uc.authRepo.AddUserRole(ctx, userID, role); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	uc.Audit.Record(ctx, entity.AuditUserCreate, actorFromContext(ctx), userID, map[string]any{"roles": roles})
+	return userID, nil
+}
 
+// createUser validates the input, hashes the password, and creates the user in the database.
+func (uc *AuthUsecase) createUser(ctx context.Context, username, email, password string) (uuid.UUID, error) {
 	if !validateUsername(username) {
-		return uuid.Nil, errors.New("username must be between 3 and 30 characters")
+		return uuid.Nil, apperror.InvalidArgument("invalid_username", "username must be between 3 and 30 characters")
+	}
+	if uc.isReservedUsername(username) {
+		return uuid.Nil, apperror.InvalidArgument("username_reserved", "username is reserved")
 	}
 
-	if !validateEmail(email) {
-		return uuid.Nil, errors.New("invalid email format")
+	email, err := uc.Emails.Normalize(email)
+	if err != nil {
+		return uuid.Nil, err
 	}
 	if err := validatePassword(password); err != nil {
 		return uuid.Nil, err
 	}
 
-	passwordHash, err := hashPassword(password)
+	passwordHash, err := uc.Hasher.Hash(password)
 	if err != nil {
 		return uuid.Nil, err
 	}
-	userID, err = uuid.NewUUID()
+	userID, err := uuid.NewUUID()
 	if err != nil {
 		return uuid.Nil, err
 	}
 
 	return uc.authRepo.CreateUser(ctx, userID, email, username, passwordHash)
+}
 
+const maxDeviceNameLength = 64
+
+// Reasons returned by CheckUsername when the username can't be registered.
+const (
+	UsernameInvalid  = "invalid"
+	UsernameReserved = "reserved"
+	UsernameTaken    = "taken"
+)
+
+// CheckUsername reports whether the username can be registered, and if not, why.
+func (uc *AuthUsecase) CheckUsername(ctx context.Context, username string) (available bool, reason string, err error) {
+	if !validateUsername(username) {
+		return false, UsernameInvalid, nil
+	}
+	if uc.isReservedUsername(username) {
+		return false, UsernameReserved, nil
+	}
+	exists, err := uc.authRepo.UsernameExists(ctx, username)
+	if err != nil {
+		return false, "", err
+	}
+	if exists {
+		return false, UsernameTaken, nil
+	}
+	return true, "", nil
+}
+
+func (uc *AuthUsecase) isReservedUsername(username string) bool {
+	_, ok := uc.reservedUsernames[strings.ToLower(username)]
+	return ok
 }
 
 // LoginUser authenticates the user by verifying the provided credentials.
 // If successful, it generates an access token and a refresh token, stores the session in the database, and returns the access token.
 // If authentication fails, it returns an error.
+// After CaptchaCfg.LoginFailures failed attempts for the login name a valid CAPTCHA token is required as well.
 func (uc *AuthUsecase) LoginUser(ctx context.Context,
 	login,
 	password,
 	userAgent,
-	ip string) (uuid.UUID, string, string, error) {
+	ip,
+	captchaToken string) (uuid.UUID, string, string, error) {
+
+	if err := uc.checkLoginCaptcha(ctx, login, captchaToken, ip); err != nil {
+		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		uc.Audit.Record(ctx, entity.AuditLoginFailed, uuid.Nil, uuid.Nil, map[string]any{"login": login, "reason": "captcha"})
+		return uuid.Nil, "", "", err
+	}
 
 	userID, passwordHash, err := uc.authRepo.GetUserByLogin(ctx, login)
 	if err != nil {
 		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		uc.Audit.Record(ctx, entity.AuditLoginFailed, uuid.Nil, uuid.Nil, map[string]any{"login": login, "reason": "unknown_user"})
+		_ = uc.Failures.Add(ctx, login)
 		return uuid.Nil, "", "", err
 	}
-	if !verifyPassword(password, passwordHash) {
+	ok, needsRehash := uc.Hasher.Verify(password, passwordHash)
+	if !ok {
 		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
-		return uuid.Nil, "", "", errors.New("invalid credentials")
+		uc.Audit.Record(ctx, entity.AuditLoginFailed, uuid.Nil, userID, map[string]any{"login": login, "reason": "wrong_password"})
+		_ = uc.Failures.Add(ctx, login)
+		return uuid.Nil, "", "", apperror.Unauthenticated("invalid_credentials", "invalid credentials")
 	}
-
-	accessToken, err := uc.JWTManager.NewAccessToken(userID)
+	_ = uc.Failures.Reset(ctx, login)
+	isBlocked, err := uc.authRepo.UserIsBlocked(ctx, userID)
 	if err != nil {
 		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
 		return uuid.Nil, "", "", err
 	}
+	if isBlocked {
+		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		uc.Audit.Record(ctx, entity.AuditLoginFailed, uuid.Nil, userID, map[string]any{"login": login, "reason": "blocked"})
+		return uuid.Nil, "", "", customerrors.ErrUserBlocked
+	}
+	if needsRehash {
+		// migrate legacy hashes (bcrypt or outdated argon2 parameters) while we have the plain password
+		if newHash, err := uc.Hasher.Hash(password); err == nil {
+			_ = uc.authRepo.UpdatePasswordHash(ctx, userID, newHash)
+		}
+	}
 
-	refreshToken, err := uuid.NewUUID()
+	netipAddr, err := netip.ParseAddr(ip)
+	if err != nil {
+		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		return uuid.Nil, "", "", apperror.InvalidArgument("invalid_ip", "invalid IP address")
+	}
+
+	known, hasHistory, err := uc.authRepo.IsKnownDevice(ctx, userID, netipAddr, userAgent)
 	if err != nil {
 		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
 		return uuid.Nil, "", "", err
 	}
+	location := uc.lookupLocation(netipAddr)
+	knownCountry := true
+	if location.CountryCode != "" {
+		if knownCountry, err = uc.authRepo.IsKnownCountry(ctx, userID, location.CountryCode); err != nil {
+			uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
+			return uuid.Nil, "", "", err
+		}
+	}
+	// the very first login has nothing to compare against
+	suspicious := hasHistory && (!known || !knownCountry)
+	if suspicious && (uc.LoginCfg.RequireConfirmation || uc.Flags.Enabled(ctx, entity.FeatureLoginConfirmation, userID)) {
+		if err := uc.requestLoginConfirmation(ctx, userID, netipAddr, userAgent, location); err != nil {
+			uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
+			return uuid.Nil, "", "", err
+		}
+		uc.Audit.Record(ctx, entity.AuditLoginNewDevice, userID, userID, map[string]any{"confirmation_required": true})
+		return uuid.Nil, "", "", customerrors.ErrLoginConfirmationRequired
+	}
 
-	netipAddr, err := netip.ParseAddr(ip)
+	sessionID, accessToken, refreshToken, err := uc.startSession(ctx, userID, netipAddr, userAgent, location, suspicious)
 	if err != nil {
 		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
-		return uuid.Nil, "", "", errors.New("invalid IP address")
+		return uuid.Nil, "", "", err
+	}
+
+	if suspicious {
+		uc.Audit.Record(ctx, entity.AuditLoginNewDevice, userID, userID, map[string]any{
+			"session_id":  sessionID,
+			"new_country": !knownCountry,
+			"country":     location.CountryCode,
+		})
+		if uc.LoginCfg.NewDeviceAlerts {
+			// the alert is best effort, the login itself already succeeded
+			_ = uc.sendNewDeviceAlert(ctx, userID, netipAddr, userAgent, location)
+		}
+	}
+
+	uc.Metrics.LoginAttempts.WithLabelValues("success").Inc()
+	uc.Audit.Record(ctx, entity.AuditLogin, userID, userID, map[string]any{"session_id": sessionID, "new_device": suspicious})
+	return userID, accessToken, refreshToken, nil
+}
+
+// ConfirmLogin completes a login from a new device using the token emailed to the user.
+func (uc *AuthUsecase) ConfirmLogin(ctx context.Context, token string) (uuid.UUID, string, string, error) {
+	userID, ip, userAgent, err := uc.authRepo.ConsumeLoginConfirmation(ctx, hashToken(token))
+	if err != nil {
+		return uuid.Nil, "", "", err
+	}
+	// the user may have been blocked while the confirmation was pending
+	isBlocked, err := uc.authRepo.UserIsBlocked(ctx, userID)
+	if err != nil {
+		return uuid.Nil, "", "", err
+	}
+	if isBlocked {
+		return uuid.Nil, "", "", customerrors.ErrUserBlocked
+	}
+
+	sessionID, accessToken, refreshToken, err := uc.startSession(ctx, userID, ip, userAgent, uc.lookupLocation(ip), false)
+	if err != nil {
+		return uuid.Nil, "", "", err
+	}
+
+	uc.Metrics.LoginAttempts.WithLabelValues("success").Inc()
+	uc.Audit.Record(ctx, entity.AuditLogin, userID, userID, map[string]any{"session_id": sessionID, "confirmed": true})
+	return userID, accessToken, refreshToken, nil
+}
+
+// checkLoginCaptcha requires a valid CAPTCHA token once the login name had too many recent failed logins.
+// The check is skipped if the failures can't be counted, so an outage of the counter doesn't lock users out.
+func (uc *AuthUsecase) checkLoginCaptcha(ctx context.Context, login, captchaToken, ip string) error {
+	if uc.CaptchaCfg.LoginFailures <= 0 {
+		return nil
+	}
+	failures, err := uc.Failures.Count(ctx, login)
+	if err != nil || failures < int64(uc.CaptchaCfg.LoginFailures) {
+		return nil
+	}
+	return uc.verifyCaptcha(ctx, captchaToken, ip)
+}
+
+// verifyCaptcha reports missing and rejected tokens as ErrCaptchaRequired, so clients know to show a challenge.
+func (uc *AuthUsecase) verifyCaptcha(ctx context.Context, captchaToken, ip string) error {
+	err := uc.Captcha.Verify(ctx, captchaToken, ip)
+	if errors.Is(err, captcha.ErrMissingToken) || errors.Is(err, captcha.ErrRejected) {
+		return customerrors.ErrCaptchaRequired
+	}
+	return err
+}
+
+// startSession stores a new session for the device and issues its access and refresh tokens.
+func (uc *AuthUsecase) startSession(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string, location geoip.Location, suspicious bool) (uuid.UUID, string, string, error) {
+	sessionID := uuid.New()
+	accessToken, err := uc.newAccessToken(ctx, userID, sessionID)
+	if err != nil {
+		return uuid.Nil, "", "", err
+	}
+
+	refreshToken, err := uuid.NewUUID()
+	if err != nil {
+		return uuid.Nil, "", "", err
 	}
 
 	session := entity.Session{
-		ID:           uuid.New(),
+		ID:           sessionID,
 		UserID:       userID,
 		RefreshToken: refreshToken,
+		IsSuspicious: suspicious,
 		CreatedAt:    time.Now(),
-		ExpiresAt:    time.Now().Add(15 * 24 * time.Hour),
+		ExpiresAt:    uc.sessionExpiry(time.Now(), time.Now()),
 		UserAgent:    userAgent,
-		ClientIP:     netipAddr,
+		ClientIP:     ip,
+		CountryCode:  location.CountryCode,
+		City:         location.City,
 	}
 
-	err = uc.authRepo.StoreSession(ctx, userID, session)
-	if err != nil {
-		uc.Metrics.LoginAttempts.WithLabelValues("failure").Inc()
+	if err := uc.authRepo.StoreSession(ctx, userID, session); err != nil {
+		return uuid.Nil, "", "", err
+	}
+	if err := uc.authRepo.RememberDevice(ctx, userID, ip, userAgent, location.CountryCode); err != nil {
 		return uuid.Nil, "", "", err
 	}
+	uc.publishSessionEvent(ctx, entity.SessionEvent{
+		Type:        entity.SessionEventCreated,
+		UserID:      userID,
+		SessionID:   sessionID,
+		DeviceLabel: useragent.Parse(userAgent).Label(),
+		ClientIP:    ip.String(),
+		CountryCode: location.CountryCode,
+	})
+	return sessionID, accessToken, refreshToken.String(), nil
+}
 
-	uc.Metrics.LoginAttempts.WithLabelValues("success").Inc()
-	return userID, accessToken, refreshToken.String(), nil
+// sessionExpiry returns when a session started at loginAt and refreshed at now expires:
+// RefreshTokenTTL from now, capped at AbsoluteLifetime after login.
+func (uc *AuthUsecase) sessionExpiry(loginAt, now time.Time) time.Time {
+	expiresAt := now.Add(uc.SessionCfg.RefreshTokenTTL)
+	if uc.SessionCfg.AbsoluteLifetime > 0 {
+		if limit := loginAt.Add(uc.SessionCfg.AbsoluteLifetime); expiresAt.After(limit) {
+			return limit
+		}
+	}
+	return expiresAt
 }
 
-// LogoutSession logs out the user from a specific session by deleting that session from the database.
-func (uc *AuthUsecase) LogoutSession(ctx context.Context, userID string, sessionID string) error {
-	uid, err := uuid.Parse(userID)
+// lookupLocation resolves where the IP is located, the location is informational so lookup failures leave it empty.
+func (uc *AuthUsecase) lookupLocation(ip netip.Addr) geoip.Location {
+	location, err := uc.Geo.Lookup(ip)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return geoip.Location{}
 	}
-	sid, err := uuid.Parse(sessionID)
+	return location
+}
+
+// locationLine formats the location as an email line, empty if the location is unknown.
+func locationLine(location geoip.Location) string {
+	switch {
+	case location.City != "" && location.Country != "":
+		return "Location: " + location.City + ", " + location.Country + "\n"
+	case location.Country != "":
+		return "Location: " + location.Country + "\n"
+	default:
+		return ""
+	}
+}
+
+// requestLoginConfirmation stores the pending login and emails the confirmation link to the user.
+func (uc *AuthUsecase) requestLoginConfirmation(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string, location geoip.Location) error {
+	email, err := uc.authRepo.GetUserEmail(ctx, userID)
 	if err != nil {
-		return errors.New("invalid session ID")
+		return err
 	}
-	err = uc.authRepo.DeleteSession(ctx, uid, sid)
+
+	token, tokenHash, err := newConfirmationToken()
 	if err != nil {
 		return err
 	}
-	return nil
+	expiresAt := time.Now().Add(uc.LoginCfg.ConfirmationTTL)
+	if err := uc.authRepo.CreateLoginConfirmation(ctx, userID, ip, userAgent, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	body := "Someone is trying to sign in to your account from a new device:\n\n" +
+		"IP address: " + ip.String() + "\n" +
+		locationLine(location) +
+		"Device: " + userAgent + "\n\n" +
+		"If it was you, confirm the sign in by opening the link below:\n\n" +
+		uc.LoginCfg.ConfirmationURL + "?token=" + token + "\n\n" +
+		"If it was not you, change your password."
+	return uc.Mailer.Send(ctx, email, "Confirm sign in from a new device", body)
+}
+
+// sendNewDeviceAlert notifies the user that their account was signed in from a new device.
+func (uc *AuthUsecase) sendNewDeviceAlert(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string, location geoip.Location) error {
+	email, err := uc.authRepo.GetUserEmail(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	body := "Your account was just signed in from a new device:\n\n" +
+		"IP address: " + ip.String() + "\n" +
+		locationLine(location) +
+		"Device: " + userAgent + "\n\n" +
+		"If it was not you, change your password and sign out of all sessions."
+	return uc.Mailer.Send(ctx, email, "New device signed in", body)
+}
+
+// LogoutSession logs out the user from one of their own sessions by deleting it and revoking the access tokens bound to it.
+// The user ID must come from the authenticated token, so users can only end their own sessions.
+func (uc *AuthUsecase) LogoutSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if sessionID == uuid.Nil {
+		return apperror.InvalidArgument("invalid_session_id", "invalid session ID")
+	}
+	err := uc.authRepo.DeleteSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditLogout, userID, userID, map[string]any{"session_id": sessionID})
+	uc.publishSessionEvent(ctx, entity.SessionEvent{Type: entity.SessionEventRevoked, UserID: userID, SessionID: sessionID})
+	return uc.Denylist.RevokeSession(ctx, sessionID)
+}
+
+// WatchSessions streams the session events of the user until cancel is called.
+func (uc *AuthUsecase) WatchSessions(userID uuid.UUID) (<-chan entity.SessionEvent, func()) {
+	return uc.Events.Subscribe(userID)
+}
+
+// publishSessionEvent notifies the user's clients, it is best effort since the change itself already happened.
+func (uc *AuthUsecase) publishSessionEvent(ctx context.Context, event entity.SessionEvent) {
+	event.OccurredAt = time.Now()
+	_ = uc.Events.Publish(ctx, event)
+}
+
+// ListSessions returns a page of the active sessions of the user with a device label for each, newest first.
+// An empty cursor starts from the newest session; nextCursor fetches the following page and is empty on the last one.
+func (uc *AuthUsecase) ListSessions(ctx context.Context, userID uuid.UUID, cursor string, limit int) (sessions []entity.Session, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sessions, err = uc.authRepo.ListSessions(ctx, userID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	sessions, nextCursor = pagination.Page(sessions, limit, func(s entity.Session) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+	})
+	for i := range sessions {
+		sessions[i].DeviceLabel = useragent.Parse(sessions[i].UserAgent).Label()
+	}
+	return sessions, nextCursor, nil
+}
+
+// RenameSession names the device of one of the user's sessions, an empty name restores the derived label.
+func (uc *AuthUsecase) RenameSession(ctx context.Context, userID, sessionID uuid.UUID, name string) error {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxDeviceNameLength {
+		return apperror.InvalidArgument("device_name_too_long", "device name must be at most 64 characters")
+	}
+	return uc.authRepo.RenameSession(ctx, userID, sessionID, name)
 }
 
 // LogoutAllSessions logs out the user from all sessions by deleting all sessions associated with the user from the database.
-func (uc *AuthUsecase) LogoutAllSessions(ctx context.Context, userID string) error {
-	uid, err := uuid.Parse(userID)
+func (uc *AuthUsecase) LogoutAllSessions(ctx context.Context, userID uuid.UUID) error {
+	err := uc.authRepo.DeleteAllSessions(ctx, userID)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
-	err = uc.authRepo.DeleteAllSessions(ctx, uid)
+	uc.Audit.Record(ctx, entity.AuditLogoutAll, userID, userID, nil)
+	uc.publishSessionEvent(ctx, entity.SessionEvent{Type: entity.SessionEventRevoked, UserID: userID})
+	// access tokens are stateless, so they have to be revoked explicitly to take effect before expiry
+	return uc.Denylist.RevokeUser(ctx, userID, time.Now())
+}
+
+// ChangePassword verifies the current password, validates and stores the new one. If revokeSessions is set, the
+// other sessions of the user are deleted and their access tokens revoked, so every other device has to log in
+// again; the session the password is changed from stays signed in.
+func (uc *AuthUsecase) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string, revokeSessions bool) error {
+	passwordHash, err := uc.authRepo.GetPasswordHash(ctx, userID)
 	if err != nil {
 		return err
 	}
+	if ok, _ := uc.Hasher.Verify(currentPassword, passwordHash); !ok {
+		return customerrors.ErrWrongPassword
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	newHash, err := uc.Hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := uc.authRepo.UpdatePasswordHash(ctx, userID, newHash); err != nil {
+		return err
+	}
+
+	uc.Audit.Record(ctx, entity.AuditPasswordChange, userID, userID, map[string]any{"revoke_sessions": revokeSessions})
+	if !revokeSessions {
+		return nil
+	}
+
+	// the tokens of the other sessions are revoked one by one, a cutoff for the user would end the current one too
+	principal, _ := ctxUtil.PrincipalFromContext(ctx)
+	revoked, err := uc.authRepo.DeleteOtherSessions(ctx, userID, principal.SessionID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range revoked {
+		uc.publishSessionEvent(ctx, entity.SessionEvent{Type: entity.SessionEventRevoked, UserID: userID, SessionID: sessionID})
+		if err := uc.Denylist.RevokeSession(ctx, sessionID); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// VerifyUser checks if the provided access token is valid and returns the associated user ID if the token is valid.
-// It also checks if the user is blocked and returns an error if the user is blocked.
-func (uc *AuthUsecase) VerifyUser(token string) (userID uuid.UUID, err error) {
-	userID, err = uc.JWTManager.VerifyAccessToken(token)
+// RequestEmailChange starts the email change by mailing a one-time confirmation token to the new address.
+// The email is only swapped once the token is confirmed, so a typo cannot lock the user out.
+func (uc *AuthUsecase) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail string) error {
+	newEmail, err := uc.Emails.Normalize(newEmail)
 	if err != nil {
-		return uuid.Nil, err
+		return err
 	}
-	isBlocked, err := uc.authRepo.UserIsBlocked(userID)
+
+	token, tokenHash, err := newConfirmationToken()
 	if err != nil {
-		return uuid.Nil, err
+		return err
+	}
+	expiresAt := time.Now().Add(uc.EmailCfg.ConfirmationTTL)
+	if err := uc.authRepo.CreateEmailChangeRequest(ctx, userID, newEmail, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	body := "Confirm your new email address by opening the link below:\n\n" +
+		uc.EmailCfg.ConfirmationURL + "?token=" + token + "\n\n" +
+		"If you did not request this change, ignore this email."
+	return uc.Mailer.Send(ctx, newEmail, "Confirm your new email address", body)
+}
+
+// ConfirmEmailChange applies the pending email change identified by the token.
+func (uc *AuthUsecase) ConfirmEmailChange(ctx context.Context, token string) error {
+	if token == "" {
+		return customerrors.ErrInvalidToken
+	}
+	userID, err := uc.authRepo.ConfirmEmailChange(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditEmailChange, userID, userID, nil)
+	return nil
+}
+
+// DeleteAccount soft-deletes the account after re-confirming the password, ends all sessions and revokes access tokens.
+// Personal data is kept for the grace period (so support can restore the account) and then erased by AnonymizeDeletedAccounts.
+func (uc *AuthUsecase) DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error {
+	passwordHash, err := uc.authRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ok, _ := uc.Hasher.Verify(password, passwordHash); !ok {
+		return customerrors.ErrWrongPassword
+	}
+
+	if err := uc.authRepo.SoftDeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditAccountDelete, userID, userID, nil)
+	uc.publishSessionEvent(ctx, entity.SessionEvent{Type: entity.SessionEventRevoked, UserID: userID})
+	return uc.Denylist.RevokeUser(ctx, userID, time.Now())
+}
+
+// RestoreAccount undeletes the account on behalf of an admin, which is possible until AnonymizeDeletedAccounts erased
+// its personal data. The user has to log in again, their sessions were ended with the deletion.
+func (uc *AuthUsecase) RestoreAccount(ctx context.Context, userID uuid.UUID) error {
+	if err := uc.authRepo.RestoreUser(ctx, userID); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditAccountRestore, actorFromContext(ctx), userID, nil)
+	return nil
+}
+
+// AnonymizeDeletedAccounts erases PII of accounts deleted longer than gracePeriod ago. It is run by a background job.
+func (uc *AuthUsecase) AnonymizeDeletedAccounts(ctx context.Context, gracePeriod time.Duration) (int64, error) {
+	return uc.authRepo.AnonymizeDeletedUsers(ctx, time.Now().Add(-gracePeriod))
+}
+
+// CleanupExpiredSessions deletes sessions expired longer than retention ago, which can't be refreshed anymore.
+// It is run by a scheduled job.
+func (uc *AuthUsecase) CleanupExpiredSessions(ctx context.Context, retention time.Duration) (int64, error) {
+	return uc.authRepo.DeleteExpiredSessions(ctx, time.Now().Add(-retention))
+}
+
+// PruneExpiredTokens deletes expired email change and login confirmation tokens, used ones are deleted when they
+// are used. It is run by a scheduled job.
+func (uc *AuthUsecase) PruneExpiredTokens(ctx context.Context) (int64, error) {
+	return uc.authRepo.DeleteExpiredTokens(ctx, time.Now())
+}
+
+// BlockUser blocks the user until the given time (nil blocks indefinitely), ends all of their sessions
+// and revokes issued access tokens so the block takes effect immediately.
+func (uc *AuthUsecase) BlockUser(ctx context.Context, userID uuid.UUID, reason string, until *time.Time) error {
+	if until != nil && until.Before(time.Now()) {
+		return apperror.InvalidArgument("invalid_expiry", "block expiry must be in the future")
+	}
+	err := uc.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.authRepo.SetUserBlocked(ctx, userID, true, reason, until); err != nil {
+			return err
+		}
+		return uc.authRepo.DeleteAllSessions(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+	metadata := map[string]any{"reason": reason}
+	if until != nil {
+		metadata["until"] = until
+	}
+	uc.Audit.Record(ctx, entity.AuditUserBlock, actorFromContext(ctx), userID, metadata)
+	uc.publishSessionEvent(ctx, entity.SessionEvent{Type: entity.SessionEventRevoked, UserID: userID})
+	return uc.Denylist.RevokeUser(ctx, userID, time.Now())
+}
+
+// LookupUser returns the account of a user for admins, query is the user ID, the username or the email address.
+func (uc *AuthUsecase) LookupUser(ctx context.Context, query string) (entity.UserAccount, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return entity.UserAccount{}, apperror.InvalidArgument("empty_query", "query must not be empty")
+	}
+	if userID, err := uuid.Parse(query); err == nil {
+		return uc.authRepo.LookupUser(ctx, userID, "")
+	}
+	return uc.authRepo.LookupUser(ctx, uuid.Nil, query)
+}
+
+// RevokeUserSession ends one of the sessions of the user on behalf of an admin and revokes the access tokens bound to it.
+func (uc *AuthUsecase) RevokeUserSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := uc.authRepo.DeleteSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditSessionRevoke, actorFromContext(ctx), userID, map[string]any{"session_id": sessionID})
+	uc.publishSessionEvent(ctx, entity.SessionEvent{Type: entity.SessionEventRevoked, UserID: userID, SessionID: sessionID})
+	return uc.Denylist.RevokeSession(ctx, sessionID)
+}
+
+// RevokeUserSessions ends all sessions of the user on behalf of an admin and revokes issued access tokens.
+func (uc *AuthUsecase) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := uc.authRepo.DeleteAllSessions(ctx, userID); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditSessionRevokeAll, actorFromContext(ctx), userID, nil)
+	uc.publishSessionEvent(ctx, entity.SessionEvent{Type: entity.SessionEventRevoked, UserID: userID})
+	return uc.Denylist.RevokeUser(ctx, userID, time.Now())
+}
+
+// UnblockUser lifts the block of the user.
+func (uc *AuthUsecase) UnblockUser(ctx context.Context, userID uuid.UUID) error {
+	if err := uc.authRepo.SetUserBlocked(ctx, userID, false, "", nil); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditUserUnblock, actorFromContext(ctx), userID, nil)
+	return nil
+}
+
+// GrantRole grants the role to the user. It takes effect with the next issued access token.
+func (uc *AuthUsecase) GrantRole(ctx context.Context, userID uuid.UUID, role entity.Role) error {
+	if !role.Valid() {
+		return apperror.InvalidArgument("unknown_role", "unknown role")
+	}
+	if err := uc.authRepo.AddUserRole(ctx, userID, role); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditRoleGrant, actorFromContext(ctx), userID, map[string]any{"role": role})
+	return nil
+}
+
+// RevokeRole revokes the role from the user. Issued access tokens still carry the role,
+// so they are revoked and the user has to refresh the session to get a token with the new role set.
+func (uc *AuthUsecase) RevokeRole(ctx context.Context, userID uuid.UUID, role entity.Role) error {
+	if role == entity.RoleUser {
+		return apperror.InvalidArgument("role_not_revocable", "the user role cannot be revoked")
+	}
+	if err := uc.authRepo.RemoveUserRole(ctx, userID, role); err != nil {
+		return err
+	}
+	uc.Audit.Record(ctx, entity.AuditRoleRevoke, actorFromContext(ctx), userID, map[string]any{"role": role})
+	return uc.Denylist.RevokeUser(ctx, userID, time.Now())
+}
+
+// VerifyUser checks if the provided access token is valid and returns its user ID, session ID and roles.
+// It also checks if the token was revoked or the user is blocked and returns an error in that case.
+func (uc *AuthUsecase) VerifyUser(ctx context.Context, token string) (jwt.AccessToken, error) {
+	accessToken, err := uc.JWTManager.ParseAccessToken(token)
+	if err != nil {
+		return jwt.AccessToken{}, err
+	}
+	revoked, err := uc.Denylist.IsRevoked(ctx, accessToken.UserID, accessToken.SessionID, accessToken.IssuedAt)
+	if err != nil {
+		return jwt.AccessToken{}, err
+	}
+	if revoked {
+		return jwt.AccessToken{}, apperror.Unauthenticated("token_revoked", "token has been revoked")
+	}
+	isBlocked, err := uc.authRepo.UserIsBlocked(ctx, accessToken.UserID)
+	if err != nil {
+		return jwt.AccessToken{}, err
 	}
 	if isBlocked {
-		return uuid.Nil, errors.New("user is blocked")
+		return jwt.AccessToken{}, customerrors.ErrUserBlocked
 	}
-	return userID, nil
+	return accessToken, nil
+}
+
+// Introspect reports whether an access or refresh token is currently valid and what it grants (RFC 7662).
+// The hint selects which token type is tried first. Invalid tokens are reported inactive, not as an error.
+func (uc *AuthUsecase) Introspect(ctx context.Context, token, tokenTypeHint string) (entity.TokenIntrospection, error) {
+	if tokenTypeHint == entity.TokenTypeRefresh {
+		if info, err := uc.introspectRefreshToken(ctx, token); err != nil || info.Active {
+			return info, err
+		}
+		return uc.introspectAccessToken(ctx, token)
+	}
+	if info, err := uc.introspectAccessToken(ctx, token); err != nil || info.Active {
+		return info, err
+	}
+	return uc.introspectRefreshToken(ctx, token)
+}
+
+func (uc *AuthUsecase) introspectAccessToken(ctx context.Context, token string) (entity.TokenIntrospection, error) {
+	accessToken, err := uc.JWTManager.ParseAccessToken(token)
+	if err != nil {
+		return entity.TokenIntrospection{}, nil
+	}
+	revoked, err := uc.Denylist.IsRevoked(ctx, accessToken.UserID, accessToken.SessionID, accessToken.IssuedAt)
+	if err != nil {
+		return entity.TokenIntrospection{}, err
+	}
+	isBlocked, err := uc.authRepo.UserIsBlocked(ctx, accessToken.UserID)
+	if err != nil {
+		return entity.TokenIntrospection{}, err
+	}
+	if revoked || isBlocked {
+		return entity.TokenIntrospection{}, nil
+	}
+	return entity.TokenIntrospection{
+		Active:    true,
+		TokenType: entity.TokenTypeAccess,
+		UserID:    accessToken.UserID,
+		SessionID: accessToken.SessionID,
+		Scopes:    accessToken.Roles,
+		IssuedAt:  accessToken.IssuedAt,
+		ExpiresAt: accessToken.ExpiresAt,
+	}, nil
+}
+
+func (uc *AuthUsecase) introspectRefreshToken(ctx context.Context, token string) (entity.TokenIntrospection, error) {
+	refreshToken, err := uuid.Parse(token)
+	if err != nil {
+		return entity.TokenIntrospection{}, nil
+	}
+	session, err := uc.authRepo.GetSessionByRefreshToken(ctx, refreshToken)
+	if errors.Is(err, customerrors.ErrInvalidToken) {
+		return entity.TokenIntrospection{}, nil
+	}
+	if err != nil {
+		return entity.TokenIntrospection{}, err
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		return entity.TokenIntrospection{}, nil
+	}
+	isBlocked, err := uc.authRepo.UserIsBlocked(ctx, session.UserID)
+	if err != nil {
+		return entity.TokenIntrospection{}, err
+	}
+	if isBlocked {
+		return entity.TokenIntrospection{}, nil
+	}
+	return entity.TokenIntrospection{
+		Active:    true,
+		TokenType: entity.TokenTypeRefresh,
+		UserID:    session.UserID,
+		SessionID: session.ID,
+		IssuedAt:  session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
 }
 
-// hashPassword hashes the given password using bcrypt
-func hashPassword(password string) (string, error) {
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(passwordHash), err
+// newAccessToken issues an access token bound to the session and carrying the current roles of the user.
+func (uc *AuthUsecase) newAccessToken(ctx context.Context, userID, sessionID uuid.UUID) (string, error) {
+	roles, err := uc.authRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return uc.JWTManager.NewAccessToken(userID, sessionID, names)
 }
 
-// verifyPassword compares the provided password with the stored password hash and returns true if they match, false otherwise.
-func verifyPassword(password, passwordHash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
-	return err == nil
+// actorFromContext returns the authenticated user performing the request, uuid.Nil for system actions.
+func actorFromContext(ctx context.Context) uuid.UUID {
+	actorID, _ := ctxUtil.UserIDFromContext(ctx)
+	return actorID
+}
+
+// newConfirmationToken generates a random URL-safe token and the hash that is stored instead of the token itself.
+func newConfirmationToken() (token string, tokenHash []byte, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
 }
 
 // ValidatePassword checks if the password meets certain criteria
@@ -271,49 +1133,27 @@ func validatePassword(password string) error {
 	}
 
 	if !hasMinLen {
-		return errors.New("password must be at least 8 characters long")
+		return apperror.InvalidArgument("weak_password", "password must be at least 8 characters long")
 	}
 	if !hasUpper {
-		return errors.New("password must contain at least one uppercase letter")
+		return apperror.InvalidArgument("weak_password", "password must contain at least one uppercase letter")
 	}
 	if !hasLower {
-		return errors.New("password must contain at least one lowercase letter")
+		return apperror.InvalidArgument("weak_password", "password must contain at least one lowercase letter")
 	}
 	if !hasNumber {
-		return errors.New("password must contain at least one number")
+		return apperror.InvalidArgument("weak_password", "password must contain at least one number")
 	}
 	if !hasSpecial {
-		return errors.New("password must contain at least one special character")
+		return apperror.InvalidArgument("weak_password", "password must contain at least one special character")
 	}
 
 	return nil
 }
 
-// Simple function to check if a string contains '@' symbol
-func containsAtSymbol(s string) bool {
-	for _, char := range s {
-		if char == '@' {
-			return true
-		}
-	}
-	return false
-}
-
-func validateEmail(email string) bool {
-	// Simple email validation
-	if len(email) < 5 || len(email) > 50 {
-		return false
-	}
-	if !containsAtSymbol(email) {
-		return false
-	}
-	return true
-}
-
 func validateUsername(username string) bool {
 	if len(username) < 3 || len(username) > 30 {
 		return false
 	}
 	return true
 }
-
@@ -0,0 +1,727 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/internal/metrics"
+	"main/internal/usecase/auth"
+	"main/internal/usecase/auth/mocks"
+	"main/pkg/apperror"
+	"main/pkg/captcha"
+	"main/pkg/customerrors"
+	"main/pkg/email"
+	"main/pkg/geoip"
+	"main/pkg/jwt"
+	"main/pkg/password"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ auth.AuthRepo   = (*mocks.AuthRepo)(nil)
+	_ auth.JWTManager = (*mocks.JWTManager)(nil)
+)
+
+const (
+	validPassword = "Str0ng!pass"
+	clientIP      = "203.0.113.7"
+	userAgent     = "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0"
+)
+
+var errDB = errors.New("connection reset")
+
+// env is the usecase under test with its mocked and fake dependencies.
+type env struct {
+	uc       *auth.AuthUsecase
+	repo     *mocks.AuthRepo
+	jwt      *mocks.JWTManager
+	hasher   *password.Hasher
+	denylist *fakeDenylist
+	captcha  *fakeCaptcha
+	failures *fakeFailures
+	mailer   *fakeMailer
+	events   *fakeEvents
+}
+
+type envConfig struct {
+	session      config.SessionConfig
+	login        config.LoginSecurityConfig
+	captcha      config.CaptchaConfig
+	registration config.RegistrationConfig
+}
+
+func newEnv(t *testing.T, configure ...func(cfg *envConfig)) *env {
+	t.Helper()
+	cfg := envConfig{
+		session:      config.SessionConfig{RefreshTokenTTL: 7 * 24 * time.Hour, SlidingExpiration: true, AbsoluteLifetime: 30 * 24 * time.Hour},
+		login:        config.LoginSecurityConfig{ConfirmationURL: "https://threads.test/login/confirm", ConfirmationTTL: 15 * time.Minute},
+		captcha:      config.CaptchaConfig{LoginFailures: 3},
+		registration: config.RegistrationConfig{ReservedUsernames: []string{"admin"}},
+	}
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+
+	// the minimum bcrypt cost keeps the tests fast
+	hasher, err := password.NewHasher(password.AlgorithmBcrypt, 4, password.Argon2Params{})
+	require.NoError(t, err)
+
+	e := &env{
+		repo:     mocks.NewAuthRepo(t),
+		jwt:      mocks.NewJWTManager(t),
+		hasher:   hasher,
+		denylist: &fakeDenylist{},
+		captcha:  &fakeCaptcha{},
+		failures: &fakeFailures{counts: map[string]int64{}},
+		mailer:   &fakeMailer{},
+		events:   &fakeEvents{},
+	}
+	e.uc = auth.NewAuthUsecase(e.repo, nil, e.jwt, e.denylist, hasher, email.NewValidator(nil), geoip.NopResolver{},
+		e.captcha, e.failures, e.mailer, nopAudit{}, e.events, offFlags{}, config.EmailConfig{}, cfg.login,
+		cfg.registration, cfg.session, cfg.captcha, metrics.NewMetrics(prometheus.NewRegistry()))
+	return e
+}
+
+func (e *env) hash(t *testing.T, plain string) string {
+	t.Helper()
+	hash, err := e.hasher.Hash(plain)
+	require.NoError(t, err)
+	return hash
+}
+
+// requireCode asserts that err is an apperror with the code.
+func requireCode(t *testing.T, err error, code string) {
+	t.Helper()
+	require.Error(t, err)
+	appErr := apperror.From(err)
+	require.NotNil(t, appErr, "expected an apperror, got %v", err)
+	assert.Equal(t, code, appErr.Code)
+}
+
+func TestRegisterUser(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		password string
+		captcha  error
+		setup    func(e *env)
+		wantCode string
+		wantErr  error
+	}{
+		{
+			name:     "creates the user with a normalized email and hashed password",
+			username: "alice",
+			email:    "Alice@Example.com",
+			password: validPassword,
+			setup: func(e *env) {
+				e.repo.On("CreateUser", mock.Anything, mock.Anything, "alice@example.com", "alice", mock.MatchedBy(func(hash string) bool {
+					ok, _ := e.hasher.Verify(validPassword, hash)
+					return ok
+				})).Return(userID, nil)
+			},
+		},
+		{
+			name:     "username too short",
+			username: "al",
+			email:    "alice@example.com",
+			password: validPassword,
+			wantCode: "invalid_username",
+		},
+		{
+			name:     "reserved username is matched case-insensitively",
+			username: "Admin",
+			email:    "alice@example.com",
+			password: validPassword,
+			wantCode: "username_reserved",
+		},
+		{
+			name:     "invalid email",
+			username: "alice",
+			email:    "not-an-email",
+			password: validPassword,
+			wantErr:  email.ErrInvalidEmail,
+		},
+		{
+			name:     "password without a special character",
+			username: "alice",
+			email:    "alice@example.com",
+			password: "Str0ngpass",
+			wantCode: "weak_password",
+		},
+		{
+			name:     "password too short",
+			username: "alice",
+			email:    "alice@example.com",
+			password: "S0!a",
+			wantCode: "weak_password",
+		},
+		{
+			name:     "rejected captcha asks for a new challenge",
+			username: "alice",
+			email:    "alice@example.com",
+			password: validPassword,
+			captcha:  captcha.ErrRejected,
+			wantCode: "captcha_required",
+		},
+		{
+			name:     "taken email is reported as is",
+			username: "alice",
+			email:    "alice@example.com",
+			password: validPassword,
+			setup: func(e *env) {
+				e.repo.On("CreateUser", mock.Anything, mock.Anything, "alice@example.com", "alice", mock.Anything).Return(uuid.Nil, customerrors.ErrEmailTaken)
+			},
+			wantErr: customerrors.ErrEmailTaken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEnv(t)
+			e.captcha.err = tt.captcha
+			if tt.setup != nil {
+				tt.setup(e)
+			}
+
+			got, err := e.uc.RegisterUser(context.Background(), tt.username, tt.email, tt.password, "captcha-token")
+
+			switch {
+			case tt.wantCode != "":
+				requireCode(t, err, tt.wantCode)
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, userID, got)
+			}
+			if tt.wantCode != "" || tt.wantErr != nil {
+				assert.Equal(t, uuid.Nil, got)
+			}
+		})
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	userID := uuid.New()
+	ip := netip.MustParseAddr(clientIP)
+
+	tests := []struct {
+		name       string
+		login      string
+		password   string
+		ip         string
+		configure  func(cfg *envConfig)
+		failures   int64
+		captcha    error
+		setup      func(t *testing.T, e *env)
+		wantCode   string
+		wantErr    error
+		wantFailed bool
+		wantEmails int
+	}{
+		{
+			name:     "first login starts a session",
+			login:    "alice",
+			password: validPassword,
+			ip:       clientIP,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+				e.repo.On("UserIsBlocked", mock.Anything, userID).Return(false, nil)
+				e.repo.On("IsKnownDevice", mock.Anything, userID, ip, userAgent).Return(false, false, nil)
+				e.repo.On("GetUserRoles", mock.Anything, userID).Return([]entity.Role{entity.RoleUser}, nil)
+				e.jwt.On("NewAccessToken", userID, mock.Anything, []string{"user"}).Return("access-token", nil)
+				e.repo.On("StoreSession", mock.Anything, userID, mock.MatchedBy(func(s entity.Session) bool {
+					return s.UserID == userID && !s.IsSuspicious && s.ClientIP == ip && s.UserAgent == userAgent &&
+						(s.ExpiresAt.Sub(s.CreatedAt)-7*24*time.Hour).Abs() < time.Second
+				})).Return(nil)
+				e.repo.On("RememberDevice", mock.Anything, userID, ip, userAgent, "").Return(nil)
+			},
+		},
+		{
+			name:       "unknown user counts as a failed login",
+			login:      "nobody",
+			password:   validPassword,
+			ip:         clientIP,
+			wantErr:    customerrors.ErrNotFound,
+			wantFailed: true,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "nobody").Return(uuid.Nil, "", customerrors.ErrNotFound)
+			},
+		},
+		{
+			name:       "wrong password",
+			login:      "alice",
+			password:   "Wr0ng!pass",
+			ip:         clientIP,
+			wantCode:   "invalid_credentials",
+			wantFailed: true,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+			},
+		},
+		{
+			name:     "blocked user gets no session",
+			login:    "alice",
+			password: validPassword,
+			ip:       clientIP,
+			wantErr:  customerrors.ErrUserBlocked,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+				e.repo.On("UserIsBlocked", mock.Anything, userID).Return(true, nil)
+			},
+		},
+		{
+			name:     "block check failure fails the login",
+			login:    "alice",
+			password: validPassword,
+			ip:       clientIP,
+			wantErr:  errDB,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+				e.repo.On("UserIsBlocked", mock.Anything, userID).Return(false, errDB)
+			},
+		},
+		{
+			name:     "invalid client IP",
+			login:    "alice",
+			password: validPassword,
+			ip:       "not-an-ip",
+			wantCode: "invalid_ip",
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+				e.repo.On("UserIsBlocked", mock.Anything, userID).Return(false, nil)
+			},
+		},
+		{
+			name:     "captcha is required after too many failures",
+			login:    "alice",
+			password: validPassword,
+			ip:       clientIP,
+			failures: 3,
+			captcha:  captcha.ErrMissingToken,
+			wantCode: "captcha_required",
+		},
+		{
+			name:     "new device must be confirmed by email when required",
+			login:    "alice",
+			password: validPassword,
+			ip:       clientIP,
+			configure: func(cfg *envConfig) {
+				cfg.login.RequireConfirmation = true
+			},
+			wantErr:    customerrors.ErrLoginConfirmationRequired,
+			wantEmails: 1,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+				e.repo.On("UserIsBlocked", mock.Anything, userID).Return(false, nil)
+				e.repo.On("IsKnownDevice", mock.Anything, userID, ip, userAgent).Return(false, true, nil)
+				e.repo.On("GetUserEmail", mock.Anything, userID).Return("alice@example.com", nil)
+				e.repo.On("CreateLoginConfirmation", mock.Anything, userID, ip, userAgent, mock.Anything, mock.MatchedBy(func(expiresAt time.Time) bool {
+					return time.Until(expiresAt) > 14*time.Minute && time.Until(expiresAt) <= 15*time.Minute
+				})).Return(nil)
+			},
+		},
+		{
+			name:       "new device is flagged and alerted when confirmation is not required",
+			login:      "alice",
+			password:   validPassword,
+			ip:         clientIP,
+			configure:  func(cfg *envConfig) { cfg.login.NewDeviceAlerts = true },
+			wantEmails: 1,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+				e.repo.On("UserIsBlocked", mock.Anything, userID).Return(false, nil)
+				e.repo.On("IsKnownDevice", mock.Anything, userID, ip, userAgent).Return(false, true, nil)
+				e.repo.On("GetUserRoles", mock.Anything, userID).Return([]entity.Role{entity.RoleUser}, nil)
+				e.jwt.On("NewAccessToken", userID, mock.Anything, []string{"user"}).Return("access-token", nil)
+				e.repo.On("StoreSession", mock.Anything, userID, mock.MatchedBy(func(s entity.Session) bool {
+					return s.IsSuspicious
+				})).Return(nil)
+				e.repo.On("RememberDevice", mock.Anything, userID, ip, userAgent, "").Return(nil)
+				e.repo.On("GetUserEmail", mock.Anything, userID).Return("alice@example.com", nil)
+			},
+		},
+		{
+			name:     "token signing failure stores no session",
+			login:    "alice",
+			password: validPassword,
+			ip:       clientIP,
+			wantErr:  jwt.ErrUnknownKeyID,
+			setup: func(t *testing.T, e *env) {
+				e.repo.On("GetUserByLogin", mock.Anything, "alice").Return(userID, e.hash(t, validPassword), nil)
+				e.repo.On("UserIsBlocked", mock.Anything, userID).Return(false, nil)
+				e.repo.On("IsKnownDevice", mock.Anything, userID, ip, userAgent).Return(true, true, nil)
+				e.repo.On("GetUserRoles", mock.Anything, userID).Return([]entity.Role{entity.RoleUser}, nil)
+				e.jwt.On("NewAccessToken", userID, mock.Anything, []string{"user"}).Return("", jwt.ErrUnknownKeyID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configure := []func(cfg *envConfig){}
+			if tt.configure != nil {
+				configure = append(configure, tt.configure)
+			}
+			e := newEnv(t, configure...)
+			e.failures.counts[tt.login] = tt.failures
+			e.captcha.err = tt.captcha
+			if tt.setup != nil {
+				tt.setup(t, e)
+			}
+
+			gotUserID, accessToken, refreshToken, err := e.uc.LoginUser(context.Background(), tt.login, tt.password, userAgent, tt.ip, "")
+
+			switch {
+			case tt.wantCode != "":
+				requireCode(t, err, tt.wantCode)
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, userID, gotUserID)
+				assert.Equal(t, "access-token", accessToken)
+				_, err := uuid.Parse(refreshToken)
+				assert.NoError(t, err, "refresh token should be a UUID")
+				assert.Equal(t, []entity.SessionEventType{entity.SessionEventCreated}, e.events.types())
+				assert.Zero(t, e.failures.counts[tt.login], "a successful login resets the failures")
+			}
+			if tt.wantCode != "" || tt.wantErr != nil {
+				assert.Empty(t, accessToken)
+				assert.Empty(t, refreshToken)
+				e.repo.AssertNotCalled(t, "StoreSession", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.wantFailed {
+				assert.Equal(t, tt.failures+1, e.failures.counts[tt.login])
+			}
+			assert.Len(t, e.mailer.sent, tt.wantEmails)
+		})
+	}
+}
+
+func TestRefreshSessionToken(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+	refreshToken := uuid.New()
+
+	tests := []struct {
+		name      string
+		token     string
+		configure func(cfg *envConfig)
+		session   entity.Session
+		repoErr   error
+		setup     func(e *env)
+		// wantExpiry checks the expiry of the refreshed session
+		wantExpiry func(t *testing.T, session entity.Session, stored time.Time)
+		wantCode   string
+		wantErr    error
+	}{
+		{
+			name:     "malformed token",
+			token:    "not-a-uuid",
+			wantCode: "invalid_session_id",
+		},
+		{
+			name:    "unknown token",
+			token:   refreshToken.String(),
+			repoErr: customerrors.ErrInvalidToken,
+			wantErr: customerrors.ErrInvalidToken,
+		},
+		{
+			name:     "expired session is deleted",
+			token:    refreshToken.String(),
+			session:  entity.Session{CreatedAt: time.Now().Add(-8 * 24 * time.Hour), ExpiresAt: time.Now().Add(-time.Second)},
+			setup:    func(e *env) { e.repo.On("DeleteSession", mock.Anything, userID, sessionID).Return(nil) },
+			wantCode: "session_expired",
+		},
+		{
+			name:     "session expiring right now is expired",
+			token:    refreshToken.String(),
+			session:  entity.Session{CreatedAt: time.Now().Add(-7 * 24 * time.Hour), ExpiresAt: time.Now()},
+			setup:    func(e *env) { e.repo.On("DeleteSession", mock.Anything, userID, sessionID).Return(nil) },
+			wantCode: "session_expired",
+		},
+		{
+			name:    "sliding expiration extends the session by the refresh token TTL",
+			token:   refreshToken.String(),
+			session: entity.Session{CreatedAt: time.Now().Add(-24 * time.Hour), ExpiresAt: time.Now().Add(time.Hour)},
+			wantExpiry: func(t *testing.T, session entity.Session, stored time.Time) {
+				assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), stored, time.Minute)
+			},
+		},
+		{
+			name:    "sliding expiration is capped at the absolute lifetime",
+			token:   refreshToken.String(),
+			session: entity.Session{CreatedAt: time.Now().Add(-29 * 24 * time.Hour), ExpiresAt: time.Now().Add(time.Hour)},
+			wantExpiry: func(t *testing.T, session entity.Session, stored time.Time) {
+				assert.True(t, stored.Equal(session.CreatedAt.Add(30*24*time.Hour)), "expiry %v should be capped", stored)
+			},
+		},
+		{
+			name:      "fixed expiration keeps the expiry",
+			token:     refreshToken.String(),
+			configure: func(cfg *envConfig) { cfg.session.SlidingExpiration = false },
+			session:   entity.Session{CreatedAt: time.Now().Add(-24 * time.Hour), ExpiresAt: time.Now().Add(time.Hour)},
+			wantExpiry: func(t *testing.T, session entity.Session, stored time.Time) {
+				assert.True(t, stored.Equal(session.ExpiresAt))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configure := []func(cfg *envConfig){}
+			if tt.configure != nil {
+				configure = append(configure, tt.configure)
+			}
+			e := newEnv(t, configure...)
+			session := tt.session
+			session.ID, session.UserID, session.RefreshToken = sessionID, userID, refreshToken
+			if tt.token != "not-a-uuid" {
+				e.repo.On("GetSessionByRefreshToken", mock.Anything, refreshToken).Return(session, tt.repoErr)
+			}
+			if tt.setup != nil {
+				tt.setup(e)
+			}
+			var stored entity.Session
+			if tt.wantExpiry != nil {
+				e.repo.On("RefreshSession", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					stored = args.Get(1).(entity.Session)
+				}).Return(nil)
+				e.repo.On("GetUserRoles", mock.Anything, userID).Return([]entity.Role{entity.RoleUser}, nil)
+				e.jwt.On("NewAccessToken", userID, sessionID, []string{"user"}).Return("access-token", nil)
+			}
+
+			accessToken, newRefreshToken, err := e.uc.RefreshSessionToken(context.Background(), tt.token)
+
+			switch {
+			case tt.wantCode != "":
+				requireCode(t, err, tt.wantCode)
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "access-token", accessToken)
+				assert.Equal(t, stored.RefreshToken.String(), newRefreshToken)
+				assert.NotEqual(t, refreshToken.String(), newRefreshToken, "the refresh token is rotated")
+				tt.wantExpiry(t, session, stored.ExpiresAt)
+			}
+			if tt.wantCode != "" || tt.wantErr != nil {
+				assert.Empty(t, accessToken)
+				assert.Empty(t, newRefreshToken)
+				e.repo.AssertNotCalled(t, "RefreshSession", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestLogoutSession(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	tests := []struct {
+		name        string
+		sessionID   uuid.UUID
+		deleteErr   error
+		wantCode    string
+		wantErr     error
+		wantRevoked bool
+	}{
+		{name: "deletes the session and revokes its tokens", sessionID: sessionID, wantRevoked: true},
+		{name: "nil session", sessionID: uuid.Nil, wantCode: "invalid_session_id"},
+		{name: "session of another user", sessionID: sessionID, deleteErr: customerrors.ErrNotFound, wantErr: customerrors.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEnv(t)
+			if tt.sessionID != uuid.Nil {
+				e.repo.On("DeleteSession", mock.Anything, userID, tt.sessionID).Return(tt.deleteErr)
+			}
+
+			err := e.uc.LogoutSession(context.Background(), userID, tt.sessionID)
+
+			switch {
+			case tt.wantCode != "":
+				requireCode(t, err, tt.wantCode)
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, []entity.SessionEventType{entity.SessionEventRevoked}, e.events.types())
+			}
+			if tt.wantRevoked {
+				assert.Equal(t, []uuid.UUID{tt.sessionID}, e.denylist.sessions)
+			} else {
+				assert.Empty(t, e.denylist.sessions)
+			}
+		})
+	}
+}
+
+func TestLogoutAllSessions(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("deletes every session and revokes the issued tokens", func(t *testing.T) {
+		e := newEnv(t)
+		e.repo.On("DeleteAllSessions", mock.Anything, userID).Return(nil)
+
+		require.NoError(t, e.uc.LogoutAllSessions(context.Background(), userID))
+		assert.Equal(t, []uuid.UUID{userID}, e.denylist.users)
+		assert.Equal(t, []entity.SessionEventType{entity.SessionEventRevoked}, e.events.types())
+	})
+
+	t.Run("tokens stay valid if the sessions couldn't be deleted", func(t *testing.T) {
+		e := newEnv(t)
+		e.repo.On("DeleteAllSessions", mock.Anything, userID).Return(errDB)
+
+		require.ErrorIs(t, e.uc.LogoutAllSessions(context.Background(), userID), errDB)
+		assert.Empty(t, e.denylist.users)
+	})
+}
+
+func TestVerifyUser(t *testing.T) {
+	token := jwt.AccessToken{UserID: uuid.New(), SessionID: uuid.New(), Roles: []string{"user"}, IssuedAt: time.Now().Add(-time.Minute), ExpiresAt: time.Now().Add(14 * time.Minute)}
+
+	tests := []struct {
+		name     string
+		parseErr error
+		revoked  bool
+		blocked  bool
+		wantCode string
+		wantErr  error
+	}{
+		{name: "valid token"},
+		{name: "invalid or expired token", parseErr: jwt.ErrUnknownKeyID, wantErr: jwt.ErrUnknownKeyID},
+		{name: "revoked token", revoked: true, wantCode: "token_revoked"},
+		{name: "blocked user", blocked: true, wantErr: customerrors.ErrUserBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEnv(t)
+			e.jwt.On("ParseAccessToken", "token").Return(token, tt.parseErr)
+			e.denylist.revoked = tt.revoked
+			if tt.parseErr == nil && !tt.revoked {
+				e.repo.On("UserIsBlocked", mock.Anything, token.UserID).Return(tt.blocked, nil)
+			}
+
+			got, err := e.uc.VerifyUser(context.Background(), "token")
+
+			switch {
+			case tt.wantCode != "":
+				requireCode(t, err, tt.wantCode)
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, token, got)
+			}
+		})
+	}
+}
+
+type fakeDenylist struct {
+	revoked  bool
+	users    []uuid.UUID
+	sessions []uuid.UUID
+}
+
+func (d *fakeDenylist) RevokeUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	d.users = append(d.users, userID)
+	return nil
+}
+
+func (d *fakeDenylist) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	d.sessions = append(d.sessions, sessionID)
+	return nil
+}
+
+func (d *fakeDenylist) IsRevoked(ctx context.Context, userID, sessionID uuid.UUID, issuedAt time.Time) (bool, error) {
+	return d.revoked, nil
+}
+
+type fakeCaptcha struct {
+	err error
+}
+
+func (c *fakeCaptcha) Verify(ctx context.Context, token, remoteIP string) error {
+	return c.err
+}
+
+type fakeFailures struct {
+	counts map[string]int64
+}
+
+func (f *fakeFailures) Count(ctx context.Context, login string) (int64, error) {
+	return f.counts[login], nil
+}
+
+func (f *fakeFailures) Add(ctx context.Context, login string) error {
+	f.counts[login]++
+	return nil
+}
+
+func (f *fakeFailures) Reset(ctx context.Context, login string) error {
+	delete(f.counts, login)
+	return nil
+}
+
+type fakeMailer struct {
+	sent []string
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+type fakeEvents struct {
+	mu     sync.Mutex
+	events []entity.SessionEvent
+}
+
+func (b *fakeEvents) Publish(ctx context.Context, event entity.SessionEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	return nil
+}
+
+func (b *fakeEvents) Subscribe(userID uuid.UUID) (<-chan entity.SessionEvent, func()) {
+	return make(chan entity.SessionEvent), func() {}
+}
+
+func (b *fakeEvents) types() []entity.SessionEventType {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	types := make([]entity.SessionEventType, len(b.events))
+	for i, event := range b.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+type nopAudit struct{}
+
+func (nopAudit) Record(ctx context.Context, eventType entity.AuditEventType, actorID, subjectID uuid.UUID, metadata map[string]any) {
+}
+
+type offFlags struct{}
+
+func (offFlags) Enabled(ctx context.Context, name string, userID uuid.UUID) bool {
+	return false
+}
@@ -0,0 +1,202 @@
+// Package mocks provides testify mocks of the dependencies of the auth usecase, so its tests can set
+// expectations per call instead of running Postgres.
+package mocks
+
+import (
+	"context"
+	"main/domain/entity"
+	"net/netip"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// AuthRepo is a mock of auth.AuthRepo.
+type AuthRepo struct {
+	mock.Mock
+}
+
+// NewAuthRepo returns an AuthRepo whose expectations are asserted when the test ends.
+func NewAuthRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AuthRepo {
+	m := &AuthRepo{}
+	m.Test(t)
+	t.Cleanup(func() { m.AssertExpectations(t) })
+	return m
+}
+
+func (m *AuthRepo) CreateUser(ctx context.Context, userID uuid.UUID, email string, username string, passwordHash string) (uuid.UUID, error) {
+	args := m.Called(ctx, userID, email, username, passwordHash)
+	r0, _ := args.Get(0).(uuid.UUID)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) GetUserByLogin(ctx context.Context, login string) (uuid.UUID, string, error) {
+	args := m.Called(ctx, login)
+	r0, _ := args.Get(0).(uuid.UUID)
+	return r0, args.String(1), args.Error(2)
+}
+
+func (m *AuthRepo) StoreSession(ctx context.Context, userID uuid.UUID, session entity.Session) error {
+	args := m.Called(ctx, userID, session)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) DeleteSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) DeleteAllSessions(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) DeleteOtherSessions(ctx context.Context, userID, keepID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(ctx, userID, keepID)
+	r0, _ := args.Get(0).([]uuid.UUID)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) UserIsBlocked(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *AuthRepo) SetUserBlocked(ctx context.Context, userID uuid.UUID, blocked bool, reason string, until *time.Time) error {
+	args := m.Called(ctx, userID, blocked, reason, until)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]entity.Role, error) {
+	args := m.Called(ctx, userID)
+	r0, _ := args.Get(0).([]entity.Role)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) AddUserRole(ctx context.Context, userID uuid.UUID, role entity.Role) error {
+	args := m.Called(ctx, userID, role)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) RemoveUserRole(ctx context.Context, userID uuid.UUID, role entity.Role) error {
+	args := m.Called(ctx, userID, role)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) GetSessionByRefreshToken(ctx context.Context, refreshToken uuid.UUID) (entity.Session, error) {
+	args := m.Called(ctx, refreshToken)
+	r0, _ := args.Get(0).(entity.Session)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) RefreshSession(ctx context.Context, session entity.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *AuthRepo) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) CreateEmailChangeRequest(ctx context.Context, userID uuid.UUID, newEmail string, tokenHash []byte, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, newEmail, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) ConfirmEmailChange(ctx context.Context, tokenHash []byte) (uuid.UUID, error) {
+	args := m.Called(ctx, tokenHash)
+	r0, _ := args.Get(0).(uuid.UUID)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) SoftDeleteUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) RestoreUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) AnonymizeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, deletedBefore)
+	r0, _ := args.Get(0).(int64)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) DeleteExpiredSessions(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	args := m.Called(ctx, expiredBefore)
+	r0, _ := args.Get(0).(int64)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	args := m.Called(ctx, expiredBefore)
+	r0, _ := args.Get(0).(int64)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) ListSessions(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Session, error) {
+	args := m.Called(ctx, userID, beforeTime, beforeID, limit)
+	r0, _ := args.Get(0).([]entity.Session)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) RenameSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID, name string) error {
+	args := m.Called(ctx, userID, sessionID, name)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) UsernameExists(ctx context.Context, username string) (bool, error) {
+	args := m.Called(ctx, username)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *AuthRepo) LookupUser(ctx context.Context, userID uuid.UUID, login string) (entity.UserAccount, error) {
+	args := m.Called(ctx, userID, login)
+	r0, _ := args.Get(0).(entity.UserAccount)
+	return r0, args.Error(1)
+}
+
+func (m *AuthRepo) GetUserEmail(ctx context.Context, userID uuid.UUID) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *AuthRepo) IsKnownDevice(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string) (bool, bool, error) {
+	args := m.Called(ctx, userID, ip, userAgent)
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+func (m *AuthRepo) RememberDevice(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string, countryCode string) error {
+	args := m.Called(ctx, userID, ip, userAgent, countryCode)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) IsKnownCountry(ctx context.Context, userID uuid.UUID, countryCode string) (bool, error) {
+	args := m.Called(ctx, userID, countryCode)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *AuthRepo) CreateLoginConfirmation(ctx context.Context, userID uuid.UUID, ip netip.Addr, userAgent string, tokenHash []byte, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, ip, userAgent, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *AuthRepo) ConsumeLoginConfirmation(ctx context.Context, tokenHash []byte) (uuid.UUID, netip.Addr, string, error) {
+	args := m.Called(ctx, tokenHash)
+	r0, _ := args.Get(0).(uuid.UUID)
+	r1, _ := args.Get(1).(netip.Addr)
+	return r0, r1, args.String(2), args.Error(3)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"main/pkg/jwt"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// JWTManager is a mock of auth.JWTManager.
+type JWTManager struct {
+	mock.Mock
+}
+
+// NewJWTManager returns a JWTManager whose expectations are asserted when the test ends.
+func NewJWTManager(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *JWTManager {
+	m := &JWTManager{}
+	m.Test(t)
+	t.Cleanup(func() { m.AssertExpectations(t) })
+	return m
+}
+
+func (m *JWTManager) NewAccessToken(userID uuid.UUID, sessionID uuid.UUID, roles []string) (string, error) {
+	args := m.Called(userID, sessionID, roles)
+	return args.String(0), args.Error(1)
+}
+
+func (m *JWTManager) ParseAccessToken(token string) (jwt.AccessToken, error) {
+	args := m.Called(token)
+	r0, _ := args.Get(0).(jwt.AccessToken)
+	return r0, args.Error(1)
+}
@@ -0,0 +1,79 @@
+package blacklist
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BlacklistRepo defines the interface for block storage.
+type BlacklistRepo interface {
+	// Block records the block and ends the follows between the users, blocking twice changes nothing.
+	Block(ctx context.Context, blockerID, blockedID uuid.UUID) error
+
+	// Unblock removes the block, it is a no-op if there is no block.
+	Unblock(ctx context.Context, blockerID, blockedID uuid.UUID) error
+
+	// ListBlocked returns the users the blocker blocked before the given position, newest first.
+	ListBlocked(ctx context.Context, blockerID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.BlockedEntry, error)
+}
+
+// Timelines keeps the cached home timelines.
+type Timelines interface {
+	// Invalidate drops the cached timelines of the users, so they are built again.
+	Invalidate(ctx context.Context, userIDs ...uuid.UUID)
+}
+
+type BlacklistUsecase struct {
+	blacklistRepo BlacklistRepo
+	timelines     Timelines
+}
+
+func NewBlacklistUsecase(blacklistRepo BlacklistRepo, timelines Timelines) *BlacklistUsecase {
+	return &BlacklistUsecase{
+		blacklistRepo: blacklistRepo,
+		timelines:     timelines,
+	}
+}
+
+// Block blocks another user on behalf of the user and ends the follows between them, blocking a blocked user succeeds.
+func (uc *BlacklistUsecase) Block(ctx context.Context, userID, blockedID uuid.UUID) error {
+	if userID == blockedID {
+		return apperror.InvalidArgument("self_block", "you can't block yourself")
+	}
+	if err := uc.blacklistRepo.Block(ctx, userID, blockedID); err != nil {
+		return err
+	}
+	// the follows between the users ended, so their timelines lost each other's posts
+	uc.timelines.Invalidate(ctx, userID, blockedID)
+	return nil
+}
+
+// Unblock removes the user's block of another user, unblocking a user who isn't blocked succeeds.
+func (uc *BlacklistUsecase) Unblock(ctx context.Context, userID, blockedID uuid.UUID) error {
+	return uc.blacklistRepo.Unblock(ctx, userID, blockedID)
+}
+
+// ListBlocked returns a page of the users the user blocked, most recent blocks first. An empty cursor starts
+// from the newest block; nextCursor fetches the following page and is empty on the last one.
+func (uc *BlacklistUsecase) ListBlocked(ctx context.Context, userID uuid.UUID, cursor string, limit int) (entries []entity.BlockedEntry, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one extra entry tells whether there is a next page
+	entries, err = uc.blacklistRepo.ListBlocked(ctx, userID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	entries, nextCursor = pagination.Page(entries, limit, func(e entity.BlockedEntry) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: e.BlockedAt, ID: e.User.ID}
+	})
+	return entries, nextCursor, nil
+}
@@ -0,0 +1,797 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	"main/pkg/media"
+	"main/pkg/pagination"
+	"math"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxMessageLength is the maximum length of a message in characters.
+	maxMessageLength = 2000
+	// maxTitleLength is the maximum length of a group title in characters.
+	maxTitleLength = 100
+	// maxGroupMembers is the maximum number of users a group is created with or added at once, the creator excluded.
+	maxGroupMembers = 100
+	// maxQueryLength is the maximum length of a message search query in characters.
+	maxQueryLength = 200
+	// outboxBatchSize is the maximum number of outbox events published by one relay run.
+	outboxBatchSize = 500
+	// thumbnailSize is the longest side of attachment thumbnails in pixels.
+	thumbnailSize = 320
+)
+
+// ChatRepo defines the interface for chat and message storage.
+type ChatRepo interface {
+	// CreateChat returns the id of the chat between the user and the peer, creating it if there is none.
+	CreateChat(ctx context.Context, userID, peerID uuid.UUID) (uuid.UUID, error)
+
+	// CreateGroup stores a new group chat with the creator as its admin.
+	CreateGroup(ctx context.Context, chat entity.Chat, creatorID uuid.UUID, memberIDs []uuid.UUID) error
+
+	// GetChat returns a chat of the user.
+	GetChat(ctx context.Context, userID, chatID uuid.UUID) (entity.Chat, error)
+
+	// ListChats returns the chats of the user active before the given position, most recently active first.
+	ListChats(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Chat, error)
+
+	// UpdateGroup changes the non-nil fields of a group chat.
+	UpdateGroup(ctx context.Context, chatID uuid.UUID, update entity.GroupUpdate) error
+
+	// GetMember returns the membership of the user in a chat.
+	GetMember(ctx context.Context, chatID, userID uuid.UUID) (entity.ChatMember, error)
+
+	// ListMemberIDs returns the ids of all members of a chat.
+	ListMemberIDs(ctx context.Context, chatID uuid.UUID) ([]uuid.UUID, error)
+
+	// ListMembers returns the members of a chat who joined after the given position, in the order they joined.
+	ListMembers(ctx context.Context, viewerID, chatID uuid.UUID, afterTime time.Time, afterID uuid.UUID, limit int) ([]entity.ChatMember, error)
+
+	// AddMembers adds users to a group chat as regular members.
+	AddMembers(ctx context.Context, chatID uuid.UUID, userIDs []uuid.UUID) error
+
+	// RemoveMember removes a user from a group chat.
+	RemoveMember(ctx context.Context, chatID, userID uuid.UUID) error
+
+	// SetMemberRole changes the role of a member of a group chat.
+	SetMemberRole(ctx context.Context, chatID, userID uuid.UUID, role entity.ChatRole) error
+
+	// JoinChat adds the user to a public group chat.
+	JoinChat(ctx context.Context, chatID, userID uuid.UUID) error
+
+	// SendMessage stores a message with its event and bumps the activity of its chat.
+	SendMessage(ctx context.Context, message entity.Message) error
+
+	// MarkRead moves the user's read position in a chat forward to the message and reports whether it moved.
+	MarkRead(ctx context.Context, userID, chatID, messageID uuid.UUID) (bool, error)
+
+	// ListMessages returns the messages of a chat of the user sent before the given position, newest first.
+	ListMessages(ctx context.Context, userID, chatID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Message, error)
+
+	// SearchMessages returns the messages of the user's chats, or of one of them, matching the query
+	// sent before the given position, newest first.
+	SearchMessages(ctx context.Context, userID, chatID uuid.UUID, query string, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.MessageMatch, error)
+
+	// GetMessage returns a message of a chat of the user.
+	GetMessage(ctx context.Context, userID, chatID, messageID uuid.UUID) (entity.Message, error)
+
+	// EditMessage replaces the content of a message of the sender, marks it edited and stores its event.
+	EditMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID, content string) (entity.Message, error)
+
+	// DeleteMessage deletes a message of the sender for everyone and stores its event, the sender can restore it
+	// until it is purged.
+	DeleteMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID) error
+
+	// RestoreMessage undeletes a message of the sender deleted since the given time and stores its event.
+	RestoreMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID, deletedSince time.Time) error
+
+	// RemoveMessage deletes a message of the sender for everyone, erases it at once and stores its event.
+	// Returns the attachment the message had.
+	RemoveMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID) (*entity.MessageAttachment, error)
+
+	// PurgeDeletedMessages erases the messages deleted before the given time and returns them with the attachments
+	// they had.
+	PurgeDeletedMessages(ctx context.Context, deletedBefore time.Time) ([]entity.Message, error)
+
+	// HideMessage deletes a message of a chat of the user for the user only.
+	HideMessage(ctx context.Context, userID, chatID, messageID uuid.UUID) error
+
+	// RelayOutbox publishes up to limit stored events in the order they happened and reports how many were published.
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, event entity.ChatEvent, recipients []uuid.UUID) error) (int, error)
+}
+
+// Blacklist tells whether a user blocked another one.
+type Blacklist interface {
+	// IsBlocked reports whether the blocker blocked the user.
+	IsBlocked(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error)
+}
+
+// ChatEvents delivers chat events to the connected clients of their recipients.
+type ChatEvents interface {
+	// Publish delivers the event to the clients of the recipients.
+	Publish(ctx context.Context, event entity.ChatEvent, recipients []uuid.UUID) error
+
+	// Subscribe returns the events of the user until cancel is called.
+	Subscribe(userID uuid.UUID) (<-chan entity.ChatEvent, func())
+}
+
+// TypingDebouncer throttles typing indicators.
+type TypingDebouncer interface {
+	// Allow reports whether the typing indicator of the user in the chat should be sent.
+	Allow(ctx context.Context, userID, chatID uuid.UUID) (bool, error)
+}
+
+// MediaStore defines the interface for keeping uploaded media files.
+type MediaStore interface {
+	// Put stores the file under the key and returns the URL it is served from.
+	Put(ctx context.Context, key, contentType string, r io.Reader, size int64) (string, error)
+	// Delete removes the file.
+	Delete(ctx context.Context, key string) error
+}
+
+// Presence keeps track of the users who have a real-time connection open.
+type Presence interface {
+	// Track counts a connection of the user as open until stop is called or ctx is cancelled.
+	Track(ctx context.Context, userID uuid.UUID) (stop func())
+}
+
+// Notifier tells users about what happened to them.
+type Notifier interface {
+	// Notify queues an event for the users it concerns.
+	Notify(ctx context.Context, event entity.NotificationEvent) error
+}
+
+// ContentPolicy checks user content before it's published.
+type ContentPolicy interface {
+	// Check returns customerrors.ErrContentRejected if the text must not be published, otherwise its review flag.
+	Check(ctx context.Context, kind entity.ContentKind, authorID uuid.UUID, text string) (entity.ContentFlag, error)
+	// Flag queues the stored content for review if its flag asks for it.
+	Flag(ctx context.Context, flag entity.ContentFlag, contentID uuid.UUID) error
+}
+
+type ChatUsecase struct {
+	chatRepo  ChatRepo
+	blacklist Blacklist
+	events    ChatEvents
+	typing    TypingDebouncer
+	presence  Presence
+	media     MediaStore
+	mediaCfg  config.MediaConfig
+	notifier  Notifier
+	policy    ContentPolicy
+	// editWindow is how long after sending a message can be edited, non-positive means forever
+	editWindow time.Duration
+	// undeleteWindow is how long a message deleted for everyone can be restored before it is purged
+	undeleteWindow time.Duration
+}
+
+func NewChatUsecase(chatRepo ChatRepo, blacklist Blacklist, events ChatEvents, typing TypingDebouncer, presence Presence, mediaStore MediaStore, mediaCfg config.MediaConfig, notifier Notifier, policy ContentPolicy, editWindow, undeleteWindow time.Duration) *ChatUsecase {
+	return &ChatUsecase{
+		chatRepo:       chatRepo,
+		blacklist:      blacklist,
+		events:         events,
+		typing:         typing,
+		presence:       presence,
+		media:          mediaStore,
+		mediaCfg:       mediaCfg,
+		notifier:       notifier,
+		policy:         policy,
+		editWindow:     editWindow,
+		undeleteWindow: undeleteWindow,
+	}
+}
+
+// CreateChat returns the direct chat between the user and the peer, creating it on the first call.
+// Users who blocked the user or were blocked by them can't be messaged.
+func (uc *ChatUsecase) CreateChat(ctx context.Context, userID, peerID uuid.UUID) (entity.Chat, error) {
+	if userID == peerID {
+		return entity.Chat{}, apperror.InvalidArgument("self_chat", "you can't start a chat with yourself")
+	}
+	blocked, err := uc.blacklist.IsBlocked(ctx, peerID, userID)
+	if err != nil {
+		return entity.Chat{}, err
+	}
+	if blocked {
+		return entity.Chat{}, customerrors.ErrBlockedByUser
+	}
+	if blocked, err = uc.blacklist.IsBlocked(ctx, userID, peerID); err != nil {
+		return entity.Chat{}, err
+	}
+	if blocked {
+		return entity.Chat{}, apperror.FailedPrecondition("user_blocked_by_you", "unblock the user to message them")
+	}
+
+	chatID, err := uc.chatRepo.CreateChat(ctx, userID, peerID)
+	if err != nil {
+		return entity.Chat{}, err
+	}
+	return uc.chatRepo.GetChat(ctx, userID, chatID)
+}
+
+// CreateGroup creates a group chat titled title with the user as its admin and memberIDs as regular members.
+// Users who blocked the user can't be added.
+func (uc *ChatUsecase) CreateGroup(ctx context.Context, userID uuid.UUID, title, avatarURL string, public bool, memberIDs []uuid.UUID) (entity.Chat, error) {
+	title = strings.TrimSpace(title)
+	if err := validateTitle(title); err != nil {
+		return entity.Chat{}, err
+	}
+	memberIDs, err := uc.checkNewMembers(ctx, userID, memberIDs)
+	if err != nil {
+		return entity.Chat{}, err
+	}
+
+	chat := entity.Chat{
+		ID:        uuid.New(),
+		IsGroup:   true,
+		Title:     title,
+		AvatarURL: strings.TrimSpace(avatarURL),
+		Public:    public,
+		CreatedAt: time.Now(),
+	}
+	if err := uc.chatRepo.CreateGroup(ctx, chat, userID, memberIDs); err != nil {
+		return entity.Chat{}, err
+	}
+	return uc.chatRepo.GetChat(ctx, userID, chat.ID)
+}
+
+// GetChat returns one of the user's chats.
+func (uc *ChatUsecase) GetChat(ctx context.Context, userID, chatID uuid.UUID) (entity.Chat, error) {
+	return uc.chatRepo.GetChat(ctx, userID, chatID)
+}
+
+// UpdateGroup changes the title, the avatar or the visibility of a group chat the user is an admin of.
+func (uc *ChatUsecase) UpdateGroup(ctx context.Context, userID, chatID uuid.UUID, update entity.GroupUpdate) (entity.Chat, error) {
+	if update.Title != nil {
+		title := strings.TrimSpace(*update.Title)
+		if err := validateTitle(title); err != nil {
+			return entity.Chat{}, err
+		}
+		update.Title = &title
+	}
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		update.AvatarURL = &avatarURL
+	}
+	if err := uc.requireAdmin(ctx, userID, chatID); err != nil {
+		return entity.Chat{}, err
+	}
+	if err := uc.chatRepo.UpdateGroup(ctx, chatID, update); err != nil {
+		return entity.Chat{}, err
+	}
+	return uc.chatRepo.GetChat(ctx, userID, chatID)
+}
+
+// ListMembers returns a page of the members of one of the user's chats in the order they joined. An empty cursor
+// starts from the first member; nextCursor fetches the following page and is empty on the last one.
+func (uc *ChatUsecase) ListMembers(ctx context.Context, userID, chatID uuid.UUID, cursor string, limit int) (members []entity.ChatMember, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := uc.chatRepo.GetMember(ctx, chatID, userID); err != nil {
+		return nil, "", err
+	}
+
+	// one extra member tells whether there is a next page
+	members, err = uc.chatRepo.ListMembers(ctx, userID, chatID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	members, nextCursor = pagination.Page(members, limit, func(m entity.ChatMember) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: m.JoinedAt, ID: m.User.ID}
+	})
+	return members, nextCursor, nil
+}
+
+// AddMembers adds users to a group chat the user is an admin of, users who are already members keep their role.
+// Users who blocked the user can't be added.
+func (uc *ChatUsecase) AddMembers(ctx context.Context, userID, chatID uuid.UUID, memberIDs []uuid.UUID) error {
+	memberIDs, err := uc.checkNewMembers(ctx, userID, memberIDs)
+	if err != nil {
+		return err
+	}
+	if len(memberIDs) == 0 {
+		return apperror.InvalidArgument("no_users", "no users to add")
+	}
+	if err := uc.requireAdmin(ctx, userID, chatID); err != nil {
+		return err
+	}
+	return uc.chatRepo.AddMembers(ctx, chatID, memberIDs)
+}
+
+// RemoveMember removes a member from a group chat the user is an admin of, removing the user themselves leaves the group.
+func (uc *ChatUsecase) RemoveMember(ctx context.Context, userID, chatID, memberID uuid.UUID) error {
+	if memberID == userID {
+		return uc.LeaveChat(ctx, userID, chatID)
+	}
+	if err := uc.requireAdmin(ctx, userID, chatID); err != nil {
+		return err
+	}
+	return uc.chatRepo.RemoveMember(ctx, chatID, memberID)
+}
+
+// SetMemberRole makes a member of a group chat the user is an admin of an admin or a regular member.
+func (uc *ChatUsecase) SetMemberRole(ctx context.Context, userID, chatID, memberID uuid.UUID, role entity.ChatRole) error {
+	if !role.Valid() {
+		return apperror.InvalidArgument("invalid_role", "role must be member or admin")
+	}
+	if err := uc.requireAdmin(ctx, userID, chatID); err != nil {
+		return err
+	}
+	return uc.chatRepo.SetMemberRole(ctx, chatID, memberID, role)
+}
+
+// JoinChat makes the user a member of a public group chat, joining a group the user is a member of succeeds.
+func (uc *ChatUsecase) JoinChat(ctx context.Context, userID, chatID uuid.UUID) error {
+	return uc.chatRepo.JoinChat(ctx, chatID, userID)
+}
+
+// LeaveChat removes the user from a group chat. If the last admin leaves, the longest standing member becomes an admin.
+func (uc *ChatUsecase) LeaveChat(ctx context.Context, userID, chatID uuid.UUID) error {
+	return uc.chatRepo.RemoveMember(ctx, chatID, userID)
+}
+
+// requireAdmin returns customerrors.ErrNotFound if the user isn't a member of the chat
+// and customerrors.ErrNotChatAdmin if they aren't an admin of it.
+func (uc *ChatUsecase) requireAdmin(ctx context.Context, userID, chatID uuid.UUID) error {
+	member, err := uc.chatRepo.GetMember(ctx, chatID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role != entity.ChatRoleAdmin {
+		return customerrors.ErrNotChatAdmin
+	}
+	return nil
+}
+
+// checkNewMembers drops duplicates and the user from memberIDs and rejects users who blocked the user.
+func (uc *ChatUsecase) checkNewMembers(ctx context.Context, userID uuid.UUID, memberIDs []uuid.UUID) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]struct{}, len(memberIDs))
+	unique := make([]uuid.UUID, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if _, ok := seen[id]; ok || id == userID {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	if len(unique) > maxGroupMembers {
+		return nil, apperror.InvalidArgument("too_many_members", fmt.Sprintf("at most %d users can be added at once", maxGroupMembers))
+	}
+	for _, id := range unique {
+		blocked, err := uc.blacklist.IsBlocked(ctx, id, userID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, customerrors.ErrBlockedByUser
+		}
+	}
+	return unique, nil
+}
+
+// ListChats returns a page of the user's chats, most recently active first. An empty cursor starts from the most
+// recent chat; nextCursor fetches the following page and is empty on the last one.
+func (uc *ChatUsecase) ListChats(ctx context.Context, userID uuid.UUID, cursor string, limit int) (chats []entity.Chat, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one extra chat tells whether there is a next page
+	chats, err = uc.chatRepo.ListChats(ctx, userID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	chats, nextCursor = pagination.Page(chats, limit, func(c entity.Chat) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: c.LastActivityAt, ID: c.ID}
+	})
+	return chats, nextCursor, nil
+}
+
+// SendMessage sends a message to one of the user's chats. The message is rejected with customerrors.ErrBlockedByUser
+// if the other participant blocked the user and with customerrors.ErrContentRejected if it breaks the content policy.
+// Messages are private, so the policy can't shadow-limit them, limited messages are only queued for review.
+func (uc *ChatUsecase) SendMessage(ctx context.Context, userID, chatID uuid.UUID, content string) (entity.Message, error) {
+	content, err := validateMessage(content, false)
+	if err != nil {
+		return entity.Message{}, err
+	}
+	flag, err := uc.policy.Check(ctx, entity.ContentMessage, userID, content)
+	if err != nil {
+		return entity.Message{}, err
+	}
+
+	message := entity.Message{
+		ID:        uuid.New(),
+		ChatID:    chatID,
+		SenderID:  userID,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	if err := uc.chatRepo.SendMessage(ctx, message); err != nil {
+		return entity.Message{}, err
+	}
+	_ = uc.policy.Flag(ctx, flag, message.ID)
+	uc.relayOutbox(ctx)
+	uc.notifyMessage(ctx, message)
+	return message, nil
+}
+
+// EditMessage replaces the content of one of the user's messages and pushes the edited message to the members.
+// Messages can be edited within the edit window after sending, deleted messages can't be edited.
+func (uc *ChatUsecase) EditMessage(ctx context.Context, userID, chatID, messageID uuid.UUID, content string) (entity.Message, error) {
+	message, err := uc.chatRepo.GetMessage(ctx, userID, chatID, messageID)
+	if err != nil {
+		return entity.Message{}, err
+	}
+	if message.Deleted {
+		return entity.Message{}, customerrors.ErrNotFound
+	}
+	// the caption of a media message may be removed
+	if content, err = validateMessage(content, message.Attachment != nil); err != nil {
+		return entity.Message{}, err
+	}
+	if message.SenderID != userID {
+		return entity.Message{}, apperror.PermissionDenied("not_message_sender", "you can only edit your own messages")
+	}
+	if uc.editWindow > 0 && time.Since(message.CreatedAt) > uc.editWindow {
+		return entity.Message{}, apperror.FailedPrecondition("edit_window_expired", fmt.Sprintf("messages can only be edited within %s of sending", uc.editWindow))
+	}
+
+	message, err = uc.chatRepo.EditMessage(ctx, userID, chatID, messageID, content)
+	if err != nil {
+		return entity.Message{}, err
+	}
+	uc.relayOutbox(ctx)
+	return message, nil
+}
+
+// DeleteMessage deletes a message of one of the user's chats. Deleted for everyone, the message is replaced with
+// a tombstone for all members and only its sender may do that, they can restore it within the undelete window;
+// otherwise it's only hidden from the user. The members the message is deleted for are told about it.
+func (uc *ChatUsecase) DeleteMessage(ctx context.Context, userID, chatID, messageID uuid.UUID, forEveryone bool) error {
+	if !forEveryone {
+		if err := uc.chatRepo.HideMessage(ctx, userID, chatID, messageID); err != nil {
+			return err
+		}
+		// only the user's other devices need to drop the message
+		_ = uc.events.Publish(ctx, entity.ChatEvent{
+			Type:       entity.ChatEventMessageDeleted,
+			ChatID:     chatID,
+			UserID:     userID,
+			MessageID:  &messageID,
+			OccurredAt: time.Now(),
+		}, []uuid.UUID{userID})
+		return nil
+	}
+
+	message, err := uc.chatRepo.GetMessage(ctx, userID, chatID, messageID)
+	if err != nil {
+		return err
+	}
+	if message.SenderID != userID {
+		return apperror.PermissionDenied("not_message_sender", "you can only delete your own messages for everyone")
+	}
+	// the attachment is kept for a restore and deleted when the message is purged
+	if err := uc.chatRepo.DeleteMessage(ctx, userID, chatID, messageID); err != nil {
+		return err
+	}
+	uc.relayOutbox(ctx)
+	return nil
+}
+
+// RestoreMessage undeletes a message the user sent and deleted for everyone within the undelete window,
+// the members are told about it.
+func (uc *ChatUsecase) RestoreMessage(ctx context.Context, userID, chatID, messageID uuid.UUID) error {
+	if err := uc.chatRepo.RestoreMessage(ctx, userID, chatID, messageID, time.Now().Add(-uc.undeleteWindow)); err != nil {
+		return err
+	}
+	uc.relayOutbox(ctx)
+	return nil
+}
+
+// RemoveMessage deletes a message for everyone and erases it with its attachment at once, e.g. when it's taken down,
+// so its sender can't restore it.
+func (uc *ChatUsecase) RemoveMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID) error {
+	attachment, err := uc.chatRepo.RemoveMessage(ctx, senderID, chatID, messageID)
+	if err != nil {
+		return err
+	}
+	uc.relayOutbox(ctx)
+	if attachment != nil {
+		// nothing references the files anymore, a leftover file is only wasted space
+		uc.deleteAttachment(context.WithoutCancel(ctx), *attachment, messageID)
+	}
+	return nil
+}
+
+// PurgeDeletedMessages erases the messages deleted for everyone longer than the undelete window ago, with their
+// attachments, and returns how many were purged. It is run by a scheduled job.
+func (uc *ChatUsecase) PurgeDeletedMessages(ctx context.Context) (int, error) {
+	purged, err := uc.chatRepo.PurgeDeletedMessages(ctx, time.Now().Add(-uc.undeleteWindow))
+	if err != nil {
+		return 0, err
+	}
+	for _, message := range purged {
+		if message.Attachment != nil {
+			uc.deleteAttachment(ctx, *message.Attachment, message.ID)
+		}
+	}
+	return len(purged), nil
+}
+
+// notifyMessage tells the other members of the chat about a new message, members who already have an unread
+// notification about the chat from the sender aren't notified again.
+func (uc *ChatUsecase) notifyMessage(ctx context.Context, message entity.Message) {
+	_ = uc.notifier.Notify(ctx, entity.NotificationEvent{Type: entity.NotificationMessage, ActorID: message.SenderID, ChatID: &message.ChatID})
+}
+
+// SendAttachment sends a message with an uploaded media file of the given size in bytes and an optional caption to one
+// of the user's chats. The type of the file is detected from its content and has to be one of the configured
+// attachment types; images get a thumbnail, videos are limited like video posts.
+func (uc *ChatUsecase) SendAttachment(ctx context.Context, userID, chatID uuid.UUID, caption string, file io.ReadSeeker, size int64) (entity.Message, error) {
+	caption, err := validateMessage(caption, true)
+	if err != nil {
+		return entity.Message{}, err
+	}
+	flag, err := uc.policy.Check(ctx, entity.ContentMessage, userID, caption)
+	if err != nil {
+		return entity.Message{}, err
+	}
+	if size > uc.mediaCfg.MaxAttachmentSize {
+		return entity.Message{}, apperror.InvalidArgument("attachment_too_large", fmt.Sprintf("attachment must be at most %d MB", uc.mediaCfg.MaxAttachmentSize>>20))
+	}
+	// checked before anything is uploaded, the chat and the blocks are checked again when the message is stored
+	if _, err := uc.chatRepo.GetMember(ctx, chatID, userID); err != nil {
+		return entity.Message{}, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return entity.Message{}, apperror.InvalidArgument("empty_attachment", "attachment must not be empty")
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	if !slices.Contains(uc.mediaCfg.AttachmentTypes, contentType) {
+		return entity.Message{}, apperror.InvalidArgument("attachment_type_not_allowed", fmt.Sprintf("attachments of type %s are not allowed", contentType))
+	}
+	attachment := entity.MessageAttachment{Type: contentType, Size: size}
+
+	var thumbnail []byte
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return entity.Message{}, err
+		}
+		// images the thumbnailer can't decode are sent without a thumbnail
+		if thumb, width, height, err := media.Thumbnail(file, thumbnailSize); err == nil {
+			thumbnail, attachment.Width, attachment.Height = thumb, width, height
+		}
+	case strings.HasPrefix(contentType, "video/"):
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return entity.Message{}, err
+		}
+		duration, err := media.ProbeDuration(file, size)
+		if err != nil {
+			return entity.Message{}, err
+		}
+		if duration > uc.mediaCfg.MaxVideoDuration {
+			return entity.Message{}, apperror.InvalidArgument("video_too_long", fmt.Sprintf("video must be at most %s long", uc.mediaCfg.MaxVideoDuration))
+		}
+		attachment.Duration = int(math.Ceil(duration.Seconds()))
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return entity.Message{}, err
+	}
+
+	message := entity.Message{
+		ID:         uuid.New(),
+		ChatID:     chatID,
+		SenderID:   userID,
+		Content:    caption,
+		CreatedAt:  time.Now(),
+		Attachment: &attachment,
+	}
+	var ext string
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		ext = exts[0]
+	}
+	if attachment.URL, err = uc.media.Put(ctx, attachmentKey(message.ID, ext), contentType, file, size); err != nil {
+		return entity.Message{}, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	if thumbnail != nil {
+		attachment.ThumbnailURL, err = uc.media.Put(ctx, thumbnailKey(message.ID), "image/jpeg", bytes.NewReader(thumbnail), int64(len(thumbnail)))
+		if err != nil {
+			uc.deleteAttachment(context.WithoutCancel(ctx), attachment, message.ID)
+			return entity.Message{}, fmt.Errorf("failed to store thumbnail: %w", err)
+		}
+	}
+	if err := uc.chatRepo.SendMessage(ctx, message); err != nil {
+		// the message doesn't exist, so nothing references the uploaded files
+		uc.deleteAttachment(context.WithoutCancel(ctx), attachment, message.ID)
+		return entity.Message{}, err
+	}
+	_ = uc.policy.Flag(ctx, flag, message.ID)
+	uc.relayOutbox(ctx)
+	uc.notifyMessage(ctx, message)
+	return message, nil
+}
+
+// deleteAttachment removes the files of the attachment of a message, it is best effort.
+func (uc *ChatUsecase) deleteAttachment(ctx context.Context, attachment entity.MessageAttachment, messageID uuid.UUID) {
+	if attachment.URL != "" {
+		_ = uc.media.Delete(ctx, attachmentKey(messageID, path.Ext(attachment.URL)))
+	}
+	if attachment.ThumbnailURL != "" {
+		_ = uc.media.Delete(ctx, thumbnailKey(messageID))
+	}
+}
+
+func attachmentKey(messageID uuid.UUID, ext string) string {
+	return "messages/" + messageID.String() + "/file" + ext
+}
+
+func thumbnailKey(messageID uuid.UUID) string {
+	return "messages/" + messageID.String() + "/thumbnail.jpg"
+}
+
+// SendTyping tells the other members of one of the user's chats that the user is typing. Typing indicators
+// aren't stored, and repeated ones within the debounce interval are dropped.
+func (uc *ChatUsecase) SendTyping(ctx context.Context, userID, chatID uuid.UUID) error {
+	if _, err := uc.chatRepo.GetMember(ctx, chatID, userID); err != nil {
+		return err
+	}
+	// an indicator too many is better than a lost one, so it's sent if the debouncer fails
+	if allowed, err := uc.typing.Allow(ctx, userID, chatID); err == nil && !allowed {
+		return nil
+	}
+	uc.publishChatEvent(ctx, entity.ChatEvent{Type: entity.ChatEventTyping, ChatID: chatID, UserID: userID}, userID)
+	return nil
+}
+
+// MarkRead marks one of the user's chats read up to the message and tells the members about it, so the other
+// members see the read receipt and the user's other devices clear the unread count. Marking a message older
+// than the last read one succeeds without changes.
+func (uc *ChatUsecase) MarkRead(ctx context.Context, userID, chatID, messageID uuid.UUID) error {
+	advanced, err := uc.chatRepo.MarkRead(ctx, userID, chatID, messageID)
+	if err != nil {
+		return err
+	}
+	if advanced {
+		uc.publishChatEvent(ctx, entity.ChatEvent{Type: entity.ChatEventRead, ChatID: chatID, UserID: userID, MessageID: &messageID}, uuid.Nil)
+	}
+	return nil
+}
+
+// WatchChats returns the events of all chats of the user until cancel is called, meanwhile the user counts as online
+// and isn't sent push notifications.
+func (uc *ChatUsecase) WatchChats(ctx context.Context, userID uuid.UUID) (<-chan entity.ChatEvent, func()) {
+	events, unsubscribe := uc.events.Subscribe(userID)
+	stop := uc.presence.Track(ctx, userID)
+	return events, func() {
+		stop()
+		unsubscribe()
+	}
+}
+
+// RelayOutbox publishes the stored message events to the members' clients and reports how many were published.
+// Message events are stored with the messages, so those the real-time layer couldn't take are retried by the next run.
+func (uc *ChatUsecase) RelayOutbox(ctx context.Context) (int, error) {
+	return uc.chatRepo.RelayOutbox(ctx, outboxBatchSize, uc.events.Publish)
+}
+
+// relayOutbox publishes the events of a change right after it's stored, failures are left to the periodic relay.
+func (uc *ChatUsecase) relayOutbox(ctx context.Context) {
+	_, _ = uc.RelayOutbox(ctx)
+}
+
+// validateMessage returns the content of a message without surrounding whitespace, or an error if it's too long or,
+// unless the message has an attachment, empty.
+func validateMessage(content string, hasAttachment bool) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" && !hasAttachment {
+		return "", apperror.InvalidArgument("empty_message", "message must not be empty")
+	}
+	if utf8.RuneCountInString(content) > maxMessageLength {
+		return "", apperror.InvalidArgument("message_too_long", "message must be at most 2000 characters")
+	}
+	return content, nil
+}
+
+// publishChatEvent delivers the event to the members of its chat except skip, it is best effort since
+// the change itself already happened.
+func (uc *ChatUsecase) publishChatEvent(ctx context.Context, event entity.ChatEvent, skip uuid.UUID) {
+	memberIDs, err := uc.chatRepo.ListMemberIDs(ctx, event.ChatID)
+	if err != nil {
+		return
+	}
+	recipients := make([]uuid.UUID, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if id != skip {
+			recipients = append(recipients, id)
+		}
+	}
+	event.OccurredAt = time.Now()
+	_ = uc.events.Publish(ctx, event, recipients)
+}
+
+// ListMessages returns a page of the history of one of the user's chats, newest first. An empty cursor starts from
+// the newest message; nextCursor fetches older messages and is empty on the last page.
+func (uc *ChatUsecase) ListMessages(ctx context.Context, userID, chatID uuid.UUID, cursor string, limit int) (messages []entity.Message, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one extra message tells whether there is a next page
+	messages, err = uc.chatRepo.ListMessages(ctx, userID, chatID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	messages, nextCursor = pagination.Page(messages, limit, func(m entity.Message) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: m.CreatedAt, ID: m.ID}
+	})
+	return messages, nextCursor, nil
+}
+
+// SearchMessages returns a page of the user's messages matching the full-text query, newest first, across all of
+// the user's chats or, unless chatID is uuid.Nil, in one of them. An empty cursor starts from the newest match;
+// nextCursor fetches older ones and is empty on the last page.
+func (uc *ChatUsecase) SearchMessages(ctx context.Context, userID, chatID uuid.UUID, query, cursor string, limit int) (matches []entity.MessageMatch, nextCursor string, err error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxQueryLength {
+		return nil, "", customerrors.ErrInvalidQuery
+	}
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if chatID != uuid.Nil {
+		if _, err := uc.chatRepo.GetMember(ctx, chatID, userID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// one extra match tells whether there is a next page
+	matches, err = uc.chatRepo.SearchMessages(ctx, userID, chatID, query, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	matches, nextCursor = pagination.Page(matches, limit, func(m entity.MessageMatch) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: m.Message.CreatedAt, ID: m.Message.ID}
+	})
+	return matches, nextCursor, nil
+}
+
+func validateTitle(title string) error {
+	if title == "" {
+		return apperror.InvalidArgument("empty_title", "title must not be empty")
+	}
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		return apperror.InvalidArgument("title_too_long", "title must be at most 100 characters")
+	}
+	return nil
+}
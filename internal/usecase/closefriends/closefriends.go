@@ -0,0 +1,89 @@
+package closefriends
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CloseFriendsRepo defines the interface for close friends list storage.
+type CloseFriendsRepo interface {
+	// AddCloseFriend puts the friend on the user's list, adding twice changes nothing.
+	AddCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error
+
+	// RemoveCloseFriend takes the friend off the user's list, it is a no-op if they aren't on it.
+	RemoveCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error
+
+	// ListCloseFriends returns the users on the list added before the given position, newest first.
+	ListCloseFriends(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.CloseFriendEntry, error)
+}
+
+// Blacklist tells whether a user blocked another one.
+type Blacklist interface {
+	// IsBlocked reports whether the blocker blocked the user.
+	IsBlocked(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error)
+}
+
+type CloseFriendsUsecase struct {
+	closeFriendsRepo CloseFriendsRepo
+	blacklist        Blacklist
+}
+
+func NewCloseFriendsUsecase(closeFriendsRepo CloseFriendsRepo, blacklist Blacklist) *CloseFriendsUsecase {
+	return &CloseFriendsUsecase{
+		closeFriendsRepo: closeFriendsRepo,
+		blacklist:        blacklist,
+	}
+}
+
+// AddCloseFriend puts another user on the user's close friends list, so they see the user's close friends posts.
+// Adding a user who is already on the list succeeds, users who blocked the user or were blocked by them can't be added.
+func (uc *CloseFriendsUsecase) AddCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error {
+	if userID == friendID {
+		return apperror.InvalidArgument("self_close_friend", "you can't add yourself to close friends")
+	}
+	blocked, err := uc.blacklist.IsBlocked(ctx, friendID, userID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return customerrors.ErrBlockedByUser
+	}
+	if blocked, err = uc.blacklist.IsBlocked(ctx, userID, friendID); err != nil {
+		return err
+	}
+	if blocked {
+		return apperror.FailedPrecondition("user_blocked_by_you", "unblock the user to add them to close friends")
+	}
+	return uc.closeFriendsRepo.AddCloseFriend(ctx, userID, friendID)
+}
+
+// RemoveCloseFriend takes another user off the user's close friends list, removing a user who isn't on it succeeds.
+func (uc *CloseFriendsUsecase) RemoveCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error {
+	return uc.closeFriendsRepo.RemoveCloseFriend(ctx, userID, friendID)
+}
+
+// ListCloseFriends returns a page of the user's close friends list, most recently added first. An empty cursor starts
+// from the newest entry; nextCursor fetches the following page and is empty on the last one.
+func (uc *CloseFriendsUsecase) ListCloseFriends(ctx context.Context, userID uuid.UUID, cursor string, limit int) (entries []entity.CloseFriendEntry, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one extra entry tells whether there is a next page
+	entries, err = uc.closeFriendsRepo.ListCloseFriends(ctx, userID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	entries, nextCursor = pagination.Page(entries, limit, func(e entity.CloseFriendEntry) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: e.AddedAt, ID: e.User.ID}
+	})
+	return entries, nextCursor, nil
+}
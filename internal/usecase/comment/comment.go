@@ -0,0 +1,215 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/mention"
+	"main/pkg/pagination"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxContentLength is the maximum length of a comment in characters.
+	maxContentLength = 500
+)
+
+// CommentRepo defines the interface for comment storage.
+type CommentRepo interface {
+	// CreateComment stores a new comment and bumps the reply counter of the parent comment.
+	CreateComment(ctx context.Context, comment entity.Comment) error
+
+	// GetComment returns the comment.
+	GetComment(ctx context.Context, id uuid.UUID) (entity.Comment, error)
+
+	// UpdateComment replaces the content of a comment of the user and returns the updated comment.
+	UpdateComment(ctx context.Context, userID, commentID uuid.UUID, content string) (entity.Comment, error)
+
+	// DeleteComment marks a comment of the user deleted with its replies and decrements the counters, they can be
+	// restored until they are purged.
+	DeleteComment(ctx context.Context, userID, commentID uuid.UUID) error
+
+	// RestoreComment undeletes a comment of the user deleted since the given time with the replies deleted with it.
+	RestoreComment(ctx context.Context, userID, commentID uuid.UUID, deletedSince time.Time) error
+
+	// RemoveComment deletes a comment for good with its replies, whether or not they were deleted before.
+	RemoveComment(ctx context.Context, commentID uuid.UUID) error
+
+	// PurgeDeletedComments deletes for good the comments deleted before the given time and returns how many were purged.
+	PurgeDeletedComments(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	// ListComments returns a page of comments selected by the filter.
+	ListComments(ctx context.Context, filter entity.CommentFilter) ([]entity.Comment, error)
+}
+
+// Counters keeps the comment counters of posts.
+type Counters interface {
+	// Add changes the counter of id by delta, failures are repaired by a later reconciliation.
+	Add(ctx context.Context, counter entity.Counter, id uuid.UUID, delta int64)
+}
+
+// Notifier tells users about what happened to them.
+type Notifier interface {
+	// Notify queues an event for the users it concerns.
+	Notify(ctx context.Context, event entity.NotificationEvent) error
+}
+
+// ContentPolicy checks user content before it's published.
+type ContentPolicy interface {
+	// Check returns customerrors.ErrContentRejected if the text must not be published, otherwise its review flag.
+	Check(ctx context.Context, kind entity.ContentKind, authorID uuid.UUID, text string) (entity.ContentFlag, error)
+	// Flag queues the stored content for review if its flag asks for it.
+	Flag(ctx context.Context, flag entity.ContentFlag, contentID uuid.UUID) error
+}
+
+type CommentUsecase struct {
+	commentRepo CommentRepo
+	notifier    Notifier
+	policy      ContentPolicy
+	counters    Counters
+	// undeleteWindow is how long a deleted comment can be restored before it is purged
+	undeleteWindow time.Duration
+}
+
+func NewCommentUsecase(commentRepo CommentRepo, notifier Notifier, policy ContentPolicy, counters Counters, undeleteWindow time.Duration) *CommentUsecase {
+	return &CommentUsecase{
+		commentRepo:    commentRepo,
+		notifier:       notifier,
+		policy:         policy,
+		counters:       counters,
+		undeleteWindow: undeleteWindow,
+	}
+}
+
+// CreateComment comments the post on behalf of the user, a replyTo other than uuid.Nil makes it a reply
+// to that comment of the post. The content must pass the content policy, which may also shadow-limit the comment.
+func (uc *CommentUsecase) CreateComment(ctx context.Context, userID, postID, replyTo uuid.UUID, content string) (entity.Comment, error) {
+	content = strings.TrimSpace(content)
+	if err := validateContent(content); err != nil {
+		return entity.Comment{}, err
+	}
+	flag, err := uc.policy.Check(ctx, entity.ContentComment, userID, content)
+	if err != nil {
+		return entity.Comment{}, err
+	}
+
+	now := time.Now()
+	comment := entity.Comment{
+		ID:        uuid.New(),
+		PostID:    postID,
+		UserID:    userID,
+		Content:   content,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Limited:   flag.Limited(),
+	}
+	if replyTo != uuid.Nil {
+		comment.ReplyTo = &replyTo
+	}
+	if err := uc.commentRepo.CreateComment(ctx, comment); err != nil {
+		return entity.Comment{}, err
+	}
+	uc.counters.Add(ctx, entity.CounterPostComments, postID, 1)
+	_ = uc.policy.Flag(ctx, flag, comment.ID)
+	// nobody hears about a shadow-limited comment
+	if comment.Limited {
+		return comment, nil
+	}
+	// the post's author and the author of the replied comment hear about the comment, mentioned users about the mention
+	_ = uc.notifier.Notify(ctx, entity.NotificationEvent{Type: entity.NotificationComment, ActorID: userID, PostID: &comment.PostID, CommentID: &comment.ID})
+	if mentions := mention.Extract(content); len(mentions) > 0 {
+		_ = uc.notifier.Notify(ctx, entity.NotificationEvent{Type: entity.NotificationMention, ActorID: userID, PostID: &comment.PostID, CommentID: &comment.ID, Mentions: mentions})
+	}
+	return comment, nil
+}
+
+// GetComment returns the comment.
+func (uc *CommentUsecase) GetComment(ctx context.Context, commentID uuid.UUID) (entity.Comment, error) {
+	return uc.commentRepo.GetComment(ctx, commentID)
+}
+
+// UpdateComment edits the content of one of the user's comments.
+func (uc *CommentUsecase) UpdateComment(ctx context.Context, userID, commentID uuid.UUID, content string) (entity.Comment, error) {
+	content = strings.TrimSpace(content)
+	if err := validateContent(content); err != nil {
+		return entity.Comment{}, err
+	}
+	return uc.commentRepo.UpdateComment(ctx, userID, commentID, content)
+}
+
+// DeleteComment deletes one of the user's comments together with the replies to it. The user can restore it within
+// the undelete window, then it is purged.
+func (uc *CommentUsecase) DeleteComment(ctx context.Context, userID, commentID uuid.UUID) error {
+	return uc.commentRepo.DeleteComment(ctx, userID, commentID)
+}
+
+// RestoreComment undeletes one of the user's comments deleted within the undelete window, together with the replies
+// deleted with it. The post and the parent comment must not be deleted.
+func (uc *CommentUsecase) RestoreComment(ctx context.Context, userID, commentID uuid.UUID) error {
+	return uc.commentRepo.RestoreComment(ctx, userID, commentID, time.Now().Add(-uc.undeleteWindow))
+}
+
+// RemoveComment deletes a comment with its replies for good, e.g. when it's taken down, so its author can't restore it.
+func (uc *CommentUsecase) RemoveComment(ctx context.Context, commentID uuid.UUID) error {
+	return uc.commentRepo.RemoveComment(ctx, commentID)
+}
+
+// PurgeDeletedComments deletes for good the comments deleted longer than the undelete window ago and returns how
+// many were purged. It is run by a scheduled job.
+func (uc *CommentUsecase) PurgeDeletedComments(ctx context.Context) (int64, error) {
+	return uc.commentRepo.PurgeDeletedComments(ctx, time.Now().Add(-uc.undeleteWindow))
+}
+
+// ListComments returns a page of top level comments of the post, or of replies to replyTo if it isn't uuid.Nil.
+// Shadow-limited comments are only listed for their author, viewerID is uuid.Nil for anonymous viewers.
+// An empty sort lists the newest comments first. An empty cursor starts from the first comment;
+// nextCursor fetches the following page in the same order and is empty on the last one.
+func (uc *CommentUsecase) ListComments(ctx context.Context, viewerID, postID, replyTo uuid.UUID, sort entity.CommentSort, cursor string, limit int) (comments []entity.Comment, nextCursor string, err error) {
+	if sort == "" {
+		sort = entity.CommentSortNewest
+	}
+	if !sort.Valid() {
+		return nil, "", apperror.InvalidArgument("invalid_sort", fmt.Sprintf("unknown sort %q", sort))
+	}
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := entity.CommentFilter{
+		ViewerID:     viewerID,
+		PostID:       postID,
+		ReplyTo:      replyTo,
+		Sort:         sort,
+		AfterReplies: int(after.Score),
+		AfterTime:    after.CreatedAt,
+		AfterID:      after.ID,
+		Limit:        limit + 1, // one extra comment tells whether there is a next page
+	}
+
+	comments, err = uc.commentRepo.ListComments(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	// the reply count is only compared in the top order, it is kept in every cursor so the cursor doesn't depend on the order
+	comments, nextCursor = pagination.Page(comments, limit, func(c entity.Comment) pagination.Cursor {
+		return pagination.Cursor{Score: float64(c.RepliesCount), CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	return comments, nextCursor, nil
+}
+
+func validateContent(content string) error {
+	if content == "" {
+		return apperror.InvalidArgument("empty_comment", "comment must not be empty")
+	}
+	if utf8.RuneCountInString(content) > maxContentLength {
+		return apperror.InvalidArgument("comment_too_long", "comment must be at most 500 characters")
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package counter
+
+import (
+	"context"
+	"log/slog"
+	"main/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// CounterRepo defines the interface for the denormalized counters of the source of truth.
+type CounterRepo interface {
+	// AddDeltas adds the deltas to the counter of the rows they are keyed by, counters don't go below zero.
+	AddDeltas(ctx context.Context, counter entity.Counter, deltas map[uuid.UUID]int64) error
+
+	// ReconcilePostCounters recounts likes, reposts, quotes and comments of posts whose counters drifted.
+	ReconcilePostCounters(ctx context.Context) (int64, error)
+
+	// ReconcileFollowCounters recounts followers and followings of profiles whose counters drifted.
+	ReconcileFollowCounters(ctx context.Context) (int64, error)
+}
+
+// PendingStore defines the interface for keeping increments until they are written, shared by every instance.
+type PendingStore interface {
+	// Add adds delta to the pending delta of the counter of id.
+	Add(ctx context.Context, counter entity.Counter, id uuid.UUID, delta int64) error
+
+	// AddAll adds the deltas back, e.g. when they couldn't be written.
+	AddAll(ctx context.Context, counter entity.Counter, deltas map[uuid.UUID]int64) error
+
+	// Take removes and returns the pending deltas of the counter.
+	Take(ctx context.Context, counter entity.Counter) (map[uuid.UUID]int64, error)
+}
+
+// CounterUsecase keeps the like, repost, comment, follow and link click counters. Without a pending store
+// increments are written at once, with one they are collected and written in batches by Flush, so hot rows
+// aren't updated on every like. Either way the counters may drift, e.g. when an instance dies between a like
+// and its increment. ReconcilePosts and ReconcileFollows repair the post and follow counters from the source
+// tables, link clicks have no source to recount them from, so they are best-effort and a lost click stays lost.
+type CounterUsecase struct {
+	counterRepo CounterRepo
+	pending     PendingStore
+	// batchSize is how many rows of a counter one statement of Flush updates at most
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewCounterUsecase(counterRepo CounterRepo, pending PendingStore, batchSize int, logger *slog.Logger) *CounterUsecase {
+	return &CounterUsecase{
+		counterRepo: counterRepo,
+		pending:     pending,
+		batchSize:   batchSize,
+		logger:      logger,
+	}
+}
+
+// Add changes the counter of id by delta. The change the counter follows is already stored, so a failing write
+// is logged but never fails it; post and follow counters are repaired by the next reconciliation.
+func (uc *CounterUsecase) Add(ctx context.Context, counter entity.Counter, id uuid.UUID, delta int64) {
+	// the request may already be cancelled (e.g. client disconnected), the increment should be written anyway
+	ctx = context.WithoutCancel(ctx)
+	if uc.pending != nil {
+		err := uc.pending.Add(ctx, counter, id, delta)
+		if err == nil {
+			return
+		}
+		// e.g. Redis is unavailable, the increment is written at once instead
+		uc.logger.Warn("Failed to queue counter increment", "counter", counter, "error", err)
+	}
+	if err := uc.counterRepo.AddDeltas(ctx, counter, map[uuid.UUID]int64{id: delta}); err != nil {
+		uc.logger.Error("Failed to write counter increment", "counter", counter, "id", id, "error", err)
+	}
+}
+
+// Flush writes the pending increments of every counter and returns how many rows were updated. It is run by
+// every instance, each flush takes different increments. Increments that can't be written are put back for the
+// next flush.
+func (uc *CounterUsecase) Flush(ctx context.Context) (int64, error) {
+	if uc.pending == nil {
+		return 0, nil
+	}
+	var flushed int64
+	for _, counter := range entity.Counters {
+		deltas, err := uc.pending.Take(ctx, counter)
+		if err != nil {
+			return flushed, err
+		}
+		batches := split(deltas, uc.batchSize)
+		for i, batch := range batches {
+			if err := uc.counterRepo.AddDeltas(ctx, counter, batch); err != nil {
+				uc.putBack(ctx, counter, batches[i:])
+				return flushed, err
+			}
+			flushed += int64(len(batch))
+		}
+	}
+	return flushed, nil
+}
+
+// putBack returns the batches to the pending store. Increments that can't be put back are lost until the next
+// reconciliation, or for good for link clicks.
+func (uc *CounterUsecase) putBack(ctx context.Context, counter entity.Counter, batches []map[uuid.UUID]int64) {
+	ctx = context.WithoutCancel(ctx)
+	for _, batch := range batches {
+		if err := uc.pending.AddAll(ctx, counter, batch); err != nil {
+			uc.logger.Error("Failed to put back counter increments", "counter", counter, "count", len(batch), "error", err)
+		}
+	}
+}
+
+// ReconcilePosts repairs the post counters that drifted from the source tables and returns how many posts were
+// fixed. Pending increments are flushed first, so they aren't counted twice; increments made while recounting
+// may still be, until the next reconciliation.
+func (uc *CounterUsecase) ReconcilePosts(ctx context.Context) (int64, error) {
+	if _, err := uc.Flush(ctx); err != nil {
+		return 0, err
+	}
+	return uc.counterRepo.ReconcilePostCounters(ctx)
+}
+
+// ReconcileFollows repairs the follower and following counters that drifted from the follows and returns how many
+// profiles were fixed. Pending increments are flushed first, like for ReconcilePosts.
+func (uc *CounterUsecase) ReconcileFollows(ctx context.Context) (int64, error) {
+	if _, err := uc.Flush(ctx); err != nil {
+		return 0, err
+	}
+	return uc.counterRepo.ReconcileFollowCounters(ctx)
+}
+
+// split splits the deltas into maps of at most size entries, a size below one keeps them together.
+func split(deltas map[uuid.UUID]int64, size int) []map[uuid.UUID]int64 {
+	if len(deltas) == 0 {
+		return nil
+	}
+	if size < 1 || len(deltas) <= size {
+		return []map[uuid.UUID]int64{deltas}
+	}
+	var batches []map[uuid.UUID]int64
+	batch := make(map[uuid.UUID]int64, size)
+	for id, delta := range deltas {
+		batch[id] = delta
+		if len(batch) == size {
+			batches = append(batches, batch)
+			batch = make(map[uuid.UUID]int64, size)
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
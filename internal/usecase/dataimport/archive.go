@@ -0,0 +1,262 @@
+package dataimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"main/domain/entity"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// errUnknownArchive is returned for a ZIP archive without any of the files of a known export.
+var errUnknownArchive = errors.New("not a Twitter or Threads export")
+
+// parseArchive reads the follows and posts of an uploaded archive: a ZIP exported from Twitter/X or Threads, or
+// a JSON document in the platform neutral format. No file in it is read past limit bytes.
+func parseArchive(r io.ReaderAt, size, limit int64) (entity.ImportArchive, error) {
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil && !errors.Is(err, io.EOF) {
+		return entity.ImportArchive{}, err
+	}
+	if !bytes.Equal(magic, []byte("PK\x03\x04")) {
+		return parseJSON(io.LimitReader(io.NewSectionReader(r, 0, size), limit))
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return entity.ImportArchive{}, err
+	}
+	// Twitter archives keep their data in data/*.js, Threads exports in JSON files somewhere under a threads
+	// directory; the paths changed between versions of both, so files are found by name
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+		switch {
+		case name == "tweets.js" || name == "tweet.js":
+			files["tweets"] = f
+		case name == "threads_and_replies.json":
+			files["threads"] = f
+		case name == "following.json" && strings.Contains(f.Name, "threads"):
+			files["following"] = f
+		}
+	}
+
+	archive := entity.ImportArchive{Source: entity.DataImportThreads}
+	if f, ok := files["tweets"]; ok {
+		archive.Source = entity.DataImportTwitter
+		archive.Posts, err = readFile(f, limit, parseTweets)
+		return archive, err
+	}
+	f, ok := files["threads"]
+	if !ok {
+		return archive, errUnknownArchive
+	}
+	if archive.Posts, err = readFile(f, limit, parseThreads); err != nil {
+		return archive, err
+	}
+	if f, ok := files["following"]; ok {
+		archive.Follows, err = readFile(f, limit, parseThreadsFollowing)
+	}
+	return archive, err
+}
+
+// readFile parses a file of a ZIP archive.
+func readFile[T any](f *zip.File, limit int64, parse func([]byte) ([]T, error)) ([]T, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is too large", path.Base(f.Name))
+	}
+	entries, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path.Base(f.Name), err)
+	}
+	return entries, nil
+}
+
+// parseJSON reads an archive in the platform neutral format:
+//
+//	{"following": [{"username": "...", "email": "..."}], "posts": [{"text": "...", "created_at": "RFC 3339 time"}]}
+func parseJSON(r io.Reader) (entity.ImportArchive, error) {
+	var doc struct {
+		Following []entity.ImportFollow `json:"following"`
+		Posts     []entity.ImportPost   `json:"posts"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return entity.ImportArchive{}, err
+	}
+	archive := entity.ImportArchive{Source: entity.DataImportJSON}
+	for _, f := range doc.Following {
+		if f.Username != "" || f.Email != "" {
+			archive.Follows = append(archive.Follows, f)
+		}
+	}
+	for _, p := range doc.Posts {
+		if strings.TrimSpace(p.Text) != "" && !p.CreatedAt.IsZero() {
+			archive.Posts = append(archive.Posts, p)
+		}
+	}
+	return archive, nil
+}
+
+// parseTweets reads the tweets of a Twitter archive, a JavaScript file assigning them to a global. Retweets and
+// replies are left out, they make no sense without the tweets they refer to. Twitter archives only name
+// followed accounts by their numeric ID, so follows can't be imported from them.
+func parseTweets(data []byte) ([]entity.ImportPost, error) {
+	var tweets []struct {
+		Tweet struct {
+			FullText          string `json:"full_text"`
+			CreatedAt         string `json:"created_at"`
+			InReplyToStatusID string `json:"in_reply_to_status_id_str"`
+			Entities          struct {
+				URLs []struct {
+					URL         string `json:"url"`
+					ExpandedURL string `json:"expanded_url"`
+				} `json:"urls"`
+			} `json:"entities"`
+		} `json:"tweet"`
+	}
+	if err := json.Unmarshal(stripAssignment(data), &tweets); err != nil {
+		return nil, err
+	}
+	var posts []entity.ImportPost
+	for _, t := range tweets {
+		tweet := t.Tweet
+		if tweet.InReplyToStatusID != "" || strings.HasPrefix(tweet.FullText, "RT @") {
+			continue
+		}
+		createdAt, err := time.Parse(time.RubyDate, tweet.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tweet time %q", tweet.CreatedAt)
+		}
+		// links are shortened to t.co, which only redirects for links on Twitter
+		text := tweet.FullText
+		for _, u := range tweet.Entities.URLs {
+			if u.URL != "" && u.ExpandedURL != "" {
+				text = strings.ReplaceAll(text, u.URL, u.ExpandedURL)
+			}
+		}
+		text = strings.TrimSpace(html.UnescapeString(text))
+		if text != "" {
+			posts = append(posts, entity.ImportPost{Text: text, CreatedAt: createdAt})
+		}
+	}
+	return posts, nil
+}
+
+// stripAssignment returns the JSON value of a Twitter archive file, "window.YTD.tweets.part0 = [...]".
+func stripAssignment(data []byte) []byte {
+	if i := bytes.IndexByte(data, '='); i >= 0 && bytes.HasPrefix(bytes.TrimSpace(data), []byte("window.")) {
+		return data[i+1:]
+	}
+	return data
+}
+
+// threadsMedia is an entry of a Threads export, the text of a post is in its title or the one of its media.
+type threadsMedia struct {
+	Title             string `json:"title"`
+	CreationTimestamp int64  `json:"creation_timestamp"`
+}
+
+// parseThreads reads the posts of a Threads export.
+func parseThreads(data []byte) ([]entity.ImportPost, error) {
+	var doc struct {
+		Posts []struct {
+			threadsMedia
+			Media []threadsMedia `json:"media"`
+		} `json:"text_post_app_text_posts"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var posts []entity.ImportPost
+	for _, p := range doc.Posts {
+		entry := p.threadsMedia
+		if len(p.Media) > 0 {
+			if entry.Title == "" {
+				entry.Title = p.Media[0].Title
+			}
+			if entry.CreationTimestamp == 0 {
+				entry.CreationTimestamp = p.Media[0].CreationTimestamp
+			}
+		}
+		text := strings.TrimSpace(fixEncoding(entry.Title))
+		if text == "" || entry.CreationTimestamp == 0 {
+			continue
+		}
+		posts = append(posts, entity.ImportPost{Text: text, CreatedAt: time.Unix(entry.CreationTimestamp, 0).UTC()})
+	}
+	return posts, nil
+}
+
+// parseThreadsFollowing reads the followed accounts of a Threads export. They are listed under a key that
+// differs between versions of the export, so any list of relationships is taken.
+func parseThreadsFollowing(data []byte) ([]entity.ImportFollow, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var follows []entity.ImportFollow
+	for key, raw := range doc {
+		if !strings.Contains(key, "following") {
+			continue
+		}
+		var relationships []struct {
+			Title          string `json:"title"`
+			StringListData []struct {
+				Href  string `json:"href"`
+				Value string `json:"value"`
+			} `json:"string_list_data"`
+		}
+		if err := json.Unmarshal(raw, &relationships); err != nil {
+			return nil, err
+		}
+		for _, r := range relationships {
+			username := r.Title
+			for _, d := range r.StringListData {
+				if username == "" {
+					username = d.Value
+				}
+				// the profile link is the only place some versions name the account
+				if u, err := url.Parse(d.Href); username == "" && err == nil {
+					username = strings.TrimPrefix(path.Base(strings.TrimSuffix(u.Path, "/")), "@")
+				}
+			}
+			if username = fixEncoding(username); username != "" && username != "." && username != "/" {
+				follows = append(follows, entity.ImportFollow{Username: username})
+			}
+		}
+	}
+	return follows, nil
+}
+
+// fixEncoding repairs text from Meta's exports, which write each byte of UTF-8 text as its own character.
+// Text that doesn't look like it was mangled that way is returned as it is.
+func fixEncoding(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			return s
+		}
+		b = append(b, byte(r))
+	}
+	if !utf8.Valid(b) {
+		return s
+	}
+	return string(b)
+}
@@ -0,0 +1,257 @@
+package dataimport
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// claimBatchSize is the number of imports claimed at once, they are applied one after another.
+const claimBatchSize = 1
+
+// ImportRepo defines the interface for data imports.
+type ImportRepo interface {
+	// CreateImport queues an import of the archive, customerrors.ErrImportInProgress if one is already queued.
+	CreateImport(ctx context.Context, importID, userID uuid.UUID, archive entity.ImportArchive) (entity.DataImport, error)
+
+	// GetImport returns a data import of the user.
+	GetImport(ctx context.Context, userID, importID uuid.UUID) (entity.DataImport, error)
+
+	// ClaimImports marks up to limit pending imports, or imports without progress since staleBefore, as processing.
+	ClaimImports(ctx context.Context, staleBefore time.Time, limit int) ([]entity.DataImport, error)
+
+	// GetArchive returns the follows and posts of an import that is still being applied.
+	GetArchive(ctx context.Context, importID uuid.UUID) (entity.ImportArchive, error)
+
+	// SaveProgress records how far the import got.
+	SaveProgress(ctx context.Context, importID uuid.UUID, follows, posts entity.ImportProgress) error
+
+	// CompleteImport marks the import completed and drops its archive.
+	CompleteImport(ctx context.Context, importID uuid.UUID) error
+
+	// FailImport marks the import failed with the reason and drops its archive.
+	FailImport(ctx context.Context, importID uuid.UUID, reason string) error
+
+	// FindUsers returns the IDs of the users with any of the usernames or emails, keyed by lowercase username and email.
+	FindUsers(ctx context.Context, usernames, emails []string) (map[string]uuid.UUID, error)
+}
+
+// Follows follows accounts on behalf of users.
+type Follows interface {
+	// Follow makes the user follow another account, or asks to if it is private, following it again succeeds.
+	Follow(ctx context.Context, userID, followeeID uuid.UUID) (entity.FollowState, error)
+}
+
+// Posts publishes posts brought over from other platforms.
+type Posts interface {
+	// ImportPost publishes a post of the user at the time it was originally published and reports whether it
+	// wasn't imported before.
+	ImportPost(ctx context.Context, userID, postID uuid.UUID, description string, createdAt time.Time) (bool, error)
+}
+
+// FeatureFlags tells which gradually rolled out features are on for a user.
+type FeatureFlags interface {
+	Enabled(ctx context.Context, name string, userID uuid.UUID) bool
+}
+
+type ImportUsecase struct {
+	repo    ImportRepo
+	follows Follows
+	posts   Posts
+	flags   FeatureFlags
+	cfg     config.ImportConfig
+	logger  *slog.Logger
+}
+
+func NewImportUsecase(repo ImportRepo, follows Follows, posts Posts, flags FeatureFlags, cfg config.ImportConfig, logger *slog.Logger) *ImportUsecase {
+	return &ImportUsecase{
+		repo:    repo,
+		follows: follows,
+		posts:   posts,
+		flags:   flags,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// StartImport reads an archive the user exported from another platform and queues the import of its follows and
+// posts, a background job applies them and the progress can be polled with GetImport. Accounts the user followed
+// are matched to users by username or email; posts keep the time they were published at. One import of a user is
+// applied at a time. Returns customerrors.ErrFeatureDisabled unless the data_import feature is on for the user.
+func (uc *ImportUsecase) StartImport(ctx context.Context, userID uuid.UUID, r io.ReaderAt, size int64) (entity.DataImport, error) {
+	if !uc.flags.Enabled(ctx, entity.FeatureDataImport, userID) {
+		return entity.DataImport{}, customerrors.ErrFeatureDisabled
+	}
+	if size > uc.cfg.MaxArchiveSize {
+		return entity.DataImport{}, apperror.RequestTooLarge("archive_too_large", fmt.Sprintf("archive must be at most %d MB", uc.cfg.MaxArchiveSize>>20))
+	}
+	archive, err := parseArchive(r, size, uc.cfg.MaxArchiveSize)
+	if err != nil {
+		return entity.DataImport{}, apperror.InvalidArgument("invalid_archive", "archive can't be read: "+err.Error())
+	}
+	archive.Follows = uniqueFollows(archive.Follows)
+	if len(archive.Follows) > uc.cfg.MaxFollows {
+		archive.Follows = archive.Follows[:uc.cfg.MaxFollows]
+	}
+	// oldest first, so the posts appear in the order they were published; the newest are kept if there are too many
+	slices.SortStableFunc(archive.Posts, func(a, b entity.ImportPost) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	if len(archive.Posts) > uc.cfg.MaxPosts {
+		archive.Posts = archive.Posts[len(archive.Posts)-uc.cfg.MaxPosts:]
+	}
+	if len(archive.Follows) == 0 && len(archive.Posts) == 0 {
+		return entity.DataImport{}, apperror.InvalidArgument("empty_archive", "archive has no follows or posts to import")
+	}
+	return uc.repo.CreateImport(ctx, uuid.New(), userID, archive)
+}
+
+// uniqueFollows leaves out the accounts listed more than once.
+func uniqueFollows(follows []entity.ImportFollow) []entity.ImportFollow {
+	seen := make(map[entity.ImportFollow]bool, len(follows))
+	unique := follows[:0]
+	for _, f := range follows {
+		key := entity.ImportFollow{Username: strings.ToLower(f.Username), Email: strings.ToLower(f.Email)}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, f)
+		}
+	}
+	return unique
+}
+
+// GetImport returns a data import of the user with its progress.
+func (uc *ImportUsecase) GetImport(ctx context.Context, userID, importID uuid.UUID) (entity.DataImport, error) {
+	return uc.repo.GetImport(ctx, userID, importID)
+}
+
+// ProcessImports applies the queued imports until none are left and returns how many were completed. A failed
+// import is marked failed, so the user can start another one; what it applied before stays. It is run by
+// a background job.
+func (uc *ImportUsecase) ProcessImports(ctx context.Context) (completed int, err error) {
+	var errs []error
+	for {
+		imports, err := uc.repo.ClaimImports(ctx, time.Now().Add(-uc.cfg.ProcessTimeout), claimBatchSize)
+		if err != nil {
+			return completed, errors.Join(append(errs, err)...)
+		}
+		if len(imports) == 0 {
+			return completed, errors.Join(errs...)
+		}
+		for _, imp := range imports {
+			if err := uc.process(ctx, imp); err != nil {
+				// a stopping job leaves the import processing, it is resumed once it's considered abandoned
+				if ctx.Err() != nil {
+					return completed, errors.Join(append(errs, err)...)
+				}
+				errs = append(errs, fmt.Errorf("import %s: %w", imp.ID, err))
+				if err := uc.repo.FailImport(ctx, imp.ID, err.Error()); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			completed++
+		}
+	}
+}
+
+// process applies the follows and then the posts of the import in batches, starting where a previous run
+// stopped, and saves the progress after each batch.
+func (uc *ImportUsecase) process(ctx context.Context, imp entity.DataImport) error {
+	archive, err := uc.repo.GetArchive(ctx, imp.ID)
+	if err != nil {
+		return err
+	}
+	follows, posts := imp.Follows, imp.Posts
+
+	for follows.Processed < len(archive.Follows) {
+		batch := archive.Follows[follows.Processed:min(follows.Processed+uc.cfg.BatchSize, len(archive.Follows))]
+		imported, err := uc.importFollows(ctx, imp.UserID, batch)
+		if err != nil {
+			return err
+		}
+		follows.Processed += len(batch)
+		follows.Imported += imported
+		if err := uc.repo.SaveProgress(ctx, imp.ID, follows, posts); err != nil {
+			return err
+		}
+	}
+
+	for posts.Processed < len(archive.Posts) {
+		end := min(posts.Processed+uc.cfg.BatchSize, len(archive.Posts))
+		for i := posts.Processed; i < end; i++ {
+			post := archive.Posts[i]
+			// derived from the import and the position, so a resumed import skips the posts it already stored
+			postID := uuid.NewSHA1(imp.ID, []byte(strconv.Itoa(i)))
+			ok, err := uc.posts.ImportPost(ctx, imp.UserID, postID, post.Text, post.CreatedAt)
+			if err != nil && !skippable(err) {
+				return err
+			}
+			if ok {
+				posts.Imported++
+			}
+		}
+		posts.Processed = end
+		if err := uc.repo.SaveProgress(ctx, imp.ID, follows, posts); err != nil {
+			return err
+		}
+	}
+
+	if err := uc.repo.CompleteImport(ctx, imp.ID); err != nil {
+		return err
+	}
+	uc.logger.Info("Data import completed", "import_id", imp.ID, "user_id", imp.UserID, "source", imp.Source,
+		"follows", follows.Imported, "posts", posts.Imported)
+	return nil
+}
+
+// importFollows follows the accounts of the batch that are users, and returns how many were followed.
+func (uc *ImportUsecase) importFollows(ctx context.Context, userID uuid.UUID, batch []entity.ImportFollow) (int, error) {
+	var usernames, emails []string
+	for _, f := range batch {
+		if f.Username != "" {
+			usernames = append(usernames, f.Username)
+		}
+		if f.Email != "" {
+			emails = append(emails, f.Email)
+		}
+	}
+	users, err := uc.repo.FindUsers(ctx, usernames, emails)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, f := range batch {
+		followeeID := cmp.Or(users[strings.ToLower(f.Username)], users[strings.ToLower(f.Email)])
+		if followeeID == uuid.Nil || followeeID == userID {
+			continue
+		}
+		_, err := uc.follows.Follow(ctx, userID, followeeID)
+		if err != nil && !skippable(err) {
+			return imported, err
+		}
+		if err == nil {
+			imported++
+		}
+	}
+	return imported, nil
+}
+
+// skippable reports whether an entry failed for a reason of its own, like a post over the length limit or
+// an account that blocked the user, rather than a failure the whole import has to stop for.
+func skippable(err error) bool {
+	appErr := apperror.From(err)
+	return appErr != nil && appErr.Kind != apperror.KindUnavailable && appErr.Kind != apperror.KindInternal
+}
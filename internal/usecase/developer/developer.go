@@ -0,0 +1,292 @@
+package developer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/internal/metrics"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// secretPrefix and tokenPrefix mark client secrets and access tokens so leaked ones are easy to recognize,
+	// e.g. by secret scanners
+	secretPrefix = "thr_cs_"
+	tokenPrefix  = "thr_at_"
+
+	maxNameLength        = 100
+	maxDescriptionLength = 500
+	defaultUsageDays     = 7
+)
+
+// AppRepo defines the interface for developer app storage.
+type AppRepo interface {
+	// CreateApp stores a new app with the hash of its client secret.
+	CreateApp(ctx context.Context, app entity.DeveloperApp, secretHash []byte) error
+
+	// CountApps returns how many apps the user owns.
+	CountApps(ctx context.Context, ownerID uuid.UUID) (int, error)
+
+	// ListApps returns the apps of the user, newest first.
+	ListApps(ctx context.Context, ownerID uuid.UUID) ([]entity.DeveloperApp, error)
+
+	// GetApp returns the app of the owner.
+	GetApp(ctx context.Context, ownerID, appID uuid.UUID) (entity.DeveloperApp, error)
+
+	// GetAppByClientID returns the app with the client ID and the hash of its secret.
+	GetAppByClientID(ctx context.Context, clientID string) (entity.DeveloperApp, []byte, error)
+
+	// UpdateSecret replaces the secret hash of the owner's app.
+	UpdateSecret(ctx context.Context, ownerID, appID uuid.UUID, secretHash []byte) error
+
+	// DeleteApp deletes the owner's app.
+	DeleteApp(ctx context.Context, ownerID, appID uuid.UUID) error
+
+	// TouchApp records that the app was just issued a token.
+	TouchApp(ctx context.Context, appID uuid.UUID) error
+}
+
+// TokenStore defines the interface for keeping the access tokens of apps until they expire.
+type TokenStore interface {
+	// Store keeps the token under its hash until it expires.
+	Store(ctx context.Context, tokenHash []byte, token entity.AppToken) error
+
+	// Get returns the token with the hash, customerrors.ErrInvalidToken if it expired or was revoked.
+	Get(ctx context.Context, tokenHash []byte) (entity.AppToken, error)
+
+	// RevokeApp drops every token of the app.
+	RevokeApp(ctx context.Context, appID uuid.UUID) error
+}
+
+// UsageStore defines the interface for counting the requests of apps per day.
+type UsageStore interface {
+	// Record counts a request of the app made at the given time, throttled if its rate limit rejected it.
+	Record(ctx context.Context, appID uuid.UUID, at time.Time, throttled bool) error
+
+	// Days returns the usage of the app on each of the days days up to the given time, newest first.
+	Days(ctx context.Context, appID uuid.UUID, until time.Time, days int) ([]entity.AppUsage, error)
+}
+
+// AuditRecorder defines the interface for recording security events.
+type AuditRecorder interface {
+	Record(ctx context.Context, eventType entity.AuditEventType, actorID, subjectID uuid.UUID, metadata map[string]any)
+}
+
+// DeveloperUsecase manages the apps third-party developers register and authenticates them: an app exchanges its
+// client ID and secret for an access token (the OAuth2 client credentials grant), which it presents to the public
+// API. Tokens are opaque and kept in Redis, so deleting an app or rotating its secret revokes them at once.
+type DeveloperUsecase struct {
+	appRepo AppRepo
+	tokens  TokenStore
+	usage   UsageStore
+	Audit   AuditRecorder
+	cfg     config.DeveloperConfig
+	logger  *slog.Logger
+	Metrics *metrics.Metrics
+}
+
+func NewDeveloperUsecase(appRepo AppRepo, tokens TokenStore, usage UsageStore, audit AuditRecorder, cfg config.DeveloperConfig, logger *slog.Logger, metrics *metrics.Metrics) *DeveloperUsecase {
+	return &DeveloperUsecase{
+		appRepo: appRepo,
+		tokens:  tokens,
+		usage:   usage,
+		Audit:   audit,
+		cfg:     cfg,
+		logger:  logger,
+		Metrics: metrics,
+	}
+}
+
+// CreateApp registers an app of the user allowed to request tokens with the scopes. The returned plain client
+// secret is not stored and can't be shown again.
+func (uc *DeveloperUsecase) CreateApp(ctx context.Context, ownerID uuid.UUID, name, description string, scopes []string) (string, entity.DeveloperApp, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", entity.DeveloperApp{}, apperror.InvalidArgument("invalid_name", "name is required and must be at most 100 characters")
+	}
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return "", entity.DeveloperApp{}, apperror.InvalidArgument("invalid_description", "description must be at most 500 characters")
+	}
+	if len(scopes) == 0 {
+		return "", entity.DeveloperApp{}, apperror.InvalidArgument("scope_required", "at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(entity.AppScopes, scope) {
+			return "", entity.DeveloperApp{}, apperror.InvalidArgument("invalid_scope", "invalid scope "+scope+", expected one of "+strings.Join(entity.AppScopes, ", "))
+		}
+	}
+	count, err := uc.appRepo.CountApps(ctx, ownerID)
+	if err != nil {
+		return "", entity.DeveloperApp{}, err
+	}
+	if count >= uc.cfg.MaxApps {
+		return "", entity.DeveloperApp{}, apperror.FailedPrecondition("app_limit_reached", "you already have the maximum number of apps, delete one first")
+	}
+
+	clientID, err := randomString("", 16)
+	if err != nil {
+		return "", entity.DeveloperApp{}, err
+	}
+	secret, err := randomString(secretPrefix, 32)
+	if err != nil {
+		return "", entity.DeveloperApp{}, err
+	}
+	app := entity.DeveloperApp{
+		ID:          uuid.New(),
+		OwnerID:     ownerID,
+		Name:        name,
+		Description: description,
+		ClientID:    clientID,
+		Scopes:      slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedAt:   time.Now(),
+	}
+	if err := uc.appRepo.CreateApp(ctx, app, hash(secret)); err != nil {
+		return "", entity.DeveloperApp{}, err
+	}
+	uc.Audit.Record(ctx, entity.AuditAppCreate, ownerID, ownerID, map[string]any{"app_id": app.ID, "name": name, "scopes": app.Scopes})
+	return secret, app, nil
+}
+
+// ListApps returns the apps of the user, newest first, without their secrets.
+func (uc *DeveloperUsecase) ListApps(ctx context.Context, ownerID uuid.UUID) ([]entity.DeveloperApp, error) {
+	return uc.appRepo.ListApps(ctx, ownerID)
+}
+
+// RotateSecret replaces the client secret of the user's app and revokes its tokens, e.g. after the secret leaked.
+// The returned plain secret can't be shown again.
+func (uc *DeveloperUsecase) RotateSecret(ctx context.Context, ownerID, appID uuid.UUID) (string, error) {
+	secret, err := randomString(secretPrefix, 32)
+	if err != nil {
+		return "", err
+	}
+	if err := uc.appRepo.UpdateSecret(ctx, ownerID, appID, hash(secret)); err != nil {
+		return "", err
+	}
+	uc.revokeTokens(ctx, appID)
+	uc.Audit.Record(ctx, entity.AuditAppSecretRotate, ownerID, ownerID, map[string]any{"app_id": appID})
+	return secret, nil
+}
+
+// DeleteApp deletes the user's app and revokes its tokens.
+func (uc *DeveloperUsecase) DeleteApp(ctx context.Context, ownerID, appID uuid.UUID) error {
+	if err := uc.appRepo.DeleteApp(ctx, ownerID, appID); err != nil {
+		return err
+	}
+	uc.revokeTokens(ctx, appID)
+	uc.Audit.Record(ctx, entity.AuditAppDelete, ownerID, ownerID, map[string]any{"app_id": appID})
+	return nil
+}
+
+// revokeTokens drops the tokens of the app. Tokens that can't be dropped stay valid until they expire, which is
+// logged rather than failing the change of the app that already happened.
+func (uc *DeveloperUsecase) revokeTokens(ctx context.Context, appID uuid.UUID) {
+	if err := uc.tokens.RevokeApp(context.WithoutCancel(ctx), appID); err != nil {
+		uc.logger.Error("Failed to revoke app tokens", "app_id", appID, "error", err)
+	}
+}
+
+// Usage returns the daily requests of the user's app over the last days, today first. A non-positive number of
+// days returns the last week; it is capped at the retention of the usage.
+func (uc *DeveloperUsecase) Usage(ctx context.Context, ownerID, appID uuid.UUID, days int) ([]entity.AppUsage, error) {
+	if _, err := uc.appRepo.GetApp(ctx, ownerID, appID); err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = defaultUsageDays
+	}
+	days = min(days, int(uc.cfg.UsageRetention/(24*time.Hour)))
+	return uc.usage.Days(ctx, appID, time.Now(), days)
+}
+
+// IssueToken authenticates the app by its client ID and secret and issues an access token with the requested
+// scopes, all scopes of the app if none are requested. Returns customerrors.ErrInvalidClient for unknown clients
+// or wrong secrets and customerrors.ErrInvalidScope for scopes the app wasn't granted.
+func (uc *DeveloperUsecase) IssueToken(ctx context.Context, clientID, secret string, scopes []string) (string, entity.AppToken, error) {
+	app, secretHash, err := uc.appRepo.GetAppByClientID(ctx, clientID)
+	if errors.Is(err, customerrors.ErrInvalidToken) {
+		return "", entity.AppToken{}, customerrors.ErrInvalidClient
+	}
+	if err != nil {
+		return "", entity.AppToken{}, err
+	}
+	if subtle.ConstantTimeCompare(hash(secret), secretHash) != 1 {
+		return "", entity.AppToken{}, customerrors.ErrInvalidClient
+	}
+	if len(scopes) == 0 {
+		scopes = app.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(app.Scopes, scope) {
+			return "", entity.AppToken{}, customerrors.ErrInvalidScope
+		}
+	}
+
+	plain, err := randomString(tokenPrefix, 32)
+	if err != nil {
+		return "", entity.AppToken{}, err
+	}
+	token := entity.AppToken{
+		AppID:     app.ID,
+		Scopes:    scopes,
+		RateLimit: app.RateLimit,
+		ExpiresAt: time.Now().Add(uc.cfg.TokenTTL),
+	}
+	if token.RateLimit <= 0 {
+		token.RateLimit = uc.cfg.RateLimit
+	}
+	if err := uc.tokens.Store(ctx, hash(plain), token); err != nil {
+		return "", entity.AppToken{}, err
+	}
+	// last use is informational, failing to record it must not fail the call
+	_ = uc.appRepo.TouchApp(ctx, app.ID)
+	return plain, token, nil
+}
+
+// Authenticate returns the access token matching the plain token if it is still valid.
+// Returns customerrors.ErrInvalidToken for unknown, revoked or expired tokens.
+func (uc *DeveloperUsecase) Authenticate(ctx context.Context, plain string) (entity.AppToken, error) {
+	if !strings.HasPrefix(plain, tokenPrefix) {
+		return entity.AppToken{}, customerrors.ErrInvalidToken
+	}
+	return uc.tokens.Get(ctx, hash(plain))
+}
+
+// RecordRequest counts a request of the app to the public API towards its usage, throttled if its rate limit
+// rejected it. Failures are logged, usage is informational.
+func (uc *DeveloperUsecase) RecordRequest(ctx context.Context, appID uuid.UUID, throttled bool) {
+	status := "ok"
+	if throttled {
+		status = "throttled"
+	}
+	uc.Metrics.AppRequests.WithLabelValues(status).Inc()
+	if err := uc.usage.Record(context.WithoutCancel(ctx), appID, time.Now(), throttled); err != nil {
+		uc.logger.Warn("Failed to record app usage", "app_id", appID, "error", err)
+	}
+}
+
+// randomString returns the prefix followed by n random bytes, base64url encoded.
+func randomString(prefix string, n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hash(plain string) []byte {
+	sum := sha256.Sum256([]byte(plain))
+	return sum[:]
+}
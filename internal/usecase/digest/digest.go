@@ -0,0 +1,136 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"main/domain/entity"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// claimBatchSize is the number of digests claimed at once.
+	claimBatchSize = 100
+	// maxFollowers and maxPosts limit how many followers and posts a digest lists.
+	maxFollowers = 10
+	maxPosts     = 5
+	// excerptLength is the number of characters of a post description shown in a digest.
+	excerptLength = 140
+)
+
+//go:embed digest.tmpl
+var digestTemplate string
+
+var bodyTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"sub": func(a, b int) int { return a - b },
+	"excerpt": func(s string) string {
+		r := []rune(s)
+		if len(r) <= excerptLength {
+			return s
+		}
+		return string(r[:excerptLength]) + "..."
+	},
+}).Parse(digestTemplate))
+
+// DigestRepo defines the interface for the users due a digest and the activity they missed.
+type DigestRepo interface {
+	// ClaimDue returns up to limit users whose digest is due and marks it sent.
+	ClaimDue(ctx context.Context, limit int) ([]entity.Digest, error)
+
+	// NewFollowers returns up to limit of the latest followers of the user since the given time and how many there are.
+	NewFollowers(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]entity.UserCard, int, error)
+
+	// TopPosts returns up to limit of the most liked posts of the accounts the user follows since the given time.
+	TopPosts(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]entity.DigestPost, error)
+
+	// CountUnread returns the number of unread notifications of the user.
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// Mailer defines the interface for sending emails to users.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type DigestUsecase struct {
+	repo   DigestRepo
+	mailer Mailer
+	appURL string
+}
+
+// NewDigestUsecase creates the digest usecase, appURL is the address of the web app the emails link to.
+func NewDigestUsecase(repo DigestRepo, mailer Mailer, appURL string) *DigestUsecase {
+	return &DigestUsecase{
+		repo:   repo,
+		mailer: mailer,
+		appURL: appURL,
+	}
+}
+
+// SendDigests emails the digests that are due until none are left and returns the number of emails sent.
+// Users with no activity get no email. A digest is marked sent when it's claimed, so one that fails
+// is skipped rather than sent twice by jobs running at the same time.
+func (uc *DigestUsecase) SendDigests(ctx context.Context) (sent int, err error) {
+	var errs []error
+	for {
+		digests, err := uc.repo.ClaimDue(ctx, claimBatchSize)
+		if err != nil {
+			return sent, errors.Join(append(errs, err)...)
+		}
+		if len(digests) == 0 {
+			return sent, errors.Join(errs...)
+		}
+		for _, digest := range digests {
+			ok, err := uc.send(ctx, digest)
+			if ok {
+				sent++
+			}
+			errs = append(errs, err)
+		}
+	}
+}
+
+func (uc *DigestUsecase) send(ctx context.Context, digest entity.Digest) (bool, error) {
+	var err error
+	digest.NewFollowers, digest.NewFollowersCount, err = uc.repo.NewFollowers(ctx, digest.UserID, digest.Since, maxFollowers)
+	if err != nil {
+		return false, err
+	}
+	digest.TopPosts, err = uc.repo.TopPosts(ctx, digest.UserID, digest.Since, maxPosts)
+	if err != nil {
+		return false, err
+	}
+	digest.UnreadNotifications, err = uc.repo.CountUnread(ctx, digest.UserID)
+	if err != nil {
+		return false, err
+	}
+	if digest.Empty() {
+		return false, nil
+	}
+
+	body, err := uc.render(digest)
+	if err != nil {
+		return false, err
+	}
+	subject := "Your weekly Threads digest"
+	if digest.Frequency == entity.DigestDaily {
+		subject = "Your daily Threads digest"
+	}
+	if err := uc.mailer.Send(ctx, digest.Email, subject, body); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (uc *DigestUsecase) render(digest entity.Digest) (string, error) {
+	var buf bytes.Buffer
+	err := bodyTemplate.Execute(&buf, struct {
+		entity.Digest
+		AppURL string
+	}{digest, uc.appURL})
+	return buf.String(), err
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"main/domain/entity"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// relayBatchSize is the maximum number of outbox events published by one relay run, in one write.
+const relayBatchSize = 500
+
+// EventsRepo defines the interface for the outbox of domain events.
+type EventsRepo interface {
+	// RelayOutbox hands up to limit stored events to publish in the order they happened, they are deleted once
+	// publish succeeds, and reports how many were published.
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []entity.DomainEvent) error) (int, error)
+}
+
+// Producer writes messages to Kafka, it is satisfied by kafka.Writer.
+type Producer interface {
+	// WriteMessages writes the messages to their topics, acknowledged by all in-sync replicas.
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+}
+
+type EventsUsecase struct {
+	repo     EventsRepo
+	producer Producer
+	topic    string
+}
+
+// NewEventsUsecase publishes the domain events to the topic. Without a producer, when Kafka isn't configured,
+// the events are dropped so the outbox doesn't grow.
+func NewEventsUsecase(repo EventsRepo, producer Producer, topic string) *EventsUsecase {
+	return &EventsUsecase{
+		repo:     repo,
+		producer: producer,
+		topic:    topic,
+	}
+}
+
+// RelayOutbox publishes the stored domain events and reports how many were published. The events of a failed
+// run are retried by the next one, so consumers get every event at least once and drop duplicates by event ID.
+func (uc *EventsUsecase) RelayOutbox(ctx context.Context) (int, error) {
+	return uc.repo.RelayOutbox(ctx, relayBatchSize, uc.publish)
+}
+
+// publish writes the events keyed by what they're about, each one as its JSON envelope with the type also in
+// the event_type header, so consumers can filter without decoding.
+func (uc *EventsUsecase) publish(ctx context.Context, events []entity.DomainEvent) error {
+	if uc.producer == nil {
+		return nil
+	}
+	messages := make([]kafka.Message, 0, len(events))
+	for _, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Topic:   uc.topic,
+			Key:     []byte(e.Key.String()),
+			Value:   value,
+			Headers: []kafka.Header{{Key: "event_type", Value: []byte(e.Type)}},
+			Time:    e.OccurredAt,
+		})
+	}
+	return uc.producer.WriteMessages(ctx, messages...)
+}
@@ -0,0 +1,277 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/pkg/customerrors"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// claimBatchSize is the number of exports claimed at once, they are prepared one after another.
+	claimBatchSize = 1
+	// purgeBatchSize is the number of expired exports deleted at once.
+	purgeBatchSize = 100
+	// archiveName is the file name of the archive users download.
+	archiveName = "threads-data.zip"
+)
+
+// ExportRepo defines the interface for data exports and the user data they contain.
+type ExportRepo interface {
+	// CreateExport queues a data export of the user, customerrors.ErrExportInProgress if one is already queued.
+	CreateExport(ctx context.Context, exportID, userID uuid.UUID) (entity.DataExport, error)
+
+	// GetExport returns a data export of the user.
+	GetExport(ctx context.Context, userID, exportID uuid.UUID) (entity.DataExport, error)
+
+	// ClaimExports marks up to limit pending exports, or exports processing since before staleBefore, as processing.
+	ClaimExports(ctx context.Context, staleBefore time.Time, limit int) ([]entity.DataExport, error)
+
+	// CompleteExport marks the export ready with its archive, which is kept until expiresAt.
+	CompleteExport(ctx context.Context, exportID uuid.UUID, objectKey string, size int64, expiresAt time.Time) (entity.DataExport, error)
+
+	// FailExport marks the export failed with the reason.
+	FailExport(ctx context.Context, exportID uuid.UUID, reason string) error
+
+	// ExpiredExports returns up to limit exports whose archive expired before the given time.
+	ExpiredExports(ctx context.Context, before time.Time, limit int) ([]entity.DataExport, error)
+
+	// DeleteExport deletes the record of an export.
+	DeleteExport(ctx context.Context, exportID uuid.UUID) error
+
+	// GetUserData collects the data of the user that goes into an export.
+	GetUserData(ctx context.Context, userID uuid.UUID) (entity.UserData, error)
+}
+
+// Store keeps the archives, they are private and downloaded through signed URLs.
+type Store interface {
+	Put(ctx context.Context, key, contentType string, r io.Reader, size int64) (string, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL the file under the key can be downloaded from until expiresAt.
+	SignedURL(ctx context.Context, key string, expiresAt time.Time) (string, error)
+}
+
+// FeatureFlags tells which gradually rolled out features are on for a user.
+type FeatureFlags interface {
+	Enabled(ctx context.Context, name string, userID uuid.UUID) bool
+}
+
+// Mailer defines the interface for sending emails to users.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type ExportUsecase struct {
+	repo   ExportRepo
+	store  Store
+	mailer Mailer
+	flags  FeatureFlags
+	cfg    config.ExportConfig
+}
+
+func NewExportUsecase(repo ExportRepo, store Store, mailer Mailer, flags FeatureFlags, cfg config.ExportConfig) *ExportUsecase {
+	return &ExportUsecase{
+		repo:   repo,
+		store:  store,
+		mailer: mailer,
+		flags:  flags,
+		cfg:    cfg,
+	}
+}
+
+// RequestExport queues an export of the user's data, a background job prepares the archive and emails the user
+// a download link. One export of a user is prepared at a time. Returns customerrors.ErrFeatureDisabled unless
+// the data_export feature is on for the user.
+func (uc *ExportUsecase) RequestExport(ctx context.Context, userID uuid.UUID) (entity.DataExport, error) {
+	if !uc.flags.Enabled(ctx, entity.FeatureDataExport, userID) {
+		return entity.DataExport{}, customerrors.ErrFeatureDisabled
+	}
+	return uc.repo.CreateExport(ctx, uuid.New(), userID)
+}
+
+// GetExport returns a data export of the user, with a fresh download link if the archive is ready.
+func (uc *ExportUsecase) GetExport(ctx context.Context, userID, exportID uuid.UUID) (entity.DataExport, error) {
+	export, err := uc.repo.GetExport(ctx, userID, exportID)
+	if err != nil {
+		return entity.DataExport{}, err
+	}
+	return export, uc.sign(ctx, &export)
+}
+
+// sign sets the download link of a ready export whose archive hasn't expired yet.
+func (uc *ExportUsecase) sign(ctx context.Context, export *entity.DataExport) error {
+	if export.Status != entity.DataExportReady || export.ExpiresAt == nil || !time.Now().Before(*export.ExpiresAt) {
+		return nil
+	}
+	expiresAt := time.Now().Add(uc.cfg.LinkTTL)
+	if export.ExpiresAt.Before(expiresAt) {
+		expiresAt = *export.ExpiresAt
+	}
+	url, err := uc.store.SignedURL(ctx, export.ObjectKey, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to sign download link: %w", err)
+	}
+	export.DownloadURL = url
+	return nil
+}
+
+// ProcessExports prepares the queued exports until none are left and returns how many are ready. A failed export
+// is marked failed, so the user can request another one. It is run by a background job.
+func (uc *ExportUsecase) ProcessExports(ctx context.Context) (ready int, err error) {
+	var errs []error
+	for {
+		exports, err := uc.repo.ClaimExports(ctx, time.Now().Add(-uc.cfg.ProcessTimeout), claimBatchSize)
+		if err != nil {
+			return ready, errors.Join(append(errs, err)...)
+		}
+		if len(exports) == 0 {
+			return ready, errors.Join(errs...)
+		}
+		for _, export := range exports {
+			if err := uc.process(ctx, export); err != nil {
+				errs = append(errs, fmt.Errorf("export %s: %w", export.ID, err))
+				if err := uc.repo.FailExport(ctx, export.ID, err.Error()); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			ready++
+		}
+	}
+}
+
+// process builds the archive of the export, stores it and emails the user the download link.
+func (uc *ExportUsecase) process(ctx context.Context, export entity.DataExport) error {
+	data, err := uc.repo.GetUserData(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	archive, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	if err := writeArchive(archive, data); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%s/%s/%s", export.UserID, export.ID, archiveName)
+	if _, err := uc.store.Put(ctx, key, "application/zip", archive, size); err != nil {
+		return fmt.Errorf("failed to store archive: %w", err)
+	}
+	export, err = uc.repo.CompleteExport(ctx, export.ID, key, size, time.Now().Add(uc.cfg.Retention))
+	if err != nil {
+		return err
+	}
+
+	// the archive is ready even if the email can't be sent, the user can still get the link from the API
+	if err := uc.sign(ctx, &export); err == nil {
+		body := "The export of your Threads data is ready, download it here:\n\n" +
+			export.DownloadURL + "\n\n" +
+			"The link expires in " + uc.cfg.LinkTTL.String() + ", the archive is available until " +
+			export.ExpiresAt.UTC().Format(time.RFC1123) + ".\n\n" +
+			"If you didn't request the export, change your password."
+		_ = uc.mailer.Send(ctx, data.Account.Email, "Your data export is ready", body)
+	}
+	return nil
+}
+
+// archivedSession is a session as it appears in an archive, without the refresh token.
+type archivedSession struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UserAgent   string     `json:"user_agent"`
+	ClientIP    netip.Addr `json:"client_ip"`
+	DeviceName  string     `json:"device_name,omitempty"`
+	CountryCode string     `json:"country_code,omitempty"`
+	City        string     `json:"city,omitempty"`
+}
+
+// writeArchive writes the data as a ZIP archive with one JSON file per kind of data.
+func writeArchive(w io.Writer, data entity.UserData) error {
+	sessions := make([]archivedSession, 0, len(data.Sessions))
+	for _, s := range data.Sessions {
+		sessions = append(sessions, archivedSession{
+			ID:          s.ID,
+			CreatedAt:   s.CreatedAt,
+			ExpiresAt:   s.ExpiresAt,
+			UserAgent:   s.UserAgent,
+			ClientIP:    s.ClientIP,
+			DeviceName:  s.DeviceName,
+			CountryCode: s.CountryCode,
+			City:        s.City,
+		})
+	}
+	files := []struct {
+		name string
+		data any
+	}{
+		{"account.json", map[string]any{"account": data.Account, "profile": data.Profile}},
+		{"posts.json", nonNil(data.Posts)},
+		{"comments.json", nonNil(data.Comments)},
+		{"messages.json", nonNil(data.Messages)},
+		{"sessions.json", sessions},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// nonNil returns an empty slice for nil, so it's written as [] rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// PurgeExpiredExports deletes the archives kept longer than the retention and the records of their exports, and
+// returns how many were deleted. It is run by a scheduled job.
+func (uc *ExportUsecase) PurgeExpiredExports(ctx context.Context) (purged int, err error) {
+	for {
+		exports, err := uc.repo.ExpiredExports(ctx, time.Now(), purgeBatchSize)
+		if err != nil || len(exports) == 0 {
+			return purged, err
+		}
+		for _, export := range exports {
+			if err := uc.store.Delete(ctx, export.ObjectKey); err != nil {
+				return purged, err
+			}
+			if err := uc.repo.DeleteExport(ctx, export.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+}
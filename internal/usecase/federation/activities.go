@@ -0,0 +1,166 @@
+package federation
+
+import (
+	"html"
+	"main/domain/entity"
+	"main/pkg/activitypub"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The documents of a user live under /ap/users/<id>, those of a post under /ap/posts/<id>. IDs rather than
+// usernames are used so the documents stay put when a user is renamed.
+
+func (uc *FederationUsecase) actorURL(userID uuid.UUID) string {
+	return uc.cfg.BaseURL + "/ap/users/" + userID.String()
+}
+
+func (uc *FederationUsecase) keyID(userID uuid.UUID) string {
+	return uc.actorURL(userID) + "#main-key"
+}
+
+func (uc *FederationUsecase) noteURL(postID uuid.UUID) string {
+	return uc.cfg.BaseURL + "/ap/posts/" + postID.String()
+}
+
+// localUser returns the user whose actor URL this is, false for the URLs of other servers.
+func (uc *FederationUsecase) localUser(actorURL string) (uuid.UUID, bool) {
+	id, ok := strings.CutPrefix(actorURL, uc.cfg.BaseURL+"/ap/users/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(id)
+	return userID, err == nil
+}
+
+func (uc *FederationUsecase) person(profile entity.Profile, publicKey string) activitypub.Actor {
+	actor := uc.actorURL(profile.UserID)
+	person := activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                actor,
+		Type:              activitypub.TypePerson,
+		PreferredUsername: profile.Username,
+		Name:              profile.Name,
+		Summary:           toHTML(profile.Bio),
+		Inbox:             actor + "/inbox",
+		Outbox:            actor + "/outbox",
+		Followers:         actor + "/followers",
+		Following:         actor + "/following",
+		PublicKey: activitypub.PublicKey{
+			ID:           uc.keyID(profile.UserID),
+			Owner:        actor,
+			PublicKeyPem: publicKey,
+		},
+		Endpoints: &activitypub.Endpoints{SharedInbox: uc.cfg.BaseURL + "/ap/inbox"},
+	}
+	if profile.AvatarURL != "" {
+		person.Icon = &activitypub.Image{Type: "Image", MediaType: mediaType(profile.AvatarURL), URL: profile.AvatarURL}
+	}
+	return person
+}
+
+// note is the Note of a public post, addressed to everyone with a copy to the author's followers.
+func (uc *FederationUsecase) note(post entity.Post) activitypub.Note {
+	published, updated := post.CreatedAt.UTC(), post.UpdatedAt.UTC()
+	note := activitypub.Note{
+		ID:           uc.noteURL(post.ID),
+		Type:         activitypub.TypeNote,
+		AttributedTo: uc.actorURL(post.UserID),
+		Content:      toHTML(post.Description),
+		Published:    &published,
+		To:           []string{activitypub.Public},
+		Cc:           []string{uc.actorURL(post.UserID) + "/followers"},
+	}
+	if updated.After(published) {
+		note.Updated = &updated
+	}
+	if post.MediaURL != "" {
+		note.Attachment = []activitypub.Image{{Type: activitypub.TypeDocument, MediaType: mediaType(post.MediaURL), URL: post.MediaURL}}
+	}
+	return note
+}
+
+// create is the Create activity that published the post.
+func (uc *FederationUsecase) create(post entity.Post) activitypub.Activity {
+	note := uc.note(post)
+	return activitypub.Activity{
+		ID:        note.ID + "/activity",
+		Type:      activitypub.TypeCreate,
+		Actor:     note.AttributedTo,
+		Object:    note,
+		Published: note.Published,
+		To:        note.To,
+		Cc:        note.Cc,
+	}
+}
+
+// announce is the Announce activity of the user's repost of the post. Its ID is derived from both, so undoing
+// the repost can name it.
+func (uc *FederationUsecase) announce(userID, postID uuid.UUID) activitypub.Activity {
+	actor := uc.actorURL(userID)
+	now := time.Now().UTC()
+	return activitypub.Activity{
+		ID:        actor + "/announces/" + postID.String(),
+		Type:      activitypub.TypeAnnounce,
+		Actor:     actor,
+		Object:    uc.noteURL(postID),
+		Published: &now,
+		To:        []string{activitypub.Public},
+		Cc:        []string{actor + "/followers"},
+	}
+}
+
+// undo is the activity undoing one of the user's activities.
+func (uc *FederationUsecase) undo(userID uuid.UUID, activity activitypub.Activity) activitypub.Activity {
+	activity.Context = nil
+	return activitypub.Activity{
+		ID:     activity.ID + "/undo/" + uuid.NewString(),
+		Type:   activitypub.TypeUndo,
+		Actor:  uc.actorURL(userID),
+		Object: activity,
+		To:     activity.To,
+		Cc:     activity.Cc,
+	}
+}
+
+// deleteNote is the Delete activity replacing the user's post with a tombstone.
+func (uc *FederationUsecase) deleteNote(userID, postID uuid.UUID) activitypub.Activity {
+	note := uc.noteURL(postID)
+	return activitypub.Activity{
+		ID:     note + "/delete/" + uuid.NewString(),
+		Type:   activitypub.TypeDelete,
+		Actor:  uc.actorURL(userID),
+		Object: map[string]string{"id": note, "type": activitypub.TypeTombstone},
+		To:     []string{activitypub.Public},
+		Cc:     []string{uc.actorURL(userID) + "/followers"},
+	}
+}
+
+// toHTML turns plain text into the HTML of ActivityPub content: escaped, paragraphs at blank lines and line breaks
+// at the other newlines.
+func toHTML(text string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>"))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
+
+// mediaType guesses the media type of a file from the extension of its URL, empty if it can't.
+func mediaType(url string) string {
+	return mime.TypeByExtension(path.Ext(strings.SplitN(url, "?", 2)[0]))
+}
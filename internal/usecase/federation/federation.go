@@ -0,0 +1,584 @@
+// Package federation makes profiles and public posts visible to Mastodon-compatible servers over ActivityPub:
+// users are discovered with WebFinger, their actors, outboxes and posts are served as ActivityStreams documents,
+// remote accounts follow them through signed requests to their inboxes, and new posts, reposts and deletions are
+// delivered to the inboxes of their remote followers.
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/pkg/activitypub"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	"main/pkg/httpsig"
+	"main/pkg/pagination"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// keySize is the size of the RSA keys users sign their deliveries with, what Mastodon uses
+	keySize = 2048
+	// deliveryLease is how long claimed deliveries are held before they're tried again if the job dies
+	deliveryLease = 5 * time.Minute
+	// retryBackoff is the wait after the first failed delivery, it doubles with every further one
+	retryBackoff = time.Minute
+)
+
+// FederationRepo defines the interface for federation state.
+type FederationRepo interface {
+	// GetProfile returns the profile of a user whose account federates, customerrors.ErrNotFound otherwise.
+	GetProfile(ctx context.Context, userID uuid.UUID) (entity.Profile, error)
+
+	// GetProfileByUsername returns the profile of the federating user with the username.
+	GetProfileByUsername(ctx context.Context, username string) (entity.Profile, error)
+
+	// GetPublicPost returns a public post of a federating user, customerrors.ErrNotFound otherwise.
+	GetPublicPost(ctx context.Context, postID uuid.UUID) (entity.Post, error)
+
+	// ListPublicPosts returns the public posts of the user before the position, newest first.
+	ListPublicPosts(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Post, error)
+
+	// CountPublicPosts returns how many public posts the user has.
+	CountPublicPosts(ctx context.Context, userID uuid.UUID) (int, error)
+
+	// GetKey returns the PEM encoded key pair of the user, customerrors.ErrNotFound if none was made yet.
+	GetKey(ctx context.Context, userID uuid.UUID) (privateKey, publicKey string, err error)
+
+	// CreateKey stores the key pair of the user unless one was stored meanwhile, and returns the stored one.
+	CreateKey(ctx context.Context, userID uuid.UUID, privateKey, publicKey string) (string, string, error)
+
+	// GetRemoteActor returns the cached remote actor, customerrors.ErrNotFound if it isn't cached.
+	GetRemoteActor(ctx context.Context, actorID string) (entity.RemoteActor, error)
+
+	// SaveRemoteActor caches the remote actor.
+	SaveRemoteActor(ctx context.Context, actor entity.RemoteActor) error
+
+	// DeleteRemoteActor forgets a remote actor and its follows.
+	DeleteRemoteActor(ctx context.Context, actorID string) error
+
+	// AddRemoteFollower records that the remote actor follows the user with the Follow activity.
+	AddRemoteFollower(ctx context.Context, userID uuid.UUID, actorID, followID string) error
+
+	// RemoveRemoteFollower removes the remote actor from the followers of the user.
+	RemoveRemoteFollower(ctx context.Context, userID uuid.UUID, actorID string) error
+
+	// FollowerInboxes returns the inboxes the activities of the user are delivered to.
+	FollowerInboxes(ctx context.Context, userID uuid.UUID) ([]string, error)
+
+	// EnqueueDeliveries queues the activity of the user for delivery to each of the inboxes.
+	EnqueueDeliveries(ctx context.Context, userID uuid.UUID, inboxes []string, activity []byte) error
+
+	// ClaimDeliveries returns up to limit due deliveries and postpones them by lease.
+	ClaimDeliveries(ctx context.Context, lease time.Duration, limit int) ([]entity.FederationDelivery, error)
+
+	// DeleteDelivery removes a delivery that succeeded or is given up.
+	DeleteDelivery(ctx context.Context, deliveryID uuid.UUID) error
+
+	// RetryDelivery records a failed attempt of the delivery and schedules the next one.
+	RetryDelivery(ctx context.Context, deliveryID uuid.UUID, nextAttemptAt time.Time, reason string) error
+}
+
+// Client talks to remote servers.
+type Client interface {
+	// FetchActor fetches the actor document at the URL.
+	FetchActor(ctx context.Context, actorURL string) (activitypub.Actor, error)
+
+	// Deliver posts the activity to the inbox signed with the key of the sending actor.
+	Deliver(ctx context.Context, inbox string, activity []byte, keyID string, key *rsa.PrivateKey) error
+}
+
+type FederationUsecase struct {
+	repo   FederationRepo
+	client Client
+	cfg    config.FederationConfig
+	logger *slog.Logger
+}
+
+func NewFederationUsecase(repo FederationRepo, client Client, cfg config.FederationConfig, logger *slog.Logger) *FederationUsecase {
+	return &FederationUsecase{
+		repo:   repo,
+		client: client,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// WebFinger resolves acct:username@domain, or the URL of an actor, to the actor of the user.
+func (uc *FederationUsecase) WebFinger(ctx context.Context, resource string) (activitypub.JRD, error) {
+	var (
+		profile entity.Profile
+		err     error
+	)
+	if userID, ok := uc.localUser(resource); ok {
+		profile, err = uc.repo.GetProfile(ctx, userID)
+	} else {
+		acct, ok := strings.CutPrefix(resource, "acct:")
+		if !ok {
+			return activitypub.JRD{}, apperror.InvalidArgument("invalid_resource", "resource must be an acct: URI or an actor URL")
+		}
+		username, domain, _ := strings.Cut(strings.TrimPrefix(acct, "@"), "@")
+		if !strings.EqualFold(domain, uc.cfg.Domain) {
+			return activitypub.JRD{}, customerrors.ErrNotFound
+		}
+		profile, err = uc.repo.GetProfileByUsername(ctx, username)
+	}
+	if err != nil {
+		return activitypub.JRD{}, err
+	}
+
+	actor := uc.actorURL(profile.UserID)
+	return activitypub.JRD{
+		Subject: "acct:" + profile.Username + "@" + uc.cfg.Domain,
+		Aliases: []string{actor},
+		Links:   []activitypub.Link{{Rel: "self", Type: activitypub.ContentType, Href: actor}},
+	}, nil
+}
+
+// Actor returns the Person of the user, with the public key remote servers verify the user's deliveries with.
+func (uc *FederationUsecase) Actor(ctx context.Context, userID uuid.UUID) (activitypub.Actor, error) {
+	profile, err := uc.repo.GetProfile(ctx, userID)
+	if err != nil {
+		return activitypub.Actor{}, err
+	}
+	_, publicKey, err := uc.key(ctx, userID)
+	if err != nil {
+		return activitypub.Actor{}, err
+	}
+	return uc.person(profile, publicKey), nil
+}
+
+// key returns the private key and the PEM encoded public key of the user, making them the first time.
+func (uc *FederationUsecase) key(ctx context.Context, userID uuid.UUID) (*rsa.PrivateKey, string, error) {
+	privatePEM, publicPEM, err := uc.repo.GetKey(ctx, userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		privatePEM, publicPEM, err = uc.createKey(ctx, userID)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, "", fmt.Errorf("private key of user %s is not PEM encoded", userID)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("private key of user %s: %w", userID, err)
+	}
+	return key, publicPEM, nil
+}
+
+func (uc *FederationUsecase) createKey(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+	return uc.repo.CreateKey(ctx, userID, string(privatePEM), string(publicPEM))
+}
+
+// Outbox returns the collection of the user's public posts. Its items are on pages of Create activities, the
+// first page is requested with page set and the following ones with the cursor of the previous one.
+func (uc *FederationUsecase) Outbox(ctx context.Context, userID uuid.UUID, page bool, cursor string) (any, error) {
+	if _, err := uc.repo.GetProfile(ctx, userID); err != nil {
+		return nil, err
+	}
+	outbox := uc.actorURL(userID) + "/outbox"
+	if !page {
+		total, err := uc.repo.CountPublicPosts(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return activitypub.OrderedCollection{
+			Context:    activitypub.Context,
+			ID:         outbox,
+			Type:       activitypub.TypeOrderedCollection,
+			TotalItems: total,
+			First:      outbox + "?page=true",
+		}, nil
+	}
+
+	before, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := uc.repo.ListPublicPosts(ctx, userID, before.CreatedAt, before.ID, uc.cfg.PageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	posts, next := pagination.Page(posts, uc.cfg.PageSize, func(p entity.Post) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	})
+
+	result := activitypub.OrderedCollectionPage{
+		Context:      activitypub.Context,
+		ID:           outbox + "?page=true",
+		Type:         activitypub.TypeOrderedCollectionPage,
+		PartOf:       outbox,
+		OrderedItems: make([]any, 0, len(posts)),
+	}
+	if cursor != "" {
+		result.ID += "&cursor=" + url.QueryEscape(cursor)
+	}
+	if next != "" {
+		result.Next = outbox + "?page=true&cursor=" + url.QueryEscape(next)
+	}
+	for _, post := range posts {
+		result.OrderedItems = append(result.OrderedItems, uc.create(post))
+	}
+	return result, nil
+}
+
+// Followers returns the collection of the user's followers, local and remote. Only its size is published.
+func (uc *FederationUsecase) Followers(ctx context.Context, userID uuid.UUID) (activitypub.OrderedCollection, error) {
+	profile, err := uc.repo.GetProfile(ctx, userID)
+	if err != nil {
+		return activitypub.OrderedCollection{}, err
+	}
+	return activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         uc.actorURL(userID) + "/followers",
+		Type:       activitypub.TypeOrderedCollection,
+		TotalItems: profile.FollowersCount,
+	}, nil
+}
+
+// Following returns the collection of the accounts the user follows. Only its size is published.
+func (uc *FederationUsecase) Following(ctx context.Context, userID uuid.UUID) (activitypub.OrderedCollection, error) {
+	profile, err := uc.repo.GetProfile(ctx, userID)
+	if err != nil {
+		return activitypub.OrderedCollection{}, err
+	}
+	return activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         uc.actorURL(userID) + "/following",
+		Type:       activitypub.TypeOrderedCollection,
+		TotalItems: profile.FollowingCount,
+	}, nil
+}
+
+// Note returns the Note of a public post.
+func (uc *FederationUsecase) Note(ctx context.Context, postID uuid.UUID) (activitypub.Note, error) {
+	post, err := uc.repo.GetPublicPost(ctx, postID)
+	if err != nil {
+		return activitypub.Note{}, err
+	}
+	note := uc.note(post)
+	note.Context = activitypub.Context
+	return note, nil
+}
+
+// HandleInbox handles an activity delivered to the inbox of a user or to the shared inbox. The request must be
+// signed with the key of the activity's actor, which is fetched from its server and cached. Follows of local
+// users are accepted at once, undone follows removed and deleted remote accounts forgotten; other activities
+// are ignored.
+func (uc *FederationUsecase) HandleInbox(ctx context.Context, r *http.Request, body []byte) error {
+	sig, err := httpsig.Parse(r)
+	if errors.Is(err, httpsig.ErrMissingSignature) {
+		return apperror.Unauthenticated("signature_required", "request must be signed")
+	}
+	if err != nil {
+		return apperror.Unauthenticated("invalid_signature", err.Error())
+	}
+	var activity activitypub.Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		return apperror.InvalidArgument("invalid_activity", "activity is not valid JSON")
+	}
+	if activity.Actor == "" || activity.Type == "" {
+		return apperror.InvalidArgument("invalid_activity", "activity must have a type and an actor")
+	}
+	if uc.blocked(activity.Actor) {
+		return apperror.PermissionDenied("domain_blocked", "the server of the actor is blocked")
+	}
+
+	// a deleted account can't be fetched anymore, its deletion is verified with the cached key if there is one
+	if activity.Type == activitypub.TypeDelete && activity.ObjectID() == activity.Actor {
+		actor, err := uc.repo.GetRemoteActor(ctx, activity.Actor)
+		if errors.Is(err, customerrors.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := uc.verify(r, sig, actor, body); err != nil {
+			return err
+		}
+		return uc.repo.DeleteRemoteActor(ctx, actor.ID)
+	}
+
+	actor, err := uc.remoteActor(ctx, activity.Actor, false)
+	if err != nil {
+		return err
+	}
+	if err := uc.verify(r, sig, actor, body); err != nil {
+		// the actor may have rotated its key since it was cached
+		if time.Since(actor.FetchedAt) < time.Minute {
+			return err
+		}
+		if actor, err = uc.remoteActor(ctx, activity.Actor, true); err != nil {
+			return err
+		}
+		if err := uc.verify(r, sig, actor, body); err != nil {
+			return err
+		}
+	}
+
+	switch activity.Type {
+	case activitypub.TypeFollow:
+		return uc.follow(ctx, actor, activity)
+	case activitypub.TypeUndo:
+		if activity.ObjectType() != activitypub.TypeFollow || activity.ObjectField("actor") != actor.ID {
+			return nil
+		}
+		userID, ok := uc.localUser(activity.ObjectField("object"))
+		if !ok {
+			return nil
+		}
+		return uc.repo.RemoveRemoteFollower(ctx, userID, actor.ID)
+	}
+	return nil
+}
+
+// verify checks that the request was signed with the key of the actor.
+func (uc *FederationUsecase) verify(r *http.Request, sig httpsig.Signature, actor entity.RemoteActor, body []byte) error {
+	if sig.KeyID != actor.KeyID {
+		return apperror.Unauthenticated("invalid_signature", "request isn't signed with the key of the actor")
+	}
+	key, err := activitypub.ParsePublicKey(actor.PublicKeyPEM)
+	if err != nil {
+		return apperror.Unauthenticated("invalid_signature", "the key of the actor can't be read")
+	}
+	if err := httpsig.Verify(r, sig, key, body, uc.cfg.SignatureSkew); err != nil {
+		return apperror.Unauthenticated("invalid_signature", err.Error())
+	}
+	return nil
+}
+
+// remoteActor returns the remote actor from the cache, or fetched from its server if it isn't cached, the cached
+// copy is older than the actor TTL or refresh is set.
+func (uc *FederationUsecase) remoteActor(ctx context.Context, actorID string, refresh bool) (entity.RemoteActor, error) {
+	if !refresh {
+		actor, err := uc.repo.GetRemoteActor(ctx, actorID)
+		if err == nil && time.Since(actor.FetchedAt) < uc.cfg.ActorTTL {
+			return actor, nil
+		}
+		if err != nil && !errors.Is(err, customerrors.ErrNotFound) {
+			return entity.RemoteActor{}, err
+		}
+	}
+
+	fetched, err := uc.client.FetchActor(ctx, actorID)
+	if err != nil {
+		var statusErr *activitypub.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 {
+			return entity.RemoteActor{}, apperror.Unauthenticated("unknown_actor", "the actor can't be fetched")
+		}
+		return entity.RemoteActor{}, fmt.Errorf("failed to fetch actor %s: %w", actorID, err)
+	}
+	// the document must be the actor's own, not one of another server claiming to be it
+	if fetched.ID != actorID || fetched.PublicKey.Owner != actorID {
+		return entity.RemoteActor{}, apperror.Unauthenticated("unknown_actor", "the fetched actor doesn't match")
+	}
+	actor := entity.RemoteActor{
+		ID:           fetched.ID,
+		Username:     fetched.PreferredUsername,
+		Inbox:        fetched.Inbox,
+		KeyID:        fetched.PublicKey.ID,
+		PublicKeyPEM: fetched.PublicKey.PublicKeyPem,
+		FetchedAt:    time.Now(),
+	}
+	if fetched.Endpoints != nil {
+		actor.SharedInbox = fetched.Endpoints.SharedInbox
+	}
+	if err := uc.repo.SaveRemoteActor(ctx, actor); err != nil {
+		return entity.RemoteActor{}, err
+	}
+	return actor, nil
+}
+
+// follow records the remote actor as a follower of the local user the Follow is for and accepts it.
+func (uc *FederationUsecase) follow(ctx context.Context, actor entity.RemoteActor, follow activitypub.Activity) error {
+	userID, ok := uc.localUser(follow.ObjectID())
+	if !ok {
+		return apperror.InvalidArgument("invalid_activity", "follow isn't for a local user")
+	}
+	if _, err := uc.repo.GetProfile(ctx, userID); err != nil {
+		return err
+	}
+	if err := uc.repo.AddRemoteFollower(ctx, userID, actor.ID, follow.ID); err != nil {
+		return err
+	}
+	accept := activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      uc.actorURL(userID) + "#accepts/" + uuid.NewString(),
+		Type:    activitypub.TypeAccept,
+		Actor:   uc.actorURL(userID),
+		Object: activitypub.Activity{
+			ID:     follow.ID,
+			Type:   activitypub.TypeFollow,
+			Actor:  actor.ID,
+			Object: uc.actorURL(userID),
+		},
+	}
+	return uc.enqueue(ctx, userID, []string{actor.Inbox}, accept)
+}
+
+// blocked reports whether the URL is on a blocked domain or one of its subdomains.
+func (uc *FederationUsecase) blocked(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range uc.cfg.BlockedDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue queues the activity of the user for delivery to the inboxes.
+func (uc *FederationUsecase) enqueue(ctx context.Context, userID uuid.UUID, inboxes []string, activity activitypub.Activity) error {
+	if len(inboxes) == 0 {
+		return nil
+	}
+	activity.Context = activitypub.Context
+	encoded, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return uc.repo.EnqueueDeliveries(ctx, userID, inboxes, encoded)
+}
+
+// broadcast queues the activity of the user for delivery to their remote followers if the user federates.
+// Failures are logged, the local action it federates already happened.
+func (uc *FederationUsecase) broadcast(ctx context.Context, userID uuid.UUID, activity activitypub.Activity) {
+	// the request may already be cancelled (e.g. client disconnected), the activity should be delivered anyway
+	ctx = context.WithoutCancel(ctx)
+	if _, err := uc.repo.GetProfile(ctx, userID); err != nil {
+		if !errors.Is(err, customerrors.ErrNotFound) {
+			uc.logger.Warn("Failed to federate activity", "user_id", userID, "type", activity.Type, "error", err)
+		}
+		return
+	}
+	inboxes, err := uc.repo.FollowerInboxes(ctx, userID)
+	if err == nil {
+		err = uc.enqueue(ctx, userID, inboxes, activity)
+	}
+	if err != nil {
+		uc.logger.Warn("Failed to federate activity", "user_id", userID, "type", activity.Type, "error", err)
+	}
+}
+
+// Publish delivers a new post to the remote followers of its author, if it is public and wasn't shadow-limited.
+func (uc *FederationUsecase) Publish(ctx context.Context, post entity.Post) {
+	if post.Visibility != entity.PostVisibilityPublic || post.Limited {
+		return
+	}
+	uc.broadcast(ctx, post.UserID, uc.create(post))
+}
+
+// Announce delivers the user's repost of a public post, or its undoing, to the user's remote followers.
+func (uc *FederationUsecase) Announce(ctx context.Context, userID, postID uuid.UUID, undo bool) {
+	if _, err := uc.repo.GetPublicPost(context.WithoutCancel(ctx), postID); err != nil {
+		return
+	}
+	activity := uc.announce(userID, postID)
+	if undo {
+		activity = uc.undo(userID, activity)
+	}
+	uc.broadcast(ctx, userID, activity)
+}
+
+// Delete tells the remote followers of the user that the post was deleted.
+func (uc *FederationUsecase) Delete(ctx context.Context, userID, postID uuid.UUID) {
+	uc.broadcast(ctx, userID, uc.deleteNote(userID, postID))
+}
+
+// DeliverActivities delivers the queued activities that are due and returns how many were delivered. Failed
+// deliveries are retried with exponential backoff until the maximum attempts; those a remote server refused
+// for good and those to blocked domains are dropped. It is run by a background job.
+func (uc *FederationUsecase) DeliverActivities(ctx context.Context) (delivered int, err error) {
+	deliveries, err := uc.repo.ClaimDeliveries(ctx, deliveryLease, uc.cfg.DeliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	keys := make(map[uuid.UUID]*rsa.PrivateKey)
+	var errs []error
+	for _, d := range deliveries {
+		if uc.blocked(d.Inbox) {
+			errs = appendErr(errs, uc.repo.DeleteDelivery(ctx, d.ID))
+			continue
+		}
+		key, ok := keys[d.UserID]
+		if !ok {
+			if key, _, err = uc.key(ctx, d.UserID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			keys[d.UserID] = key
+		}
+
+		err := uc.client.Deliver(ctx, d.Inbox, d.Activity, uc.keyID(d.UserID), key)
+		switch {
+		case err == nil:
+			delivered++
+			errs = appendErr(errs, uc.repo.DeleteDelivery(ctx, d.ID))
+		case ctx.Err() != nil:
+			// the claim lapses and the delivery is tried again
+			return delivered, errors.Join(append(errs, err)...)
+		case !retryable(err) || d.Attempts+1 >= uc.cfg.MaxAttempts:
+			uc.logger.Warn("Dropping federation delivery", "delivery_id", d.ID, "inbox", d.Inbox, "attempts", d.Attempts+1, "error", err)
+			errs = appendErr(errs, uc.repo.DeleteDelivery(ctx, d.ID))
+		default:
+			next := time.Now().Add(retryBackoff << min(d.Attempts, 16))
+			errs = appendErr(errs, uc.repo.RetryDelivery(ctx, d.ID, next, err.Error()))
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// retryable reports whether a failed delivery may succeed later: anything but a refusal of the remote server,
+// which is a 4xx answer other than a timeout or rate limit.
+func retryable(err error) bool {
+	var statusErr *activitypub.StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, activitypub.ErrForbiddenAddress)
+}
+
+func appendErr(errs []error, err error) []error {
+	if err != nil {
+		return append(errs, err)
+	}
+	return errs
+}
+
+// Nop is used when federation is off, nothing is delivered.
+type Nop struct{}
+
+func (Nop) Publish(context.Context, entity.Post)                 {}
+func (Nop) Announce(context.Context, uuid.UUID, uuid.UUID, bool) {}
+func (Nop) Delete(context.Context, uuid.UUID, uuid.UUID)         {}
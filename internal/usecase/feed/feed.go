@@ -0,0 +1,199 @@
+package feed
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/pkg/hashtag"
+	"main/pkg/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// trendingWindow is how far back posts are counted for trending hashtags
+	trendingWindow       = 24 * time.Hour
+	defaultTrendingLimit = 10
+	maxTrendingHashtags  = 50
+)
+
+// FeedRepo defines the interface for reading hashtag listings and rankings.
+type FeedRepo interface {
+	// ListHashtagPosts returns posts tagged with the hashtag that the viewer may see older than the given position, newest first.
+	ListHashtagPosts(ctx context.Context, viewerID uuid.UUID, tag string, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Post, error)
+
+	// TrendingHashtags returns the hashtags used by the most posts since the given time.
+	TrendingHashtags(ctx context.Context, since time.Time, limit int) ([]entity.TrendingHashtag, error)
+
+	// RefreshExploreRanking rebuilds the explore ranking from posts published since the given time.
+	RefreshExploreRanking(ctx context.Context, since time.Time) (int64, error)
+
+	// ListExplore returns ranked posts the viewer may see below the given position, highest score first.
+	ListExplore(ctx context.Context, viewerID uuid.UUID, beforeScore float64, beforeID uuid.UUID, limit int) ([]entity.RankedPost, error)
+}
+
+// Timelines defines the interface for reading home timelines.
+type Timelines interface {
+	// ListFeed returns posts published or reposted by the user and the accounts they follow
+	// older than the given position, newest first.
+	ListFeed(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int) ([]entity.FeedItem, error)
+}
+
+// FeatureFlags tells which gradually rolled out features are on for a user.
+type FeatureFlags interface {
+	Enabled(ctx context.Context, name string, userID uuid.UUID) bool
+}
+
+type FeedUsecase struct {
+	feedRepo   FeedRepo
+	timelines  Timelines
+	ranker     Ranker
+	flags      FeatureFlags
+	PostsCfg   config.PostsConfig
+	RankingCfg config.FeedRankingConfig
+}
+
+func NewFeedUsecase(feedRepo FeedRepo, timelines Timelines, ranker Ranker, flags FeatureFlags, postsCfg config.PostsConfig, rankingCfg config.FeedRankingConfig) *FeedUsecase {
+	return &FeedUsecase{
+		feedRepo:   feedRepo,
+		timelines:  timelines,
+		ranker:     ranker,
+		flags:      flags,
+		PostsCfg:   postsCfg,
+		RankingCfg: rankingCfg,
+	}
+}
+
+// GetFeed returns a page of the user's home timeline: posts published or reposted by the user and the accounts they follow,
+// newest first. An empty cursor starts from the newest entry; nextCursor fetches the following page and is empty on the last one.
+// For users with the feed_ranking feature flag the newest entries are ranked instead, see rankedFeed.
+func (uc *FeedUsecase) GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) (items []entity.FeedItem, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	// the flag is checked on the first page only, so a feed keeps its order when the flag changes while it is read
+	if after.Score > 0 || (cursor == "" && uc.flags.Enabled(ctx, entity.FeatureFeedRanking, userID)) {
+		return uc.rankedFeed(ctx, userID, after, limit)
+	}
+	return uc.chronologicalFeed(ctx, userID, after.CreatedAt, after.ID, limit)
+}
+
+func (uc *FeedUsecase) chronologicalFeed(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.FeedItem, string, error) {
+	// one extra entry tells whether there is a next page
+	items, err := uc.timelines.ListFeed(ctx, userID, beforeTime, beforeID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	items, nextCursor := pagination.Page(items, limit, func(item entity.FeedItem) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: item.At, ID: item.Post.ID}
+	})
+	return items, nextCursor, nil
+}
+
+// rankedFeed pages through the ranked candidates of the feed: the newest entries up to the candidate size, in the
+// order of the ranker. The cursors of ranked pages hold the offset into the ranking as score and the newest candidate
+// as position, so each page ranks the same candidates again while newer entries wait for the next first page.
+// After the candidates the feed continues with older entries newest first.
+func (uc *FeedUsecase) rankedFeed(ctx context.Context, userID uuid.UUID, after pagination.Cursor, limit int) ([]entity.FeedItem, string, error) {
+	beforeTime, beforeID := time.Time{}, uuid.Nil
+	if after.Score > 0 {
+		// uuid.Max includes every entry at the time of the newest candidate
+		beforeTime, beforeID = after.CreatedAt, uuid.Max
+	}
+	// one extra entry tells whether there are entries older than the candidates
+	candidates, err := uc.timelines.ListFeed(ctx, userID, beforeTime, beforeID, uc.RankingCfg.CandidateSize+1)
+	if err != nil {
+		return nil, "", err
+	}
+	older := len(candidates) > uc.RankingCfg.CandidateSize
+	candidates = candidates[:min(len(candidates), uc.RankingCfg.CandidateSize)]
+	if len(candidates) == 0 {
+		return nil, "", nil
+	}
+	oldest := candidates[len(candidates)-1]
+
+	start := min(int(after.Score), len(candidates))
+	if start == len(candidates) {
+		// candidates were deleted since the previous page
+		if !older {
+			return nil, "", nil
+		}
+		return uc.chronologicalFeed(ctx, userID, oldest.At, oldest.Post.ID, limit)
+	}
+	ranked, err := uc.ranker.Rank(ctx, userID, candidates)
+	if err != nil {
+		return nil, "", err
+	}
+	end := min(start+limit, len(ranked))
+	var next pagination.Cursor
+	switch {
+	case end < len(ranked):
+		next = pagination.Cursor{Score: float64(end), CreatedAt: candidates[0].At, ID: uuid.Max}
+	case older:
+		next = pagination.Cursor{CreatedAt: oldest.At, ID: oldest.Post.ID}
+	default:
+		return ranked[start:end], "", nil
+	}
+	return ranked[start:end], next.Encode(), nil
+}
+
+// GetHashtagPosts returns a page of posts tagged with the hashtag, newest first. The tag may start with '#'
+// and is matched case-insensitively. Only posts the viewer may see are listed, viewerID is uuid.Nil for anonymous viewers.
+// Cursors work like in GetFeed.
+func (uc *FeedUsecase) GetHashtagPosts(ctx context.Context, viewerID uuid.UUID, tag, cursor string, limit int) (posts []entity.Post, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	tag, ok := hashtag.Normalize(tag)
+	if !ok {
+		return nil, "", nil
+	}
+
+	posts, err = uc.feedRepo.ListHashtagPosts(ctx, viewerID, tag, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	posts, nextCursor = pagination.Page(posts, limit, func(post entity.Post) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+	})
+	return posts, nextCursor, nil
+}
+
+// TrendingHashtags returns the hashtags used by the most posts of the last 24 hours, most used first.
+func (uc *FeedUsecase) TrendingHashtags(ctx context.Context, limit int) ([]entity.TrendingHashtag, error) {
+	if limit <= 0 {
+		limit = defaultTrendingLimit
+	}
+	limit = min(limit, maxTrendingHashtags)
+	return uc.feedRepo.TrendingHashtags(ctx, time.Now().Add(-trendingWindow), limit)
+}
+
+// GetExplore returns a page of trending posts ranked by time decayed engagement, viewerID is uuid.Nil for anonymous viewers.
+// The ranking is rebuilt periodically by RefreshExplore. Cursors work like in GetFeed.
+func (uc *FeedUsecase) GetExplore(ctx context.Context, viewerID uuid.UUID, cursor string, limit int) (posts []entity.RankedPost, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	posts, err = uc.feedRepo.ListExplore(ctx, viewerID, after.Score, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	posts, nextCursor = pagination.Page(posts, limit, func(post entity.RankedPost) pagination.Cursor {
+		return pagination.Cursor{Score: post.Score, ID: post.Post.ID}
+	})
+	return posts, nextCursor, nil
+}
+
+// RefreshExplore rebuilds the explore ranking from posts of the configured window and returns how many were ranked.
+// It is run by a background job.
+func (uc *FeedUsecase) RefreshExplore(ctx context.Context) (int64, error) {
+	return uc.feedRepo.RefreshExploreRanking(ctx, time.Now().Add(-uc.PostsCfg.ExploreWindow))
+}
@@ -0,0 +1,109 @@
+package feed
+
+import (
+	"cmp"
+	"context"
+	"main/domain/entity"
+	"main/internal/config"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ranking algorithms of the home feed, selected by config.FeedRankingConfig.Algorithm.
+const (
+	AlgorithmChronological = "chronological"
+	AlgorithmWeighted      = "weighted"
+)
+
+// Ranker orders the candidate entries of a home feed page.
+type Ranker interface {
+	// Rank returns the candidates of the user's feed, best first. It must return the same order for the same
+	// candidates and signals, since every page of a feed ranks its candidates again.
+	Rank(ctx context.Context, userID uuid.UUID, candidates []entity.FeedItem) ([]entity.FeedItem, error)
+}
+
+// AffinityRepo defines the interface for reading how much a user interacts with other accounts.
+type AffinityRepo interface {
+	// Affinities returns how many of the authors' posts the user liked or commented on since the given time, keyed by author.
+	Affinities(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]int64, error)
+}
+
+// Chronological keeps the candidates newest first, as a baseline for other rankers.
+type Chronological struct{}
+
+func (Chronological) Rank(ctx context.Context, userID uuid.UUID, candidates []entity.FeedItem) ([]entity.FeedItem, error) {
+	return candidates, nil
+}
+
+// WeightedRanker scores an entry by recency * (1 + affinity) * (1 + engagement): recency halves every half-life,
+// affinity counts the user's likes and comments on the author and engagement the likes, reposts, quotes and
+// comments of the post, weighted like in the explore ranking. Counts are dampened logarithmically so a few
+// very popular posts or close friends don't take over the feed.
+type WeightedRanker struct {
+	affinities AffinityRepo
+	cfg        config.FeedRankingConfig
+}
+
+func NewWeightedRanker(affinities AffinityRepo, cfg config.FeedRankingConfig) *WeightedRanker {
+	return &WeightedRanker{
+		affinities: affinities,
+		cfg:        cfg,
+	}
+}
+
+func (r *WeightedRanker) Rank(ctx context.Context, userID uuid.UUID, candidates []entity.FeedItem) ([]entity.FeedItem, error) {
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+	var authorIDs []uuid.UUID
+	for _, item := range candidates {
+		authorIDs = append(authorIDs, item.Post.UserID)
+		if item.RepostedBy != nil {
+			authorIDs = append(authorIDs, *item.RepostedBy)
+		}
+	}
+	slices.SortFunc(authorIDs, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	affinities, err := r.affinities.Affinities(ctx, userID, slices.Compact(authorIDs), time.Now().Add(-r.cfg.AffinityWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	// entries are scored against the newest candidate instead of the current time, so the order of a feed
+	// doesn't change as its pages are read
+	newest := candidates[0].At
+	type scored struct {
+		item  entity.FeedItem
+		score float64
+	}
+	ranked := make([]scored, len(candidates))
+	for i, item := range candidates {
+		ranked[i] = scored{item: item, score: r.score(item, newest, affinities)}
+	}
+	// stable, so entries with the same score stay newest first
+	slices.SortStableFunc(ranked, func(a, b scored) int { return cmp.Compare(b.score, a.score) })
+	items := make([]entity.FeedItem, len(ranked))
+	for i, s := range ranked {
+		items[i] = s.item
+	}
+	return items, nil
+}
+
+func (r *WeightedRanker) score(item entity.FeedItem, newest time.Time, affinities map[uuid.UUID]int64) float64 {
+	age := max(newest.Sub(item.At), 0)
+	recency := math.Exp2(-age.Hours() / r.cfg.HalfLife.Hours())
+
+	// a repost is as close to the user as the closer of the author and the reposter
+	affinity := affinities[item.Post.UserID]
+	if item.RepostedBy != nil {
+		affinity = max(affinity, affinities[*item.RepostedBy])
+	}
+	post := item.Post
+	engagement := post.LikesCount + 2*post.RepostsCount + 2*post.QuotesCount + 3*post.CommentsCount
+
+	return recency *
+		(1 + r.cfg.AffinityWeight*math.Log1p(float64(affinity))) *
+		(1 + r.cfg.EngagementWeight*math.Log1p(float64(engagement)))
+}
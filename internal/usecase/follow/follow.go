@@ -0,0 +1,225 @@
+package follow
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FollowRepo defines the interface for the social graph storage.
+type FollowRepo interface {
+	// Follow records the follow, or a follow request for a private account, and reports the state it left and
+	// whether it changed, following twice changes nothing.
+	Follow(ctx context.Context, followerID, followeeID uuid.UUID) (entity.FollowState, bool, error)
+
+	// Unfollow removes the follow or the follow request and reports whether there was a follow.
+	Unfollow(ctx context.Context, followerID, followeeID uuid.UUID) (bool, error)
+
+	// Restricted reports whether the user's account is private and the viewer doesn't follow it.
+	Restricted(ctx context.Context, viewerID, userID uuid.UUID) (bool, error)
+
+	// ListFollowRequests returns the pending requests to follow the user made before the given position, newest first.
+	ListFollowRequests(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.FollowRequest, error)
+
+	// ApproveFollowRequest turns the request into a follow and reports whether the follow is new.
+	ApproveFollowRequest(ctx context.Context, userID, followerID uuid.UUID) (bool, error)
+
+	// RejectFollowRequest removes the request.
+	RejectFollowRequest(ctx context.Context, userID, followerID uuid.UUID) error
+
+	// ListFollowers returns the accounts following the user that followed before the given position, newest first.
+	ListFollowers(ctx context.Context, viewerID, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.FollowEntry, error)
+
+	// ListFollowing returns the accounts the user followed before the given position, newest first.
+	ListFollowing(ctx context.Context, viewerID, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.FollowEntry, error)
+}
+
+// Blacklist tells whether a user blocked another one.
+type Blacklist interface {
+	// IsBlocked reports whether the blocker blocked the user.
+	IsBlocked(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error)
+}
+
+// Counters keeps the follower and following counters.
+type Counters interface {
+	// Add changes the counter of id by delta, failures are repaired by a later reconciliation.
+	Add(ctx context.Context, counter entity.Counter, id uuid.UUID, delta int64)
+}
+
+// Timelines keeps the cached home timelines.
+type Timelines interface {
+	// Invalidate drops the cached timelines of the users, so they are built again.
+	Invalidate(ctx context.Context, userIDs ...uuid.UUID)
+}
+
+// Notifier tells users about what happened to them.
+type Notifier interface {
+	// Notify queues an event for the users it concerns.
+	Notify(ctx context.Context, event entity.NotificationEvent) error
+}
+
+type FollowUsecase struct {
+	followRepo FollowRepo
+	blacklist  Blacklist
+	notifier   Notifier
+	counters   Counters
+	timelines  Timelines
+}
+
+func NewFollowUsecase(followRepo FollowRepo, blacklist Blacklist, notifier Notifier, counters Counters, timelines Timelines) *FollowUsecase {
+	return &FollowUsecase{
+		followRepo: followRepo,
+		blacklist:  blacklist,
+		notifier:   notifier,
+		counters:   counters,
+		timelines:  timelines,
+	}
+}
+
+// Follow makes the user follow another account, following an already followed account succeeds.
+// A private account is only followed once its owner approves: until then the follow is a pending request
+// and entity.FollowStateRequested is returned. Accounts that blocked the user or that the user blocked can't be followed.
+func (uc *FollowUsecase) Follow(ctx context.Context, userID, followeeID uuid.UUID) (entity.FollowState, error) {
+	if userID == followeeID {
+		return "", apperror.InvalidArgument("self_follow", "you can't follow yourself")
+	}
+	blocked, err := uc.blacklist.IsBlocked(ctx, followeeID, userID)
+	if err != nil {
+		return "", err
+	}
+	if blocked {
+		return "", customerrors.ErrBlockedByUser
+	}
+	if blocked, err = uc.blacklist.IsBlocked(ctx, userID, followeeID); err != nil {
+		return "", err
+	}
+	if blocked {
+		return "", apperror.FailedPrecondition("user_blocked_by_you", "unblock the user to follow them")
+	}
+	state, changed, err := uc.followRepo.Follow(ctx, userID, followeeID)
+	if err != nil {
+		return "", err
+	}
+	if changed {
+		event := entity.NotificationEvent{Type: entity.NotificationFollow, ActorID: userID, UserID: &followeeID}
+		if state == entity.FollowStateRequested {
+			event.Type = entity.NotificationFollowRequest
+		} else {
+			uc.bumpCounters(ctx, userID, followeeID, 1)
+		}
+		// notifications are best effort, the follow is already stored; a repeated follow notifies nobody again
+		_ = uc.notifier.Notify(ctx, event)
+	}
+	return state, nil
+}
+
+// Unfollow stops the user following another account or withdraws the request to follow it,
+// unfollowing an account that isn't followed succeeds.
+func (uc *FollowUsecase) Unfollow(ctx context.Context, userID, followeeID uuid.UUID) error {
+	removed, err := uc.followRepo.Unfollow(ctx, userID, followeeID)
+	if err != nil {
+		return err
+	}
+	if removed {
+		uc.bumpCounters(ctx, userID, followeeID, -1)
+	}
+	return nil
+}
+
+// ListFollowRequests returns a page of the pending requests to follow the user, most recent first. An empty cursor
+// starts from the newest request; nextCursor fetches the following page and is empty on the last one.
+func (uc *FollowUsecase) ListFollowRequests(ctx context.Context, userID uuid.UUID, cursor string, limit int) (requests []entity.FollowRequest, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	// one extra request tells whether there is a next page
+	requests, err = uc.followRepo.ListFollowRequests(ctx, userID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	requests, nextCursor = pagination.Page(requests, limit, func(fr entity.FollowRequest) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: fr.RequestedAt, ID: fr.User.ID}
+	})
+	return requests, nextCursor, nil
+}
+
+// ApproveFollowRequest lets the follower follow the user's private account.
+// Returns customerrors.ErrNotFound if the follower didn't ask to follow the user.
+func (uc *FollowUsecase) ApproveFollowRequest(ctx context.Context, userID, followerID uuid.UUID) error {
+	followed, err := uc.followRepo.ApproveFollowRequest(ctx, userID, followerID)
+	if err != nil {
+		return err
+	}
+	if followed {
+		uc.bumpCounters(ctx, followerID, userID, 1)
+	}
+	return nil
+}
+
+// RejectFollowRequest turns down the follower's request to follow the user.
+// Returns customerrors.ErrNotFound if the follower didn't ask to follow the user.
+func (uc *FollowUsecase) RejectFollowRequest(ctx context.Context, userID, followerID uuid.UUID) error {
+	return uc.followRepo.RejectFollowRequest(ctx, userID, followerID)
+}
+
+// bumpCounters changes the following counter of the follower and the followers counter of the followee by delta.
+// The follower's timeline gains or loses the followee's posts, so it is built again.
+func (uc *FollowUsecase) bumpCounters(ctx context.Context, followerID, followeeID uuid.UUID, delta int64) {
+	uc.counters.Add(ctx, entity.CounterUserFollowing, followerID, delta)
+	uc.counters.Add(ctx, entity.CounterUserFollowers, followeeID, delta)
+	uc.timelines.Invalidate(ctx, followerID)
+}
+
+// ListFollowers returns a page of the accounts following the user, most recent follows first, viewerID is uuid.Nil
+// for anonymous viewers. The lists of a private account are only shown to the user and their followers,
+// others get customerrors.ErrPrivateAccount. An empty cursor starts from the newest follow;
+// nextCursor fetches the following page and is empty on the last one. Users who were blocked get customerrors.ErrNotFound.
+func (uc *FollowUsecase) ListFollowers(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) (entries []entity.FollowEntry, nextCursor string, err error) {
+	return uc.list(ctx, uc.followRepo.ListFollowers, viewerID, userID, cursor, limit)
+}
+
+// ListFollowing returns a page of the accounts the user follows, it works like ListFollowers.
+func (uc *FollowUsecase) ListFollowing(ctx context.Context, viewerID, userID uuid.UUID, cursor string, limit int) (entries []entity.FollowEntry, nextCursor string, err error) {
+	return uc.list(ctx, uc.followRepo.ListFollowing, viewerID, userID, cursor, limit)
+}
+
+type listFunc func(ctx context.Context, viewerID, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.FollowEntry, error)
+
+func (uc *FollowUsecase) list(ctx context.Context, list listFunc, viewerID, userID uuid.UUID, cursor string, limit int) (entries []entity.FollowEntry, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	blocked, err := uc.blacklist.IsBlocked(ctx, userID, viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if blocked {
+		return nil, "", customerrors.ErrNotFound
+	}
+	restricted, err := uc.followRepo.Restricted(ctx, viewerID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if restricted {
+		return nil, "", customerrors.ErrPrivateAccount
+	}
+
+	// one extra entry tells whether there is a next page
+	entries, err = list(ctx, viewerID, userID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	entries, nextCursor = pagination.Page(entries, limit, func(e entity.FollowEntry) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: e.FollowedAt, ID: e.User.ID}
+	})
+	return entries, nextCursor, nil
+}
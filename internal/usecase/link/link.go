@@ -0,0 +1,55 @@
+// Package link serves the shortened links of posts: redirects that count clicks, and the click counts their
+// authors see.
+package link
+
+import (
+	"context"
+	"main/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// LinkRepo defines the interface for the shortened links of posts.
+type LinkRepo interface {
+	// GetLink returns the ID and the target of the shortened link with the code if anyone may see its post.
+	GetLink(ctx context.Context, code string) (uuid.UUID, string, error)
+
+	// ListPostLinks returns the links of a post of the user with their clicks.
+	ListPostLinks(ctx context.Context, userID, postID uuid.UUID) ([]entity.LinkClicks, error)
+}
+
+// Counters keeps the click counters.
+type Counters interface {
+	// Add changes the counter of id by delta, clicks lost to a failure aren't recounted.
+	Add(ctx context.Context, counter entity.Counter, id uuid.UUID, delta int64)
+}
+
+type LinkUsecase struct {
+	repo     LinkRepo
+	counters Counters
+}
+
+func NewLinkUsecase(repo LinkRepo, counters Counters) *LinkUsecase {
+	return &LinkUsecase{
+		repo:     repo,
+		counters: counters,
+	}
+}
+
+// Follow returns the target of the shortened link with the code and counts the click. Links of posts that
+// aren't visible to anonymous viewers, like deleted, followers-only or private account posts, are
+// customerrors.ErrNotFound.
+func (uc *LinkUsecase) Follow(ctx context.Context, code string) (string, error) {
+	id, url, err := uc.repo.GetLink(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	uc.counters.Add(ctx, entity.CounterLinkClicks, id, 1)
+	return url, nil
+}
+
+// PostLinks returns the links of one of the user's posts with their clicks, customerrors.ErrNotFound if the
+// post isn't the user's. Clicks not yet written by the counters are missing until the next flush.
+func (uc *LinkUsecase) PostLinks(ctx context.Context, userID, postID uuid.UUID) ([]entity.LinkClicks, error) {
+	return uc.repo.ListPostLinks(ctx, userID, postID)
+}
@@ -0,0 +1,112 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	ctxUtil "main/pkg/utils/context"
+
+	"github.com/google/uuid"
+)
+
+// KeyRotator defines the interface for switching the access token signing key.
+type KeyRotator interface {
+	// Rotate stores a new signing key every instance switches to and returns its ID, the previous key stays
+	// accepted until its tokens expire.
+	Rotate(ctx context.Context) (string, error)
+
+	// KeyID returns the ID of the current signing key.
+	KeyID() string
+}
+
+// Counters defines the interface for repairing denormalized counters.
+type Counters interface {
+	// ReconcilePosts recounts likes, reposts, quotes and comments of posts whose counters drifted.
+	ReconcilePosts(ctx context.Context) (int64, error)
+
+	// ReconcileFollows recounts followers and followings of profiles whose counters drifted.
+	ReconcileFollows(ctx context.Context) (int64, error)
+}
+
+// ExploreRanking defines the interface for rebuilding the explore ranking.
+type ExploreRanking interface {
+	// RefreshExplore rebuilds the explore ranking and returns how many posts were ranked.
+	RefreshExplore(ctx context.Context) (int64, error)
+}
+
+// AuditRecorder defines the interface for recording security-relevant events.
+type AuditRecorder interface {
+	Record(ctx context.Context, eventType entity.AuditEventType, actorID, subjectID uuid.UUID, metadata map[string]any)
+}
+
+// Names of the backfills RunBackfill knows.
+const (
+	BackfillPostCounters   = "post_counters"
+	BackfillFollowCounters = "follow_counters"
+	BackfillExploreRanking = "explore_ranking"
+)
+
+// MaintenanceUsecase runs operational tasks on demand that otherwise only happen in background jobs.
+type MaintenanceUsecase struct {
+	keys     KeyRotator
+	counters Counters
+	explore  ExploreRanking
+	audit    AuditRecorder
+}
+
+func NewMaintenanceUsecase(keys KeyRotator, counters Counters, explore ExploreRanking, audit AuditRecorder) *MaintenanceUsecase {
+	return &MaintenanceUsecase{
+		keys:     keys,
+		counters: counters,
+		explore:  explore,
+		audit:    audit,
+	}
+}
+
+// RotateSigningKey switches every instance to a new signing key and returns its ID. The key signs once every
+// instance loaded it, within two key sync intervals. Only asymmetric keys can be rotated.
+func (uc *MaintenanceUsecase) RotateSigningKey(ctx context.Context) (string, error) {
+	previous := uc.keys.KeyID()
+	keyID, err := uc.keys.Rotate(ctx)
+	if err != nil {
+		return "", err
+	}
+	uc.audit.Record(ctx, entity.AuditKeyRotate, actorFromContext(ctx), uuid.Nil,
+		map[string]any{"key_id": keyID, "previous_key_id": previous})
+	return keyID, nil
+}
+
+// Backfills returns the names of the backfills RunBackfill knows.
+func (uc *MaintenanceUsecase) Backfills() []string {
+	return []string{BackfillPostCounters, BackfillFollowCounters, BackfillExploreRanking}
+}
+
+// RunBackfill runs the named backfill and returns how many rows it changed.
+func (uc *MaintenanceUsecase) RunBackfill(ctx context.Context, name string) (int64, error) {
+	var (
+		count int64
+		err   error
+	)
+	switch name {
+	case BackfillPostCounters:
+		count, err = uc.counters.ReconcilePosts(ctx)
+	case BackfillFollowCounters:
+		count, err = uc.counters.ReconcileFollows(ctx)
+	case BackfillExploreRanking:
+		count, err = uc.explore.RefreshExplore(ctx)
+	default:
+		return 0, apperror.InvalidArgument("unknown_backfill", fmt.Sprintf("unknown backfill %q, known backfills are %v", name, uc.Backfills()))
+	}
+	if err != nil {
+		return count, err
+	}
+	uc.audit.Record(ctx, entity.AuditBackfill, actorFromContext(ctx), uuid.Nil, map[string]any{"name": name, "count": count})
+	return count, nil
+}
+
+// actorFromContext returns the user performing the request, uuid.Nil if there is none.
+func actorFromContext(ctx context.Context) uuid.UUID {
+	actorID, _ := ctxUtil.UserIDFromContext(ctx)
+	return actorID
+}
@@ -0,0 +1,63 @@
+package moderation
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	"main/pkg/moderation"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ModerationRepo defines the interface for the review queue of content.
+type ModerationRepo interface {
+	// AddFlag queues content for review.
+	AddFlag(ctx context.Context, flag entity.ContentFlag) error
+}
+
+type ModerationUsecase struct {
+	repo   ModerationRepo
+	policy moderation.Policy
+}
+
+func NewModerationUsecase(repo ModerationRepo, policy moderation.Policy) *ModerationUsecase {
+	return &ModerationUsecase{
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+// Check runs the content policy on text the author is about to publish. It returns customerrors.ErrContentRejected
+// if the text must not be published, otherwise the flag to pass to Flag once the content is stored; its verdict
+// is empty if the policy had no objection.
+func (uc *ModerationUsecase) Check(ctx context.Context, kind entity.ContentKind, authorID uuid.UUID, text string) (entity.ContentFlag, error) {
+	flag := entity.ContentFlag{Kind: kind, AuthorID: authorID}
+	if text == "" {
+		return flag, nil
+	}
+
+	// policies that fail are skipped, an unavailable classifier doesn't stop users from posting
+	decision, _ := uc.policy.Check(ctx, moderation.Content{Kind: string(kind), AuthorID: authorID.String(), Text: text})
+	switch decision.Verdict {
+	case moderation.Reject:
+		return flag, customerrors.ErrContentRejected
+	case moderation.Limit:
+		flag.Verdict = entity.ModerationLimit
+	case moderation.Flag:
+		flag.Verdict = entity.ModerationFlag
+	}
+	flag.Reason = decision.Reason
+	return flag, nil
+}
+
+// Flag queues the stored content for review if the policy flagged or limited it.
+func (uc *ModerationUsecase) Flag(ctx context.Context, flag entity.ContentFlag, contentID uuid.UUID) error {
+	if flag.Verdict == "" {
+		return nil
+	}
+	flag.ID = uuid.New()
+	flag.ContentID = contentID
+	flag.CreatedAt = time.Now()
+	return uc.repo.AddFlag(ctx, flag)
+}
@@ -0,0 +1,207 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"main/domain/entity"
+	"main/pkg/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// eventBatchSize is the maximum number of events a worker takes at once.
+	eventBatchSize = 100
+	// eventLease is how long a worker holds the events it took, they are retried by any worker after it.
+	eventLease = time.Minute
+	// maxEventAttempts is how many times an event is tried before it's dropped.
+	maxEventAttempts = 5
+)
+
+// NotificationRepo defines the interface for the notification queue and the notifications of users.
+type NotificationRepo interface {
+	// AddEvent queues an event for the fan-out workers.
+	AddEvent(ctx context.Context, event entity.NotificationEvent) error
+
+	// ClaimEvents takes up to limit queued events for the lease duration.
+	ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]entity.NotificationEvent, error)
+
+	// DeleteEvents removes events from the queue.
+	DeleteEvents(ctx context.Context, ids []int64) error
+
+	// Recipients returns the users each event concerns.
+	Recipients(ctx context.Context, events []entity.NotificationEvent) ([][]uuid.UUID, error)
+
+	// AddNotifications notifies the recipients of each event who want to hear about it and returns the users who
+	// were notified per event.
+	AddNotifications(ctx context.Context, events []entity.NotificationEvent, recipients [][]uuid.UUID) ([][]uuid.UUID, error)
+
+	// ListNotifications returns the notifications of the user created before the given position, newest first.
+	ListNotifications(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Notification, error)
+
+	// ListNotificationsAfter returns the notifications of the user created after the given position, oldest first.
+	ListNotificationsAfter(ctx context.Context, userID uuid.UUID, afterTime time.Time, afterID uuid.UUID, limit int) ([]entity.Notification, error)
+
+	// CountUnread returns the number of unread notifications of the user.
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+
+	// MarkRead marks a notification of the user read.
+	MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error
+
+	// MarkAllRead marks every notification of the user read.
+	MarkAllRead(ctx context.Context, userID uuid.UUID) error
+}
+
+// NotificationEvents wakes up the notification streams of users.
+type NotificationEvents interface {
+	// Publish tells the streams of the users that they have new notifications.
+	Publish(ctx context.Context, userIDs []uuid.UUID) error
+	// Subscribe returns the signals of the user until cancel is called.
+	Subscribe(userID uuid.UUID) (signals <-chan struct{}, cancel func())
+}
+
+// Pusher delivers notifications to the devices of users who aren't in the app.
+type Pusher interface {
+	// Push sends the notification about the event to the devices of the offline recipients.
+	Push(ctx context.Context, event entity.NotificationEvent, recipients []uuid.UUID) error
+}
+
+type NotificationUsecase struct {
+	repo   NotificationRepo
+	events NotificationEvents
+	pusher Pusher
+}
+
+func NewNotificationUsecase(repo NotificationRepo, events NotificationEvents, pusher Pusher) *NotificationUsecase {
+	return &NotificationUsecase{
+		repo:   repo,
+		events: events,
+		pusher: pusher,
+	}
+}
+
+// Notify queues an event, the fan-out workers turn it into notifications of the users it concerns.
+func (uc *NotificationUsecase) Notify(ctx context.Context, event entity.NotificationEvent) error {
+	return uc.repo.AddEvent(ctx, event)
+}
+
+// ProcessEvents fans out queued events until the queue is drained and returns the number of notifications created.
+// Several workers can run it at once, each takes its own events. Events that fail are retried after their lease
+// expires and dropped after maxEventAttempts tries.
+func (uc *NotificationUsecase) ProcessEvents(ctx context.Context) (notified int64, err error) {
+	var errs []error
+	for {
+		events, err := uc.repo.ClaimEvents(ctx, eventBatchSize, eventLease)
+		if err != nil {
+			return notified, errors.Join(append(errs, err)...)
+		}
+		// failed events stay leased, so an empty batch means everything was taken
+		if len(events) == 0 {
+			return notified, errors.Join(errs...)
+		}
+		var dropped []int64
+		pending := events[:0]
+		for _, event := range events {
+			if event.Attempts > maxEventAttempts {
+				dropped = append(dropped, event.ID)
+				continue
+			}
+			pending = append(pending, event)
+		}
+		if len(dropped) > 0 {
+			errs = append(errs, uc.repo.DeleteEvents(ctx, dropped))
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		n, err := uc.fanOut(ctx, pending)
+		if err != nil {
+			// one event failing fails the whole batch, the events are retried one by one so the others go through
+			for _, event := range pending {
+				n, err := uc.fanOut(ctx, []entity.NotificationEvent{event})
+				notified += n
+				errs = append(errs, err)
+			}
+			continue
+		}
+		notified += n
+	}
+}
+
+// fanOut notifies the recipients of the events and removes the events from the queue, the statements of all
+// events are sent in batches.
+func (uc *NotificationUsecase) fanOut(ctx context.Context, events []entity.NotificationEvent) (int64, error) {
+	recipients, err := uc.repo.Recipients(ctx, events)
+	if err != nil {
+		return 0, err
+	}
+	notified, err := uc.repo.AddNotifications(ctx, events, recipients)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		// streams and pushes are best effort, the notifications are stored and a retried event wouldn't notify anyone again;
+		// streams also check for notifications periodically
+		_ = uc.events.Publish(ctx, notified[i])
+		_ = uc.pusher.Push(ctx, event, notified[i])
+		count += int64(len(notified[i]))
+		ids[i] = event.ID
+	}
+	return count, uc.repo.DeleteEvents(ctx, ids)
+}
+
+// ListNotifications returns a page of the user's notifications, newest first. An empty cursor starts from the newest
+// notification; nextCursor fetches older ones and is empty on the last page.
+func (uc *NotificationUsecase) ListNotifications(ctx context.Context, userID uuid.UUID, cursor string, limit int) (notifications []entity.Notification, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one extra notification tells whether there is a next page
+	notifications, err = uc.repo.ListNotifications(ctx, userID, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	notifications, nextCursor = pagination.Page(notifications, limit, func(n entity.Notification) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+	})
+	return notifications, nextCursor, nil
+}
+
+// WatchNotifications returns a signal whenever the user gets new notifications until cancel is called.
+// Signals that weren't consumed yet are merged, the notifications are read with NotificationsAfter.
+func (uc *NotificationUsecase) WatchNotifications(userID uuid.UUID) (<-chan struct{}, func()) {
+	return uc.events.Subscribe(userID)
+}
+
+// NotificationsAfter returns up to limit of the user's notifications created after the cursor position, oldest first.
+// The cursor of a notification is its pagination cursor, an empty cursor starts from the oldest notification.
+func (uc *NotificationUsecase) NotificationsAfter(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]entity.Notification, error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.ListNotificationsAfter(ctx, userID, after.CreatedAt, after.ID, limit)
+}
+
+// CountUnread returns the number of the user's unread notifications, the badge count.
+func (uc *NotificationUsecase) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	return uc.repo.CountUnread(ctx, userID)
+}
+
+// MarkRead marks one of the user's notifications read.
+func (uc *NotificationUsecase) MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	return uc.repo.MarkRead(ctx, userID, notificationID)
+}
+
+// MarkAllRead marks all of the user's notifications read.
+func (uc *NotificationUsecase) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
+	return uc.repo.MarkAllRead(ctx, userID)
+}
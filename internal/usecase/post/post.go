@@ -0,0 +1,454 @@
+package post
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"main/domain/entity"
+	"main/internal/config"
+	"main/pkg/apperror"
+	"main/pkg/hashtag"
+	"main/pkg/link"
+	"main/pkg/media"
+	"main/pkg/mention"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxDescriptionLength is the maximum length of a post description in characters.
+const maxDescriptionLength = 500
+
+// PostRepo defines the interface for post storage.
+type PostRepo interface {
+	// CreatePost stores a new post with its hashtags, bumping the quotes counter of the original for a quote post.
+	// The original must be visible to the post's author.
+	CreatePost(ctx context.Context, post entity.Post) error
+
+	// ImportPost stores a post brought over from another platform and reports whether it wasn't stored before.
+	ImportPost(ctx context.Context, post entity.Post) (bool, error)
+
+	// GetPost returns the post if the viewer may see it.
+	GetPost(ctx context.Context, viewerID, id uuid.UUID) (entity.Post, error)
+
+	// GetPosts returns the posts of the IDs the viewer may see, in no particular order.
+	GetPosts(ctx context.Context, viewerID uuid.UUID, ids []uuid.UUID) ([]entity.Post, error)
+
+	// LikedPosts returns which of the posts the user liked.
+	LikedPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) ([]uuid.UUID, error)
+
+	// UpdatePost replaces the description, the hashtags and a non-empty visibility of a post of the user at the
+	// version and returns the updated post.
+	UpdatePost(ctx context.Context, userID, postID uuid.UUID, version int64, description string, visibility entity.PostVisibility, hashtags []string, links []entity.PostLink) (entity.Post, error)
+
+	// DeletePost marks a post of the user deleted, it can be restored until it is purged.
+	DeletePost(ctx context.Context, userID, postID uuid.UUID) error
+
+	// RestorePost undeletes a post of the user deleted since the given time.
+	RestorePost(ctx context.Context, userID, postID uuid.UUID, deletedSince time.Time) error
+
+	// RemovePost deletes a post for good, whether or not it was deleted before.
+	RemovePost(ctx context.Context, postID uuid.UUID) error
+
+	// PurgeDeletedPosts deletes for good the posts deleted before the given time and returns how many were purged.
+	PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	// LikePost records the like and reports whether the post wasn't liked before, liking twice changes nothing.
+	LikePost(ctx context.Context, userID, postID uuid.UUID) (bool, error)
+
+	// UnlikePost removes the like and reports whether there was one.
+	UnlikePost(ctx context.Context, userID, postID uuid.UUID) (bool, error)
+
+	// Repost shares the post with the user's followers at the given time and reports whether it wasn't reposted before.
+	Repost(ctx context.Context, userID, postID uuid.UUID, at time.Time) (bool, error)
+
+	// Unrepost removes the repost and reports whether there was one.
+	Unrepost(ctx context.Context, userID, postID uuid.UUID) (bool, error)
+}
+
+// Counters keeps the denormalized counters of posts.
+type Counters interface {
+	// Add changes the counter of id by delta, failures are repaired by a later reconciliation.
+	Add(ctx context.Context, counter entity.Counter, id uuid.UUID, delta int64)
+}
+
+// Timelines keeps the cached home timelines.
+type Timelines interface {
+	// Publish adds a new entry of the author to their timeline and, if followers is set, to those of their followers.
+	Publish(ctx context.Context, authorID uuid.UUID, entry entity.TimelineEntry, followers bool)
+	// Retract removes an entry of the author from their timeline and those of their followers.
+	Retract(ctx context.Context, authorID uuid.UUID, entry entity.TimelineEntry)
+}
+
+// Federation shares posts with the followers of their authors on other servers.
+type Federation interface {
+	// Publish delivers a new post, only public posts are federated.
+	Publish(ctx context.Context, post entity.Post)
+	// Announce delivers the user's repost of the post, or its undoing.
+	Announce(ctx context.Context, userID, postID uuid.UUID, undo bool)
+	// Delete tells the other servers that the post was deleted.
+	Delete(ctx context.Context, userID, postID uuid.UUID)
+}
+
+// MediaStore defines the interface for keeping uploaded media files.
+type MediaStore interface {
+	// Put stores the file under the key and returns the URL it is served from.
+	Put(ctx context.Context, key, contentType string, r io.Reader, size int64) (string, error)
+	// Delete removes the file.
+	Delete(ctx context.Context, key string) error
+}
+
+// Notifier tells users about what happened to them.
+type Notifier interface {
+	// Notify queues an event for the users it concerns.
+	Notify(ctx context.Context, event entity.NotificationEvent) error
+}
+
+// ContentPolicy checks user content before it's published.
+type ContentPolicy interface {
+	// Check returns customerrors.ErrContentRejected if the text must not be published, otherwise its review flag.
+	Check(ctx context.Context, kind entity.ContentKind, authorID uuid.UUID, text string) (entity.ContentFlag, error)
+	// Flag queues the stored content for review if its flag asks for it.
+	Flag(ctx context.Context, flag entity.ContentFlag, contentID uuid.UUID) error
+}
+
+type PostUsecase struct {
+	postRepo   PostRepo
+	Media      MediaStore
+	MediaCfg   config.MediaConfig
+	notifier   Notifier
+	policy     ContentPolicy
+	counters   Counters
+	timelines  Timelines
+	federation Federation
+	// undeleteWindow is how long a deleted post can be restored before it is purged
+	undeleteWindow time.Duration
+}
+
+func NewPostUsecase(postRepo PostRepo, mediaStore MediaStore, mediaCfg config.MediaConfig, notifier Notifier, policy ContentPolicy, counters Counters, timelines Timelines, federation Federation, undeleteWindow time.Duration) *PostUsecase {
+	return &PostUsecase{
+		postRepo:       postRepo,
+		Media:          mediaStore,
+		MediaCfg:       mediaCfg,
+		notifier:       notifier,
+		policy:         policy,
+		counters:       counters,
+		timelines:      timelines,
+		federation:     federation,
+		undeleteWindow: undeleteWindow,
+	}
+}
+
+// CreatePost publishes a post of the user, it needs a description or an attached media URL.
+// A quoteOfID other than uuid.Nil makes it a quote post of that post, customerrors.ErrNotFound is returned if the
+// user can't see the original. An empty visibility makes the post public.
+// The description must pass the content policy, which may also shadow-limit the post.
+func (uc *PostUsecase) CreatePost(ctx context.Context, userID uuid.UUID, description, mediaURL string, quoteOfID uuid.UUID, visibility entity.PostVisibility) (entity.Post, error) {
+	description = strings.TrimSpace(description)
+	if err := validateDescription(description); err != nil {
+		return entity.Post{}, err
+	}
+	visibility, err := validateVisibility(visibility)
+	if err != nil {
+		return entity.Post{}, err
+	}
+	if description == "" && mediaURL == "" {
+		return entity.Post{}, apperror.InvalidArgument("empty_post", "post must have a description or media")
+	}
+	flag, err := uc.policy.Check(ctx, entity.ContentPost, userID, description)
+	if err != nil {
+		return entity.Post{}, err
+	}
+
+	now := time.Now()
+	post := entity.Post{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Description: description,
+		MediaURL:    mediaURL,
+		Visibility:  visibility,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Hashtags:    hashtag.Extract(description),
+		Links:       extractLinks(description),
+		Limited:     flag.Limited(),
+	}
+	if quoteOfID != uuid.Nil {
+		post.QuoteOfID = &quoteOfID
+	}
+	if err := uc.postRepo.CreatePost(ctx, post); err != nil {
+		return entity.Post{}, err
+	}
+	uc.published(ctx, post, flag)
+	return post, nil
+}
+
+// CreateVideoPost publishes a post of the user with an uploaded MP4/QuickTime video of the given size in bytes.
+// The duration is read from the file, videos longer or larger than configured are rejected.
+func (uc *PostUsecase) CreateVideoPost(ctx context.Context, userID uuid.UUID, description string, visibility entity.PostVisibility, video io.ReadSeeker, size int64) (entity.Post, error) {
+	description = strings.TrimSpace(description)
+	if err := validateDescription(description); err != nil {
+		return entity.Post{}, err
+	}
+	visibility, err := validateVisibility(visibility)
+	if err != nil {
+		return entity.Post{}, err
+	}
+	if size > uc.MediaCfg.MaxVideoSize {
+		return entity.Post{}, apperror.InvalidArgument("video_too_large", fmt.Sprintf("video must be at most %d MB", uc.MediaCfg.MaxVideoSize>>20))
+	}
+	duration, err := media.ProbeDuration(video, size)
+	if err != nil {
+		return entity.Post{}, err
+	}
+	if duration > uc.MediaCfg.MaxVideoDuration {
+		return entity.Post{}, apperror.InvalidArgument("video_too_long", fmt.Sprintf("video must be at most %s long", uc.MediaCfg.MaxVideoDuration))
+	}
+	flag, err := uc.policy.Check(ctx, entity.ContentPost, userID, description)
+	if err != nil {
+		return entity.Post{}, err
+	}
+	if _, err := video.Seek(0, io.SeekStart); err != nil {
+		return entity.Post{}, err
+	}
+
+	now := time.Now()
+	post := entity.Post{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Description: description,
+		IsVideo:     true,
+		Visibility:  visibility,
+		Duration:    int(math.Ceil(duration.Seconds())),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Hashtags:    hashtag.Extract(description),
+		Links:       extractLinks(description),
+		Limited:     flag.Limited(),
+	}
+	key := "videos/" + post.ID.String() + ".mp4"
+	if post.MediaURL, err = uc.Media.Put(ctx, key, "video/mp4", video, size); err != nil {
+		return entity.Post{}, fmt.Errorf("failed to store video: %w", err)
+	}
+	if err := uc.postRepo.CreatePost(ctx, post); err != nil {
+		// the post doesn't exist, so nothing references the uploaded file
+		_ = uc.Media.Delete(context.WithoutCancel(ctx), key)
+		return entity.Post{}, err
+	}
+	uc.published(ctx, post, flag)
+	return post, nil
+}
+
+// ImportPost publishes a post of the user brought over from another platform with the ID and the time it was
+// originally published at, and reports whether it wasn't imported before. It is checked like a new post but
+// only added to the author's own timeline, and mentions aren't notified: it is old news to followers.
+func (uc *PostUsecase) ImportPost(ctx context.Context, userID, postID uuid.UUID, description string, createdAt time.Time) (bool, error) {
+	description = strings.TrimSpace(description)
+	if err := validateDescription(description); err != nil {
+		return false, err
+	}
+	if description == "" {
+		return false, apperror.InvalidArgument("empty_post", "post must have a description or media")
+	}
+	flag, err := uc.policy.Check(ctx, entity.ContentPost, userID, description)
+	if err != nil {
+		return false, err
+	}
+
+	post := entity.Post{
+		ID:          postID,
+		UserID:      userID,
+		Description: description,
+		Visibility:  entity.PostVisibilityPublic,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		Hashtags:    hashtag.Extract(description),
+		Links:       extractLinks(description),
+		Limited:     flag.Limited(),
+	}
+	imported, err := uc.postRepo.ImportPost(ctx, post)
+	if err != nil || !imported {
+		return false, err
+	}
+	_ = uc.policy.Flag(ctx, flag, post.ID)
+	uc.timelines.Publish(ctx, post.UserID, entity.TimelineEntry{PostID: post.ID, At: post.CreatedAt}, false)
+	return true, nil
+}
+
+// published queues a new post the content policy objected to for review, adds it to the timelines, notifies
+// the mentioned users and federates it. Followers don't get shadow-limited and private posts.
+func (uc *PostUsecase) published(ctx context.Context, post entity.Post, flag entity.ContentFlag) {
+	_ = uc.policy.Flag(ctx, flag, post.ID)
+	followers := !post.Limited && post.Visibility != entity.PostVisibilityPrivate
+	uc.timelines.Publish(ctx, post.UserID, entity.TimelineEntry{PostID: post.ID, At: post.CreatedAt}, followers)
+	if !post.Limited {
+		uc.notifyMentions(ctx, post)
+	}
+	uc.federation.Publish(ctx, post)
+}
+
+// notifyMentions tells the users mentioned in the post's description about it.
+func (uc *PostUsecase) notifyMentions(ctx context.Context, post entity.Post) {
+	mentions := mention.Extract(post.Description)
+	if len(mentions) == 0 {
+		return
+	}
+	_ = uc.notifier.Notify(ctx, entity.NotificationEvent{Type: entity.NotificationMention, ActorID: post.UserID, PostID: &post.ID, Mentions: mentions})
+}
+
+// GetPost returns the post if the viewer may see it, viewerID is uuid.Nil for anonymous viewers.
+// Hidden posts are reported as not found.
+func (uc *PostUsecase) GetPost(ctx context.Context, viewerID, postID uuid.UUID) (entity.Post, error) {
+	return uc.postRepo.GetPost(ctx, viewerID, postID)
+}
+
+// GetPosts returns the posts of the IDs the viewer may see, hidden and missing posts are left out.
+// The order of the posts is unspecified.
+func (uc *PostUsecase) GetPosts(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) ([]entity.Post, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+	return uc.postRepo.GetPosts(ctx, viewerID, postIDs)
+}
+
+// LikedPosts returns which of the posts the user liked, none for anonymous viewers.
+func (uc *PostUsecase) LikedPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if userID == uuid.Nil || len(postIDs) == 0 {
+		return nil, nil
+	}
+	return uc.postRepo.LikedPosts(ctx, userID, postIDs)
+}
+
+// UpdatePost edits the description of one of the user's posts, its hashtags and links are extracted again. Links
+// the post already had keep their short codes and clicks.
+// The visibility is changed unless it is empty. The update is made on the version of the post the user saw,
+// customerrors.ErrVersionConflict is returned if the post was edited since.
+func (uc *PostUsecase) UpdatePost(ctx context.Context, userID, postID uuid.UUID, version int64, description string, visibility entity.PostVisibility) (entity.Post, error) {
+	description = strings.TrimSpace(description)
+	if err := validateDescription(description); err != nil {
+		return entity.Post{}, err
+	}
+	if visibility != "" && !visibility.Valid() {
+		return entity.Post{}, apperror.InvalidArgument("invalid_visibility", "visibility must be public, followers, close_friends or private")
+	}
+	return uc.postRepo.UpdatePost(ctx, userID, postID, version, description, visibility, hashtag.Extract(description), extractLinks(description))
+}
+
+// DeletePost deletes one of the user's posts. The user can restore it within the undelete window, then it is purged.
+func (uc *PostUsecase) DeletePost(ctx context.Context, userID, postID uuid.UUID) error {
+	if err := uc.postRepo.DeletePost(ctx, userID, postID); err != nil {
+		return err
+	}
+	uc.federation.Delete(ctx, userID, postID)
+	return nil
+}
+
+// RestorePost undeletes one of the user's posts deleted within the undelete window.
+func (uc *PostUsecase) RestorePost(ctx context.Context, userID, postID uuid.UUID) error {
+	return uc.postRepo.RestorePost(ctx, userID, postID, time.Now().Add(-uc.undeleteWindow))
+}
+
+// RemovePost deletes a post for good, e.g. when it's taken down, so its author can't restore it.
+func (uc *PostUsecase) RemovePost(ctx context.Context, postID uuid.UUID) error {
+	// the author is only needed to federate the removal, posts the anonymous viewer can't see weren't federated
+	post, lookupErr := uc.postRepo.GetPost(ctx, uuid.Nil, postID)
+	if err := uc.postRepo.RemovePost(ctx, postID); err != nil {
+		return err
+	}
+	if lookupErr == nil {
+		uc.federation.Delete(ctx, post.UserID, postID)
+	}
+	return nil
+}
+
+// PurgeDeletedPosts deletes for good the posts deleted longer than the undelete window ago and returns how many
+// were purged. It is run by a scheduled job.
+func (uc *PostUsecase) PurgeDeletedPosts(ctx context.Context) (int64, error) {
+	return uc.postRepo.PurgeDeletedPosts(ctx, time.Now().Add(-uc.undeleteWindow))
+}
+
+// LikePost likes the post on behalf of the user, liking an already liked post succeeds.
+func (uc *PostUsecase) LikePost(ctx context.Context, userID, postID uuid.UUID) error {
+	liked, err := uc.postRepo.LikePost(ctx, userID, postID)
+	if err != nil {
+		return err
+	}
+	if liked {
+		uc.counters.Add(ctx, entity.CounterPostLikes, postID, 1)
+		// a repeated like notifies nobody again
+		_ = uc.notifier.Notify(ctx, entity.NotificationEvent{Type: entity.NotificationLike, ActorID: userID, PostID: &postID})
+	}
+	return nil
+}
+
+// UnlikePost removes the user's like from the post, unliking a post that isn't liked succeeds.
+func (uc *PostUsecase) UnlikePost(ctx context.Context, userID, postID uuid.UUID) error {
+	removed, err := uc.postRepo.UnlikePost(ctx, userID, postID)
+	if err != nil {
+		return err
+	}
+	if removed {
+		uc.counters.Add(ctx, entity.CounterPostLikes, postID, -1)
+	}
+	return nil
+}
+
+// Repost shares the post with the user's followers, reposting an already reposted post succeeds.
+func (uc *PostUsecase) Repost(ctx context.Context, userID, postID uuid.UUID) error {
+	at := time.Now()
+	reposted, err := uc.postRepo.Repost(ctx, userID, postID, at)
+	if err != nil {
+		return err
+	}
+	if reposted {
+		uc.counters.Add(ctx, entity.CounterPostReposts, postID, 1)
+		uc.timelines.Publish(ctx, userID, entity.TimelineEntry{PostID: postID, RepostedBy: &userID, At: at}, true)
+		uc.federation.Announce(ctx, userID, postID, false)
+	}
+	return nil
+}
+
+// Unrepost removes the user's repost of the post, undoing a repost that doesn't exist succeeds.
+func (uc *PostUsecase) Unrepost(ctx context.Context, userID, postID uuid.UUID) error {
+	removed, err := uc.postRepo.Unrepost(ctx, userID, postID)
+	if err != nil {
+		return err
+	}
+	if removed {
+		uc.counters.Add(ctx, entity.CounterPostReposts, postID, -1)
+		uc.timelines.Retract(ctx, userID, entity.TimelineEntry{PostID: postID, RepostedBy: &userID})
+		uc.federation.Announce(ctx, userID, postID, true)
+	}
+	return nil
+}
+
+func validateDescription(description string) error {
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return apperror.InvalidArgument("description_too_long", "description must be at most 500 characters")
+	}
+	return nil
+}
+
+// validateVisibility defaults an empty visibility to public.
+func validateVisibility(visibility entity.PostVisibility) (entity.PostVisibility, error) {
+	if visibility == "" {
+		return entity.PostVisibilityPublic, nil
+	}
+	if !visibility.Valid() {
+		return "", apperror.InvalidArgument("invalid_visibility", "visibility must be public, followers, close_friends or private")
+	}
+	return visibility, nil
+}
+
+// extractLinks returns the links of the description with new short codes. The repo keeps the codes of links
+// a post already had.
+func extractLinks(description string) []entity.PostLink {
+	var links []entity.PostLink
+	for _, l := range link.Extract(description) {
+		links = append(links, entity.PostLink{Code: link.NewCode(), Text: l.Text, URL: l.URL})
+	}
+	return links
+}
@@ -0,0 +1,140 @@
+package profile
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/customerrors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxNameLength      = 100
+	maxBioLength       = 300
+	maxAvatarURLLength = 2048
+	minAge             = 13
+	maxAge             = 120
+)
+
+// ProfileRepo defines the interface for profile storage.
+type ProfileRepo interface {
+	// GetProfile returns the profile of an active user, Restricted tells whether the viewer may only see the public fields.
+	GetProfile(ctx context.Context, viewerID, userID uuid.UUID) (entity.Profile, error)
+
+	// GetProfiles returns the profiles of the active users among the IDs who didn't block the viewer.
+	GetProfiles(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]entity.Profile, error)
+
+	// UpdateProfile changes the non-nil fields of the user's profile.
+	UpdateProfile(ctx context.Context, userID uuid.UUID, update entity.ProfileUpdate) error
+}
+
+// Blacklist tells whether a user blocked another one.
+type Blacklist interface {
+	// IsBlocked reports whether the blocker blocked the user.
+	IsBlocked(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error)
+}
+
+type ProfileUsecase struct {
+	profileRepo ProfileRepo
+	blacklist   Blacklist
+}
+
+func NewProfileUsecase(profileRepo ProfileRepo, blacklist Blacklist) *ProfileUsecase {
+	return &ProfileUsecase{
+		profileRepo: profileRepo,
+		blacklist:   blacklist,
+	}
+}
+
+// GetProfile returns the user's profile as the viewer may see it, viewerID is uuid.Nil for anonymous viewers.
+// The bio, gender and age are hidden from viewers the privacy level of the account doesn't include,
+// and those of a private account from everyone but the user and their followers.
+// Users who were blocked get customerrors.ErrNotFound, as if the account didn't exist.
+func (uc *ProfileUsecase) GetProfile(ctx context.Context, viewerID, userID uuid.UUID) (entity.Profile, error) {
+	blocked, err := uc.blacklist.IsBlocked(ctx, userID, viewerID)
+	if err != nil {
+		return entity.Profile{}, err
+	}
+	if blocked {
+		return entity.Profile{}, customerrors.ErrNotFound
+	}
+
+	profile, err := uc.profileRepo.GetProfile(ctx, viewerID, userID)
+	if err != nil {
+		return entity.Profile{}, err
+	}
+	return hideRestricted(profile), nil
+}
+
+// GetProfiles is GetProfile for several users at once. Users who don't exist or blocked the viewer are left out,
+// the order of the profiles is unspecified.
+func (uc *ProfileUsecase) GetProfiles(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]entity.Profile, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	profiles, err := uc.profileRepo.GetProfiles(ctx, viewerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		profiles[i] = hideRestricted(profiles[i])
+	}
+	return profiles, nil
+}
+
+// hideRestricted clears the fields a restricted viewer may not see.
+func hideRestricted(profile entity.Profile) entity.Profile {
+	if profile.Restricted {
+		profile.Bio = ""
+		profile.Gender = ""
+		profile.Age = nil
+	}
+	return profile
+}
+
+// UpdateProfile changes the non-nil fields of the user's profile and returns the updated profile.
+func (uc *ProfileUsecase) UpdateProfile(ctx context.Context, userID uuid.UUID, update entity.ProfileUpdate) (entity.Profile, error) {
+	if err := validateUpdate(&update); err != nil {
+		return entity.Profile{}, err
+	}
+	if err := uc.profileRepo.UpdateProfile(ctx, userID, update); err != nil {
+		return entity.Profile{}, err
+	}
+	return uc.profileRepo.GetProfile(ctx, userID, userID)
+}
+
+// validateUpdate trims the text fields and checks them.
+func validateUpdate(update *entity.ProfileUpdate) error {
+	if update.Name != nil {
+		*update.Name = strings.TrimSpace(*update.Name)
+		if utf8.RuneCountInString(*update.Name) > maxNameLength {
+			return apperror.InvalidArgument("name_too_long", "name must be at most 100 characters")
+		}
+	}
+	if update.Bio != nil {
+		*update.Bio = strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(*update.Bio) > maxBioLength {
+			return apperror.InvalidArgument("bio_too_long", "bio must be at most 300 characters")
+		}
+	}
+	if update.AvatarURL != nil && *update.AvatarURL != "" {
+		if len(*update.AvatarURL) > maxAvatarURLLength {
+			return apperror.InvalidArgument("invalid_avatar_url", "avatar URL is too long")
+		}
+		u, err := url.Parse(*update.AvatarURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apperror.InvalidArgument("invalid_avatar_url", "avatar must be an http or https URL")
+		}
+	}
+	if update.Gender != nil && !update.Gender.Valid() {
+		return apperror.InvalidArgument("invalid_gender", "gender must be male, female or other")
+	}
+	if update.Age != nil && *update.Age != 0 && (*update.Age < minAge || *update.Age > maxAge) {
+		return apperror.InvalidArgument("invalid_age", "age must be between 13 and 120")
+	}
+	return nil
+}
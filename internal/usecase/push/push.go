@@ -0,0 +1,174 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/push"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxTokenLength bounds registration tokens and WebPush endpoints, real ones are a few hundred characters.
+const maxTokenLength = 2048
+
+// PushRepo defines the interface for the devices of users.
+type PushRepo interface {
+	// AddDevice registers the device of the user and returns it, moving a known token to the user.
+	AddDevice(ctx context.Context, device entity.PushDevice) (entity.PushDevice, error)
+
+	// DeleteDevice unregisters a device of the user.
+	DeleteDevice(ctx context.Context, userID, deviceID uuid.UUID) error
+
+	// DeleteToken forgets a token the push service stopped accepting.
+	DeleteToken(ctx context.Context, platform entity.PushPlatform, token string) error
+
+	// ListDevices returns the devices of the users.
+	ListDevices(ctx context.Context, userIDs []uuid.UUID) ([]entity.PushDevice, error)
+
+	// Username returns the username of an active user.
+	Username(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// Presence tells which users have a real-time connection open.
+type Presence interface {
+	// Online returns the users among userIDs that have an open connection.
+	Online(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+}
+
+type PushUsecase struct {
+	repo      PushRepo
+	presence  Presence
+	providers map[entity.PushPlatform]push.Provider
+}
+
+// NewPushUsecase takes the providers of the configured push services, devices of other platforms can't be registered.
+func NewPushUsecase(repo PushRepo, presence Presence, providers map[entity.PushPlatform]push.Provider) *PushUsecase {
+	return &PushUsecase{
+		repo:      repo,
+		presence:  presence,
+		providers: providers,
+	}
+}
+
+// RegisterDevice registers a device of the user to push notifications to. Registering a token again updates
+// its keys; a token registered by another user, e.g. before switching accounts on the device, moves to the user.
+func (uc *PushUsecase) RegisterDevice(ctx context.Context, userID uuid.UUID, device entity.PushDevice) (entity.PushDevice, error) {
+	if !device.Platform.Valid() {
+		return entity.PushDevice{}, apperror.InvalidArgument("invalid_platform", "platform must be fcm, apns or webpush")
+	}
+	if _, ok := uc.providers[device.Platform]; !ok {
+		return entity.PushDevice{}, apperror.FailedPrecondition("platform_disabled", fmt.Sprintf("%s push notifications are not enabled", device.Platform))
+	}
+	device.Token = strings.TrimSpace(device.Token)
+	if device.Token == "" || len(device.Token) > maxTokenLength {
+		return entity.PushDevice{}, apperror.InvalidArgument("invalid_push_token", fmt.Sprintf("token must be 1 to %d characters", maxTokenLength))
+	}
+	if device.Platform == entity.PushWebPush {
+		endpoint, err := url.Parse(device.Token)
+		if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+			return entity.PushDevice{}, apperror.InvalidArgument("invalid_push_token", "token must be the https endpoint of the push subscription")
+		}
+		if device.P256dh == "" || device.Auth == "" {
+			return entity.PushDevice{}, apperror.InvalidArgument("invalid_push_keys", "p256dh and auth keys of the push subscription are required")
+		}
+	} else {
+		device.P256dh, device.Auth = "", ""
+	}
+	device.UserID = userID
+	return uc.repo.AddDevice(ctx, device)
+}
+
+// UnregisterDevice stops pushing notifications to one of the user's devices.
+func (uc *PushUsecase) UnregisterDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	return uc.repo.DeleteDevice(ctx, userID, deviceID)
+}
+
+// ListDevices returns the user's devices, most recently registered first.
+func (uc *PushUsecase) ListDevices(ctx context.Context, userID uuid.UUID) ([]entity.PushDevice, error) {
+	return uc.repo.ListDevices(ctx, []uuid.UUID{userID})
+}
+
+// WebPushKey returns the VAPID public key browsers subscribe with, empty if WebPush isn't enabled.
+func (uc *PushUsecase) WebPushKey() string {
+	if p, ok := uc.providers[entity.PushWebPush].(interface{ PublicKey() string }); ok {
+		return p.PublicKey()
+	}
+	return ""
+}
+
+// Push sends the notification about the event to the devices of the recipients who have no real-time connection
+// open, those who do see it in the app. Tokens the push services reject as expired are forgotten.
+func (uc *PushUsecase) Push(ctx context.Context, event entity.NotificationEvent, recipients []uuid.UUID) error {
+	if len(recipients) == 0 || len(uc.providers) == 0 {
+		return nil
+	}
+	// if presence is unknown, a notification too many beats a missed one
+	online, _ := uc.presence.Online(ctx, recipients)
+	offline := make([]uuid.UUID, 0, len(recipients))
+	for _, id := range recipients {
+		if !online[id] {
+			offline = append(offline, id)
+		}
+	}
+	if len(offline) == 0 {
+		return nil
+	}
+	devices, err := uc.repo.ListDevices(ctx, offline)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+	username, err := uc.repo.Username(ctx, event.ActorID)
+	if err != nil {
+		return err
+	}
+
+	msg := message(event, username)
+	var errs []error
+	for _, d := range devices {
+		provider, ok := uc.providers[d.Platform]
+		if !ok {
+			continue
+		}
+		err := provider.Send(ctx, push.Target{Token: d.Token, P256dh: d.P256dh, Auth: d.Auth}, msg)
+		if errors.Is(err, push.ErrUnregistered) {
+			err = uc.repo.DeleteToken(ctx, d.Platform, d.Token)
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// message renders the notification about the event, the data lets the app open what it's about.
+func message(event entity.NotificationEvent, username string) push.Message {
+	var body string
+	switch event.Type {
+	case entity.NotificationFollow:
+		body = "started following you"
+	case entity.NotificationFollowRequest:
+		body = "asked to follow you"
+	case entity.NotificationLike:
+		body = "liked your post"
+	case entity.NotificationComment:
+		body = "commented on your post"
+	case entity.NotificationMention:
+		body = "mentioned you"
+	case entity.NotificationMessage:
+		body = "sent you a message"
+	}
+	data := map[string]string{"type": string(event.Type), "actor_id": event.ActorID.String()}
+	if event.PostID != nil {
+		data["post_id"] = event.PostID.String()
+	}
+	if event.CommentID != nil {
+		data["comment_id"] = event.CommentID.String()
+	}
+	if event.ChatID != nil {
+		data["chat_id"] = event.ChatID.String()
+	}
+	return push.Message{Title: "@" + username, Body: body, Data: data}
+}
@@ -0,0 +1,173 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"main/domain/entity"
+	"main/pkg/apperror"
+	"main/pkg/pagination"
+	ctxUtil "main/pkg/utils/context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReviewRepo defines the interface for the review queue of content the content policy objected to.
+type ReviewRepo interface {
+	// ListOpenFlags returns the unresolved flags created after the given position, oldest first.
+	ListOpenFlags(ctx context.Context, afterTime time.Time, afterID uuid.UUID, limit int) ([]entity.ContentFlag, error)
+
+	// GetFlag returns the flag.
+	GetFlag(ctx context.Context, id uuid.UUID) (entity.ContentFlag, error)
+
+	// ResolveFlags resolves the open flags of the content and returns how many there were.
+	ResolveFlags(ctx context.Context, kind entity.ContentKind, contentID, resolvedBy uuid.UUID, resolution entity.ReportResolution) (int64, error)
+
+	// LiftLimit shows shadow-limited content to everyone again.
+	LiftLimit(ctx context.Context, kind entity.ContentKind, contentID uuid.UUID) error
+
+	// ContentAuthor returns the author of the content and, for a message, its chat.
+	ContentAuthor(ctx context.Context, kind entity.ContentKind, contentID uuid.UUID) (authorID, chatID uuid.UUID, err error)
+}
+
+// Transactor defines the interface for running several repository calls atomically.
+type Transactor interface {
+	// WithinTx runs fn in a transaction carried by the context passed to it.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// PostRemover deletes posts for good.
+type PostRemover interface {
+	// RemovePost deletes a post for good, its author can't restore it.
+	RemovePost(ctx context.Context, postID uuid.UUID) error
+}
+
+// CommentRemover deletes comments for good.
+type CommentRemover interface {
+	// RemoveComment deletes a comment with its replies for good, its author can't restore it.
+	RemoveComment(ctx context.Context, commentID uuid.UUID) error
+}
+
+// MessageRemover deletes chat messages for good.
+type MessageRemover interface {
+	// RemoveMessage deletes a message of the sender in the chat for every member and erases it at once.
+	RemoveMessage(ctx context.Context, senderID, chatID, messageID uuid.UUID) error
+}
+
+// AuditRecorder defines the interface for recording security-relevant events.
+type AuditRecorder interface {
+	Record(ctx context.Context, eventType entity.AuditEventType, actorID, subjectID uuid.UUID, metadata map[string]any)
+}
+
+type ReviewUsecase struct {
+	repo     ReviewRepo
+	tx       Transactor
+	posts    PostRemover
+	comments CommentRemover
+	messages MessageRemover
+	audit    AuditRecorder
+}
+
+func NewReviewUsecase(repo ReviewRepo, tx Transactor, posts PostRemover, comments CommentRemover, messages MessageRemover, audit AuditRecorder) *ReviewUsecase {
+	return &ReviewUsecase{
+		repo:     repo,
+		tx:       tx,
+		posts:    posts,
+		comments: comments,
+		messages: messages,
+		audit:    audit,
+	}
+}
+
+// ListReports returns a page of the content waiting for review, oldest first. An empty cursor starts from the oldest
+// report; nextCursor fetches newer ones and is empty on the last page.
+func (uc *ReviewUsecase) ListReports(ctx context.Context, cursor string, limit int) (reports []entity.ContentFlag, nextCursor string, err error) {
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one extra report tells whether there is a next page
+	reports, err = uc.repo.ListOpenFlags(ctx, after.CreatedAt, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	reports, nextCursor = pagination.Page(reports, limit, func(f entity.ContentFlag) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: f.CreatedAt, ID: f.ID}
+	})
+	return reports, nextCursor, nil
+}
+
+// ResolveReport resolves a report and every other open report of the same content. Dismissed content stays published
+// and a shadow limit is lifted, removed content is taken down. Returns customerrors.ErrNotFound if the report
+// doesn't exist and an error if it's already resolved.
+func (uc *ReviewUsecase) ResolveReport(ctx context.Context, reportID uuid.UUID, resolution entity.ReportResolution) error {
+	if !resolution.Valid() {
+		return apperror.InvalidArgument("invalid_resolution", "resolution must be dismissed or removed")
+	}
+	report, err := uc.repo.GetFlag(ctx, reportID)
+	if err != nil {
+		return err
+	}
+	if report.ResolvedAt != nil {
+		return apperror.FailedPrecondition("report_resolved", "report is already resolved")
+	}
+
+	if resolution == entity.ReportRemoved {
+		return uc.TakeDown(ctx, report.Kind, report.ContentID, "report "+report.ID.String())
+	}
+	err = uc.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if report.Limited() {
+			if err := uc.repo.LiftLimit(ctx, report.Kind, report.ContentID); err != nil {
+				return err
+			}
+		}
+		_, err := uc.repo.ResolveFlags(ctx, report.Kind, report.ContentID, actorFromContext(ctx), entity.ReportDismissed)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	uc.audit.Record(ctx, entity.AuditReportDismiss, actorFromContext(ctx), report.AuthorID,
+		map[string]any{"content_type": report.Kind, "content_id": report.ContentID})
+	return nil
+}
+
+// TakeDown removes a post, a comment with its replies or a message for every chat member and resolves
+// the open reports of it. Taken down content is deleted for good, even if its author deleted it before, so that
+// they can't restore it. Returns customerrors.ErrNotFound if the content doesn't exist.
+func (uc *ReviewUsecase) TakeDown(ctx context.Context, kind entity.ContentKind, contentID uuid.UUID, reason string) error {
+	authorID, chatID, err := uc.repo.ContentAuthor(ctx, kind, contentID)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case entity.ContentPost:
+		err = uc.posts.RemovePost(ctx, contentID)
+	case entity.ContentComment:
+		err = uc.comments.RemoveComment(ctx, contentID)
+	case entity.ContentMessage:
+		err = uc.messages.RemoveMessage(ctx, authorID, chatID, contentID)
+	default:
+		return apperror.InvalidArgument("unknown_content_type", fmt.Sprintf("unknown content type %q", kind))
+	}
+	if err != nil {
+		return err
+	}
+
+	actorID := actorFromContext(ctx)
+	if _, err := uc.repo.ResolveFlags(ctx, kind, contentID, actorID, entity.ReportRemoved); err != nil {
+		return err
+	}
+	uc.audit.Record(ctx, entity.AuditContentTakedown, actorID, authorID,
+		map[string]any{"content_type": kind, "content_id": contentID, "reason": reason})
+	return nil
+}
+
+// actorFromContext returns the user performing the request, uuid.Nil if there is none.
+func actorFromContext(ctx context.Context) uuid.UUID {
+	actorID, _ := ctxUtil.UserIDFromContext(ctx)
+	return actorID
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/pkg/customerrors"
+	"main/pkg/pagination"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxQueryLength is the maximum length of a search query in characters.
+	maxQueryLength = 200
+
+	defaultUserResults = 10
+	maxUserResults     = 50
+	// maxUserQueryLength is the longest username or display name worth matching
+	maxUserQueryLength = 100
+)
+
+// SearchRepo defines the interface for full-text search.
+type SearchRepo interface {
+	// SearchPosts returns posts matching the query that the viewer may see below the given position, most relevant first.
+	SearchPosts(ctx context.Context, viewerID uuid.UUID, query string, beforeRank float64, beforeID uuid.UUID, limit int) ([]entity.RankedPost, error)
+}
+
+// UserSearchRepo defines the interface for looking up users by name.
+type UserSearchRepo interface {
+	// SearchUsers returns users whose username starts with the query or whose display name contains it, best matches first.
+	SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit int) ([]entity.UserCard, error)
+}
+
+type SearchUsecase struct {
+	searchRepo SearchRepo
+	userRepo   UserSearchRepo
+}
+
+func NewSearchUsecase(searchRepo SearchRepo, userRepo UserSearchRepo) *SearchUsecase {
+	return &SearchUsecase{
+		searchRepo: searchRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// SearchPosts returns a page of posts matching the query, most relevant first, viewerID is uuid.Nil for anonymous viewers.
+// An empty cursor starts from the most relevant post; nextCursor fetches the following page and is empty on the last one.
+func (uc *SearchUsecase) SearchPosts(ctx context.Context, viewerID uuid.UUID, query, cursor string, limit int) (posts []entity.RankedPost, nextCursor string, err error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxQueryLength {
+		return nil, "", customerrors.ErrInvalidQuery
+	}
+	limit = pagination.Limit(limit)
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one extra post tells whether there is a next page
+	posts, err = uc.searchRepo.SearchPosts(ctx, viewerID, query, after.Score, after.ID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	posts, nextCursor = pagination.Page(posts, limit, func(post entity.RankedPost) pagination.Cursor {
+		return pagination.Cursor{Score: post.Score, ID: post.Post.ID}
+	})
+	return posts, nextCursor, nil
+}
+
+// SearchUsers returns users matching the query by username prefix or display name, best matches first,
+// as cards carrying the follow state relative to the viewer. viewerID is uuid.Nil for anonymous viewers.
+// A leading '@' is ignored.
+func (uc *SearchUsecase) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit int) ([]entity.UserCard, error) {
+	query = strings.TrimPrefix(strings.TrimSpace(query), "@")
+	if query == "" || utf8.RuneCountInString(query) > maxUserQueryLength {
+		return nil, customerrors.ErrInvalidQuery
+	}
+	if limit <= 0 {
+		limit = defaultUserResults
+	}
+	limit = min(limit, maxUserResults)
+	return uc.userRepo.SearchUsers(ctx, viewerID, query, limit)
+}
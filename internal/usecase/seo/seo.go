@@ -0,0 +1,98 @@
+// Package seo serves public content to crawlers: link previews of public posts with Open Graph and Twitter
+// Card metadata, and sitemaps of the public profiles and posts for search engines.
+package seo
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/internal/config"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxPreviewDescription is the length previews cut descriptions to, about what link cards show.
+const maxPreviewDescription = 200
+
+// SEORepo defines the interface for what crawlers see.
+type SEORepo interface {
+	// GetPostPreview returns a public post of an indexable user with the profile of its author.
+	GetPostPreview(ctx context.Context, postID uuid.UUID) (entity.Post, entity.Profile, error)
+
+	// ListIndexableProfiles returns the indexable users with IDs after afterID, ordered by ID.
+	ListIndexableProfiles(ctx context.Context, afterID uuid.UUID, limit int) ([]entity.Profile, error)
+
+	// ListIndexablePosts returns the public posts of indexable users before the position, newest first.
+	ListIndexablePosts(ctx context.Context, beforeTime time.Time, beforeID uuid.UUID, limit int) ([]entity.Post, error)
+
+	// ReplaceSitemaps replaces the stored sitemaps with the generated ones.
+	ReplaceSitemaps(ctx context.Context, sitemaps []entity.Sitemap) error
+
+	// ListSitemaps returns the stored sitemaps without their content.
+	ListSitemaps(ctx context.Context) ([]entity.Sitemap, error)
+
+	// GetSitemap returns a stored sitemap, customerrors.ErrNotFound if there is none with the index.
+	GetSitemap(ctx context.Context, index int) (entity.Sitemap, error)
+}
+
+type SEOUsecase struct {
+	repo SEORepo
+	cfg  config.SEOConfig
+}
+
+func NewSEOUsecase(repo SEORepo, cfg config.SEOConfig) *SEOUsecase {
+	return &SEOUsecase{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// PostPreview returns the link preview of a public post. Posts that aren't public, and those of private or
+// hidden accounts, are customerrors.ErrNotFound, so their links unfurl to nothing.
+func (uc *SEOUsecase) PostPreview(ctx context.Context, postID uuid.UUID) (entity.PostPreview, error) {
+	post, author, err := uc.repo.GetPostPreview(ctx, postID)
+	if err != nil {
+		return entity.PostPreview{}, err
+	}
+
+	title := "@" + author.Username
+	if author.Name != "" {
+		title = author.Name + " (@" + author.Username + ")"
+	}
+	preview := entity.PostPreview{
+		URL:         uc.postURL(post.ID),
+		SiteName:    uc.cfg.SiteName,
+		Title:       title + " on " + uc.cfg.SiteName,
+		Description: truncate(strings.Join(strings.Fields(post.Description), " "), maxPreviewDescription),
+		AvatarURL:   author.AvatarURL,
+		Author:      author.Username,
+		PublishedAt: post.CreatedAt,
+	}
+	switch {
+	case post.MediaURL != "" && post.IsVideo:
+		preview.VideoURL = post.MediaURL
+	case post.MediaURL != "":
+		preview.ImageURL = post.MediaURL
+	}
+	return preview, nil
+}
+
+func (uc *SEOUsecase) postURL(postID uuid.UUID) string {
+	return uc.cfg.AppURL + "/posts/" + postID.String()
+}
+
+func (uc *SEOUsecase) profileURL(username string) string {
+	return uc.cfg.AppURL + "/" + url.PathEscape(username)
+}
+
+// truncate cuts the text to limit characters, ending it with "..." if it was cut.
+func truncate(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:limit])) + "..."
+}
@@ -0,0 +1,165 @@
+package seo
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"main/domain/entity"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sitemapNamespace is the XML namespace of the sitemaps protocol
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	// maxSitemapURLs is the most URLs the protocol allows in one sitemap
+	maxSitemapURLs = 50000
+	// sitemapPageSize is the number of profiles or posts read at once while generating
+	sitemapPageSize = 5000
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+func lastMod(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// sitemapWriter splits the URLs into sitemaps of at most maxSitemapURLs.
+type sitemapWriter struct {
+	sitemaps    []entity.Sitemap
+	current     []sitemapURL
+	generatedAt time.Time
+}
+
+func (w *sitemapWriter) add(loc string, modified time.Time) error {
+	w.current = append(w.current, sitemapURL{Loc: loc, LastMod: lastMod(modified)})
+	if len(w.current) == maxSitemapURLs {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *sitemapWriter) flush() error {
+	if len(w.current) == 0 {
+		return nil
+	}
+	content, err := encodeXML(urlSet{XMLNS: sitemapNamespace, URLs: w.current})
+	if err != nil {
+		return err
+	}
+	w.sitemaps = append(w.sitemaps, entity.Sitemap{
+		Index:       len(w.sitemaps) + 1,
+		Content:     content,
+		URLCount:    len(w.current),
+		GeneratedAt: w.generatedAt,
+	})
+	w.current = nil
+	return nil
+}
+
+func encodeXML(v any) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	if err := xml.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// GenerateSitemaps lists the public profiles and then the newest public posts, up to the configured number of
+// URLs, in sitemaps that replace the previous ones, and returns how many URLs were listed. It is run by a
+// scheduled job.
+func (uc *SEOUsecase) GenerateSitemaps(ctx context.Context) (int, error) {
+	w := &sitemapWriter{generatedAt: time.Now()}
+	count := 0
+
+	var afterID uuid.UUID
+	for count < uc.cfg.SitemapMaxURLs {
+		profiles, err := uc.repo.ListIndexableProfiles(ctx, afterID, min(sitemapPageSize, uc.cfg.SitemapMaxURLs-count))
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range profiles {
+			if err := w.add(uc.profileURL(p.Username), p.UpdatedAt); err != nil {
+				return 0, err
+			}
+		}
+		count += len(profiles)
+		if len(profiles) < sitemapPageSize {
+			break
+		}
+		afterID = profiles[len(profiles)-1].UserID
+	}
+
+	var (
+		beforeTime time.Time
+		beforeID   uuid.UUID
+	)
+	for count < uc.cfg.SitemapMaxURLs {
+		posts, err := uc.repo.ListIndexablePosts(ctx, beforeTime, beforeID, min(sitemapPageSize, uc.cfg.SitemapMaxURLs-count))
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range posts {
+			if err := w.add(uc.postURL(p.ID), p.UpdatedAt); err != nil {
+				return 0, err
+			}
+		}
+		count += len(posts)
+		if len(posts) < sitemapPageSize {
+			break
+		}
+		last := posts[len(posts)-1]
+		beforeTime, beforeID = last.CreatedAt, last.ID
+	}
+
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	if err := uc.repo.ReplaceSitemaps(ctx, w.sitemaps); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SitemapIndex returns the sitemap index listing the generated sitemaps, served at /sitemap.xml.
+func (uc *SEOUsecase) SitemapIndex(ctx context.Context) ([]byte, error) {
+	sitemaps, err := uc.repo.ListSitemaps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := sitemapIndex{XMLNS: sitemapNamespace, Sitemaps: make([]sitemapURL, 0, len(sitemaps))}
+	for _, s := range sitemaps {
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{
+			Loc:     uc.cfg.BaseURL + "/sitemaps/" + strconv.Itoa(s.Index) + ".xml",
+			LastMod: lastMod(s.GeneratedAt),
+		})
+	}
+	return encodeXML(index)
+}
+
+// Sitemap returns a generated sitemap, customerrors.ErrNotFound if there is none with the index.
+func (uc *SEOUsecase) Sitemap(ctx context.Context, index int) ([]byte, error) {
+	sitemap, err := uc.repo.GetSitemap(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	return sitemap.Content, nil
+}
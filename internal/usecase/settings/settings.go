@@ -0,0 +1,45 @@
+package settings
+
+import (
+	"context"
+	"main/domain/entity"
+	"main/pkg/apperror"
+
+	"github.com/google/uuid"
+)
+
+// SettingsRepo defines the interface for user settings storage.
+type SettingsRepo interface {
+	// GetSettings returns the settings of the user.
+	GetSettings(ctx context.Context, userID uuid.UUID) (entity.UserSettings, error)
+
+	// UpdateSettings changes the non-nil settings of the user and returns the updated settings.
+	UpdateSettings(ctx context.Context, userID uuid.UUID, update entity.SettingsUpdate) (entity.UserSettings, error)
+}
+
+type SettingsUsecase struct {
+	settingsRepo SettingsRepo
+}
+
+func NewSettingsUsecase(settingsRepo SettingsRepo) *SettingsUsecase {
+	return &SettingsUsecase{
+		settingsRepo: settingsRepo,
+	}
+}
+
+// GetSettings returns the settings of the user.
+func (uc *SettingsUsecase) GetSettings(ctx context.Context, userID uuid.UUID) (entity.UserSettings, error) {
+	return uc.settingsRepo.GetSettings(ctx, userID)
+}
+
+// UpdateSettings changes the non-nil settings of the user and returns the updated settings.
+// The new privacy level and private account setting apply to profile and feed reads right away.
+func (uc *SettingsUsecase) UpdateSettings(ctx context.Context, userID uuid.UUID, update entity.SettingsUpdate) (entity.UserSettings, error) {
+	if update.PrivacyLevel != nil && !update.PrivacyLevel.Valid() {
+		return entity.UserSettings{}, apperror.InvalidArgument("invalid_privacy_level", "privacy level must be everyone, followers or nobody")
+	}
+	if update.EmailDigest != nil && !update.EmailDigest.Valid() {
+		return entity.UserSettings{}, apperror.InvalidArgument("invalid_email_digest", "email digest must be off, daily or weekly")
+	}
+	return uc.settingsRepo.UpdateSettings(ctx, userID, update)
+}
@@ -0,0 +1,193 @@
+// Package signingkey shares the access token signing keys between the instances. The keys are stored in the
+// database encrypted with the key encryption key of the config, every instance signs with the same key and
+// accepts the tokens of all the others.
+package signingkey
+
+import (
+	"context"
+	"crypto"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"main/domain/entity"
+	"main/pkg/jwt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SigningKeyRepo defines the interface for the stored signing keys.
+type SigningKeyRepo interface {
+	// ListSigningKeys returns every stored signing key, ordered by the time it becomes active.
+	ListSigningKeys(ctx context.Context) ([]entity.SigningKey, error)
+
+	// CreateSigningKey stores a signing key, keeping an existing key with the same ID.
+	CreateSigningKey(ctx context.Context, key entity.SigningKey) error
+
+	// DeleteSigningKeys deletes the signing keys with the IDs.
+	DeleteSigningKeys(ctx context.Context, ids []string) error
+}
+
+// KeyManager defines the interface for the keys access tokens are signed and verified with.
+type KeyManager interface {
+	// SigningKey returns the ID and the private key of the current signing key.
+	SigningKey() (string, crypto.Signer)
+
+	// KeyID returns the ID of the current signing key.
+	KeyID() string
+
+	// GenerateKey generates a fresh key of the same type as the current signing key.
+	GenerateKey() (crypto.Signer, error)
+
+	// SetKeys switches signing to the private key and replaces the keys accepted for verification.
+	SetKeys(keyID string, privateKey crypto.Signer, publicKeys map[string]crypto.PublicKey) error
+}
+
+type SigningKeyUsecase struct {
+	repo SigningKeyRepo
+	keys KeyManager
+	// kek encrypts the stored private keys, so reading the database or a backup isn't enough to sign tokens
+	kek      cipher.AEAD
+	tokenTTL time.Duration
+	// publishDelay is how long a rotated key is accepted before it signs, two sync intervals, so every instance
+	// loaded it by then
+	publishDelay time.Duration
+}
+
+func NewSigningKeyUsecase(repo SigningKeyRepo, keys KeyManager, kek cipher.AEAD, tokenTTL, syncInterval time.Duration) *SigningKeyUsecase {
+	return &SigningKeyUsecase{
+		repo:         repo,
+		keys:         keys,
+		kek:          kek,
+		tokenTTL:     tokenTTL,
+		publishDelay: 2 * syncInterval,
+	}
+}
+
+// Load switches the instance to the stored keys: it signs with the newest active key and accepts every key whose
+// tokens may still be valid, including the keys that aren't active yet. Without stored keys the configured key is
+// stored first, so the first instance to start shares its key with the others. It is run on start and by every
+// instance each sync interval.
+func (uc *SigningKeyUsecase) Load(ctx context.Context) error {
+	keys, err := uc.repo.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		keyID, privateKey := uc.keys.SigningKey()
+		if err := uc.store(ctx, keyID, privateKey, time.Now()); err != nil {
+			return err
+		}
+		if keys, err = uc.repo.ListSigningKeys(ctx); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	current := activeKey(keys, now)
+	publicKeys := make(map[string]crypto.PublicKey, len(keys))
+	var signer crypto.Signer
+	for i, key := range keys {
+		if expired(keys, i, current, now, uc.tokenTTL) {
+			continue
+		}
+		encoded, err := uc.open(key)
+		if err != nil {
+			return fmt.Errorf("decrypt signing key %q: %w", key.ID, err)
+		}
+		privateKey, err := jwt.ParsePrivateKey(encoded)
+		if err != nil {
+			return fmt.Errorf("parse signing key %q: %w", key.ID, err)
+		}
+		publicKeys[key.ID] = privateKey.Public()
+		if i == current {
+			signer = privateKey
+		}
+	}
+	return uc.keys.SetKeys(keys[current].ID, signer, publicKeys)
+}
+
+// Rotate stores a new signing key of the same type as the current one and returns its ID. Every instance accepts
+// the key from its next load and signs with it after the publish delay, so no instance gets tokens of a key it
+// doesn't know. Keys whose tokens expired are deleted. It is run by one instance per rotation interval, or by an
+// admin.
+func (uc *SigningKeyUsecase) Rotate(ctx context.Context) (string, error) {
+	privateKey, err := uc.keys.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	keyID := uuid.NewString()
+	if err := uc.store(ctx, keyID, privateKey, time.Now().Add(uc.publishDelay)); err != nil {
+		return "", err
+	}
+
+	keys, err := uc.repo.ListSigningKeys(ctx)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	current := activeKey(keys, now)
+	var expiredIDs []string
+	for i, key := range keys {
+		if expired(keys, i, current, now, uc.tokenTTL) {
+			expiredIDs = append(expiredIDs, key.ID)
+		}
+	}
+	if len(expiredIDs) > 0 {
+		if err := uc.repo.DeleteSigningKeys(ctx, expiredIDs); err != nil {
+			return "", err
+		}
+	}
+	return keyID, uc.Load(ctx)
+}
+
+// KeyID returns the ID of the key the instance signs with.
+func (uc *SigningKeyUsecase) KeyID() string {
+	return uc.keys.KeyID()
+}
+
+func (uc *SigningKeyUsecase) store(ctx context.Context, keyID string, privateKey crypto.Signer, activeAt time.Time) error {
+	if privateKey == nil {
+		return jwt.ErrUnsupportedKey
+	}
+	encoded, err := jwt.MarshalPrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	return uc.repo.CreateSigningKey(ctx, entity.SigningKey{ID: keyID, EncryptedKey: uc.seal(keyID, encoded), ActiveAt: activeAt})
+}
+
+// seal encrypts the encoded private key with a random nonce put in front of it. The key ID is authenticated
+// along, so a stored key can't be passed off under another ID.
+func (uc *SigningKeyUsecase) seal(keyID string, encoded []byte) []byte {
+	nonce := make([]byte, uc.kek.NonceSize())
+	_, _ = rand.Read(nonce)
+	return uc.kek.Seal(nonce, nonce, encoded, []byte(keyID))
+}
+
+// open decrypts the private key of a stored key, it fails if the key was encrypted with another key encryption key.
+func (uc *SigningKeyUsecase) open(key entity.SigningKey) ([]byte, error) {
+	size := uc.kek.NonceSize()
+	if len(key.EncryptedKey) < size {
+		return nil, errors.New("encrypted key is too short")
+	}
+	return uc.kek.Open(nil, key.EncryptedKey[:size], key.EncryptedKey[size:], []byte(key.ID))
+}
+
+// activeKey returns the index of the newest key active at now, the first key if none is active yet.
+func activeKey(keys []entity.SigningKey, now time.Time) int {
+	current := 0
+	for i, key := range keys {
+		if !key.ActiveAt.After(now) {
+			current = i
+		}
+	}
+	return current
+}
+
+// expired reports whether the tokens of the key at index i expired: a key signs until the next one becomes
+// active, its tokens are valid for the token TTL after that.
+func expired(keys []entity.SigningKey, i, current int, now time.Time, tokenTTL time.Duration) bool {
+	return i < current && keys[i+1].ActiveAt.Add(tokenTTL).Before(now)
+}
@@ -0,0 +1,349 @@
+package timeline
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"main/domain/entity"
+	"main/internal/config"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxRangeRounds is how many times a page is read from the cache before it is read from Postgres, when
+// entries the user may not see anymore, e.g. of deleted posts, keep it from filling up.
+const maxRangeRounds = 3
+
+// TimelineRepo defines the interface for reading timelines from the source of truth.
+type TimelineRepo interface {
+	// ListFeed returns posts published or reposted by the user and the accounts they follow
+	// older than the given position, newest first.
+	ListFeed(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int) ([]entity.FeedItem, error)
+
+	// ListCelebrityFeed is ListFeed limited to the followed accounts with at least minFollowers followers.
+	ListCelebrityFeed(ctx context.Context, userID uuid.UUID, minFollowers int64, beforeTime time.Time, beforePostID uuid.UUID, limit int) ([]entity.FeedItem, error)
+
+	// GetPosts returns the posts of the IDs the viewer may see, in no particular order.
+	GetPosts(ctx context.Context, viewerID uuid.UUID, ids []uuid.UUID) ([]entity.Post, error)
+}
+
+// FollowerRepo defines the interface for finding whose timelines an entry goes to.
+type FollowerRepo interface {
+	// FollowerIDs returns up to limit IDs of the followers of the user after afterID, in ID order.
+	FollowerIDs(ctx context.Context, userID, afterID uuid.UUID, limit int) ([]uuid.UUID, error)
+
+	// FollowersCount returns the followers counter of the user.
+	FollowersCount(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// Cache defines the interface for the cached home timelines.
+type Cache interface {
+	// Push adds the entry to the cached timelines of the users.
+	Push(ctx context.Context, userIDs []uuid.UUID, entry entity.TimelineEntry) error
+
+	// Remove removes the entry from the timelines of the users.
+	Remove(ctx context.Context, userIDs []uuid.UUID, entry entity.TimelineEntry) error
+
+	// Store replaces the timeline of the user with the entries, newest first.
+	Store(ctx context.Context, userID uuid.UUID, entries []entity.TimelineEntry) error
+
+	// Range returns up to limit entries of the timeline older than the given position, newest first, whether the
+	// timeline is cached and whether it is full, so older entries may be missing from it.
+	Range(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int) ([]entity.TimelineEntry, bool, bool, error)
+
+	// Invalidate drops the timelines of the users.
+	Invalidate(ctx context.Context, userIDs ...uuid.UUID) error
+}
+
+// fanout is an entry to add to or remove from the timelines of the followers of its author.
+type fanout struct {
+	authorID uuid.UUID
+	entry    entity.TimelineEntry
+	remove   bool
+}
+
+// TimelineUsecase serves home timelines from a cache filled on write: new posts and reposts are pushed to the
+// cached timelines of the author's followers by a pool of workers, so reading a timeline is a range read plus
+// loading the posts. Entries of accounts with more followers than the celebrity threshold aren't pushed,
+// they are read from Postgres and merged in when the timeline is read. Timelines that aren't cached and
+// pages older than the cache keeps are read from Postgres. Without a cache every timeline is read from Postgres.
+type TimelineUsecase struct {
+	timelineRepo TimelineRepo
+	followers    FollowerRepo
+	cache        Cache
+	cfg          config.TimelineConfig
+	logger       *slog.Logger
+	queue        chan fanout
+}
+
+func NewTimelineUsecase(timelineRepo TimelineRepo, followers FollowerRepo, cache Cache, cfg config.TimelineConfig, logger *slog.Logger) *TimelineUsecase {
+	return &TimelineUsecase{
+		timelineRepo: timelineRepo,
+		followers:    followers,
+		cache:        cache,
+		cfg:          cfg,
+		logger:       logger,
+		queue:        make(chan fanout, cfg.QueueSize),
+	}
+}
+
+// Publish adds a new entry of the author to their timeline and, if followers is set, to the timelines of their
+// followers. Failures are logged, the timelines are repaired when they expire.
+func (uc *TimelineUsecase) Publish(ctx context.Context, authorID uuid.UUID, entry entity.TimelineEntry, followers bool) {
+	if uc.cache == nil {
+		return
+	}
+	// the request may already be cancelled (e.g. client disconnected), the entry should be published anyway
+	ctx = context.WithoutCancel(ctx)
+	// authors see their own entries at once
+	if err := uc.cache.Push(ctx, []uuid.UUID{authorID}, entry); err != nil {
+		uc.logger.Warn("Failed to push timeline entry", "user_id", authorID, "error", err)
+	}
+	if followers {
+		uc.enqueue(ctx, fanout{authorID: authorID, entry: entry})
+	}
+}
+
+// Retract removes an entry of the author, e.g. an undone repost, from their timeline and those of their followers.
+func (uc *TimelineUsecase) Retract(ctx context.Context, authorID uuid.UUID, entry entity.TimelineEntry) {
+	if uc.cache == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err := uc.cache.Remove(ctx, []uuid.UUID{authorID}, entry); err != nil {
+		uc.logger.Warn("Failed to remove timeline entry", "user_id", authorID, "error", err)
+	}
+	uc.enqueue(ctx, fanout{authorID: authorID, entry: entry, remove: true})
+}
+
+// Invalidate drops the cached timelines of the users, e.g. after they followed or unfollowed someone, so they are
+// read from Postgres and cached again.
+func (uc *TimelineUsecase) Invalidate(ctx context.Context, userIDs ...uuid.UUID) {
+	if uc.cache == nil {
+		return
+	}
+	if err := uc.cache.Invalidate(context.WithoutCancel(ctx), userIDs...); err != nil {
+		uc.logger.Warn("Failed to invalidate timelines", "error", err)
+	}
+}
+
+// enqueue hands the fan-out to the workers. When they fall behind it runs on the caller, which slows down
+// publishing instead of losing entries.
+func (uc *TimelineUsecase) enqueue(ctx context.Context, job fanout) {
+	select {
+	case uc.queue <- job:
+	default:
+		uc.run(ctx, job)
+	}
+}
+
+// Run runs the fan-out workers until ctx is done. Fan-outs still queued then are lost, the timelines missing
+// their entries are repaired when they expire.
+func (uc *TimelineUsecase) Run(ctx context.Context) error {
+	if uc.cache == nil {
+		return nil
+	}
+	var wg sync.WaitGroup
+	for range uc.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-uc.queue:
+					uc.run(ctx, job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (uc *TimelineUsecase) run(ctx context.Context, job fanout) {
+	if err := uc.fanOut(ctx, job); err != nil {
+		uc.logger.Error("Failed to fan out timeline entry", "author_id", job.authorID, "post_id", job.entry.PostID, "error", err)
+	}
+}
+
+// fanOut pushes the entry to, or removes it from, the timelines of the author's followers a batch at a time.
+// Entries of celebrities are read on demand instead.
+func (uc *TimelineUsecase) fanOut(ctx context.Context, job fanout) error {
+	count, err := uc.followers.FollowersCount(ctx, job.authorID)
+	if err != nil {
+		return err
+	}
+	if count >= uc.cfg.CelebrityThreshold {
+		return nil
+	}
+	var after uuid.UUID
+	for {
+		ids, err := uc.followers.FollowerIDs(ctx, job.authorID, after, uc.cfg.FanoutBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if job.remove {
+			err = uc.cache.Remove(ctx, ids, job.entry)
+		} else {
+			err = uc.cache.Push(ctx, ids, job.entry)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ids) < uc.cfg.FanoutBatchSize {
+			return nil
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// ListFeed returns up to limit entries of the user's home timeline older than the (beforeTime, beforePostID)
+// position, newest first, a zero beforeTime starts from the newest entry. The first page of a timeline that isn't
+// cached caches it. If the cache can't be read the timeline is read from Postgres.
+func (uc *TimelineUsecase) ListFeed(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int) ([]entity.FeedItem, error) {
+	if uc.cache == nil {
+		return uc.timelineRepo.ListFeed(ctx, userID, beforeTime, beforePostID, limit)
+	}
+
+	var (
+		items   []entity.FeedItem
+		oldest  *entity.FeedItem
+		drained bool
+	)
+	position, positionID := beforeTime, beforePostID
+	for round := 0; len(items) < limit; round++ {
+		if round == maxRangeRounds {
+			return uc.timelineRepo.ListFeed(ctx, userID, beforeTime, beforePostID, limit)
+		}
+		entries, built, full, err := uc.cache.Range(ctx, userID, position, positionID, limit-len(items))
+		if err != nil {
+			uc.logger.Warn("Failed to read cached timeline", "user_id", userID, "error", err)
+			return uc.timelineRepo.ListFeed(ctx, userID, beforeTime, beforePostID, limit)
+		}
+		if !built {
+			return uc.build(ctx, userID, beforeTime, beforePostID, limit)
+		}
+		if len(entries) < limit-len(items) {
+			// the rest of the page is older than the cache keeps
+			if full {
+				return uc.timelineRepo.ListFeed(ctx, userID, beforeTime, beforePostID, limit)
+			}
+			drained = true
+		}
+		loaded, err := uc.load(ctx, userID, entries)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, loaded...)
+		if drained {
+			break
+		}
+		last := entries[len(entries)-1]
+		oldest = &entity.FeedItem{Post: entity.Post{ID: last.PostID}, At: last.At}
+		position, positionID = last.At, last.PostID
+	}
+
+	celebrities, err := uc.timelineRepo.ListCelebrityFeed(ctx, userID, uc.cfg.CelebrityThreshold, beforeTime, beforePostID, limit)
+	if err != nil {
+		return nil, err
+	}
+	// entries of celebrities older than the last cached entry read could come after cached ones not read yet
+	if !drained && oldest != nil {
+		celebrities = slices.DeleteFunc(celebrities, func(item entity.FeedItem) bool { return newer(*oldest, item) })
+	}
+	return merge(items, celebrities, limit), nil
+}
+
+// build reads the newest entries of the user's timeline from Postgres, caches them and returns the page
+// older than the given position.
+func (uc *TimelineUsecase) build(ctx context.Context, userID uuid.UUID, beforeTime time.Time, beforePostID uuid.UUID, limit int) ([]entity.FeedItem, error) {
+	if !beforeTime.IsZero() {
+		// a client paging through an old timeline doesn't need a new one cached
+		return uc.timelineRepo.ListFeed(ctx, userID, beforeTime, beforePostID, limit)
+	}
+	items, err := uc.timelineRepo.ListFeed(ctx, userID, time.Time{}, uuid.Nil, max(limit, uc.cfg.MaxEntries))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entity.TimelineEntry, len(items))
+	for i, item := range items {
+		entries[i] = entity.TimelineEntry{PostID: item.Post.ID, RepostedBy: item.RepostedBy, At: item.At}
+	}
+	if err := uc.cache.Store(ctx, userID, entries); err != nil {
+		uc.logger.Warn("Failed to cache timeline", "user_id", userID, "error", err)
+	}
+	return items[:min(limit, len(items))], nil
+}
+
+// load returns the entries with their posts, leaving out those the user may not see (anymore).
+func (uc *TimelineUsecase) load(ctx context.Context, userID uuid.UUID, entries []entity.TimelineEntry) ([]entity.FeedItem, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.PostID
+	}
+	posts, err := uc.timelineRepo.GetPosts(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]entity.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	items := make([]entity.FeedItem, 0, len(entries))
+	for _, entry := range entries {
+		if post, ok := byID[entry.PostID]; ok {
+			items = append(items, entity.FeedItem{Post: post, RepostedBy: entry.RepostedBy, At: entry.At})
+		}
+	}
+	return items, nil
+}
+
+// merge merges two timelines, newest first, into one of up to limit entries without duplicates.
+func merge(a, b []entity.FeedItem, limit int) []entity.FeedItem {
+	type entryKey struct {
+		postID, repostedBy uuid.UUID
+	}
+	seen := make(map[entryKey]bool, len(a)+len(b))
+	merged := slices.Concat(a, b)
+	slices.SortStableFunc(merged, func(x, y entity.FeedItem) int {
+		if c := y.At.Compare(x.At); c != 0 {
+			return c
+		}
+		return cmp.Compare(y.Post.ID.String(), x.Post.ID.String())
+	})
+	items := make([]entity.FeedItem, 0, min(limit, len(merged)))
+	for _, item := range merged {
+		k := entryKey{postID: item.Post.ID}
+		if item.RepostedBy != nil {
+			k.repostedBy = *item.RepostedBy
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		items = append(items, item)
+		if len(items) == limit {
+			break
+		}
+	}
+	return items
+}
+
+// newer reports whether a comes before b on a timeline.
+func newer(a, b entity.FeedItem) bool {
+	if !a.At.Equal(b.At) {
+		return a.At.After(b.At)
+	}
+	return a.Post.ID.String() > b.Post.ID.String()
+}
@@ -0,0 +1,39 @@
+// Logs the seeded users in and rotates their refresh tokens: the password hashing, token signing and session
+// queries behind every login and refresh.
+import http from 'k6/http';
+import { check, sleep } from 'k6';
+import { BASE_URL, loginFor } from './lib.js';
+
+export const options = {
+  scenarios: {
+    auth: {
+      executor: 'ramping-vus',
+      stages: [
+        { duration: '30s', target: 20 },
+        { duration: '1m', target: 20 },
+        { duration: '15s', target: 0 },
+      ],
+    },
+  },
+  thresholds: {
+    http_req_failed: ['rate<0.01'],
+    'http_req_duration{name:POST /login}': ['p(95)<500'],
+    'http_req_duration{name:POST /auth/refresh}': ['p(95)<150'],
+  },
+};
+
+export default function () {
+  let { refreshToken } = loginFor(__VU);
+  for (let i = 0; i < 5; i++) {
+    const res = http.post(`${BASE_URL}/auth/refresh`, null, {
+      cookies: { refresh_token: refreshToken },
+      tags: { name: 'POST /auth/refresh' },
+    });
+    check(res, { 'refresh: 200': (r) => r.status === 200 });
+    if (res.status !== 200) {
+      return;
+    }
+    refreshToken = res.cookies.refresh_token[0].value;
+    sleep(0.5);
+  }
+}
@@ -0,0 +1,55 @@
+// Browses the home feed a few pages deep and the explore page, the heaviest reads of the API.
+import http from 'k6/http';
+import { check, sleep } from 'k6';
+import { BASE_URL, authorized, loginFor } from './lib.js';
+
+export const options = {
+  scenarios: {
+    feed: {
+      executor: 'constant-arrival-rate',
+      rate: Number(__ENV.RATE || 50),
+      timeUnit: '1s',
+      duration: __ENV.DURATION || '2m',
+      preAllocatedVUs: 50,
+      maxVUs: 200,
+    },
+  },
+  thresholds: {
+    http_req_failed: ['rate<0.01'],
+    'http_req_duration{name:GET /feed}': ['p(95)<200', 'p(99)<500'],
+    'http_req_duration{name:GET /explore}': ['p(95)<200'],
+  },
+};
+
+// the access token of the VU, logged in on its first iteration and again when it expired
+let accessToken;
+
+function get(url, name) {
+  if (!accessToken) {
+    accessToken = loginFor(__VU).accessToken;
+  }
+  let res = http.get(url, authorized(accessToken, name));
+  if (res.status === 401) {
+    accessToken = loginFor(__VU).accessToken;
+    res = http.get(url, authorized(accessToken, name));
+  }
+  return res;
+}
+
+export default function () {
+  let cursor = '';
+  for (let page = 0; page < 3; page++) {
+    const res = get(`${BASE_URL}/feed?limit=20&cursor=${encodeURIComponent(cursor)}`, 'GET /feed');
+    check(res, { 'feed: 200': (r) => r.status === 200 });
+    if (res.status !== 200) {
+      return;
+    }
+    cursor = res.json('next_cursor');
+    if (!cursor) {
+      break;
+    }
+    sleep(0.2);
+  }
+  const res = get(`${BASE_URL}/explore?limit=20`, 'GET /explore');
+  check(res, { 'explore: 200': (r) => r.status === 200 });
+}
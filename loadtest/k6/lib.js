@@ -0,0 +1,39 @@
+// Shared setup of the k6 scenarios: the target, the seeded accounts and logging in.
+import http from 'k6/http';
+import { check, fail } from 'k6';
+import { SharedArray } from 'k6/data';
+
+export const BASE_URL = (__ENV.BASE_URL || 'http://localhost:8082') + '/api/v1';
+const PASSWORD = __ENV.PASSWORD || 'Passw0rd!';
+
+// the usernames written by `seed -logins`, one per line
+export const logins = new SharedArray('logins', () =>
+  open(__ENV.LOGINS || '../logins.txt')
+    .split('\n')
+    .filter((line) => line !== ''),
+);
+
+// login signs a seeded user in and returns the access and refresh tokens. The refresh token cookie is Secure,
+// so k6 won't send it back over plain HTTP by itself; the scenarios pass it explicitly.
+export function login(username) {
+  const res = http.post(`${BASE_URL}/login`, JSON.stringify({ login: username, password: PASSWORD }), {
+    headers: { 'Content-Type': 'application/json' },
+    tags: { name: 'POST /login' },
+  });
+  if (!check(res, { 'login: 200': (r) => r.status === 200 })) {
+    fail(`login of ${username} failed with ${res.status}: ${res.body}`);
+  }
+  return {
+    accessToken: res.json('access_token'),
+    refreshToken: res.cookies.refresh_token[0].value,
+  };
+}
+
+// loginFor picks the seeded user of the virtual user, so concurrent VUs use different accounts.
+export function loginFor(vu) {
+  return login(logins[(vu - 1) % logins.length]);
+}
+
+export function authorized(accessToken, name) {
+  return { headers: { Authorization: `Bearer ${accessToken}` }, tags: { name } };
+}
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_pending_erasure ON users(deleted_at) WHERE deleted_at IS NOT NULL AND anonymized_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_users_pending_erasure;
+ALTER TABLE users DROP COLUMN IF EXISTS anonymized_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
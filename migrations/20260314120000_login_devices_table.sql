@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- devices (IP + User-Agent) the user signed in from, kept after the session ends to detect new devices
+CREATE TABLE IF NOT EXISTS user_devices (
+    user_id UUID NOT NULL,
+    ip_address INET NOT NULL,
+    user_agent TEXT NOT NULL,
+    first_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, ip_address, user_agent),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- logins from a new device waiting for email confirmation
+CREATE TABLE IF NOT EXISTS login_confirmations (
+    token_hash BYTEA PRIMARY KEY,
+    user_id UUID NOT NULL,
+    ip_address INET NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_confirmations_user_id ON login_confirmations(user_id);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS is_suspicious BOOLEAN DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE sessions DROP COLUMN IF EXISTS is_suspicious;
+DROP TABLE IF EXISTS login_confirmations;
+DROP TABLE IF EXISTS user_devices;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- username availability is checked case-insensitively
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_users_username_lower;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- emails are stored lowercased, addresses that would collide with an existing lowercase one are left as they are
+UPDATE users u SET email = LOWER(u.email)
+WHERE u.email <> LOWER(u.email)
+  AND NOT EXISTS (SELECT 1 FROM users o WHERE o.email = LOWER(u.email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    -- sha256 of the key, the plain key is never stored
+    key_hash BYTEA NOT NULL UNIQUE,
+    -- first characters of the key, to tell keys apart in listings
+    prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- created_at used to be overwritten on every refresh, it now stays the login time
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS refreshed_at TIMESTAMP WITH TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE sessions DROP COLUMN IF EXISTS refreshed_at;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- name given by the user, the label derived from the User-Agent is shown when it is empty
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_name VARCHAR(64);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_name;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS country_code VARCHAR(2);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS city VARCHAR(255);
+-- signing in from a country the user never signed in from is treated like a new device
+ALTER TABLE user_devices ADD COLUMN IF NOT EXISTS country_code VARCHAR(2);
+CREATE INDEX IF NOT EXISTS idx_user_devices_user_country ON user_devices(user_id, country_code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_user_devices_user_country;
+ALTER TABLE user_devices DROP COLUMN IF EXISTS country_code;
+ALTER TABLE sessions DROP COLUMN IF EXISTS city;
+ALTER TABLE sessions DROP COLUMN IF EXISTS country_code;
+-- +goose StatementEnd
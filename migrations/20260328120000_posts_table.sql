@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS posts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    media_url TEXT,
+    is_video BOOLEAN NOT NULL DEFAULT FALSE,
+    -- video length in seconds
+    duration INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_posts_user_created ON posts(user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS posts;
+-- +goose StatementEnd
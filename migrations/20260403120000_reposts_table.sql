@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS reposts (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_reposts_post ON reposts(post_id);
+CREATE INDEX IF NOT EXISTS idx_reposts_user_created ON reposts(user_id, created_at DESC);
+-- a quote post references the original, quotes outlive a deleted original
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS quote_of_id UUID REFERENCES posts(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_quote_of ON posts(quote_of_id) WHERE quote_of_id IS NOT NULL;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reposts_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS quotes_count INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE posts DROP COLUMN IF EXISTS quotes_count;
+ALTER TABLE posts DROP COLUMN IF EXISTS reposts_count;
+DROP INDEX IF EXISTS idx_posts_quote_of;
+ALTER TABLE posts DROP COLUMN IF EXISTS quote_of_id;
+DROP TABLE IF EXISTS reposts;
+-- +goose StatementEnd
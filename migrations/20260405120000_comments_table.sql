@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS comments (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    reply_to UUID,
+    content TEXT NOT NULL,
+    replies_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reply_to) REFERENCES comments(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_comments_post_created ON comments(post_id, created_at, id) WHERE reply_to IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_reply_to_created ON comments(reply_to, created_at, id) WHERE reply_to IS NOT NULL;
+-- denormalized COUNT(*) of comments including replies, kept in sync on create/delete and reconciled periodically
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_count INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE posts DROP COLUMN IF EXISTS comments_count;
+DROP TABLE IF EXISTS comments;
+-- +goose StatementEnd
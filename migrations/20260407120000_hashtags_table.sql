@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS hashtags (
+    id BIGSERIAL PRIMARY KEY,
+    -- lowercased, without the leading '#'
+    tag TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS post_hashtags (
+    hashtag_id BIGINT NOT NULL,
+    post_id UUID NOT NULL,
+    -- copied from the post, so a tag's posts are paginated without joining posts
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    PRIMARY KEY (hashtag_id, post_id),
+    FOREIGN KEY (hashtag_id) REFERENCES hashtags(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_post_hashtags_tag_created ON post_hashtags(hashtag_id, created_at DESC, post_id DESC);
+CREATE INDEX IF NOT EXISTS idx_post_hashtags_post ON post_hashtags(post_id);
+CREATE INDEX IF NOT EXISTS idx_post_hashtags_created ON post_hashtags(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS post_hashtags;
+DROP TABLE IF EXISTS hashtags;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'followers', 'private'));
+-- posts of private accounts are only visible to their followers, whatever the post visibility
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id UUID PRIMARY KEY,
+    private_account BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS user_settings;
+ALTER TABLE posts DROP COLUMN IF EXISTS visibility;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- explore ranking, rebuilt periodically from the post counters
+CREATE TABLE IF NOT EXISTS post_rankings (
+    post_id UUID PRIMARY KEY,
+    score DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_post_rankings_score ON post_rankings(score DESC, post_id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS post_rankings;
+-- +goose StatementEnd
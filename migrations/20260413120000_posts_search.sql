@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the 'simple' configuration doesn't stem, so it works the same for every language posts are written in
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', description)) STORED;
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_posts_search_vector;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE TABLE IF NOT EXISTS profiles (
+    user_id UUID PRIMARY KEY,
+    -- display name, shown next to the username
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- trigram indexes serve both the prefix and the substring ILIKE matches of user search
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (LOWER(username) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_profiles_name_trgm ON profiles USING GIN (LOWER(name) gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_users_username_trgm;
+DROP TABLE IF EXISTS profiles;
+-- +goose StatementEnd
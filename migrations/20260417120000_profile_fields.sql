@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE profiles
+    ADD COLUMN IF NOT EXISTS bio VARCHAR(300) NOT NULL DEFAULT '',
+    -- empty when not specified
+    ADD COLUMN IF NOT EXISTS gender VARCHAR(10) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS age SMALLINT;
+-- profiles are created on registration from now on, earlier users get an empty one
+INSERT INTO profiles (user_id) SELECT id FROM users ON CONFLICT (user_id) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE profiles DROP COLUMN IF EXISTS bio, DROP COLUMN IF EXISTS gender, DROP COLUMN IF EXISTS age;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE profiles
+    ADD COLUMN IF NOT EXISTS followers_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS following_count INTEGER NOT NULL DEFAULT 0;
+UPDATE profiles SET
+    followers_count = (SELECT COUNT(*) FROM follows WHERE followee_id = profiles.user_id),
+    following_count = (SELECT COUNT(*) FROM follows WHERE follower_id = profiles.user_id);
+-- follower and following lists page through (created_at, id) of the follow
+CREATE INDEX IF NOT EXISTS idx_follows_followee_created ON follows(followee_id, created_at DESC, follower_id DESC);
+CREATE INDEX IF NOT EXISTS idx_follows_follower_created ON follows(follower_id, created_at DESC, followee_id DESC);
+DROP INDEX IF EXISTS idx_follows_followee;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows(followee_id);
+DROP INDEX IF EXISTS idx_follows_follower_created;
+DROP INDEX IF EXISTS idx_follows_followee_created;
+ALTER TABLE profiles DROP COLUMN IF EXISTS followers_count, DROP COLUMN IF EXISTS following_count;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE user_settings
+    -- who sees the profile details and finds the account's posts in explore, hashtags and search
+    ADD COLUMN IF NOT EXISTS privacy_level TEXT NOT NULL DEFAULT 'everyone'
+        CHECK (privacy_level IN ('everyone', 'followers', 'nobody')),
+    ADD COLUMN IF NOT EXISTS notify_likes BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS notify_comments BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS notify_follows BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS notify_mentions BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS notify_messages BOOLEAN NOT NULL DEFAULT TRUE;
+-- settings are created on registration from now on, earlier users get the defaults
+INSERT INTO user_settings (user_id) SELECT id FROM users ON CONFLICT (user_id) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE user_settings
+    DROP COLUMN IF EXISTS privacy_level,
+    DROP COLUMN IF EXISTS notify_likes,
+    DROP COLUMN IF EXISTS notify_comments,
+    DROP COLUMN IF EXISTS notify_follows,
+    DROP COLUMN IF EXISTS notify_mentions,
+    DROP COLUMN IF EXISTS notify_messages;
+-- +goose StatementEnd
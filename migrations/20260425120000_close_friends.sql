@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS close_friends (
+    user_id UUID NOT NULL,
+    friend_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, friend_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (friend_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (user_id <> friend_id)
+);
+-- the list pages through (created_at, friend_id)
+CREATE INDEX IF NOT EXISTS idx_close_friends_user_created ON close_friends(user_id, created_at DESC, friend_id DESC);
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_visibility_check;
+ALTER TABLE posts ADD CONSTRAINT posts_visibility_check
+    CHECK (visibility IN ('public', 'followers', 'close_friends', 'private'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+UPDATE posts SET visibility = 'private' WHERE visibility = 'close_friends';
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_visibility_check;
+ALTER TABLE posts ADD CONSTRAINT posts_visibility_check CHECK (visibility IN ('public', 'followers', 'private'));
+DROP TABLE IF EXISTS close_friends;
+-- +goose StatementEnd
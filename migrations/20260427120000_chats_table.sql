@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS chats (
+    id UUID PRIMARY KEY,
+    -- the pair is stored ordered, so there is one chat per pair of users
+    user1_id UUID NOT NULL,
+    user2_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- the time of the last message, or of the creation for chats without messages; chats are listed by it
+    last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (user1_id, user2_id),
+    FOREIGN KEY (user1_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user2_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (user1_id < user2_id)
+);
+CREATE INDEX IF NOT EXISTS idx_chats_user1_activity ON chats(user1_id, last_activity_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_chats_user2_activity ON chats(user2_id, last_activity_at DESC, id DESC);
+CREATE TABLE IF NOT EXISTS messages (
+    id UUID PRIMARY KEY,
+    chat_id UUID NOT NULL,
+    sender_id UUID NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- the history pages through (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_messages_chat_created ON messages(chat_id, created_at DESC, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS chats;
+-- +goose StatementEnd
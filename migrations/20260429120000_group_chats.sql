@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS is_group BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS title VARCHAR(100);
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS avatar_url TEXT;
+-- anyone can join a public group without being added
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS public BOOLEAN NOT NULL DEFAULT FALSE;
+-- the pair only identifies direct chats, groups are found through chat_members
+ALTER TABLE chats ALTER COLUMN user1_id DROP NOT NULL;
+ALTER TABLE chats ALTER COLUMN user2_id DROP NOT NULL;
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_check;
+ALTER TABLE chats ADD CONSTRAINT chats_check CHECK (
+    (is_group AND user1_id IS NULL AND user2_id IS NULL) OR (NOT is_group AND user1_id < user2_id)
+);
+CREATE TABLE IF NOT EXISTS chat_members (
+    chat_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'admin')),
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (chat_id, user_id),
+    FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_chat_members_user ON chat_members(user_id, chat_id);
+-- the member list pages through (joined_at, user_id)
+CREATE INDEX IF NOT EXISTS idx_chat_members_chat_joined ON chat_members(chat_id, joined_at, user_id);
+INSERT INTO chat_members (chat_id, user_id, joined_at)
+SELECT id, user1_id, created_at FROM chats WHERE NOT is_group
+UNION ALL
+SELECT id, user2_id, created_at FROM chats WHERE NOT is_group
+ON CONFLICT (chat_id, user_id) DO NOTHING;
+DROP INDEX IF EXISTS idx_chats_user1_activity;
+DROP INDEX IF EXISTS idx_chats_user2_activity;
+CREATE INDEX IF NOT EXISTS idx_chats_activity ON chats(last_activity_at DESC, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_chats_activity;
+DROP TABLE IF EXISTS chat_members;
+DELETE FROM chats WHERE is_group;
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_check;
+ALTER TABLE chats ALTER COLUMN user1_id SET NOT NULL;
+ALTER TABLE chats ALTER COLUMN user2_id SET NOT NULL;
+ALTER TABLE chats ADD CONSTRAINT chats_check CHECK (user1_id < user2_id);
+ALTER TABLE chats DROP COLUMN IF EXISTS public;
+ALTER TABLE chats DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE chats DROP COLUMN IF EXISTS title;
+ALTER TABLE chats DROP COLUMN IF EXISTS is_group;
+CREATE INDEX IF NOT EXISTS idx_chats_user1_activity ON chats(user1_id, last_activity_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_chats_user2_activity ON chats(user2_id, last_activity_at DESC, id DESC);
+-- +goose StatementEnd
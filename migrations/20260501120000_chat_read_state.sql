@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the last message the member read and its time, messages of others sent after it are unread
+ALTER TABLE chat_members ADD COLUMN IF NOT EXISTS last_read_message_id UUID REFERENCES messages(id) ON DELETE SET NULL;
+ALTER TABLE chat_members ADD COLUMN IF NOT EXISTS last_read_at TIMESTAMP WITH TIME ZONE;
+-- existing chats start out read, so members aren't flooded with old unread messages
+UPDATE chat_members cm SET last_read_at = c.last_activity_at FROM chats c WHERE c.id = cm.chat_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE chat_members DROP COLUMN IF EXISTS last_read_at;
+ALTER TABLE chat_members DROP COLUMN IF EXISTS last_read_message_id;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- chat events stored in the transaction of the change they're about and published by the relay, rows are deleted once published
+CREATE TABLE IF NOT EXISTS chat_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(32) NOT NULL,
+    chat_id UUID NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    message_id UUID REFERENCES messages(id) ON DELETE CASCADE,
+    -- the members of the chat when the event happened
+    recipients UUID[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS chat_outbox;
+-- +goose StatementEnd
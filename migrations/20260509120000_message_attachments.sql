@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the uploaded file of media messages: url, type, size, dimensions or duration and thumbnail
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS attachment JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE messages DROP COLUMN IF EXISTS attachment;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- events written by producers and fanned out to notifications by the workers, rows are deleted once fanned out
+CREATE TABLE IF NOT EXISTS notification_events (
+    id BIGSERIAL PRIMARY KEY,
+    payload JSONB NOT NULL,
+    -- a worker holds the event until then, a crashed worker's events are picked up again after it
+    locked_until TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    chat_id UUID REFERENCES chats(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    read_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications (user_id, created_at DESC, id DESC);
+-- unread badge counts
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications (user_id) WHERE read_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_events;
+-- +goose StatementEnd
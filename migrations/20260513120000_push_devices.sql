@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- devices notifications are pushed to while their user has no real-time connection open
+CREATE TABLE IF NOT EXISTS push_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(16) NOT NULL CHECK (platform IN ('fcm', 'apns', 'webpush')),
+    -- the registration token, or the push service endpoint of a WebPush subscription
+    token TEXT NOT NULL,
+    -- keys of a WebPush subscription the payload is encrypted for
+    p256dh TEXT,
+    auth TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    -- a token belongs to the last user who registered it, e.g. after switching accounts on a phone
+    UNIQUE (platform, token)
+);
+CREATE INDEX IF NOT EXISTS idx_push_devices_user ON push_devices (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS push_devices;
+-- +goose StatementEnd
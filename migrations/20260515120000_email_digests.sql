@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE user_settings
+    ADD COLUMN IF NOT EXISTS email_digest TEXT NOT NULL DEFAULT 'off'
+        CHECK (email_digest IN ('off', 'daily', 'weekly')),
+    -- the activity since then goes into the next digest
+    ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_user_settings_email_digest ON user_settings (email_digest, digest_sent_at) WHERE email_digest <> 'off';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_user_settings_email_digest;
+ALTER TABLE user_settings
+    DROP COLUMN IF EXISTS email_digest,
+    DROP COLUMN IF EXISTS digest_sent_at;
+-- +goose StatementEnd
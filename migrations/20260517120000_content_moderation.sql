@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- shadow-limited content is only shown to its author
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS limited BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS limited BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- content the content policy flagged or limited, waiting for a reviewer
+CREATE TABLE IF NOT EXISTS content_flags (
+    id UUID PRIMARY KEY,
+    content_type TEXT NOT NULL CHECK (content_type IN ('post', 'comment', 'message')),
+    content_id UUID NOT NULL,
+    author_id UUID NOT NULL,
+    verdict TEXT NOT NULL CHECK (verdict IN ('flag', 'limit')),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_content_flags_open ON content_flags(created_at) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_content_flags_content ON content_flags(content_type, content_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS content_flags;
+ALTER TABLE comments DROP COLUMN IF EXISTS limited;
+ALTER TABLE posts DROP COLUMN IF EXISTS limited;
+-- +goose StatementEnd
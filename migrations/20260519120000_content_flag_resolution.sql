@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE content_flags
+    ADD COLUMN IF NOT EXISTS resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS resolution TEXT CHECK (resolution IN ('dismissed', 'removed'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE content_flags
+    DROP COLUMN IF EXISTS resolution,
+    DROP COLUMN IF EXISTS resolved_by;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- domain events stored in the transaction of the change they're about and published to Kafka by the relay,
+-- rows are deleted once published. event_id is sent along so consumers can drop the duplicates a relay that
+-- failed after publishing may produce
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    event_key UUID NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS event_outbox;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- when each scheduled job last started, so a job runs once per interval across all instances
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name VARCHAR(64) PRIMARY KEY,
+    last_run_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_email_change_requests_expires_at ON email_change_requests(expires_at);
+CREATE INDEX IF NOT EXISTS idx_login_confirmations_expires_at ON login_confirmations(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_login_confirmations_expires_at;
+DROP INDEX IF EXISTS idx_email_change_requests_expires_at;
+DROP INDEX IF EXISTS idx_sessions_expires_at;
+DROP TABLE IF EXISTS scheduled_jobs;
+-- +goose StatementEnd
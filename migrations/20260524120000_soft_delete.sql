@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- deleted posts and comments are kept for the undelete window, then purged
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- messages deleted for everyone keep their content for the undelete window, purging erases it and leaves the tombstone
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS purged_at TIMESTAMP WITH TIME ZONE;
+UPDATE messages SET purged_at = deleted_at WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_pending_purge ON messages(deleted_at) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_messages_pending_purge;
+UPDATE messages SET content = '', attachment = NULL WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
+ALTER TABLE messages DROP COLUMN IF EXISTS purged_at;
+DROP INDEX IF EXISTS idx_comments_deleted_at;
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+DELETE FROM comments WHERE deleted_at IS NOT NULL;
+DELETE FROM posts WHERE deleted_at IS NOT NULL;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
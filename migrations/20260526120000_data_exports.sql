@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    -- the archive in the export storage, set when the export is ready
+    object_key TEXT,
+    size BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- exports processing for too long were abandoned by a crashed job and are taken again
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    -- the archive is deleted then
+    expires_at TIMESTAMP WITH TIME ZONE,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- one export of a user is prepared at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_user_active ON data_exports(user_id) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_created ON data_exports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_data_exports_queue ON data_exports(created_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires ON data_exports(expires_at) WHERE expires_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS data_exports;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the version of a post or profile changes with every change of the row, counters included, and makes its ETag
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_row_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- updates leaving the row as it was, e.g. of the counter reconciliation, keep the version
+CREATE TRIGGER posts_bump_version
+    BEFORE UPDATE ON posts
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION bump_row_version();
+CREATE TRIGGER profiles_bump_version
+    BEFORE UPDATE ON profiles
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION bump_row_version();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TRIGGER IF EXISTS profiles_bump_version ON profiles;
+DROP TRIGGER IF EXISTS posts_bump_version ON posts;
+DROP FUNCTION IF EXISTS bump_row_version();
+ALTER TABLE profiles DROP COLUMN IF EXISTS version;
+ALTER TABLE posts DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- updates must name the version they were made on, so the version only changes with what users edit: counters
+-- changing all the time would fail the edits of popular posts and profiles
+DROP TRIGGER IF EXISTS posts_bump_version ON posts;
+CREATE TRIGGER posts_bump_version
+    BEFORE UPDATE ON posts
+    FOR EACH ROW WHEN ((OLD.description, OLD.visibility, OLD.media_url)
+        IS DISTINCT FROM (NEW.description, NEW.visibility, NEW.media_url))
+    EXECUTE FUNCTION bump_row_version();
+DROP TRIGGER IF EXISTS profiles_bump_version ON profiles;
+CREATE TRIGGER profiles_bump_version
+    BEFORE UPDATE ON profiles
+    FOR EACH ROW WHEN ((OLD.name, OLD.bio, OLD.avatar_url, OLD.gender, OLD.age)
+        IS DISTINCT FROM (NEW.name, NEW.bio, NEW.avatar_url, NEW.gender, NEW.age))
+    EXECUTE FUNCTION bump_row_version();
+
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+-- sending a digest isn't a change of the settings
+CREATE TRIGGER user_settings_bump_version
+    BEFORE UPDATE ON user_settings
+    FOR EACH ROW WHEN ((OLD.privacy_level, OLD.private_account, OLD.notify_likes, OLD.notify_comments,
+            OLD.notify_follows, OLD.notify_mentions, OLD.notify_messages, OLD.email_digest)
+        IS DISTINCT FROM (NEW.privacy_level, NEW.private_account, NEW.notify_likes, NEW.notify_comments,
+            NEW.notify_follows, NEW.notify_mentions, NEW.notify_messages, NEW.email_digest))
+    EXECUTE FUNCTION bump_row_version();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TRIGGER IF EXISTS user_settings_bump_version ON user_settings;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS version;
+DROP TRIGGER IF EXISTS profiles_bump_version ON profiles;
+CREATE TRIGGER profiles_bump_version
+    BEFORE UPDATE ON profiles
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION bump_row_version();
+DROP TRIGGER IF EXISTS posts_bump_version ON posts;
+CREATE TRIGGER posts_bump_version
+    BEFORE UPDATE ON posts
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION bump_row_version();
+-- +goose StatementEnd
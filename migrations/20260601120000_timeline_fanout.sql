@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- fan-out on write pages through the followers of an author by ID
+CREATE INDEX IF NOT EXISTS idx_follows_followee_follower ON follows(followee_id, follower_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_follows_followee_follower;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- feed ranking counts the recent comments of a user on each author
+CREATE INDEX IF NOT EXISTS idx_comments_user_created ON comments(user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_comments_user_created;
+-- +goose StatementEnd
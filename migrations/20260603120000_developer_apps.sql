@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS developer_apps (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    -- sha256 of the client secret, the plain secret is never stored
+    secret_hash BYTEA NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    -- requests per minute, NULL for the default limit; raised by operators for trusted integrations
+    rate_limit INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_developer_apps_owner ON developer_apps(owner_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS developer_apps;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE IF NOT EXISTS data_imports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    source TEXT NOT NULL CHECK (source IN ('twitter', 'threads', 'json')),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    -- the follows and posts parsed from the uploaded archive, cleared when the import ends
+    archive JSONB,
+    follows_total INT NOT NULL DEFAULT 0,
+    follows_processed INT NOT NULL DEFAULT 0,
+    follows_imported INT NOT NULL DEFAULT 0,
+    posts_total INT NOT NULL DEFAULT 0,
+    posts_processed INT NOT NULL DEFAULT 0,
+    posts_imported INT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- when the progress was last saved, imports processing without progress for too long were abandoned by
+    -- a crashed job and are resumed
+    updated_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- one import of a user is applied at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_imports_user_active ON data_imports(user_id) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_data_imports_queue ON data_imports(created_at) WHERE status IN ('pending', 'processing');
+-- imported follows are matched by email as well as by username
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS idx_users_email_lower;
+DROP TABLE IF EXISTS data_imports;
+-- +goose StatementEnd
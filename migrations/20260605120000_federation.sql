@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the key pair each federated user signs their activities with, made when it's first needed
+CREATE TABLE IF NOT EXISTS federation_keys (
+    user_id UUID PRIMARY KEY,
+    private_key TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- accounts of other servers, cached from their actor documents
+CREATE TABLE IF NOT EXISTS remote_actors (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL DEFAULT '',
+    inbox TEXT NOT NULL,
+    shared_inbox TEXT,
+    key_id TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_remote_actors_key_id ON remote_actors(key_id);
+
+CREATE TABLE IF NOT EXISTS remote_followers (
+    user_id UUID NOT NULL,
+    actor_id TEXT NOT NULL,
+    -- the Follow activity, accepted by referring to it
+    follow_id TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, actor_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_remote_followers_actor ON remote_followers(actor_id);
+
+-- activities waiting to be delivered, one row per inbox
+CREATE TABLE IF NOT EXISTS federation_deliveries (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    inbox TEXT NOT NULL,
+    activity JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_federation_deliveries_due ON federation_deliveries(next_attempt_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS federation_deliveries;
+DROP TABLE IF EXISTS remote_followers;
+DROP TABLE IF EXISTS remote_actors;
+DROP TABLE IF EXISTS federation_keys;
+-- +goose StatementEnd
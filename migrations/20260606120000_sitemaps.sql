@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the generated sitemap files, replaced as a whole by every run of the sitemap job
+CREATE TABLE IF NOT EXISTS sitemaps (
+    idx INTEGER PRIMARY KEY,
+    content TEXT NOT NULL,
+    url_count INTEGER NOT NULL,
+    generated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS sitemaps;
+-- +goose StatementEnd
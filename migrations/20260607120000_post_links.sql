@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the URLs of post descriptions, each shortened to /l/<code>; clicks is batched like the other counters
+CREATE TABLE IF NOT EXISTS post_links (
+    id UUID PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    post_id UUID NOT NULL,
+    -- the order of the links in the description
+    position SMALLINT NOT NULL,
+    -- the URL as written in the description
+    text TEXT NOT NULL,
+    -- the canonical target
+    url TEXT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    -- an edit keeps the code and clicks of the links it doesn't change
+    UNIQUE (post_id, url)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS post_links;
+-- +goose StatementEnd
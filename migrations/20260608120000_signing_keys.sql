@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- the access token signing keys of all instances, each instance loads them periodically
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id TEXT PRIMARY KEY,
+    -- PEM encoded PKCS#8 sealed with AES-GCM under jwt.key_encryption_key, the nonce first
+    encrypted_key BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- tokens are signed with the newest active key, a rotated key is stored before it becomes active
+    active_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS signing_keys;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- follows of private accounts wait here until the owner approves them, follows only holds approved follows
+CREATE TABLE IF NOT EXISTS follow_requests (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (follower_id <> followee_id)
+);
+-- the owner pages through the requests by (created_at, follower_id)
+CREATE INDEX IF NOT EXISTS idx_follow_requests_followee_created ON follow_requests(followee_id, created_at DESC, follower_id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE IF EXISTS follow_requests;
+-- +goose StatementEnd
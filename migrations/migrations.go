@@ -0,0 +1,9 @@
+// Package migrations embeds the versioned SQL migrations of the database schema, so the binary can apply them itself.
+package migrations
+
+import "embed"
+
+// FS holds the goose-style migrations, named <version>_<name>.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,197 @@
+// Package activitypub has the ActivityStreams documents and the WebFinger resource descriptors that servers of
+// the fediverse such as Mastodon exchange, limited to what federating profiles and posts needs, and a client
+// fetching remote actors and delivering activities to their inboxes.
+package activitypub
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+)
+
+const (
+	// ContentType is the media type of ActivityPub documents.
+	ContentType = "application/activity+json"
+	// LDContentType is the JSON-LD media type ActivityPub documents may also be requested and sent with.
+	LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+	// JRDContentType is the media type of WebFinger responses.
+	JRDContentType = "application/jrd+json"
+
+	// Public is the collection addressing an object to everyone.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// Context is the JSON-LD context of the documents, ActivityStreams and the security vocabulary of public keys.
+var Context = []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"}
+
+// Types of the objects and activities that are sent or handled.
+const (
+	TypePerson   = "Person"
+	TypeNote     = "Note"
+	TypeDocument = "Document"
+	// TypeTombstone replaces a deleted object
+	TypeTombstone = "Tombstone"
+
+	TypeCreate   = "Create"
+	TypeDelete   = "Delete"
+	TypeFollow   = "Follow"
+	TypeAccept   = "Accept"
+	TypeReject   = "Reject"
+	TypeUndo     = "Undo"
+	TypeAnnounce = "Announce"
+
+	TypeOrderedCollection     = "OrderedCollection"
+	TypeOrderedCollectionPage = "OrderedCollectionPage"
+)
+
+// PublicKey is the key an actor signs its requests with.
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Endpoints are the endpoints an actor shares with the other actors of its server.
+type Endpoints struct {
+	// SharedInbox receives activities addressed to several actors of the server once
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+// Image is an image or another attached file of an actor or an object.
+type Image struct {
+	Type      string `json:"type"`
+	MediaType string `json:"mediaType,omitempty"`
+	URL       string `json:"url"`
+}
+
+// Actor is an account, a Person for users.
+type Actor struct {
+	Context                   any        `json:"@context,omitempty"`
+	ID                        string     `json:"id"`
+	Type                      string     `json:"type"`
+	PreferredUsername         string     `json:"preferredUsername"`
+	Name                      string     `json:"name,omitempty"`
+	Summary                   string     `json:"summary,omitempty"`
+	Inbox                     string     `json:"inbox"`
+	Outbox                    string     `json:"outbox,omitempty"`
+	Followers                 string     `json:"followers,omitempty"`
+	Following                 string     `json:"following,omitempty"`
+	ManuallyApprovesFollowers bool       `json:"manuallyApprovesFollowers"`
+	Published                 *time.Time `json:"published,omitempty"`
+	Icon                      *Image     `json:"icon,omitempty"`
+	PublicKey                 PublicKey  `json:"publicKey"`
+	Endpoints                 *Endpoints `json:"endpoints,omitempty"`
+}
+
+// Note is a post.
+type Note struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	AttributedTo string     `json:"attributedTo,omitempty"`
+	Content      string     `json:"content,omitempty"`
+	Published    *time.Time `json:"published,omitempty"`
+	Updated      *time.Time `json:"updated,omitempty"`
+	To           []string   `json:"to,omitempty"`
+	Cc           []string   `json:"cc,omitempty"`
+	Attachment   []Image    `json:"attachment,omitempty"`
+}
+
+// Activity is something an actor did to an object. Object is the object itself or its ID; in received
+// activities it is a string or a map of the decoded JSON object.
+type Activity struct {
+	Context   any        `json:"@context,omitempty"`
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	Actor     string     `json:"actor"`
+	Object    any        `json:"object"`
+	Published *time.Time `json:"published,omitempty"`
+	To        []string   `json:"to,omitempty"`
+	Cc        []string   `json:"cc,omitempty"`
+}
+
+// ObjectID returns the ID of the object of a received activity, empty if it has none.
+func (a Activity) ObjectID() string {
+	switch o := a.Object.(type) {
+	case string:
+		return o
+	case map[string]any:
+		id, _ := o["id"].(string)
+		return id
+	}
+	return ""
+}
+
+// ObjectType returns the type of the object of a received activity if it's embedded, empty otherwise.
+func (a Activity) ObjectType() string {
+	if o, ok := a.Object.(map[string]any); ok {
+		t, _ := o["type"].(string)
+		return t
+	}
+	return ""
+}
+
+// ObjectField returns a string field of the object of a received activity if it's embedded, e.g. the object
+// of an undone Follow.
+func (a Activity) ObjectField(name string) string {
+	if o, ok := a.Object.(map[string]any); ok {
+		v, _ := o[name].(string)
+		return v
+	}
+	return ""
+}
+
+// OrderedCollection is a collection of an actor, like its outbox or followers. Its items are on the pages,
+// starting with First.
+type OrderedCollection struct {
+	Context    any    `json:"@context,omitempty"`
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	TotalItems int    `json:"totalItems"`
+	First      string `json:"first,omitempty"`
+}
+
+// OrderedCollectionPage is a page of a collection, Next is empty on the last one.
+type OrderedCollectionPage struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	PartOf       string `json:"partOf"`
+	Next         string `json:"next,omitempty"`
+	OrderedItems []any  `json:"orderedItems"`
+}
+
+// JRD is a WebFinger resource descriptor (RFC 7033), it links an acct: URI to the actor.
+type JRD struct {
+	Subject string   `json:"subject"`
+	Aliases []string `json:"aliases,omitempty"`
+	Links   []Link   `json:"links"`
+}
+
+type Link struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// ParsePublicKey parses a PEM encoded RSA public key of an actor, in PKIX or PKCS #1 form.
+func ParsePublicKey(pemKey string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("activitypub: public key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("activitypub: public key is not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,149 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"main/pkg/httpsig"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxDocumentSize limits the size of fetched documents, actors are a few kilobytes.
+const maxDocumentSize = 1 << 20
+
+// ErrForbiddenAddress is returned for requests to the loopback, private or link-local addresses a server of
+// the fediverse can't be on, so remote servers can't make this one reach into its own network.
+var ErrForbiddenAddress = errors.New("activitypub: address is not public")
+
+// StatusError is a response of a remote server with an unexpected status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("activitypub: %s answered %d", e.URL, e.StatusCode)
+}
+
+// Client fetches documents from remote servers and delivers activities to them.
+type Client struct {
+	client    *http.Client
+	userAgent string
+	// insecure allows plain HTTP and non-public addresses, for federating between local test servers
+	insecure bool
+}
+
+// NewClient returns a client identifying itself with the user agent. Requests time out after timeout; unless
+// insecure is set, they are only made over HTTPS to public addresses.
+func NewClient(userAgent string, timeout time.Duration, insecure bool) *Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !insecure {
+		// checked on the resolved address, so a public name resolving to a private address is refused too
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+				ip.IsUnspecified() || ip.IsMulticast() {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	c := &Client{
+		userAgent: userAgent,
+		insecure:  insecure,
+	}
+	c.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("activitypub: too many redirects")
+			}
+			return c.checkURL(req.URL.String())
+		},
+	}
+	return c
+}
+
+func (c *Client) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && !(c.insecure && u.Scheme == "http") {
+		return fmt.Errorf("activitypub: %s is not an HTTPS URL", rawURL)
+	}
+	return nil
+}
+
+// FetchActor fetches the actor document at the URL.
+func (c *Client) FetchActor(ctx context.Context, actorURL string) (Actor, error) {
+	if err := c.checkURL(actorURL); err != nil {
+		return Actor{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorURL, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+	req.Header.Set("Accept", ContentType+", "+LDContentType)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Actor{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Actor{}, &StatusError{URL: actorURL, StatusCode: resp.StatusCode}
+	}
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&actor); err != nil {
+		return Actor{}, fmt.Errorf("activitypub: invalid actor %s: %w", actorURL, err)
+	}
+	if actor.ID == "" || actor.Inbox == "" {
+		return Actor{}, fmt.Errorf("activitypub: actor %s has no id or inbox", actorURL)
+	}
+	return actor, nil
+}
+
+// Deliver posts the activity, already encoded, to the inbox signed with the key of the sending actor.
+func (c *Client) Deliver(ctx context.Context, inbox string, activity []byte, keyID string, key *rsa.PrivateKey) error {
+	if err := c.checkURL(inbox); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(activity))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", c.userAgent)
+	if err := httpsig.Sign(req, keyID, key, activity); err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: inbox, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package apperror defines the errors usecases return for failures the caller can act on. Each error has a Kind,
+// which the HTTP and gRPC layers map to a status code in one place, and a machine-readable Code, e.g.
+// "email_taken", clients can switch on instead of parsing the message. Any other error is reported to clients
+// as an internal error without details.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Kind is the category of an error, it decides the HTTP status and the gRPC code.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalidArgument
+	KindNotFound
+	KindAlreadyExists
+	KindUnauthenticated
+	KindPermissionDenied
+	// KindFailedPrecondition is a valid request the current state doesn't allow, e.g. a missing CAPTCHA
+	KindFailedPrecondition
+	KindResourceExhausted
+	// KindUnavailable is a dependency that is down for now, the request may succeed when retried later
+	KindUnavailable
+	// KindRequestTooLarge is a request bigger than the server accepts, e.g. a body over the limit of its route
+	KindRequestTooLarge
+	// KindConflict is a change that lost to a concurrent one, e.g. an update of an outdated version; it may be
+	// retried after reading the current state
+	KindConflict
+)
+
+// Error is an error the client is told about, Message is safe to show to the user.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Fields are the violations of an invalid request, one per field
+	Fields []FieldViolation
+}
+
+// FieldViolation tells what is wrong with one field of a request. Field is the path of the field as the client
+// sent it, e.g. "notifications.likes".
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+	// Rule names the broken rule and Params are its parameters, e.g. "min_len" and ["8"], so the English
+	// Description can be translated
+	Rule   string   `json:"-"`
+	Params []string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New returns an error of the kind with a machine-readable code, lowercase with underscores.
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func InvalidArgument(code, message string) *Error {
+	return New(KindInvalidArgument, code, message)
+}
+
+// Invalid returns the InvalidArgument error of a request with the violations, its message lists them all.
+func Invalid(fields []FieldViolation) *Error {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.Field + " " + f.Description
+	}
+	return &Error{Kind: KindInvalidArgument, Code: "invalid_request", Message: strings.Join(parts, "; "), Fields: fields}
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func AlreadyExists(code, message string) *Error {
+	return New(KindAlreadyExists, code, message)
+}
+
+func Unauthenticated(code, message string) *Error {
+	return New(KindUnauthenticated, code, message)
+}
+
+func PermissionDenied(code, message string) *Error {
+	return New(KindPermissionDenied, code, message)
+}
+
+func FailedPrecondition(code, message string) *Error {
+	return New(KindFailedPrecondition, code, message)
+}
+
+func ResourceExhausted(code, message string) *Error {
+	return New(KindResourceExhausted, code, message)
+}
+
+func Unavailable(code, message string) *Error {
+	return New(KindUnavailable, code, message)
+}
+
+func RequestTooLarge(code, message string) *Error {
+	return New(KindRequestTooLarge, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// From returns the Error in the chain of err, or nil if there is none.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
+
+// HTTPStatus returns the HTTP status errors of the kind are answered with.
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case KindInvalidArgument:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindAlreadyExists, KindConflict:
+		return http.StatusConflict
+	case KindUnauthenticated:
+		return http.StatusUnauthorized
+	case KindPermissionDenied, KindFailedPrecondition:
+		return http.StatusForbidden
+	case KindResourceExhausted:
+		return http.StatusTooManyRequests
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindRequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode returns the gRPC code errors of the kind are answered with.
+func (k Kind) GRPCCode() codes.Code {
+	switch k {
+	case KindInvalidArgument:
+		return codes.InvalidArgument
+	case KindNotFound:
+		return codes.NotFound
+	case KindAlreadyExists:
+		return codes.AlreadyExists
+	case KindUnauthenticated:
+		return codes.Unauthenticated
+	case KindPermissionDenied:
+		return codes.PermissionDenied
+	case KindFailedPrecondition:
+		return codes.FailedPrecondition
+	case KindResourceExhausted, KindRequestTooLarge:
+		return codes.ResourceExhausted
+	case KindUnavailable:
+		return codes.Unavailable
+	case KindConflict:
+		return codes.Aborted
+	default:
+		return codes.Internal
+	}
+}
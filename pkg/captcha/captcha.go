@@ -0,0 +1,105 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Siteverify endpoints of the supported providers, they all share the same request and response format.
+const (
+	HCaptchaURL  = "https://api.hcaptcha.com/siteverify"
+	ReCaptchaURL = "https://www.google.com/recaptcha/api/siteverify"
+	TurnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+var (
+	ErrMissingToken = errors.New("captcha token is required")
+	ErrRejected     = errors.New("captcha verification failed")
+)
+
+// Verifier checks a CAPTCHA token solved by the client.
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// NopVerifier accepts every token, it is used when CAPTCHA is disabled.
+type NopVerifier struct{}
+
+func (NopVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	return nil
+}
+
+// SiteVerifier verifies tokens with the siteverify API of hCaptcha, reCAPTCHA or Turnstile.
+type SiteVerifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewSiteVerifier(verifyURL, secret string, timeout time.Duration) *SiteVerifier {
+	return &SiteVerifier{
+		url:    verifyURL,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// NewVerifier returns the verifier of the named provider ("hcaptcha", "recaptcha" or "turnstile").
+func NewVerifier(provider, secret string, timeout time.Duration) (*SiteVerifier, error) {
+	switch strings.ToLower(provider) {
+	case "hcaptcha":
+		return NewSiteVerifier(HCaptchaURL, secret, timeout), nil
+	case "recaptcha":
+		return NewSiteVerifier(ReCaptchaURL, secret, timeout), nil
+	case "turnstile":
+		return NewSiteVerifier(TurnstileURL, secret, timeout), nil
+	default:
+		return nil, fmt.Errorf("unsupported captcha provider %q", provider)
+	}
+}
+
+type siteverifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify returns ErrMissingToken for an empty token and ErrRejected if the provider doesn't accept it.
+func (v *SiteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha siteverify: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha siteverify: unexpected status %d", resp.StatusCode)
+	}
+
+	var result siteverifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("captcha siteverify: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrRejected, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
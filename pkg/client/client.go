@@ -0,0 +1,185 @@
+// Package client connects other services to the gRPC API. It wraps the generated clients with the options every
+// caller needs: calls are authenticated with the service's API key, or the access token of the user the service
+// acts for, get a deadline when they have none and are retried while the server is unavailable.
+//
+//	c, err := client.New("dns:///threads:50052", client.Options{APIKey: os.Getenv("THREADS_API_KEY")})
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//	resp, err := c.Auth.Introspect(ctx, &authpb.IntrospectRequest{Token: token})
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	adminpb "main/pkg/proto/gen/admin/v1"
+	authpb "main/pkg/proto/gen/auth/v1"
+	chatpb "main/pkg/proto/gen/chat/v1"
+	commentspb "main/pkg/proto/gen/comments/v1"
+	notificationspb "main/pkg/proto/gen/notifications/v1"
+	postspb "main/pkg/proto/gen/posts/v1"
+	profilepb "main/pkg/proto/gen/profile/v1"
+	ctxUtil "main/pkg/utils/context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DefaultTimeout is the deadline of unary calls whose context has none.
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxAttempts is how often a call failing with UNAVAILABLE is tried, including the first attempt.
+	DefaultMaxAttempts = 3
+)
+
+// Options configure the connections of a Client, the zero value connects without TLS and authentication.
+type Options struct {
+	// TLS secures the connections, nil for plain connections, e.g. inside the cluster network
+	TLS *tls.Config
+	// APIKey authenticates the service on calls whose context carries no access token
+	APIKey string
+	// Timeout of unary calls without a deadline, DefaultTimeout if 0; streams are never limited
+	Timeout time.Duration
+	// MaxAttempts of calls failing with UNAVAILABLE, DefaultMaxAttempts if 0 and 1 for no retries; gRPC allows 5 at most
+	MaxAttempts int
+	// Conns is the number of connections calls are spread over, 1 if 0
+	Conns int
+	// Compress gzips the calls, and so the responses, worth it over links where bandwidth costs more than CPU
+	Compress bool
+	// DialOptions are added to those of the client, e.g. for tracing
+	DialOptions []grpc.DialOption
+}
+
+// Client holds the clients of the services, sharing the connections to the target.
+type Client struct {
+	Auth          authpb.AuthServiceClient
+	Admin         adminpb.AdminServiceClient
+	Posts         postspb.PostServiceClient
+	Comments      commentspb.CommentServiceClient
+	Profiles      profilepb.ProfileServiceClient
+	Chat          chatpb.ChatServiceClient
+	Notifications notificationspb.NotificationServiceClient
+
+	conns *pool
+}
+
+// New connects to the target, e.g. "dns:///threads:50052" to balance the calls over all addresses of the name.
+// Connections are established lazily, by the first call.
+func New(target string, opts Options) (*Client, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Conns == 0 {
+		opts.Conns = 1
+	}
+
+	creds := insecure.NewCredentials()
+	if opts.TLS != nil {
+		creds = credentials.NewTLS(opts.TLS)
+	}
+	dialOptions := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig(opts.MaxAttempts)),
+		grpc.WithChainUnaryInterceptor(unaryInterceptor(opts)),
+		grpc.WithChainStreamInterceptor(streamInterceptor(opts)),
+	}, opts.DialOptions...)
+	if opts.Compress {
+		dialOptions = append(dialOptions, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	conns, err := newPool(target, opts.Conns, dialOptions)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", target, err)
+	}
+	return &Client{
+		Auth:          authpb.NewAuthServiceClient(conns),
+		Admin:         adminpb.NewAdminServiceClient(conns),
+		Posts:         postspb.NewPostServiceClient(conns),
+		Comments:      commentspb.NewCommentServiceClient(conns),
+		Profiles:      profilepb.NewProfileServiceClient(conns),
+		Chat:          chatpb.NewChatServiceClient(conns),
+		Notifications: notificationspb.NewNotificationServiceClient(conns),
+		conns:         conns,
+	}, nil
+}
+
+// Close closes the connections, calls in progress fail.
+func (c *Client) Close() error {
+	return c.conns.Close()
+}
+
+type accessTokenKey struct{}
+
+// WithAccessToken makes the calls with the context act for the user of the access token, instead of the service
+// of the API key.
+func WithAccessToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessTokenKey{}, token)
+}
+
+// serviceConfig retries calls of every method that failed with UNAVAILABLE, the server didn't process them, and
+// balances them over all addresses of the target.
+func serviceConfig(maxAttempts int) string {
+	if maxAttempts < 2 {
+		return `{"loadBalancingConfig": [{"round_robin": {}}]}`
+	}
+	return fmt.Sprintf(`{
+		"loadBalancingConfig": [{"round_robin": {}}],
+		"methodConfig": [{
+			"name": [{}],
+			"retryPolicy": {
+				"maxAttempts": %d,
+				"initialBackoff": "0.1s",
+				"maxBackoff": "2s",
+				"backoffMultiplier": 2,
+				"retryableStatusCodes": ["UNAVAILABLE"]
+			}
+		}]
+	}`, maxAttempts)
+}
+
+// outgoingContext adds the credentials and the request ID to the metadata of the call, unless the caller set them.
+func outgoingContext(ctx context.Context, opts Options) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	var pairs []string
+	if len(md.Get("authorization")) == 0 && len(md.Get("x-api-key")) == 0 {
+		if token, ok := ctx.Value(accessTokenKey{}).(string); ok && token != "" {
+			pairs = append(pairs, "authorization", "Bearer "+token)
+		} else if opts.APIKey != "" {
+			pairs = append(pairs, "x-api-key", opts.APIKey)
+		}
+	}
+	// the calls of a request of the calling service share its ID, so their logs can be matched
+	if id, ok := ctxUtil.RequestIDFromContext(ctx); ok && len(md.Get("x-request-id")) == 0 {
+		pairs = append(pairs, "x-request-id", id)
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+func unaryInterceptor(opts Options) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+		return invoker(outgoingContext(ctx, opts), method, req, reply, cc, callOpts...)
+	}
+}
+
+func streamInterceptor(opts Options) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx, opts), desc, cc, method, callOpts...)
+	}
+}
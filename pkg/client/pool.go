@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+// pool spreads the calls over several connections to the same target. A single HTTP/2 connection limits the
+// concurrent streams, so services making many calls at once need more than one.
+type pool struct {
+	conns []*grpc.ClientConn
+	next  atomic.Uint64
+}
+
+func newPool(target string, size int, opts []grpc.DialOption) (*pool, error) {
+	p := &pool{}
+	for range size {
+		conn, err := grpc.NewClient(target, opts...)
+		if err != nil {
+			_ = p.Close()
+			return nil, err
+		}
+		p.conns = append(p.conns, conn)
+	}
+	return p, nil
+}
+
+func (p *pool) conn() *grpc.ClientConn {
+	return p.conns[(p.next.Add(1)-1)%uint64(len(p.conns))]
+}
+
+func (p *pool) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return p.conn().Invoke(ctx, method, args, reply, opts...)
+}
+
+func (p *pool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return p.conn().NewStream(ctx, desc, method, opts...)
+}
+
+func (p *pool) Close() error {
+	var errs []error
+	for _, conn := range p.conns {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -1,7 +1,43 @@
 package customerrors
 
-import "errors"
+import "main/pkg/apperror"
 
 var (
-	ErrNoTagsAffected = errors.New("no rows were affected by the operation")
+	ErrNoTagsAffected = apperror.NotFound("not_found", "no rows were affected by the operation")
+	ErrNotFound       = apperror.NotFound("not_found", "resource not found")
+	ErrInvalidCursor  = apperror.InvalidArgument("invalid_cursor", "pagination cursor is invalid")
+	ErrInvalidQuery   = apperror.InvalidArgument("invalid_query", "search query must be between 1 and 200 characters")
+	ErrWrongPassword  = apperror.PermissionDenied("wrong_password", "current password is incorrect")
+	ErrInvalidToken   = apperror.InvalidArgument("invalid_token", "token is invalid or expired")
+	ErrUserBlocked    = apperror.PermissionDenied("user_blocked", "user is blocked")
+	// ErrEmailTaken is returned when the email address belongs to another account
+	ErrEmailTaken = apperror.AlreadyExists("email_taken", "email address is already registered")
+	// ErrUsernameTaken is returned when the username belongs to another account
+	ErrUsernameTaken = apperror.AlreadyExists("username_taken", "username is already taken")
+	// ErrPrivateAccount is returned when the caller needs to follow a private account to see the requested data
+	ErrPrivateAccount = apperror.PermissionDenied("private_account", "account is private")
+	// ErrBlockedByUser is returned when the caller tries to interact with a user who blocked them
+	ErrBlockedByUser = apperror.PermissionDenied("blocked_by_user", "this user has blocked you")
+	// ErrNotChatAdmin is returned when a chat member who isn't an admin tries to manage the group
+	ErrNotChatAdmin = apperror.PermissionDenied("not_chat_admin", "only chat admins can do this")
+	// ErrLoginConfirmationRequired is returned for logins from a new device until the user confirms them by email
+	ErrLoginConfirmationRequired = apperror.FailedPrecondition("login_confirmation_required", "login from a new device must be confirmed by email")
+	// ErrCaptchaRequired is returned when a CAPTCHA token is required but missing or not accepted by the provider
+	ErrCaptchaRequired = apperror.FailedPrecondition("captcha_required", "captcha verification required")
+	// ErrContentRejected is returned when the content policy doesn't allow publishing a post, comment or message
+	ErrContentRejected = apperror.InvalidArgument("content_rejected", "content violates the content policy")
+	// ErrExportInProgress is returned when a data export is requested while the previous one is still being prepared
+	ErrExportInProgress = apperror.AlreadyExists("export_in_progress", "a data export is already being prepared")
+	// ErrImportInProgress is returned when a data import is started while the previous one is still being applied
+	ErrImportInProgress = apperror.AlreadyExists("import_in_progress", "a data import is already in progress")
+	// ErrFeatureDisabled is returned when the caller uses a feature that isn't rolled out to them
+	ErrFeatureDisabled = apperror.PermissionDenied("feature_disabled", "this feature is not available yet")
+	// ErrVersionConflict is returned when an update names a version of the resource that was changed since
+	ErrVersionConflict = apperror.Conflict("version_conflict", "the resource was changed by another request, reload it and try again")
+	// ErrInvalidClient is returned when a developer app authenticates with an unknown client ID or a wrong secret
+	ErrInvalidClient = apperror.Unauthenticated("invalid_client", "client authentication failed")
+	// ErrInvalidScope is returned when a developer app asks for a token with scopes it wasn't granted
+	ErrInvalidScope = apperror.InvalidArgument("invalid_scope", "requested scope is invalid or exceeds the scopes of the app")
+	// ErrDatabaseUnavailable is returned without querying the database while its circuit breaker is open
+	ErrDatabaseUnavailable = apperror.Unavailable("database_unavailable", "service is temporarily unavailable, try again later")
 )
@@ -0,0 +1,91 @@
+package email
+
+import (
+	"bufio"
+	"main/pkg/apperror"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// maxLength is the longest address that fits the SMTP path limit (RFC 5321).
+const maxLength = 254
+
+var (
+	ErrInvalidEmail    = apperror.InvalidArgument("invalid_email", "invalid email format")
+	ErrDisposableEmail = apperror.InvalidArgument("disposable_email", "disposable email addresses are not allowed")
+)
+
+// Validator parses email addresses per RFC 5322 and rejects addresses of blocked (disposable) domains.
+type Validator struct {
+	blockedDomains map[string]struct{}
+}
+
+func NewValidator(blockedDomains []string) *Validator {
+	blocked := make(map[string]struct{}, len(blockedDomains))
+	for _, domain := range blockedDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			blocked[domain] = struct{}{}
+		}
+	}
+	return &Validator{blockedDomains: blocked}
+}
+
+// Normalize validates the address and returns it lowercased, the form it is stored and looked up in.
+// Display names ("Bob <bob@example.com>") are rejected, only a bare address is accepted.
+func (v *Validator) Normalize(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if len(address) > maxLength {
+		return "", ErrInvalidEmail
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Name != "" || parsed.Address != address {
+		return "", ErrInvalidEmail
+	}
+
+	normalized := strings.ToLower(parsed.Address)
+	domain := normalized[strings.LastIndexByte(normalized, '@')+1:]
+	// net/mail accepts dotless domains like "localhost", which can't receive mail from us
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, "[") {
+		return "", ErrInvalidEmail
+	}
+	if v.isBlocked(domain) {
+		return "", ErrDisposableEmail
+	}
+	return normalized, nil
+}
+
+// isBlocked reports whether the domain or any of its parent domains is blocked.
+func (v *Validator) isBlocked(domain string) bool {
+	for {
+		if _, ok := v.blockedDomains[domain]; ok {
+			return true
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			return false
+		}
+		domain = domain[i+1:]
+	}
+}
+
+// LoadDomainList reads a blocklist file with one domain per line, blank lines and lines starting with # are skipped.
+func LoadDomainList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains, scanner.Err()
+}
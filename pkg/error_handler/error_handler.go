@@ -3,20 +3,78 @@ package errorhandler
 import (
 	"errors"
 	"log/slog"
+	"main/pkg/apperror"
+	"main/pkg/errreport"
+	"main/pkg/i18n"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 )
 
-func HandleError(err error, c echo.Context) {
+// statusCodes are the machine-readable codes of errors that only carry an HTTP status.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "invalid_argument",
+	http.StatusUnauthorized:          "unauthenticated",
+	http.StatusForbidden:             "permission_denied",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "already_exists",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+	http.StatusTooManyRequests:       "resource_exhausted",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+// ErrorResponse is the body of every error response. Code is machine-readable, Message is meant for people.
+type ErrorResponse struct {
+	Message string `json:"error"`
+	Code    string `json:"code"`
+	// Fields are set for requests that failed validation
+	Fields []apperror.FieldViolation `json:"fields,omitempty"`
+}
+
+// Handler writes the error responses, their messages in the language of the Accept-Language header.
+type Handler struct {
+	translator *i18n.Translator
+	reporter   errreport.Reporter
+}
+
+func New(translator *i18n.Translator, reporter errreport.Reporter) *Handler {
+	return &Handler{translator: translator, reporter: reporter}
+}
+
+// HandleError answers with the status and code of the apperror.Error in the chain of err, or with those of an
+// echo.HTTPError. Any other error is internal, its details are logged and reported, not sent to the client.
+func (h *Handler) HandleError(err error, c echo.Context) {
 
 	code := http.StatusInternalServerError
-	message := "Internal Server Error"
+	resp := ErrorResponse{Message: "Internal Server Error", Code: "internal"}
+	locale := h.translator.Locale(c.Request().Header.Get("Accept-Language"))
 
 	var he *echo.HTTPError
-	if errors.As(err, &he) {
+	appErr := apperror.From(err)
+	if appErr != nil {
+		translated := h.translator.Error(locale, appErr)
+		code = translated.Kind.HTTPStatus()
+		resp = ErrorResponse{Message: translated.Message, Code: translated.Code, Fields: translated.Fields}
+	} else if errors.As(err, &he) {
 		code = he.Code
-		message = he.Message.(string)
+		resp.Message = http.StatusText(code)
+		if message, ok := he.Message.(string); ok {
+			resp.Message = message
+		}
+		resp.Code = statusCodes[code]
+		if resp.Code == "" && code < http.StatusInternalServerError {
+			resp.Code = "invalid_argument"
+		} else if resp.Code == "" {
+			resp.Code = "internal"
+		}
+	}
+	if appErr == nil {
+		resp.Message = h.translator.Message(locale, resp.Message)
+	}
+	// an echo.HTTPError with a 5xx status is deliberate, e.g. from the recovery middleware that reported the panic
+	if code == http.StatusInternalServerError && he == nil {
+		h.reporter.Report(c.Request().Context(), err, map[string]string{"method": c.Request().Method, "path": c.Path()})
 	}
 
 	if code == http.StatusInternalServerError {
@@ -37,7 +95,7 @@ func HandleError(err error, c echo.Context) {
 		if c.Request().Method == http.MethodHead {
 			err = c.NoContent(code)
 		} else {
-			err = c.JSON(code, map[string]string{"error": message})
+			err = c.JSON(code, resp)
 		}
 	}
 }
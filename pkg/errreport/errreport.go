@@ -0,0 +1,47 @@
+// Package errreport sends unexpected errors and panics to an error aggregator, with the ID and the user of
+// the request they happened in and the stack trace of the code that reported them.
+package errreport
+
+import (
+	"context"
+	"runtime"
+	"strings"
+)
+
+// Reporter sends errors to an error aggregator. Reports must not block the caller.
+type Reporter interface {
+	// Report sends err with the request ID and user ID of ctx, the tags and the stack of the caller. Called while
+	// recovering from a panic, the stack includes the frames that panicked.
+	Report(ctx context.Context, err error, tags map[string]string)
+}
+
+// Nop drops the errors, it is used when no aggregator is configured.
+type Nop struct{}
+
+func (Nop) Report(context.Context, error, map[string]string) {}
+
+// Frame is a function call of a stack trace.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// callers returns the stack of the caller of the function calling it, the innermost call first.
+func callers() []Frame {
+	pcs := make([]uintptr, 64)
+	// skip runtime.Callers, callers and the Report method
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []Frame
+	for {
+		frame, more := frames.Next()
+		// the panicking machinery isn't of interest, the frames around it are
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			return stack
+		}
+	}
+}
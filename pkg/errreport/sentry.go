@@ -0,0 +1,202 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	ctxUtil "main/pkg/utils/context"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sentry reports errors to Sentry, or any aggregator accepting its events, e.g. GlitchTip. Reports are queued
+// and sent by Run, when the queue is full they are dropped.
+type Sentry struct {
+	client      *http.Client
+	logger      *slog.Logger
+	storeURL    string
+	auth        string
+	environment string
+	release     string
+	serverName  string
+	events      chan sentryEvent
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	User        *sentryUser       `json:"user,omitempty"`
+	Exception   []sentryException `json:"exception"`
+}
+
+type sentryUser struct {
+	ID string `json:"id"`
+}
+
+type sentryException struct {
+	Type       string           `json:"type"`
+	Value      string           `json:"value"`
+	Stacktrace sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// NewSentry reports to the project of the DSN, https://<key>@<host>/<project ID>. Each event may take up to
+// timeout to send, queueSize events wait to be sent at most.
+func NewSentry(dsn, environment, release string, timeout time.Duration, queueSize int, logger *slog.Logger) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse DSN: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("DSN has no public key")
+	}
+	// the project ID is the last segment of the path, Sentry may be served under the rest of it
+	i := strings.LastIndex(u.Path, "/")
+	if i < 0 || u.Path[i+1:] == "" {
+		return nil, errors.New("DSN has no project ID")
+	}
+	path, project := u.Path[:i], u.Path[i+1:]
+	auth := "Sentry sentry_version=7, sentry_client=threads/1.0, sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	serverName, _ := os.Hostname()
+	return &Sentry{
+		client:      &http.Client{Timeout: timeout},
+		logger:      logger,
+		storeURL:    fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path, project),
+		auth:        auth,
+		environment: environment,
+		release:     release,
+		serverName:  serverName,
+		events:      make(chan sentryEvent, queueSize),
+	}, nil
+}
+
+func (s *Sentry) Report(ctx context.Context, err error, tags map[string]string) {
+	stack := callers()
+	event := sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC(),
+		Level:       "error",
+		Platform:    "go",
+		ServerName:  s.serverName,
+		Environment: s.environment,
+		Release:     s.release,
+		Tags:        make(map[string]string, len(tags)+1),
+	}
+	for k, v := range tags {
+		event.Tags[k] = v
+	}
+	if id, ok := ctxUtil.RequestIDFromContext(ctx); ok {
+		event.Tags["request_id"] = id
+	}
+	if principal, ok := ctxUtil.RequestPrincipalFromContext(ctx); ok && principal.IsUser() {
+		event.User = &sentryUser{ID: principal.UserID.String()}
+	} else if ok && principal.Method == ctxUtil.AuthAppToken {
+		event.Tags["app_id"] = principal.AppID.String()
+	} else if ok {
+		event.Tags["api_key_id"] = principal.APIKeyID.String()
+	}
+	// Sentry wants the outermost call first
+	frames := make([]sentryFrame, len(stack))
+	for i, f := range stack {
+		frames[len(stack)-1-i] = sentryFrame{
+			Function: f.Function,
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(f.Function, "main.") || strings.HasPrefix(f.Function, "main/"),
+		}
+	}
+	event.Exception = []sentryException{{Type: errorType(err), Value: err.Error(), Stacktrace: sentryStacktrace{Frames: frames}}}
+
+	select {
+	case s.events <- event:
+	default:
+		s.logger.Warn("Error report dropped, the queue is full", "error", err)
+	}
+}
+
+// Run sends the queued reports until ctx is cancelled, then sends those still queued.
+func (s *Sentry) Run(ctx context.Context) error {
+	for {
+		select {
+		case event := <-s.events:
+			s.send(event)
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-s.events:
+					s.send(event)
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func (s *Sentry) send(event sentryEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Error("Failed to encode an error report", "error", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		s.logger.Error("Failed to send an error report", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.logger.Error("Failed to send an error report", "error", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Error("Error report rejected", "status", resp.StatusCode, "event_id", event.EventID)
+	}
+}
+
+// errorType names the error by the type of the innermost error it wraps, e.g. *pgconn.PgError.
+func errorType(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = next
+	}
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
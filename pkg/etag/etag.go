@@ -0,0 +1,58 @@
+// Package etag implements conditional GETs. Responses carry a weak ETag built from the version of what they show,
+// and requests whose If-None-Match lists it are answered with 304 Not Modified instead of the body.
+package etag
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Weak returns the weak ETag of the parts, e.g. the version of a row and the viewer dependent flags of its
+// representation.
+func Weak(parts ...any) string {
+	s := make([]string, len(parts))
+	for i, part := range parts {
+		s[i] = fmt.Sprint(part)
+	}
+	return `W/"` + strings.Join(s, ".") + `"`
+}
+
+// Match reports whether the If-None-Match header lists the ETag, compared weakly: W/"1" matches "1".
+func Match(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified sets the ETag of the response and reports whether the client has it already, the caller then answers
+// with http.StatusNotModified. Caches must revalidate the response; authenticated ones are only cached by the
+// client, as they may show what others don't see.
+func NotModified(c echo.Context, etag string) bool {
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Add("Vary", "Authorization")
+	if c.Request().Header.Get("Authorization") != "" {
+		header.Set("Cache-Control", "private, no-cache")
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+	return Match(c.Request().Header.Get("If-None-Match"), etag)
+}
+
+// Respond answers with 304 Not Modified when the client has the ETag, with the JSON of v otherwise.
+func Respond(c echo.Context, etag string, v any) error {
+	if NotModified(c, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, v)
+}
@@ -0,0 +1,74 @@
+// Package featureflags decides whether a feature is on for a user, so features can be rolled out gradually:
+// to everyone, to listed users or to a percentage of users. Flags come from the config file (Static) or from Redis
+// (RedisProvider), where they can be changed without a redeploy. Flags that aren't defined anywhere are off.
+package featureflags
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Flag configures a feature. It is on for everyone if Enabled, otherwise for the Users and for Percentage percent
+// of the other users. Users are picked by a hash of the flag name and their ID, so a user who has the feature keeps
+// it while the percentage grows, and different flags reach different users.
+type Flag struct {
+	Enabled    bool        `json:"enabled"`
+	Users      []uuid.UUID `json:"users,omitempty"`
+	Percentage int         `json:"percentage,omitempty"`
+}
+
+// EnabledFor reports whether the flag with the name is on for the user, anonymous users (uuid.Nil) only get
+// features enabled for everyone.
+func (f Flag) EnabledFor(name string, userID uuid.UUID) bool {
+	if f.Enabled {
+		return true
+	}
+	if userID == uuid.Nil {
+		return false
+	}
+	if slices.Contains(f.Users, userID) {
+		return true
+	}
+	return f.Percentage > 0 && bucket(name, userID) < f.Percentage
+}
+
+// bucket places the user in one of 100 buckets of the flag.
+func bucket(name string, userID uuid.UUID) int {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	h.Write(userID[:])
+	return int(binary.BigEndian.Uint64(h.Sum(nil)) % 100)
+}
+
+// Provider looks up flags by name.
+type Provider interface {
+	// Lookup returns the flag with the name, ok is false if it isn't defined.
+	Lookup(ctx context.Context, name string) (flag Flag, ok bool)
+}
+
+// Static are flags that don't change while the service runs, e.g. from the config file.
+type Static map[string]Flag
+
+func (s Static) Lookup(ctx context.Context, name string) (Flag, bool) {
+	flag, ok := s[name]
+	return flag, ok
+}
+
+// Flags checks features against the flags of a provider.
+type Flags struct {
+	provider Provider
+}
+
+func New(provider Provider) *Flags {
+	return &Flags{provider: provider}
+}
+
+// Enabled reports whether the feature is on for the user, undefined features are off.
+func (f *Flags) Enabled(ctx context.Context, name string, userID uuid.UUID) bool {
+	flag, ok := f.provider.Lookup(ctx, name)
+	return ok && flag.EnabledFor(name, userID)
+}
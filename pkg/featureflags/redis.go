@@ -0,0 +1,80 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKey is the hash of the flags in Redis, its fields are flag names and the values Flag as JSON, e.g.
+// HSET feature_flags data_export '{"percentage": 10}'.
+const redisKey = "feature_flags"
+
+// RedisProvider reads flags from Redis, flags that aren't in Redis are looked up in the fallback, e.g. the flags
+// of the config file. The flags are cached for the TTL, so a change reaches every instance within it. While Redis
+// can't be read the last flags read are used.
+type RedisProvider struct {
+	client   *redis.Client
+	fallback Provider
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	flags     map[string]Flag
+	fetchedAt time.Time
+}
+
+func NewRedisProvider(client *redis.Client, fallback Provider, ttl time.Duration) *RedisProvider {
+	return &RedisProvider{
+		client:   client,
+		fallback: fallback,
+		ttl:      ttl,
+	}
+}
+
+func (p *RedisProvider) Lookup(ctx context.Context, name string) (Flag, bool) {
+	if flag, ok := p.cached(ctx)[name]; ok {
+		return flag, true
+	}
+	return p.fallback.Lookup(ctx, name)
+}
+
+// cached returns the flags read from Redis, reading them again once they're older than the TTL.
+func (p *RedisProvider) cached(ctx context.Context) map[string]Flag {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.fetchedAt) < p.ttl {
+		return p.flags
+	}
+	// retried after the TTL too when Redis fails, so a Redis outage doesn't add a round trip to every check
+	p.fetchedAt = time.Now()
+	values, err := p.client.HGetAll(ctx, redisKey).Result()
+	if err != nil {
+		return p.flags
+	}
+	flags := make(map[string]Flag, len(values))
+	for name, value := range values {
+		var flag Flag
+		if json.Unmarshal([]byte(value), &flag) == nil {
+			flags[name] = flag
+		}
+	}
+	p.flags = flags
+	return flags
+}
+
+// Set stores the flag in Redis, it overrides the fallback once the caches of the instances expire.
+func (p *RedisProvider) Set(ctx context.Context, name string, flag Flag) error {
+	value, err := json.Marshal(flag)
+	if err != nil {
+		return err
+	}
+	return p.client.HSet(ctx, redisKey, name, value).Err()
+}
+
+// Delete removes the flag from Redis, the fallback applies again.
+func (p *RedisProvider) Delete(ctx context.Context, name string) error {
+	return p.client.HDel(ctx, redisKey, name).Err()
+}
@@ -0,0 +1,23 @@
+package geoip
+
+import "net/netip"
+
+// Location is where an IP address is registered, unknown parts are empty.
+type Location struct {
+	// CountryCode is the ISO 3166-1 alpha-2 code, e.g. "DE"
+	CountryCode string
+	Country     string
+	City        string
+}
+
+// Resolver looks up the location of an IP address. Implementations must be safe for concurrent use.
+type Resolver interface {
+	Lookup(ip netip.Addr) (Location, error)
+}
+
+// NopResolver is used when no GeoIP database is configured, every address has an unknown location.
+type NopResolver struct{}
+
+func (NopResolver) Lookup(netip.Addr) (Location, error) {
+	return Location{}, nil
+}
@@ -0,0 +1,331 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of every MaxMind DB file.
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+var ErrInvalidDatabase = errors.New("invalid MaxMind database")
+
+// MMDBResolver resolves locations from a MaxMind GeoIP2/GeoLite2 City database (.mmdb), loaded fully into memory.
+// See https://maxmind.github.io/MaxMind-DB/ for the format.
+type MMDBResolver struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+// OpenMMDB reads the database file at path.
+func OpenMMDB(path string) (*MMDBResolver, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMMDBResolver(buf)
+}
+
+// NewMMDBResolver parses a database already read into memory.
+func NewMMDBResolver(buf []byte) (*MMDBResolver, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalidDatabase)
+	}
+	metaStart := i + len(metadataMarker)
+	meta, _, err := decoder{data: buf[metaStart:]}.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidDatabase, err)
+	}
+	m, ok := meta.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	r := &MMDBResolver{
+		buf:        buf,
+		nodeCount:  uintField(m, "node_count"),
+		recordSize: uintField(m, "record_size"),
+		ipVersion:  uintField(m, "ip_version"),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.recordSize)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	// the search tree is followed by 16 zero bytes, then the data section
+	if treeSize+16 > uint(i) {
+		return nil, fmt.Errorf("%w: search tree exceeds file", ErrInvalidDatabase)
+	}
+	r.data = buf[treeSize+16 : i]
+
+	// IPv4 addresses live under ::/96 in IPv6 databases
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for bit := 0; bit < 96 && node < r.nodeCount; bit++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Lookup returns the location of the address, an empty location if the database has no entry for it.
+func (r *MMDBResolver) Lookup(ip netip.Addr) (Location, error) {
+	ip = ip.Unmap()
+	var bits []byte
+	node := uint(0)
+	switch {
+	case ip.Is4() && r.ipVersion == 6:
+		b := ip.As4()
+		bits, node = b[:], r.ipv4Start
+	case ip.Is4():
+		b := ip.As4()
+		bits = b[:]
+	case ip.Is6() && r.ipVersion == 6:
+		b := ip.As16()
+		bits = b[:]
+	default:
+		return Location{}, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := (bits[i/8] >> (7 - uint(i%8))) & 1
+		node = r.record(node, uint(bit))
+	}
+	if node <= r.nodeCount {
+		// equal to node count means the address is not in the database
+		return Location{}, nil
+	}
+
+	offset := node - r.nodeCount - 16
+	value, _, err := decoder{data: r.data}.decode(offset)
+	if err != nil {
+		return Location{}, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+	record, _ := value.(map[string]any)
+	return Location{
+		CountryCode: stringField(record, "country", "iso_code"),
+		Country:     stringField(record, "country", "names", "en"),
+		City:        stringField(record, "city", "names", "en"),
+	}, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a search tree node.
+func (r *MMDBResolver) record(node, bit uint) uint {
+	b := r.buf[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// decoder reads values of the MaxMind DB data section format.
+type decoder struct {
+	data []byte
+}
+
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decode returns the value at offset and the offset right after it.
+func (d decoder) decode(offset uint) (any, uint, error) {
+	ctrl, err := d.byteAt(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == typePointer {
+		target, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target)
+		return value, next, err
+	}
+
+	if kind == typeExtended {
+		ext, err := d.byteAt(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		kind = 7 + uint(ext)
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		b, err := d.bytesAt(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch size {
+		case 29:
+			size = 29 + uint(b[0])
+		case 30:
+			size = 285 + (uint(b[0])<<8 | uint(b[1]))
+		default:
+			size = 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+		}
+		offset += n
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]any, size)
+		for range size {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, _ := key.(string)
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+		for range size {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	b, err := d.bytesAt(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset += size
+	switch kind {
+	case typeString:
+		return string(b), offset, nil
+	case typeBytes:
+		return b, offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), offset, nil
+	case typeUint16, typeUint32, typeUint64, typeInt32:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		if kind == typeInt32 {
+			return int32(v), offset, nil
+		}
+		return v, offset, nil
+	case typeUint128:
+		// not used by city databases, kept as raw bytes
+		return b, offset, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown data type %d", kind)
+	}
+}
+
+// pointer resolves a pointer control byte to the offset it points to.
+func (d decoder) pointer(ctrl byte, offset uint) (target, next uint, err error) {
+	n := uint(ctrl>>3)&0x3 + 1
+	b, err := d.bytesAt(offset, n)
+	if err != nil {
+		return 0, 0, err
+	}
+	v := uint(ctrl & 0x7)
+	switch n {
+	case 1:
+		target = v<<8 | uint(b[0])
+	case 2:
+		target = (v<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		target = (v<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		target = uint(binary.BigEndian.Uint32(b))
+	}
+	return target, offset + n, nil
+}
+
+func (d decoder) byteAt(offset uint) (byte, error) {
+	if offset >= uint(len(d.data)) {
+		return 0, errors.New("unexpected end of data")
+	}
+	return d.data[offset], nil
+}
+
+func (d decoder) bytesAt(offset, n uint) ([]byte, error) {
+	if offset+n > uint(len(d.data)) {
+		return nil, errors.New("unexpected end of data")
+	}
+	return d.data[offset : offset+n], nil
+}
+
+func uintField(m map[string]any, key string) uint {
+	v, _ := m[key].(uint64)
+	return uint(v)
+}
+
+// stringField follows the path of nested map keys and returns the string at its end.
+func stringField(m map[string]any, path ...string) string {
+	var cur any = m
+	for _, key := range path {
+		next, ok := cur.(map[string]any)
+		if !ok {
+			return ""
+		}
+		cur = next[key]
+	}
+	s, _ := cur.(string)
+	return s
+}
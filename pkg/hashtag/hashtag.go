@@ -0,0 +1,53 @@
+package hashtag
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength is the longest hashtag in characters, longer ones are ignored.
+	MaxLength = 50
+	// MaxPerText limits how many hashtags are taken from one text.
+	MaxPerText = 30
+)
+
+// a hashtag starts a word, so "page#section" in URLs or "&#39;" entities are not hashtags
+var pattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_]+)`)
+
+// Extract returns the distinct normalized hashtags of the text in the order they appear.
+func Extract(text string) []string {
+	var tags []string
+	seen := make(map[string]struct{})
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		tag, ok := Normalize(match[1])
+		if !ok {
+			continue
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+		if len(tags) == MaxPerText {
+			break
+		}
+	}
+	return tags
+}
+
+// Normalize lowercases the tag and strips a leading '#'. It reports false for strings that can't be hashtags.
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if tag == "" || utf8.RuneCountInString(tag) > MaxLength {
+		return "", false
+	}
+	for _, r := range tag {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			return "", false
+		}
+	}
+	return tag, true
+}
@@ -0,0 +1,186 @@
+// Package httpsig signs and verifies HTTP requests with the Signature header of draft-cavage-http-signatures,
+// the scheme ActivityPub servers such as Mastodon authenticate their requests to each other with. Only RSA keys
+// with SHA-256 are supported, named rsa-sha256 or hs2019 by the signer.
+package httpsig
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("httpsig: request is not signed")
+	ErrInvalidSignature = errors.New("httpsig: invalid signature")
+)
+
+// Signature is a parsed Signature header.
+type Signature struct {
+	// KeyID names the key the request was signed with, for ActivityPub the URL of the actor's public key
+	KeyID     string
+	Algorithm string
+	// Headers are the covered headers in the order they were signed, lowercase
+	Headers   []string
+	Signature []byte
+}
+
+// Sign signs the request with the key: the request target, Host, Date and, for a request with a body, a Digest
+// of the body are covered. Date and Digest are set unless present, body must be what the request sends.
+func Sign(r *http.Request, keyID string, key *rsa.PrivateKey, body []byte) error {
+	if r.Header.Get("Date") == "" {
+		r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		if r.Header.Get("Digest") == "" {
+			r.Header.Set("Digest", digest(body))
+		}
+		headers = append(headers, "digest")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(r, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// Parse reads the Signature header of the request, ErrMissingSignature if there is none.
+func Parse(r *http.Request) (Signature, error) {
+	header := r.Header.Get("Signature")
+	if header == "" {
+		return Signature{}, ErrMissingSignature
+	}
+	var sig Signature
+	params := map[string]string{}
+	for _, param := range splitParams(header) {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return Signature{}, fmt.Errorf("%w: malformed parameter %q", ErrInvalidSignature, param)
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		params[strings.TrimSpace(name)] = value
+	}
+	sig.KeyID, sig.Algorithm = params["keyId"], params["algorithm"]
+	if sig.KeyID == "" || params["signature"] == "" {
+		return Signature{}, fmt.Errorf("%w: keyId and signature are required", ErrInvalidSignature)
+	}
+	// the default of the draft, only the Date header
+	sig.Headers = []string{"date"}
+	if h := params["headers"]; h != "" {
+		sig.Headers = strings.Fields(strings.ToLower(h))
+	}
+	var err error
+	if sig.Signature, err = base64.StdEncoding.DecodeString(params["signature"]); err != nil {
+		return Signature{}, fmt.Errorf("%w: signature is not base64", ErrInvalidSignature)
+	}
+	return sig, nil
+}
+
+// splitParams splits the parameters of a Signature header at the commas outside quotes.
+func splitParams(header string) []string {
+	var params []string
+	quoted, start := false, 0
+	for i, c := range header {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			params = append(params, strings.TrimSpace(header[start:i]))
+			start = i + 1
+		}
+	}
+	return append(params, strings.TrimSpace(header[start:]))
+}
+
+// Verify checks the signature of the request with the signer's public key. The signature must cover the request
+// target, Host and Date, and the Digest if the request has a body, which must match it; the Date must be within
+// maxSkew of the current time, so captured requests can't be replayed later.
+func Verify(r *http.Request, sig Signature, key *rsa.PublicKey, body []byte, maxSkew time.Duration) error {
+	if sig.Algorithm != "" && sig.Algorithm != "rsa-sha256" && sig.Algorithm != "hs2019" {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, sig.Algorithm)
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !slices.Contains(sig.Headers, h) {
+			return fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("%w: invalid date", ErrInvalidSignature)
+	}
+	if skew := time.Since(date); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("%w: date is too far from the current time", ErrInvalidSignature)
+	}
+	if len(body) > 0 && !digestMatches(r.Header.Get("Digest"), body) {
+		return fmt.Errorf("%w: digest doesn't match the body", ErrInvalidSignature)
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(r, sig.Headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig.Signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signingString builds the string a signature covering the headers is made over.
+func signingString(r *http.Request, headers []string) string {
+	var b strings.Builder
+	for i, h := range headers {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(h)
+		b.WriteString(": ")
+		switch h {
+		case "(request-target)":
+			b.WriteString(strings.ToLower(r.Method) + " " + r.URL.RequestURI())
+		case "host":
+			// the Host header is moved to the request's Host field by the server and the client
+			host := r.Host
+			if host == "" {
+				host = r.URL.Host
+			}
+			b.WriteString(host)
+		default:
+			b.WriteString(strings.Join(r.Header.Values(h), ", "))
+		}
+	}
+	return b.String()
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// digestMatches reports whether the SHA-256 digest among the comma separated ones of the header matches the body.
+func digestMatches(header string, body []byte) bool {
+	want := digest(body)
+	for _, d := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+		if strings.EqualFold(name, "SHA-256") && "SHA-256="+value == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Package i18n translates the messages of errors shown to users. The catalogs are JSON files in locales/, named
+// after their language tag and embedded in the binary. A catalog maps English messages to their translation,
+// and the rules of field violations (see apperror.FieldViolation) to templates with {0} for the first parameter.
+// Messages are written in English in the code, so English needs no catalog and is the fallback for messages
+// a catalog doesn't have.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"main/pkg/apperror"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// English is the language messages are written in.
+const English = "en"
+
+type catalog struct {
+	Messages map[string]string `json:"messages"`
+	Rules    map[string]string `json:"rules"`
+}
+
+// Translator picks the supported language a client prefers and translates messages into it.
+type Translator struct {
+	matcher language.Matcher
+	// locales are the supported languages, English first, in the order of the matcher
+	locales  []string
+	catalogs map[string]catalog
+}
+
+// New loads the embedded catalogs.
+func New() (*Translator, error) {
+	t := &Translator{locales: []string{English}, catalogs: map[string]catalog{}}
+	tags := []language.Tag{language.English}
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		locale := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", file.Name(), err)
+		}
+		data, err := locales.ReadFile("locales/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		var c catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", file.Name(), err)
+		}
+		tags = append(tags, tag)
+		t.locales = append(t.locales, locale)
+		t.catalogs[locale] = c
+	}
+	t.matcher = language.NewMatcher(tags)
+	return t, nil
+}
+
+// Locale returns the supported language that best matches the preferences, given as Accept-Language values or
+// language tags in the order they are preferred. It is English if none matches.
+func (t *Translator) Locale(preferences ...string) string {
+	var tags []language.Tag
+	for _, preference := range preferences {
+		parsed, _, err := language.ParseAcceptLanguage(preference)
+		if err == nil {
+			tags = append(tags, parsed...)
+		}
+	}
+	if len(tags) == 0 {
+		return English
+	}
+	_, index, confidence := t.matcher.Match(tags...)
+	if confidence == language.No {
+		return English
+	}
+	return t.locales[index]
+}
+
+// Message translates the message into the locale, a message the catalog doesn't have is returned as is.
+func (t *Translator) Message(locale, message string) string {
+	if translated, ok := t.catalogs[locale].Messages[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Violation translates the description of the violation into the locale, the English description is kept for
+// rules the catalog doesn't have.
+func (t *Translator) Violation(locale string, v apperror.FieldViolation) apperror.FieldViolation {
+	template, ok := t.catalogs[locale].Rules[v.Rule]
+	if !ok {
+		return v
+	}
+	for i, param := range v.Params {
+		template = strings.ReplaceAll(template, "{"+strconv.Itoa(i)+"}", param)
+	}
+	v.Description = template
+	return v
+}
+
+// Error returns the error with its message, or the descriptions of its field violations, translated into
+// the locale. The kind and the code stay the same.
+func (t *Translator) Error(locale string, err *apperror.Error) *apperror.Error {
+	if locale == English {
+		return err
+	}
+	if len(err.Fields) > 0 {
+		fields := make([]apperror.FieldViolation, len(err.Fields))
+		for i, f := range err.Fields {
+			fields[i] = t.Violation(locale, f)
+		}
+		translated := apperror.Invalid(fields)
+		translated.Kind, translated.Code = err.Kind, err.Code
+		return translated
+	}
+	translated := *err
+	translated.Message = t.Message(locale, err.Message)
+	return &translated
+}
@@ -0,0 +1,51 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Claims is the payload of an access token. The user ID is carried in the standard "sub" claim.
+type Claims struct {
+	SessionID string   `json:"sid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AccessToken holds the verified content of an access token.
+type AccessToken struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Roles     []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// toAccessToken converts verified claims, rejecting tokens whose IDs are not valid UUIDs.
+func (c *Claims) toAccessToken() (AccessToken, error) {
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return AccessToken{}, jwt.ErrTokenMalformed
+	}
+
+	var sessionID uuid.UUID
+	if c.SessionID != "" {
+		if sessionID, err = uuid.Parse(c.SessionID); err != nil {
+			return AccessToken{}, jwt.ErrTokenMalformed
+		}
+	}
+
+	if c.IssuedAt == nil || c.ExpiresAt == nil {
+		return AccessToken{}, jwt.ErrTokenMalformed
+	}
+
+	return AccessToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		Roles:     c.Roles,
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiresAt: c.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+)
+
+// JWK is a single public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every public key currently accepted for verification.
+// HMAC managers have no public keys, so the set is empty.
+func (manager *JWTManager) JWKS() JWKSet {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(manager.publicKeys))}
+	for kid, key := range manager.publicKeys {
+		if jwk, ok := toJWK(kid, key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// SetKeys switches signing to the private key with keyID and replaces the keys accepted for verification with
+// publicKeys and the signing key. Keys added with AddPublicKey stay accepted.
+func (manager *JWTManager) SetKeys(keyID string, privateKey crypto.Signer, publicKeys map[string]crypto.PublicKey) error {
+	method, err := signingMethodFor(privateKey.Public())
+	if err != nil {
+		return err
+	}
+	accepted := make(map[string]crypto.PublicKey, len(publicKeys)+len(manager.staticKeys)+1)
+	for kid, key := range publicKeys {
+		if _, err := signingMethodFor(key); err != nil {
+			return err
+		}
+		accepted[kid] = key
+	}
+	accepted[keyID] = privateKey.Public()
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	for kid, key := range manager.staticKeys {
+		accepted[kid] = key
+	}
+	manager.keyID = keyID
+	manager.privateKey = privateKey
+	manager.signingMethod = method
+	manager.publicKeys = accepted
+	return nil
+}
+
+// KeyID returns the ID of the current signing key, empty for HMAC managers.
+func (manager *JWTManager) KeyID() string {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	return manager.keyID
+}
+
+// SigningKey returns the ID and the private key of the current signing key, a nil key for HMAC managers.
+func (manager *JWTManager) SigningKey() (string, crypto.Signer) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	return manager.keyID, manager.privateKey
+}
+
+// GenerateKey generates a fresh key of the same type and size as the current signing key.
+// HMAC managers have no key to rotate and get ErrUnsupportedKey.
+func (manager *JWTManager) GenerateKey() (crypto.Signer, error) {
+	manager.mu.RLock()
+	current := manager.privateKey
+	manager.mu.RUnlock()
+
+	switch k := current.(type) {
+	case *rsa.PrivateKey:
+		return rsa.GenerateKey(rand.Reader, k.N.BitLen())
+	case ed25519.PrivateKey:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+func toJWK(kid string, key crypto.PublicKey) (JWK, bool) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     kid,
+			Use:       "sig",
+			Algorithm: "RS256",
+			N:         base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     kid,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(k),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
@@ -0,0 +1,94 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported key type, expected RSA or Ed25519")
+	ErrInvalidPEM     = errors.New("failed to decode PEM block")
+)
+
+// LoadPrivateKey reads a PEM encoded PKCS#8 or PKCS#1 private key from disk.
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+// ParsePrivateKey decodes a PEM encoded PKCS#8 or PKCS#1 private key.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// MarshalPrivateKey encodes the private key as PEM encoded PKCS#8, which ParsePrivateKey reads.
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX public key from disk.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// signingMethodFor picks the JWT signing method matching the key type.
+func signingMethodFor(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
@@ -1,32 +1,111 @@
 package jwt
 
 import (
+	"crypto"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+var ErrUnknownKeyID = errors.New("unknown key id")
+
 type JWTManager struct {
 	secretKey      string
-	accessTokenTTL int
+	accessTokenTTL time.Duration
+	issuer         string
+	audience       string
+
+	// asymmetric signing, used instead of secretKey when privateKey is set
+	keyID         string
+	privateKey    crypto.Signer
+	signingMethod jwt.SigningMethod
+
+	mu         sync.RWMutex
+	publicKeys map[string]crypto.PublicKey
+	// staticKeys are the keys added with AddPublicKey, they stay accepted when SetKeys replaces the others
+	staticKeys map[string]crypto.PublicKey
 }
 
-func NewJWTManager(secretKey string, tokenTTL int) *JWTManager {
+func NewJWTManager(secretKey string, tokenTTL time.Duration, issuer, audience string) *JWTManager {
 	return &JWTManager{
 		secretKey:      secretKey,
 		accessTokenTTL: tokenTTL,
+		issuer:         issuer,
+		audience:       audience,
+		publicKeys:     make(map[string]crypto.PublicKey),
+		staticKeys:     make(map[string]crypto.PublicKey),
 	}
 }
 
-// NewAccessToken generates a new JWT access token for the given user ID.
-func (manager *JWTManager) NewAccessToken(userID uuid.UUID) (string, error) {
-	jwtClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Duration(manager.accessTokenTTL) * time.Minute).Unix(),
-		"iat":     time.Now().Unix(),
-	})
-	tokenString, err := jwtClaims.SignedString([]byte(manager.secretKey))
+// NewAsymmetricJWTManager creates a manager that signs tokens with an RSA (RS256) or Ed25519 (EdDSA) private key.
+// The key ID is embedded into the "kid" header so verifiers can pick the right public key.
+func NewAsymmetricJWTManager(keyID string, privateKey crypto.Signer, tokenTTL time.Duration, issuer, audience string) (*JWTManager, error) {
+	method, err := signingMethodFor(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &JWTManager{
+		accessTokenTTL: tokenTTL,
+		issuer:         issuer,
+		audience:       audience,
+		keyID:          keyID,
+		privateKey:     privateKey,
+		signingMethod:  method,
+		publicKeys:     map[string]crypto.PublicKey{keyID: privateKey.Public()},
+		staticKeys:     make(map[string]crypto.PublicKey),
+	}, nil
+}
+
+// AddPublicKey registers an additional public key accepted during verification (e.g. a previous key during rotation).
+func (manager *JWTManager) AddPublicKey(keyID string, key crypto.PublicKey) error {
+	if _, err := signingMethodFor(key); err != nil {
+		return err
+	}
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.publicKeys[keyID] = key
+	manager.staticKeys[keyID] = key
+	return nil
+}
+
+// RemovePublicKey stops accepting tokens signed with the given key ID.
+func (manager *JWTManager) RemovePublicKey(keyID string) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	delete(manager.publicKeys, keyID)
+	delete(manager.staticKeys, keyID)
+}
+
+// NewAccessToken generates a new JWT access token for the given user ID and roles, bound to the session.
+func (manager *JWTManager) NewAccessToken(userID, sessionID uuid.UUID, roles []string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		SessionID: sessionID.String(),
+		Roles:     roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			Issuer:    manager.issuer,
+			Audience:  jwt.ClaimStrings{manager.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(manager.accessTokenTTL)),
+		},
+	}
+
+	manager.mu.RLock()
+	keyID, privateKey, method := manager.keyID, manager.privateKey, manager.signingMethod
+	manager.mu.RUnlock()
+
+	if privateKey == nil {
+		jwtClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return jwtClaims.SignedString([]byte(manager.secretKey))
+	}
+
+	jwtClaims := jwt.NewWithClaims(method, claims)
+	jwtClaims.Header["kid"] = keyID
+	tokenString, err := jwtClaims.SignedString(privateKey)
 	if err != nil {
 		return "", err
 	}
@@ -35,21 +114,48 @@ func (manager *JWTManager) NewAccessToken(userID uuid.UUID) (string, error) {
 
 // VerifyAccessToken verifies the access token and returns the user ID if the token is valid.
 func (manager *JWTManager) VerifyAccessToken(tokenString string) (userID uuid.UUID, err error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrTokenMalformed
-		}
-		return []byte(manager.secretKey), nil
-	})
+	token, err := manager.ParseAccessToken(tokenString)
+	return token.UserID, err
+}
+
+// ParseAccessToken verifies the signature, expiry, issuer and audience of the access token and returns its content.
+// The issue time is needed to check the token against the revocation list.
+func (manager *JWTManager) ParseAccessToken(tokenString string) (AccessToken, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, manager.keyFunc,
+		jwt.WithIssuer(manager.issuer),
+		jwt.WithAudience(manager.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
-		return uuid.Nil, err
-	}
-	sub, err := token.Claims.GetSubject()
-	if err != nil || sub == "" {
-		return uuid.Nil, jwt.ErrTokenMalformed
+		return AccessToken{}, err
 	}
+	return claims.toAccessToken()
+}
 
-	uuid := uuid.MustParse(sub)
-
-	return uuid, nil
+// keyFunc resolves the verification key: the shared secret for HMAC tokens, or the public key referenced by "kid".
+func (manager *JWTManager) keyFunc(token *jwt.Token) (any, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if manager.secretKey == "" {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return []byte(manager.secretKey), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodEd25519:
+		kid, _ := token.Header["kid"].(string)
+		manager.mu.RLock()
+		key, ok := manager.publicKeys[kid]
+		manager.mu.RUnlock()
+		if !ok {
+			return nil, ErrUnknownKeyID
+		}
+		method, err := signingMethodFor(key)
+		if err != nil || method.Alg() != token.Method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key, nil
+	default:
+		return nil, jwt.ErrTokenMalformed
+	}
 }
@@ -0,0 +1,75 @@
+package jwt_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"main/pkg/jwt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type namedManager struct {
+	name    string
+	manager *jwt.JWTManager
+}
+
+// managers returns a manager per supported signing algorithm.
+func managers(b *testing.B) []namedManager {
+	b.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rs256, err := jwt.NewAsymmetricJWTManager("rsa", rsaKey, 15*time.Minute, "threads", "threads")
+	if err != nil {
+		b.Fatal(err)
+	}
+	eddsa, err := jwt.NewAsymmetricJWTManager("ed25519", edKey, 15*time.Minute, "threads", "threads")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return []namedManager{
+		{"HS256", jwt.NewJWTManager("benchmark-secret-key-of-32-bytes", 15*time.Minute, "threads", "threads")},
+		{"RS256", rs256},
+		{"EdDSA", eddsa},
+	}
+}
+
+func BenchmarkNewAccessToken(b *testing.B) {
+	userID, sessionID := uuid.New(), uuid.New()
+	roles := []string{"user"}
+	for _, m := range managers(b) {
+		b.Run(m.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := m.manager.NewAccessToken(userID, sessionID, roles); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseAccessToken(b *testing.B) {
+	for _, m := range managers(b) {
+		token, err := m.manager.NewAccessToken(uuid.New(), uuid.New(), []string{"user"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(m.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := m.manager.ParseAccessToken(token); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package link finds the URLs in user text, normalizes them to canonical targets and makes the codes the
+// shortened links are served under.
+package link
+
+import (
+	"crypto/rand"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxPerText limits how many links are taken from one text.
+	MaxPerText = 10
+	// MaxLength is the longest URL in characters, longer ones are left alone.
+	MaxLength = 2048
+	// codeLength is the number of characters of a code, 62^8 codes make collisions negligible
+	codeLength = 8
+)
+
+// a URL starts a word and runs until whitespace or a character that can't be part of it
+var pattern = regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_/@.])((?:https?://|www\.)[^\s<>"'` + "`" + `]+)`)
+
+// trackingParams are query parameters that only tell where a click came from, the canonical URL drops them.
+var trackingParams = []string{"utm_", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "igshid"}
+
+// Link is a URL found in a text.
+type Link struct {
+	// Text is the URL as written in the text
+	Text string
+	// URL is the canonical target
+	URL string
+}
+
+// Extract returns the distinct links of the text in the order they appear. Trailing punctuation, such as the
+// period ending a sentence, isn't taken as part of a URL; text that doesn't parse as an http(s) URL is skipped.
+func Extract(text string) []Link {
+	var links []Link
+	seen := make(map[string]struct{})
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		raw := trimTrailing(match[1])
+		if len(raw) > MaxLength {
+			continue
+		}
+		canonical, ok := Canonical(raw)
+		if !ok {
+			continue
+		}
+		if _, dup := seen[canonical]; dup {
+			continue
+		}
+		seen[canonical] = struct{}{}
+		links = append(links, Link{Text: raw, URL: canonical})
+		if len(links) == MaxPerText {
+			break
+		}
+	}
+	return links
+}
+
+// trimTrailing strips the punctuation ending a URL in prose, and closing brackets without an opening one
+// in the URL, e.g. for a URL in parentheses.
+func trimTrailing(raw string) string {
+	for raw != "" {
+		last := raw[len(raw)-1]
+		switch {
+		case strings.IndexByte(".,:;!?*", last) >= 0:
+			raw = raw[:len(raw)-1]
+		case last == ')' && strings.Count(raw, "(") < strings.Count(raw, ")"),
+			last == ']' && strings.Count(raw, "[") < strings.Count(raw, "]"):
+			raw = raw[:len(raw)-1]
+		default:
+			return raw
+		}
+	}
+	return raw
+}
+
+// Canonical normalizes an http(s) URL: the scheme and host are lowercased, a default port, the fragment and
+// tracking parameters are dropped and an empty path becomes "/". A URL without a scheme is taken as http.
+// It reports false for anything else, so only web pages are ever redirected to.
+func Canonical(raw string) (string, bool) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil {
+		return "", false
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if host == "" || !strings.Contains(host, ".") && net.ParseIP(host) == nil {
+		return "", false
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" && !(u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment, u.RawFragment = "", ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.RawQuery != "" {
+		// re-encoded only if parameters were dropped, the encoding of the others is left as the site wrote it
+		query, removed := u.Query(), false
+		for name := range query {
+			for _, param := range trackingParams {
+				if strings.HasPrefix(strings.ToLower(name), param) {
+					query.Del(name)
+					removed = true
+				}
+			}
+		}
+		if removed {
+			u.RawQuery = query.Encode()
+		}
+	}
+	return u.String(), true
+}
+
+const codeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// NewCode returns a random code of letters and digits for a shortened link.
+func NewCode() string {
+	b := make([]byte, codeLength)
+	_, _ = rand.Read(b)
+	for i := range b {
+		// 256 isn't a multiple of 62, the slight bias doesn't matter for codes that only need to be unique
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b)
+}
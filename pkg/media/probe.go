@@ -0,0 +1,106 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"main/pkg/apperror"
+	"time"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for files that aren't MP4/QuickTime videos.
+	ErrUnsupportedFormat = apperror.InvalidArgument("unsupported_video", "unsupported video format, only MP4 and QuickTime are accepted")
+	ErrMalformedVideo    = apperror.InvalidArgument("malformed_video", "malformed video file")
+)
+
+// ProbeDuration reads the duration of an MP4/QuickTime video from its movie header ("mvhd" box).
+// The moov box may be at the end of the file, so the reader has to be seekable. size is the length of the file.
+func ProbeDuration(r io.ReadSeeker, size int64) (time.Duration, error) {
+	typ, _, _, err := readBoxHeader(r, 0, size)
+	if err != nil || (typ != "ftyp" && typ != "moov" && typ != "wide" && typ != "mdat") {
+		return 0, ErrUnsupportedFormat
+	}
+
+	moovStart, moovEnd, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhdStart, mvhdEnd, err := findBox(r, moovStart, moovEnd, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(mvhdStart, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// version(1) flags(3), then creation/modification times, timescale and duration,
+	// the times and the duration are 64 bit in version 1 and 32 bit in version 0
+	buf := make([]byte, 32)
+	n, err := io.ReadFull(r, buf[:min(int64(len(buf)), mvhdEnd-mvhdStart)])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, ErrMalformedVideo
+	}
+	buf = buf[:n]
+
+	var timescale, duration uint64
+	switch {
+	case len(buf) >= 32 && buf[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(buf[20:24]))
+		duration = binary.BigEndian.Uint64(buf[24:32])
+	case len(buf) >= 20 && buf[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(buf[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(buf[16:20]))
+	default:
+		return 0, ErrMalformedVideo
+	}
+	if timescale == 0 {
+		return 0, ErrMalformedVideo
+	}
+	seconds := duration / timescale
+	rest := duration % timescale
+	return time.Duration(seconds)*time.Second + time.Duration(rest*uint64(time.Second)/timescale), nil
+}
+
+// findBox returns the payload bounds of the first box of the given type among the boxes between start and end.
+func findBox(r io.ReadSeeker, start, end int64, want string) (int64, int64, error) {
+	for offset := start; offset < end; {
+		typ, headerLen, boxLen, err := readBoxHeader(r, offset, end)
+		if err != nil {
+			return 0, 0, err
+		}
+		if typ == want {
+			return offset + headerLen, offset + boxLen, nil
+		}
+		offset += boxLen
+	}
+	return 0, 0, ErrMalformedVideo
+}
+
+// readBoxHeader reads the header of the box at offset and returns its type, header length and total length.
+func readBoxHeader(r io.ReadSeeker, offset, end int64) (string, int64, int64, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return "", 0, 0, err
+	}
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:8]); err != nil {
+		return "", 0, 0, ErrMalformedVideo
+	}
+	typ := string(header[4:8])
+	headerLen := int64(8)
+	boxLen := int64(binary.BigEndian.Uint32(header[:4]))
+	switch boxLen {
+	case 0: // the box extends to the end of the file
+		boxLen = end - offset
+	case 1: // the real size follows the type as a 64 bit integer
+		if _, err := io.ReadFull(r, header[8:16]); err != nil {
+			return "", 0, 0, ErrMalformedVideo
+		}
+		headerLen = 16
+		boxLen = int64(binary.BigEndian.Uint64(header[8:16]))
+	}
+	if boxLen < headerLen || offset+boxLen > end {
+		return "", 0, 0, ErrMalformedVideo
+	}
+	return typ, headerLen, boxLen, nil
+}
@@ -0,0 +1,136 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPresignExpiry is the longest validity S3 accepts for a presigned URL.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// ErrNotSigned is returned by SignedURL of a local store created without a secret.
+var ErrNotSigned = errors.New("media store has no signing secret")
+
+// SignedStore keeps private files, they are only readable through signed URLs that expire.
+type SignedStore interface {
+	Store
+	// SignedURL returns a URL the file under the key can be downloaded from until expiresAt.
+	SignedURL(ctx context.Context, key string, expiresAt time.Time) (string, error)
+}
+
+// NewSignedLocalStore creates a local store for private files: they are served by the store itself (see ServeHTTP)
+// at BaseURL, to requests with a valid signature made with the secret.
+func NewSignedLocalStore(dir, baseURL string, secret []byte) (*LocalStore, error) {
+	s, err := NewLocalStore(dir, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	s.secret = secret
+	return s, nil
+}
+
+func (s *LocalStore) SignedURL(ctx context.Context, key string, expiresAt time.Time) (string, error) {
+	if len(s.secret) == 0 {
+		return "", ErrNotSigned
+	}
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {s.signature(key, expires)}}
+	return s.baseURL + "/" + key + "?" + query.Encode(), nil
+}
+
+// ServeHTTP serves the file of the request path as an attachment, if the URL was signed by SignedURL and hasn't
+// expired. The path is the key, so the handler is mounted with the prefix of BaseURL stripped.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	expires := r.URL.Query().Get("expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if len(s.secret) == 0 || err != nil || key == "" || path.Clean("/"+key) != "/"+key ||
+		!hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.signature(key, expires))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > expiresAt {
+		http.Error(w, "link expired", http.StatusGone)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(key)))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// signature signs the key with its expiry, the expiry is the decimal Unix time from the URL.
+func (s *LocalStore) signature(key, expires string) string {
+	return hex.EncodeToString(hmacSHA256(s.secret, key+"\n"+expires))
+}
+
+// SignedURL returns a presigned GET URL of the object, on the endpoint rather than PublicURL, which may be a CDN
+// that doesn't check signatures. S3 limits the validity to 7 days, a later expiresAt is cut to that.
+func (s *S3Store) SignedURL(ctx context.Context, key string, expiresAt time.Time) (string, error) {
+	return s.presign(http.MethodGet, key, time.Now().UTC(), expiresAt)
+}
+
+// presign builds a URL authenticated by AWS Signature Version 4 query parameters.
+func (s *S3Store) presign(method, key string, now, expiresAt time.Time) (string, error) {
+	ttl := min(expiresAt.Sub(now), maxPresignExpiry)
+	if ttl <= 0 {
+		return "", fmt.Errorf("expiry %s is in the past", expiresAt)
+	}
+	u, err := url.Parse(s.objectURL(key))
+	if err != nil {
+		return "", err
+	}
+
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.accessKey + "/" + scope},
+		"X-Amz-Date":          {amzDate},
+		"X-Amz-Expires":       {strconv.Itoa(int(ttl / time.Second))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	// Encode sorts the parameters, S3 expects spaces as %20
+	rawQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		rawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+	signature := hex.EncodeToString(hmacSHA256(s.signingKey(day), stringToSign))
+
+	u.RawQuery = rawQuery + "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
@@ -0,0 +1,186 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store keeps uploaded media files and serves them by URL.
+type Store interface {
+	// Put stores the file under the key and returns the URL it is served from.
+	Put(ctx context.Context, key, contentType string, r io.Reader, size int64) (string, error)
+	// Delete removes the file, deleting a missing file succeeds.
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalStore keeps files in a directory that is served at BaseURL, e.g. by the HTTP server itself.
+type LocalStore struct {
+	dir     string
+	baseURL string
+	// secret signs download URLs of private stores, see NewSignedLocalStore
+	secret []byte
+}
+
+func NewLocalStore(dir, baseURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %w", err)
+	}
+	return &LocalStore{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key, contentType string, r io.Reader, size int64) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	// written to a temporary file first, so a failed upload never leaves a truncated file behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// S3Store keeps files in a bucket of S3 or an S3 compatible service (MinIO, R2, ...), addressed path-style.
+// Requests are signed with AWS Signature Version 4, PublicURL is where the bucket is readable from, e.g. a CDN.
+type S3Store struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	publicURL string
+	client    *http.Client
+}
+
+func NewS3Store(endpoint, region, bucket, accessKey, secretKey, publicURL string, timeout time.Duration) *S3Store {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if publicURL == "" {
+		publicURL = endpoint + "/" + bucket
+	}
+	return &S3Store{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key, contentType string, r io.Reader, size int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), r)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	if err := s.do(req); err != nil {
+		return "", err
+	}
+	return s.publicURL + "/" + key, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req)
+}
+
+func (s *S3Store) objectURL(key string) string {
+	return s.endpoint + "/" + s.bucket + "/" + key
+}
+
+func (s *S3Store) do(req *http.Request) error {
+	s.sign(req, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("object storage returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header, the payload is left unsigned so it can be streamed.
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	const payloadHash = "UNSIGNED-PAYLOAD"
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	signature := hex.EncodeToString(hmacSHA256(s.signingKey(day), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// signingKey derives the Signature Version 4 key of the day.
+func (s *S3Store) signingKey(day string) []byte {
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
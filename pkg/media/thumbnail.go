@@ -0,0 +1,78 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"main/pkg/apperror"
+)
+
+// maxImagePixels keeps decoding from allocating gigabytes for images that claim huge dimensions.
+const maxImagePixels = 50_000_000
+
+// ErrUnsupportedImage is returned for files that aren't JPEG, PNG or GIF images.
+var ErrUnsupportedImage = apperror.InvalidArgument("unsupported_image", "unsupported image format, only JPEG, PNG and GIF are accepted")
+
+// Thumbnail returns the image scaled down to fit in a maxSide square as a JPEG, together with the size of
+// the original. Images that already fit keep their size. Transparent areas become white.
+func Thumbnail(r io.ReadSeeker, maxSide int) (thumb []byte, width, height int, err error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, 0, 0, ErrUnsupportedImage
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, 0, err
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, 0, 0, ErrUnsupportedImage
+	}
+
+	bounds := src.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+	tw, th := width, height
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			tw, th = maxSide, max(1, height*maxSide/width)
+		} else {
+			tw, th = max(1, width*maxSide/height), maxSide
+		}
+	}
+
+	// every pixel of the thumbnail is the average of the source pixels it covers
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := bounds.Min.Y + y*height/th
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/th)
+		for x := 0; x < tw; x++ {
+			x0 := bounds.Min.X + x*width/tw
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/tw)
+			var sr, sg, sb, sa, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					sr, sg, sb, sa = sr+uint64(cr), sg+uint64(cg), sb+uint64(cb), sa+uint64(ca)
+					n++
+				}
+			}
+			// the colors are premultiplied, adding the missing alpha blends them over white
+			white := 0xffff - sa/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((sr/n + white) >> 8),
+				G: uint8((sg/n + white) >> 8),
+				B: uint8((sb/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), width, height, nil
+}
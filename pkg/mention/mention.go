@@ -0,0 +1,40 @@
+package mention
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MinLength and MaxLength bound usernames, shorter or longer mentions are ignored.
+	MinLength = 3
+	MaxLength = 30
+	// MaxPerText limits how many users one text can mention.
+	MaxPerText = 20
+)
+
+// a mention starts a word, so emails like "name@example.com" don't mention anyone
+var pattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.]+)`)
+
+// Extract returns the distinct lowercased usernames mentioned in the text in the order they appear.
+func Extract(text string) []string {
+	var usernames []string
+	seen := make(map[string]struct{})
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		// a mention at the end of a sentence is followed by a dot
+		username := strings.ToLower(strings.TrimRight(match[1], "."))
+		if n := utf8.RuneCountInString(username); n < MinLength || n > MaxLength {
+			continue
+		}
+		if _, dup := seen[username]; dup {
+			continue
+		}
+		seen[username] = struct{}{}
+		usernames = append(usernames, username)
+		if len(usernames) == MaxPerText {
+			break
+		}
+	}
+	return usernames
+}
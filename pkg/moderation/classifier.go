@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Thresholds map the score of a classifier to a verdict, the strictest threshold the score reaches wins.
+// A zero threshold is never reached.
+type Thresholds struct {
+	Flag   float64
+	Limit  float64
+	Reject float64
+}
+
+func (t Thresholds) verdict(score float64) Verdict {
+	switch {
+	case t.Reject > 0 && score >= t.Reject:
+		return Reject
+	case t.Limit > 0 && score >= t.Limit:
+		return Limit
+	case t.Flag > 0 && score >= t.Flag:
+		return Flag
+	}
+	return Allow
+}
+
+// Classifier asks an external HTTP service how likely content is to break the rules. The service gets
+// a POST with {"kind": ..., "text": ...} and answers {"score": 0..1, "labels": [...]}.
+type Classifier struct {
+	url        string
+	thresholds Thresholds
+	client     *http.Client
+}
+
+func NewClassifier(url string, thresholds Thresholds, timeout time.Duration) *Classifier {
+	return &Classifier{
+		url:        url,
+		thresholds: thresholds,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Classifier) Check(ctx context.Context, content Content) (Decision, error) {
+	body, err := json.Marshal(map[string]string{"kind": content.Kind, "text": content.Text})
+	if err != nil {
+		return Decision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Decision{}, fmt.Errorf("classifier request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Decision{}, fmt.Errorf("classifier responded with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Score  float64  `json:"score"`
+		Labels []string `json:"labels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Decision{}, fmt.Errorf("invalid classifier response: %w", err)
+	}
+
+	decision := Decision{Verdict: c.thresholds.verdict(result.Score)}
+	if decision.Verdict != Allow {
+		decision.Reason = fmt.Sprintf("classifier score %.2f", result.Score)
+		if len(result.Labels) > 0 {
+			decision.Reason += " (" + strings.Join(result.Labels, ", ") + ")"
+		}
+	}
+	return decision, nil
+}
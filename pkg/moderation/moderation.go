@@ -0,0 +1,134 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Verdict is what happens to content a policy checked, later verdicts are stricter.
+type Verdict int
+
+const (
+	// Allow publishes the content
+	Allow Verdict = iota
+	// Flag publishes the content and queues it for review
+	Flag
+	// Limit publishes the content to its author only and queues it for review
+	Limit
+	// Reject refuses to publish the content
+	Reject
+)
+
+// ParseVerdict parses a verdict configured as "allow", "flag", "limit" or "reject".
+func ParseVerdict(s string) (Verdict, error) {
+	switch strings.ToLower(s) {
+	case "allow":
+		return Allow, nil
+	case "flag":
+		return Flag, nil
+	case "limit":
+		return Limit, nil
+	case "reject":
+		return Reject, nil
+	}
+	return Allow, fmt.Errorf("unknown moderation verdict %q", s)
+}
+
+func (v Verdict) String() string {
+	switch v {
+	case Flag:
+		return "flag"
+	case Limit:
+		return "limit"
+	case Reject:
+		return "reject"
+	default:
+		return "allow"
+	}
+}
+
+// Content is a piece of text a user is about to publish.
+type Content struct {
+	// Kind is "post", "comment" or "message"
+	Kind     string
+	AuthorID string
+	Text     string
+}
+
+// Decision is the verdict of a policy with the reason given to reviewers.
+type Decision struct {
+	Verdict Verdict
+	Reason  string
+}
+
+// Policy decides what happens to content before it's published.
+type Policy interface {
+	Check(ctx context.Context, content Content) (Decision, error)
+}
+
+// NopPolicy allows all content, it is used when content filtering is disabled.
+type NopPolicy struct{}
+
+func (NopPolicy) Check(ctx context.Context, content Content) (Decision, error) {
+	return Decision{Verdict: Allow}, nil
+}
+
+// Chain checks content against several policies, the strictest decision wins. Policies that fail are skipped
+// so an unavailable classifier doesn't stop users from posting; their errors are returned with the decision.
+type Chain []Policy
+
+func (c Chain) Check(ctx context.Context, content Content) (Decision, error) {
+	decision := Decision{Verdict: Allow}
+	var errs []error
+	for _, policy := range c {
+		d, err := policy.Check(ctx, content)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if d.Verdict > decision.Verdict {
+			decision = d
+		}
+		if decision.Verdict == Reject {
+			break
+		}
+	}
+	return decision, errors.Join(errs...)
+}
+
+// BannedWords gives its verdict to content matching any of its case-insensitive regular expressions.
+type BannedWords struct {
+	patterns []*regexp.Regexp
+	verdict  Verdict
+}
+
+// NewBannedWords compiles the patterns, plain words only match whole words.
+func NewBannedWords(patterns []string, verdict Verdict) (*BannedWords, error) {
+	b := &BannedWords{verdict: verdict}
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if regexp.QuoteMeta(p) == p {
+			p = `\b` + p + `\b`
+		}
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banned word pattern %q: %w", p, err)
+		}
+		b.patterns = append(b.patterns, re)
+	}
+	return b, nil
+}
+
+func (b *BannedWords) Check(ctx context.Context, content Content) (Decision, error) {
+	for _, re := range b.patterns {
+		if match := re.FindString(content.Text); match != "" {
+			return Decision{Verdict: b.verdict, Reason: fmt.Sprintf("banned word %q", match)}, nil
+		}
+	}
+	return Decision{Verdict: Allow}, nil
+}
@@ -0,0 +1,92 @@
+// Package pagination implements keyset pagination with opaque cursors. A cursor holds the position of the last
+// entry of a page, the next page continues strictly after it, so pages stay stable while new entries are added.
+package pagination
+
+import (
+	"encoding/base64"
+	"main/pkg/customerrors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Request is the page a client asks for, embedded in the request DTOs of paginated endpoints.
+type Request struct {
+	// Cursor is the next_cursor of the previous page, empty for the first page
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+}
+
+// Response is embedded in the response DTOs of paginated endpoints.
+type Response struct {
+	// NextCursor is passed as cursor to fetch the next page, empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// Cursor is the keyset position of an entry: listings are ordered by (Score, CreatedAt, ID) or by (CreatedAt, ID).
+type Cursor struct {
+	// Score is the rank of ranked listings (relevance, engagement, reply count), zero for listings ordered by time
+	Score     float64
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode makes the opaque string handed to clients.
+func (c Cursor) Encode() string {
+	raw := strconv.FormatFloat(c.Score, 'g', -1, 64) + "|" + c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor made by Encode, an empty cursor is the zero position of the first page.
+// Malformed cursors are reported as customerrors.ErrInvalidCursor.
+func Decode(cursor string) (Cursor, error) {
+	if cursor == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, customerrors.ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return Cursor{}, customerrors.ErrInvalidCursor
+	}
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return Cursor{}, customerrors.ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return Cursor{}, customerrors.ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return Cursor{}, customerrors.ErrInvalidCursor
+	}
+	return Cursor{Score: score, CreatedAt: createdAt, ID: id}, nil
+}
+
+// Limit applies the default page size to a non-positive limit and caps it at MaxLimit.
+func Limit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	return min(limit, MaxLimit)
+}
+
+// Page cuts the entries fetched with limit+1 down to limit and returns the cursor of the next page,
+// the extra entry only tells whether there is one. position returns the cursor of an entry.
+func Page[T any](entries []T, limit int, position func(T) Cursor) ([]T, string) {
+	if len(entries) <= limit {
+		return entries, ""
+	}
+	entries = entries[:limit]
+	return entries, position(entries[limit-1]).Encode()
+}
@@ -0,0 +1,126 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrInvalidHash = errors.New("invalid password hash format")
+
+// Argon2Params are the argon2id cost parameters, see RFC 9106 for recommended values.
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Hasher hashes passwords with the configured algorithm and verifies hashes produced by any supported algorithm,
+// so users with legacy hashes can still log in and get re-hashed.
+type Hasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     Argon2Params
+}
+
+func NewHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*Hasher, error) {
+	switch algorithm {
+	case AlgorithmBcrypt, AlgorithmArgon2id:
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %q", algorithm)
+	}
+	if bcryptCost == 0 {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	return &Hasher{
+		algorithm:  algorithm,
+		bcryptCost: bcryptCost,
+		argon2:     argon2Params,
+	}, nil
+}
+
+// Hash hashes the password with the configured algorithm.
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, h.argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2.Iterations, h.argon2.Memory, h.argon2.Parallelism, h.argon2.KeyLength)
+
+	// PHC string format, the same one used by the reference argon2 implementation
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.argon2.Memory, h.argon2.Iterations, h.argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify compares the password with the stored hash.
+// needsRehash is true when the hash was produced by another algorithm or with outdated parameters.
+func (h *Hasher) Verify(password, hash string) (ok bool, needsRehash bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, false
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false
+		}
+		outdated := params.Memory != h.argon2.Memory ||
+			params.Iterations != h.argon2.Iterations ||
+			params.Parallelism != h.argon2.Parallelism
+		return true, h.algorithm != AlgorithmArgon2id || outdated
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, h.algorithm != AlgorithmBcrypt || (err == nil && cost != h.bcryptCost)
+}
+
+func decodeArgon2(hash string) (params Argon2Params, salt, key []byte, err error) {
+	// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,1721 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.32.1
+// source: posts/v1/posts.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	_ "main/pkg/proto/gen/validate/v1"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Post struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId      string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	MediaUrl    string                 `protobuf:"bytes,4,opt,name=media_url,json=mediaUrl,proto3" json:"media_url,omitempty"`
+	IsVideo     bool                   `protobuf:"varint,5,opt,name=is_video,json=isVideo,proto3" json:"is_video,omitempty"`
+	// video length in seconds
+	Duration   int32                  `protobuf:"varint,6,opt,name=duration,proto3" json:"duration,omitempty"`
+	CreatedAt  *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	LikesCount int32                  `protobuf:"varint,9,opt,name=likes_count,json=likesCount,proto3" json:"likes_count,omitempty"`
+	// id of the quoted post, empty for regular posts
+	QuoteOfId     string `protobuf:"bytes,10,opt,name=quote_of_id,json=quoteOfId,proto3" json:"quote_of_id,omitempty"`
+	RepostsCount  int32  `protobuf:"varint,11,opt,name=reposts_count,json=repostsCount,proto3" json:"reposts_count,omitempty"`
+	QuotesCount   int32  `protobuf:"varint,12,opt,name=quotes_count,json=quotesCount,proto3" json:"quotes_count,omitempty"`
+	CommentsCount int32  `protobuf:"varint,13,opt,name=comments_count,json=commentsCount,proto3" json:"comments_count,omitempty"`
+	// lowercased, without the leading '#'
+	Hashtags []string `protobuf:"bytes,14,rep,name=hashtags,proto3" json:"hashtags,omitempty"`
+	// "public", "followers", "close_friends" or "private", posts of private accounts are only shown to followers
+	Visibility string `protobuf:"bytes,15,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	// changes with every edit of the post, updates name the version they were made on
+	Version       int64 `protobuf:"varint,16,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Post) Reset() {
+	*x = Post{}
+	mi := &file_posts_v1_posts_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Post) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Post) ProtoMessage() {}
+
+func (x *Post) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Post.ProtoReflect.Descriptor instead.
+func (*Post) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Post) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Post) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Post) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Post) GetMediaUrl() string {
+	if x != nil {
+		return x.MediaUrl
+	}
+	return ""
+}
+
+func (x *Post) GetIsVideo() bool {
+	if x != nil {
+		return x.IsVideo
+	}
+	return false
+}
+
+func (x *Post) GetDuration() int32 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *Post) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Post) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Post) GetLikesCount() int32 {
+	if x != nil {
+		return x.LikesCount
+	}
+	return 0
+}
+
+func (x *Post) GetQuoteOfId() string {
+	if x != nil {
+		return x.QuoteOfId
+	}
+	return ""
+}
+
+func (x *Post) GetRepostsCount() int32 {
+	if x != nil {
+		return x.RepostsCount
+	}
+	return 0
+}
+
+func (x *Post) GetQuotesCount() int32 {
+	if x != nil {
+		return x.QuotesCount
+	}
+	return 0
+}
+
+func (x *Post) GetCommentsCount() int32 {
+	if x != nil {
+		return x.CommentsCount
+	}
+	return 0
+}
+
+func (x *Post) GetHashtags() []string {
+	if x != nil {
+		return x.Hashtags
+	}
+	return nil
+}
+
+func (x *Post) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *Post) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type CreatePostRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Description string                 `protobuf:"bytes,1,opt,name=description,proto3" json:"description,omitempty"`
+	MediaUrl    string                 `protobuf:"bytes,2,opt,name=media_url,json=mediaUrl,proto3" json:"media_url,omitempty"`
+	// makes the post a quote of the given post
+	QuoteOfId string `protobuf:"bytes,3,opt,name=quote_of_id,json=quoteOfId,proto3" json:"quote_of_id,omitempty"`
+	// "public" (default), "followers", "close_friends" or "private"
+	Visibility    string `protobuf:"bytes,4,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePostRequest) Reset() {
+	*x = CreatePostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePostRequest) ProtoMessage() {}
+
+func (x *CreatePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePostRequest.ProtoReflect.Descriptor instead.
+func (*CreatePostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreatePostRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreatePostRequest) GetMediaUrl() string {
+	if x != nil {
+		return x.MediaUrl
+	}
+	return ""
+}
+
+func (x *CreatePostRequest) GetQuoteOfId() string {
+	if x != nil {
+		return x.QuoteOfId
+	}
+	return ""
+}
+
+func (x *CreatePostRequest) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+type CreatePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePostResponse) Reset() {
+	*x = CreatePostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePostResponse) ProtoMessage() {}
+
+func (x *CreatePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePostResponse.ProtoReflect.Descriptor instead.
+func (*CreatePostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreatePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type GetPostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPostRequest) Reset() {
+	*x = GetPostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPostRequest) ProtoMessage() {}
+
+func (x *GetPostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPostRequest.ProtoReflect.Descriptor instead.
+func (*GetPostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetPostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type GetPostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPostResponse) Reset() {
+	*x = GetPostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPostResponse) ProtoMessage() {}
+
+func (x *GetPostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPostResponse.ProtoReflect.Descriptor instead.
+func (*GetPostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetPostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type UpdatePostRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	PostId      string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// left unchanged if empty
+	Visibility string `protobuf:"bytes,3,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	// the version of the post the edit was made on, the update fails with ABORTED if it was edited since
+	Version       int64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePostRequest) Reset() {
+	*x = UpdatePostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePostRequest) ProtoMessage() {}
+
+func (x *UpdatePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePostRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdatePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *UpdatePostRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *UpdatePostRequest) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *UpdatePostRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type UpdatePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePostResponse) Reset() {
+	*x = UpdatePostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePostResponse) ProtoMessage() {}
+
+func (x *UpdatePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePostResponse.ProtoReflect.Descriptor instead.
+func (*UpdatePostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdatePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type DeletePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePostRequest) Reset() {
+	*x = DeletePostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePostRequest) ProtoMessage() {}
+
+func (x *DeletePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePostRequest.ProtoReflect.Descriptor instead.
+func (*DeletePostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeletePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type DeletePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePostResponse) Reset() {
+	*x = DeletePostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePostResponse) ProtoMessage() {}
+
+func (x *DeletePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePostResponse.ProtoReflect.Descriptor instead.
+func (*DeletePostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeletePostResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type GetFeedRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFeedRequest) Reset() {
+	*x = GetFeedRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFeedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFeedRequest) ProtoMessage() {}
+
+func (x *GetFeedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFeedRequest.ProtoReflect.Descriptor instead.
+func (*GetFeedRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetFeedRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *GetFeedRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// FeedItem is a timeline entry, a post itself or a repost of it
+type FeedItem struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Post  *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	// id of the account that reposted the post, empty if the entry is the post itself
+	RepostedBy    string                 `protobuf:"bytes,2,opt,name=reposted_by,json=repostedBy,proto3" json:"reposted_by,omitempty"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FeedItem) Reset() {
+	*x = FeedItem{}
+	mi := &file_posts_v1_posts_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeedItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeedItem) ProtoMessage() {}
+
+func (x *FeedItem) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeedItem.ProtoReflect.Descriptor instead.
+func (*FeedItem) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FeedItem) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+func (x *FeedItem) GetRepostedBy() string {
+	if x != nil {
+		return x.RepostedBy
+	}
+	return ""
+}
+
+func (x *FeedItem) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+type GetFeedResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// empty on the last page
+	NextCursor    string      `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	Items         []*FeedItem `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFeedResponse) Reset() {
+	*x = GetFeedResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFeedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFeedResponse) ProtoMessage() {}
+
+func (x *GetFeedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFeedResponse.ProtoReflect.Descriptor instead.
+func (*GetFeedResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetFeedResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+func (x *GetFeedResponse) GetItems() []*FeedItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type LikePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LikePostRequest) Reset() {
+	*x = LikePostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LikePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikePostRequest) ProtoMessage() {}
+
+func (x *LikePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikePostRequest.ProtoReflect.Descriptor instead.
+func (*LikePostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LikePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type LikePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LikePostResponse) Reset() {
+	*x = LikePostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LikePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikePostResponse) ProtoMessage() {}
+
+func (x *LikePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikePostResponse.ProtoReflect.Descriptor instead.
+func (*LikePostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *LikePostResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type UnlikePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlikePostRequest) Reset() {
+	*x = UnlikePostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlikePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlikePostRequest) ProtoMessage() {}
+
+func (x *UnlikePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlikePostRequest.ProtoReflect.Descriptor instead.
+func (*UnlikePostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UnlikePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type UnlikePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlikePostResponse) Reset() {
+	*x = UnlikePostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlikePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlikePostResponse) ProtoMessage() {}
+
+func (x *UnlikePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlikePostResponse.ProtoReflect.Descriptor instead.
+func (*UnlikePostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UnlikePostResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type RepostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepostRequest) Reset() {
+	*x = RepostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepostRequest) ProtoMessage() {}
+
+func (x *RepostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepostRequest.ProtoReflect.Descriptor instead.
+func (*RepostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RepostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type RepostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepostResponse) Reset() {
+	*x = RepostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepostResponse) ProtoMessage() {}
+
+func (x *RepostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepostResponse.ProtoReflect.Descriptor instead.
+func (*RepostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RepostResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type UnrepostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnrepostRequest) Reset() {
+	*x = UnrepostRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnrepostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnrepostRequest) ProtoMessage() {}
+
+func (x *UnrepostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnrepostRequest.ProtoReflect.Descriptor instead.
+func (*UnrepostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *UnrepostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type UnrepostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnrepostResponse) Reset() {
+	*x = UnrepostResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnrepostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnrepostResponse) ProtoMessage() {}
+
+func (x *UnrepostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnrepostResponse.ProtoReflect.Descriptor instead.
+func (*UnrepostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UnrepostResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type GetHashtagPostsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// with or without the leading '#', case-insensitive
+	Tag string `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	// next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHashtagPostsRequest) Reset() {
+	*x = GetHashtagPostsRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHashtagPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHashtagPostsRequest) ProtoMessage() {}
+
+func (x *GetHashtagPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHashtagPostsRequest.ProtoReflect.Descriptor instead.
+func (*GetHashtagPostsRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetHashtagPostsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *GetHashtagPostsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *GetHashtagPostsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetHashtagPostsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Posts []*Post                `protobuf:"bytes,1,rep,name=posts,proto3" json:"posts,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHashtagPostsResponse) Reset() {
+	*x = GetHashtagPostsResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHashtagPostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHashtagPostsResponse) ProtoMessage() {}
+
+func (x *GetHashtagPostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHashtagPostsResponse.ProtoReflect.Descriptor instead.
+func (*GetHashtagPostsResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetHashtagPostsResponse) GetPosts() []*Post {
+	if x != nil {
+		return x.Posts
+	}
+	return nil
+}
+
+func (x *GetHashtagPostsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type TrendingHashtag struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	PostsCount    int32                  `protobuf:"varint,2,opt,name=posts_count,json=postsCount,proto3" json:"posts_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrendingHashtag) Reset() {
+	*x = TrendingHashtag{}
+	mi := &file_posts_v1_posts_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrendingHashtag) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrendingHashtag) ProtoMessage() {}
+
+func (x *TrendingHashtag) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrendingHashtag.ProtoReflect.Descriptor instead.
+func (*TrendingHashtag) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *TrendingHashtag) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *TrendingHashtag) GetPostsCount() int32 {
+	if x != nil {
+		return x.PostsCount
+	}
+	return 0
+}
+
+type GetTrendingHashtagsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTrendingHashtagsRequest) Reset() {
+	*x = GetTrendingHashtagsRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTrendingHashtagsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTrendingHashtagsRequest) ProtoMessage() {}
+
+func (x *GetTrendingHashtagsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTrendingHashtagsRequest.ProtoReflect.Descriptor instead.
+func (*GetTrendingHashtagsRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetTrendingHashtagsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetTrendingHashtagsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hashtags      []*TrendingHashtag     `protobuf:"bytes,1,rep,name=hashtags,proto3" json:"hashtags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTrendingHashtagsResponse) Reset() {
+	*x = GetTrendingHashtagsResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTrendingHashtagsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTrendingHashtagsResponse) ProtoMessage() {}
+
+func (x *GetTrendingHashtagsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTrendingHashtagsResponse.ProtoReflect.Descriptor instead.
+func (*GetTrendingHashtagsResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetTrendingHashtagsResponse) GetHashtags() []*TrendingHashtag {
+	if x != nil {
+		return x.Hashtags
+	}
+	return nil
+}
+
+type SearchPostsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// web search syntax: "quoted phrases", -excluded words, or
+	Query string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchPostsRequest) Reset() {
+	*x = SearchPostsRequest{}
+	mi := &file_posts_v1_posts_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPostsRequest) ProtoMessage() {}
+
+func (x *SearchPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPostsRequest.ProtoReflect.Descriptor instead.
+func (*SearchPostsRequest) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *SearchPostsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchPostsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *SearchPostsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SearchPostsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Posts []*Post                `protobuf:"bytes,1,rep,name=posts,proto3" json:"posts,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchPostsResponse) Reset() {
+	*x = SearchPostsResponse{}
+	mi := &file_posts_v1_posts_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPostsResponse) ProtoMessage() {}
+
+func (x *SearchPostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_v1_posts_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPostsResponse.ProtoReflect.Descriptor instead.
+func (*SearchPostsResponse) Descriptor() ([]byte, []int) {
+	return file_posts_v1_posts_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SearchPostsResponse) GetPosts() []*Post {
+	if x != nil {
+		return x.Posts
+	}
+	return nil
+}
+
+func (x *SearchPostsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+var File_posts_v1_posts_proto protoreflect.FileDescriptor
+
+const file_posts_v1_posts_proto_rawDesc = "" +
+	"\n" +
+	"\x14posts/v1/posts.proto\x12\bposts.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1avalidate/v1/validate.proto\"\xa1\x04\n" +
+	"\x04Post\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1b\n" +
+	"\tmedia_url\x18\x04 \x01(\tR\bmediaUrl\x12\x19\n" +
+	"\bis_video\x18\x05 \x01(\bR\aisVideo\x12\x1a\n" +
+	"\bduration\x18\x06 \x01(\x05R\bduration\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x1f\n" +
+	"\vlikes_count\x18\t \x01(\x05R\n" +
+	"likesCount\x12\x1e\n" +
+	"\vquote_of_id\x18\n" +
+	" \x01(\tR\tquoteOfId\x12#\n" +
+	"\rreposts_count\x18\v \x01(\x05R\frepostsCount\x12!\n" +
+	"\fquotes_count\x18\f \x01(\x05R\vquotesCount\x12%\n" +
+	"\x0ecomments_count\x18\r \x01(\x05R\rcommentsCount\x12\x1a\n" +
+	"\bhashtags\x18\x0e \x03(\tR\bhashtags\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x0f \x01(\tR\n" +
+	"visibility\x12\x18\n" +
+	"\aversion\x18\x10 \x01(\x03R\aversion\"\xd4\x01\n" +
+	"\x11CreatePostRequest\x12)\n" +
+	"\vdescription\x18\x01 \x01(\tB\a\xc2\xf3\x18\x03\x18\xf4\x03R\vdescription\x12\x1b\n" +
+	"\tmedia_url\x18\x02 \x01(\tR\bmediaUrl\x12&\n" +
+	"\vquote_of_id\x18\x03 \x01(\tB\x06\xc2\xf3\x18\x02 \x01R\tquoteOfId\x12O\n" +
+	"\n" +
+	"visibility\x18\x04 \x01(\tB/\xc2\xf3\x18+2\x06public2\tfollowers2\rclose_friends2\aprivateR\n" +
+	"visibility\"8\n" +
+	"\x12CreatePostResponse\x12\"\n" +
+	"\x04post\x18\x01 \x01(\v2\x0e.posts.v1.PostR\x04post\"3\n" +
+	"\x0eGetPostRequest\x12!\n" +
+	"\apost_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06postId\"5\n" +
+	"\x0fGetPostResponse\x12\"\n" +
+	"\x04post\x18\x01 \x01(\v2\x0e.posts.v1.PostR\x04post\"\xd4\x01\n" +
+	"\x11UpdatePostRequest\x12!\n" +
+	"\apost_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06postId\x12)\n" +
+	"\vdescription\x18\x02 \x01(\tB\a\xc2\xf3\x18\x03\x18\xf4\x03R\vdescription\x12O\n" +
+	"\n" +
+	"visibility\x18\x03 \x01(\tB/\xc2\xf3\x18+2\x06public2\tfollowers2\rclose_friends2\aprivateR\n" +
+	"visibility\x12 \n" +
+	"\aversion\x18\x04 \x01(\x03B\x06\xc2\xf3\x18\x02\b\x01R\aversion\"8\n" +
+	"\x12UpdatePostResponse\x12\"\n" +
+	"\x04post\x18\x01 \x01(\v2\x0e.posts.v1.PostR\x04post\"6\n" +
+	"\x11DeletePostRequest\x12!\n" +
+	"\apost_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06postId\".\n" +
+	"\x12DeletePostResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\">\n" +
+	"\x0eGetFeedRequest\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"{\n" +
+	"\bFeedItem\x12\"\n" +
+	"\x04post\x18\x01 \x01(\v2\x0e.posts.v1.PostR\x04post\x12\x1f\n" +
+	"\vreposted_by\x18\x02 \x01(\tR\n" +
+	"repostedBy\x12*\n" +
+	"\x02at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\"i\n" +
+	"\x0fGetFeedResponse\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\x12(\n" +
+	"\x05items\x18\x03 \x03(\v2\x12.posts.v1.FeedItemR\x05itemsJ\x04\b\x01\x10\x02R\x05posts\"4\n" +
+	"\x0fLikePostRequest\x12!\n" +
+	"\apost_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06postId\",\n" +
+	"\x10LikePostResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"6\n" +
+	"\x11UnlikePostRequest\x12!\n" +
+	"\apost_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06postId\".\n" +
+	"\x12UnlikePostResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"2\n" +
+	"\rRepostRequest\x12!\n" +
+	"\apost_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06postId\"*\n" +
+	"\x0eRepostResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"4\n" +
+	"\x0fUnrepostRequest\x12!\n" +
+	"\apost_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06postId\",\n" +
+	"\x10UnrepostResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"X\n" +
+	"\x16GetHashtagPostsRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"`\n" +
+	"\x17GetHashtagPostsResponse\x12$\n" +
+	"\x05posts\x18\x01 \x03(\v2\x0e.posts.v1.PostR\x05posts\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"D\n" +
+	"\x0fTrendingHashtag\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x1f\n" +
+	"\vposts_count\x18\x02 \x01(\x05R\n" +
+	"postsCount\"2\n" +
+	"\x1aGetTrendingHashtagsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"T\n" +
+	"\x1bGetTrendingHashtagsResponse\x125\n" +
+	"\bhashtags\x18\x01 \x03(\v2\x19.posts.v1.TrendingHashtagR\bhashtags\"X\n" +
+	"\x12SearchPostsRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\\\n" +
+	"\x13SearchPostsResponse\x12$\n" +
+	"\x05posts\x18\x01 \x03(\v2\x0e.posts.v1.PostR\x05posts\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor2\xfc\x06\n" +
+	"\vPostService\x12G\n" +
+	"\n" +
+	"CreatePost\x12\x1b.posts.v1.CreatePostRequest\x1a\x1c.posts.v1.CreatePostResponse\x12>\n" +
+	"\aGetPost\x12\x18.posts.v1.GetPostRequest\x1a\x19.posts.v1.GetPostResponse\x12G\n" +
+	"\n" +
+	"UpdatePost\x12\x1b.posts.v1.UpdatePostRequest\x1a\x1c.posts.v1.UpdatePostResponse\x12G\n" +
+	"\n" +
+	"DeletePost\x12\x1b.posts.v1.DeletePostRequest\x1a\x1c.posts.v1.DeletePostResponse\x12A\n" +
+	"\bLikePost\x12\x19.posts.v1.LikePostRequest\x1a\x1a.posts.v1.LikePostResponse\x12G\n" +
+	"\n" +
+	"UnlikePost\x12\x1b.posts.v1.UnlikePostRequest\x1a\x1c.posts.v1.UnlikePostResponse\x12;\n" +
+	"\x06Repost\x12\x17.posts.v1.RepostRequest\x1a\x18.posts.v1.RepostResponse\x12A\n" +
+	"\bUnrepost\x12\x19.posts.v1.UnrepostRequest\x1a\x1a.posts.v1.UnrepostResponse\x12>\n" +
+	"\aGetFeed\x12\x18.posts.v1.GetFeedRequest\x1a\x19.posts.v1.GetFeedResponse\x12V\n" +
+	"\x0fGetHashtagPosts\x12 .posts.v1.GetHashtagPostsRequest\x1a!.posts.v1.GetHashtagPostsResponse\x12b\n" +
+	"\x13GetTrendingHashtags\x12$.posts.v1.GetTrendingHashtagsRequest\x1a%.posts.v1.GetTrendingHashtagsResponse\x12J\n" +
+	"\vSearchPosts\x12\x1c.posts.v1.SearchPostsRequest\x1a\x1d.posts.v1.SearchPostsResponseB\x1aZ\x18threads/pkg/gen/posts/v1b\x06proto3"
+
+var (
+	file_posts_v1_posts_proto_rawDescOnce sync.Once
+	file_posts_v1_posts_proto_rawDescData []byte
+)
+
+func file_posts_v1_posts_proto_rawDescGZIP() []byte {
+	file_posts_v1_posts_proto_rawDescOnce.Do(func() {
+		file_posts_v1_posts_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_posts_v1_posts_proto_rawDesc), len(file_posts_v1_posts_proto_rawDesc)))
+	})
+	return file_posts_v1_posts_proto_rawDescData
+}
+
+var file_posts_v1_posts_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_posts_v1_posts_proto_goTypes = []any{
+	(*Post)(nil),                        // 0: posts.v1.Post
+	(*CreatePostRequest)(nil),           // 1: posts.v1.CreatePostRequest
+	(*CreatePostResponse)(nil),          // 2: posts.v1.CreatePostResponse
+	(*GetPostRequest)(nil),              // 3: posts.v1.GetPostRequest
+	(*GetPostResponse)(nil),             // 4: posts.v1.GetPostResponse
+	(*UpdatePostRequest)(nil),           // 5: posts.v1.UpdatePostRequest
+	(*UpdatePostResponse)(nil),          // 6: posts.v1.UpdatePostResponse
+	(*DeletePostRequest)(nil),           // 7: posts.v1.DeletePostRequest
+	(*DeletePostResponse)(nil),          // 8: posts.v1.DeletePostResponse
+	(*GetFeedRequest)(nil),              // 9: posts.v1.GetFeedRequest
+	(*FeedItem)(nil),                    // 10: posts.v1.FeedItem
+	(*GetFeedResponse)(nil),             // 11: posts.v1.GetFeedResponse
+	(*LikePostRequest)(nil),             // 12: posts.v1.LikePostRequest
+	(*LikePostResponse)(nil),            // 13: posts.v1.LikePostResponse
+	(*UnlikePostRequest)(nil),           // 14: posts.v1.UnlikePostRequest
+	(*UnlikePostResponse)(nil),          // 15: posts.v1.UnlikePostResponse
+	(*RepostRequest)(nil),               // 16: posts.v1.RepostRequest
+	(*RepostResponse)(nil),              // 17: posts.v1.RepostResponse
+	(*UnrepostRequest)(nil),             // 18: posts.v1.UnrepostRequest
+	(*UnrepostResponse)(nil),            // 19: posts.v1.UnrepostResponse
+	(*GetHashtagPostsRequest)(nil),      // 20: posts.v1.GetHashtagPostsRequest
+	(*GetHashtagPostsResponse)(nil),     // 21: posts.v1.GetHashtagPostsResponse
+	(*TrendingHashtag)(nil),             // 22: posts.v1.TrendingHashtag
+	(*GetTrendingHashtagsRequest)(nil),  // 23: posts.v1.GetTrendingHashtagsRequest
+	(*GetTrendingHashtagsResponse)(nil), // 24: posts.v1.GetTrendingHashtagsResponse
+	(*SearchPostsRequest)(nil),          // 25: posts.v1.SearchPostsRequest
+	(*SearchPostsResponse)(nil),         // 26: posts.v1.SearchPostsResponse
+	(*timestamppb.Timestamp)(nil),       // 27: google.protobuf.Timestamp
+}
+var file_posts_v1_posts_proto_depIdxs = []int32{
+	27, // 0: posts.v1.Post.created_at:type_name -> google.protobuf.Timestamp
+	27, // 1: posts.v1.Post.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: posts.v1.CreatePostResponse.post:type_name -> posts.v1.Post
+	0,  // 3: posts.v1.GetPostResponse.post:type_name -> posts.v1.Post
+	0,  // 4: posts.v1.UpdatePostResponse.post:type_name -> posts.v1.Post
+	0,  // 5: posts.v1.FeedItem.post:type_name -> posts.v1.Post
+	27, // 6: posts.v1.FeedItem.at:type_name -> google.protobuf.Timestamp
+	10, // 7: posts.v1.GetFeedResponse.items:type_name -> posts.v1.FeedItem
+	0,  // 8: posts.v1.GetHashtagPostsResponse.posts:type_name -> posts.v1.Post
+	22, // 9: posts.v1.GetTrendingHashtagsResponse.hashtags:type_name -> posts.v1.TrendingHashtag
+	0,  // 10: posts.v1.SearchPostsResponse.posts:type_name -> posts.v1.Post
+	1,  // 11: posts.v1.PostService.CreatePost:input_type -> posts.v1.CreatePostRequest
+	3,  // 12: posts.v1.PostService.GetPost:input_type -> posts.v1.GetPostRequest
+	5,  // 13: posts.v1.PostService.UpdatePost:input_type -> posts.v1.UpdatePostRequest
+	7,  // 14: posts.v1.PostService.DeletePost:input_type -> posts.v1.DeletePostRequest
+	12, // 15: posts.v1.PostService.LikePost:input_type -> posts.v1.LikePostRequest
+	14, // 16: posts.v1.PostService.UnlikePost:input_type -> posts.v1.UnlikePostRequest
+	16, // 17: posts.v1.PostService.Repost:input_type -> posts.v1.RepostRequest
+	18, // 18: posts.v1.PostService.Unrepost:input_type -> posts.v1.UnrepostRequest
+	9,  // 19: posts.v1.PostService.GetFeed:input_type -> posts.v1.GetFeedRequest
+	20, // 20: posts.v1.PostService.GetHashtagPosts:input_type -> posts.v1.GetHashtagPostsRequest
+	23, // 21: posts.v1.PostService.GetTrendingHashtags:input_type -> posts.v1.GetTrendingHashtagsRequest
+	25, // 22: posts.v1.PostService.SearchPosts:input_type -> posts.v1.SearchPostsRequest
+	2,  // 23: posts.v1.PostService.CreatePost:output_type -> posts.v1.CreatePostResponse
+	4,  // 24: posts.v1.PostService.GetPost:output_type -> posts.v1.GetPostResponse
+	6,  // 25: posts.v1.PostService.UpdatePost:output_type -> posts.v1.UpdatePostResponse
+	8,  // 26: posts.v1.PostService.DeletePost:output_type -> posts.v1.DeletePostResponse
+	13, // 27: posts.v1.PostService.LikePost:output_type -> posts.v1.LikePostResponse
+	15, // 28: posts.v1.PostService.UnlikePost:output_type -> posts.v1.UnlikePostResponse
+	17, // 29: posts.v1.PostService.Repost:output_type -> posts.v1.RepostResponse
+	19, // 30: posts.v1.PostService.Unrepost:output_type -> posts.v1.UnrepostResponse
+	11, // 31: posts.v1.PostService.GetFeed:output_type -> posts.v1.GetFeedResponse
+	21, // 32: posts.v1.PostService.GetHashtagPosts:output_type -> posts.v1.GetHashtagPostsResponse
+	24, // 33: posts.v1.PostService.GetTrendingHashtags:output_type -> posts.v1.GetTrendingHashtagsResponse
+	26, // 34: posts.v1.PostService.SearchPosts:output_type -> posts.v1.SearchPostsResponse
+	23, // [23:35] is the sub-list for method output_type
+	11, // [11:23] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_posts_v1_posts_proto_init() }
+func file_posts_v1_posts_proto_init() {
+	if File_posts_v1_posts_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_posts_v1_posts_proto_rawDesc), len(file_posts_v1_posts_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   27,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_posts_v1_posts_proto_goTypes,
+		DependencyIndexes: file_posts_v1_posts_proto_depIdxs,
+		MessageInfos:      file_posts_v1_posts_proto_msgTypes,
+	}.Build()
+	File_posts_v1_posts_proto = out.File
+	file_posts_v1_posts_proto_goTypes = nil
+	file_posts_v1_posts_proto_depIdxs = nil
+}
@@ -0,0 +1,2555 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.32.1
+// source: profile/v1/profile.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	_ "main/pkg/proto/gen/validate/v1"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Profile struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	UserId    string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username  string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Name      string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Bio       string                 `protobuf:"bytes,4,opt,name=bio,proto3" json:"bio,omitempty"`
+	AvatarUrl string                 `protobuf:"bytes,5,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	// male, female, other or empty when not specified
+	Gender string `protobuf:"bytes,6,opt,name=gender,proto3" json:"gender,omitempty"`
+	// 0 when not specified
+	Age       int32 `protobuf:"varint,7,opt,name=age,proto3" json:"age,omitempty"`
+	IsPrivate bool  `protobuf:"varint,8,opt,name=is_private,json=isPrivate,proto3" json:"is_private,omitempty"`
+	// set when the private fields are hidden from the caller
+	Restricted     bool                   `protobuf:"varint,9,opt,name=restricted,proto3" json:"restricted,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	FollowersCount int32                  `protobuf:"varint,11,opt,name=followers_count,json=followersCount,proto3" json:"followers_count,omitempty"`
+	FollowingCount int32                  `protobuf:"varint,12,opt,name=following_count,json=followingCount,proto3" json:"following_count,omitempty"`
+	// changes with every edit of the profile, updates name the version they were made on
+	Version       int64 `protobuf:"varint,13,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Profile) Reset() {
+	*x = Profile{}
+	mi := &file_profile_v1_profile_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Profile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Profile) ProtoMessage() {}
+
+func (x *Profile) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Profile.ProtoReflect.Descriptor instead.
+func (*Profile) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Profile) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Profile) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *Profile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Profile) GetBio() string {
+	if x != nil {
+		return x.Bio
+	}
+	return ""
+}
+
+func (x *Profile) GetAvatarUrl() string {
+	if x != nil {
+		return x.AvatarUrl
+	}
+	return ""
+}
+
+func (x *Profile) GetGender() string {
+	if x != nil {
+		return x.Gender
+	}
+	return ""
+}
+
+func (x *Profile) GetAge() int32 {
+	if x != nil {
+		return x.Age
+	}
+	return 0
+}
+
+func (x *Profile) GetIsPrivate() bool {
+	if x != nil {
+		return x.IsPrivate
+	}
+	return false
+}
+
+func (x *Profile) GetRestricted() bool {
+	if x != nil {
+		return x.Restricted
+	}
+	return false
+}
+
+func (x *Profile) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Profile) GetFollowersCount() int32 {
+	if x != nil {
+		return x.FollowersCount
+	}
+	return 0
+}
+
+func (x *Profile) GetFollowingCount() int32 {
+	if x != nil {
+		return x.FollowingCount
+	}
+	return 0
+}
+
+func (x *Profile) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// UserCard is a user in a list, following and follows_you are relative to the caller.
+type UserCard struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username      string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	AvatarUrl     string                 `protobuf:"bytes,4,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	Following     bool                   `protobuf:"varint,5,opt,name=following,proto3" json:"following,omitempty"`
+	FollowsYou    bool                   `protobuf:"varint,6,opt,name=follows_you,json=followsYou,proto3" json:"follows_you,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserCard) Reset() {
+	*x = UserCard{}
+	mi := &file_profile_v1_profile_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserCard) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserCard) ProtoMessage() {}
+
+func (x *UserCard) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserCard.ProtoReflect.Descriptor instead.
+func (*UserCard) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UserCard) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UserCard) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *UserCard) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UserCard) GetAvatarUrl() string {
+	if x != nil {
+		return x.AvatarUrl
+	}
+	return ""
+}
+
+func (x *UserCard) GetFollowing() bool {
+	if x != nil {
+		return x.Following
+	}
+	return false
+}
+
+func (x *UserCard) GetFollowsYou() bool {
+	if x != nil {
+		return x.FollowsYou
+	}
+	return false
+}
+
+type FollowEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserCard              `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	FollowedAt    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=followed_at,json=followedAt,proto3" json:"followed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FollowEntry) Reset() {
+	*x = FollowEntry{}
+	mi := &file_profile_v1_profile_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FollowEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FollowEntry) ProtoMessage() {}
+
+func (x *FollowEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FollowEntry.ProtoReflect.Descriptor instead.
+func (*FollowEntry) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FollowEntry) GetUser() *UserCard {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *FollowEntry) GetFollowedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FollowedAt
+	}
+	return nil
+}
+
+type GetProfileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProfileRequest) Reset() {
+	*x = GetProfileRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProfileRequest) ProtoMessage() {}
+
+func (x *GetProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProfileRequest.ProtoReflect.Descriptor instead.
+func (*GetProfileRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetProfileRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type GetProfileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Profile       *Profile               `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProfileResponse) Reset() {
+	*x = GetProfileResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProfileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProfileResponse) ProtoMessage() {}
+
+func (x *GetProfileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProfileResponse.ProtoReflect.Descriptor instead.
+func (*GetProfileResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetProfileResponse) GetProfile() *Profile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+// UpdateProfileRequest changes the caller's profile, unset fields are left unchanged.
+// An empty string clears a field and age 0 clears the age.
+type UpdateProfileRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Name      *string                `protobuf:"bytes,1,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Bio       *string                `protobuf:"bytes,2,opt,name=bio,proto3,oneof" json:"bio,omitempty"`
+	AvatarUrl *string                `protobuf:"bytes,3,opt,name=avatar_url,json=avatarUrl,proto3,oneof" json:"avatar_url,omitempty"`
+	Gender    *string                `protobuf:"bytes,4,opt,name=gender,proto3,oneof" json:"gender,omitempty"`
+	Age       *int32                 `protobuf:"varint,5,opt,name=age,proto3,oneof" json:"age,omitempty"`
+	// the version of the profile the edit was made on, the update fails with ABORTED if it was edited since
+	Version       int64 `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateProfileRequest) Reset() {
+	*x = UpdateProfileRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateProfileRequest) ProtoMessage() {}
+
+func (x *UpdateProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateProfileRequest.ProtoReflect.Descriptor instead.
+func (*UpdateProfileRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateProfileRequest) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetBio() string {
+	if x != nil && x.Bio != nil {
+		return *x.Bio
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetAvatarUrl() string {
+	if x != nil && x.AvatarUrl != nil {
+		return *x.AvatarUrl
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetGender() string {
+	if x != nil && x.Gender != nil {
+		return *x.Gender
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetAge() int32 {
+	if x != nil && x.Age != nil {
+		return *x.Age
+	}
+	return 0
+}
+
+func (x *UpdateProfileRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type UpdateProfileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Profile       *Profile               `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateProfileResponse) Reset() {
+	*x = UpdateProfileResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateProfileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateProfileResponse) ProtoMessage() {}
+
+func (x *UpdateProfileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateProfileResponse.ProtoReflect.Descriptor instead.
+func (*UpdateProfileResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateProfileResponse) GetProfile() *Profile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+type FollowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FollowRequest) Reset() {
+	*x = FollowRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FollowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FollowRequest) ProtoMessage() {}
+
+func (x *FollowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FollowRequest.ProtoReflect.Descriptor instead.
+func (*FollowRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FollowRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type FollowResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// the account is private, the follow waits for the owner's approval
+	Requested     bool `protobuf:"varint,2,opt,name=requested,proto3" json:"requested,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FollowResponse) Reset() {
+	*x = FollowResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FollowResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FollowResponse) ProtoMessage() {}
+
+func (x *FollowResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FollowResponse.ProtoReflect.Descriptor instead.
+func (*FollowResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *FollowResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *FollowResponse) GetRequested() bool {
+	if x != nil {
+		return x.Requested
+	}
+	return false
+}
+
+type UnfollowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnfollowRequest) Reset() {
+	*x = UnfollowRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnfollowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnfollowRequest) ProtoMessage() {}
+
+func (x *UnfollowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnfollowRequest.ProtoReflect.Descriptor instead.
+func (*UnfollowRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UnfollowRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type UnfollowResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnfollowResponse) Reset() {
+	*x = UnfollowResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnfollowResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnfollowResponse) ProtoMessage() {}
+
+func (x *UnfollowResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnfollowResponse.ProtoReflect.Descriptor instead.
+func (*UnfollowResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UnfollowResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type ListFollowsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFollowsRequest) Reset() {
+	*x = ListFollowsRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFollowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFollowsRequest) ProtoMessage() {}
+
+func (x *ListFollowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFollowsRequest.ProtoReflect.Descriptor instead.
+func (*ListFollowsRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListFollowsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListFollowsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListFollowsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListFollowsResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*FollowEntry         `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFollowsResponse) Reset() {
+	*x = ListFollowsResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFollowsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFollowsResponse) ProtoMessage() {}
+
+func (x *ListFollowsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFollowsResponse.ProtoReflect.Descriptor instead.
+func (*ListFollowsResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListFollowsResponse) GetEntries() []*FollowEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListFollowsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type FollowRequestEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserCard              `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	RequestedAt   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=requested_at,json=requestedAt,proto3" json:"requested_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FollowRequestEntry) Reset() {
+	*x = FollowRequestEntry{}
+	mi := &file_profile_v1_profile_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FollowRequestEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FollowRequestEntry) ProtoMessage() {}
+
+func (x *FollowRequestEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FollowRequestEntry.ProtoReflect.Descriptor instead.
+func (*FollowRequestEntry) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *FollowRequestEntry) GetUser() *UserCard {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *FollowRequestEntry) GetRequestedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RequestedAt
+	}
+	return nil
+}
+
+type ListFollowRequestsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFollowRequestsRequest) Reset() {
+	*x = ListFollowRequestsRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFollowRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFollowRequestsRequest) ProtoMessage() {}
+
+func (x *ListFollowRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFollowRequestsRequest.ProtoReflect.Descriptor instead.
+func (*ListFollowRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListFollowRequestsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListFollowRequestsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListFollowRequestsResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*FollowRequestEntry  `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFollowRequestsResponse) Reset() {
+	*x = ListFollowRequestsResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFollowRequestsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFollowRequestsResponse) ProtoMessage() {}
+
+func (x *ListFollowRequestsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFollowRequestsResponse.ProtoReflect.Descriptor instead.
+func (*ListFollowRequestsResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListFollowRequestsResponse) GetEntries() []*FollowRequestEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListFollowRequestsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type ApproveFollowRequestRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// the user who asked to follow the caller
+	UserId        string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveFollowRequestRequest) Reset() {
+	*x = ApproveFollowRequestRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveFollowRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveFollowRequestRequest) ProtoMessage() {}
+
+func (x *ApproveFollowRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveFollowRequestRequest.ProtoReflect.Descriptor instead.
+func (*ApproveFollowRequestRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ApproveFollowRequestRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type ApproveFollowRequestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveFollowRequestResponse) Reset() {
+	*x = ApproveFollowRequestResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveFollowRequestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveFollowRequestResponse) ProtoMessage() {}
+
+func (x *ApproveFollowRequestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveFollowRequestResponse.ProtoReflect.Descriptor instead.
+func (*ApproveFollowRequestResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ApproveFollowRequestResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type RejectFollowRequestRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// the user who asked to follow the caller
+	UserId        string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RejectFollowRequestRequest) Reset() {
+	*x = RejectFollowRequestRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RejectFollowRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectFollowRequestRequest) ProtoMessage() {}
+
+func (x *RejectFollowRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectFollowRequestRequest.ProtoReflect.Descriptor instead.
+func (*RejectFollowRequestRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RejectFollowRequestRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type RejectFollowRequestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RejectFollowRequestResponse) Reset() {
+	*x = RejectFollowRequestResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RejectFollowRequestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectFollowRequestResponse) ProtoMessage() {}
+
+func (x *RejectFollowRequestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectFollowRequestResponse.ProtoReflect.Descriptor instead.
+func (*RejectFollowRequestResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RejectFollowRequestResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type BlockUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockUserRequest) Reset() {
+	*x = BlockUserRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockUserRequest) ProtoMessage() {}
+
+func (x *BlockUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockUserRequest.ProtoReflect.Descriptor instead.
+func (*BlockUserRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *BlockUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type BlockUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockUserResponse) Reset() {
+	*x = BlockUserResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockUserResponse) ProtoMessage() {}
+
+func (x *BlockUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockUserResponse.ProtoReflect.Descriptor instead.
+func (*BlockUserResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *BlockUserResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type UnblockUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnblockUserRequest) Reset() {
+	*x = UnblockUserRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnblockUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnblockUserRequest) ProtoMessage() {}
+
+func (x *UnblockUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnblockUserRequest.ProtoReflect.Descriptor instead.
+func (*UnblockUserRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *UnblockUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type UnblockUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnblockUserResponse) Reset() {
+	*x = UnblockUserResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnblockUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnblockUserResponse) ProtoMessage() {}
+
+func (x *UnblockUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnblockUserResponse.ProtoReflect.Descriptor instead.
+func (*UnblockUserResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *UnblockUserResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type BlockedEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserCard              `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	BlockedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=blocked_at,json=blockedAt,proto3" json:"blocked_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockedEntry) Reset() {
+	*x = BlockedEntry{}
+	mi := &file_profile_v1_profile_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockedEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockedEntry) ProtoMessage() {}
+
+func (x *BlockedEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockedEntry.ProtoReflect.Descriptor instead.
+func (*BlockedEntry) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *BlockedEntry) GetUser() *UserCard {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *BlockedEntry) GetBlockedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.BlockedAt
+	}
+	return nil
+}
+
+type ListBlockedUsersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlockedUsersRequest) Reset() {
+	*x = ListBlockedUsersRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlockedUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlockedUsersRequest) ProtoMessage() {}
+
+func (x *ListBlockedUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlockedUsersRequest.ProtoReflect.Descriptor instead.
+func (*ListBlockedUsersRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListBlockedUsersRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListBlockedUsersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListBlockedUsersResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*BlockedEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlockedUsersResponse) Reset() {
+	*x = ListBlockedUsersResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlockedUsersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlockedUsersResponse) ProtoMessage() {}
+
+func (x *ListBlockedUsersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlockedUsersResponse.ProtoReflect.Descriptor instead.
+func (*ListBlockedUsersResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ListBlockedUsersResponse) GetEntries() []*BlockedEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListBlockedUsersResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type NotificationSettings struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Likes    bool                   `protobuf:"varint,1,opt,name=likes,proto3" json:"likes,omitempty"`
+	Comments bool                   `protobuf:"varint,2,opt,name=comments,proto3" json:"comments,omitempty"`
+	Follows  bool                   `protobuf:"varint,3,opt,name=follows,proto3" json:"follows,omitempty"`
+	Mentions bool                   `protobuf:"varint,4,opt,name=mentions,proto3" json:"mentions,omitempty"`
+	Messages bool                   `protobuf:"varint,5,opt,name=messages,proto3" json:"messages,omitempty"`
+	// off, daily or weekly: how often the digest of missed activity is emailed
+	EmailDigest   string `protobuf:"bytes,6,opt,name=email_digest,json=emailDigest,proto3" json:"email_digest,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NotificationSettings) Reset() {
+	*x = NotificationSettings{}
+	mi := &file_profile_v1_profile_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NotificationSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NotificationSettings) ProtoMessage() {}
+
+func (x *NotificationSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NotificationSettings.ProtoReflect.Descriptor instead.
+func (*NotificationSettings) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *NotificationSettings) GetLikes() bool {
+	if x != nil {
+		return x.Likes
+	}
+	return false
+}
+
+func (x *NotificationSettings) GetComments() bool {
+	if x != nil {
+		return x.Comments
+	}
+	return false
+}
+
+func (x *NotificationSettings) GetFollows() bool {
+	if x != nil {
+		return x.Follows
+	}
+	return false
+}
+
+func (x *NotificationSettings) GetMentions() bool {
+	if x != nil {
+		return x.Mentions
+	}
+	return false
+}
+
+func (x *NotificationSettings) GetMessages() bool {
+	if x != nil {
+		return x.Messages
+	}
+	return false
+}
+
+func (x *NotificationSettings) GetEmailDigest() string {
+	if x != nil {
+		return x.EmailDigest
+	}
+	return ""
+}
+
+type Settings struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// everyone, followers or nobody: who sees the profile details and finds the posts in explore, hashtags and search
+	PrivacyLevel string `protobuf:"bytes,1,opt,name=privacy_level,json=privacyLevel,proto3" json:"privacy_level,omitempty"`
+	// limits all posts and the profile details to followers
+	PrivateAccount bool                   `protobuf:"varint,2,opt,name=private_account,json=privateAccount,proto3" json:"private_account,omitempty"`
+	Notifications  *NotificationSettings  `protobuf:"bytes,3,opt,name=notifications,proto3" json:"notifications,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// changes with every change of the settings, updates name the version they were made on
+	Version       int64 `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Settings) Reset() {
+	*x = Settings{}
+	mi := &file_profile_v1_profile_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Settings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Settings) ProtoMessage() {}
+
+func (x *Settings) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Settings.ProtoReflect.Descriptor instead.
+func (*Settings) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *Settings) GetPrivacyLevel() string {
+	if x != nil {
+		return x.PrivacyLevel
+	}
+	return ""
+}
+
+func (x *Settings) GetPrivateAccount() bool {
+	if x != nil {
+		return x.PrivateAccount
+	}
+	return false
+}
+
+func (x *Settings) GetNotifications() *NotificationSettings {
+	if x != nil {
+		return x.Notifications
+	}
+	return nil
+}
+
+func (x *Settings) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Settings) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type GetSettingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSettingsRequest) Reset() {
+	*x = GetSettingsRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSettingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSettingsRequest) ProtoMessage() {}
+
+func (x *GetSettingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSettingsRequest.ProtoReflect.Descriptor instead.
+func (*GetSettingsRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{29}
+}
+
+type GetSettingsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Settings      *Settings              `protobuf:"bytes,1,opt,name=settings,proto3" json:"settings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSettingsResponse) Reset() {
+	*x = GetSettingsResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSettingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSettingsResponse) ProtoMessage() {}
+
+func (x *GetSettingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSettingsResponse.ProtoReflect.Descriptor instead.
+func (*GetSettingsResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *GetSettingsResponse) GetSettings() *Settings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+// UpdateSettingsRequest changes the caller's settings, unset fields are left unchanged.
+type UpdateSettingsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PrivacyLevel   *string                `protobuf:"bytes,1,opt,name=privacy_level,json=privacyLevel,proto3,oneof" json:"privacy_level,omitempty"`
+	PrivateAccount *bool                  `protobuf:"varint,2,opt,name=private_account,json=privateAccount,proto3,oneof" json:"private_account,omitempty"`
+	NotifyLikes    *bool                  `protobuf:"varint,3,opt,name=notify_likes,json=notifyLikes,proto3,oneof" json:"notify_likes,omitempty"`
+	NotifyComments *bool                  `protobuf:"varint,4,opt,name=notify_comments,json=notifyComments,proto3,oneof" json:"notify_comments,omitempty"`
+	NotifyFollows  *bool                  `protobuf:"varint,5,opt,name=notify_follows,json=notifyFollows,proto3,oneof" json:"notify_follows,omitempty"`
+	NotifyMentions *bool                  `protobuf:"varint,6,opt,name=notify_mentions,json=notifyMentions,proto3,oneof" json:"notify_mentions,omitempty"`
+	NotifyMessages *bool                  `protobuf:"varint,7,opt,name=notify_messages,json=notifyMessages,proto3,oneof" json:"notify_messages,omitempty"`
+	EmailDigest    *string                `protobuf:"bytes,8,opt,name=email_digest,json=emailDigest,proto3,oneof" json:"email_digest,omitempty"`
+	// the version of the settings the change was made on, the update fails with ABORTED if they changed since
+	Version       int64 `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSettingsRequest) Reset() {
+	*x = UpdateSettingsRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSettingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSettingsRequest) ProtoMessage() {}
+
+func (x *UpdateSettingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSettingsRequest.ProtoReflect.Descriptor instead.
+func (*UpdateSettingsRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *UpdateSettingsRequest) GetPrivacyLevel() string {
+	if x != nil && x.PrivacyLevel != nil {
+		return *x.PrivacyLevel
+	}
+	return ""
+}
+
+func (x *UpdateSettingsRequest) GetPrivateAccount() bool {
+	if x != nil && x.PrivateAccount != nil {
+		return *x.PrivateAccount
+	}
+	return false
+}
+
+func (x *UpdateSettingsRequest) GetNotifyLikes() bool {
+	if x != nil && x.NotifyLikes != nil {
+		return *x.NotifyLikes
+	}
+	return false
+}
+
+func (x *UpdateSettingsRequest) GetNotifyComments() bool {
+	if x != nil && x.NotifyComments != nil {
+		return *x.NotifyComments
+	}
+	return false
+}
+
+func (x *UpdateSettingsRequest) GetNotifyFollows() bool {
+	if x != nil && x.NotifyFollows != nil {
+		return *x.NotifyFollows
+	}
+	return false
+}
+
+func (x *UpdateSettingsRequest) GetNotifyMentions() bool {
+	if x != nil && x.NotifyMentions != nil {
+		return *x.NotifyMentions
+	}
+	return false
+}
+
+func (x *UpdateSettingsRequest) GetNotifyMessages() bool {
+	if x != nil && x.NotifyMessages != nil {
+		return *x.NotifyMessages
+	}
+	return false
+}
+
+func (x *UpdateSettingsRequest) GetEmailDigest() string {
+	if x != nil && x.EmailDigest != nil {
+		return *x.EmailDigest
+	}
+	return ""
+}
+
+func (x *UpdateSettingsRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type UpdateSettingsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Settings      *Settings              `protobuf:"bytes,1,opt,name=settings,proto3" json:"settings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSettingsResponse) Reset() {
+	*x = UpdateSettingsResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSettingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSettingsResponse) ProtoMessage() {}
+
+func (x *UpdateSettingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSettingsResponse.ProtoReflect.Descriptor instead.
+func (*UpdateSettingsResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *UpdateSettingsResponse) GetSettings() *Settings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type AddCloseFriendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddCloseFriendRequest) Reset() {
+	*x = AddCloseFriendRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddCloseFriendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddCloseFriendRequest) ProtoMessage() {}
+
+func (x *AddCloseFriendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddCloseFriendRequest.ProtoReflect.Descriptor instead.
+func (*AddCloseFriendRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *AddCloseFriendRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type AddCloseFriendResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddCloseFriendResponse) Reset() {
+	*x = AddCloseFriendResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddCloseFriendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddCloseFriendResponse) ProtoMessage() {}
+
+func (x *AddCloseFriendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddCloseFriendResponse.ProtoReflect.Descriptor instead.
+func (*AddCloseFriendResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *AddCloseFriendResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type RemoveCloseFriendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveCloseFriendRequest) Reset() {
+	*x = RemoveCloseFriendRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveCloseFriendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveCloseFriendRequest) ProtoMessage() {}
+
+func (x *RemoveCloseFriendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveCloseFriendRequest.ProtoReflect.Descriptor instead.
+func (*RemoveCloseFriendRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *RemoveCloseFriendRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type RemoveCloseFriendResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveCloseFriendResponse) Reset() {
+	*x = RemoveCloseFriendResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveCloseFriendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveCloseFriendResponse) ProtoMessage() {}
+
+func (x *RemoveCloseFriendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveCloseFriendResponse.ProtoReflect.Descriptor instead.
+func (*RemoveCloseFriendResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *RemoveCloseFriendResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type CloseFriendEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserCard              `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	AddedAt       *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=added_at,json=addedAt,proto3" json:"added_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseFriendEntry) Reset() {
+	*x = CloseFriendEntry{}
+	mi := &file_profile_v1_profile_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseFriendEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseFriendEntry) ProtoMessage() {}
+
+func (x *CloseFriendEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseFriendEntry.ProtoReflect.Descriptor instead.
+func (*CloseFriendEntry) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *CloseFriendEntry) GetUser() *UserCard {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *CloseFriendEntry) GetAddedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AddedAt
+	}
+	return nil
+}
+
+type ListCloseFriendsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCloseFriendsRequest) Reset() {
+	*x = ListCloseFriendsRequest{}
+	mi := &file_profile_v1_profile_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCloseFriendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCloseFriendsRequest) ProtoMessage() {}
+
+func (x *ListCloseFriendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCloseFriendsRequest.ProtoReflect.Descriptor instead.
+func (*ListCloseFriendsRequest) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *ListCloseFriendsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListCloseFriendsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListCloseFriendsResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*CloseFriendEntry    `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCloseFriendsResponse) Reset() {
+	*x = ListCloseFriendsResponse{}
+	mi := &file_profile_v1_profile_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCloseFriendsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCloseFriendsResponse) ProtoMessage() {}
+
+func (x *ListCloseFriendsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profile_v1_profile_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCloseFriendsResponse.ProtoReflect.Descriptor instead.
+func (*ListCloseFriendsResponse) Descriptor() ([]byte, []int) {
+	return file_profile_v1_profile_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ListCloseFriendsResponse) GetEntries() []*CloseFriendEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListCloseFriendsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+var File_profile_v1_profile_proto protoreflect.FileDescriptor
+
+const file_profile_v1_profile_proto_rawDesc = "" +
+	"\n" +
+	"\x18profile/v1/profile.proto\x12\n" +
+	"profile.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1avalidate/v1/validate.proto\"\x93\x03\n" +
+	"\aProfile\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x10\n" +
+	"\x03bio\x18\x04 \x01(\tR\x03bio\x12\x1d\n" +
+	"\n" +
+	"avatar_url\x18\x05 \x01(\tR\tavatarUrl\x12\x16\n" +
+	"\x06gender\x18\x06 \x01(\tR\x06gender\x12\x10\n" +
+	"\x03age\x18\a \x01(\x05R\x03age\x12\x1d\n" +
+	"\n" +
+	"is_private\x18\b \x01(\bR\tisPrivate\x12\x1e\n" +
+	"\n" +
+	"restricted\x18\t \x01(\bR\n" +
+	"restricted\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12'\n" +
+	"\x0ffollowers_count\x18\v \x01(\x05R\x0efollowersCount\x12'\n" +
+	"\x0ffollowing_count\x18\f \x01(\x05R\x0efollowingCount\x12\x18\n" +
+	"\aversion\x18\r \x01(\x03R\aversion\"\xb1\x01\n" +
+	"\bUserCard\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"avatar_url\x18\x04 \x01(\tR\tavatarUrl\x12\x1c\n" +
+	"\tfollowing\x18\x05 \x01(\bR\tfollowing\x12\x1f\n" +
+	"\vfollows_you\x18\x06 \x01(\bR\n" +
+	"followsYou\"t\n" +
+	"\vFollowEntry\x12(\n" +
+	"\x04user\x18\x01 \x01(\v2\x14.profile.v1.UserCardR\x04user\x12;\n" +
+	"\vfollowed_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"followedAt\"6\n" +
+	"\x11GetProfileRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"C\n" +
+	"\x12GetProfileResponse\x12-\n" +
+	"\aprofile\x18\x01 \x01(\v2\x13.profile.v1.ProfileR\aprofile\"\x97\x02\n" +
+	"\x14UpdateProfileRequest\x12\x17\n" +
+	"\x04name\x18\x01 \x01(\tH\x00R\x04name\x88\x01\x01\x12\x15\n" +
+	"\x03bio\x18\x02 \x01(\tH\x01R\x03bio\x88\x01\x01\x12+\n" +
+	"\n" +
+	"avatar_url\x18\x03 \x01(\tB\a\xc2\xf3\x18\x03\x18\x80\x10H\x02R\tavatarUrl\x88\x01\x01\x126\n" +
+	"\x06gender\x18\x04 \x01(\tB\x19\xc2\xf3\x18\x152\x04male2\x06female2\x05otherH\x03R\x06gender\x88\x01\x01\x12\x15\n" +
+	"\x03age\x18\x05 \x01(\x05H\x04R\x03age\x88\x01\x01\x12 \n" +
+	"\aversion\x18\x06 \x01(\x03B\x06\xc2\xf3\x18\x02\b\x01R\aversionB\a\n" +
+	"\x05_nameB\x06\n" +
+	"\x04_bioB\r\n" +
+	"\v_avatar_urlB\t\n" +
+	"\a_genderB\x06\n" +
+	"\x04_age\"F\n" +
+	"\x15UpdateProfileResponse\x12-\n" +
+	"\aprofile\x18\x01 \x01(\v2\x13.profile.v1.ProfileR\aprofile\"2\n" +
+	"\rFollowRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"H\n" +
+	"\x0eFollowResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1c\n" +
+	"\trequested\x18\x02 \x01(\bR\trequested\"4\n" +
+	"\x0fUnfollowRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\",\n" +
+	"\x10UnfollowResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"e\n" +
+	"\x12ListFollowsRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"i\n" +
+	"\x13ListFollowsResponse\x121\n" +
+	"\aentries\x18\x01 \x03(\v2\x17.profile.v1.FollowEntryR\aentries\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"}\n" +
+	"\x12FollowRequestEntry\x12(\n" +
+	"\x04user\x18\x01 \x01(\v2\x14.profile.v1.UserCardR\x04user\x12=\n" +
+	"\frequested_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\vrequestedAt\"I\n" +
+	"\x19ListFollowRequestsRequest\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"w\n" +
+	"\x1aListFollowRequestsResponse\x128\n" +
+	"\aentries\x18\x01 \x03(\v2\x1e.profile.v1.FollowRequestEntryR\aentries\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"@\n" +
+	"\x1bApproveFollowRequestRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"8\n" +
+	"\x1cApproveFollowRequestResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"?\n" +
+	"\x1aRejectFollowRequestRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"7\n" +
+	"\x1bRejectFollowRequestResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"5\n" +
+	"\x10BlockUserRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"-\n" +
+	"\x11BlockUserResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"7\n" +
+	"\x12UnblockUserRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"/\n" +
+	"\x13UnblockUserResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"s\n" +
+	"\fBlockedEntry\x12(\n" +
+	"\x04user\x18\x01 \x01(\v2\x14.profile.v1.UserCardR\x04user\x129\n" +
+	"\n" +
+	"blocked_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tblockedAt\"G\n" +
+	"\x17ListBlockedUsersRequest\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"o\n" +
+	"\x18ListBlockedUsersResponse\x122\n" +
+	"\aentries\x18\x01 \x03(\v2\x18.profile.v1.BlockedEntryR\aentries\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"\xbd\x01\n" +
+	"\x14NotificationSettings\x12\x14\n" +
+	"\x05likes\x18\x01 \x01(\bR\x05likes\x12\x1a\n" +
+	"\bcomments\x18\x02 \x01(\bR\bcomments\x12\x18\n" +
+	"\afollows\x18\x03 \x01(\bR\afollows\x12\x1a\n" +
+	"\bmentions\x18\x04 \x01(\bR\bmentions\x12\x1a\n" +
+	"\bmessages\x18\x05 \x01(\bR\bmessages\x12!\n" +
+	"\femail_digest\x18\x06 \x01(\tR\vemailDigest\"\xf5\x01\n" +
+	"\bSettings\x12#\n" +
+	"\rprivacy_level\x18\x01 \x01(\tR\fprivacyLevel\x12'\n" +
+	"\x0fprivate_account\x18\x02 \x01(\bR\x0eprivateAccount\x12F\n" +
+	"\rnotifications\x18\x03 \x01(\v2 .profile.v1.NotificationSettingsR\rnotifications\x129\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\x03R\aversion\"\x14\n" +
+	"\x12GetSettingsRequest\"G\n" +
+	"\x13GetSettingsResponse\x120\n" +
+	"\bsettings\x18\x01 \x01(\v2\x14.profile.v1.SettingsR\bsettings\"\xeb\x04\n" +
+	"\x15UpdateSettingsRequest\x12K\n" +
+	"\rprivacy_level\x18\x01 \x01(\tB!\xc2\xf3\x18\x1d2\beveryone2\tfollowers2\x06nobodyH\x00R\fprivacyLevel\x88\x01\x01\x12,\n" +
+	"\x0fprivate_account\x18\x02 \x01(\bH\x01R\x0eprivateAccount\x88\x01\x01\x12&\n" +
+	"\fnotify_likes\x18\x03 \x01(\bH\x02R\vnotifyLikes\x88\x01\x01\x12,\n" +
+	"\x0fnotify_comments\x18\x04 \x01(\bH\x03R\x0enotifyComments\x88\x01\x01\x12*\n" +
+	"\x0enotify_follows\x18\x05 \x01(\bH\x04R\rnotifyFollows\x88\x01\x01\x12,\n" +
+	"\x0fnotify_mentions\x18\x06 \x01(\bH\x05R\x0enotifyMentions\x88\x01\x01\x12,\n" +
+	"\x0fnotify_messages\x18\a \x01(\bH\x06R\x0enotifyMessages\x88\x01\x01\x12@\n" +
+	"\femail_digest\x18\b \x01(\tB\x18\xc2\xf3\x18\x142\x03off2\x05daily2\x06weeklyH\aR\vemailDigest\x88\x01\x01\x12 \n" +
+	"\aversion\x18\t \x01(\x03B\x06\xc2\xf3\x18\x02\b\x01R\aversionB\x10\n" +
+	"\x0e_privacy_levelB\x12\n" +
+	"\x10_private_accountB\x0f\n" +
+	"\r_notify_likesB\x12\n" +
+	"\x10_notify_commentsB\x11\n" +
+	"\x0f_notify_followsB\x12\n" +
+	"\x10_notify_mentionsB\x12\n" +
+	"\x10_notify_messagesB\x0f\n" +
+	"\r_email_digest\"J\n" +
+	"\x16UpdateSettingsResponse\x120\n" +
+	"\bsettings\x18\x01 \x01(\v2\x14.profile.v1.SettingsR\bsettings\":\n" +
+	"\x15AddCloseFriendRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"2\n" +
+	"\x16AddCloseFriendResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"=\n" +
+	"\x18RemoveCloseFriendRequest\x12!\n" +
+	"\auser_id\x18\x01 \x01(\tB\b\xc2\xf3\x18\x04\b\x01 \x01R\x06userId\"5\n" +
+	"\x19RemoveCloseFriendResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"s\n" +
+	"\x10CloseFriendEntry\x12(\n" +
+	"\x04user\x18\x01 \x01(\v2\x14.profile.v1.UserCardR\x04user\x125\n" +
+	"\badded_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\aaddedAt\"G\n" +
+	"\x17ListCloseFriendsRequest\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"s\n" +
+	"\x18ListCloseFriendsResponse\x126\n" +
+	"\aentries\x18\x01 \x03(\v2\x1c.profile.v1.CloseFriendEntryR\aentries\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor2\xd3\v\n" +
+	"\x0eProfileService\x12K\n" +
+	"\n" +
+	"GetProfile\x12\x1d.profile.v1.GetProfileRequest\x1a\x1e.profile.v1.GetProfileResponse\x12T\n" +
+	"\rUpdateProfile\x12 .profile.v1.UpdateProfileRequest\x1a!.profile.v1.UpdateProfileResponse\x12?\n" +
+	"\x06Follow\x12\x19.profile.v1.FollowRequest\x1a\x1a.profile.v1.FollowResponse\x12E\n" +
+	"\bUnfollow\x12\x1b.profile.v1.UnfollowRequest\x1a\x1c.profile.v1.UnfollowResponse\x12c\n" +
+	"\x12ListFollowRequests\x12%.profile.v1.ListFollowRequestsRequest\x1a&.profile.v1.ListFollowRequestsResponse\x12i\n" +
+	"\x14ApproveFollowRequest\x12'.profile.v1.ApproveFollowRequestRequest\x1a(.profile.v1.ApproveFollowRequestResponse\x12f\n" +
+	"\x13RejectFollowRequest\x12&.profile.v1.RejectFollowRequestRequest\x1a'.profile.v1.RejectFollowRequestResponse\x12P\n" +
+	"\rListFollowers\x12\x1e.profile.v1.ListFollowsRequest\x1a\x1f.profile.v1.ListFollowsResponse\x12P\n" +
+	"\rListFollowing\x12\x1e.profile.v1.ListFollowsRequest\x1a\x1f.profile.v1.ListFollowsResponse\x12H\n" +
+	"\tBlockUser\x12\x1c.profile.v1.BlockUserRequest\x1a\x1d.profile.v1.BlockUserResponse\x12N\n" +
+	"\vUnblockUser\x12\x1e.profile.v1.UnblockUserRequest\x1a\x1f.profile.v1.UnblockUserResponse\x12]\n" +
+	"\x10ListBlockedUsers\x12#.profile.v1.ListBlockedUsersRequest\x1a$.profile.v1.ListBlockedUsersResponse\x12N\n" +
+	"\vGetSettings\x12\x1e.profile.v1.GetSettingsRequest\x1a\x1f.profile.v1.GetSettingsResponse\x12W\n" +
+	"\x0eUpdateSettings\x12!.profile.v1.UpdateSettingsRequest\x1a\".profile.v1.UpdateSettingsResponse\x12W\n" +
+	"\x0eAddCloseFriend\x12!.profile.v1.AddCloseFriendRequest\x1a\".profile.v1.AddCloseFriendResponse\x12`\n" +
+	"\x11RemoveCloseFriend\x12$.profile.v1.RemoveCloseFriendRequest\x1a%.profile.v1.RemoveCloseFriendResponse\x12]\n" +
+	"\x10ListCloseFriends\x12#.profile.v1.ListCloseFriendsRequest\x1a$.profile.v1.ListCloseFriendsResponseB\x1cZ\x1athreads/pkg/gen/profile/v1b\x06proto3"
+
+var (
+	file_profile_v1_profile_proto_rawDescOnce sync.Once
+	file_profile_v1_profile_proto_rawDescData []byte
+)
+
+func file_profile_v1_profile_proto_rawDescGZIP() []byte {
+	file_profile_v1_profile_proto_rawDescOnce.Do(func() {
+		file_profile_v1_profile_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_profile_v1_profile_proto_rawDesc), len(file_profile_v1_profile_proto_rawDesc)))
+	})
+	return file_profile_v1_profile_proto_rawDescData
+}
+
+var file_profile_v1_profile_proto_msgTypes = make([]protoimpl.MessageInfo, 40)
+var file_profile_v1_profile_proto_goTypes = []any{
+	(*Profile)(nil),                      // 0: profile.v1.Profile
+	(*UserCard)(nil),                     // 1: profile.v1.UserCard
+	(*FollowEntry)(nil),                  // 2: profile.v1.FollowEntry
+	(*GetProfileRequest)(nil),            // 3: profile.v1.GetProfileRequest
+	(*GetProfileResponse)(nil),           // 4: profile.v1.GetProfileResponse
+	(*UpdateProfileRequest)(nil),         // 5: profile.v1.UpdateProfileRequest
+	(*UpdateProfileResponse)(nil),        // 6: profile.v1.UpdateProfileResponse
+	(*FollowRequest)(nil),                // 7: profile.v1.FollowRequest
+	(*FollowResponse)(nil),               // 8: profile.v1.FollowResponse
+	(*UnfollowRequest)(nil),              // 9: profile.v1.UnfollowRequest
+	(*UnfollowResponse)(nil),             // 10: profile.v1.UnfollowResponse
+	(*ListFollowsRequest)(nil),           // 11: profile.v1.ListFollowsRequest
+	(*ListFollowsResponse)(nil),          // 12: profile.v1.ListFollowsResponse
+	(*FollowRequestEntry)(nil),           // 13: profile.v1.FollowRequestEntry
+	(*ListFollowRequestsRequest)(nil),    // 14: profile.v1.ListFollowRequestsRequest
+	(*ListFollowRequestsResponse)(nil),   // 15: profile.v1.ListFollowRequestsResponse
+	(*ApproveFollowRequestRequest)(nil),  // 16: profile.v1.ApproveFollowRequestRequest
+	(*ApproveFollowRequestResponse)(nil), // 17: profile.v1.ApproveFollowRequestResponse
+	(*RejectFollowRequestRequest)(nil),   // 18: profile.v1.RejectFollowRequestRequest
+	(*RejectFollowRequestResponse)(nil),  // 19: profile.v1.RejectFollowRequestResponse
+	(*BlockUserRequest)(nil),             // 20: profile.v1.BlockUserRequest
+	(*BlockUserResponse)(nil),            // 21: profile.v1.BlockUserResponse
+	(*UnblockUserRequest)(nil),           // 22: profile.v1.UnblockUserRequest
+	(*UnblockUserResponse)(nil),          // 23: profile.v1.UnblockUserResponse
+	(*BlockedEntry)(nil),                 // 24: profile.v1.BlockedEntry
+	(*ListBlockedUsersRequest)(nil),      // 25: profile.v1.ListBlockedUsersRequest
+	(*ListBlockedUsersResponse)(nil),     // 26: profile.v1.ListBlockedUsersResponse
+	(*NotificationSettings)(nil),         // 27: profile.v1.NotificationSettings
+	(*Settings)(nil),                     // 28: profile.v1.Settings
+	(*GetSettingsRequest)(nil),           // 29: profile.v1.GetSettingsRequest
+	(*GetSettingsResponse)(nil),          // 30: profile.v1.GetSettingsResponse
+	(*UpdateSettingsRequest)(nil),        // 31: profile.v1.UpdateSettingsRequest
+	(*UpdateSettingsResponse)(nil),       // 32: profile.v1.UpdateSettingsResponse
+	(*AddCloseFriendRequest)(nil),        // 33: profile.v1.AddCloseFriendRequest
+	(*AddCloseFriendResponse)(nil),       // 34: profile.v1.AddCloseFriendResponse
+	(*RemoveCloseFriendRequest)(nil),     // 35: profile.v1.RemoveCloseFriendRequest
+	(*RemoveCloseFriendResponse)(nil),    // 36: profile.v1.RemoveCloseFriendResponse
+	(*CloseFriendEntry)(nil),             // 37: profile.v1.CloseFriendEntry
+	(*ListCloseFriendsRequest)(nil),      // 38: profile.v1.ListCloseFriendsRequest
+	(*ListCloseFriendsResponse)(nil),     // 39: profile.v1.ListCloseFriendsResponse
+	(*timestamppb.Timestamp)(nil),        // 40: google.protobuf.Timestamp
+}
+var file_profile_v1_profile_proto_depIdxs = []int32{
+	40, // 0: profile.v1.Profile.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 1: profile.v1.FollowEntry.user:type_name -> profile.v1.UserCard
+	40, // 2: profile.v1.FollowEntry.followed_at:type_name -> google.protobuf.Timestamp
+	0,  // 3: profile.v1.GetProfileResponse.profile:type_name -> profile.v1.Profile
+	0,  // 4: profile.v1.UpdateProfileResponse.profile:type_name -> profile.v1.Profile
+	2,  // 5: profile.v1.ListFollowsResponse.entries:type_name -> profile.v1.FollowEntry
+	1,  // 6: profile.v1.FollowRequestEntry.user:type_name -> profile.v1.UserCard
+	40, // 7: profile.v1.FollowRequestEntry.requested_at:type_name -> google.protobuf.Timestamp
+	13, // 8: profile.v1.ListFollowRequestsResponse.entries:type_name -> profile.v1.FollowRequestEntry
+	1,  // 9: profile.v1.BlockedEntry.user:type_name -> profile.v1.UserCard
+	40, // 10: profile.v1.BlockedEntry.blocked_at:type_name -> google.protobuf.Timestamp
+	24, // 11: profile.v1.ListBlockedUsersResponse.entries:type_name -> profile.v1.BlockedEntry
+	27, // 12: profile.v1.Settings.notifications:type_name -> profile.v1.NotificationSettings
+	40, // 13: profile.v1.Settings.updated_at:type_name -> google.protobuf.Timestamp
+	28, // 14: profile.v1.GetSettingsResponse.settings:type_name -> profile.v1.Settings
+	28, // 15: profile.v1.UpdateSettingsResponse.settings:type_name -> profile.v1.Settings
+	1,  // 16: profile.v1.CloseFriendEntry.user:type_name -> profile.v1.UserCard
+	40, // 17: profile.v1.CloseFriendEntry.added_at:type_name -> google.protobuf.Timestamp
+	37, // 18: profile.v1.ListCloseFriendsResponse.entries:type_name -> profile.v1.CloseFriendEntry
+	3,  // 19: profile.v1.ProfileService.GetProfile:input_type -> profile.v1.GetProfileRequest
+	5,  // 20: profile.v1.ProfileService.UpdateProfile:input_type -> profile.v1.UpdateProfileRequest
+	7,  // 21: profile.v1.ProfileService.Follow:input_type -> profile.v1.FollowRequest
+	9,  // 22: profile.v1.ProfileService.Unfollow:input_type -> profile.v1.UnfollowRequest
+	14, // 23: profile.v1.ProfileService.ListFollowRequests:input_type -> profile.v1.ListFollowRequestsRequest
+	16, // 24: profile.v1.ProfileService.ApproveFollowRequest:input_type -> profile.v1.ApproveFollowRequestRequest
+	18, // 25: profile.v1.ProfileService.RejectFollowRequest:input_type -> profile.v1.RejectFollowRequestRequest
+	11, // 26: profile.v1.ProfileService.ListFollowers:input_type -> profile.v1.ListFollowsRequest
+	11, // 27: profile.v1.ProfileService.ListFollowing:input_type -> profile.v1.ListFollowsRequest
+	20, // 28: profile.v1.ProfileService.BlockUser:input_type -> profile.v1.BlockUserRequest
+	22, // 29: profile.v1.ProfileService.UnblockUser:input_type -> profile.v1.UnblockUserRequest
+	25, // 30: profile.v1.ProfileService.ListBlockedUsers:input_type -> profile.v1.ListBlockedUsersRequest
+	29, // 31: profile.v1.ProfileService.GetSettings:input_type -> profile.v1.GetSettingsRequest
+	31, // 32: profile.v1.ProfileService.UpdateSettings:input_type -> profile.v1.UpdateSettingsRequest
+	33, // 33: profile.v1.ProfileService.AddCloseFriend:input_type -> profile.v1.AddCloseFriendRequest
+	35, // 34: profile.v1.ProfileService.RemoveCloseFriend:input_type -> profile.v1.RemoveCloseFriendRequest
+	38, // 35: profile.v1.ProfileService.ListCloseFriends:input_type -> profile.v1.ListCloseFriendsRequest
+	4,  // 36: profile.v1.ProfileService.GetProfile:output_type -> profile.v1.GetProfileResponse
+	6,  // 37: profile.v1.ProfileService.UpdateProfile:output_type -> profile.v1.UpdateProfileResponse
+	8,  // 38: profile.v1.ProfileService.Follow:output_type -> profile.v1.FollowResponse
+	10, // 39: profile.v1.ProfileService.Unfollow:output_type -> profile.v1.UnfollowResponse
+	15, // 40: profile.v1.ProfileService.ListFollowRequests:output_type -> profile.v1.ListFollowRequestsResponse
+	17, // 41: profile.v1.ProfileService.ApproveFollowRequest:output_type -> profile.v1.ApproveFollowRequestResponse
+	19, // 42: profile.v1.ProfileService.RejectFollowRequest:output_type -> profile.v1.RejectFollowRequestResponse
+	12, // 43: profile.v1.ProfileService.ListFollowers:output_type -> profile.v1.ListFollowsResponse
+	12, // 44: profile.v1.ProfileService.ListFollowing:output_type -> profile.v1.ListFollowsResponse
+	21, // 45: profile.v1.ProfileService.BlockUser:output_type -> profile.v1.BlockUserResponse
+	23, // 46: profile.v1.ProfileService.UnblockUser:output_type -> profile.v1.UnblockUserResponse
+	26, // 47: profile.v1.ProfileService.ListBlockedUsers:output_type -> profile.v1.ListBlockedUsersResponse
+	30, // 48: profile.v1.ProfileService.GetSettings:output_type -> profile.v1.GetSettingsResponse
+	32, // 49: profile.v1.ProfileService.UpdateSettings:output_type -> profile.v1.UpdateSettingsResponse
+	34, // 50: profile.v1.ProfileService.AddCloseFriend:output_type -> profile.v1.AddCloseFriendResponse
+	36, // 51: profile.v1.ProfileService.RemoveCloseFriend:output_type -> profile.v1.RemoveCloseFriendResponse
+	39, // 52: profile.v1.ProfileService.ListCloseFriends:output_type -> profile.v1.ListCloseFriendsResponse
+	36, // [36:53] is the sub-list for method output_type
+	19, // [19:36] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
+}
+
+func init() { file_profile_v1_profile_proto_init() }
+func file_profile_v1_profile_proto_init() {
+	if File_profile_v1_profile_proto != nil {
+		return
+	}
+	file_profile_v1_profile_proto_msgTypes[5].OneofWrappers = []any{}
+	file_profile_v1_profile_proto_msgTypes[31].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_profile_v1_profile_proto_rawDesc), len(file_profile_v1_profile_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   40,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_profile_v1_profile_proto_goTypes,
+		DependencyIndexes: file_profile_v1_profile_proto_depIdxs,
+		MessageInfos:      file_profile_v1_profile_proto_msgTypes,
+	}.Build()
+	File_profile_v1_profile_proto = out.File
+	file_profile_v1_profile_proto_goTypes = nil
+	file_profile_v1_profile_proto_depIdxs = nil
+}
@@ -0,0 +1,219 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.32.1
+// source: validate/v1/validate.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FieldRules are checked by the validation interceptor before the request reaches the handler.
+type FieldRules struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// required rejects the zero value: an empty string or list, 0 or an unset message
+	Required bool `protobuf:"varint,1,opt,name=required,proto3" json:"required,omitempty"`
+	// min_len and max_len bound the number of characters of strings and the number of items of lists
+	MinLen uint32 `protobuf:"varint,2,opt,name=min_len,json=minLen,proto3" json:"min_len,omitempty"`
+	MaxLen uint32 `protobuf:"varint,3,opt,name=max_len,json=maxLen,proto3" json:"max_len,omitempty"`
+	// uuid requires a non-empty string to be a UUID
+	Uuid bool `protobuf:"varint,4,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// email requires a non-empty string to be an email address
+	Email bool `protobuf:"varint,5,opt,name=email,proto3" json:"email,omitempty"`
+	// in lists the values a non-empty string may take
+	In []string `protobuf:"bytes,6,rep,name=in,proto3" json:"in,omitempty"`
+	// gte and lte bound integers, they apply when set
+	Gte           *int64 `protobuf:"varint,7,opt,name=gte,proto3,oneof" json:"gte,omitempty"`
+	Lte           *int64 `protobuf:"varint,8,opt,name=lte,proto3,oneof" json:"lte,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldRules) Reset() {
+	*x = FieldRules{}
+	mi := &file_validate_v1_validate_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldRules) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldRules) ProtoMessage() {}
+
+func (x *FieldRules) ProtoReflect() protoreflect.Message {
+	mi := &file_validate_v1_validate_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldRules.ProtoReflect.Descriptor instead.
+func (*FieldRules) Descriptor() ([]byte, []int) {
+	return file_validate_v1_validate_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FieldRules) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+func (x *FieldRules) GetMinLen() uint32 {
+	if x != nil {
+		return x.MinLen
+	}
+	return 0
+}
+
+func (x *FieldRules) GetMaxLen() uint32 {
+	if x != nil {
+		return x.MaxLen
+	}
+	return 0
+}
+
+func (x *FieldRules) GetUuid() bool {
+	if x != nil {
+		return x.Uuid
+	}
+	return false
+}
+
+func (x *FieldRules) GetEmail() bool {
+	if x != nil {
+		return x.Email
+	}
+	return false
+}
+
+func (x *FieldRules) GetIn() []string {
+	if x != nil {
+		return x.In
+	}
+	return nil
+}
+
+func (x *FieldRules) GetGte() int64 {
+	if x != nil && x.Gte != nil {
+		return *x.Gte
+	}
+	return 0
+}
+
+func (x *FieldRules) GetLte() int64 {
+	if x != nil && x.Lte != nil {
+		return *x.Lte
+	}
+	return 0
+}
+
+var file_validate_v1_validate_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*FieldRules)(nil),
+		Field:         51000,
+		Name:          "validate.v1.field",
+		Tag:           "bytes,51000,opt,name=field",
+		Filename:      "validate/v1/validate.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// optional validate.v1.FieldRules field = 51000;
+	E_Field = &file_validate_v1_validate_proto_extTypes[0]
+)
+
+var File_validate_v1_validate_proto protoreflect.FileDescriptor
+
+const file_validate_v1_validate_proto_rawDesc = "" +
+	"\n" +
+	"\x1avalidate/v1/validate.proto\x12\vvalidate.v1\x1a google/protobuf/descriptor.proto\"\xd2\x01\n" +
+	"\n" +
+	"FieldRules\x12\x1a\n" +
+	"\brequired\x18\x01 \x01(\bR\brequired\x12\x17\n" +
+	"\amin_len\x18\x02 \x01(\rR\x06minLen\x12\x17\n" +
+	"\amax_len\x18\x03 \x01(\rR\x06maxLen\x12\x12\n" +
+	"\x04uuid\x18\x04 \x01(\bR\x04uuid\x12\x14\n" +
+	"\x05email\x18\x05 \x01(\bR\x05email\x12\x0e\n" +
+	"\x02in\x18\x06 \x03(\tR\x02in\x12\x15\n" +
+	"\x03gte\x18\a \x01(\x03H\x00R\x03gte\x88\x01\x01\x12\x15\n" +
+	"\x03lte\x18\b \x01(\x03H\x01R\x03lte\x88\x01\x01B\x06\n" +
+	"\x04_gteB\x06\n" +
+	"\x04_lte:N\n" +
+	"\x05field\x12\x1d.google.protobuf.FieldOptions\x18\xb8\x8e\x03 \x01(\v2\x17.validate.v1.FieldRulesR\x05fieldB Z\x1emain/pkg/proto/gen/validate/v1b\x06proto3"
+
+var (
+	file_validate_v1_validate_proto_rawDescOnce sync.Once
+	file_validate_v1_validate_proto_rawDescData []byte
+)
+
+func file_validate_v1_validate_proto_rawDescGZIP() []byte {
+	file_validate_v1_validate_proto_rawDescOnce.Do(func() {
+		file_validate_v1_validate_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_validate_v1_validate_proto_rawDesc), len(file_validate_v1_validate_proto_rawDesc)))
+	})
+	return file_validate_v1_validate_proto_rawDescData
+}
+
+var file_validate_v1_validate_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_validate_v1_validate_proto_goTypes = []any{
+	(*FieldRules)(nil),                // 0: validate.v1.FieldRules
+	(*descriptorpb.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+}
+var file_validate_v1_validate_proto_depIdxs = []int32{
+	1, // 0: validate.v1.field:extendee -> google.protobuf.FieldOptions
+	0, // 1: validate.v1.field:type_name -> validate.v1.FieldRules
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	1, // [1:2] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_validate_v1_validate_proto_init() }
+func file_validate_v1_validate_proto_init() {
+	if File_validate_v1_validate_proto != nil {
+		return
+	}
+	file_validate_v1_validate_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_validate_v1_validate_proto_rawDesc), len(file_validate_v1_validate_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_validate_v1_validate_proto_goTypes,
+		DependencyIndexes: file_validate_v1_validate_proto_depIdxs,
+		MessageInfos:      file_validate_v1_validate_proto_msgTypes,
+		ExtensionInfos:    file_validate_v1_validate_proto_extTypes,
+	}.Build()
+	File_validate_v1_validate_proto = out.File
+	file_validate_v1_validate_proto_goTypes = nil
+	file_validate_v1_validate_proto_depIdxs = nil
+}
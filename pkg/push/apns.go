@@ -0,0 +1,150 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionURL = "https://api.push.apple.com/3/device/"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com/3/device/"
+	// apnsTokenTTL is how long a provider token is reused, APNs rejects tokens older than an hour
+	// and those refreshed more often than every 20 minutes.
+	apnsTokenTTL = 50 * time.Minute
+)
+
+// APNs sends messages to iOS apps through the Apple Push Notification service over HTTP/2, authenticating
+// with a token signed by a .p8 key of the team.
+type APNs struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+	topic  string
+	url    string
+	client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNs reads the .p8 signing key; topic is the bundle ID of the app and sandbox selects the development environment.
+func NewAPNs(keyFile, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("apns key: failed to decode PEM block")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("apns key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns key: expected an ECDSA key")
+	}
+	url := apnsProductionURL
+	if sandbox {
+		url = apnsSandboxURL
+	}
+	return &APNs{
+		key:    key,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		url:    url,
+		client: newHTTPClient(),
+	}, nil
+}
+
+type apnsPayload struct {
+	APS  apnsAPS           `json:"aps"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type apnsAPS struct {
+	Alert apnsAlert `json:"alert"`
+	Sound string    `json:"sound"`
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Send returns ErrUnregistered if APNs reports the device token invalid or no longer active.
+func (a *APNs) Send(ctx context.Context, target Target, msg Message) error {
+	token, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(apnsPayload{
+		APS:  apnsAPS{Alert: apnsAlert{Title: msg.Title, Body: msg.Body}, Sound: "default"},
+		Data: msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+target.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns send: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&result)
+	if resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "Unregistered" {
+		return ErrUnregistered
+	}
+	return fmt.Errorf("apns send: unexpected status %d: %s", resp.StatusCode, result.Reason)
+}
+
+// providerToken returns the cached provider token, signing a new one once it gets old.
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.issuedAt) < apnsTokenTTL {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.keyID
+	signed, err := token.SignedString(a.key)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.issuedAt = signed, now
+	return a.token, nil
+}
@@ -0,0 +1,184 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	fcmScope   = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmSendURL = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+)
+
+// serviceAccount is the part of a Google service account key file FCM needs.
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCM sends messages to Android and iOS apps through the Firebase Cloud Messaging HTTP v1 API, authenticating
+// with a service account. Access tokens are cached until shortly before they expire.
+type FCM struct {
+	account serviceAccount
+	key     *rsa.PrivateKey
+	client  *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCM reads the service account key file downloaded from the Firebase console.
+func NewFCM(credentialsFile string) (*FCM, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("fcm credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, errors.New("fcm credentials: not a service account key file")
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("fcm credentials: failed to decode private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("fcm credentials: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("fcm credentials: expected an RSA key")
+	}
+	return &FCM{
+		account: account,
+		key:     key,
+		client:  newHTTPClient(),
+	}, nil
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmError struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Send returns ErrUnregistered if FCM reports the token unregistered or invalid.
+func (f *FCM) Send(ctx context.Context, target Target, msg Message) error {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        target.Token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(fcmSendURL, f.account.ProjectID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm send: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var result fcmError
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&result)
+	if resp.StatusCode == http.StatusNotFound || result.Error.Status == "UNREGISTERED" ||
+		(resp.StatusCode == http.StatusBadRequest && result.Error.Status == "INVALID_ARGUMENT" && strings.Contains(result.Error.Message, "registration token")) {
+		return ErrUnregistered
+	}
+	return fmt.Errorf("fcm send: unexpected status %d: %s", resp.StatusCode, result.Error.Message)
+}
+
+// token returns a cached OAuth2 access token, exchanging a signed assertion for a new one when it's about to expire.
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Until(f.expiresAt) > time.Minute {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fcm token: unexpected status %d", resp.StatusCode)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("fcm token: %w", err)
+	}
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
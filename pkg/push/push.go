@@ -0,0 +1,39 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrUnregistered is returned for devices the provider no longer delivers to, their tokens should be forgotten.
+var ErrUnregistered = errors.New("push token is no longer valid")
+
+// Message is a notification shown by the device.
+type Message struct {
+	Title string
+	Body  string
+	// Data is handed to the app along with the notification
+	Data map[string]string
+}
+
+// Target is the device a message is sent to. Token is the registration token for FCM and APNs and the push
+// service endpoint for WebPush, whose subscriptions also carry the P256dh and Auth keys.
+type Target struct {
+	Token  string
+	P256dh string
+	Auth   string
+}
+
+// Provider delivers messages through a push service.
+type Provider interface {
+	Send(ctx context.Context, target Target, msg Message) error
+}
+
+// defaultTimeout bounds a single request to a push service.
+const defaultTimeout = 10 * time.Second
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: defaultTimeout}
+}
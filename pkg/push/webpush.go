@@ -0,0 +1,202 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// webPushTTL is how long the push service keeps a message for a browser that is offline.
+	webPushTTL = 24 * time.Hour
+	// webPushRecordSize is the aes128gcm record size, messages are sent as a single record.
+	webPushRecordSize = 4096
+)
+
+// WebPush sends messages to browsers through the push service of their subscription, encrypting the payload
+// as described in RFC 8291 and identifying the server with VAPID (RFC 8292).
+type WebPush struct {
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	client    *http.Client
+}
+
+// NewWebPush takes the VAPID private key as an unpadded base64url P-256 scalar, the format generated by the usual
+// web push tools, and a contact URL or mailto: address for the push services.
+func NewWebPush(privateKey, subject string) (*WebPush, error) {
+	d, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("vapid key: %w", err)
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("vapid key: %w", err)
+	}
+	public := ecdhKey.PublicKey().Bytes()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &WebPush{
+		key:       key,
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		subject:   subject,
+		client:    newHTTPClient(),
+	}, nil
+}
+
+// PublicKey returns the VAPID public key browsers subscribe with (applicationServerKey).
+func (w *WebPush) PublicKey() string {
+	return w.publicKey
+}
+
+type webPushPayload struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// Send returns ErrUnregistered if the push service reports the subscription expired or unknown.
+func (w *WebPush) Send(ctx context.Context, target Target, msg Message) error {
+	endpoint, err := url.Parse(target.Token)
+	if err != nil || endpoint.Scheme != "https" {
+		return ErrUnregistered
+	}
+	payload, err := json.Marshal(webPushPayload{Title: msg.Title, Body: msg.Body, Data: msg.Data})
+	if err != nil {
+		return err
+	}
+	body, err := encryptWebPush(target, payload)
+	if err != nil {
+		return err
+	}
+	vapid, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": w.subject,
+	}).SignedString(w.key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", fmt.Sprint(int(webPushTTL.Seconds())))
+	req.Header.Set("Authorization", "vapid t="+vapid+", k="+w.publicKey)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webpush send: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrUnregistered
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webpush send: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// encryptWebPush encrypts the payload for the subscription keys into a single aes128gcm record (RFC 8188),
+// with a fresh sender key and salt per message.
+func encryptWebPush(target Target, payload []byte) ([]byte, error) {
+	uaPublicBytes, err := base64.RawURLEncoding.DecodeString(target.P256dh)
+	if err != nil {
+		return nil, ErrUnregistered
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, ErrUnregistered
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(target.Auth)
+	if err != nil || len(authSecret) == 0 {
+		return nil, ErrUnregistered
+	}
+	if len(payload)+17 > webPushRecordSize-86 {
+		return nil, errors.New("webpush payload too large")
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// the input keying material mixes the ECDH secret with the subscription's auth secret
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublicBytes...), asPublic...)
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	ikm, err := hkdf.Expand(sha256.New, prkKey, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// header: salt, record size, key id length and the sender's public key as the key id
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	// 0x02 delimits the last (and only) record
+	plaintext := append(payload, 0x02)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
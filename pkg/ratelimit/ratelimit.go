@@ -0,0 +1,95 @@
+// Package ratelimit limits how often a caller may make a request, with the state kept in Redis so every instance
+// enforces the same allowance. Requests are spread over the window by the generic cell rate algorithm: a caller
+// may make Burst requests at once, after which one more is allowed every Window/Requests.
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "rate_limit:"
+
+// Rule allows Requests requests per Window, up to Burst of them at once. A Burst of 0 allows the whole window's
+// requests at once.
+type Rule struct {
+	Requests int
+	Window   time.Duration
+	Burst    int
+}
+
+func (r Rule) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Requests
+}
+
+// Result is the outcome of a request, Remaining is how many more requests could be made right away.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// allowScript keeps the theoretical arrival time of the caller's next request in microseconds of the Redis clock,
+// so the instances don't depend on their own clocks agreeing.
+var allowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+  tat = now
+end
+local newTat = tat + interval
+local allowAt = newTat - interval * burst
+if now < allowAt then
+  return {0, 0, math.ceil(allowAt - now)}
+end
+redis.call('SET', KEYS[1], string.format('%.0f', newTat), 'PX', math.ceil((newTat - now) / 1000) + 1)
+return {1, math.floor((now - allowAt) / interval), 0}
+`)
+
+type Limiter struct {
+	client *redis.Client
+}
+
+func NewLimiter(client *redis.Client) *Limiter {
+	return &Limiter{client: client}
+}
+
+// Allow counts a request of the caller identified by key against the rule. Callers share an allowance when they
+// share a key, so the key should name both the limited operation and the caller.
+func (l *Limiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	interval := rule.Window.Microseconds() / int64(rule.Requests)
+	reply, err := allowScript.Run(ctx, l.client, []string{keyPrefix + key}, interval, rule.burst()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    reply[0] == 1,
+		Limit:      rule.burst(),
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Microsecond,
+	}, nil
+}
+
+// Headers returns the X-RateLimit-* headers, and Retry-After for a rejected request, telling clients the state
+// of their allowance.
+func (r Result) Headers() map[string]string {
+	headers := map[string]string{
+		"X-RateLimit-Limit":     strconv.Itoa(r.Limit),
+		"X-RateLimit-Remaining": strconv.Itoa(r.Remaining),
+	}
+	if !r.Allowed {
+		// whole seconds, rounded up so a client retrying right then is let through
+		headers["Retry-After"] = strconv.FormatInt(int64((r.RetryAfter+time.Second-1)/time.Second), 10)
+	}
+	return headers
+}
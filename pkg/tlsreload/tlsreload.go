@@ -0,0 +1,121 @@
+// Package tlsreload serves TLS with a certificate read from files and re-reads the files when they change on disk,
+// so certificates rotated by e.g. cert-manager are used without a restart.
+package tlsreload
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader holds the server certificate and, for mutual TLS, the pool of client CAs. Handshakes use whatever
+// was loaded last.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu       sync.RWMutex
+	config   *tls.Config
+	modTimes []time.Time
+}
+
+// New loads the certificate and key, and the CAs client certificates must be signed by if clientCAFile is set.
+func New(certFile, keyFile, clientCAFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the config servers are set up with, each handshake gets the certificates loaded last.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.config, nil
+		},
+	}
+}
+
+// Reload re-reads the files if any of them changed since they were loaded. A certificate that fails to load is
+// reported and the previous one stays in use.
+func (r *Reloader) Reload(ctx context.Context) error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	changed := false
+	for i, t := range modTimes {
+		changed = changed || !t.Equal(r.modTimes[i])
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	return r.load(modTimes)
+}
+
+func (r *Reloader) files() []string {
+	if r.clientCAFile == "" {
+		return []string{r.certFile, r.keyFile}
+	}
+	return []string{r.certFile, r.keyFile, r.clientCAFile}
+}
+
+func (r *Reloader) stat() ([]time.Time, error) {
+	files := r.files()
+	modTimes := make([]time.Time, len(files))
+	for i, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (r *Reloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		// the gRPC transport negotiates HTTP/2 through ALPN, HTTP/1.1 is kept for the HTTP server
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("client CA file contains no PEM certificates")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.mu.Lock()
+	r.config = config
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
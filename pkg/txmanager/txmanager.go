@@ -0,0 +1,62 @@
+// Package txmanager runs several repository calls in one database transaction. The transaction travels in the
+// context, repositories pick it up with From and fall back to the pool outside of a transaction.
+package txmanager
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is what repositories run statements on, implemented by pools, e.g. *pgxpool.Pool, and by pgx.Tx.
+// Begin on a transaction starts a savepoint, so repositories may keep opening their own transactions.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type txKey struct{}
+
+// From returns the transaction of the context, or the pool if there is none.
+func From(ctx context.Context, pool Querier) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+// Replicas hands out the pool of a read replica.
+type Replicas interface {
+	Pool() Querier
+}
+
+// ForRead returns what a read that tolerates replication lag runs on: the transaction of the context, so reads in
+// a transaction see its writes, or a replica.
+func ForRead(ctx context.Context, replicas Replicas) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return replicas.Pool()
+}
+
+type Manager struct {
+	pool Querier
+}
+
+func New(pool Querier) *Manager {
+	return &Manager{pool: pool}
+}
+
+// WithinTx runs fn in a transaction carried by the context passed to it. The transaction is committed if fn returns
+// nil and rolled back otherwise. Calls nested in another WithinTx join the outer transaction.
+func (m *Manager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+	return pgx.BeginFunc(ctx, m.pool, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -0,0 +1,90 @@
+package useragent
+
+import "strings"
+
+// Device is what could be recognized from a User-Agent, unknown parts are empty.
+type Device struct {
+	Browser string
+	OS      string
+	// App is set for non-browser clients, e.g. gRPC or HTTP libraries and command line tools
+	App string
+}
+
+// token markers are checked in order, the first match wins.
+// Order matters: Edge and Opera also send "Chrome/", Chrome also sends "Safari/", Android also sends "Linux".
+var (
+	apps = []struct{ marker, name string }{
+		{"grpc-", "gRPC client"},
+		{"okhttp/", "Android app"},
+		{"CFNetwork/", "iOS app"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+		{"Go-http-client/", "Go client"},
+		{"python-requests/", "Python client"},
+	}
+	browsers = []struct{ marker, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"YaBrowser/", "Yandex Browser"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	systems = []struct{ marker, name string }{
+		{"Windows", "Windows"},
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"Macintosh", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// Parse recognizes the browser, operating system or client application from a User-Agent header.
+func Parse(userAgent string) Device {
+	var d Device
+	for _, a := range apps {
+		if strings.Contains(userAgent, a.marker) {
+			d.App = a.name
+			break
+		}
+	}
+	if d.App == "" && strings.HasPrefix(userAgent, "Mozilla/") {
+		for _, b := range browsers {
+			if strings.Contains(userAgent, b.marker) {
+				d.Browser = b.name
+				break
+			}
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.marker) {
+			d.OS = s.name
+			break
+		}
+	}
+	return d
+}
+
+// Label is a human readable device name like "Chrome on Windows".
+func (d Device) Label() string {
+	client := d.App
+	if client == "" {
+		client = d.Browser
+	}
+	switch {
+	case client != "" && d.OS != "":
+		return client + " on " + d.OS
+	case client != "":
+		return client
+	case d.OS != "":
+		return d.OS + " device"
+	default:
+		return "Unknown device"
+	}
+}
@@ -2,19 +2,126 @@ package context
 
 import (
 	"context"
+	"slices"
+
+	"github.com/google/uuid"
 )
 
 type key int
 
 const (
-	userIDKey key = iota
+	principalKey key = iota
+	clientInfoKey
+	requestScopeKey
 )
 
-func NewContext(ctx context.Context, userID string) context.Context {
-	return context.WithValue(ctx, userIDKey, userID)
+// AuthMethod is how the caller of a request proved who they are.
+type AuthMethod string
+
+const (
+	// AuthAccessToken is a user presenting the access token of a session.
+	AuthAccessToken AuthMethod = "access_token"
+	// AuthAPIKey is an internal service presenting an API key, it acts for no user.
+	AuthAPIKey AuthMethod = "api_key"
+	// AuthAppToken is a third-party developer app presenting an access token of the public API, it acts for no user.
+	AuthAppToken AuthMethod = "app_token"
+)
+
+// Principal is the authenticated caller of a request: a user with the session of their access token and their
+// roles, a service with its API key or a developer app with its access token.
+type Principal struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Roles     []string
+	APIKeyID  uuid.UUID
+	AppID     uuid.UUID
+	Method    AuthMethod
+}
+
+// IsUser reports whether the caller is a user rather than a service.
+func (p Principal) IsUser() bool {
+	return p.UserID != uuid.Nil
+}
+
+// HasAnyRole reports whether the caller has any of the roles.
+func (p Principal) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestScope is shared by all contexts derived from the one of a request, so middleware that runs before
+// the authentication still learns the caller, e.g. to report a panic.
+type requestScope struct {
+	requestID string
+	principal *Principal
+}
+
+type clientInfo struct {
+	ip        string
+	userAgent string
+}
+
+// WithPrincipal stores the authenticated caller in the context.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	if scope, ok := ctx.Value(requestScopeKey).(*requestScope); ok {
+		scope.principal = &p
+	}
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFromContext returns the authenticated caller, false for anonymous requests.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
+
+// UserIDFromContext returns the authenticated user, false for anonymous requests and services.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok || !p.IsUser() {
+		return uuid.Nil, false
+	}
+	return p.UserID, true
+}
+
+// WithClientInfo stores the client IP and User-Agent of the request in the context.
+func WithClientInfo(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, clientInfoKey, clientInfo{ip: ip, userAgent: userAgent})
+}
+
+// ClientInfoFromContext returns the client IP and User-Agent of the request, empty if unknown.
+func ClientInfoFromContext(ctx context.Context) (ip, userAgent string) {
+	info, _ := ctx.Value(clientInfoKey).(clientInfo)
+	return info.ip, info.userAgent
+}
+
+// WithRequestID starts the scope of a request with its ID in the context.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestScopeKey, &requestScope{requestID: requestID})
+}
+
+// RequestIDFromContext returns the ID of the request.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	scope, ok := ctx.Value(requestScopeKey).(*requestScope)
+	if !ok {
+		return "", false
+	}
+	return scope.requestID, true
 }
 
-func FromContext(ctx context.Context) (string, bool) {
-	id, ok := ctx.Value(userIDKey).(string)
-	return id, ok
+// RequestPrincipalFromContext returns the caller of the request like PrincipalFromContext, also in contexts
+// derived before the authentication.
+func RequestPrincipalFromContext(ctx context.Context) (Principal, bool) {
+	if p, ok := PrincipalFromContext(ctx); ok {
+		return p, true
+	}
+	scope, ok := ctx.Value(requestScopeKey).(*requestScope)
+	if !ok || scope.principal == nil {
+		return Principal{}, false
+	}
+	return *scope.principal, true
 }
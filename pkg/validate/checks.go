@@ -0,0 +1,81 @@
+package validate
+
+import (
+	"fmt"
+	"main/pkg/apperror"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Descriptions of the violations, the field path is put in front of them. Their Rule and Params let pkg/i18n
+// translate them.
+var (
+	descRequired = apperror.FieldViolation{Rule: "required", Description: "is required"}
+	descUUID     = apperror.FieldViolation{Rule: "uuid", Description: "must be a UUID"}
+	descEmail    = apperror.FieldViolation{Rule: "email", Description: "must be an email address"}
+	descURL      = apperror.FieldViolation{Rule: "url", Description: "must be an http or https URL"}
+)
+
+// at returns the violation of the field at path.
+func at(path string, desc apperror.FieldViolation) apperror.FieldViolation {
+	desc.Field = path
+	return desc
+}
+
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
+
+// isEmail accepts a bare address only, like email.Validator does, whether the domain is allowed is up to the usecase.
+func isEmail(s string) bool {
+	parsed, err := mail.ParseAddress(s)
+	return err == nil && parsed.Name == "" && parsed.Address == s
+}
+
+func isOneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func descOneOf(values []string) apperror.FieldViolation {
+	list := strings.Join(values, ", ")
+	return apperror.FieldViolation{Rule: "oneof", Params: []string{list}, Description: "must be one of " + list}
+}
+
+func descMinLen(n int64) apperror.FieldViolation {
+	return bound("min_len", n, "must be at least %s characters long")
+}
+
+func descMaxLen(n int64) apperror.FieldViolation {
+	return bound("max_len", n, "must be at most %s characters long")
+}
+
+func descMinItems(n int64) apperror.FieldViolation {
+	return bound("min_items", n, "must have at least %s items")
+}
+
+func descMaxItems(n int64) apperror.FieldViolation {
+	return bound("max_items", n, "must have at most %s items")
+}
+
+func descMin(n int64) apperror.FieldViolation {
+	return bound("min", n, "must be at least %s")
+}
+
+func descMax(n int64) apperror.FieldViolation {
+	return bound("max", n, "must be at most %s")
+}
+
+// bound returns the violation of a rule with the bound n, format is its English description with a %s for n.
+func bound(rule string, n int64, format string) apperror.FieldViolation {
+	param := strconv.FormatInt(n, 10)
+	return apperror.FieldViolation{Rule: rule, Params: []string{param}, Description: fmt.Sprintf(format, param)}
+}
@@ -0,0 +1,162 @@
+package validate
+
+import (
+	"main/pkg/apperror"
+	validatev1 "main/pkg/proto/gen/validate/v1"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type protoField struct {
+	desc  protoreflect.FieldDescriptor
+	rules *validatev1.FieldRules
+}
+
+// protoFieldsCache holds the fields with rules, and the message fields to descend into, of each message type.
+var protoFieldsCache sync.Map
+
+// Message validates m against the validate.v1.field options of its fields, nested messages included. Field paths
+// use the proto field names, e.g. "notifications.likes" or "member_ids[1]".
+func Message(m proto.Message) error {
+	var violations []apperror.FieldViolation
+	checkMessage(m.ProtoReflect(), "", &violations)
+	if len(violations) > 0 {
+		return apperror.Invalid(violations)
+	}
+	return nil
+}
+
+func checkMessage(m protoreflect.Message, prefix string, violations *[]apperror.FieldViolation) {
+	for _, f := range protoFieldsOf(m.Descriptor()) {
+		path := joinPath(prefix, string(f.desc.Name()))
+		fd := f.desc
+		switch {
+		case fd.IsList():
+			list := m.Get(fd).List()
+			if desc := checkCount(int64(list.Len()), f.rules); desc.Rule != "" {
+				*violations = append(*violations, at(path, desc))
+				continue
+			}
+			for i := 0; i < list.Len(); i++ {
+				itemPath := path + "[" + strconv.Itoa(i) + "]"
+				if fd.Kind() == protoreflect.MessageKind {
+					checkMessage(list.Get(i).Message(), itemPath, violations)
+				} else if desc := checkScalar(fd, list.Get(i), f.rules, false); desc.Rule != "" {
+					*violations = append(*violations, at(itemPath, desc))
+				}
+			}
+		case fd.IsMap():
+			if desc := checkCount(int64(m.Get(fd).Map().Len()), f.rules); desc.Rule != "" {
+				*violations = append(*violations, at(path, desc))
+			}
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if !m.Has(fd) {
+				if f.rules.GetRequired() {
+					*violations = append(*violations, at(path, descRequired))
+				}
+				continue
+			}
+			checkMessage(m.Get(fd).Message(), path, violations)
+		default:
+			if desc := checkScalar(fd, m.Get(fd), f.rules, f.rules.GetRequired()); desc.Rule != "" {
+				*violations = append(*violations, at(path, desc))
+			}
+		}
+	}
+}
+
+// checkCount checks the rules of a repeated or map field that are about the number of items.
+func checkCount(n int64, rules *validatev1.FieldRules) apperror.FieldViolation {
+	if rules.GetRequired() && n == 0 {
+		return descRequired
+	}
+	if minLen := int64(rules.GetMinLen()); minLen > 0 && n < minLen {
+		return descMinItems(minLen)
+	}
+	if maxLen := int64(rules.GetMaxLen()); maxLen > 0 && n > maxLen {
+		return descMaxItems(maxLen)
+	}
+	return apperror.FieldViolation{}
+}
+
+// checkScalar checks a singular value, or an item of a repeated field where length rules are about the field.
+func checkScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value, rules *validatev1.FieldRules, required bool) apperror.FieldViolation {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s := v.String()
+		if s == "" {
+			if required {
+				return descRequired
+			}
+			return apperror.FieldViolation{}
+		}
+		if !fd.IsList() {
+			n := int64(utf8.RuneCountInString(s))
+			if minLen := int64(rules.GetMinLen()); minLen > 0 && n < minLen {
+				return descMinLen(minLen)
+			}
+			if maxLen := int64(rules.GetMaxLen()); maxLen > 0 && n > maxLen {
+				return descMaxLen(maxLen)
+			}
+		}
+		if rules.GetUuid() && !isUUID(s) {
+			return descUUID
+		}
+		if rules.GetEmail() && !isEmail(s) {
+			return descEmail
+		}
+		if len(rules.GetIn()) > 0 && !isOneOf(s, rules.GetIn()) {
+			return descOneOf(rules.GetIn())
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return checkInt(v.Int(), rules, required)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return checkInt(int64(v.Uint()), rules, required)
+	case protoreflect.EnumKind:
+		if required && v.Enum() == 0 {
+			return descRequired
+		}
+	case protoreflect.BytesKind:
+		if required && len(v.Bytes()) == 0 {
+			return descRequired
+		}
+	}
+	return apperror.FieldViolation{}
+}
+
+func checkInt(n int64, rules *validatev1.FieldRules, required bool) apperror.FieldViolation {
+	if required && n == 0 {
+		return descRequired
+	}
+	if rules.Gte != nil && n < rules.GetGte() {
+		return descMin(rules.GetGte())
+	}
+	if rules.Lte != nil && n > rules.GetLte() {
+		return descMax(rules.GetLte())
+	}
+	return apperror.FieldViolation{}
+}
+
+func protoFieldsOf(md protoreflect.MessageDescriptor) []protoField {
+	if cached, ok := protoFieldsCache.Load(md.FullName()); ok {
+		return cached.([]protoField)
+	}
+	var fields []protoField
+	descs := md.Fields()
+	for i := 0; i < descs.Len(); i++ {
+		fd := descs.Get(i)
+		rules, _ := proto.GetExtension(fd.Options(), validatev1.E_Field).(*validatev1.FieldRules)
+		isMessage := fd.Kind() == protoreflect.MessageKind && !fd.IsMap()
+		if rules == nil && !isMessage {
+			continue
+		}
+		fields = append(fields, protoField{desc: fd, rules: rules})
+	}
+	protoFieldsCache.Store(md.FullName(), fields)
+	return fields
+}
@@ -0,0 +1,307 @@
+package validate_test
+
+import (
+	"main/pkg/apperror"
+	adminpb "main/pkg/proto/gen/admin/v1"
+	validatev1 "main/pkg/proto/gen/validate/v1"
+	"main/pkg/validate"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// testMessages declares, like a .proto file would, a Request message with a field for every rule and kind of field:
+//
+//	enum Kind { KIND_UNSPECIFIED = 0; KIND_A = 1; }
+//	message Item { string value = 1 [(validate.v1.field) = {required: true}]; }
+//	message Request {
+//	  string name = 1 [(validate.v1.field) = {required: true, min_len: 2, max_len: 4}];
+//	  string id = 2 [(validate.v1.field) = {uuid: true}];
+//	  string email = 3 [(validate.v1.field) = {email: true}];
+//	  string role = 4 [(validate.v1.field) = {in: ["user", "admin"]}];
+//	  int32 count = 5 [(validate.v1.field) = {gte: 1, lte: 10}];
+//	  uint64 size = 6 [(validate.v1.field) = {lte: 5}];
+//	  sint64 delta = 7 [(validate.v1.field) = {gte: -5}];
+//	  Kind kind = 8 [(validate.v1.field) = {required: true}];
+//	  bytes data = 9 [(validate.v1.field) = {required: true}];
+//	  repeated string ids = 10 [(validate.v1.field) = {min_len: 1, max_len: 2, uuid: true}];
+//	  repeated Item items = 11;
+//	  Item item = 12 [(validate.v1.field) = {required: true}];
+//	  Item extra = 13;
+//	  map<string, string> labels = 14 [(validate.v1.field) = {max_len: 1}];
+//	}
+func testMessages(t *testing.T) (request, item protoreflect.MessageDescriptor) {
+	t.Helper()
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, rules *validatev1.FieldRules) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if rules != nil {
+			f.Options = &descriptorpb.FieldOptions{}
+			proto.SetExtension(f.Options, validatev1.E_Field, rules)
+		}
+		return f
+	}
+	repeated := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return f
+	}
+	ofType := func(f *descriptorpb.FieldDescriptorProto, typeName string) *descriptorpb.FieldDescriptorProto {
+		f.TypeName = proto.String(typeName)
+		return f
+	}
+	const (
+		str   = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		msg   = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		enum  = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+		bytes = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	)
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("validate/test.proto"),
+		Package: proto.String("validate.test"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_A"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Item"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("value", 1, str, &validatev1.FieldRules{Required: true})},
+			},
+			{
+				Name: proto.String("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, str, &validatev1.FieldRules{Required: true, MinLen: 2, MaxLen: 4}),
+					field("id", 2, str, &validatev1.FieldRules{Uuid: true}),
+					field("email", 3, str, &validatev1.FieldRules{Email: true}),
+					field("role", 4, str, &validatev1.FieldRules{In: []string{"user", "admin"}}),
+					field("count", 5, descriptorpb.FieldDescriptorProto_TYPE_INT32, &validatev1.FieldRules{Gte: proto.Int64(1), Lte: proto.Int64(10)}),
+					field("size", 6, descriptorpb.FieldDescriptorProto_TYPE_UINT64, &validatev1.FieldRules{Lte: proto.Int64(5)}),
+					field("delta", 7, descriptorpb.FieldDescriptorProto_TYPE_SINT64, &validatev1.FieldRules{Gte: proto.Int64(-5)}),
+					ofType(field("kind", 8, enum, &validatev1.FieldRules{Required: true}), ".validate.test.Kind"),
+					field("data", 9, bytes, &validatev1.FieldRules{Required: true}),
+					repeated(field("ids", 10, str, &validatev1.FieldRules{MinLen: 1, MaxLen: 2, Uuid: true})),
+					repeated(ofType(field("items", 11, msg, nil), ".validate.test.Item")),
+					ofType(field("item", 12, msg, &validatev1.FieldRules{Required: true}), ".validate.test.Item"),
+					ofType(field("extra", 13, msg, nil), ".validate.test.Item"),
+					repeated(ofType(field("labels", 14, msg, &validatev1.FieldRules{MaxLen: 1}), ".validate.test.Request.LabelsEntry")),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name:    proto.String("LabelsEntry"),
+					Field:   []*descriptorpb.FieldDescriptorProto{field("key", 1, str, nil), field("value", 2, str, nil)},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd.Messages().ByName("Request"), fd.Messages().ByName("Item")
+}
+
+func TestMessage(t *testing.T) {
+	requestDesc, itemDesc := testMessages(t)
+	fields := requestDesc.Fields()
+	newItem := func(value string) protoreflect.Value {
+		item := dynamicpb.NewMessage(itemDesc)
+		item.Set(itemDesc.Fields().ByName("value"), protoreflect.ValueOfString(value))
+		return protoreflect.ValueOfMessage(item)
+	}
+	set := func(m *dynamicpb.Message, name string, v protoreflect.Value) {
+		m.Set(fields.ByName(protoreflect.Name(name)), v)
+	}
+	appendTo := func(m *dynamicpb.Message, name string, values ...protoreflect.Value) {
+		list := m.Mutable(fields.ByName(protoreflect.Name(name))).List()
+		for _, v := range values {
+			list.Append(v)
+		}
+	}
+	str := protoreflect.ValueOfString
+	validRequest := func() *dynamicpb.Message {
+		m := dynamicpb.NewMessage(requestDesc)
+		set(m, "name", str("ann"))
+		set(m, "count", protoreflect.ValueOfInt32(1))
+		set(m, "kind", protoreflect.ValueOfEnum(1))
+		set(m, "data", protoreflect.ValueOfBytes([]byte{1}))
+		appendTo(m, "ids", str("0b0c8f0e-59d4-4a5c-9d1a-7d6b1b5d0c3e"))
+		set(m, "item", newItem("x"))
+		return m
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *dynamicpb.Message)
+		want   []apperror.FieldViolation
+	}{
+		{
+			name:   "valid",
+			modify: func(m *dynamicpb.Message) {},
+		},
+		{
+			name: "valid optional fields",
+			modify: func(m *dynamicpb.Message) {
+				set(m, "name", str("äöüß"))
+				set(m, "id", str("0b0c8f0e-59d4-4a5c-9d1a-7d6b1b5d0c3e"))
+				set(m, "email", str("ann@example.com"))
+				set(m, "role", str("admin"))
+				set(m, "count", protoreflect.ValueOfInt32(10))
+				set(m, "size", protoreflect.ValueOfUint64(5))
+				set(m, "delta", protoreflect.ValueOfInt64(-5))
+				appendTo(m, "items", newItem("a"), newItem("b"))
+				set(m, "extra", newItem("c"))
+				m.Mutable(fields.ByName("labels")).Map().Set(str("k").MapKey(), str("v"))
+			},
+		},
+		{
+			name:   "required string",
+			modify: func(m *dynamicpb.Message) { set(m, "name", str("")) },
+			want:   []apperror.FieldViolation{{Field: "name", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "min length counts characters",
+			modify: func(m *dynamicpb.Message) { set(m, "name", str("a")) },
+			want:   []apperror.FieldViolation{{Field: "name", Rule: "min_len", Params: []string{"2"}, Description: "must be at least 2 characters long"}},
+		},
+		{
+			name:   "max length",
+			modify: func(m *dynamicpb.Message) { set(m, "name", str("annie")) },
+			want:   []apperror.FieldViolation{{Field: "name", Rule: "max_len", Params: []string{"4"}, Description: "must be at most 4 characters long"}},
+		},
+		{
+			name:   "uuid",
+			modify: func(m *dynamicpb.Message) { set(m, "id", str("42")) },
+			want:   []apperror.FieldViolation{{Field: "id", Rule: "uuid", Description: "must be a UUID"}},
+		},
+		{
+			name:   "email",
+			modify: func(m *dynamicpb.Message) { set(m, "email", str("ann@")) },
+			want:   []apperror.FieldViolation{{Field: "email", Rule: "email", Description: "must be an email address"}},
+		},
+		{
+			name:   "in",
+			modify: func(m *dynamicpb.Message) { set(m, "role", str("root")) },
+			want:   []apperror.FieldViolation{{Field: "role", Rule: "oneof", Params: []string{"user, admin"}, Description: "must be one of user, admin"}},
+		},
+		{
+			name:   "gte applies to the zero value",
+			modify: func(m *dynamicpb.Message) { set(m, "count", protoreflect.ValueOfInt32(0)) },
+			want:   []apperror.FieldViolation{{Field: "count", Rule: "min", Params: []string{"1"}, Description: "must be at least 1"}},
+		},
+		{
+			name:   "lte",
+			modify: func(m *dynamicpb.Message) { set(m, "count", protoreflect.ValueOfInt32(11)) },
+			want:   []apperror.FieldViolation{{Field: "count", Rule: "max", Params: []string{"10"}, Description: "must be at most 10"}},
+		},
+		{
+			name:   "lte of an unsigned integer",
+			modify: func(m *dynamicpb.Message) { set(m, "size", protoreflect.ValueOfUint64(6)) },
+			want:   []apperror.FieldViolation{{Field: "size", Rule: "max", Params: []string{"5"}, Description: "must be at most 5"}},
+		},
+		{
+			name:   "negative gte",
+			modify: func(m *dynamicpb.Message) { set(m, "delta", protoreflect.ValueOfInt64(-6)) },
+			want:   []apperror.FieldViolation{{Field: "delta", Rule: "min", Params: []string{"-5"}, Description: "must be at least -5"}},
+		},
+		{
+			name:   "required enum",
+			modify: func(m *dynamicpb.Message) { set(m, "kind", protoreflect.ValueOfEnum(0)) },
+			want:   []apperror.FieldViolation{{Field: "kind", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "required bytes",
+			modify: func(m *dynamicpb.Message) { m.Clear(fields.ByName("data")) },
+			want:   []apperror.FieldViolation{{Field: "data", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "min items",
+			modify: func(m *dynamicpb.Message) { m.Clear(fields.ByName("ids")) },
+			want:   []apperror.FieldViolation{{Field: "ids", Rule: "min_items", Params: []string{"1"}, Description: "must have at least 1 items"}},
+		},
+		{
+			name: "max items",
+			modify: func(m *dynamicpb.Message) {
+				appendTo(m, "ids", str("f47ac10b-58cc-4372-a567-0e02b2c3d479"), str("6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+			},
+			want: []apperror.FieldViolation{{Field: "ids", Rule: "max_items", Params: []string{"2"}, Description: "must have at most 2 items"}},
+		},
+		{
+			name:   "rules of the items of a repeated field",
+			modify: func(m *dynamicpb.Message) { appendTo(m, "ids", str("42")) },
+			want:   []apperror.FieldViolation{{Field: "ids[1]", Rule: "uuid", Description: "must be a UUID"}},
+		},
+		{
+			name:   "repeated messages are validated",
+			modify: func(m *dynamicpb.Message) { appendTo(m, "items", newItem("a"), newItem("")) },
+			want:   []apperror.FieldViolation{{Field: "items[1].value", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "required message",
+			modify: func(m *dynamicpb.Message) { m.Clear(fields.ByName("item")) },
+			want:   []apperror.FieldViolation{{Field: "item", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "nested message",
+			modify: func(m *dynamicpb.Message) { set(m, "item", newItem("")) },
+			want:   []apperror.FieldViolation{{Field: "item.value", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "optional nested message",
+			modify: func(m *dynamicpb.Message) { set(m, "extra", newItem("")) },
+			want:   []apperror.FieldViolation{{Field: "extra.value", Rule: "required", Description: "is required"}},
+		},
+		{
+			name: "max items of a map",
+			modify: func(m *dynamicpb.Message) {
+				labels := m.Mutable(fields.ByName("labels")).Map()
+				labels.Set(str("a").MapKey(), str("1"))
+				labels.Set(str("b").MapKey(), str("2"))
+			},
+			want: []apperror.FieldViolation{{Field: "labels", Rule: "max_items", Params: []string{"1"}, Description: "must have at most 1 items"}},
+		},
+		{
+			name: "every invalid field is reported",
+			modify: func(m *dynamicpb.Message) {
+				set(m, "name", str(""))
+				set(m, "role", str("root"))
+				set(m, "item", newItem(""))
+			},
+			want: []apperror.FieldViolation{
+				{Field: "name", Rule: "required", Description: "is required"},
+				{Field: "role", Rule: "oneof", Params: []string{"user, admin"}, Description: "must be one of user, admin"},
+				{Field: "item.value", Rule: "required", Description: "is required"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := validRequest()
+			tt.modify(m)
+			assert.Equal(t, tt.want, violations(t, validate.Message(m)))
+		})
+	}
+}
+
+func TestMessageGenerated(t *testing.T) {
+	// the rules of the generated messages are read from their descriptors the same way
+	err := validate.Message(&adminpb.BlockUserRequest{UserId: "42"})
+	assert.Equal(t, []apperror.FieldViolation{{Field: "user_id", Rule: "uuid", Description: "must be a UUID"}}, violations(t, err))
+	assert.EqualError(t, err, "user_id must be a UUID")
+
+	assert.NoError(t, validate.Message(&adminpb.BlockUserRequest{UserId: "0b0c8f0e-59d4-4a5c-9d1a-7d6b1b5d0c3e"}))
+}
@@ -0,0 +1,136 @@
+// Package validate checks requests before they reach the handlers and reports every invalid field at once as an
+// apperror with field violations.
+//
+// HTTP request DTOs declare their rules in `validate` struct tags checked by go-playground/validator, Struct
+// translates its errors into field violations named like the client sends the fields. gRPC requests declare theirs
+// as validate.v1.field options in the protos, see Message.
+package validate
+
+import (
+	"errors"
+	"main/pkg/apperror"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(fieldName)
+	return v
+}
+
+// Struct validates the struct v points to against its `validate` tags. Values that aren't structs have no rules.
+func Struct(v any) error {
+	err := validate.Struct(v)
+	var invalid *validator.InvalidValidationError
+	if err == nil || errors.As(err, &invalid) {
+		return nil
+	}
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	root := reflect.TypeOf(v)
+	for root.Kind() == reflect.Pointer {
+		root = root.Elem()
+	}
+	violations := make([]apperror.FieldViolation, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		violations = append(violations, at(fieldPath(root, fe), describe(fe)))
+	}
+	return apperror.Invalid(violations)
+}
+
+// describe returns the violation of the rule the field failed.
+func describe(fe validator.FieldError) apperror.FieldViolation {
+	switch fe.Tag() {
+	case "required":
+		return descRequired
+	case "email":
+		return descEmail
+	case "uuid":
+		return descUUID
+	case "url", "http_url":
+		return descURL
+	case "oneof":
+		return descOneOf(strings.Fields(fe.Param()))
+	case "min", "max":
+		n, _ := strconv.ParseInt(fe.Param(), 10, 64)
+		isMin := fe.Tag() == "min"
+		switch fe.Kind() {
+		case reflect.String:
+			if isMin {
+				return descMinLen(n)
+			}
+			return descMaxLen(n)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			if isMin {
+				return descMinItems(n)
+			}
+			return descMaxItems(n)
+		}
+		if isMin {
+			return descMin(n)
+		}
+		return descMax(n)
+	}
+	return apperror.FieldViolation{Rule: fe.Tag(), Description: "is invalid"}
+}
+
+// fieldPath returns the path of the field the client sends, like "tags[0]" or "address.city". The namespace of
+// the error starts with the name of the root struct and names embedded structs, whose fields are promoted, so
+// both are dropped.
+func fieldPath(root reflect.Type, fe validator.FieldError) string {
+	names := strings.Split(fe.Namespace(), ".")[1:]
+	goNames := strings.Split(fe.StructNamespace(), ".")[1:]
+	t := root
+	var path string
+	for i, name := range names {
+		goName, _, _ := strings.Cut(goNames[i], "[")
+		sf, ok := t.FieldByName(goName)
+		if !ok {
+			path = joinPath(path, name)
+			continue
+		}
+		t = elemType(sf.Type)
+		if !sf.Anonymous {
+			path = joinPath(path, name)
+		}
+	}
+	return path
+}
+
+// elemType returns the struct type the fields of a value of type t are looked up in.
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// fieldName is the name the client sends the field by: its JSON name, else its query or form parameter.
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "query", "form"} {
+		name, _, _ := strings.Cut(sf.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,140 @@
+package validate_test
+
+import (
+	"errors"
+	"main/pkg/apperror"
+	"main/pkg/validate"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type Paging struct {
+	Order string `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+// request covers how fields are named and nested and how each failed rule is described.
+type request struct {
+	Paging
+	Name     string     `json:"name" validate:"required,min=3,max=5"`
+	Email    string     `json:"email" validate:"omitempty,email"`
+	ID       string     `query:"id" validate:"omitempty,uuid"`
+	Website  string     `form:"website" validate:"omitempty,http_url"`
+	Age      int        `json:"age" validate:"min=13,max=130"`
+	Tags     []string   `json:"tags" validate:"max=2,dive,min=2"`
+	Address  address    `json:"address"`
+	Previous []*address `json:"previous" validate:"dive"`
+}
+
+func validRequest() *request {
+	return &request{Name: "Ann", Age: 30, Address: address{City: "Oslo"}}
+}
+
+// violations returns the field violations of the error Struct or Message returned.
+func violations(t *testing.T, err error) []apperror.FieldViolation {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var appErr *apperror.Error
+	require.True(t, errors.As(err, &appErr), "not an apperror: %v", err)
+	assert.Equal(t, apperror.KindInvalidArgument, appErr.Kind)
+	return appErr.Fields
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(r *request)
+		want   []apperror.FieldViolation
+	}{
+		{
+			name:   "valid",
+			modify: func(r *request) {},
+		},
+		{
+			name:   "required",
+			modify: func(r *request) { r.Name = "" },
+			want:   []apperror.FieldViolation{{Field: "name", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "min length of a string",
+			modify: func(r *request) { r.Name = "ab" },
+			want:   []apperror.FieldViolation{{Field: "name", Rule: "min_len", Params: []string{"3"}, Description: "must be at least 3 characters long"}},
+		},
+		{
+			name:   "max number",
+			modify: func(r *request) { r.Age = 131 },
+			want:   []apperror.FieldViolation{{Field: "age", Rule: "max", Params: []string{"130"}, Description: "must be at most 130"}},
+		},
+		{
+			name:   "max items of a slice",
+			modify: func(r *request) { r.Tags = []string{"ab", "cd", "ef"} },
+			want:   []apperror.FieldViolation{{Field: "tags", Rule: "max_items", Params: []string{"2"}, Description: "must have at most 2 items"}},
+		},
+		{
+			name:   "email",
+			modify: func(r *request) { r.Email = "ann" },
+			want:   []apperror.FieldViolation{{Field: "email", Rule: "email", Description: "must be an email address"}},
+		},
+		{
+			name:   "uuid named by the query tag",
+			modify: func(r *request) { r.ID = "42" },
+			want:   []apperror.FieldViolation{{Field: "id", Rule: "uuid", Description: "must be a UUID"}},
+		},
+		{
+			name:   "url named by the form tag",
+			modify: func(r *request) { r.Website = "ftp://example.com" },
+			want:   []apperror.FieldViolation{{Field: "website", Rule: "url", Description: "must be an http or https URL"}},
+		},
+		{
+			name:   "fields of embedded structs are promoted",
+			modify: func(r *request) { r.Order = "random" },
+			want:   []apperror.FieldViolation{{Field: "order", Rule: "oneof", Params: []string{"asc, desc"}, Description: "must be one of asc, desc"}},
+		},
+		{
+			name:   "dive checks each item",
+			modify: func(r *request) { r.Tags = []string{"a", "bb"} },
+			want:   []apperror.FieldViolation{{Field: "tags[0]", Rule: "min_len", Params: []string{"2"}, Description: "must be at least 2 characters long"}},
+		},
+		{
+			name:   "nested struct",
+			modify: func(r *request) { r.Address.City = "" },
+			want:   []apperror.FieldViolation{{Field: "address.city", Rule: "required", Description: "is required"}},
+		},
+		{
+			name:   "struct items",
+			modify: func(r *request) { r.Previous = []*address{{City: "Rome"}, {}} },
+			want:   []apperror.FieldViolation{{Field: "previous[1].city", Rule: "required", Description: "is required"}},
+		},
+		{
+			name: "every invalid field is reported",
+			modify: func(r *request) {
+				r.Name = ""
+				r.Age = 200
+			},
+			want: []apperror.FieldViolation{
+				{Field: "name", Rule: "required", Description: "is required"},
+				{Field: "age", Rule: "max", Params: []string{"130"}, Description: "must be at most 130"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := validRequest()
+			tt.modify(r)
+			assert.Equal(t, tt.want, violations(t, validate.Struct(r)))
+		})
+	}
+}
+
+func TestStructNonStructs(t *testing.T) {
+	var r *request
+	assert.NoError(t, validate.Struct(r))
+	assert.NoError(t, validate.Struct(42))
+}